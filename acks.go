@@ -0,0 +1,436 @@
+package ep
+
+import (
+    "io"
+    "net"
+    "sync"
+    "time"
+    "bytes"
+    "context"
+    "encoding/binary"
+)
+
+var _ = registerGob(&withAcks{})
+
+// AckRedials is the number of attempts to re-establish a broken connection of
+// an acknowledged exchange (see WithAcks), before failing
+var AckRedials = 3
+
+// ackBufferSize bounds the received data of an acknowledged connection that
+// wasn't read yet, and the written data that wasn't acknowledged yet. Beyond
+// it, the receiving and the writing wait, respectively, like the buffers of
+// the underlying connection
+var ackBufferSize = 16 << 20
+
+// ackCloseTimeout bounds the time to wait for the acknowledgment of the sent
+// batches when closing an acknowledged connection
+const ackCloseTimeout = 5 * time.Second
+
+// WithAcks returns a Runner that runs the provided runner with acknowledged
+// exchanges. Every batch sent between nodes is numbered, and acknowledged by
+// its receiver. When a connection between nodes breaks, it's re-established,
+// and the batches that weren't acknowledged are re-sent, while the receiver
+// discards the ones it has already received. It gives effectively-once
+// delivery between nodes across transient network failures, for sink
+// pipelines that must neither lose nor duplicate rows. When distributed, it
+// applies to all nodes.
+func WithAcks(r Runner) Runner {
+    return &withAcks{r}
+}
+
+type withAcks struct { Runner Runner }
+
+func (r *withAcks) Returns() []Type { return r.Runner.Returns() }
+func (r *withAcks) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *withAcks) Run(ctx context.Context, inp, out chan Dataset) error {
+    ctx = context.WithValue(ctx, "ep.Acks", true)
+    return r.Runner.Run(ctx, inp, out)
+}
+
+func (r *withAcks) inner() []Runner { return []Runner{r.Runner} }
+func (r *withAcks) withInner(inner []Runner) Runner {
+    return &withAcks{inner[0]}
+}
+
+// acksOf returns true if the exchanges of the job in the context are
+// acknowledged
+func acksOf(ctx context.Context) bool {
+    acks, _ := ctx.Value("ep.Acks").(bool)
+    return acks
+}
+
+const (
+    frameData = 1
+    frameAck = 2 // the sequence number of the last received data frame
+    frameResume = 3 // same as ack, sent first upon reconnecting
+    frameClose = 4
+)
+
+// frame is a single message of an acknowledged connection. Data frames are
+// the writes to the connection, numbered by their sequence numbers
+type frame struct {
+    Kind byte
+    Seq uint64
+    Payload []byte
+}
+
+func writeFrame(w io.Writer, f frame) error {
+    b := make([]byte, 13 + len(f.Payload))
+    b[0] = f.Kind
+    binary.BigEndian.PutUint64(b[1:], f.Seq)
+    binary.BigEndian.PutUint32(b[9:], uint32(len(f.Payload)))
+    copy(b[13:], f.Payload)
+    _, err := w.Write(b)
+    return err
+}
+
+// readFrame reads the next frame. The size of the payload is verified before
+// it's allocated, see MaxMessageSize
+func readFrame(r io.Reader, limit sizeLimit) (f frame, err error) {
+    h := make([]byte, 13)
+    _, err = io.ReadFull(r, h)
+    if err != nil {
+        return
+    }
+
+    f.Kind, f.Seq = h[0], binary.BigEndian.Uint64(h[1:])
+    n := binary.BigEndian.Uint32(h[9:])
+    if err = limit.check(uint64(n)); err != nil {
+        return f, frameError{err}
+    }
+
+    f.Payload = make([]byte, n)
+    _, err = io.ReadFull(r, f.Payload)
+    return
+}
+
+// frameError is an invalid frame, which fails the connection rather than
+// re-establishing it
+type frameError struct { error }
+
+// ackedConn is a connection that survives the breaking of its underlying
+// connection, by re-establishing it with redial. Its writes are sent as
+// numbered data frames, which are acknowledged by the other end. Upon
+// reconnecting, both ends exchange the sequence number of the last frame they
+// have received, and re-send the frames that follow it. An end might not notice
+// the broken connection at all (e.g. when its peer has already replaced it),
+// thus a resume frame is always answered by a resume frame. The frames are read by
+// a go-routine, and the control frames (acks, resumes and close) are written
+// by another one, in order to never block the reading on writes.
+type ackedConn struct {
+    conn net.Conn // the current connection, replaced upon reconnecting
+    redial func() (net.Conn, error)
+    limit sizeLimit // of the frames, see readFrame
+    bufferSize int // see ackBufferSize
+
+    wl sync.Mutex // serializes the writes to the connection
+    l sync.Mutex // guards all of the fields below
+    cond *sync.Cond
+    sent uint64 // sequence number of the last written data frame
+    unacked []frame // written data frames that weren't acknowledged
+    unackedBytes int // the size of the payloads of the unacked frames
+    received uint64 // sequence number of the last received data frame
+    acked uint64 // the last received frame that was acknowledged
+    buf bytes.Buffer // the received data that wasn't read yet
+    resume bool // a resume frame is pending, after reconnecting
+    resumed bool // a resume frame was sent on the current connection
+    queued bool // new frames are queued until the unacked frames are re-sent
+    resending bool // re-sending the unacked frames after reconnecting
+    resent uint64 // the last re-sent frame
+    closing bool // closed locally, pending the acks of the unacked frames
+    closed bool // closed locally, pending the close frame
+    eof bool // closed by the other end
+    err error // failed to reconnect
+    done chan struct{} // closed when the control frames writer exits
+}
+
+func newAckedConn(conn net.Conn, redial func() (net.Conn, error)) *ackedConn {
+    c := &ackedConn{
+        conn: conn,
+        redial: redial,
+        limit: messageLimit(),
+        bufferSize: ackBufferSize,
+        done: make(chan struct{}),
+    }
+    c.cond = sync.NewCond(&c.l)
+    go c.receive()
+    go c.control()
+    return c
+}
+
+// Read the received data, in order, without duplicates
+func (c *ackedConn) Read(b []byte) (int, error) {
+    c.l.Lock()
+    defer c.l.Unlock()
+    for c.buf.Len() == 0 && !c.eof && c.err == nil && !c.closing {
+        c.cond.Wait()
+    }
+
+    if c.closing {
+        return 0, net.ErrClosed
+    } else if c.buf.Len() > 0 {
+        defer c.cond.Broadcast() // the receiving might wait, see ackBufferSize
+        return c.buf.Read(b)
+    } else if c.err != nil {
+        return 0, c.err
+    }
+    return 0, io.EOF
+}
+
+// Write the data as a single data frame. It's written immediately, unless the
+// connection is being re-established, in which case it's queued after the
+// frames that are re-sent. Write errors of the underlying connection are
+// ignored, as the frame is re-sent once it's re-established.
+func (c *ackedConn) Write(b []byte) (int, error) {
+    // wait for the acknowledgment of the previous frames before the writes are
+    // serialized, as the control frames are written meanwhile
+    c.l.Lock()
+    for c.unackedBytes >= c.bufferSize && !c.closing && !c.eof && c.err == nil {
+        c.cond.Wait()
+    }
+    c.l.Unlock()
+
+    c.wl.Lock()
+    defer c.wl.Unlock()
+
+    c.l.Lock()
+    if c.closing {
+        c.l.Unlock()
+        return 0, net.ErrClosed
+    } else if c.err != nil {
+        c.l.Unlock()
+        return 0, c.err
+    } else if c.eof {
+        c.l.Unlock()
+        return 0, io.ErrClosedPipe
+    }
+
+    c.sent++
+    f := frame{frameData, c.sent, append([]byte{}, b...)}
+    c.unacked = append(c.unacked, f)
+    c.unackedBytes += len(f.Payload)
+    conn, queued := c.conn, c.queued
+    c.l.Unlock()
+
+    if !queued && writeFrame(conn, f) != nil {
+        conn.Close() // the reader re-establishes the connection
+    }
+    return len(b), nil
+}
+
+// Close the connection once all of the written frames are acknowledged, or
+// the other end has closed as well
+func (c *ackedConn) Close() error {
+    c.l.Lock()
+    if c.closing {
+        c.l.Unlock()
+        return nil
+    }
+
+    c.closing = true
+    timer := time.AfterFunc(ackCloseTimeout, func() {
+        c.l.Lock()
+        c.eof = true // give up on the other end
+        c.cond.Broadcast()
+        c.l.Unlock()
+    })
+
+    for len(c.unacked) > 0 && !c.eof && c.err == nil {
+        c.cond.Wait()
+    }
+
+    timer.Stop()
+    c.closed = true
+    c.cond.Broadcast()
+    c.l.Unlock()
+
+    <- c.done
+    c.l.Lock()
+    defer c.l.Unlock()
+    return c.conn.Close()
+}
+
+// receive the frames of the other end until it closes, while re-establishing
+// the connection when it breaks
+func (c *ackedConn) receive() {
+    c.l.Lock()
+    conn := c.conn
+    c.l.Unlock()
+
+    for {
+        // wait for the received data to be read, see ackBufferSize
+        c.l.Lock()
+        for c.buf.Len() >= c.bufferSize && !c.closing && c.err == nil {
+            c.cond.Wait()
+        }
+        c.l.Unlock()
+
+        f, err := readFrame(conn, c.limit)
+        if invalid, ok := err.(frameError); ok {
+            c.fail(conn, invalid.error)
+            return
+        } else if err != nil {
+            conn, err = c.reconnect(conn)
+            if conn == nil {
+                return
+            }
+            continue
+        }
+
+        c.l.Lock()
+        switch f.Kind {
+        case frameData:
+            if f.Seq == c.received + 1 {
+                c.received = f.Seq
+                c.buf.Write(f.Payload)
+            } // otherwise, it's a duplicate of a re-sent frame
+        case frameAck:
+            c.ack(f.Seq)
+        case frameResume:
+            // the other end has reconnected. Re-send the frames that follow
+            // the last one it has received, after answering with a resume of
+            // our own, in case this end didn't notice the broken connection
+            c.ack(f.Seq)
+            c.resent = f.Seq
+            c.resending, c.queued = true, true
+            c.resume = c.resume || !c.resumed
+        case frameClose:
+            c.eof = true
+        }
+
+        c.cond.Broadcast()
+        eof := c.eof
+        c.l.Unlock()
+        if eof {
+            return
+        }
+    }
+}
+
+// ack drops the frames up to the sequence number, which were received by the
+// other end
+func (c *ackedConn) ack(seq uint64) {
+    i := 0
+    for i < len(c.unacked) && c.unacked[i].Seq <= seq {
+        c.unackedBytes -= len(c.unacked[i].Payload)
+        i++
+    }
+    c.unacked = c.unacked[i:]
+}
+
+// fail the connection with the error of an invalid frame
+func (c *ackedConn) fail(conn net.Conn, err error) {
+    conn.Close()
+    c.l.Lock()
+    defer c.l.Unlock()
+    c.err = err
+    c.cond.Broadcast()
+}
+
+// reconnect replaces the broken connection with a new one, unless it was
+// closed by either end. Returns nil if it was closed or failed to reconnect
+func (c *ackedConn) reconnect(old net.Conn) (net.Conn, error) {
+    old.Close()
+
+    c.l.Lock()
+    stop := c.closed || c.eof
+    c.queued = true
+    c.l.Unlock()
+    if stop {
+        return nil, nil
+    }
+
+    var conn net.Conn
+    var err error
+    for i := 0; i < AckRedials; i++ {
+        conn, err = c.redial()
+        if err == nil {
+            break
+        }
+    }
+
+    c.l.Lock()
+    defer c.l.Unlock()
+    defer c.cond.Broadcast()
+    if err != nil {
+        c.err = err
+        return nil, err
+    } else if c.closed {
+        conn.Close()
+        return nil, nil
+    }
+
+    c.conn, c.resume, c.resumed = conn, true, false
+    return conn, nil
+}
+
+// control writes the control frames, and re-sends the unacked frames after
+// reconnecting, until the connection is closed
+func (c *ackedConn) control() {
+    defer close(c.done)
+
+    c.l.Lock()
+    for {
+        for !c.resume && !c.resending && c.received == c.acked && !c.closed && c.err == nil {
+            c.cond.Wait()
+        }
+
+        conn := c.conn
+        frames := []frame{}
+        switch {
+        case c.err != nil:
+            c.l.Unlock()
+            return
+        case c.resume:
+            c.resume, c.resumed, c.acked = false, true, c.received
+            frames = append(frames, frame{frameResume, c.received, nil})
+        case c.resending:
+            for _, f := range c.unacked {
+                if f.Seq > c.resent {
+                    frames = append(frames, f)
+                }
+            }
+
+            if len(frames) == 0 {
+                c.resending, c.queued = false, false
+                continue
+            }
+            c.resent = frames[len(frames) - 1].Seq
+        case c.received > c.acked:
+            c.acked = c.received
+            frames = append(frames, frame{frameAck, c.received, nil})
+        case c.closed:
+            c.l.Unlock()
+            c.wl.Lock()
+            writeFrame(conn, frame{frameClose, 0, nil})
+            c.wl.Unlock()
+            return
+        }
+        c.l.Unlock()
+
+        // write errors are detected by the reader, which reconnects
+        c.wl.Lock()
+        for _, f := range frames {
+            if writeFrame(conn, f) != nil {
+                break
+            }
+        }
+        c.wl.Unlock()
+        c.l.Lock()
+    }
+}
+
+func (c *ackedConn) current() net.Conn {
+    c.l.Lock()
+    defer c.l.Unlock()
+    return c.conn
+}
+
+func (c *ackedConn) LocalAddr() net.Addr { return c.current().LocalAddr() }
+func (c *ackedConn) RemoteAddr() net.Addr { return c.current().RemoteAddr() }
+func (c *ackedConn) SetDeadline(t time.Time) error { return c.current().SetDeadline(t) }
+func (c *ackedConn) SetReadDeadline(t time.Time) error { return c.current().SetReadDeadline(t) }
+func (c *ackedConn) SetWriteDeadline(t time.Time) error { return c.current().SetWriteDeadline(t) }
@@ -0,0 +1,219 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "net"
+    "sort"
+    "sync"
+    "time"
+    "bytes"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// flakyDialer breaks the first data connection it dials after the provided
+// number of writes
+type flakyDialer struct {
+    net.Listener
+    Writes int
+    l sync.Mutex
+    broken bool
+}
+
+func (d *flakyDialer) Dial(network, addr string) (net.Conn, error) {
+    conn, err := d.Listener.(dialer).Dial(network, addr)
+    return &flakyConn{Conn: conn, d: d}, err
+}
+
+func (d *flakyDialer) isBroken() bool {
+    d.l.Lock()
+    defer d.l.Unlock()
+    return d.broken
+}
+
+type flakyConn struct {
+    net.Conn
+    d *flakyDialer
+    writes int
+    data bool
+}
+
+func (c *flakyConn) Write(b []byte) (int, error) {
+    c.writes++
+    if c.writes == 1 {
+        typee, _, err := readHandshake(bytes.NewReader(b))
+        c.data = err == nil && typee == connData
+    }
+
+    c.d.l.Lock()
+    breaks := c.data && !c.d.broken && c.writes > c.d.Writes
+    c.d.broken = c.d.broken || breaks
+    c.d.l.Unlock()
+
+    if breaks {
+        c.Conn.Close()
+        return 0, fmt.Errorf("connection reset by peer")
+    }
+    return c.Conn.Write(b)
+}
+
+// pipePair returns both ends of an in-memory connection
+func pipePair(ln net.Listener) (net.Conn, net.Conn) {
+    server := make(chan net.Conn, 1)
+    go func() {
+        conn, _ := ln.Accept()
+        server <- conn
+    }()
+
+    client, _ := ln.(dialer).Dial("pipe", ln.Addr().String())
+    return client, <- server
+}
+
+func TestAckedConn(t *testing.T) {
+    ln, err := NewPipes().Listen(":5551")
+    require.NoError(t, err)
+    defer ln.Close()
+
+    // the replacement connection of both ends, once the first one breaks
+    a1, b1 := pipePair(ln)
+    a2, b2 := make(chan net.Conn, 1), make(chan net.Conn, 1)
+    go func() {
+        a, b := pipePair(ln)
+        a2 <- a
+        b2 <- b
+    }()
+
+    a := newAckedConn(a1, func() (net.Conn, error) { return <- a2, nil })
+    b := newAckedConn(b1, func() (net.Conn, error) { return <- b2, nil })
+
+    _, err = a.Write([]byte("hello "))
+    require.NoError(t, err)
+
+    res := make([]byte, 6)
+    _, err = io.ReadFull(b, res)
+    require.NoError(t, err)
+    require.Equal(t, "hello ", string(res))
+
+    // break the connection, the writes are re-sent exactly once
+    a1.Close()
+    _, err = a.Write([]byte("world"))
+    require.NoError(t, err)
+    _, err = b.Write([]byte("back"))
+    require.NoError(t, err)
+
+    res = make([]byte, 5)
+    _, err = io.ReadFull(b, res)
+    require.NoError(t, err)
+    require.Equal(t, "world", string(res))
+
+    res = make([]byte, 4)
+    _, err = io.ReadFull(a, res)
+    require.NoError(t, err)
+    require.Equal(t, "back", string(res))
+
+    require.NoError(t, a.Close())
+    _, err = b.Read(res)
+    require.Equal(t, io.EOF, err)
+    require.NoError(t, b.Close())
+}
+
+// Tests that the unread data of the receiver and the unacknowledged data of
+// the writer are bounded
+func TestAckedConnBuffers(t *testing.T) {
+    defer func(size int) { ackBufferSize = size }(ackBufferSize)
+    ackBufferSize = 4000
+
+    ln, err := NewPipes().Listen(":5551")
+    require.NoError(t, err)
+    defer ln.Close()
+
+    a1, b1 := pipePair(ln)
+    a := newAckedConn(a1, nil)
+    b := newAckedConn(b1, nil)
+
+    written := make(chan int, 100)
+    go func() {
+        defer close(written)
+        for i := 0; i < 100; i++ {
+            _, err := a.Write(make([]byte, 1000))
+            if err != nil {
+                return
+            }
+            written <- i
+        }
+    }()
+
+    // the writer waits, while nothing is read
+    time.Sleep(50 * time.Millisecond)
+    require.True(t, len(written) < 100)
+    b.l.Lock()
+    require.True(t, b.buf.Len() <= ackBufferSize + 1000)
+    b.l.Unlock()
+
+    res := make([]byte, 100 * 1000)
+    _, err = io.ReadFull(b, res)
+    require.NoError(t, err)
+    for _ = range written {}
+
+    require.NoError(t, a.Close())
+    require.NoError(t, b.Close())
+}
+
+func TestReadFrameMaxSize(t *testing.T) {
+    limit := sizeLimit{"MaxMessageSize", 1000}
+    var buf bytes.Buffer
+    require.NoError(t, writeFrame(&buf, frame{frameData, 1, make([]byte, 1001)}))
+    _, err := readFrame(&buf, limit)
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "exceeds the maximum size of 1000")
+
+    // a huge size isn't allocated
+    b := []byte{frameData, 0, 0, 0, 0, 0, 0, 0, 1, 0xFF, 0xFF, 0xFF, 0xFF}
+    _, err = readFrame(bytes.NewReader(b), limit)
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "exceeds the maximum size of 1000")
+}
+
+// Tests that all of the rows are delivered exactly once, when a connection
+// between the nodes breaks in the middle of the exchange
+func TestWithAcksDistributed(t *testing.T) {
+    pipes := NewPipes()
+    ln1, err := pipes.Listen(":5551")
+    require.NoError(t, err)
+
+    dialer := &flakyDialer{Listener: ln1, Writes: 10}
+    dist1 := NewDistributer(":5551", dialer)
+    defer dist1.Close()
+    go dist1.Start()
+
+    ln2, err := pipes.Listen(":5552")
+    require.NoError(t, err)
+
+    dist2 := NewDistributer(":5552", ln2)
+    defer dist2.Close()
+    go dist2.Start()
+
+    inp := []Dataset{}
+    expected := []string{}
+    for i := 0; i < 50; i++ {
+        inp = append(inp, NewDataset(Strs{fmt.Sprint(i)}))
+        expected = append(expected, fmt.Sprint(i))
+    }
+    sort.Strings(expected)
+
+    runner := WithAcks(Pipeline(Scatter(), Gather()))
+    runner = dist1.Distribute(runner, ":5551", ":5552")
+    data, err := testRun(runner, inp...)
+    require.NoError(t, err)
+    require.True(t, dialer.isBroken())
+
+    res := data.At(0).Strings()
+    sort.Strings(res)
+    require.Equal(t, expected, res)
+}
+
+func TestWithAcksExplain(t *testing.T) {
+    runner := WithAcks(Pick(0))
+    require.Equal(t, "WithAcks", Explain(runner).Kind)
+}
@@ -0,0 +1,193 @@
+package ep
+
+import (
+    "strconv"
+)
+
+var _ = registerGob(&count{}, &sum{}, &minmax{}, &avg{}, &avgState{})
+
+// Aggregator computes a single value out of a group of rows. Aggregation is
+// performed in two phases: first, partial states are computed on every node
+// for the rows that are local to it, and then these states are exchanged and
+// merged into the final result. See GroupBy.
+//
+// Implement it for user-defined aggregate functions. The states are arbitrary
+// values, but since they're transmitted between the nodes, they must be
+// serializable with gob, and their concrete types must be registered. Using
+// `Aggregators.Register()` registers both the Aggregator and the type of its
+// initial state. States may be shared between groups and phases, thus Add and
+// Merge should return a new state rather than modifying it in-place.
+type Aggregator interface {
+
+    // Returns the type of the aggregated value
+    Returns() Type
+
+    // InitState returns a new state of an empty group
+    InitState() interface{}
+
+    // Add a batch of rows of a single group to the state, and return the
+    // updated state. The batch contains all of the input columns.
+    Add(state interface{}, data Dataset) (interface{}, error)
+
+    // Merge another partial state into the state, and return the merged state
+    Merge(state, other interface{}) (interface{}, error)
+
+    // Finalize computes the aggregated value out of the state. The returned
+    // Data must contain a single value
+    Finalize(state interface{}) (Data, error)
+}
+
+// Count returns an Aggregator that counts the number of rows in the group
+func Count() Aggregator { return &count{} }
+
+// Sum returns an Aggregator that sums the numeric values of a column
+func Sum(col int) Aggregator { return &sum{col} }
+
+// Avg returns an Aggregator that averages the numeric values of a column
+func Avg(col int) Aggregator { return &avg{col} }
+
+// Min returns an Aggregator that computes the minimum value of a column, as
+// determined by the column's Data.Less()
+func Min(col int) Aggregator { return &minmax{col, false} }
+
+// Max returns an Aggregator that computes the maximum value of a column, as
+// determined by the column's Data.Less()
+func Max(col int) Aggregator { return &minmax{col, true} }
+
+type count struct {}
+func (*count) Returns() Type { return Int }
+func (*count) InitState() interface{} { return int64(0) }
+func (*count) Add(state interface{}, data Dataset) (interface{}, error) {
+    return state.(int64) + int64(data.Len()), nil
+}
+
+func (*count) Merge(state, other interface{}) (interface{}, error) {
+    return state.(int64) + other.(int64), nil
+}
+
+func (*count) Finalize(state interface{}) (Data, error) {
+    return Ints{state.(int64)}, nil
+}
+
+type sum struct { Col int }
+func (*sum) Returns() Type { return Float }
+func (*sum) InitState() interface{} { return float64(0) }
+func (agg *sum) Add(state interface{}, data Dataset) (interface{}, error) {
+    v, _, err := sumColumn(data.At(agg.Col))
+    return state.(float64) + v, err
+}
+
+func (*sum) Merge(state, other interface{}) (interface{}, error) {
+    return state.(float64) + other.(float64), nil
+}
+
+func (*sum) Finalize(state interface{}) (Data, error) {
+    return Floats{state.(float64)}, nil
+}
+
+type avg struct { Col int }
+type avgState struct { Sum float64; Count int64 }
+func (*avg) Returns() Type { return Float }
+func (*avg) InitState() interface{} { return &avgState{} }
+func (agg *avg) Add(state interface{}, data Dataset) (interface{}, error) {
+    v, n, err := sumColumn(data.At(agg.Col))
+    s := state.(*avgState)
+    return &avgState{s.Sum + v, s.Count + n}, err
+}
+
+func (*avg) Merge(state, other interface{}) (interface{}, error) {
+    s, o := state.(*avgState), other.(*avgState)
+    return &avgState{s.Sum + o.Sum, s.Count + o.Count}, nil
+}
+
+func (*avg) Finalize(state interface{}) (Data, error) {
+    s := state.(*avgState)
+    if s.Count == 0 {
+        return Null.Data(1), nil
+    }
+
+    return Floats{s.Sum / float64(s.Count)}, nil
+}
+
+// minmax state is a single-value Data of the minimum (or maximum) value found,
+// or nil for empty groups
+type minmax struct { Col int; IsMax bool }
+func (*minmax) Returns() Type { return Any }
+func (*minmax) InitState() interface{} { return nil }
+func (agg *minmax) Add(state interface{}, data Dataset) (interface{}, error) {
+    col := nonNulls(data.At(agg.Col))
+    if col.Len() == 0 || col.Type() == Null {
+        return state, nil
+    }
+
+    best := 0
+    for i := 1; i < col.Len(); i++ {
+        if agg.better(col, i, best) {
+            best = i
+        }
+    }
+
+    return agg.Merge(state, Clone(col.Slice(best, best + 1)))
+}
+
+func (agg *minmax) Merge(state, other interface{}) (interface{}, error) {
+    if state == nil {
+        return other, nil
+    } else if other == nil {
+        return state, nil
+    }
+
+    both := Clone(state.(Data)).Append(other.(Data))
+    if agg.better(both, 1, 0) {
+        return other, nil
+    }
+    return state, nil
+}
+
+func (*minmax) Finalize(state interface{}) (Data, error) {
+    if state == nil {
+        return Null.Data(1), nil
+    }
+    return state.(Data), nil
+}
+
+// returns true if the value at index i is a better candidate than the value at
+// index j
+func (agg *minmax) better(data Data, i, j int) bool {
+    if agg.IsMax {
+        return data.Less(j, i)
+    }
+    return data.Less(i, j)
+}
+
+// sum the numeric values of the column, and also return the number of values
+// summed. Nulls are skipped. Numeric columns are summed natively, while other
+// columns are parsed from their string representation.
+func sumColumn(data Data) (float64, int64, error) {
+    var res float64
+    switch vs := nonNulls(data).(type) {
+    case nulls:
+        return 0, 0, nil
+    case Ints:
+        for _, v := range vs {
+            res += float64(v)
+        }
+        return res, int64(len(vs)), nil
+    case Floats:
+        for _, v := range vs {
+            res += v
+        }
+        return res, int64(len(vs)), nil
+    }
+
+    data = nonNulls(data)
+    for _, s := range data.Strings() {
+        v, err := strconv.ParseFloat(s, 64)
+        if err != nil {
+            return 0, 0, err
+        }
+
+        res += v
+    }
+    return res, int64(data.Len()), nil
+}
@@ -0,0 +1,72 @@
+package ep
+
+import (
+    "fmt"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = Aggregators.Register("COUNT_DISTINCT", &countDistinct{})
+
+// countDistinct is an example user-defined Aggregator that counts the distinct
+// values of a column. Its state is the set of values seen.
+type countDistinct struct { Col int }
+func (*countDistinct) Returns() Type { return Str }
+func (*countDistinct) InitState() interface{} { return map[string]bool{} }
+func (agg *countDistinct) Add(state interface{}, data Dataset) (interface{}, error) {
+    res := map[string]bool{}
+    for k := range state.(map[string]bool) {
+        res[k] = true
+    }
+
+    for _, k := range data.At(agg.Col).Strings() {
+        res[k] = true
+    }
+    return res, nil
+}
+
+func (agg *countDistinct) Merge(state, other interface{}) (interface{}, error) {
+    res := map[string]bool{}
+    for _, m := range []interface{}{state, other} {
+        for k := range m.(map[string]bool) {
+            res[k] = true
+        }
+    }
+    return res, nil
+}
+
+func (*countDistinct) Finalize(state interface{}) (Data, error) {
+    n := len(state.(map[string]bool))
+    return Strs{strconv.Itoa(n)}, nil
+}
+
+func ExampleAggregator() {
+    keys := Strs{"a", "b", "a", "a"}
+    values := Strs{"x", "y", "z", "x"}
+    runner := GroupBy([]int{0}, &countDistinct{1}, Count())
+    data, err := testRun(runner, NewDataset(keys, values))
+    fmt.Println(data, err)
+
+    // Output: [[a b] [2 1] [3 1]] <nil>
+}
+
+func TestAggregatorsRegistry(t *testing.T) {
+    aggs := Aggregators.Get("COUNT_DISTINCT")
+    require.Equal(t, 1, len(aggs))
+    require.Equal(t, Str, aggs[0].Returns())
+}
+
+func TestMinMax(t *testing.T) {
+    runner := GroupBy(nil, Min(0), Max(0))
+    data1 := NewDataset(Strs{"b", "c"})
+    data2 := NewDataset(Strs{"a", "d", "b"})
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, "[[a] [d]]", fmt.Sprintf("%v", data))
+
+    // empty groups produce nulls
+    data, err = testRun(runner)
+    require.NoError(t, err)
+    require.Equal(t, Null, data.At(0).Type())
+}
@@ -0,0 +1,288 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "sync"
+    "time"
+    "context"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&analysis{}, &analyzeHooks{})
+
+// RunStats are the runtime metrics of a single runner on a single node, as
+// recorded by Analyze
+type RunStats struct {
+    Node string // the node that ran the runner, or empty if not distributed
+    RowsIn int
+    RowsOut int
+    Duration time.Duration // wall time while the runner was running
+
+    // PeakBytes is the estimated size of the largest dataset the runner has
+    // received or emitted
+    PeakBytes int
+}
+
+// Analyze returns a Runner that runs the plan in analyze mode, along with the
+// explained plan (see Explain). Every runner of the plan is wrapped with Hooks
+// (see Wrap) that record its RunStats on every node. Once the plan completes,
+// the stats of all nodes are gathered into the master node, and attached to
+// the explained plan. Thus, the stats are only available after the returned
+// Runner has completed successfully
+func Analyze(r Runner) (Runner, *PlanNode) {
+    plan := Explain(r)
+    a := &analysis{UID: uuid.NewV4().String()}
+
+    // the analysis must run on all nodes, thus within the distribution
+    if dist, ok := r.(*distRunner); ok {
+        a.Runner = instrument(dist.Runner, plan.Children[0], &a.nodes)
+        return dist.withInner([]Runner{a}), plan
+    }
+
+    a.Runner = instrument(r, plan, &a.nodes)
+    return a, plan
+}
+
+// instrument returns a copy of the runner, where it and all of its inner
+// runners are wrapped with analyzeHooks by their index in nodes. See Analyze
+func instrument(r Runner, n *PlanNode, nodes *[]*PlanNode) Runner {
+    id := len(*nodes)
+    *nodes = append(*nodes, n)
+
+    children := append([]Runner{}, planChildren(r)...)
+    if len(children) > 0 {
+        for i, child := range children {
+            children[i] = instrument(child, n.Children[i], nodes)
+        }
+        r = withPlanChildren(r, children)
+    }
+    return Wrap(r, &analyzeHooks{id})
+}
+
+// analysis runs the instrumented plan with a collector of its RunStats, and
+// then gathers the stats of all nodes into the master node
+type analysis struct {
+    UID string
+    Runner Runner
+    nodes []*PlanNode // the explained plan nodes by id, only on the master
+}
+
+func (a *analysis) Returns() []Type { return a.Runner.Returns() }
+func (a *analysis) returnsFrom(inp []Type) []Type {
+    return returnsFrom(a.Runner, inp)
+}
+
+func (a *analysis) Run(ctx context.Context, inp, out chan Dataset) error {
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    c := &collector{node: thisNode, stats: map[int]*analyzed{}}
+    err := a.Runner.Run(context.WithValue(ctx, "ep.Analysis", c), inp, out)
+    if err != nil {
+        return err
+    }
+
+    stats := c.dataset()
+    if ctx.Value("ep.AllNodes") != nil {
+        inp := make(chan Dataset, 1)
+        inp <- stats
+        close(inp)
+
+        ex := &exchange{UID: a.UID + ":analyze", SendTo: sendGather}
+        stats, err = collect(ctx, ex, inp)
+        if err != nil {
+            return err
+        }
+    }
+
+    if a.nodes != nil && stats != nil {
+        a.attach(stats)
+    }
+    return nil
+}
+
+// attach the gathered stats to the explained plan nodes, sorted by node
+func (a *analysis) attach(stats Dataset) {
+    for _, n := range a.nodes {
+        n.Stats = nil
+    }
+
+    ids := stats.At(0).(Ints)
+    nodes := stats.At(1).(Strs)
+    values := [4]Ints{}
+    for i := range values {
+        values[i] = stats.At(i + 2).(Ints)
+    }
+
+    for i := range ids {
+        n := a.nodes[ids[i]]
+        n.Stats = append(n.Stats, RunStats{
+            Node: nodes[i],
+            RowsIn: int(values[0][i]),
+            RowsOut: int(values[1][i]),
+            Duration: time.Duration(values[2][i]),
+            PeakBytes: int(values[3][i]),
+        })
+    }
+
+    for _, n := range a.nodes {
+        sort.Slice(n.Stats, func(i, j int) bool {
+            return n.Stats[i].Node < n.Stats[j].Node
+        })
+    }
+}
+
+// collector of the RunStats of the runners of a plan on a single node, by the
+// ids of the runners
+type collector struct {
+    sync.Mutex
+    node string
+    stats map[int]*analyzed
+}
+
+// analyzed are the stats of a single runner, including the state required for
+// measuring its wall time while it's running concurrently more than once
+type analyzed struct {
+    RunStats
+    running int
+    since time.Time
+}
+
+func (c *collector) get(id int) *analyzed {
+    s := c.stats[id]
+    if s == nil {
+        s = &analyzed{RunStats: RunStats{Node: c.node}}
+        c.stats[id] = s
+    }
+    return s
+}
+
+// dataset returns the collected stats, a row per runner
+func (c *collector) dataset() Dataset {
+    c.Lock()
+    defer c.Unlock()
+
+    ids := make(Ints, 0, len(c.stats))
+    for id := range c.stats {
+        ids = append(ids, int64(id))
+    }
+    sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+    nodes, rowsIn, rowsOut, durations, peaks := Strs{}, Ints{}, Ints{}, Ints{}, Ints{}
+    for _, id := range ids {
+        s := c.stats[int(id)]
+        nodes = append(nodes, s.Node)
+        rowsIn = append(rowsIn, int64(s.RowsIn))
+        rowsOut = append(rowsOut, int64(s.RowsOut))
+        durations = append(durations, int64(s.Duration))
+        peaks = append(peaks, int64(s.PeakBytes))
+    }
+
+    return newNamedDataset(
+        []string{"id", "node", "rows_in", "rows_out", "duration", "peak_bytes"},
+        ids, nodes, rowsIn, rowsOut, durations, peaks,
+    )
+}
+
+// analyzeHooks record the RunStats of the runner with the id into the
+// collector of the context, if any
+type analyzeHooks struct { ID int }
+
+func (h *analyzeHooks) update(ctx context.Context, fn func(s *analyzed)) {
+    c, ok := ctx.Value("ep.Analysis").(*collector)
+    if !ok {
+        return
+    }
+
+    c.Lock()
+    defer c.Unlock()
+    fn(c.get(h.ID))
+}
+
+func (h *analyzeHooks) Start(ctx context.Context) {
+    h.update(ctx, func(s *analyzed) {
+        if s.running == 0 {
+            s.since = time.Now()
+        }
+        s.running++
+    })
+}
+
+func (h *analyzeHooks) Input(ctx context.Context, data Dataset) {
+    size := estimateBytes(data)
+    h.update(ctx, func(s *analyzed) {
+        s.RowsIn += data.Len()
+        if size > s.PeakBytes {
+            s.PeakBytes = size
+        }
+    })
+}
+
+func (h *analyzeHooks) Output(ctx context.Context, data Dataset) {
+    size := estimateBytes(data)
+    h.update(ctx, func(s *analyzed) {
+        s.RowsOut += data.Len()
+        if size > s.PeakBytes {
+            s.PeakBytes = size
+        }
+    })
+}
+
+func (h *analyzeHooks) End(ctx context.Context, err error) {
+    h.update(ctx, func(s *analyzed) {
+        s.running--
+        if s.running == 0 {
+            s.Duration += time.Since(s.since)
+        }
+    })
+}
+
+// estimateBytes estimates the size of the values of the data, by the widths of
+// fixed width types, or the lengths of the string values otherwise
+func estimateBytes(data Data) int {
+    if set, ok := data.(Dataset); ok {
+        size := 0
+        for i := 0; i < set.Width(); i++ {
+            size += estimateBytes(set.At(i))
+        }
+        return size
+    }
+
+    if width := Meta(data.Type()).Size(); width > 0 {
+        return width * data.Len()
+    }
+
+    size := 0
+    if strs, ok := data.(Strs); ok {
+        for _, s := range strs {
+            size += len(s)
+        }
+        return size
+    }
+
+    for _, s := range data.Strings() {
+        size += len(s)
+    }
+    return size
+}
+
+// total returns the stats of all nodes combined: the sum of the rows, and the
+// maximum duration and peak bytes
+func total(stats []RunStats) RunStats {
+    var res RunStats
+    for _, s := range stats {
+        res.RowsIn += s.RowsIn
+        res.RowsOut += s.RowsOut
+        if s.Duration > res.Duration {
+            res.Duration = s.Duration
+        }
+
+        if s.PeakBytes > res.PeakBytes {
+            res.PeakBytes = s.PeakBytes
+        }
+    }
+    return res
+}
+
+func (s RunStats) String() string {
+    return fmt.Sprintf("rows=%d->%d time=%s peak=%dB", s.RowsIn, s.RowsOut, s.Duration, s.PeakBytes)
+}
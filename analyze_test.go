@@ -0,0 +1,78 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleAnalyze() {
+    runner := Pipeline(Filter(Where(0, "!=", "b")), Pick(0))
+    runner, plan := Analyze(runner)
+
+    data, err := testRun(runner, NewDataset(Strs{"a", "b", "c"}))
+    fmt.Println(data, err)
+
+    for _, n := range plan.Children {
+        s := n.Stats[0]
+        fmt.Println(n.Kind, s.RowsIn, s.RowsOut, s.PeakBytes)
+    }
+
+    // Output:
+    // [[a c]] <nil>
+    // Filter 3 2 3
+    // Pick 2 2 2
+}
+
+// Test that the stats of all nodes are gathered into the master node
+func TestAnalyzeDistributed(t *testing.T) {
+    dists := []Distributer{}
+    addrs := []string{}
+    for i := 0; i < 2; i++ {
+        ln, err := net.Listen("tcp", "127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(ln.Addr().String(), ln)
+        go dist.Start()
+        defer dist.Close()
+
+        dists = append(dists, dist)
+        addrs = append(addrs, dist.Addr())
+    }
+
+    scan := &scanData{NewDataset(Ints{1, 2, 3, 4, 5})}
+    runner := Pipeline(scan, Filter(Where(0, "!=", "3")), Gather())
+    runner, plan := Analyze(dists[0].Distribute(runner, addrs...))
+
+    data, err := testRun(runner)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+
+    require.Nil(t, plan.Stats)
+    pipeline := plan.Children[0]
+    require.Equal(t, 2, len(pipeline.Stats))
+
+    filter := pipeline.Children[1]
+    require.Equal(t, "Filter", filter.Kind)
+    require.Equal(t, 2, len(filter.Stats))
+    require.NotEqual(t, filter.Stats[0].Node, filter.Stats[1].Node)
+    require.Equal(t, 5, filter.Stats[0].RowsIn + filter.Stats[1].RowsIn)
+    require.Equal(t, 4, filter.Stats[0].RowsOut + filter.Stats[1].RowsOut)
+
+    gather := pipeline.Children[2]
+    require.Equal(t, 4, total(gather.Stats).RowsOut)
+    require.True(t, strings.Contains(plan.String(), "rows=4->4"), plan.String())
+}
+
+func TestAnalyzeErr(t *testing.T) {
+    runner := Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+        return nil, fmt.Errorf("bad map")
+    })
+
+    runner, plan := Analyze(runner)
+    _, err := testRun(runner, NewDataset(Strs{"a"}))
+    require.EqualError(t, err, "bad map")
+    require.Nil(t, plan.Stats)
+}
@@ -0,0 +1,71 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&rebatch{})
+
+// BatchSize is the default number of rows per batch. It's used by the runners
+// that produce their own batches (like the merged output of Sort), by Rebatch,
+// and as the maximum number of rows per message sent by exchanges, where
+// larger datasets are sent in several batches. It's a per-process setting.
+var BatchSize = 1024
+
+// Rebatch returns a Runner that re-slices its input into batches of exactly
+// `rows` rows, except for the last batch which might be smaller, or BatchSize
+// rows if rows isn't positive. Large datasets are sliced without copying, and
+// small ones are combined. Useful for adjusting the batch size between stages
+// that want different batch sizes, like large batches for scans and small
+// batches for user functions.
+func Rebatch(rows int) Runner {
+    return &rebatch{rows}
+}
+
+type rebatch struct { Rows int }
+func (*rebatch) Returns() []Type { return []Type{Wildcard} }
+func (r *rebatch) Run(ctx context.Context, inp, out chan Dataset) error {
+    size := r.Rows
+    if size <= 0 {
+        size = BatchSize
+    }
+
+    var buff Dataset // accumulated rows of smaller batches
+    for data := range inp {
+        if data.Len() == 0 {
+            continue
+        }
+
+        // complete the accumulated batch
+        if buff != nil {
+            n := size - buff.Len()
+            if n > data.Len() {
+                n = data.Len()
+            }
+
+            buff = appendClone(buff, Slice(data, 0, n))
+            data = Slice(data, n, data.Len())
+            if buff.Len() < size {
+                continue
+            }
+
+            out <- buff
+            buff = nil
+        }
+
+        // emit whole batches as-is, and accumulate the rest
+        for data.Len() >= size {
+            out <- Slice(data, 0, size)
+            data = Slice(data, size, data.Len())
+        }
+
+        if data.Len() > 0 {
+            buff = Clone(data).(Dataset)
+        }
+    }
+
+    if buff != nil {
+        out <- buff
+    }
+    return nil
+}
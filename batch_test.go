@@ -0,0 +1,73 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleRebatch() {
+    data1 := NewDataset(Strs{"a", "b", "c", "d", "e"})
+    data2 := NewDataset(Strs{"f"})
+    data, err := testRun(Rebatch(2), data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[a b c d e f]] <nil>
+}
+
+// batchLens runs the runner, and returns the lengths of its output batches
+func batchLens(r Runner, datasets ...Dataset) []int {
+    inp := make(chan Dataset, len(datasets))
+    for _, data := range datasets {
+        inp <- data
+    }
+    close(inp)
+
+    out := make(chan Dataset)
+    go func() {
+        r.Run(context.Background(), inp, out)
+        close(out)
+    }()
+
+    lens := []int{}
+    for data := range out {
+        lens = append(lens, data.Len())
+    }
+    return lens
+}
+
+func TestRebatch(t *testing.T) {
+    data := func(n int) Dataset { return NewDataset(make(Strs, n)) }
+    require.Equal(t, []int{2, 2, 2}, batchLens(Rebatch(2), data(5), data(1)))
+    require.Equal(t, []int{3, 3, 1}, batchLens(Rebatch(3), data(1), data(1), data(0), data(4), data(1)))
+    require.Equal(t, []int{}, batchLens(Rebatch(3), data(0)))
+
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 4
+    require.Equal(t, []int{4, 1}, batchLens(Rebatch(0), data(5)))
+}
+
+// Tests that the accumulated batches don't share memory with the input
+func TestRebatchCopy(t *testing.T) {
+    strs := Strs{"a", "b"}
+    res, err := testRun(Rebatch(3), NewDataset(strs), NewDataset(Strs{"c"}))
+    require.NoError(t, err)
+
+    strs[0] = "x"
+    require.Equal(t, "[[a b c]]", fmt.Sprint(res))
+}
+
+// Tests that exchanges send large datasets in batches of up to BatchSize rows
+func TestExchangeBatchSize(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 2
+
+    dists := testCluster(t, 2)
+
+    runner := dists[0].Distribute(Gather(), ":5551", ":5552")
+    lens := batchLens(runner, NewDataset(Strs{"a", "b", "c", "d", "e"}))
+    sort.Ints(lens)
+    require.Equal(t, []int{1, 2, 2}, lens)
+}
@@ -0,0 +1,46 @@
+package ep
+
+var _ = registerGob(Bool, Bools{})
+
+// Bool is a built-in Type representing boolean values. Use Bool.Data(n) to
+// create Data instances of `n` false values
+var Bool = &BoolType{}
+
+// BoolType is the Type of Bools
+type BoolType struct {}
+func (*BoolType) Name() string { return "bool" }
+func (*BoolType) Data(n uint) Data { return make(Bools, n) }
+
+// Bools is a built-in Data implementation of boolean values, where false is
+// ordered before true. It's also used as a mask for filtering the rows of
+// datasets (see Dataset.Filter)
+type Bools []bool
+func (Bools) Type() Type { return Bool }
+func (vs Bools) Len() int { return len(vs) }
+func (vs Bools) Less(i, j int) bool { return !vs[i] && vs[j] }
+func (vs Bools) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs Bools) Slice(s, e int) Data { return vs[s:e] }
+func (vs Bools) Append(o Data) Data { return append(vs, o.(Bools)...) }
+func (vs Bools) Strings() []string {
+    strs := make([]string, len(vs))
+    for i, v := range vs {
+        if v {
+            strs[i] = "true"
+        } else {
+            strs[i] = "false"
+        }
+    }
+    return strs
+}
+
+// Selection returns the selection vector of the mask: the indices of the true
+// values, in order
+func (vs Bools) Selection() []int {
+    rows := []int{}
+    for i, v := range vs {
+        if v {
+            rows = append(rows, i)
+        }
+    }
+    return rows
+}
@@ -0,0 +1,115 @@
+package ep
+
+import (
+    "sync"
+    "context"
+)
+
+var _ = registerGob(&branch{})
+
+// Branch returns a composite Runner that routes every row of its input to one
+// of two runners, based on the predicate: the matching rows are dispatched to
+// the `ifTrue` runner, and the rest to the `ifFalse` runner. The predicate is
+// tested once per row. The outputs of both runners are collected into a single
+// unified stream of datasets, thus like Union, both must return the same data
+// types.
+func Branch(pred Predicate, ifTrue, ifFalse Runner) (Runner, error) {
+    u, err := Union(ifTrue, ifFalse)
+    if err != nil {
+        return nil, err
+    }
+
+    return &branch{u.Returns(), pred, ifTrue, ifFalse}, nil
+}
+
+type branch struct {
+    Types []Type
+    Predicate Predicate
+    IfTrue Runner
+    IfFalse Runner
+}
+
+func (r *branch) Returns() []Type { return r.Types }
+func (r *branch) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    // cancel both runners upon the first error
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var l sync.Mutex
+    setErr := func(err1 error) {
+        l.Lock()
+        defer l.Unlock()
+        if err == nil {
+            err = err1
+        }
+        cancel()
+    }
+
+    runners := []Runner{r.IfTrue, r.IfFalse}
+    inputs := []chan Dataset{newChan(ctx), newChan(ctx)}
+    outputs := []chan Dataset{newChan(ctx), newChan(ctx)}
+    for i := range runners {
+        go func(i int) {
+            err1 := runSafe(ctx, runners[i], inputs[i], outputs[i])
+            close(outputs[i])
+            if err1 != nil {
+                setErr(err1)
+            }
+
+            // drain the input in case the runner has exited early, otherwise
+            // routing the input to the other runner below would block
+            for _ = range inputs[i] {}
+        }(i)
+    }
+
+    // route the rows to the runners
+    go func() {
+        defer close(inputs[0])
+        defer close(inputs[1])
+        for data := range inp {
+            if ctx.Err() != nil {
+                return
+            }
+
+            mask, err1 := r.Predicate.Test(data)
+            if err1 != nil {
+                setErr(err1)
+                return
+            }
+
+            res := []Dataset{keep(data, mask).(Dataset)}
+            for i := range mask {
+                mask[i] = !mask[i]
+            }
+            res = append(res, keep(data, mask).(Dataset))
+
+            for i, data := range res {
+                if data.Len() > 0 {
+                    inputs[i] <- data
+                }
+            }
+        }
+    }()
+
+    // collect the outputs of both runners, concurrently
+    for outputs[0] != nil || outputs[1] != nil {
+        select {
+        case data, ok := <- outputs[0]:
+            if !ok {
+                outputs[0] = nil
+                continue
+            }
+            out <- data
+        case data, ok := <- outputs[1]:
+            if !ok {
+                outputs[1] = nil
+                continue
+            }
+            out <- data
+        }
+    }
+
+    l.Lock()
+    defer l.Unlock()
+    return err
+}
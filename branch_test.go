@@ -0,0 +1,47 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// marked appends an exclamation mark to the strings of the first column
+var marked = Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+    res := Strs{}
+    for _, s := range data.At(0).Strings() {
+        res = append(res, s + "!")
+    }
+    return NewDataset(res), nil
+})
+
+// unmarked returns the first column as is
+var unmarked = Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+    return NewDataset(data.At(0)), nil
+})
+
+func TestBranch(t *testing.T) {
+    runner, err := Branch(Where(0, "<", "c"), marked, unmarked)
+    require.NoError(t, err)
+
+    data, err := testRun(runner, NewDataset(Strs{"a", "c", "b", "d"}), NewDataset(Strs{"e"}))
+    require.NoError(t, err)
+    require.ElementsMatch(t, []string{"a!", "b!", "c", "d", "e"}, data.At(0).Strings())
+}
+
+func TestBranchMismatch(t *testing.T) {
+    _, err := Branch(Where(0, "=", "a"), unmarked, &nodeAddr{})
+    require.Error(t, err)
+}
+
+func TestBranchErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    failed := Map([]Type{Str}, func(Dataset) (Dataset, error) { return nil, err })
+    runner, err1 := Branch(Where(0, "=", "a"), unmarked, failed)
+    require.NoError(t, err1)
+
+    _, err = testRun(runner, NewDataset(Strs{"a", "b"}))
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+}
@@ -0,0 +1,177 @@
+package ep
+
+import (
+    "strconv"
+    "context"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&distJoin{})
+
+// thresholds of distJoins that don't count the right rows at runtime
+const (
+    alwaysBroadcast = -1
+    alwaysPartition = -2
+)
+
+// BroadcastJoin returns a distributed Join that broadcasts the output of the
+// right runner to all nodes, and joins it locally with the output of the left
+// runner on each node. Unlike repartitioning both sides by their keys, the
+// (large) left side is never transmitted, thus it's preferable when the right
+// side is small. The right and full join types aren't supported, because the
+// unmatched right rows would be produced on all nodes. See Join.
+func BroadcastJoin(typ JoinType, leftKeys, rightKeys []int, left, right Runner) Runner {
+    if typ == RightJoin || typ == FullJoin {
+        panic("broadcast join doesn't support right and full joins")
+    }
+
+    return &distJoin{uuid.NewV4().String(), Join(typ, leftKeys, rightKeys, left, right).(*join), alwaysBroadcast}
+}
+
+// DistributedJoin returns a distributed Join that chooses the join strategy at
+// runtime: if the total number of rows produced by the right runner across all
+// nodes is at most `threshold`, the right side is broadcasted (see
+// BroadcastJoin). Otherwise, both sides are repartitioned by their keys, such
+// that matching rows are joined on the same node. When not distributed, it's
+// equivalent to Join.
+func DistributedJoin(typ JoinType, leftKeys, rightKeys []int, left, right Runner, threshold int) Runner {
+    return &distJoin{uuid.NewV4().String(), Join(typ, leftKeys, rightKeys, left, right).(*join), threshold}
+}
+
+type distJoin struct {
+    UID string
+    Join *join
+    Threshold int // maximum right rows to broadcast, or alwaysBroadcast/Partition
+}
+
+func (r *distJoin) Returns() []Type { return r.Join.Returns() }
+
+func (r *distJoin) Run(ctx context.Context, inp, out chan Dataset) error {
+    if ctx.Value("ep.AllNodes") == nil {
+        return r.Join.Run(ctx, inp, out) // not distributed
+    }
+
+    // the left output must be drained before waiting, in case we exit early
+    left, right, g := runBoth(ctx, r.Join.Left, r.Join.Right, inp)
+    drainAndWait := func() error {
+        for _ = range left {}
+        return g.Wait()
+    }
+
+    // collect the local right rows. Meanwhile, buffer the left side in order
+    // to not block it.
+    var rights Dataset
+    var pending []Dataset
+    leftOpen := left
+    for right != nil {
+        select {
+        case data, ok := <- right:
+            if !ok {
+                right = nil
+            } else if data.Len() > 0 {
+                rights = appendClone(rights, data)
+            }
+        case data, ok := <- leftOpen:
+            if !ok {
+                leftOpen = nil
+                continue
+            }
+            pending = append(pending, data)
+        }
+    }
+
+    broadcast, err := r.shouldBroadcast(ctx, rights)
+    if err != nil {
+        drainAndWait()
+        return err
+    }
+
+    // the exchanges UIDs are derived from this runner's UID, in order to be the
+    // same on all nodes.
+    var joined Runner
+    j := r.Join
+    leftSrc := &chanSource{j.Left.Returns(), pending, left}
+    rightSrc := &chanSource{j.Right.Returns(), []Dataset{rights}, nil}
+    if broadcast {
+        rightEx := &exchange{UID: r.UID + ":right", SendTo: sendBroadcast}
+        joined = Join(j.Type, j.LeftKeys, j.RightKeys, leftSrc, Pipeline(rightSrc, rightEx))
+    } else {
+        leftEx := &exchange{UID: r.UID + ":left", SendTo: sendPartition, Columns: j.LeftKeys}
+        rightEx := &exchange{UID: r.UID + ":right", SendTo: sendPartition, Columns: j.RightKeys}
+        joined = Join(j.Type, j.LeftKeys, j.RightKeys, Pipeline(leftSrc, leftEx), Pipeline(rightSrc, rightEx))
+    }
+
+    trigger := make(chan Dataset, 1)
+    trigger <- NewDataset()
+    close(trigger)
+
+    err = joined.Run(ctx, trigger, out)
+    err1 := drainAndWait()
+    if err == nil {
+        err = err1
+    }
+    return err
+}
+
+// shouldBroadcast determines if the right side should be broadcasted. All of
+// the nodes share the number of their local right rows, thus they all reach
+// the same decision.
+func (r *distJoin) shouldBroadcast(ctx context.Context, rights Dataset) (bool, error) {
+    if r.Threshold == alwaysBroadcast {
+        return true, nil
+    } else if r.Threshold == alwaysPartition {
+        return false, nil
+    } else if r.Join.Type == RightJoin || r.Join.Type == FullJoin {
+        return false, nil
+    }
+
+    n := 0
+    if rights != nil {
+        n = rights.Len()
+    }
+
+    sizes := make(chan Dataset, 1)
+    sizes <- NewDataset(Strs{strconv.Itoa(n)})
+    close(sizes)
+
+    ex := &exchange{UID: r.UID + ":size", SendTo: sendBroadcast}
+    all, err := collect(ctx, ex, sizes)
+    if err != nil {
+        return false, err
+    }
+
+    total := 0
+    for _, s := range all.At(0).Strings() {
+        n, _ := strconv.Atoi(s)
+        total += n
+    }
+    return total <= r.Threshold, nil
+}
+
+// chanSource is a local source Runner that ignores its input, and emits the
+// pending datasets followed by all of the datasets from a channel (if any).
+// It returns the types of the runner that originally produced them.
+type chanSource struct {
+    Types []Type
+    Pending []Dataset
+    Ch chan Dataset
+}
+
+func (r *chanSource) Returns() []Type { return r.Types }
+func (r *chanSource) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    for _, data := range r.Pending {
+        if data != nil {
+            out <- data
+        }
+    }
+
+    if r.Ch == nil {
+        return nil
+    }
+
+    for data := range r.Ch {
+        out <- data
+    }
+    return nil
+}
@@ -0,0 +1,106 @@
+package ep
+
+import (
+    "sort"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&nodeConst{})
+
+// nodeConst is a source Runner that ignores its input, and emits a constant
+// dataset only on a specific node
+type nodeConst struct {
+    Addr string
+    Data Dataset
+}
+
+func (r *nodeConst) Returns() []Type { return (&constRunner{r.Data}).Returns() }
+func (r *nodeConst) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    if thisNode == r.Addr {
+        out <- r.Data
+    }
+    return nil
+}
+
+func TestBroadcastJoinNotDistributed(t *testing.T) {
+    runner := BroadcastJoin(InnerJoin, []int{0}, []int{0}, users, orders)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    require.NoError(t, err)
+    require.Equal(t, []string{"1 alice 1 book", "1 alice 1 pen", "3 carol 3 cup"}, rowStrings(data))
+}
+
+func TestBroadcastJoinUnsupported(t *testing.T) {
+    require.Panics(t, func() {
+        BroadcastJoin(FullJoin, []int{0}, []int{0}, users, orders)
+    })
+}
+
+// Test that all of the join strategies produce the same rows, when the left
+// side is scattered and the right side exists only on one node
+func TestDistributedJoin(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    right := &nodeConst{":5552", NewDataset(Strs{"1", "2", "3"}, Strs{"alice", "bob", "carol"})}
+    left1 := NewDataset(Strs{"1", "1"}, Strs{"book", "pen"})
+    left2 := NewDataset(Strs{"3", "4"}, Strs{"cup", "hat"})
+
+    tests := map[string]struct {
+        Runner Runner
+        Expected []string
+    }{
+        "broadcast inner": {
+            BroadcastJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), right),
+            []string{"1 book 1 alice", "1 pen 1 alice", "3 cup 3 carol"},
+        },
+        "broadcast left": {
+            BroadcastJoin(LeftJoin, []int{0}, []int{0}, PassThrough(), right),
+            []string{"1 book 1 alice", "1 pen 1 alice", "3 cup 3 carol", "4 hat  "},
+        },
+        "broadcast anti": {
+            BroadcastJoin(AntiJoin, []int{0}, []int{0}, PassThrough(), right),
+            []string{"4 hat"},
+        },
+        "small inner": {
+            DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), right, 10),
+            []string{"1 book 1 alice", "1 pen 1 alice", "3 cup 3 carol"},
+        },
+        "large inner": {
+            DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), right, 0),
+            []string{"1 book 1 alice", "1 pen 1 alice", "3 cup 3 carol"},
+        },
+        // the left runner returns a Wildcard, thus the unmatched right rows
+        // are padded to the width of the left data
+        "full": {
+            DistributedJoin(FullJoin, []int{0}, []int{0}, PassThrough(), right, 10),
+            []string{"  2 bob", "1 book 1 alice", "1 pen 1 alice", "3 cup 3 carol", "4 hat  "},
+        },
+    }
+
+    for name, test := range tests {
+        runner := Pipeline(Scatter(), test.Runner, Gather())
+        runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+        inp := make(chan Dataset, 2)
+        inp <- left1
+        inp <- left2
+        close(inp)
+
+        // unmatched rows are in separate batches, with null columns
+        out := make(chan Dataset, 10)
+        err := runner.Run(context.Background(), inp, out)
+        close(out)
+        require.NoError(t, err, name)
+
+        rows := []string{}
+        for data := range out {
+            rows = append(rows, rowStrings(data)...)
+        }
+
+        sort.Strings(rows)
+        require.Equal(t, test.Expected, rows, name)
+    }
+}
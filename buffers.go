@@ -0,0 +1,91 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+)
+
+var _ = registerGob(&withBuffers{})
+
+// Buffers are the depths of the channel buffers of a job. Shallow buffers
+// favor latency and memory, as every dataset is handed over as soon as it's
+// produced, while deeper buffers favor throughput, by decoupling the producing
+// runners from the slower consuming ones. See WithBuffers
+type Buffers struct {
+
+    // Channels is the buffer of the channels between the runners of the
+    // built-in composite runners, like the stages of a Pipeline
+    Channels int
+
+    // ShortCircuit is the buffer of the local queue of an exchange, for the
+    // datasets it sends to its own node. It must be positive
+    ShortCircuit int
+
+    // Send is the buffer of the queues of an exchange to each of the other
+    // nodes, of the datasets that wait while the previous one is encoded and
+    // sent. Encoding is always asynchronous, even when it's unbuffered
+    Send int
+}
+
+// DefaultBuffers are the Buffers of the jobs that don't set their own. The
+// channels are unbuffered by default
+var DefaultBuffers = Buffers{Channels: 0, ShortCircuit: 1000, Send: 2}
+
+// buffersOf returns the Buffers of the job in the context, or the
+// DefaultBuffers when it doesn't set its own
+func buffersOf(ctx context.Context) Buffers {
+    b, ok := ctx.Value("ep.Buffers").(Buffers)
+    if !ok {
+        return DefaultBuffers
+    }
+    return b
+}
+
+// newChan returns a new channel between runners, buffered by the Buffers of
+// the job in the context
+func newChan(ctx context.Context) chan Dataset {
+    return make(chan Dataset, buffersOf(ctx).Channels)
+}
+
+// WithBuffers returns a Runner that runs the provided runner with the channel
+// buffers, instead of the DefaultBuffers. When distributed, they apply to all
+// nodes. Non-positive short-circuit buffers are replaced by the default
+func WithBuffers(b Buffers, r Runner) Runner {
+    if b.Channels < 0 {
+        b.Channels = 0
+    }
+
+    if b.Send < 0 {
+        b.Send = 0
+    }
+
+    if b.ShortCircuit <= 0 {
+        b.ShortCircuit = DefaultBuffers.ShortCircuit
+    }
+    return &withBuffers{b, r}
+}
+
+type withBuffers struct {
+    Buffers Buffers
+    Runner Runner
+}
+
+func (r *withBuffers) Returns() []Type { return r.Runner.Returns() }
+func (r *withBuffers) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *withBuffers) Run(ctx context.Context, inp, out chan Dataset) error {
+    ctx = context.WithValue(ctx, "ep.Buffers", r.Buffers)
+    return r.Runner.Run(ctx, inp, out)
+}
+
+func (r *withBuffers) String() string {
+    b := r.Buffers
+    return fmt.Sprintf("channels %d short-circuit %d send %d", b.Channels, b.ShortCircuit, b.Send)
+}
+
+func (r *withBuffers) inner() []Runner { return []Runner{r.Runner} }
+func (r *withBuffers) withInner(inner []Runner) Runner {
+    return &withBuffers{r.Buffers, inner[0]}
+}
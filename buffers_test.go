@@ -0,0 +1,121 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleWithBuffers() {
+    runner := Pipeline(ReadLines(strings.NewReader("a\nb")), PassThrough())
+    runner = WithBuffers(Buffers{Channels: 8}, runner)
+    fmt.Print(Explain(runner))
+
+    data, err := testRun(runner)
+    fmt.Println(data, err)
+
+    // Output:
+    // WithBuffers channels 8 short-circuit 1000 send 0 returns=[line:string]
+    //   Pipeline returns=[line:string]
+    //     ep.readLines returns=[line:string]
+    //     PassThrough returns=[line:string]
+    // [[a b]] <nil>
+}
+
+// burst emits N datasets, and then notifies that it's done
+type burst struct {
+    N int
+    done chan struct{}
+}
+
+func (*burst) Returns() []Type { return []Type{Str} }
+func (r *burst) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    for i := 0; i < r.N; i++ {
+        out <- NewDataset(Strs{fmt.Sprint(i)})
+    }
+    close(r.done)
+    return nil
+}
+
+// awaitDone forwards its input only after the burst is done
+type awaitDone struct { done chan struct{} }
+func (*awaitDone) Returns() []Type { return []Type{Wildcard} }
+func (r *awaitDone) Run(ctx context.Context, inp, out chan Dataset) error {
+    <- r.done
+    for data := range inp {
+        out <- data
+    }
+    return nil
+}
+
+// Tests that the datasets are buffered between the stages of a pipeline,
+// such that the first stage completes before the second starts consuming
+func TestWithBuffers(t *testing.T) {
+    done := make(chan struct{})
+    runner := Pipeline(&burst{3, done}, &awaitDone{done})
+    data, err := testRun(WithBuffers(Buffers{Channels: 3}, runner))
+    require.NoError(t, err)
+    require.Equal(t, []string{"0", "1", "2"}, rowStrings(data))
+}
+
+func TestWithBuffersDefaults(t *testing.T) {
+    r := WithBuffers(Buffers{Channels: -1, Send: -1}, PassThrough()).(*withBuffers)
+    require.Equal(t, Buffers{0, DefaultBuffers.ShortCircuit, 0}, r.Buffers)
+    require.Equal(t, DefaultBuffers, buffersOf(context.Background()))
+
+    ctx := context.WithValue(context.Background(), "ep.Buffers", Buffers{2, 3, 4})
+    require.Equal(t, 2, cap(newChan(ctx)))
+}
+
+func TestWithBuffersDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
+    runner = WithBuffers(Buffers{Channels: 4, ShortCircuit: 1}, runner)
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data1 := NewDataset(Strs{"hello", "world"})
+    data2 := NewDataset(Strs{"foo", "bar"})
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+}
+
+// Tests that errors don't block on a full short-circuit, when the local
+// receiver has already exited
+func TestWithBuffersErr(t *testing.T) {
+    pipes := NewPipes()
+    ln1, err := pipes.Listen(":5551")
+    require.NoError(t, err)
+
+    dist1 := NewDistributer(":5551", ln1)
+    defer dist1.Close()
+    go dist1.Start()
+
+    ln2, err := pipes.Listen(":5552")
+    require.NoError(t, err)
+
+    dialer := &errDialer{ln2, fmt.Errorf("bad connection")}
+    dist2 := NewDistributer(":5552", dialer)
+    defer dist2.Close()
+    go dist2.Start()
+
+    ln3, err := pipes.Listen(":5553")
+    require.NoError(t, err)
+
+    dist3 := NewDistributer(":5553", ln3)
+    defer dist3.Close()
+    go dist3.Start()
+
+    runner := WithBuffers(Buffers{ShortCircuit: 1}, Scatter())
+    runner = dist1.Distribute(runner, ":5551", ":5552", ":5553")
+
+    data1 := NewDataset(Strs{"hello", "world"})
+    data2 := NewDataset(Strs{"foo", "bar"})
+    _, err = testRun(runner, data1, data2)
+    require.Error(t, err)
+    require.Equal(t, "bad connection", err.Error())
+}
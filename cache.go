@@ -0,0 +1,205 @@
+package ep
+
+import (
+    "os"
+    "sync"
+    "bufio"
+    "context"
+    "io/ioutil"
+    "encoding/gob"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&cache{})
+
+// CacheBuffer is the default maximum number of rows that Cache keeps in memory
+// before spilling them to disk.
+var CacheBuffer = 100000
+
+// materialized outputs of all of the Cache runners in this process, by UID
+var caches = map[string]*cached{}
+var cachesLock sync.Mutex
+
+// Cache returns a Runner that materializes the output of the provided runner
+// on its first run, and replays it on all subsequent runs within the same
+// process, instead of re-running it. This way, a subplan that's referenced
+// several times isn't recomputed. The input of the subsequent runs is ignored,
+// and concurrent runs wait for the first run to complete. If the first run
+// fails, the error is returned from all of the waiting runs, and the next run
+// recomputes. Up to CacheBuffer rows are kept in memory; beyond that, they're
+// spilled to a temporary file. Use ClearCache to release them.
+//
+// When distributed, the copies of the runner on every node share the same
+// identity, thus every node caches its own local output.
+func Cache(r Runner) Runner {
+    return CacheSpill(r, CacheBuffer)
+}
+
+// CacheSpill is like Cache, with an explicit maximum number of rows to keep in
+// memory before spilling to disk.
+func CacheSpill(r Runner, buffer int) Runner {
+    return &cache{uuid.NewV4().String(), r, buffer}
+}
+
+// ClearCache releases the materialized output of a runner returned by Cache,
+// including its spilled file, if any. The next run recomputes it.
+func ClearCache(r Runner) {
+    c, ok := r.(*cache)
+    if !ok {
+        return
+    }
+
+    cachesLock.Lock()
+    entry := caches[c.UID]
+    delete(caches, c.UID)
+    cachesLock.Unlock()
+
+    if entry != nil {
+        <- entry.done // don't release it while it's materialized
+        entry.release()
+    }
+}
+
+type cache struct {
+    UID string
+    Runner Runner
+    Buffer int
+}
+
+func (r *cache) Returns() []Type { return r.Runner.Returns() }
+func (r *cache) Run(ctx context.Context, inp, out chan Dataset) error {
+    cachesLock.Lock()
+    entry := caches[r.UID]
+    first := entry == nil
+    if first {
+        entry = &cached{done: make(chan struct{})}
+        caches[r.UID] = entry
+    }
+    cachesLock.Unlock()
+
+    if !first {
+        for _ = range inp {}
+        select {
+        case <- entry.done:
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+
+        if entry.err != nil {
+            return entry.err
+        }
+        return entry.replay(ctx, out)
+    }
+
+    err := entry.materialize(ctx, r, inp, out)
+    if err != nil {
+        entry.err = err
+        entry.release()
+
+        cachesLock.Lock()
+        if caches[r.UID] == entry {
+            delete(caches, r.UID)
+        }
+        cachesLock.Unlock()
+    }
+
+    close(entry.done)
+    return err
+}
+
+// cached is the materialized output of a single Cache runner. It's immutable
+// once done is closed.
+type cached struct {
+    done chan struct{}
+    err error
+    batches []Dataset // in-memory batches
+    file *os.File // spilled batches, if any
+}
+
+// materialize runs the runner, and emits its output while caching it
+func (c *cached) materialize(ctx context.Context, r *cache, inp, out chan Dataset) (err error) {
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var err1 error
+    res := make(chan Dataset)
+    go func() {
+        defer close(res)
+        err1 = runSafe(ctx, r.Runner, inp, res)
+    }()
+
+    // the runner error takes precedence, as it might be the cause
+    defer func() {
+        for _ = range res {}
+        if err1 != nil {
+            err = err1
+        }
+    }()
+
+    var w *bufio.Writer
+    var enc *gob.Encoder
+    rows := 0
+    for data := range res {
+        if data.Len() == 0 {
+            out <- data
+            continue
+        }
+
+        // copy the data before emitting it, as it might be modified in-place
+        // downstream
+        orig := data
+        data = Clone(data).(Dataset)
+        out <- orig
+        if rows < r.Buffer {
+            c.batches = append(c.batches, data)
+            rows += data.Len()
+            continue
+        }
+
+        if c.file == nil {
+            c.file, err = ioutil.TempFile("", "ep-cache")
+            if err != nil {
+                return err
+            }
+
+            w = bufio.NewWriter(c.file)
+            enc = gob.NewEncoder(w)
+        }
+
+        var batch Data = data
+        err = enc.Encode(&batch)
+        if err != nil {
+            return err
+        }
+    }
+
+    if w != nil {
+        return w.Flush()
+    }
+    return nil
+}
+
+// replay the cached output. The in-memory batches are copied, as they might
+// be modified in-place downstream.
+func (c *cached) replay(ctx context.Context, out chan Dataset) error {
+    for _, data := range c.batches {
+        out <- Clone(data).(Dataset)
+    }
+
+    if c.file == nil {
+        return nil
+    }
+
+    // read the spilled batches from a separate file handle, in order to allow
+    // concurrent replays
+    return replayFile(ctx, c.file.Name(), out)
+}
+
+func (c *cached) release() {
+    c.batches = nil
+    if c.file != nil {
+        c.file.Close()
+        os.Remove(c.file.Name())
+        c.file = nil
+    }
+}
@@ -0,0 +1,56 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// counter is a source Runner that emits its runs count in several batches
+type counter struct { Runs int }
+func (*counter) Returns() []Type { return []Type{Str} }
+func (r *counter) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    r.Runs++
+    for i := 0; i < 3; i++ {
+        out <- NewDataset(Strs{fmt.Sprintf("run%d", r.Runs)})
+    }
+    return nil
+}
+
+func TestCache(t *testing.T) {
+    for _, buffer := range []int{100, 1} { // in-memory and spilled
+        c := &counter{}
+        runner := CacheSpill(c, buffer)
+        defer ClearCache(runner)
+
+        for i := 0; i < 3; i++ {
+            data, err := testRun(runner, NewDataset(Null.Data(1)))
+            require.NoError(t, err)
+            require.Equal(t, "[[run1 run1 run1]]", fmt.Sprintf("%v", data))
+        }
+        require.Equal(t, 1, c.Runs)
+
+        // cleared cache is recomputed
+        ClearCache(runner)
+        data, err := testRun(runner, NewDataset(Null.Data(1)))
+        require.NoError(t, err)
+        require.Equal(t, "[[run2 run2 run2]]", fmt.Sprintf("%v", data))
+    }
+}
+
+func TestCacheErr(t *testing.T) {
+    r := &flaky{Failures: 1}
+    runner := Cache(r)
+    defer ClearCache(runner)
+
+    _, err := testRun(runner, NewDataset(Strs{"a"}))
+    require.Error(t, err)
+
+    // failures aren't cached
+    data, err := testRun(runner, NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[a]]", fmt.Sprintf("%v", data))
+    require.Equal(t, 2, r.Runs)
+}
@@ -0,0 +1,266 @@
+package ep
+
+import (
+    "fmt"
+    "math"
+    "time"
+    "strconv"
+    "strings"
+    "context"
+)
+
+var _ = registerGob(&cast{})
+
+// layouts of the strings that are cast into times, in order
+var castTimeLayouts = []string{
+    time.RFC3339Nano,
+    "2006-01-02 15:04:05.999999999",
+    "2006-01-02",
+}
+
+// Cast returns a Runner that converts the values of column `col` of its input
+// into the provided type, and emits them in place of the original column. The
+// rest of the columns are unchanged. It fails if any of the values can't be
+// converted. See CastData for the conversion rules, and TryCast
+func Cast(col int, to Type) Runner {
+    return &cast{col, to, false}
+}
+
+// TryCast is like Cast, except that values that can't be converted become
+// nulls instead of failing
+func TryCast(col int, to Type) Runner {
+    return &cast{col, to, true}
+}
+
+type cast struct {
+    Col int
+    To Type
+    OrNull bool
+}
+
+func (*cast) Returns() []Type { return []Type{Wildcard} }
+func (r *cast) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        if r.Col < 0 || r.Col >= data.Width() {
+            return fmt.Errorf("column %d out of range %d", r.Col, data.Width())
+        }
+
+        res, err := castData(data.At(r.Col), r.To, r.OrNull)
+        if err != nil {
+            return err
+        }
+
+        cols := append([]Data{}, columns(data)...)
+        cols[r.Col] = res
+        out <- newNamedDataset(namesOf(data), cols...)
+    }
+    return nil
+}
+
+// CastData converts the values of the data into the provided type. Nulls remain
+// nulls, and data that's already of the type is returned as-is. The conversion
+// rules between the built-in types are:
+//
+//      string          from the string representation of any type
+//      int             from float (truncated toward zero, within range), bool
+//                      (1 or 0), timestamp (unix seconds) and integer strings
+//      float           from int, bool (1 or 0), timestamp (unix seconds) and
+//                      numeric strings
+//      bool            from int and float (non-zero is true), and strings
+//                      parsed by strconv.ParseBool
+//      timestamp       from int and float (unix seconds), and strings in
+//                      RFC3339, "2006-01-02 15:04:05" or "2006-01-02" formats
+//
+// Other types are converted through the string representation of the values,
+// which must be parsable into the type (e.g. decimals, UUIDs and JSONs). Any
+// failed conversion is an error.
+func CastData(data Data, to Type) (Data, error) {
+    return castData(data, to, false)
+}
+
+// castData converts the data, where failed conversions are either errors or
+// nulls
+func castData(data Data, to Type, orNull bool) (Data, error) {
+    to = unnamed(to)
+    if t, ok := to.(*NullableType); ok {
+        to = t.Of
+    }
+
+    n := data.Len()
+    if Equal(data.Type(), to) || to == Any || to == Wildcard {
+        return data, nil
+    } else if data.Type() == Null {
+        return Nullable(to.Data(uint(n)), make(Bools, n)), nil
+    }
+
+    valid := make(Bools, n)
+    for i := range valid {
+        valid[i] = true
+    }
+
+    if vs, ok := data.(nullable); ok {
+        data = vs.Values
+        copy(valid, vs.Valid)
+    }
+
+    res, err := convert(data, to, valid, orNull)
+    if err != nil || allTrue(valid) {
+        return res, err
+    }
+    return Nullable(res, valid), nil
+}
+
+// convert the valid values of the data into the type. Invalid values are left
+// as zeros. Failed conversions are marked as invalid when orNull is set.
+func convert(data Data, to Type, valid Bools, orNull bool) (Data, error) {
+    strs := data.Strings()
+    fail := func(i int) error {
+        if orNull {
+            valid[i] = false
+            return nil
+        }
+        return fmt.Errorf("unable to cast %q from %s to %s", strs[i], data.Type().Name(), to.Name())
+    }
+
+    res := to.Data(uint(data.Len()))
+    switch vs := res.(type) {
+    case Strs:
+        for i, ok := range valid {
+            if ok {
+                vs[i] = strs[i]
+            }
+        }
+        return vs, nil
+    case Ints, Floats, Bools, Times:
+        for i, ok := range valid {
+            if ok && !castValue(Value(data, i), strs[i], vs, i) {
+                if err := fail(i); err != nil {
+                    return nil, err
+                }
+            }
+        }
+        return vs, nil
+    }
+
+    // other types are parsed from strings, row by row in order to isolate the
+    // failures
+    res = res.Slice(0, 0)
+    for i, ok := range valid {
+        var v Data
+        var err error
+        if ok {
+            v, err = parseValue(to, strs[i])
+        }
+
+        if ok && err != nil {
+            if err := fail(i); err != nil {
+                return nil, err
+            }
+        }
+
+        if !ok || err != nil {
+            v = to.Data(1)
+        }
+        res = res.Append(v)
+    }
+    return res, nil
+}
+
+// castValue converts the native value (or its string representation) into
+// index i of the data, and returns false if it can't be converted
+func castValue(v interface{}, s string, res Data, i int) bool {
+    var err error
+    switch vs := res.(type) {
+    case Ints:
+        switch v := v.(type) {
+        case int64:
+            vs[i] = v
+        case float64:
+            if math.IsNaN(v) || v >= math.MaxInt64 || v < math.MinInt64 {
+                return false
+            }
+            vs[i] = int64(v)
+        case bool:
+            vs[i] = boolInt(v)
+        case time.Time:
+            vs[i] = v.Unix()
+        default:
+            vs[i], err = strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+        }
+    case Floats:
+        switch v := v.(type) {
+        case int64:
+            vs[i] = float64(v)
+        case float64:
+            vs[i] = v
+        case bool:
+            vs[i] = float64(boolInt(v))
+        case time.Time:
+            vs[i] = float64(v.UnixNano()) / 1e9
+        default:
+            vs[i], err = strconv.ParseFloat(strings.TrimSpace(s), 64)
+        }
+    case Bools:
+        switch v := v.(type) {
+        case int64:
+            vs[i] = v != 0
+        case float64:
+            vs[i] = v != 0
+        case bool:
+            vs[i] = v
+        default:
+            vs[i], err = strconv.ParseBool(strings.TrimSpace(s))
+        }
+    case Times:
+        switch v := v.(type) {
+        case int64:
+            vs[i] = v * int64(time.Second)
+        case float64:
+            vs[i] = int64(v * 1e9)
+        case time.Time:
+            vs[i] = v.UnixNano()
+        default:
+            vs[i], err = parseTime(strings.TrimSpace(s))
+        }
+    }
+    return err == nil
+}
+
+// parseValue parses a single string into Data of the type
+func parseValue(to Type, s string) (Data, error) {
+    switch to := to.(type) {
+    case *DecimalType:
+        return ParseDecimals(to.Precision, to.Scale, s)
+    case *JSONType:
+        return ParseJSONs(s)
+    }
+    return FromValues(to, []interface{}{s})
+}
+
+func parseTime(s string) (int64, error) {
+    var err error
+    for _, layout := range castTimeLayouts {
+        var t time.Time
+        t, err = time.ParseInLocation(layout, s, time.UTC)
+        if err == nil {
+            return t.UnixNano(), nil
+        }
+    }
+    return 0, err
+}
+
+func boolInt(v bool) int64 {
+    if v {
+        return 1
+    }
+    return 0
+}
+
+func allTrue(vs Bools) bool {
+    for _, v := range vs {
+        if !v {
+            return false
+        }
+    }
+    return true
+}
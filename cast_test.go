@@ -0,0 +1,88 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleCast() {
+    data := NewDataset(Strs{"a", "b"}, Strs{"1", " 2 "})
+    res, err := testRun(Cast(1, Int), data)
+    fmt.Println(res, res.At(1).Type().Name(), err)
+
+    // Output: [[a b] [1 2]] int <nil>
+}
+
+func ExampleTryCast() {
+    data := NewDataset(Strs{"1.5", "foo"})
+    res, err := testRun(TryCast(0, Float), data)
+    fmt.Println(res, err)
+
+    // Output: [[1.5 <nil>]] <nil>
+}
+
+func TestCastErr(t *testing.T) {
+    _, err := testRun(Cast(0, Int), NewDataset(Strs{"1", "foo"}))
+    require.EqualError(t, err, `unable to cast "foo" from string to int`)
+
+    _, err = testRun(Cast(1, Int), NewDataset(Strs{"1"}))
+    require.EqualError(t, err, "column 1 out of range 1")
+
+    err = Validate(Cast(2, Int), Str, Str)
+    require.Error(t, err)
+}
+
+// Tests the conversion matrix between the built-in types
+func TestCastData(t *testing.T) {
+    ts := time.Date(2018, 1, 2, 0, 0, 0, 0, time.UTC)
+    tests := []struct {
+        From Data
+        To Type
+        Expected string
+    }{
+        {Ints{1, -2}, Str, "[1 -2]"},
+        {Floats{1.9, -1.9}, Int, "[1 -1]"},
+        {Bools{true, false}, Int, "[1 0]"},
+        {NewTimes(ts), Int, fmt.Sprint([]int64{ts.Unix()})},
+        {Ints{1, 2}, Float, "[1 2]"},
+        {Bools{true}, Float, "[1]"},
+        {Ints{0, 3}, Bool, "[false true]"},
+        {Floats{0, 0.5}, Bool, "[false true]"},
+        {Strs{"true", "0", "F"}, Bool, "[true false false]"},
+        {Strs{"2018-01-02", "2018-01-02 00:00:00", "2018-01-02T00:00:00Z"}, Time, "[2018-01-02T00:00:00Z 2018-01-02T00:00:00Z 2018-01-02T00:00:00Z]"},
+        {Ints{ts.Unix()}, Time, "[2018-01-02T00:00:00Z]"},
+        {NewTimes(ts), Str, "[2018-01-02T00:00:00Z]"},
+        {Strs{"1.25"}, Decimal(5, 2), "[1.25]"},
+        {Strs{"b", "a"}, Enum, "[b a]"},
+        {Strs{`{"a": 1}`}, JSON, `[{"a":1}]`},
+        {Nullable(Strs{"1", "x"}, Bools{true, false}), Int, "[1 ]"},
+        {Nullable(Strs{"1.25", "x"}, Bools{true, false}), Decimal(5, 2), "[1.25 ]"},
+        {Null.Data(2), Int, "[ ]"},
+        {Strs{"a"}, Str, "[a]"},
+    }
+
+    for _, test := range tests {
+        res, err := CastData(test.From, test.To)
+        require.NoError(t, err, "%v to %s", test.From, test.To.Name())
+        require.Equal(t, test.Expected, fmt.Sprint(res.Strings()), "%v to %s", test.From, test.To.Name())
+        require.Equal(t, test.To.Name(), res.Type().Name())
+    }
+}
+
+func TestCastDataFailures(t *testing.T) {
+    failures := []Data{Floats{1e100}, Strs{"1.5"}, Strs{""}}
+    for _, data := range failures {
+        _, err := CastData(data, Int)
+        require.Error(t, err, "%v", data)
+
+        res, err := castData(data, Int, true)
+        require.NoError(t, err)
+        require.Equal(t, "[<nil>]", fmt.Sprint(res))
+    }
+
+    res, err := castData(Strs{"1.5", "x"}, Decimal(5, 2), true)
+    require.NoError(t, err)
+    require.Equal(t, "[1.50 <nil>]", fmt.Sprint(res))
+}
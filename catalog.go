@@ -0,0 +1,159 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "sync"
+    "context"
+)
+
+var _ = registerGob(&scanData{}, &catalogSync{})
+
+// Tables is the default Catalog of named tables. The sql package resolves
+// table references against it, unless provided with other Tables
+var Tables = NewCatalog()
+
+// Table is a named table of a Catalog, with the Runner that scans it
+type Table struct {
+    Name string
+    Schema Schema
+    Runner Runner
+}
+
+// Catalog is a registry of named tables, backed by scan runners or in-memory
+// datasets, which RunnerPlans can resolve table references against. Unlike
+// the other registries, it's safe for concurrent use, as tables are commonly
+// registered and dropped while other plans are running
+type Catalog struct {
+    mu sync.RWMutex
+    tables map[string]Table
+}
+
+// NewCatalog returns a new empty Catalog
+func NewCatalog() *Catalog {
+    return &Catalog{tables: map[string]Table{}}
+}
+
+// Register registers a table under the provided name, replacing any existing
+// table with the same name. The scan Runner is expected to produce datasets
+// of the provided schema. In order to distribute its plans, the scan Runner
+// must be distributable, and scan every nodes' share of the table
+func (c *Catalog) Register(name string, schema Schema, scan Runner) *Catalog {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.tables[name] = Table{name, schema, scan}
+    return c
+}
+
+// RegisterDataset registers an in-memory table of the provided dataset. When
+// distributed, every node scans every nodes'th row of the dataset, such that
+// every row is scanned exactly once
+func (c *Catalog) RegisterDataset(name string, data Dataset) *Catalog {
+    return c.Register(name, data.Schema(), &scanData{data})
+}
+
+// Drop removes the table registered under the provided name, if any
+func (c *Catalog) Drop(name string) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    delete(c.tables, name)
+}
+
+// Get returns the table registered under the provided name
+func (c *Catalog) Get(name string) (Table, bool) {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    t, ok := c.tables[name]
+    return t, ok
+}
+
+// Table returns the schema and scan Runner of the table registered under the
+// provided name, or an error if it's not registered
+func (c *Catalog) Table(name string) (Schema, Runner, error) {
+    t, ok := c.Get(name)
+    if !ok {
+        return nil, nil, fmt.Errorf("unknown table %s", name)
+    }
+    return t.Schema, t.Runner, nil
+}
+
+// Names returns the sorted names of all of the registered tables
+func (c *Catalog) Names() []string {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    names := make([]string, 0, len(c.tables))
+    for name := range c.tables {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Sync returns a Runner that registers a snapshot of all of the tables of this
+// catalog into the Tables catalog of every node it's distributed to. It emits
+// the address of every node, into the master node, once that node has
+// registered the tables, thus once it completes the tables can be resolved on
+// all nodes. All of the scan runners must be distributable
+func (c *Catalog) Sync() Runner {
+    c.mu.RLock()
+    defer c.mu.RUnlock()
+    tables := make([]Table, 0, len(c.tables))
+    for _, t := range c.tables {
+        tables = append(tables, t)
+    }
+    return Pipeline(&catalogSync{tables}, Gather())
+}
+
+// catalogSync registers its tables into the Tables catalog of the node it
+// runs on, and emits the address of that node
+type catalogSync struct { Tables []Table }
+
+func (r *catalogSync) Returns() []Type { return []Type{As(Str, "node")} }
+func (r *catalogSync) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    for _, t := range r.Tables {
+        Tables.Register(t.Name, t.Schema, t.Runner)
+    }
+
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    out <- newNamedDataset([]string{"node"}, Strs{thisNode})
+    return nil
+}
+
+// scanData emits its share of an in-memory dataset, which is all of it when
+// running locally, or every nodes'th row when distributed
+type scanData struct { Data Dataset }
+
+func (r *scanData) Returns() []Type {
+    types := []Type{}
+    for _, f := range r.Data.Schema() {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+// EstimateRows implements Estimator, as all nodes scan the same dataset
+func (r *scanData) EstimateRows() int { return r.Data.Len() }
+
+func (r *scanData) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    data := r.Data
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    for node, addr := range allNodes {
+        if addr != thisNode || len(allNodes) < 2 {
+            continue
+        }
+
+        mask := make(Bools, data.Len())
+        for i := range mask {
+            mask[i] = i % len(allNodes) == node
+        }
+        data = data.Filter(mask)
+    }
+
+    out <- data
+    return nil
+}
@@ -0,0 +1,86 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "sort"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleCatalog() {
+    catalog := NewCatalog()
+    catalog.RegisterDataset("users", newNamedDataset(
+        []string{"id", "name"},
+        Ints{1, 2},
+        Strs{"bob", "alice"},
+    ))
+
+    schema, scan, err := catalog.Table("users")
+    fmt.Println(catalog.Names(), schema.Names(), err)
+
+    data, err := testRun(scan)
+    fmt.Println(data, err)
+
+    _, _, err = catalog.Table("nope")
+    fmt.Println(err)
+
+    // Output:
+    // [users] [id name] <nil>
+    // [[1 2] [bob alice]] <nil>
+    // unknown table nope
+}
+
+func TestCatalogDrop(t *testing.T) {
+    catalog := NewCatalog()
+    catalog.RegisterDataset("a", NewDataset(Ints{1})).
+        RegisterDataset("b", NewDataset(Ints{2}))
+    require.Equal(t, []string{"a", "b"}, catalog.Names())
+
+    catalog.Drop("a")
+    catalog.Drop("nope")
+    require.Equal(t, []string{"b"}, catalog.Names())
+
+    _, ok := catalog.Get("a")
+    require.False(t, ok)
+}
+
+// Test that the tables are synchronized to all nodes, and that in-memory
+// tables are scanned exactly once when distributed
+func TestCatalogSync(t *testing.T) {
+    dists := []Distributer{}
+    addrs := []string{}
+    for i := 0; i < 2; i++ {
+        ln, err := net.Listen("tcp", "127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(ln.Addr().String(), ln)
+        go dist.Start()
+        defer dist.Close()
+
+        dists = append(dists, dist)
+        addrs = append(addrs, dist.Addr())
+    }
+
+    catalog := NewCatalog()
+    catalog.RegisterDataset("catalog_sync", NewDataset(Ints{1, 2, 3, 4, 5}))
+    defer Tables.Drop("catalog_sync")
+
+    data, err := testRun(dists[0].Distribute(catalog.Sync(), addrs...))
+    require.NoError(t, err)
+
+    nodes := data.At(0).Strings()
+    sort.Strings(nodes)
+    sort.Strings(addrs)
+    require.Equal(t, addrs, nodes)
+
+    _, scan, err := Tables.Table("catalog_sync")
+    require.NoError(t, err)
+
+    data, err = testRun(dists[0].Distribute(Pipeline(scan, Gather()), addrs...))
+    require.NoError(t, err)
+
+    values := data.At(0).Strings()
+    sort.Strings(values)
+    require.Equal(t, []string{"1", "2", "3", "4", "5"}, values)
+}
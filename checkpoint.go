@@ -0,0 +1,137 @@
+package ep
+
+import (
+    "io"
+    "os"
+    "bufio"
+    "context"
+    "net/url"
+    "encoding/gob"
+)
+
+var _ = registerGob(&checkpoint{})
+
+// Checkpoint returns a Runner that persists the output of the provided runner
+// to a file at the path, while emitting it. Once the runner completes
+// successfully, the checkpoint is complete, and all of the subsequent runs
+// (for example, when re-running a long pipeline after a failure) replay it
+// from the file instead of re-running the runner, ignoring their input. An
+// incomplete checkpoint of a failed run is discarded. Delete the file in order
+// to recompute it.
+//
+// When distributed, every node persists its own local output, to the path
+// suffixed by the node's address.
+func Checkpoint(path string, r Runner) Runner {
+    return &checkpoint{path, r}
+}
+
+type checkpoint struct {
+    Path string
+    Runner Runner
+}
+
+func (r *checkpoint) Returns() []Type { return r.Runner.Returns() }
+func (r *checkpoint) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    path := r.Path
+    if thisNode, ok := ctx.Value("ep.ThisNode").(string); ok {
+        path += "." + url.QueryEscape(thisNode)
+    }
+
+    if _, err = os.Stat(path); err == nil {
+        for _ = range inp {}
+        return replayFile(ctx, path, out) // complete checkpoint
+    } else if !os.IsNotExist(err) {
+        return err
+    }
+
+    // write into a temporary file, and rename it when complete. This way, the
+    // checkpoint file exists only if it's complete.
+    tmp := path + ".tmp"
+    f, err := os.Create(tmp)
+    if err != nil {
+        return err
+    }
+
+    defer func() {
+        if f != nil {
+            f.Close()
+            os.Remove(tmp)
+        }
+    }()
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var err1 error
+    res := make(chan Dataset)
+    go func() {
+        defer close(res)
+        err1 = runSafe(ctx, r.Runner, inp, res)
+    }()
+
+    // the runner error takes precedence, as it might be the cause
+    defer func() {
+        for _ = range res {}
+        if err1 != nil {
+            err = err1
+        }
+    }()
+
+    w := bufio.NewWriter(f)
+    enc := gob.NewEncoder(w)
+    for data := range res {
+        var batch Data = data
+        err = enc.Encode(&batch)
+        if err != nil {
+            return err
+        }
+
+        out <- data
+    }
+
+    if err1 != nil {
+        return err1
+    }
+
+    err = w.Flush()
+    if err == nil {
+        err = f.Sync()
+    }
+
+    if err == nil {
+        err = f.Close()
+    }
+
+    if err != nil {
+        return err
+    }
+
+    f = nil
+    return os.Rename(tmp, path)
+}
+
+// replayFile emits the gob-encoded batches of the file
+func replayFile(ctx context.Context, name string, out chan Dataset) error {
+    f, err := os.Open(name)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    dec := gob.NewDecoder(bufio.NewReader(f))
+    for {
+        var batch Data
+        err = dec.Decode(&batch)
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+
+        out <- batch.(Dataset)
+    }
+}
@@ -0,0 +1,40 @@
+package ep
+
+import (
+    "os"
+    "fmt"
+    "testing"
+    "io/ioutil"
+    "path/filepath"
+    "github.com/stretchr/testify/require"
+)
+
+func TestCheckpoint(t *testing.T) {
+    dir, err := ioutil.TempDir("", "ep-checkpoint")
+    require.NoError(t, err)
+    defer os.RemoveAll(dir)
+
+    path := filepath.Join(dir, "checkpoint")
+    r := &flaky{Failures: 1}
+    runner := Checkpoint(path, r)
+
+    // incomplete checkpoints are discarded
+    _, err = testRun(runner, NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+    require.Error(t, err)
+
+    _, err = os.Stat(path)
+    require.True(t, os.IsNotExist(err))
+
+    for i := 0; i < 2; i++ {
+        data, err := testRun(runner, NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+        require.NoError(t, err)
+        require.Equal(t, "[[a b]]", fmt.Sprintf("%v", data))
+    }
+
+    // the complete checkpoint is replayed
+    require.Equal(t, 2, r.Runs)
+
+    files, err := ioutil.ReadDir(dir)
+    require.NoError(t, err)
+    require.Equal(t, 1, len(files))
+}
@@ -0,0 +1,173 @@
+// Package cluster wraps the ep Distributer with the boilerplate needed for
+// standing up a standalone worker node: configuration loading, signal
+// handling, a health endpoint and graceful shutdown. A complete worker binary
+// is just:
+//
+//      func main() {
+//          cfg, err := cluster.LoadConfig("worker.json")
+//          if err != nil {
+//              log.Fatal(err)
+//          }
+//
+//          log.Fatal(cluster.Run(cfg)) // blocks until SIGINT or SIGTERM
+//      }
+//
+// Note that all of the Runners and Types used by the distributed plans must be
+// linked (and thus registered) into the worker binary as well.
+package cluster
+
+import (
+    "os"
+    "net"
+    "fmt"
+    "syscall"
+    "net/http"
+    "os/signal"
+    "encoding/json"
+    "github.com/panoplyio/ep"
+)
+
+// Config of a worker node
+type Config struct {
+    // Addr is the advertised address of this node, as used by its peers
+    Addr string `json:"addr"`
+
+    // Listen is the address to bind, when it differs from the advertised
+    // address (for example behind a NAT). Defaults to Addr
+    Listen string `json:"listen"`
+
+    // ListenAlso are additional addresses to bind, like an IPv6 address in
+    // addition to an IPv4 one, or a Unix domain socket ("unix:/path")
+    ListenAlso []string `json:"listen_also"`
+
+    // HealthAddr is the address of the HTTP health endpoint. Empty disables it
+    HealthAddr string `json:"health_addr"`
+}
+
+// LoadConfig reads a JSON configuration file
+func LoadConfig(path string) (*Config, error) {
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+
+    defer f.Close()
+
+    cfg := &Config{}
+    err = json.NewDecoder(f).Decode(cfg)
+    if err != nil {
+        return nil, fmt.Errorf("cluster: invalid config %s: %s", path, err)
+    }
+
+    if cfg.Addr == "" {
+        return nil, fmt.Errorf("cluster: missing addr in config %s", path)
+    }
+
+    return cfg, nil
+}
+
+// Node is a running worker node
+type Node struct {
+    ep.Distributer
+    health *http.Server
+    errs chan error
+}
+
+// Start a new worker node with the given configuration. The Distributer and
+// health endpoint are served in the background until Close() is called.
+func Start(cfg *Config) (*Node, error) {
+    bind := cfg.Listen
+    if bind == "" {
+        bind = cfg.Addr
+    }
+
+    lns := []net.Listener{}
+    for _, addr := range append([]string{bind}, cfg.ListenAlso...) {
+        ln, err := ep.Listen(addr)
+        if err != nil {
+            for _, ln := range lns {
+                ln.Close()
+            }
+            return nil, err
+        }
+        lns = append(lns, ln)
+    }
+
+    n := &Node{Distributer: ep.NewDistributer(cfg.Addr, lns...), errs: make(chan error, 2)}
+    go func() { n.errs <- n.Distributer.Start() }()
+
+    if cfg.HealthAddr != "" {
+        hln, err := net.Listen("tcp", cfg.HealthAddr)
+        if err != nil {
+            n.Distributer.Close()
+            return nil, err
+        }
+
+        mux := http.NewServeMux()
+        mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+            fmt.Fprintln(w, "OK")
+        })
+
+        n.health = &http.Server{Handler: mux}
+        go func() { n.errs <- n.health.Serve(hln) }()
+    }
+
+    return n, nil
+}
+
+// Wait blocks until the node is interrupted by SIGINT or SIGTERM, or until it
+// fails to serve. When interrupted the node is drained (see Drain), otherwise
+// it's closed.
+func (n *Node) Wait() error {
+    sigs := make(chan os.Signal, 1)
+    signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+    defer signal.Stop(sigs)
+    return n.wait(sigs)
+}
+
+func (n *Node) wait(sigs chan os.Signal) error {
+    var err error
+    select {
+    case <- sigs:
+        return n.Drain()
+    case err = <- n.errs:
+    }
+
+    err1 := n.Close()
+    if err == nil {
+        err = err1
+    }
+
+    return err
+}
+
+// Drain the node, such that the masters stop distributing runners to it, and
+// close it once its running runners complete. See ep.Distributer
+func (n *Node) Drain() error {
+    err := n.Distributer.Drain()
+    if n.health != nil {
+        n.health.Close()
+    }
+
+    return err
+}
+
+// Close the node, stop listening for incoming runners and shut down the
+// health endpoint
+func (n *Node) Close() error {
+    if n.health != nil {
+        n.health.Close()
+    }
+
+    return n.Distributer.Close()
+}
+
+// Run starts a worker node and blocks until it's interrupted.
+func Run(cfg *Config) error {
+    n, err := Start(cfg)
+    if err != nil {
+        return err
+    }
+
+    return n.Wait()
+}
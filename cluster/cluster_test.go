@@ -0,0 +1,77 @@
+package cluster
+
+import (
+    "os"
+    "time"
+    "context"
+    "syscall"
+    "testing"
+    "net/http"
+    "io/ioutil"
+    "path/filepath"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+    dir, err := ioutil.TempDir("", "ep-cluster")
+    require.NoError(t, err)
+    defer os.RemoveAll(dir)
+
+    path := filepath.Join(dir, "worker.json")
+    err = ioutil.WriteFile(path, []byte(`{"addr": ":5561", "health_addr": ":5562"}`), 0644)
+    require.NoError(t, err)
+
+    cfg, err := LoadConfig(path)
+    require.NoError(t, err)
+    require.Equal(t, ":5561", cfg.Addr)
+    require.Equal(t, ":5562", cfg.HealthAddr)
+
+    err = ioutil.WriteFile(path, []byte(`{}`), 0644)
+    require.NoError(t, err)
+
+    _, err = LoadConfig(path)
+    require.Error(t, err)
+}
+
+func TestHealthAndShutdown(t *testing.T) {
+    n, err := Start(&Config{Addr: ":5561", HealthAddr: ":5562"})
+    require.NoError(t, err)
+
+    res, err := http.Get("http://localhost:5562/health")
+    require.NoError(t, err)
+    res.Body.Close()
+    require.Equal(t, http.StatusOK, res.StatusCode)
+
+    // graceful shutdown on SIGTERM
+    sigs := make(chan os.Signal, 1)
+    sigs <- syscall.SIGTERM
+    err = n.wait(sigs)
+    require.NoError(t, err)
+
+    _, err = http.Get("http://localhost:5562/health")
+    require.Error(t, err)
+}
+
+func TestListenAlso(t *testing.T) {
+    dir, err := ioutil.TempDir("", "ep-cluster")
+    require.NoError(t, err)
+    defer os.RemoveAll(dir)
+
+    sock := "unix:" + filepath.Join(dir, "worker.sock")
+    n, err := Start(&Config{Addr: ":5561", ListenAlso: []string{sock}})
+    require.NoError(t, err)
+    defer n.Close()
+
+    // reachable through both of the listeners
+    ln, err := ep.Listen("unix:" + filepath.Join(dir, "master.sock"))
+    require.NoError(t, err)
+
+    master := ep.NewDistributer("unix:" + filepath.Join(dir, "master.sock"), ln)
+    defer master.Close()
+    go master.Start()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    require.NoError(t, master.WaitForPeers(ctx, ":5561", sock))
+}
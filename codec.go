@@ -0,0 +1,373 @@
+package ep
+
+import (
+    "fmt"
+    "math"
+    "bytes"
+    "time"
+    "encoding/gob"
+    "encoding/binary"
+    "github.com/satori/go.uuid"
+)
+
+// The built-in Data types implement gob.GobEncoder and gob.GobDecoder with a
+// compact binary encoding, instead of gob's reflection of every value: the
+// number of values followed by their contiguous fixed-width values, or, for
+// variable-width values, their lengths followed by their contiguous bytes.
+// This is the encoding used for transmitting data between nodes, see
+// Distributer. The decoded buffers are taken from the pool, see Release.
+
+// encoding modes of strings
+const (
+    strsPlain = 0
+    strsDict = 1
+)
+
+// GobEncode implements gob.GobEncoder. Strings of low cardinality are
+// automatically dictionary-encoded: the distinct values are encoded once,
+// followed by the code of every value (see Enums).
+func (vs Strs) GobEncode() ([]byte, error) {
+    dict, codes := dictEncode(vs)
+    if dict == nil {
+        return appendStrs([]byte{strsPlain}, vs), nil
+    }
+
+    b := appendStrs([]byte{strsDict}, dict)
+    return appendCodes(b, codes), nil
+}
+
+// GobDecode implements gob.GobDecoder. All of the strings share a single
+// allocation, or in dictionary-encoding, a single allocation per distinct
+// value
+func (vs *Strs) GobDecode(b []byte) error {
+    if len(b) == 0 {
+        return errCorrupt("strings")
+    }
+
+    mode := b[0]
+    strs, b, err := decodeStrs(b[1:])
+    if err != nil {
+        return err
+    } else if mode == strsPlain && len(b) == 0 {
+        *vs = strs
+        return nil
+    } else if mode != strsDict {
+        return errCorrupt("strings")
+    }
+
+    codes, err := decodeCodes(b, len(strs))
+    if err != nil {
+        return err
+    }
+
+    res := Strs(GetBuffer[string](len(codes)))
+    for i, code := range codes {
+        res[i] = strs[code]
+    }
+
+    PutBuffer(codes)
+    *vs = res
+    return nil
+}
+
+// GobEncode implements gob.GobEncoder, as the dictionary followed by the codes
+func (vs Enums) GobEncode() ([]byte, error) {
+    return appendCodes(appendStrs(nil, vs.Dict), vs.Codes), nil
+}
+
+// GobDecode implements gob.GobDecoder
+func (vs *Enums) GobDecode(b []byte) error {
+    dict, b, err := decodeStrs(b)
+    if err != nil {
+        return err
+    }
+
+    codes, err := decodeCodes(b, len(dict))
+    if err != nil {
+        return err
+    }
+
+    *vs = Enums{dict, codes}
+    return nil
+}
+
+// dictEncode returns the distinct strings and the code of every string, or
+// nil if their cardinality isn't low enough for dictionary-encoding to be
+// worthwhile
+func dictEncode(strs []string) ([]string, []uint32) {
+    const minRows = 16 // not worthwhile for small batches
+    if len(strs) < minRows {
+        return nil, nil
+    }
+
+    dict := []string{}
+    codes := make([]uint32, len(strs))
+    index := map[string]uint32{}
+    for i, s := range strs {
+        code, ok := index[s]
+        if !ok {
+            if len(dict) * 4 >= len(strs) {
+                return nil, nil // at least a quarter of the values are distinct
+            }
+
+            code = uint32(len(dict))
+            index[s] = code
+            dict = append(dict, s)
+        }
+        codes[i] = code
+    }
+    return dict, codes
+}
+
+// appendStrs appends the encoding of the strings: their number, lengths and
+// contiguous bytes
+func appendStrs(b []byte, strs []string) []byte {
+    size := 0
+    for _, s := range strs {
+        size += len(s)
+    }
+
+    b = append(make([]byte, 0, len(b) + binary.MaxVarintLen64 * (len(strs) + 1) + size), b...)
+    b = binary.AppendUvarint(b, uint64(len(strs)))
+    for _, s := range strs {
+        b = binary.AppendUvarint(b, uint64(len(s)))
+    }
+
+    for _, s := range strs {
+        b = append(b, s...)
+    }
+    return b
+}
+
+// decodeStrs decodes strings encoded with appendStrs, and returns the rest of
+// the bytes
+func decodeStrs(b []byte) (Strs, []byte, error) {
+    n, b, err := decodeLen(b, 1)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    lens := GetBuffer[int](n)
+    defer PutBuffer(lens)
+
+    size := 0
+    for i := range lens {
+        l, k := binary.Uvarint(b)
+        if k <= 0 || l > uint64(len(b)) {
+            return nil, nil, errCorrupt("strings")
+        }
+
+        lens[i], b = int(l), b[k:]
+        size += lens[i]
+    }
+
+    if size > len(b) {
+        return nil, nil, errCorrupt("strings")
+    }
+
+    all := string(b[:size])
+    res := Strs(GetBuffer[string](n))
+    for i, l := range lens {
+        res[i], all = all[:l], all[l:]
+    }
+    return res, b[size:], nil
+}
+
+func appendCodes(b []byte, codes []uint32) []byte {
+    b = binary.AppendUvarint(b, uint64(len(codes)))
+    for _, code := range codes {
+        b = binary.AppendUvarint(b, uint64(code))
+    }
+    return b
+}
+
+// decodeCodes decodes dictionary codes encoded with appendCodes, verifying
+// that they're within the dictionary
+func decodeCodes(b []byte, dict int) ([]uint32, error) {
+    n, b, err := decodeLen(b, 1)
+    if err != nil {
+        return nil, err
+    }
+
+    codes := GetBuffer[uint32](n)
+    for i := range codes {
+        code, k := binary.Uvarint(b)
+        if k <= 0 || code >= uint64(dict) {
+            return nil, errCorrupt("codes")
+        }
+        codes[i], b = uint32(code), b[k:]
+    }
+
+    if len(b) != 0 {
+        return nil, errCorrupt("codes")
+    }
+    return codes, nil
+}
+
+// GobEncode implements gob.GobEncoder, as a bitmap
+func (vs Bools) GobEncode() ([]byte, error) {
+    b := binary.AppendUvarint(nil, uint64(len(vs)))
+    bits := make([]byte, (len(vs) + 7) / 8)
+    for i, v := range vs {
+        if v {
+            bits[i / 8] |= 1 << uint(i % 8)
+        }
+    }
+    return append(b, bits...), nil
+}
+
+// GobDecode implements gob.GobDecoder
+func (vs *Bools) GobDecode(b []byte) error {
+    n, b, err := decodeLen(b, 0)
+    if err != nil {
+        return err
+    } else if len(b) != (n + 7) / 8 {
+        return errCorrupt("bools")
+    }
+
+    res := Bools(GetBuffer[bool](n))
+    for i := range res {
+        res[i] = b[i / 8] & (1 << uint(i % 8)) != 0
+    }
+
+    *vs = res
+    return nil
+}
+
+// GobEncode implements gob.GobEncoder. The built-in Ints, Floats and UUIDs are
+// encoded as contiguous fixed-width values, while other types are encoded with
+// gob
+func (vs Column[T]) GobEncode() ([]byte, error) {
+    switch vs := any(vs).(type) {
+    case Ints:
+        return encodeInts(vs), nil
+    case Floats:
+        ints := make([]int64, len(vs))
+        for i, v := range vs {
+            ints[i] = int64(math.Float64bits(v))
+        }
+        return encodeInts(ints), nil
+    case UUIDs:
+        b := binary.AppendUvarint(nil, uint64(len(vs)))
+        for _, v := range vs {
+            b = append(b, v[:]...)
+        }
+        return b, nil
+    }
+
+    var buf bytes.Buffer
+    err := gob.NewEncoder(&buf).Encode([]T(vs))
+    return buf.Bytes(), err
+}
+
+// GobDecode implements gob.GobDecoder
+func (vs *Column[T]) GobDecode(b []byte) error {
+    switch vs := any(vs).(type) {
+    case *Ints:
+        ints, err := decodeInts(b)
+        *vs = ints
+        return err
+    case *Floats:
+        ints, err := decodeInts(b)
+        res := Floats(GetBuffer[float64](len(ints)))
+        for i, v := range ints {
+            res[i] = math.Float64frombits(uint64(v))
+        }
+        PutBuffer(ints)
+        *vs = res
+        return err
+    case *UUIDs:
+        n, b, err := decodeLen(b, uuid.Size)
+        if err != nil {
+            return err
+        } else if len(b) != n * uuid.Size {
+            return errCorrupt("uuids")
+        }
+
+        res := make(UUIDs, n)
+        for i := range res {
+            copy(res[i][:], b[i * uuid.Size:])
+        }
+        *vs = res
+        return nil
+    }
+
+    var res []T
+    err := gob.NewDecoder(bytes.NewReader(b)).Decode(&res)
+    *vs = res
+    return err
+}
+
+// GobEncode implements gob.GobEncoder
+func (vs Times) GobEncode() ([]byte, error) { return encodeInts(vs), nil }
+
+// GobDecode implements gob.GobDecoder
+func (vs *Times) GobDecode(b []byte) error {
+    ints, err := decodeInts(b)
+    *vs = ints
+    return err
+}
+
+// GobEncode implements gob.GobEncoder
+func (vs Intervals) GobEncode() ([]byte, error) {
+    ints := make([]int64, len(vs))
+    for i, v := range vs {
+        ints[i] = int64(v)
+    }
+    return encodeInts(ints), nil
+}
+
+// GobDecode implements gob.GobDecoder
+func (vs *Intervals) GobDecode(b []byte) error {
+    ints, err := decodeInts(b)
+    res := make(Intervals, len(ints))
+    for i, v := range ints {
+        res[i] = time.Duration(v)
+    }
+    *vs = res
+    return err
+}
+
+func encodeInts(vs []int64) []byte {
+    b := make([]byte, 0, binary.MaxVarintLen64 + 8 * len(vs))
+    b = binary.AppendUvarint(b, uint64(len(vs)))
+    for _, v := range vs {
+        b = binary.LittleEndian.AppendUint64(b, uint64(v))
+    }
+    return b
+}
+
+func decodeInts(b []byte) ([]int64, error) {
+    n, b, err := decodeLen(b, 8)
+    if err != nil {
+        return nil, err
+    } else if len(b) != n * 8 {
+        return nil, errCorrupt("integers")
+    }
+
+    res := GetBuffer[int64](n)
+    for i := range res {
+        res[i] = int64(binary.LittleEndian.Uint64(b[i * 8:]))
+    }
+    return res, nil
+}
+
+// decodeLen decodes the number of encoded values, verifying that it doesn't
+// exceed the remaining bytes given the minimum size of every value, in order
+// to not over-allocate for corrupt inputs
+func decodeLen(b []byte, size int) (int, []byte, error) {
+    n, k := binary.Uvarint(b)
+    if k <= 0 {
+        return 0, nil, errCorrupt("length")
+    }
+
+    b = b[k:]
+    if size > 0 && n > uint64(len(b) / size) || size == 0 && n > uint64(len(b)) * 8 {
+        return 0, nil, errCorrupt("length")
+    }
+    return int(n), b, nil
+}
+
+func errCorrupt(what string) error {
+    return fmt.Errorf("corrupt encoding of %s", what)
+}
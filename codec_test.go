@@ -0,0 +1,109 @@
+package ep
+
+import (
+    "fmt"
+    "math"
+    "bytes"
+    "time"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+// Tests that the built-in types are encoded and decoded by their binary
+// encoding, including empty data
+func TestCodecRoundTrip(t *testing.T) {
+    uuids, err := ParseUUIDs("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+    require.NoError(t, err)
+
+    all := []Data{
+        Strs{"a", "", "héllo"},
+        Strs{},
+        Ints{1, -1, math.MaxInt64, math.MinInt64},
+        Floats{0.5, -1e100, math.Inf(1)},
+        Bools{true, false, false, true, true, false, true, false, true},
+        Bools{},
+        uuids,
+        NewTimes(time.Unix(1, 2)),
+        Intervals{time.Second, -time.Minute},
+        Column[point]{{1, 2}, {3, 4}},
+        Nullable(Strs{"a", "b"}, Bools{true, false}),
+        NewDataset(Strs{"a"}, Ints{1}),
+    }
+
+    for _, data := range all {
+        var buf bytes.Buffer
+        require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+        var decoded Data
+        require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+        require.Equal(t, fmt.Sprint(data), fmt.Sprint(decoded))
+        require.Equal(t, data.Type().Name(), decoded.Type().Name())
+    }
+}
+
+// Tests that the binary encoding is more compact than gob's reflection
+func TestCodecSize(t *testing.T) {
+    strs := make(Strs, 1000)
+    for i := range strs {
+        strs[i] = "value"
+    }
+
+    b, err := strs.GobEncode()
+    require.NoError(t, err)
+    require.True(t, len(b) < 1000 * 7, len(b))
+
+    b, err = Bools(make([]bool, 1000)).GobEncode()
+    require.NoError(t, err)
+    require.True(t, len(b) < 130, len(b))
+}
+
+func TestCodecCorrupt(t *testing.T) {
+    b, err := Strs{"abc", "de"}.GobEncode()
+    require.NoError(t, err)
+
+    var strs Strs
+    require.Error(t, strs.GobDecode(b[:len(b) - 1]))
+    require.Error(t, strs.GobDecode(nil))
+    require.Error(t, strs.GobDecode([]byte{0xff, 0xff, 0xff, 0xff, 0x0f}))
+
+    b, err = Ints{1, 2}.GobEncode()
+    require.NoError(t, err)
+
+    var ints Ints
+    require.Error(t, ints.GobDecode(b[:len(b) - 1]))
+    require.Error(t, ints.GobDecode(append(b, 0)))
+
+    var bools Bools
+    require.EqualError(t, bools.GobDecode([]byte{100}), "corrupt encoding of length")
+}
+
+// Tests that low-cardinality strings are dictionary-encoded
+func TestCodecDict(t *testing.T) {
+    strs := make(Strs, 1000)
+    for i := range strs {
+        strs[i] = []string{"category-a", "category-b", "category-c"}[i % 3]
+    }
+
+    b, err := strs.GobEncode()
+    require.NoError(t, err)
+    require.Equal(t, byte(strsDict), b[0])
+    require.True(t, len(b) < 1100, len(b))
+
+    var decoded Strs
+    require.NoError(t, decoded.GobDecode(b))
+    require.Equal(t, strs, decoded)
+
+    // codes out of the dictionary
+    b[len(b) - 1] = 3
+    require.EqualError(t, decoded.GobDecode(b), "corrupt encoding of codes")
+
+    // high cardinality
+    for i := range strs {
+        strs[i] = fmt.Sprint(i % 500)
+    }
+
+    b, err = strs.GobEncode()
+    require.NoError(t, err)
+    require.Equal(t, byte(strsPlain), b[0])
+}
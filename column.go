@@ -0,0 +1,143 @@
+package ep
+
+import (
+    "fmt"
+    "reflect"
+    "sync/atomic"
+)
+
+// registered ColumnTypes by the Go type of their values, keyed by a nil
+// pointer to the type. It's only modified by NewType, when initializing
+var columnTypes = map[interface{}]interface{}{}
+
+// lastColumnType caches the last looked up ColumnType, as the same one is
+// usually looked up repeatedly, like when sorting. See columnTypeOf
+var lastColumnType atomic.Value
+type cachedType struct { t interface{} }
+
+// TypeOps defines the operations of a column Type of values of the Go type T,
+// for adding new types in a few lines, without implementing the Data and Type
+// interfaces. See NewType. Several built-in types (like Ints, Floats and UUIDs)
+// are implemented this way.
+type TypeOps[T any] struct {
+
+    // Name of the type
+    Name string
+
+    // Size of a single value in bytes, or -1 for variable width values. See
+    // TypeMeta
+    Size int
+
+    // Less compares two values, used for sorting
+    Less func(a, b T) bool
+
+    // String returns the string representation of a value. Optional, defaults
+    // to fmt.Sprint
+    String func(v T) string
+
+    // Hash returns a hash of a value, consistent with Less: equal values must
+    // have equal hashes. Optional, defaults to hashing the string
+    // representation
+    Hash func(v T) uint64
+}
+
+// NewType registers the operations of the Go type T, and returns the Type of its
+// values. The Data of the type is Column[T], which is serialized with gob, thus
+// T must be serializable with gob. Every Go type may only be registered once,
+// typically into a global variable:
+//
+//      type point struct { X, Y int }
+//      var Point = ep.NewType(ep.TypeOps[point]{Name: "point", Less: ...})
+//      var data ep.Data = ep.Column[point]{{1, 2}, {3, 4}}
+func NewType[T any](ops TypeOps[T]) *ColumnType[T] {
+    rt := reflect.TypeOf((*T)(nil)).Elem()
+    if _, ok := columnTypes[(*T)(nil)]; ok {
+        panic(fmt.Sprintf("type %s is already registered", rt))
+    } else if ops.Less == nil {
+        panic(fmt.Sprintf("type %s requires a Less function", rt))
+    }
+
+    if ops.String == nil {
+        ops.String = func(v T) string { return fmt.Sprint(v) }
+    }
+
+    if ops.Hash == nil {
+        str := ops.String
+        ops.Hash = func(v T) uint64 { return HashString(str(v)) }
+    }
+
+    t := &ColumnType[T]{&ops}
+    columnTypes[(*T)(nil)] = t
+    registerGob(&ColumnType[T]{}, Column[T]{})
+    return t
+}
+
+// columnTypeOf returns the ColumnType of T, as registered by NewType. The
+// lookup avoids reflection, as it's used by the operations of every value
+func columnTypeOf[T any]() *ColumnType[T] {
+    if c, ok := lastColumnType.Load().(*cachedType); ok {
+        if t, ok := c.t.(*ColumnType[T]); ok {
+            return t
+        }
+    }
+
+    t, ok := columnTypes[(*T)(nil)].(*ColumnType[T])
+    if !ok {
+        panic(fmt.Sprintf("unregistered column type %s", reflect.TypeOf((*T)(nil)).Elem()))
+    }
+
+    lastColumnType.Store(&cachedType{t})
+    return t
+}
+
+// opsOf returns the registered operations of T
+func opsOf[T any]() *TypeOps[T] { return columnTypeOf[T]().ops }
+
+// ColumnType is the Type of a Column of values of T, as registered by NewType.
+// Like the other built-in types, it's a singleton: Column[T].Type() returns
+// the same instance that NewType has returned
+type ColumnType[T any] struct { ops *TypeOps[T] }
+func (t *ColumnType[T]) Name() string { return t.opsOf().Name }
+func (*ColumnType[T]) Data(n uint) Data { return make(Column[T], n) }
+func (t *ColumnType[T]) BaseName() string { return t.opsOf().Name }
+func (*ColumnType[T]) Parameters() []int { return nil }
+func (t *ColumnType[T]) Size() int { return t.opsOf().Size }
+
+// the operations of a decoded type, or of a zero value, are looked up
+func (t *ColumnType[T]) opsOf() *TypeOps[T] {
+    if t.ops != nil {
+        return t.ops
+    }
+    return opsOf[T]()
+}
+
+// GobEncode implements gob.GobEncoder. The operations aren't transmitted, as
+// they're registered by NewType on every node
+func (*ColumnType[T]) GobEncode() ([]byte, error) { return []byte{}, nil }
+
+// GobDecode implements gob.GobDecoder
+func (t *ColumnType[T]) GobDecode([]byte) error {
+    t.ops = opsOf[T]()
+    return nil
+}
+
+// Column is a generic Data implementation of values of T, using the operations
+// registered by NewType
+type Column[T any] []T
+func (Column[T]) Type() Type { return columnTypeOf[T]() }
+func (vs Column[T]) Len() int { return len(vs) }
+func (vs Column[T]) Less(i, j int) bool { return opsOf[T]().Less(vs[i], vs[j]) }
+func (vs Column[T]) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs Column[T]) Slice(s, e int) Data { return vs[s:e] }
+func (vs Column[T]) Append(o Data) Data { return append(vs, o.(Column[T])...) }
+// HashAt returns the hash of the value at index i. See TypeOps.Hash
+func (vs Column[T]) HashAt(i int) uint64 { return opsOf[T]().Hash(vs[i]) }
+
+func (vs Column[T]) Strings() []string {
+    str := opsOf[T]().String
+    strs := make([]string, len(vs))
+    for i, v := range vs {
+        strs[i] = str(v)
+    }
+    return strs
+}
@@ -0,0 +1,73 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+// a user-defined type of 2D points, ordered by their distance from the origin
+type point struct { X, Y int }
+
+var Point = NewType(TypeOps[point]{
+    Name: "point",
+    Size: 16,
+    Less: func(a, b point) bool { return a.X * a.X + a.Y * a.Y < b.X * b.X + b.Y * b.Y },
+    String: func(v point) string { return fmt.Sprintf("(%d,%d)", v.X, v.Y) },
+})
+
+func ExampleNewType() {
+    var points Data = Column[point]{{3, 4}, {1, 1}, {0, 2}}
+    sort.Sort(points)
+    fmt.Println(points.Strings(), points.Type().Name(), Meta(points.Type()).Size())
+
+    // Output: [(1,1) (0,2) (3,4)] point 16
+}
+
+func TestColumnRunners(t *testing.T) {
+    data := NewDataset(Column[point]{{3, 4}, {1, 1}, {3, 4}}, Strs{"a", "b", "c"})
+    res, err := testRun(Pipeline(GroupBy([]int{0}, Count()), Sort([]SortKey{{Col: 0}})), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[{1 1} {3 4}] [1 2]]", fmt.Sprint(res))
+    require.Equal(t, Point, res.At(0).Type())
+    require.True(t, Ints{}.Type() == Int)
+    require.True(t, Column[point]{}.Type() == Point)
+
+    require.Equal(t, Column[point]{}, Clone(Point.Data(0)))
+    require.Equal(t, Ints{1, 2}.HashAt(0), Ints{1}.HashAt(0))
+    require.NotEqual(t, Ints{1, 2}.HashAt(0), Ints{1, 2}.HashAt(1))
+    require.Panics(t, func() { NewType(TypeOps[point]{Name: "again"}) })
+}
+
+// columns are serializable, in order to be distributed
+func TestColumnGob(t *testing.T) {
+    var data Data = NewDataset(Column[point]{{1, 2}})
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+
+    // the types are decoded with their operations
+    var typ Type = Point
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&typ))
+    typ = nil
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&typ))
+    require.Equal(t, "point", typ.Name())
+}
+
+func BenchmarkColumnSort(b *testing.B) {
+    data := make(Ints, 1 << 16)
+    for i := 0; i < b.N; i++ {
+        b.StopTimer()
+        for j := range data {
+            data[j] = int64((j * 7919) % len(data))
+        }
+        b.StartTimer()
+        sort.Sort(data)
+    }
+}
@@ -0,0 +1,185 @@
+package ep
+
+import (
+    "math/big"
+    "reflect"
+)
+
+// Comparer is optionally implemented by Data types in order to compare values
+// across Data instances without copying them. Data types that don't implement
+// it are compared by appending both values into a single Data, and using its
+// Less(). See CompareAt
+type Comparer interface {
+
+    // Compare the value at index i with the value at index j of the other data,
+    // which is always of the same Go type. Returns -1, 0 or 1 if the value is
+    // lower than, equal to, or greater than the other value, consistent with
+    // Less
+    Compare(i int, other Data, j int) int
+}
+
+// CompareAt compares the value at index i of a with the value at index j of b,
+// returning -1, 0 or 1. Nulls are ordered before all other values, like they
+// are sorted (see Nullable). See Comparer
+func CompareAt(a Data, i int, b Data, j int) int {
+    if vs, ok := a.(nullable); ok {
+        if !vs.Valid[i] {
+            return compareNulls(true, isNullAt(b, j))
+        }
+        a = vs.Values
+    } else if isNullAt(a, i) {
+        return compareNulls(true, isNullAt(b, j))
+    }
+
+    if vs, ok := b.(nullable); ok {
+        if !vs.Valid[j] {
+            return 1
+        }
+        b = vs.Values
+    } else if isNullAt(b, j) {
+        return 1
+    }
+
+    if c, ok := a.(Comparer); ok && reflect.TypeOf(a) == reflect.TypeOf(b) {
+        return c.Compare(i, b, j)
+    }
+
+    both := appendData(Clone(a.Slice(i, i + 1)), b.Slice(j, j + 1))
+    if both.Less(0, 1) {
+        return -1
+    } else if both.Less(1, 0) {
+        return 1
+    }
+    return 0
+}
+
+func compareNulls(a, b bool) int {
+    if a && b {
+        return 0
+    } else if a {
+        return -1
+    }
+    return 1
+}
+
+// Comparator compares row i of dataset a with row j of dataset b, returning -1,
+// 0 or 1. See CompareBy
+type Comparator func(a Dataset, i int, b Dataset, j int) int
+
+// CompareBy returns a Comparator of rows by the values of the sort keys, in
+// order, used for sorting and merging sorted datasets. Descending keys reverse
+// the order of their values.
+func CompareBy(keys []SortKey) Comparator {
+    return func(a Dataset, i int, b Dataset, j int) int {
+        return compareBy(keys, a, i, b, j)
+    }
+}
+
+func compareBy(keys []SortKey, a Dataset, i int, b Dataset, j int) int {
+    for _, k := range keys {
+        c := CompareAt(a.At(k.Col), i, b.At(k.Col), j)
+        if c != 0 && k.Desc {
+            return -c
+        } else if c != 0 {
+            return c
+        }
+    }
+    return 0
+}
+
+// compareKeys compares the keys of row i in dataset a with the keys of row j
+// in dataset b, returning -1, 0 or 1
+func compareKeys(a Dataset, i int, aKeys []int, b Dataset, j int, bKeys []int) int {
+    for k := range aKeys {
+        c := CompareAt(a.At(aKeys[k]), i, b.At(bKeys[k]), j)
+        if c != 0 {
+            return c
+        }
+    }
+    return 0
+}
+
+func compareStrings(a, b string) int {
+    if a < b {
+        return -1
+    } else if a > b {
+        return 1
+    }
+    return 0
+}
+
+func compareInts(a, b int64) int {
+    if a < b {
+        return -1
+    } else if a > b {
+        return 1
+    }
+    return 0
+}
+
+// Compare implements Comparer
+func (vs Strs) Compare(i int, other Data, j int) int {
+    return compareStrings(vs[i], other.(Strs)[j])
+}
+
+// Compare implements Comparer. False is ordered before true
+func (vs Bools) Compare(i int, other Data, j int) int {
+    a, b := vs[i], other.(Bools)[j]
+    if a == b {
+        return 0
+    } else if b {
+        return -1
+    }
+    return 1
+}
+
+// Compare implements Comparer, using the Less function of the type. See
+// TypeOps
+func (vs Column[T]) Compare(i int, other Data, j int) int {
+    less := opsOf[T]().Less
+    a, b := vs[i], other.(Column[T])[j]
+    if less(a, b) {
+        return -1
+    } else if less(b, a) {
+        return 1
+    }
+    return 0
+}
+
+// Compare implements Comparer
+func (vs Times) Compare(i int, other Data, j int) int {
+    return compareInts(vs[i], other.(Times)[j])
+}
+
+// Compare implements Comparer
+func (vs Intervals) Compare(i int, other Data, j int) int {
+    return compareInts(int64(vs[i]), int64(other.(Intervals)[j]))
+}
+
+// Compare implements Comparer
+func (vs JSONs) Compare(i int, other Data, j int) int {
+    return compareStrings(vs[i], other.(JSONs)[j])
+}
+
+// Compare implements Comparer, by the values of the enums regardless of their
+// dictionaries
+func (vs Enums) Compare(i int, other Data, j int) int {
+    o := other.(Enums)
+    if sameDict(vs, o) && vs.Codes[i] == o.Codes[j] {
+        return 0
+    }
+    return compareStrings(vs.Dict[vs.Codes[i]], o.Dict[o.Codes[j]])
+}
+
+// Compare implements Comparer. Decimals of different scales are compared by
+// their exact values
+func (vs Decimals) Compare(i int, other Data, j int) int {
+    o := other.(Decimals)
+    if vs.Scale == o.Scale {
+        return compareInts(vs.Values[i], o.Values[j])
+    }
+
+    a := new(big.Rat).SetFrac(big.NewInt(vs.Values[i]), pow10(vs.Scale))
+    b := new(big.Rat).SetFrac(big.NewInt(o.Values[j]), pow10(o.Scale))
+    return a.Cmp(b)
+}
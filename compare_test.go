@@ -0,0 +1,81 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleCompareAt() {
+    values := Nullable(Ints{1, 2}, Bools{true, false})
+    fmt.Println(CompareAt(values, 0, Ints{1}, 0), CompareAt(values, 1, Ints{1}, 0), CompareAt(Strs{"b"}, 0, Strs{"a"}, 0))
+
+    // Output: 0 -1 1
+}
+
+func ExampleCompareBy() {
+    cmp := CompareBy([]SortKey{{0, false}, {1, true}})
+    a := NewDataset(Strs{"a", "a"}, Ints{1, 2})
+    b := NewDataset(Strs{"a", "b"}, Ints{1, 1})
+    fmt.Println(cmp(a, 0, b, 0), cmp(a, 1, b, 0), cmp(a, 1, b, 1))
+
+    // Output: 0 -1 -1
+}
+
+// Tests that comparisons are consistent with Less for all of the built-in
+// types, across Data instances
+func TestCompareAtConsistent(t *testing.T) {
+    dec, err := ParseDecimals(5, 2, "1.50", "-2.25")
+    require.NoError(t, err)
+
+    all := []Data{
+        Strs{"a", "b"},
+        Ints{2, -1},
+        Floats{0.5, 1},
+        Bools{true, false},
+        NewTimes(time.Unix(2, 0), time.Unix(1, 0)),
+        Intervals{time.Second, time.Minute},
+        dec,
+        JSONs{"1", "[]"},
+        NewEnums("y", "x"),
+        NewStructs([]string{"a"}, Ints{2, 1}),
+        NewLists(Int, Ints{1, 2}, Ints{1}),
+        Nullable(Ints{1, 2}, Bools{false, true}),
+        Null.Data(2),
+    }
+
+    for _, data := range all {
+        for i := 0; i < 2; i++ {
+            for j := 0; j < 2; j++ {
+                expected := 0
+                if data.Less(i, j) {
+                    expected = -1
+                } else if data.Less(j, i) {
+                    expected = 1
+                }
+
+                name := data.Type().Name()
+                require.Equal(t, expected, CompareAt(data, i, data, j), "%s %d %d", name, i, j)
+                require.Equal(t, expected, CompareAt(data.Slice(i, i + 1), 0, Clone(data.Slice(j, j + 1)), 0), name)
+            }
+        }
+    }
+}
+
+func TestCompareAtMixed(t *testing.T) {
+    a, err := ParseDecimals(5, 2, "1.50")
+    require.NoError(t, err)
+    b, err := ParseDecimals(5, 1, "1.5", "1.6")
+    require.NoError(t, err)
+    require.Equal(t, 0, CompareAt(a, 0, b, 0))
+    require.Equal(t, -1, CompareAt(a, 0, b, 1))
+
+    // enums of different dictionaries
+    require.Equal(t, 0, CompareAt(NewEnums("x", "y"), 1, NewEnums("y"), 0))
+
+    // nulls are ordered first
+    require.Equal(t, -1, CompareAt(Null.Data(1), 0, Strs{""}, 0))
+    require.Equal(t, 1, CompareAt(Strs{""}, 0, Nullable(Strs{"a"}, Bools{false}), 0))
+    require.Equal(t, 0, CompareAt(Null.Data(1), 0, Nullable(Strs{"a"}, Bools{false}), 0))
+}
@@ -0,0 +1,159 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "context"
+    "encoding/csv"
+)
+
+var _ = registerGob(&csvScan{})
+
+// CSVOptions configure the reading and writing of CSV files. See CSVScan and
+// CSVWrite
+type CSVOptions struct {
+    Comma rune // field delimiter, defaults to ','
+    Header bool // the first line of every file is the names of the columns
+    QuoteAll bool // quote all of the written fields, not just when necessary
+
+    // SplitSize is the number of bytes per file split, for reading large files
+    // in parallel across the cluster. Zero means that every file is a single
+    // split. Splitting assumes that quoted fields don't contain newlines, as
+    // splits are aligned to the lines.
+    SplitSize int64
+}
+
+// CSVScan returns a source Runner that reads the CSV files matching the glob
+// pattern, in order, and emits their rows in batches of up
+// to BatchSize rows, typed by the schema: every field is converted into the
+// type of its column with CastData, and empty fields of non-string columns are
+// nulls. Its input is ignored.
+//
+// When distributed, the files are divided into splits (see CSVOptions), which
+// are deterministically assigned to the participating nodes, such that every
+// split is read exactly once across the cluster. Thus the files must be
+// available to all of the nodes under the same paths, like on shared storage
+// or an object store. The pattern may be the URL of an object store, like
+// "s3://bucket/data/*.csv". See storage.Glob
+func CSVScan(pattern string, schema Schema, opts CSVOptions) Runner {
+    return &csvScan{pattern, schema, opts}
+}
+
+type csvScan struct {
+    Pattern string
+    Schema Schema
+    Options CSVOptions
+}
+
+func (r *csvScan) Returns() []Type {
+    types := []Type{}
+    for _, f := range r.Schema {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (r *csvScan) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    splits, err := globSplits(ctx, r.Pattern, r.Options.SplitSize)
+    if err != nil {
+        return err
+    }
+
+    for _, s := range localSplits(ctx, splits) {
+        err = r.scan(ctx, s, out)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// scan a single split. See openSplit
+func (r *csvScan) scan(ctx context.Context, s fileSplit, out chan Dataset) error {
+    f, br, start, err := openSplit(ctx, s)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    rd := csv.NewReader(br)
+    rd.FieldsPerRecord = len(r.Schema)
+    rd.ReuseRecord = true
+    if r.Options.Comma != 0 {
+        rd.Comma = r.Options.Comma
+    }
+
+    cols := make([]Strs, len(r.Schema))
+    for offset := int64(0); start + offset < s.End; offset = rd.InputOffset() {
+        record, err := rd.Read()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return fmt.Errorf("%s at offset %d: %s", s.Path, start + offset, err)
+        } else if start + offset == 0 && r.Options.Header {
+            continue
+        }
+
+        for i, v := range record {
+            cols[i] = append(cols[i], v)
+        }
+
+        if len(cols[0]) >= BatchSize {
+            err = r.emit(ctx, cols, out)
+            if err != nil {
+                return fmt.Errorf("%s: %s", s.Path, err)
+            }
+            cols = make([]Strs, len(r.Schema))
+        }
+    }
+
+    if len(cols) > 0 && len(cols[0]) > 0 {
+        err = r.emit(ctx, cols, out)
+        if err != nil {
+            return fmt.Errorf("%s: %s", s.Path, err)
+        }
+    }
+    return nil
+}
+
+// emit a batch of the string values of the columns, converted to the types of
+// the schema
+func (r *csvScan) emit(ctx context.Context, cols []Strs, out chan Dataset) error {
+    data := make([]Data, len(cols))
+    names := make([]string, len(cols))
+    for i, f := range r.Schema {
+        var err error
+        data[i], err = parseColumn(cols[i], f.Type)
+        if err != nil {
+            return err
+        }
+        names[i] = f.Name
+    }
+
+    select {
+    case out <- newNamedDataset(names, data...):
+        return nil
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// parseColumn converts the strings into the type, where empty strings of types
+// other than strings are nulls
+func parseColumn(strs Strs, t Type) (Data, error) {
+    if _, ok := unnamed(t).Data(0).(Strs); ok {
+        return strs, nil
+    }
+
+    valid := make(Bools, len(strs))
+    for i, s := range strs {
+        valid[i] = s != ""
+    }
+
+    if allTrue(valid) {
+        return CastData(strs, t)
+    }
+    return CastData(Nullable(strs, valid), t)
+}
@@ -0,0 +1,168 @@
+package ep
+
+import (
+    "os"
+    "fmt"
+    "bytes"
+    "sort"
+    "context"
+    "strings"
+    "testing"
+    "path/filepath"
+    "github.com/panoplyio/ep/storage"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleCSVScan() {
+    dir, _ := os.MkdirTemp("", "ep")
+    defer os.RemoveAll(dir)
+
+    os.WriteFile(filepath.Join(dir, "1.csv"), []byte("name,age\nbob,30\nalice,\n"), 0644)
+    os.WriteFile(filepath.Join(dir, "2.csv"), []byte("name,age\n\"eve, jr\",7\n"), 0644)
+
+    schema := Schema{{"name", Str}, {"age", Int}}
+    runner := CSVScan(filepath.Join(dir, "*.csv"), schema, CSVOptions{Header: true})
+    data, err := testRun(runner)
+    fmt.Println(data, data.Schema(), err)
+
+    // Output: [[bob alice eve, jr] [30 <nil> 7]] (name:string, age:int) <nil>
+}
+
+func TestCSVScanReturns(t *testing.T) {
+    runner := CSVScan("*.csv", Schema{{"name", Str}, {"age", Int}}, CSVOptions{})
+    require.Equal(t, "(name:string, age:int)", SchemaOf(runner.Returns()).String())
+}
+
+// Test that every row is read exactly once by the splits of all of the nodes,
+// regardless of the alignment of the splits to the lines
+func TestCSVScanDistributed(t *testing.T) {
+    dir := t.TempDir()
+    lines := []string{}
+    for i := 0; i < 100; i++ {
+        lines = append(lines, fmt.Sprintf("%d,%s", i, strings.Repeat("x", i % 7)))
+    }
+
+    content := strings.Join(lines, "\n") + "\n"
+    err := os.WriteFile(filepath.Join(dir, "a.csv"), []byte(content), 0644)
+    require.NoError(t, err)
+
+    err = os.WriteFile(filepath.Join(dir, "b.csv"), []byte("100,y\n101,y"), 0644)
+    require.NoError(t, err)
+
+    nodes := []string{":5551", ":5552", ":5553"}
+    schema := Schema{{"id", Int}, {"x", Str}}
+    for _, size := range []int64{0, 1, 2, 5, 13, 64, 1000} {
+        runner := CSVScan(filepath.Join(dir, "*.csv"), schema, CSVOptions{SplitSize: size})
+
+        ids := []int{}
+        for _, node := range nodes {
+            ctx := context.WithValue(context.Background(), "ep.AllNodes", nodes)
+            ctx = context.WithValue(ctx, "ep.ThisNode", node)
+            data, err := runCtx(ctx, runner)
+            require.NoError(t, err)
+            if data.Width() == 0 {
+                continue // no splits were assigned to this node
+            }
+
+            for _, v := range data.At(0).(Ints) {
+                ids = append(ids, int(v))
+            }
+        }
+
+        sort.Ints(ids)
+        require.Equal(t, 102, len(ids), "split size %d", size)
+        for i, id := range ids {
+            require.Equal(t, i, id, "split size %d", size)
+        }
+    }
+}
+
+func TestCSVScanBatches(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 2
+
+    dir := t.TempDir()
+    err := os.WriteFile(filepath.Join(dir, "a.csv"), []byte("a|1\nb|2\nc|3\n"), 0644)
+    require.NoError(t, err)
+
+    runner := CSVScan(filepath.Join(dir, "a.csv"), Schema{{"k", Str}, {"v", Float}}, CSVOptions{Comma: '|'})
+    require.Equal(t, []int{2, 1}, batchLens(runner))
+}
+
+func TestCSVScanErr(t *testing.T) {
+    dir := t.TempDir()
+    path := filepath.Join(dir, "a.csv")
+    err := os.WriteFile(path, []byte("a,1\nb,x\n"), 0644)
+    require.NoError(t, err)
+
+    _, err = testRun(CSVScan(path, Schema{{"k", Str}, {"v", Int}}, CSVOptions{}))
+    require.EqualError(t, err, path + `: unable to cast "x" from string to int`)
+
+    _, err = testRun(CSVScan(path, Schema{{"k", Str}}, CSVOptions{}))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), path + " at offset 0")
+
+    _, err = testRun(CSVScan("[", Schema{{"k", Str}}, CSVOptions{}))
+    require.Error(t, err)
+}
+
+// Test that the files are read from the storage of the URL scheme, and that
+// the objects are divided between the nodes
+func TestCSVScanStorage(t *testing.T) {
+    storage.Register("memtest", func(bucket string) (storage.Storage, error) {
+        return memStorage{"data/a.csv": "1,a\n2,b\n", "data/b.csv": "3,c\n", "other.csv": "4,d\n"}, nil
+    })
+    defer storage.Register("memtest", nil)
+
+    nodes := []string{":5551", ":5552"}
+    runner := CSVScan("memtest://bucket/data/*.csv", Schema{{"id", Int}, {"s", Str}}, CSVOptions{})
+    res := []string{}
+    for _, node := range nodes {
+        ctx := context.WithValue(context.Background(), "ep.AllNodes", nodes)
+        ctx = context.WithValue(ctx, "ep.ThisNode", node)
+        data, err := runCtx(ctx, runner)
+        require.NoError(t, err)
+        res = append(res, fmt.Sprint(data))
+    }
+    require.Equal(t, []string{"[[1 2] [a b]]", "[[3] [c]]"}, res)
+}
+
+// memStorage is an in-memory storage.Storage of keys to contents
+type memStorage map[string]string
+
+func (s memStorage) List(ctx context.Context, prefix string) ([]storage.Object, error) {
+    res := []storage.Object{}
+    for k, v := range s {
+        if strings.HasPrefix(k, prefix) {
+            res = append(res, storage.Object{Key: k, Size: int64(len(v))})
+        }
+    }
+    sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+    return res, nil
+}
+
+func (s memStorage) Open(ctx context.Context, key string) (storage.Reader, error) {
+    return memReader{bytes.NewReader([]byte(s[key]))}, nil
+}
+
+type memReader struct { *bytes.Reader }
+func (memReader) Close() error { return nil }
+
+// runCtx runs the runner without input, within the context
+func runCtx(ctx context.Context, r Runner) (Dataset, error) {
+    inp := make(chan Dataset)
+    close(inp)
+
+    var err error
+    out := make(chan Dataset)
+    go func() {
+        err = r.Run(ctx, inp, out)
+        close(out)
+    }()
+
+    var res = NewDataset()
+    for data := range out {
+        res = res.Append(data).(Dataset)
+    }
+    return res, err
+}
@@ -0,0 +1,157 @@
+package ep
+
+import (
+    "io"
+    "os"
+    "bufio"
+    "context"
+    "strings"
+    "unicode/utf8"
+)
+
+var _ = registerGob(&csvWrite{})
+
+// CSVWrite returns a Runner that writes its input datasets as CSV into the file
+// at path (truncating it if it exists), and emits a single summary row of the
+// number of rows and bytes written. With the Header option, the first line is
+// the names of the columns (see Dataset.Schema). Nulls are written as empty
+// fields, and fields are quoted only when necessary unless QuoteAll is set.
+// When distributed, every node writes its own input into the path on its own
+// file system. Use a Comma of '\t' for TSV.
+func CSVWrite(path string, opts CSVOptions) Runner {
+    return &csvWrite{Path: path, Options: opts}
+}
+
+// CSVWriteTo is like CSVWrite, except that it writes into the provided writer.
+// NOTE: writers cannot be serialized, thus the returned Runner cannot be
+// distributed. See CSVWrite.
+func CSVWriteTo(w io.Writer, opts CSVOptions) Runner {
+    return &csvWrite{Options: opts, w: w}
+}
+
+type csvWrite struct {
+    Path string
+    Options CSVOptions
+    w io.Writer
+}
+
+func (*csvWrite) Returns() []Type {
+    return []Type{As(Int, "rows"), As(Int, "bytes")}
+}
+
+func (r *csvWrite) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    w := r.w
+    if w == nil {
+        f, err := os.Create(r.Path)
+        if err != nil {
+            return err
+        }
+
+        defer func() {
+            if closeErr := f.Close(); err == nil {
+                err = closeErr
+            }
+        }()
+        w = f
+    }
+
+    bw := bufio.NewWriter(w)
+    cw := &countWriter{W: bw}
+    rows, header := 0, r.Options.Header
+    for data := range inp {
+        if header {
+            r.writeRecord(cw, data.Schema().Names())
+            header = false
+        }
+
+        r.writeRows(cw, data)
+        rows += data.Len()
+        if cw.Err != nil {
+            return cw.Err
+        }
+    }
+
+    err = bw.Flush()
+    if err != nil {
+        return err
+    }
+
+    out <- newNamedDataset([]string{"rows", "bytes"}, Ints{int64(rows)}, Ints{cw.N})
+    return nil
+}
+
+func (r *csvWrite) writeRows(w *countWriter, data Dataset) {
+    cols := make([][]string, data.Width())
+    for i := range cols {
+        cols[i] = data.At(i).Strings()
+    }
+
+    record := make([]string, len(cols))
+    for row := 0; row < data.Len(); row++ {
+        for i := range cols {
+            record[i] = cols[i][row]
+        }
+        r.writeRecord(w, record)
+    }
+}
+
+func (r *csvWrite) writeRecord(w *countWriter, record []string) {
+    comma := r.Options.Comma
+    if comma == 0 {
+        comma = ','
+    }
+
+    for i, field := range record {
+        if i > 0 {
+            w.WriteRune(comma)
+        }
+
+        if !r.Options.QuoteAll && !csvNeedsQuotes(field, comma) {
+            w.WriteString(field)
+            continue
+        }
+
+        w.WriteString(`"`)
+        w.WriteString(strings.Replace(field, `"`, `""`, -1))
+        w.WriteString(`"`)
+    }
+    w.WriteString("\n")
+}
+
+// csvNeedsQuotes returns true if the field can't be written as-is, because it
+// contains the delimiter, quotes or newlines, or leading spaces
+func csvNeedsQuotes(field string, comma rune) bool {
+    if field == "" {
+        return false
+    } else if field[0] == ' ' || field[0] == '\t' {
+        return true
+    }
+    return strings.ContainsRune(field, comma) || strings.ContainsAny(field, "\"\r\n")
+}
+
+// countWriter counts the bytes written into the underlying writer, and retains
+// the first error, if any
+type countWriter struct {
+    W io.Writer
+    N int64
+    Err error
+}
+
+func (w *countWriter) Write(b []byte) (int, error) {
+    if w.Err != nil {
+        return 0, w.Err
+    }
+
+    n, err := w.W.Write(b)
+    w.N += int64(n)
+    w.Err = err
+    return n, err
+}
+
+func (w *countWriter) WriteString(s string) {
+    w.Write([]byte(s))
+}
+
+func (w *countWriter) WriteRune(r rune) {
+    w.Write(utf8.AppendRune(nil, r))
+}
@@ -0,0 +1,60 @@
+package ep
+
+import (
+    "os"
+    "fmt"
+    "bytes"
+    "testing"
+    "path/filepath"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleCSVWriteTo() {
+    var buf bytes.Buffer
+    data := WithSchema(NewDataset(Strs{"bob", "eve, jr"}, Ints{30, 7}), Schema{{"name", Str}, {"age", Int}})
+    res, err := testRun(CSVWriteTo(&buf, CSVOptions{Header: true}), data)
+    fmt.Print(buf.String())
+    fmt.Println(res, err)
+
+    // Output:
+    // name,age
+    // bob,30
+    // "eve, jr",7
+    // [[2] [28]] <nil>
+}
+
+func TestCSVWriteOptions(t *testing.T) {
+    var buf bytes.Buffer
+    data1 := NewDataset(Strs{"a b", ` c`, `d"e`}, Nullable(Ints{1, 0, 3}, Bools{true, false, true}))
+    data2 := NewDataset(Strs{"f\tg"}, Ints{4})
+    _, err := testRun(CSVWriteTo(&buf, CSVOptions{Comma: '\t'}), data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, "a b\t1\n\" c\"\t\n\"d\"\"e\"\t3\n\"f\tg\"\t4\n", buf.String())
+
+    buf.Reset()
+    _, err = testRun(CSVWriteTo(&buf, CSVOptions{QuoteAll: true}), data2)
+    require.NoError(t, err)
+    require.Equal(t, "\"f\tg\",\"4\"\n", buf.String())
+}
+
+// Test that written files are read back by CSVScan
+func TestCSVWriteRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "a.csv")
+    schema := Schema{{"name", Str}, {"age", Int}, {"score", Float}}
+    data := WithSchema(NewDataset(Strs{"bob", "a,\"b\"\nc"}, Ints{30, 7}, Floats{1.5, 2}), schema)
+
+    res, err := testRun(CSVWrite(path, CSVOptions{Header: true}), data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"rows", "bytes"}, res.Schema().Names())
+
+    info, err := os.Stat(path)
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprintf("[[2] [%d]]", info.Size()), fmt.Sprint(res))
+
+    res, err = testRun(CSVScan(path, schema, CSVOptions{Header: true}))
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprint(data), fmt.Sprint(res))
+
+    _, err = testRun(CSVWrite(filepath.Join(path, "b.csv"), CSVOptions{}), data)
+    require.Error(t, err)
+}
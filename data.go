@@ -26,8 +26,16 @@ type Data interface {
 }
 
 // Clone the contents of the provided Data. Dataset also implements the Data
-// interface is a valid input to this function.
+// interface is a valid input to this function, and its columns are cloned
+// along with their names.
 func Clone(data Data) Data {
+    if set, ok := data.(Dataset); ok {
+        cols := make([]Data, set.Width())
+        for i := range cols {
+            cols[i] = Clone(set.At(i))
+        }
+        return newNamedDataset(namesOf(set), cols...)
+    }
     return data.Type().Data(0).Append(data)
 }
 
@@ -3,22 +3,13 @@ package ep
 import (
     "sort"
     "fmt"
+    "math"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
 )
 
-var Str = &StrType{}
-type StrType struct {}
-func (*StrType) Name() string { return "string" }
-func (*StrType) Data(n uint) Data { return make(Strs, n) }
-
-type Strs []string
-func (Strs) Type() Type { return Str }
-func (vs Strs) Len() int { return len(vs) }
-func (vs Strs) Less(i, j int) bool { return vs[i] < vs[j] }
-func (vs Strs) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
-func (vs Strs) Slice(s, e int) Data { return vs[s:e] }
-func (vs Strs) Strings() []string { return vs }
-func (vs Strs) Append(o Data) Data { return append(vs, o.(Strs)...) }
-
 func ExampleData() {
     var strs Data = Strs([]string{"hello", "world", "foo", "bar"})
     sort.Sort(strs)
@@ -27,3 +18,53 @@ func ExampleData() {
 
     // Output: [bar foo]
 }
+
+func ExampleInts() {
+    var ints Data = Ints{10, -2, 3}
+    sort.Sort(ints)
+    fmt.Println(ints, ints.Strings(), ints.Type().Name())
+
+    // Output: [-2 3 10] [-2 3 10] int
+}
+
+func ExampleFloats() {
+    var floats Data = Floats{2.5, math.NaN(), -1}
+    sort.Sort(floats)
+    fmt.Println(floats, floats.Strings(), floats.Type().Name())
+
+    // Output: [NaN -1 2.5] [NaN -1 2.5] float
+}
+
+// numeric data is serializable, in order to be distributed
+func TestNumericGob(t *testing.T) {
+    var data Data = NewDataset(Ints{1, 2}, Floats{0.5, 1e100})
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+}
+
+// numeric columns are aggregated natively
+func TestNumericAggregate(t *testing.T) {
+    data := NewDataset(Strs{"a", "a", "b"}, Ints{1, 2, 3}, Floats{0.5, 0.25, 1})
+    runner := GroupBy([]int{0}, Sum(1), Avg(2), Max(1), Count())
+    res, err := testRun(Pipeline(runner, Sort([]SortKey{{Col: 0}})), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[a b] [3 3] [0.375 1] [2 3] [2 1]]", fmt.Sprint(res))
+    require.Equal(t, Floats{3, 3}, res.At(1))
+    require.Equal(t, Ints{2, 3}, res.At(3))
+    require.Equal(t, Ints{2, 1}, res.At(4))
+}
+
+func ExampleBools() {
+    var bools Data = Bools{true, false, true}
+    fmt.Println(bools.Strings(), bools.(Bools).Selection())
+    sort.Sort(bools)
+    fmt.Println(bools)
+
+    // Output:
+    // [true false true] [0 2]
+    // [false true true]
+}
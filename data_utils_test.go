@@ -2,6 +2,8 @@ package ep
 
 import (
     "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
 )
 
 func ExampleClone() {
@@ -33,3 +35,32 @@ func ExamplePartition() {
     // Output:
     // [[bar] [hello hello] [world]]
 }
+
+func ExampleConcat() {
+    a := NewDataset(Strs{"a", "b"}, Ints{1, 2})
+    b := NewDataset(Strs{"c"}, Ints{3})
+    data := Concat(a, b, Slice(a, 1, 2))
+    data.At(0).(Strs)[0] = "z"
+    fmt.Println(data, a, b)
+
+    // Output: [[z b c b] [1 2 3 2]] [[a b] [1 2]] [[c] [3]]
+}
+
+func ExampleAppend() {
+    a := WithSchema(NewDataset(Strs{"a"}), Schema{{"name", Str}})
+    data := Append(a, NewDataset(Strs{"b"}))
+    fmt.Println(data, data.ColumnName(0), a)
+
+    // Output: [[a b]] name [[a]]
+}
+
+func TestCloneDataset(t *testing.T) {
+    data := WithSchema(NewDataset(Strs{"a"}, Ints{1}), Schema{{"k", Str}, {"v", Int}})
+    clone := Clone(data).(Dataset)
+    clone.At(1).(Ints)[0] = 2
+    require.Equal(t, Ints{1}, data.At(1))
+    require.Equal(t, []string{"k", "v"}, clone.Schema().Names())
+
+    require.Nil(t, Concat())
+    require.Panics(t, func() { Concat(data, NewDataset(Strs{"b"})) })
+}
@@ -1,6 +1,6 @@
 package ep
 
-var _ = registerGob(NewDataset(), &datasetType{})
+var _ = registerGob(NewDataset(), namedDataset{}, &datasetType{})
 
 // Dataset is a composite Data interface, containing several internal Data
 // objects. It's a Data in itself, but allows traversing and manipulating the
@@ -13,6 +13,25 @@ type Dataset interface {
 
     // At returns the Data instance at index i
     At(i int) Data
+
+    // Schema returns the names and types of the columns. See WithSchema
+    Schema() Schema
+
+    // ColumnName returns the name of the column at index i, or an empty string
+    // if it's not named
+    ColumnName(i int) string
+
+    // ColumnIndex returns the index of the first column with the provided name,
+    // or -1 if there's no such column
+    ColumnIndex(name string) int
+
+    // Filter returns a new Dataset containing only the rows marked as true in
+    // the mask, across all of the columns. The dataset itself isn't modified
+    Filter(mask Bools) Dataset
+
+    // Row returns the native Go values of the row at index i, a value per
+    // column. See Value
+    Row(i int) []interface{}
 }
 
 type dataset []Data
@@ -43,11 +62,12 @@ func (set dataset) At(i int) Data {
     return set[i]
 }
 
-// Append a data (assumed by interface spec to be a Dataset)
+// Append a data (assumed by interface spec to be a Dataset). If the other
+// dataset is named, its names are kept.
 func (set dataset) Append(data Data) Data {
-    other := data.(dataset)
+    other := columns(data)
     if set == nil {
-        return other
+        return data
     } else if other == nil {
         return set
     }
@@ -57,12 +77,51 @@ func (set dataset) Append(data Data) Data {
     }
 
     for i := range set {
-        set[i] = set[i].Append(other[i])
+        set[i] = appendData(set[i], other[i])
     }
 
+    if names := namesOf(data.(Dataset)); names != nil {
+        return newNamedDataset(names, set...)
+    }
     return set
 }
 
+// Slice returns the rows of the dataset from the start to end indices. Like
+// Data.Slice, the returned dataset shares the memory of the dataset, thus
+// it must not be modified in-place (see Clone).
+func Slice(data Dataset, start, end int) Dataset {
+    return data.Slice(start, end).(Dataset)
+}
+
+// Append returns a new dataset of the rows of the dataset, followed by the rows
+// of the other dataset. Unlike Data.Append, neither of them is modified. See
+// Concat.
+func Append(data, other Dataset) Dataset {
+    return Concat(data, other)
+}
+
+// Concat returns a new dataset of the rows of all of the datasets, in order,
+// which must all be of the same width. The datasets aren't modified, and don't
+// share memory with the result. The columns are named by the names of the
+// first named dataset, if any. Returns nil if there are no datasets.
+func Concat(datasets ...Dataset) Dataset {
+    var res Dataset
+    var names []string
+    for _, data := range datasets {
+        if res != nil && res.Width() != data.Width() {
+            panic("Unable to concat mismatching number of columns")
+        } else if names == nil {
+            names = namesOf(data)
+        }
+        res = appendClone(res, data)
+    }
+
+    if res != nil && names != nil {
+        res = newNamedDataset(names, columns(res)...)
+    }
+    return res
+}
+
 // see sort.Interface. Uses the last column.
 func (set dataset) Less(i, j int) bool {
     if set == nil || len(set) == 0 {
@@ -93,6 +152,35 @@ func (set dataset) Strings() []string {
     panic("Dataset cannot be cast to strings")
 }
 
+// Schema of unnamed columns, typed by the types of the columns
+func (set dataset) Schema() Schema {
+    schema := make(Schema, len(set))
+    for i, data := range set {
+        schema[i].Type = data.Type()
+    }
+    return schema
+}
+
+// ColumnName is always empty, as the columns aren't named. See WithSchema
+func (set dataset) ColumnName(i int) string { return "" }
+
+// ColumnIndex is always -1, as the columns aren't named. See WithSchema
+func (set dataset) ColumnIndex(name string) int { return -1 }
+
+// see Dataset.Filter
+func (set dataset) Filter(mask Bools) Dataset {
+    return pick(set, mask.Selection()).(Dataset)
+}
+
+// see Dataset.Row
+func (set dataset) Row(i int) []interface{} {
+    row := make([]interface{}, len(set))
+    for j, data := range set {
+        row[j] = Value(data, i)
+    }
+    return row
+}
+
 // see Data.Data
 func (set dataset) Type() Type {
     return &datasetType{}
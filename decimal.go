@@ -0,0 +1,233 @@
+package ep
+
+import (
+    "fmt"
+    "strings"
+    "math/big"
+)
+
+var _ = registerGob(&DecimalType{}, Decimals{})
+
+// MaxDecimalPrecision is the maximum number of digits of Decimal values
+const MaxDecimalPrecision = 18
+
+// Decimal returns a built-in Type representing fixed-point decimal values with
+// up to `precision` digits, `scale` of which are after the decimal point, like
+// SQL's decimal(precision, scale). Unlike floats, the values are exact, thus
+// they're suitable for financial data. Use it to create Data instances of `n`
+// zeros. It panics if the precision exceeds MaxDecimalPrecision, or if the
+// scale exceeds the precision.
+func Decimal(precision, scale int) Type {
+    if precision < 1 || precision > MaxDecimalPrecision {
+        panic(fmt.Sprintf("decimal precision %d out of range %d", precision, MaxDecimalPrecision))
+    } else if scale < 0 || scale > precision {
+        panic(fmt.Sprintf("decimal scale %d out of range %d", scale, precision))
+    }
+    return &DecimalType{precision, scale}
+}
+
+// DecimalType is the Type of Decimals
+type DecimalType struct { Precision, Scale int }
+func (t *DecimalType) Name() string {
+    return fmt.Sprintf("decimal(%d,%d)", t.Precision, t.Scale)
+}
+
+func (t *DecimalType) Data(n uint) Data {
+    return Decimals{t.Precision, t.Scale, make([]int64, n)}
+}
+
+// Decimals is a built-in Data implementation of fixed-point decimal values. The
+// values are stored as integers, scaled by 10^Scale, e.g. 123.45 is stored as
+// 12345 with a scale of 2. See Decimal
+type Decimals struct {
+    Precision, Scale int
+    Values []int64
+}
+
+// ParseDecimals parses the decimal strings into Decimals of the provided
+// precision and scale. Values with more fractional digits than the scale are
+// rounded half away from zero, while values exceeding the precision are an
+// error.
+func ParseDecimals(precision, scale int, strs ...string) (Decimals, error) {
+    t := Decimal(precision, scale).(*DecimalType)
+    res := t.Data(uint(len(strs))).(Decimals)
+    for i, s := range strs {
+        r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+        if !ok {
+            return res, fmt.Errorf("invalid decimal %q", s)
+        }
+
+        v := new(big.Int).Mul(r.Num(), pow10(scale))
+        v, err := res.fit(roundQuo(v, r.Denom()))
+        if err != nil {
+            return res, err
+        }
+        res.Values[i] = v.Int64()
+    }
+    return res, nil
+}
+
+func (vs Decimals) Type() Type { return &DecimalType{vs.Precision, vs.Scale} }
+func (vs Decimals) Len() int { return len(vs.Values) }
+func (vs Decimals) Less(i, j int) bool { return vs.Values[i] < vs.Values[j] }
+func (vs Decimals) Swap(i, j int) {
+    vs.Values[i], vs.Values[j] = vs.Values[j], vs.Values[i]
+}
+
+func (vs Decimals) Slice(s, e int) Data {
+    return Decimals{vs.Precision, vs.Scale, vs.Values[s:e]}
+}
+
+// Append another Decimals of the same scale. The precision of the result is the
+// higher of both.
+func (vs Decimals) Append(o Data) Data {
+    other := o.(Decimals)
+    if vs.Scale != other.Scale {
+        panic(fmt.Sprintf("Unable to append mismatching decimal scales %d and %d", vs.Scale, other.Scale))
+    }
+
+    if other.Precision > vs.Precision {
+        vs.Precision = other.Precision
+    }
+    vs.Values = append(vs.Values, other.Values...)
+    return vs
+}
+
+func (vs Decimals) Strings() []string {
+    strs := make([]string, len(vs.Values))
+    for i := range vs.Values {
+        strs[i] = vs.String(i)
+    }
+    return strs
+}
+
+// String returns the string representation of the value at index i, with
+// exactly Scale fractional digits
+func (vs Decimals) String(i int) string {
+    return new(big.Rat).SetFrac(big.NewInt(vs.Values[i]), pow10(vs.Scale)).FloatString(vs.Scale)
+}
+
+// Add returns new Decimals of the sums of the values and the other values, row
+// by row, at the higher scale of both. Exceeding the precision is an error.
+func (vs Decimals) Add(other Decimals) (Decimals, error) {
+    return vs.arithmetic(other, func(x, y *big.Int) *big.Int { return x.Add(x, y) })
+}
+
+// Sub returns new Decimals of the differences between the values and the other
+// values, row by row, at the higher scale of both. Exceeding the precision is
+// an error.
+func (vs Decimals) Sub(other Decimals) (Decimals, error) {
+    return vs.arithmetic(other, func(x, y *big.Int) *big.Int { return x.Sub(x, y) })
+}
+
+// Mul returns new Decimals of the products of the values and the other values,
+// row by row, rounded half away from zero to the higher scale of both.
+// Exceeding the precision is an error.
+func (vs Decimals) Mul(other Decimals) (Decimals, error) {
+    scale := maxInt(vs.Scale, other.Scale)
+    return vs.arithmetic(other, func(x, y *big.Int) *big.Int {
+        return roundQuo(x.Mul(x, y), pow10(scale))
+    })
+}
+
+// Div returns new Decimals of the quotients of the values and the other
+// values, row by row, rounded half away from zero to the higher scale of both.
+// Division by zero or exceeding the precision is an error.
+func (vs Decimals) Div(other Decimals) (Decimals, error) {
+    scale := maxInt(vs.Scale, other.Scale)
+    for _, v := range other.Values {
+        if v == 0 {
+            return Decimals{}, fmt.Errorf("division by zero")
+        }
+    }
+
+    return vs.arithmetic(other, func(x, y *big.Int) *big.Int {
+        return roundQuo(x.Mul(x, pow10(scale)), y)
+    })
+}
+
+// Rescale returns new Decimals of the values rounded half away from zero to the
+// provided scale. Exceeding the precision is an error.
+func (vs Decimals) Rescale(scale int) (Decimals, error) {
+    res := Decimal(vs.Precision, scale).Data(uint(vs.Len())).(Decimals)
+    for i, v := range vs.Values {
+        x := new(big.Int).Mul(big.NewInt(v), pow10(scale))
+        x, err := res.fit(roundQuo(x, pow10(vs.Scale)))
+        if err != nil {
+            return res, err
+        }
+        res.Values[i] = x.Int64()
+    }
+    return res, nil
+}
+
+// Sum returns the exact sum of all of the values, as a single value Decimals
+// of the same scale. Exceeding the precision is an error.
+func (vs Decimals) Sum() (Decimals, error) {
+    res := Decimals{vs.Precision, vs.Scale, []int64{0}}
+    total := new(big.Int)
+    for _, v := range vs.Values {
+        total.Add(total, big.NewInt(v))
+    }
+
+    total, err := res.fit(total)
+    res.Values[0] = total.Int64()
+    return res, err
+}
+
+// arithmetic applies the function to the values of both decimals, row by row,
+// after converting them into the higher scale of both. The function receives
+// scaled integers, and returns a scaled integer.
+func (vs Decimals) arithmetic(other Decimals, fn func(x, y *big.Int) *big.Int) (Decimals, error) {
+    if vs.Len() != other.Len() {
+        return Decimals{}, fmt.Errorf("mismatching number of decimals %d and %d", vs.Len(), other.Len())
+    }
+
+    scale := maxInt(vs.Scale, other.Scale)
+    precision := maxInt(vs.Precision, other.Precision)
+    res := Decimals{precision, scale, make([]int64, vs.Len())}
+    for i := range vs.Values {
+        x := new(big.Int).Mul(big.NewInt(vs.Values[i]), pow10(scale - vs.Scale))
+        y := new(big.Int).Mul(big.NewInt(other.Values[i]), pow10(scale - other.Scale))
+
+        v, err := res.fit(fn(x, y))
+        if err != nil {
+            return res, err
+        }
+        res.Values[i] = v.Int64()
+    }
+    return res, nil
+}
+
+// fit verifies that the scaled integer fits in the precision
+func (vs Decimals) fit(v *big.Int) (*big.Int, error) {
+    if new(big.Int).Abs(v).Cmp(pow10(vs.Precision)) >= 0 {
+        return v, fmt.Errorf("decimal overflow: %s exceeds precision %d", v, vs.Precision)
+    }
+    return v, nil
+}
+
+// roundQuo returns x / y, rounded half away from zero
+func roundQuo(x, y *big.Int) *big.Int {
+    q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+    r.Abs(r).Mul(r, big.NewInt(2))
+    if r.Cmp(new(big.Int).Abs(y)) >= 0 {
+        if (x.Sign() < 0) != (y.Sign() < 0) {
+            q.Sub(q, big.NewInt(1))
+        } else {
+            q.Add(q, big.NewInt(1))
+        }
+    }
+    return q
+}
+
+func pow10(n int) *big.Int {
+    return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+func maxInt(a, b int) int {
+    if a > b {
+        return a
+    }
+    return b
+}
@@ -0,0 +1,91 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleDecimals() {
+    prices, err := ParseDecimals(10, 2, "19.99", "0.1", "-3.005", "7")
+    sort.Sort(prices)
+    fmt.Println(prices.Strings(), prices.Type().Name(), err)
+
+    // Output: [-3.01 0.10 7.00 19.99] decimal(10,2) <nil>
+}
+
+func ExampleDecimals_Mul() {
+    prices, _ := ParseDecimals(10, 2, "19.99", "0.10")
+    rates, _ := ParseDecimals(10, 3, "0.175", "3")
+    tax, _ := prices.Mul(rates)
+    tax, _ = tax.Rescale(2)
+    total, _ := tax.Add(prices)
+    sum, _ := total.Sum()
+    fmt.Println(tax.Strings(), total.Strings(), sum.Strings())
+
+    // Output: [3.50 0.30] [23.49 0.40] [23.89]
+}
+
+// decimal arithmetic is exact, unlike floats
+func TestDecimalsExact(t *testing.T) {
+    tenth, err := ParseDecimals(18, 2, "0.1")
+    require.NoError(t, err)
+
+    total := tenth
+    for i := 0; i < 9; i++ {
+        total, err = total.Add(tenth)
+        require.NoError(t, err)
+    }
+    require.Equal(t, []string{"1.00"}, total.Strings())
+
+    third, err := ParseDecimals(18, 4, "1")
+    require.NoError(t, err)
+    three, _ := ParseDecimals(18, 0, "3")
+    third, err = third.Div(three)
+    require.NoError(t, err)
+    require.Equal(t, []string{"0.3333"}, third.Strings())
+
+    diff, err := third.Sub(tenth)
+    require.NoError(t, err)
+    require.Equal(t, []string{"0.2333"}, diff.Strings())
+}
+
+func TestDecimalsErr(t *testing.T) {
+    _, err := ParseDecimals(4, 2, "100")
+    require.Error(t, err)
+
+    _, err = ParseDecimals(4, 2, "abc")
+    require.Error(t, err)
+
+    big, _ := ParseDecimals(4, 2, "99.99")
+    _, err = big.Add(big)
+    require.Error(t, err)
+
+    zero, _ := ParseDecimals(4, 2, "0")
+    _, err = big.Div(zero)
+    require.Error(t, err)
+
+    require.Panics(t, func() { Decimal(19, 2) })
+    require.Panics(t, func() { Decimal(4, 5) })
+}
+
+// decimals are sorted natively, and serializable in order to be distributed
+func TestDecimalsSortGob(t *testing.T) {
+    amounts, err := ParseDecimals(10, 2, "10", "9.5", "-1", "100")
+    require.NoError(t, err)
+
+    res, err := testRun(Sort([]SortKey{{Col: 0}}), NewDataset(amounts))
+    require.NoError(t, err)
+    require.Equal(t, []string{"-1.00", "9.50", "10.00", "100.00"}, res.At(0).Strings())
+
+    var data Data = res
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+}
@@ -1,13 +1,16 @@
 package ep
 
 import (
-    "io"
     "net"
     "fmt"
     "sync"
     "time"
+    "errors"
+    "strings"
+    "runtime/debug"
     "context"
     "encoding/gob"
+    "github.com/satori/go.uuid"
 )
 
 var _ = registerGob(&distRunner{})
@@ -26,6 +29,21 @@ type Distributer interface {
     // Stop listening for incoming Runners to run, and close all open
     // connections.
     Close() error
+
+    // Addr returns the advertised address of this node, as used by its peers
+    Addr() string
+
+    // Drain marks this node as leaving: the masters stop distributing new
+    // runners to it, and run them on the other nodes instead. Once the
+    // runners that are already running on this node complete, it's closed.
+    // Used for rolling restarts, without failing the running queries.
+    Drain() error
+
+    // WaitForPeers blocks until the nodes of the addresses are started and
+    // accept connections, or until the context is done. Distributing runners
+    // to nodes that aren't started yet fails, thus it's used when starting a
+    // cluster, before distributing to it.
+    WaitForPeers(ctx context.Context, addrs ...string) error
 }
 
 type dialer interface {
@@ -34,24 +52,72 @@ type dialer interface {
 
 // NewDistributer creates a Distributer that can be used to distribute work of
 // Runners across multiple nodes in a cluster. Distributer must be started on
-// all node peers in order for them to receive work. You can also implement the
-// dialer interface (implemented by net.Dialer) in order to provide your own
-// connections:
+// all node peers in order for them to receive work.
+//
+// `addr` is the advertised address of this node - the one its peers use for
+// dialing it, and the one that should be passed to Distribute(). It may differ
+// from the bound address of the listener, for example when the node is behind
+// a NAT and listens on a private address. All node comparisons (this node,
+// master node) are made using the advertised addresses. Addresses prefixed by
+// "unix:" are of Unix domain sockets, for nodes that are co-located on the
+// same host, which are thus dialed without the TCP stack (see Listen).
+//
+// The node accepts connections on all of the provided listeners, like both
+// IPv4 and IPv6 listeners, or a Unix domain socket in addition to TCP. NOTE:
+// peers only dial the advertised address, thus co-located nodes are connected
+// by Unix domain sockets only when their advertised addresses themselves are
+// "unix:" prefixed. The other listeners accept connections from peers that
+// dial them directly, like the masters of another network.
+//
+// You can also implement the dialer interface (implemented by net.Dialer) in
+// order to provide your own connections. It's called with the network of the
+// address, i.e. ("unix", "/path") for "unix:/path", or ("tcp", addr):
 //
 //      type dialer interface {
 //          Dial(network, addr string) (net.Conn, error)
 //      }
 //
-func NewDistributer(addr string, listener net.Listener) Distributer {
-    return &distributer{listener, addr, make(map[string]chan net.Conn), &sync.Mutex{}, nil}
+// For tests, the listeners of Pipes connect the nodes in-memory.
+func NewDistributer(addr string, listeners ...net.Listener) Distributer {
+    l := &sync.Mutex{}
+    return &distributer{
+        listeners: listeners,
+        addr: addr,
+        connsMap: make(map[string]chan net.Conn),
+        l: l,
+        closed: make(chan struct{}),
+        idle: sync.NewCond(l),
+    }
+}
+
+// Listen on a node address: a Unix domain socket for "unix:" prefixed
+// addresses, or TCP otherwise. See NewDistributer
+func Listen(addr string) (net.Listener, error) {
+    network, addr := splitAddr(addr)
+    return net.Listen(network, addr)
+}
+
+// splitAddr returns the network and the address of a node address, for
+// listening and dialing
+func splitAddr(addr string) (string, string) {
+    if strings.HasPrefix(addr, "unix:") {
+        return "unix", strings.TrimPrefix(addr, "unix:")
+    }
+    return "tcp", addr
 }
 
 type distributer struct {
-    listener net.Listener
+    listeners []net.Listener
+    closeOnce sync.Once // closes the listeners
+    closeErr error
     addr string
     connsMap map[string]chan net.Conn
     l sync.Locker
     closeCh chan error
+    closed chan struct{} // closed by Close, aborting the pending connects
+    draining bool // refuse new runners from the masters, see Drain
+    running int // the number of runners running on this node
+    idle *sync.Cond // notified when a runner completes
 }
 
 func (d *distributer) Start() error {
@@ -60,18 +126,61 @@ func (d *distributer) Start() error {
     defer close(d.closeCh)
     d.l.Unlock()
 
+    if len(d.listeners) == 0 {
+        return fmt.Errorf("ep: no listeners")
+    }
+
+    // accept on all of the listeners, until either of them fails or they're
+    // closed. Then wait for all of them to exit
+    var first error
+    var once sync.Once
+    fail := func(err error) {
+        once.Do(func() {
+            first = err
+            d.closeListeners()
+        })
+    }
+
+    var wg sync.WaitGroup
+    for _, ln := range d.listeners[1:] {
+        wg.Add(1)
+        go func(ln net.Listener) {
+            defer wg.Done()
+            fail(d.accept(ln))
+        }(ln)
+    }
+
+    fail(d.accept(d.listeners[0]))
+    wg.Wait()
+    return first
+}
+
+func (d *distributer) accept(ln net.Listener) error {
     for {
-        conn, err := d.listener.Accept()
+        conn, err := ln.Accept()
         if err != nil {
             return err
         }
 
+        Metrics.AcceptedConns.Add(1)
         go d.Serve(conn)
     }
 }
 
+// closeListeners closes all of the listeners once, and returns the first error
+func (d *distributer) closeListeners() error {
+    d.closeOnce.Do(func() {
+        for _, ln := range d.listeners {
+            if err := ln.Close(); err != nil && d.closeErr == nil {
+                d.closeErr = err
+            }
+        }
+    })
+    return d.closeErr
+}
+
 func (d *distributer) Close() error {
-    err := d.listener.Close()
+    err := d.closeListeners()
     if err != nil {
         return err
     }
@@ -83,23 +192,119 @@ func (d *distributer) Close() error {
     // TODO: consider waiting for all served connections/runners?
     d.l.Lock()
     defer d.l.Unlock()
+    select {
+    case <- d.closed:
+    default:
+        close(d.closed)
+    }
+
     if d.closeCh != nil {
         <- d.closeCh
     }
     return nil
 }
 
-func (d *distributer) dial(addr string) (net.Conn, error) {
-    dialer, ok := d.listener.(dialer)
-    if ok {
-        return dialer.Dial("tcp", addr)
+func (d *distributer) Addr() string {
+    return d.addr
+}
+
+func (d *distributer) Drain() error {
+    d.l.Lock()
+    d.draining = true
+    for d.running > 0 {
+        d.idle.Wait()
     }
+    d.l.Unlock()
+    return d.Close()
+}
 
-    return net.Dial("tcp", addr)
+// start running a runner on this node. Runners distributed by other masters
+// are refused while draining, while the runners of this master aren't
+func (d *distributer) start(isMain bool) bool {
+    d.l.Lock()
+    defer d.l.Unlock()
+    if d.draining && !isMain {
+        return false
+    }
+
+    d.running++
+    return true
+}
+
+func (d *distributer) done() {
+    d.l.Lock()
+    defer d.l.Unlock()
+    d.running--
+    d.idle.Broadcast()
+}
+
+func (d *distributer) WaitForPeers(ctx context.Context, addrs ...string) error {
+    for _, addr := range addrs {
+        if addr == d.addr {
+            continue
+        }
+
+        // probe the peer until it responds, backing off up to a second
+        wait := 10 * time.Millisecond
+        for err := d.probe(ctx, addr); err != nil; err = d.probe(ctx, addr) {
+            timer := time.NewTimer(wait)
+            select {
+            case <- timer.C:
+            case <- ctx.Done():
+                timer.Stop()
+                return fmt.Errorf("ep: peer %s isn't ready: %s", addr, err)
+            }
+
+            if wait < time.Second {
+                wait *= 2
+            }
+        }
+    }
+    return nil
+}
+
+// probe the peer with a ping connection, which it answers once it's started
+func (d *distributer) probe(ctx context.Context, addr string) error {
+    errs := make(chan error, 1)
+    go func() {
+        conn, err := d.dial(addr)
+        if err != nil {
+            errs <- err
+            return
+        }
+
+        defer conn.Close()
+        if deadline, ok := ctx.Deadline(); ok {
+            conn.SetDeadline(deadline) // unsupported by some connections
+        }
+
+        err = writeHandshake(conn, connPing, "")
+        if err == nil {
+            _, err = readStr(conn)
+        }
+        errs <- err
+    }()
+
+    select {
+    case err := <- errs:
+        return err
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
+
+func (d *distributer) dial(addr string) (net.Conn, error) {
+    network, addr := splitAddr(addr)
+    for _, ln := range d.listeners {
+        if dialer, ok := ln.(dialer); ok {
+            return dialer.Dial(network, addr)
+        }
+    }
+    return net.Dial(network, addr)
 }
 
 func (d *distributer) Distribute(runner Runner, addrs ...string) Runner {
-    return &distRunner{runner, addrs, d.addr, d}
+    return &distRunner{runner, addrs, d.addr, "", nil, false, d}
 }
 
 // Connect to a node address for the given uid. Used by the individual exchange
@@ -115,12 +320,7 @@ func (d *distributer) Connect(addr string, uid string) (conn net.Conn, err error
             return
         }
 
-        err = writeStr(conn, "D") // Data connection
-        if err != nil {
-            return
-        }
-
-        err = writeStr(conn, d.addr + ":" + uid)
+        err = writeHandshake(conn, connData, d.addr + ":" + uid)
         if err != nil {
             return
         }
@@ -134,46 +334,92 @@ func (d *distributer) Connect(addr string, uid string) (conn net.Conn, err error
             // let it through
         case <- timer.C:
             err = fmt.Errorf("ep: connect timeout; no incoming conn")
+        case <- d.closed:
+            err = fmt.Errorf("ep: distributer closed")
         }
     }
 
+    if err == nil {
+        conn = &meteredConn{conn, addr}
+    }
     return conn, err
 }
 
-func (d *distributer) Serve(conn net.Conn) error {
-    typee, err := readStr(conn)
+func (d *distributer) Serve(conn net.Conn) (err error) {
+    // keep serving other connections when the runner panics. The panics of
+    // runners are reported to the master, see distRunner.Run
+    defer func() {
+        if p := recover(); p != nil {
+            err = &PanicError{p, string(debug.Stack())}
+        }
+    }()
+
+    typee, key, err := readHandshake(conn)
     if err != nil {
+        conn.Close()
         return err
     }
 
-    if typee == "D" { // data connection
-        key, err := readStr(conn)
-        if err != nil {
-            return err
-        }
-
+    if typee == connData {
         // wait for someone to claim it.
         d.connCh(key) <- conn
-    } else if (typee == "X") { // execute runner connection
+    } else if (typee == connRunner) {
         defer conn.Close()
 
+        // accept the runner, unless draining. See distRunner.Run
+        if !d.start(false) {
+            return writeStr(conn, statusDraining)
+        }
+
+        defer d.done()
+        err := writeStr(conn, statusOK)
+        if err != nil {
+            return err
+        }
+
         r := &distRunner{d: d}
-        dec := gob.NewDecoder(conn)
-        err := dec.Decode(r)
+        dec := newWireDecoder(conn, runnerLimit())
+        err = dec.Decode(r)
         if err != nil {
             fmt.Println("ep: distributer error", err)
             return err
         }
 
+        // the output of peers is discarded, as it's exchanged with the master
         out := make(chan Dataset)
+        go func() { for _ = range out {} }()
+
         inp := make(chan Dataset, 1)
         close(inp)
 
-        err = r.Run(context.Background(), inp, out)
+        // report the progress of the runner to the master while it's running,
+        // if it was requested, and then its completion
+        ctx := context.Background()
+        enc := gob.NewEncoder(conn)
+        stopProgress := func() {}
+        if r.Progress {
+            t := &tracker{}
+            ctx = context.WithValue(ctx, "ep.Progress", t)
+            stopProgress = sendProgress(enc, t)
+        }
+
+        err = r.Run(ctx, inp, out)
+        close(out)
+        stopProgress()
+
+        rep := &report{Done: true}
+        if err != nil {
+            rep.Err = err.Error()
+        }
+        enc.Encode(rep)
+
         if err != nil {
             fmt.Println("ep: runner error", err)
             return err
         }
+    } else if (typee == connPing) { // see WaitForPeers
+        defer conn.Close()
+        return writeStr(conn, statusOK)
     } else {
         defer conn.Close()
         
@@ -195,17 +441,71 @@ func (d *distributer) connCh(k string) (chan net.Conn) {
     return d.connsMap[k]
 }
 
+// release the channels of the connection keys, once they're no longer needed
+func (d *distributer) release(keys ...string) {
+    d.l.Lock()
+    defer d.l.Unlock()
+    for _, k := range keys {
+        delete(d.connsMap, k)
+    }
+}
+
 // distRunner wraps around a runner, and upon the initial call to Run, it
 // distributes the runner to all nodes and runs them in parallel.
 type distRunner struct {
     Runner
     Addrs []string // participating node addresses
     MasterAddr string // the master node that created the distRunner
+    Job string // the ID of the run, assigned by the master, see Job
+    Trace map[string]string // the trace context of the master, see Tracing
+    Progress bool // report the progress to the master, see WithProgress
     d *distributer
 }
 
-func (r *distRunner) Run(ctx context.Context, inp, out chan Dataset) error {
+func (r *distRunner) Run(ctx context.Context, inp, out chan Dataset) (err error) {
     isMain := r.d.addr == r.MasterAddr
+    send := r
+    if isMain {
+        var end func(error)
+        ctx, end = startSpan(ctx, "ep.Distribute", map[string]string{
+            "ep.nodes": strings.Join(r.Addrs, ","),
+        })
+        defer func() { end(err) }()
+
+        // fail before distributing a runner that the peers can't decode
+        for _, addr := range r.Addrs {
+            if addr != r.d.addr {
+                err = checkRegistered(r.Runner, "Runner")
+                if err == nil {
+                    err = checkSerializable(r.Runner, "Runner")
+                }
+                break
+            }
+        }
+
+        if err != nil {
+            return err
+        }
+
+        // every run is a separate job, as the same runner might run
+        // concurrently
+        t := progressOf(ctx)
+        send = &distRunner{r.Runner, r.Addrs, r.MasterAddr, uuid.NewV4().String(), nil, t != nil, r.d}
+        if Tracing != nil {
+            send.Trace = map[string]string{}
+            Tracing.Inject(ctx, send.Trace)
+        }
+
+        r.d.start(true)
+        defer r.d.done()
+    } else if r.Trace != nil && Tracing != nil {
+        ctx = Tracing.Extract(ctx, r.Trace)
+    }
+
+    // the draining peers refuse the runner, which then runs on the nodes that
+    // have accepted it. See Drain
+    peers, peerAddrs := []net.Conn{}, []string{}
+    accepted := map[string]bool{r.d.addr: true}
     for i := 0 ; i < len(r.Addrs) && isMain ; i++ {
         addr := r.Addrs[i]
         if addr == r.d.addr {
@@ -217,49 +517,117 @@ func (r *distRunner) Run(ctx context.Context, inp, out chan Dataset) error {
             return err
         }
 
-        err = writeStr(conn, "X") // runner connection
+        conn = &meteredConn{conn, addr}
+
+        err = writeHandshake(conn, connRunner, "")
         if err != nil {
             return err
         }
 
         defer conn.Close()
+        status, err := readStr(conn)
         if err != nil {
             return err
+        } else if status == statusDraining {
+            continue
         }
 
-        enc := gob.NewEncoder(conn)
-        err = enc.Encode(r)
+        peers = append(peers, conn)
+        peerAddrs = append(peerAddrs, addr)
+        accepted[addr] = true
+    }
+
+    if isMain {
+        send.Addrs = []string{}
+        for _, addr := range r.Addrs {
+            if accepted[addr] {
+                send.Addrs = append(send.Addrs, addr)
+            }
+        }
+    }
+
+    for _, conn := range peers {
+        err = newWireEncoder(conn, runnerLimit()).Encode(send)
         if err != nil {
             return err
         }
     }
 
-    ctx = context.WithValue(ctx, "ep.AllNodes", r.Addrs)
+    // the connections of the runners are made within the job, see Job
+    job := newJob(send.Job, r.d)
+    defer job.close()
+
+    ctx = context.WithValue(ctx, "ep.AllNodes", send.Addrs)
     ctx = context.WithValue(ctx, "ep.MasterNode", r.MasterAddr)
     ctx = context.WithValue(ctx, "ep.ThisNode", r.d.addr)
-    ctx = context.WithValue(ctx, "ep.Distributer", r.d)
+    ctx = context.WithValue(ctx, "ep.Distributer", job)
+    ctx = context.WithValue(ctx, "ep.Job", job)
 
-    return r.Runner.Run(ctx, inp, out)
-}
+    ctx, end := startSpan(ctx, "ep.Run", map[string]string{"ep.node": r.d.addr})
+    defer func() { end(err) }()
+
+    Metrics.ActiveRunners.Add(1)
+    defer Metrics.ActiveRunners.Add(-1)
 
+    start := time.Now()
+    defer func() { Metrics.RunDurations.Observe(time.Since(start).Seconds()) }()
 
-// write a null-terminated string to a writer
-func writeStr(w io.Writer, s string) error {
-    _, err := w.Write(append([]byte(s), 0))
+    // the completion reports of the peers. The first error cancels the run
+    parent := ctx
+    g, ctx := NewGroup(ctx)
+    for i := range peers {
+        conn, addr := peers[i], peerAddrs[i]
+        g.Go(func() error { return readReport(conn, addr, progressOf(ctx)) })
+    }
+
+    // the run completes when all of the nodes complete. Upon a local error,
+    // the peers aren't awaited, their connections are closed instead
+    teardown, err := setup(ctx, r.Runner)
+    if err == nil {
+        err = runSafe(ctx, r.Runner, inp, out)
+        if err1 := teardown(); err == nil {
+            err = err1
+        }
+    }
+
+    if err != nil {
+        for _, conn := range peers {
+            conn.Close()
+        }
+    }
+
+    // prefer the error of the peer that has canceled the local run
+    peerErr := g.Wait()
+    if err == nil || err == context.Canceled && parent.Err() == nil {
+        err = peerErr
+    }
     return err
 }
 
-// read a null-terminated string from a reader
-func readStr(r io.Reader) (s string, err error) {
-    b := []byte{0}
+// readReport reads the reports of the runner sent to a peer node until its
+// completion, and returns its error, if any. Its progress is added to the
+// tracker, if any. See Serve
+func readReport(conn net.Conn, addr string, t *tracker) error {
+    dec := NewWireDecoder(conn)
     for {
-        _, err = r.Read(b)
+        var rep report
+        err := dec.Decode(&rep)
         if err != nil {
-            return
-        } else if b[0] == 0 {
-            return
+            return fmt.Errorf("ep: lost connection to %s: %s", addr, err)
+        } else if rep.Done && rep.Err != "" {
+            return errors.New(rep.Err)
+        } else if rep.Done {
+            return nil
+        } else if t != nil {
+            t.add(addr, rep.Rows, rep.Total)
         }
-
-        s += string(b[0])
     }
 }
+
+
+// the statuses of the runner connections, sent by the peers to the master
+// before it sends the runner
+const (
+    statusOK = "OK"
+    statusDraining = "DRAINING"
+)
@@ -0,0 +1,205 @@
+package ep
+
+import (
+    "os"
+    "fmt"
+    "net"
+    "time"
+    "context"
+    "testing"
+    "strings"
+    "io/ioutil"
+    "path/filepath"
+    "github.com/stretchr/testify/require"
+)
+
+// Tests that a draining node completes its running runners before closing,
+// while the new runners are distributed to the other nodes
+func TestDrain(t *testing.T) {
+    pipes := NewPipes()
+    dists := []Distributer{}
+    for _, addr := range []string{":5551", ":5552", ":5553"} {
+        ln, err := pipes.Listen(addr)
+        require.NoError(t, err)
+
+        dist := NewDistributer(addr, ln)
+        defer dist.Close()
+        go dist.Start()
+        dists = append(dists, dist)
+    }
+
+    inp := []Dataset{}
+    for i := 0; i < 9; i++ {
+        inp = append(inp, NewDataset(Strs{fmt.Sprint(i)}))
+    }
+
+    // a slow runner is running on the draining node
+    runner := Pipeline(Scatter(), &slowNode{":5552"}, &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552", ":5553")
+
+    type result struct {
+        data Dataset
+        err error
+    }
+
+    slow := make(chan result, 1)
+    go func() {
+        data, err := testRun(runner, inp...)
+        slow <- result{data, err}
+    }()
+
+    d := dists[1].(*distributer)
+    for d.isIdle() {
+        time.Sleep(time.Millisecond)
+    }
+
+    drained := make(chan error, 1)
+    go func() { drained <- d.Drain() }()
+    for !d.isDraining() {
+        time.Sleep(time.Millisecond)
+    }
+
+    // new runners skip the draining node
+    runner = Pipeline(Scatter(), &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552", ":5553")
+    data, err := testRun(runner, inp...)
+    require.NoError(t, err)
+    require.Equal(t, 9, data.Len())
+    require.NotContains(t, data.At(1).Strings(), ":5552")
+
+    // the running runner completes, and then the node is closed
+    res := <- slow
+    require.NoError(t, res.err)
+    require.Equal(t, 9, res.data.Len())
+    require.Contains(t, res.data.At(1).Strings(), ":5552")
+    require.NoError(t, <- drained)
+
+    _, err = dists[0].(*distributer).dial(":5552")
+    require.Error(t, err)
+}
+
+// Tests that the peers are awaited until they're started, in any order
+func TestWaitForPeers(t *testing.T) {
+    pipes := NewPipes()
+    ln1, err := pipes.Listen(":5551")
+    require.NoError(t, err)
+
+    dist1 := NewDistributer(":5551", ln1)
+    defer dist1.Close()
+    go dist1.Start()
+
+    // the peers are started later
+    started := make(chan Distributer, 2)
+    go func() {
+        for _, addr := range []string{":5553", ":5552"} {
+            time.Sleep(20 * time.Millisecond)
+            ln, err := pipes.Listen(addr)
+            if err != nil {
+                t.Error(err) // fails the wait below
+                return
+            }
+
+            dist := NewDistributer(addr, ln)
+            go dist.Start()
+            started <- dist
+        }
+    }()
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    err = dist1.WaitForPeers(ctx, ":5551", ":5552", ":5553")
+    require.NoError(t, err)
+    defer (<- started).Close()
+    defer (<- started).Close()
+
+    runner := dist1.Distribute(Pipeline(Scatter(), &nodeAddr{}, Gather()), ":5551", ":5552", ":5553")
+    data, err := testRun(runner, NewDataset(Strs{"a", "b", "c"}))
+    require.NoError(t, err)
+    require.Equal(t, 3, data.Len())
+
+    // fails once the context is done
+    ctx, cancel = context.WithTimeout(context.Background(), 50 * time.Millisecond)
+    defer cancel()
+    err = dist1.WaitForPeers(ctx, ":5552", ":5554")
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "ep: peer :5554 isn't ready")
+}
+
+// Tests that co-located nodes are connected by Unix domain sockets, while
+// accepting TCP connections as well
+func TestUnixSockets(t *testing.T) {
+    dir, err := ioutil.TempDir("", "ep")
+    require.NoError(t, err)
+    defer os.RemoveAll(dir)
+
+    addrs, dists := []string{}, []Distributer{}
+    for _, name := range []string{"1.sock", "2.sock"} {
+        addr := "unix:" + filepath.Join(dir, name)
+        ln, err := Listen(addr)
+        require.NoError(t, err)
+
+        tcp, err := Listen("127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(addr, ln, tcp)
+        defer dist.Close()
+        go dist.Start()
+        addrs = append(addrs, addr, tcp.Addr().String())
+        dists = append(dists, dist)
+    }
+
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    require.NoError(t, dists[0].WaitForPeers(ctx, addrs...))
+
+    runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, addrs[0], addrs[2])
+    data, err := testRun(runner, NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+    require.NoError(t, err)
+    require.Equal(t, []string{addrs[0], addrs[2]}, sortedStrings(data.At(1).Strings()))
+}
+
+// unixDialer is a listener that dials only Unix addresses, by their network
+type unixDialer struct {
+    net.Listener
+}
+
+func (ln *unixDialer) Dial(network, addr string) (net.Conn, error) {
+    if network != "unix" || strings.HasPrefix(addr, "unix:") {
+        return nil, fmt.Errorf("unexpected address %s %s", network, addr)
+    }
+    return ln.Listener.(dialer).Dial(network, addr)
+}
+
+// Tests that custom dialers are called with the network of Unix addresses
+func TestDialUnix(t *testing.T) {
+    pipes := NewPipes()
+    addrs, dists := []string{"unix:/tmp/1.sock", "unix:/tmp/2.sock"}, []Distributer{}
+    for _, addr := range addrs {
+        ln, err := pipes.Listen(addr)
+        require.NoError(t, err)
+
+        dist := NewDistributer(addr, &unixDialer{ln})
+        defer dist.Close()
+        go dist.Start()
+        dists = append(dists, dist)
+    }
+
+    runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, addrs...)
+    data, err := testRun(runner, NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+    require.NoError(t, err)
+    require.Equal(t, addrs, sortedStrings(data.At(1).Strings()))
+}
+
+func (d *distributer) isIdle() bool {
+    d.l.Lock()
+    defer d.l.Unlock()
+    return d.running == 0
+}
+
+func (d *distributer) isDraining() bool {
+    d.l.Lock()
+    defer d.l.Unlock()
+    return d.draining
+}
@@ -0,0 +1,101 @@
+package ep
+
+import (
+    "sort"
+    "context"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&distSort{})
+
+// maximum number of rows sampled on every node for determining the split
+// points of the range partitions
+const sortSamples = 100
+
+// DistributedSort returns a Runner that sorts all of its input across all of
+// the nodes, and gathers the sorted output into the master node. It's a
+// composition of several steps: first, each node sorts its local input and
+// samples it. The samples are broadcasted, and used by all nodes to determine
+// the same split points for range partitioning. Then, the local rows are range
+// partitioned by these split points, such that each node receives a distinct
+// consecutive range of rows to sort. Finally, the sorted ranges are gathered
+// in order. When not distributed, it's equivalent to Sort.
+func DistributedSort(keys []SortKey) Runner {
+    return &distSort{uuid.NewV4().String(), keys}
+}
+
+type distSort struct {
+    UID string
+    Keys []SortKey
+}
+
+func (*distSort) Returns() []Type { return []Type{Wildcard} }
+func (r *distSort) Run(ctx context.Context, inp, out chan Dataset) error {
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    if len(allNodes) == 0 {
+        return Sort(r.Keys).Run(ctx, inp, out)
+    }
+
+    // sort the local input
+    local, err := collect(ctx, Sort(r.Keys), inp)
+    if err != nil {
+        return err
+    }
+
+    // share the local samples with all nodes. The exchanges UIDs are derived
+    // from this runner's UID, in order to be the same on all nodes.
+    samples := make(chan Dataset, 1)
+    if local != nil {
+        samples <- sampleRows(local, sortSamples)
+    }
+    close(samples)
+
+    broadcast := &exchange{UID: r.UID + ":samples", SendTo: sendBroadcast}
+    all, err := collect(ctx, broadcast, samples)
+    if err != nil {
+        return err
+    }
+
+    // range partition the local rows, sort each range and gather in order
+    partition := &exchange{UID: r.UID + ":range", SendTo: sendRange}
+    partition.Keys = r.Keys
+    partition.Splits = splitPoints(all, r.Keys, len(allNodes))
+    gather := &exchange{UID: r.UID + ":gather", SendTo: sendGather, Ordered: true}
+
+    rows := make(chan Dataset, 1)
+    if local != nil {
+        rows <- local
+    }
+    close(rows)
+
+    return Pipeline(partition, Sort(r.Keys), gather).Run(ctx, rows, out)
+}
+
+// sampleRows returns up to n evenly spaced rows of the data
+func sampleRows(data Dataset, n int) Dataset {
+    if data.Len() <= n {
+        return data
+    }
+
+    rows := make([]int, n)
+    for i := range rows {
+        rows[i] = i * data.Len() / n
+    }
+    return pick(data, rows).(Dataset)
+}
+
+// splitPoints returns the n-1 rows that split the samples into n ranges of
+// roughly equal sizes. The result is the same regardless of the order of the
+// samples.
+func splitPoints(samples Dataset, keys []SortKey, n int) Dataset {
+    if samples == nil || n < 2 {
+        return nil
+    }
+
+    sort.Stable(&sortable{samples, keys})
+    rows := make([]int, n - 1)
+    for i := range rows {
+        rows[i] = (i + 1) * samples.Len() / n
+    }
+    return pick(samples, rows).(Dataset)
+}
@@ -0,0 +1,39 @@
+package ep
+
+import (
+    "sort"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestDistributedSort(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    inputs := []Dataset{}
+    expected := []string{}
+    for i := 0; i < 10; i++ {
+        keys := Strs{}
+        for j := 0; j < 50; j++ {
+            k := strconv.Itoa((i * 7919 + j * 104729) % 1000)
+            keys = append(keys, k)
+            expected = append(expected, k)
+        }
+        inputs = append(inputs, NewDataset(keys))
+    }
+
+    sort.Sort(sort.Reverse(sort.StringSlice(expected)))
+    runner := Pipeline(Scatter(), DistributedSort([]SortKey{{Col: 0, Desc: true}}))
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data, err := testRun(runner, inputs...)
+    require.NoError(t, err)
+    require.Equal(t, expected, data.At(0).Strings())
+}
+
+func TestDistributedSortNotDistributed(t *testing.T) {
+    runner := DistributedSort([]SortKey{{Col: 0}})
+    data, err := testRun(runner, NewDataset(Strs{"b", "c", "a"}))
+    require.NoError(t, err)
+    require.Equal(t, []string{"a", "b", "c"}, data.At(0).Strings())
+}
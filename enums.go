@@ -0,0 +1,130 @@
+package ep
+
+import (
+    "fmt"
+)
+
+var _ = registerGob(Enum, Enums{})
+
+// Enum is a built-in Type representing strings of small cardinality, stored as
+// codes into a dictionary of the distinct values. Use Enum.Data(n) to create
+// Data instances of `n` empty strings
+var Enum = &EnumType{}
+
+// EnumType is the Type of Enums
+type EnumType struct {}
+func (*EnumType) Name() string { return "enum" }
+func (*EnumType) Data(n uint) Data {
+    return Enums{[]string{""}, make([]uint32, n)}
+}
+
+// Enums is a built-in Data implementation of dictionary-encoded strings: every
+// value is a code of an index into the dictionary. Values are ordered, hashed
+// and compared like their strings, thus they're interchangeable with Strs (see
+// NewEnums and Strings), while repeated values are stored, transmitted and
+// hashed only once. It's intended for low-cardinality categorical columns,
+// where grouping and joining by the codes is faster than by the strings.
+//
+// The dictionary is shared between slices and copies, and is never modified
+// in-place.
+type Enums struct {
+    Dict []string
+    Codes []uint32
+}
+
+// NewEnums returns the Enums of the strings
+func NewEnums(strs ...string) Enums {
+    res := Enums{Codes: make([]uint32, len(strs))}
+    index := map[string]uint32{}
+    for i, s := range strs {
+        code, ok := index[s]
+        if !ok {
+            code = uint32(len(res.Dict))
+            index[s] = code
+            res.Dict = append(res.Dict, s)
+        }
+        res.Codes[i] = code
+    }
+    return res
+}
+
+func (Enums) Type() Type { return Enum }
+func (vs Enums) Len() int { return len(vs.Codes) }
+func (vs Enums) Less(i, j int) bool {
+    return vs.Dict[vs.Codes[i]] < vs.Dict[vs.Codes[j]]
+}
+
+func (vs Enums) Swap(i, j int) {
+    vs.Codes[i], vs.Codes[j] = vs.Codes[j], vs.Codes[i]
+}
+
+func (vs Enums) Slice(s, e int) Data {
+    return Enums{vs.Dict, vs.Codes[s:e]}
+}
+
+// Append another Enums, merging its dictionary into a copy of this dictionary
+// if needed
+func (vs Enums) Append(o Data) Data {
+    other := o.(Enums)
+    if sameDict(vs, other) {
+        codes := vs.Codes[:len(vs.Codes):len(vs.Codes)] // appending copies
+        return Enums{vs.Dict, append(codes, other.Codes...)}
+    }
+
+    index := make(map[string]uint32, len(vs.Dict))
+    for code, s := range vs.Dict {
+        index[s] = uint32(code)
+    }
+
+    dict := vs.Dict[:len(vs.Dict):len(vs.Dict)] // appending copies
+    remap := make([]uint32, len(other.Dict))
+    for code, s := range other.Dict {
+        c, ok := index[s]
+        if !ok {
+            c = uint32(len(dict))
+            index[s] = c
+            dict = append(dict, s)
+        }
+        remap[code] = c
+    }
+
+    codes := vs.Codes
+    if len(dict) > len(vs.Dict) {
+        // new codes must not be visible through the old dictionary
+        codes = codes[:len(codes):len(codes)]
+    }
+
+    for _, code := range other.Codes {
+        codes = append(codes, remap[code])
+    }
+    return Enums{dict, codes}
+}
+
+func (vs Enums) Strings() []string {
+    strs := make([]string, len(vs.Codes))
+    for i, code := range vs.Codes {
+        strs[i] = vs.Dict[code]
+    }
+    return strs
+}
+
+// to-string, for debugging. Same as the array of strings
+func (vs Enums) String() string { return fmt.Sprint(vs.Strings()) }
+
+// Hash implements Hasher, by hashing every distinct value once
+func (vs Enums) Hash(seed uint64, hashes []uint64) {
+    dict := make([]uint64, len(vs.Dict))
+    for code, s := range vs.Dict {
+        dict[code] = seed ^ HashString(s)
+    }
+
+    for i, code := range vs.Codes {
+        hashes[i] = combineHash(hashes[i], dict[code])
+    }
+}
+
+// sameDict returns true if both enums share the same dictionary, thus their
+// codes are comparable
+func sameDict(a, b Enums) bool {
+    return len(a.Dict) == len(b.Dict) && (len(a.Dict) == 0 || &a.Dict[0] == &b.Dict[0])
+}
@@ -0,0 +1,89 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleEnums() {
+    colors := NewEnums("red", "green", "red", "blue")
+    sort.Sort(colors)
+    fmt.Println(colors.Strings(), len(colors.Dict))
+
+    // Output: [blue green red red] 3
+}
+
+func ExampleUUIDs() {
+    ids, err := ParseUUIDs("6ba7b811-9dad-11d1-80b4-00c04fd430c8", "{6ba7b810-9dad-11d1-80b4-00c04fd430c8}")
+    sort.Sort(ids)
+    fmt.Println(ids.Strings(), err)
+
+    // Output: [6ba7b810-9dad-11d1-80b4-00c04fd430c8 6ba7b811-9dad-11d1-80b4-00c04fd430c8] <nil>
+}
+
+func TestEnumsAppend(t *testing.T) {
+    a := NewEnums("x", "y")
+    b := NewEnums("z", "x")
+    c := a.Slice(0, 1).Append(b).(Enums)
+    require.Equal(t, []string{"x", "z", "x"}, c.Strings())
+    require.Equal(t, []string{"x", "y", "z"}, c.Dict)
+    require.Equal(t, []string{"x", "y"}, a.Strings()) // not modified
+    require.Equal(t, []string{"x", "y"}, a.Dict)
+
+    require.Equal(t, []string{"", "", "x", "y"}, Clone(Enum.Data(2).Append(a)).Strings())
+}
+
+// enums and uuids are joined on their values, like strings
+func TestEnumsUUIDsJoin(t *testing.T) {
+    ids, err := ParseUUIDs("6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+    require.NoError(t, err)
+
+    left := &constRunner{NewDataset(ids, NewEnums("a", "b"))}
+    right := &constRunner{NewDataset(Strs{ids.Strings()[1]}, Strs{"b"})}
+    res, err := testRun(Join(InnerJoin, []int{0, 1}, []int{0, 1}, left, right))
+    require.NoError(t, err)
+    require.Equal(t, 1, res.Len())
+    require.Equal(t, ids[1:], res.At(0))
+
+    _, err = ParseUUIDs("nope")
+    require.Error(t, err)
+}
+
+// enums and uuids are serializable, in order to be distributed
+func TestEnumsUUIDsGob(t *testing.T) {
+    ids, _ := ParseUUIDs("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+    var data Data = NewDataset(ids, NewEnums("a"))
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+}
+
+// Tests that enums are hashed and grouped like their strings
+func TestEnumsGroupBy(t *testing.T) {
+    enums := NewEnums("b", "a", "b", "c")
+    h1, h2 := make([]uint64, 4), make([]uint64, 4)
+    Hash(enums, 0, h1)
+    Hash(Strs{"b", "a", "b", "c"}, 0, h2)
+    require.Equal(t, h2, h1)
+
+    runner := GroupBy([]int{0}, Count())
+    data, err := testRun(runner, NewDataset(enums), NewDataset(enums.Slice(1, 3)))
+    require.NoError(t, err)
+    require.Equal(t, "[[b a c] [3 2 1]]", fmt.Sprint(data))
+}
+
+// Tests that appending enums of the same dictionary doesn't copy it
+func TestEnumsAppendSameDict(t *testing.T) {
+    enums := NewEnums("a", "b")
+    res := enums.Append(enums.Slice(1, 2)).(Enums)
+    require.True(t, sameDict(enums, res))
+    require.Equal(t, []string{"a", "b", "b"}, res.Strings())
+    require.Equal(t, 2, enums.Len())
+}
@@ -33,13 +33,32 @@
 // latter is a built-in type for handling null-data without the overhead of
 // interfaces or pointers.
 //
+// Vectorized Execution
+//
+// Runners process whole batches, thus their per-row cost should be kept to a
+// minimum: operate on the typed columns directly (like Ints or Strs) rather
+// than on Strings() or Row() per value, produce a whole column per batch (like
+// a Bools mask for Filter), and slice or pick rows instead of copying them one
+// by one. Every batch passed over a channel costs a go-routine hand-off, which
+// dominates the cost of cheap stages over small batches; re-batch small
+// batches with Rebatch, and implement IterRunner for stateless stages, such
+// that pipelines of them are pulled as Iterators in a single go-routine:
+//
+//      func (r *upper) Run(ctx context.Context, inp, out chan ep.Dataset) error {
+//          return ep.RunIter(ctx, r, inp, out)
+//      }
+//
+//      func (r *upper) Iter(ctx context.Context, inp ep.Iterator) ep.Iterator {
+//          return ep.MapBatches(inp, r.batch)
+//      }
+//
 // Registeries
 //
 // In order to support modular design, where Runners and Types are spread across
 // several different projects, ep includes global registeries that can be used
 // to share access to these declared structures. These are available through
-// the global `Runners` and `Types` variables, and they share the same generic
-// interface:
+// the global `Runners`, `Types` and `Aggregators` variables, and they share the
+// same generic interface:
 //
 //      Runners.Register(k interface{}, r Runners) Runners
 //      Runners.Get(k interface{}) []Runner
@@ -47,6 +66,9 @@
 //      Types.Register(k interface{}, t Type) Types
 //      Types.Get(k interface{}) []Type
 //
+//      Aggregators.Register(k interface{}, agg Aggregator) Aggregators
+//      Aggregators.Get(k interface{}) []Aggregator
+//
 // It's comparable to a global key-value registry of runners and types with
 // one caveat - if the key is a struct, it's first converted into a string by
 // reflecting its full type name and path. This effectively means that
@@ -54,6 +76,18 @@
 // land on the same key. This is useful for planning, where we want to match
 // based on instances of that struct. See Planning below.
 //
+// Similarly, the `Maps` and `Functions` registeries map names to functions that
+// can't be serialized. They're registered on all nodes under the same name,
+// and referenced by name in distributed plans:
+//
+//      Maps.Register(name string, returns []Type, fn MapFunc) Maps
+//      Functions.Register(name string, returns Type, fn ScalarFunc) Functions
+//
+// Custom Runners and Data types that are transmitted between nodes are
+// registered on all nodes, for their serialization, with Register:
+//
+//      var _ = ep.Register(&myRunner{}, myData{})
+//
 // Planning
 //
 // Planning is the process of constructing Runners based on some configuration
@@ -81,15 +115,17 @@
 // kind of middlewares systems where atleast one RunnerPlan must succeed. This
 // allows an opportunistic design where several runners bind to the same node,
 // each planning it differently - if they can.
+//
+// Planned Runners can then be rewritten into equivalent, cheaper, Runners by
+// the rules of the `Rules` registry, via Optimize():
+//
+//      ep.Rules.Register(rule Rule) Rules
+//      ep.Optimize(runner) // pushes filters below exchanges, prunes picks, etc.
+//
+// Table references are resolved by name against the `Tables` catalog (see
+// Catalog), which can be synchronized to all nodes with Tables.Sync().
 package ep
 
-import (
-    "encoding/gob"
-)
-
 func registerGob(es ...interface{}) bool {
-    for _, e := range es {
-        gob.Register(e)
-    }
-    return true
+    return Register(es...)
 }
@@ -0,0 +1,230 @@
+// Package eptest provides utilities for testing ep Runners: running them to
+// completion, an in-process multi-node cluster for testing their distributed
+// behavior, and assertions that they don't leak go-routines or connections.
+package eptest
+
+import (
+    "fmt"
+    "net"
+    "sync"
+    "time"
+    "bytes"
+    "context"
+    "runtime"
+    "strings"
+    "testing"
+    "github.com/panoplyio/ep"
+)
+
+// LeakTimeout is the maximum duration to wait for go-routines and connections
+// to exit before reporting them as leaked, as the peers of distributed runs
+// may complete shortly after the master node
+var LeakTimeout = 2 * time.Second
+
+// Run runs the runner with the input datasets to completion, and returns all
+// of its output appended into a single dataset, which is empty if there was no
+// output
+func Run(r ep.Runner, datasets ...ep.Dataset) (ep.Dataset, error) {
+    var err error
+
+    inp := make(chan ep.Dataset, len(datasets))
+    for _, data := range datasets {
+        inp <- data
+    }
+    close(inp)
+
+    out := make(chan ep.Dataset)
+    go func() {
+        err = r.Run(context.Background(), inp, out)
+        close(out)
+    }()
+
+    var res = ep.NewDataset()
+    for data := range out {
+        res = res.Append(data).(ep.Dataset)
+    }
+
+    return res, err
+}
+
+// Cluster is an in-process cluster of started Distributers, connected by an
+// in-memory network (see ep.Pipes), thus clusters don't bind any ports and
+// tests may run them in parallel. The first node is the master node, that
+// distributes the runners. The connections of all nodes are tracked, see
+// OpenConns.
+type Cluster struct {
+    Distributers []ep.Distributer
+    Addrs []string
+
+    pipes *ep.Pipes
+    l sync.Mutex
+    conns int
+}
+
+// NewCluster starts a cluster of n nodes, which is closed when the test and
+// all of its subtests complete
+func NewCluster(t testing.TB, n int) *Cluster {
+    c := &Cluster{pipes: ep.NewPipes()}
+    for i := 0; i < n; i++ {
+        addr := fmt.Sprintf("node%d", i)
+        ln, err := c.pipes.Listen(addr)
+        if err != nil {
+            c.Close()
+            t.Fatal(err)
+        }
+
+        dist := ep.NewDistributer(addr, &listener{ln, c})
+        go dist.Start()
+
+        c.Distributers = append(c.Distributers, dist)
+        c.Addrs = append(c.Addrs, dist.Addr())
+    }
+
+    t.Cleanup(c.Close)
+    return c
+}
+
+// Distribute returns the runner distributed by the master node to all nodes
+func (c *Cluster) Distribute(r ep.Runner) ep.Runner {
+    return c.Distributers[0].Distribute(r, c.Addrs...)
+}
+
+// OpenConns returns the number of open connections between the nodes, either
+// dialed or accepted
+func (c *Cluster) OpenConns() int {
+    c.l.Lock()
+    defer c.l.Unlock()
+    return c.conns
+}
+
+// Close closes all of the distributers
+func (c *Cluster) Close() {
+    for _, dist := range c.Distributers {
+        dist.Close()
+    }
+}
+
+// NoOpenConns asserts that all of the connections between the nodes were
+// closed, waiting up to LeakTimeout for them to close
+func (c *Cluster) NoOpenConns(t testing.TB) {
+    t.Helper()
+    deadline := time.Now().Add(LeakTimeout)
+    for c.OpenConns() > 0 && time.Now().Before(deadline) {
+        time.Sleep(time.Millisecond)
+    }
+
+    if n := c.OpenConns(); n > 0 {
+        t.Errorf("eptest: %d open connections", n)
+    }
+}
+
+func (c *Cluster) track(conn net.Conn) net.Conn {
+    c.l.Lock()
+    defer c.l.Unlock()
+    c.conns++
+    return &trackedConn{Conn: conn, c: c}
+}
+
+// listener tracks the connections it accepts, and dials (see ep.NewDistributer)
+type listener struct {
+    net.Listener
+    c *Cluster
+}
+
+type dialer interface {
+    Dial(network, addr string) (net.Conn, error)
+}
+
+func (ln *listener) Accept() (net.Conn, error) {
+    conn, err := ln.Listener.Accept()
+    if err != nil {
+        return nil, err
+    }
+    return ln.c.track(conn), nil
+}
+
+func (ln *listener) Dial(network, addr string) (net.Conn, error) {
+    conn, err := ln.Listener.(dialer).Dial(network, addr)
+    if err != nil {
+        return nil, err
+    }
+    return ln.c.track(conn), nil
+}
+
+// trackedConn decrements the open connections of the cluster once closed
+type trackedConn struct {
+    net.Conn
+    c *Cluster
+    once sync.Once
+}
+
+func (conn *trackedConn) Close() error {
+    conn.once.Do(func() {
+        conn.c.l.Lock()
+        defer conn.c.l.Unlock()
+        conn.c.conns--
+    })
+    return conn.Conn.Close()
+}
+
+// NoLeaks returns a function that asserts that all of the go-routines started
+// since NoLeaks was called have exited, waiting up to LeakTimeout for them to
+// exit. Usage:
+//
+//      defer eptest.NoLeaks(t)()
+//
+// Go-routines that are expected to keep running, like those of a Cluster, must
+// be started before calling NoLeaks.
+func NoLeaks(t testing.TB) func() {
+    before := goroutines()
+    return func() {
+        t.Helper()
+        var leaked []string
+        deadline := time.Now().Add(LeakTimeout)
+        for {
+            leaked = leaked[:0]
+            for id, stack := range goroutines() {
+                if _, ok := before[id]; !ok {
+                    leaked = append(leaked, stack)
+                }
+            }
+
+            if len(leaked) == 0 || time.Now().After(deadline) {
+                break
+            }
+            time.Sleep(time.Millisecond)
+        }
+
+        if len(leaked) > 0 {
+            t.Errorf("eptest: %d leaked go-routines:\n\n%s", len(leaked), strings.Join(leaked, "\n\n"))
+        }
+    }
+}
+
+// goroutines returns the stacks of all of the running go-routines, by their
+// ids, except for the calling go-routine
+func goroutines() map[string]string {
+    buf := make([]byte, 1 << 16)
+    for {
+        n := runtime.Stack(buf, true)
+        if n < len(buf) {
+            buf = buf[:n]
+            break
+        }
+        buf = make([]byte, 2 * len(buf))
+    }
+
+    res := map[string]string{}
+    for i, stack := range bytes.Split(buf, []byte("\n\n")) {
+        if i == 0 {
+            continue // the calling go-routine
+        }
+
+        // goroutine 12 [running]:
+        fields := strings.Fields(string(stack))
+        if len(fields) > 1 {
+            res[fields[1]] = string(stack)
+        }
+    }
+    return res
+}
@@ -0,0 +1,84 @@
+package eptest
+
+import (
+    "fmt"
+    "testing"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleRun() {
+    data1 := ep.NewDataset(ep.Strs{"hello", "world"})
+    data2 := ep.NewDataset(ep.Strs{"foo", "bar"})
+    data, err := Run(ep.PassThrough(), data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[hello world foo bar]] <nil>
+}
+
+func TestCluster(t *testing.T) {
+    cluster := NewCluster(t, 3)
+    defer NoLeaks(t)()
+    require.Equal(t, 3, len(cluster.Addrs))
+
+    runner := cluster.Distribute(ep.Pipeline(ep.Scatter(), ep.Gather()))
+    data1 := ep.NewDataset(ep.Strs{"hello", "world"})
+    data2 := ep.NewDataset(ep.Strs{"foo", "bar"})
+    data, err := Run(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+
+    cluster.NoOpenConns(t)
+}
+
+// failer records the errors reported by the assertions
+type failer struct {
+    testing.TB
+    errors []string
+}
+
+func (f *failer) Helper() {}
+func (f *failer) Errorf(format string, args ...interface{}) {
+    f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNoLeaks(t *testing.T) {
+    LeakTimeout = 10e6 // 10ms
+    defer func() { LeakTimeout = 2e9 }()
+
+    f := &failer{TB: t}
+    done := make(chan bool)
+    check := NoLeaks(f)
+    go func() { <-done }()
+    check()
+    close(done)
+
+    require.Equal(t, 1, len(f.errors))
+    require.Contains(t, f.errors[0], "eptest: 1 leaked go-routines")
+    require.Contains(t, f.errors[0], "TestNoLeaks")
+
+    f = &failer{TB: t}
+    NoLeaks(f)()
+    require.Empty(t, f.errors)
+}
+
+func TestNoOpenConns(t *testing.T) {
+    LeakTimeout = 10e6 // 10ms
+    defer func() { LeakTimeout = 2e9 }()
+
+    cluster := NewCluster(t, 1)
+    ln, err := cluster.pipes.Listen("client")
+    require.NoError(t, err)
+
+    conn, err := (&listener{ln, cluster}).Dial("tcp", cluster.Addrs[0])
+    require.NoError(t, err)
+
+    f := &failer{TB: t}
+    cluster.NoOpenConns(f)
+    require.Equal(t, 1, len(f.errors))
+    require.Contains(t, f.errors[0], "open connections")
+
+    conn.Close()
+    LeakTimeout = 2e9
+    cluster.NoOpenConns(t)
+}
@@ -3,19 +3,21 @@ package ep
 import (
     "io"
     "net"
+    "sort"
+    "sync"
     "time"
     "context"
-    "encoding/gob"
     "github.com/satori/go.uuid"
 )
 
-var _ = registerGob(&exchange{}, &dataReq{}, &errMsg{})
+var _ = registerGob(&exchange{}, &dataReq{}, &errMsg{}, &stopMsg{})
 
 const (
     sendGather = 1
     sendScatter = 2
     sendBroadcast = 3
     sendPartition = 4
+    sendRange = 5
 )
 
 // Scatter returns an exchange Runner that scatters its input uniformly to
@@ -39,86 +41,229 @@ func Broadcast() Runner {
     return &exchange{UID: uuid.NewV4().String(), SendTo: sendBroadcast}
 }
 
-// exchange is a Runner that exchanges data between peer nodes
+// Repartition returns an exchange Runner that routes each row of its input to
+// a node determined by the values of the provided columns, such that all of
+// the rows with the same values are sent to the same node. Useful for
+// distributing work by a key, like in group-by and joins.
+func Repartition(columns ...int) Runner {
+    uid := uuid.NewV4().String()
+    return &exchange{UID: uid, SendTo: sendPartition, Columns: columns}
+}
+
+// exchange is a Runner that exchanges data between peer nodes. When it's not
+// distributed, it's a passthrough.
 type exchange struct {
     UID    string
     SendTo int
+    Columns []int // partitioning columns
+    Keys []SortKey // range partitioning keys
+    Splits Dataset // range partitioning split points, a row per split
+    Ordered bool // receive from the nodes in order, rather than round-robin
+    Sequenced bool // see ScatterOrdered and GatherOrdered
 
     encs []encoder // encoders to all destination connections
+    senders []*sender // the encoders of the remote destinations, see sender
     decs []decoder // decoders from all source connections
+    encNodes []string // destination node of each encoder
+    decNodes []string // source node of each decoder
+    ctrls []encoder // encoders to remote source nodes, for stop messages
     conns []io.Closer // all open connections (used for closing)
     encsNext int // Encoders Round Robin next index
     decsNext int // Decoders Round Robin next index
+
+    l sync.Mutex // guards stopped
+    stopped map[string]bool // destination nodes that asked to stop sending
+    stops chan struct{} // notified when a destination node stops
+}
+
+func (ex *exchange) Returns() []Type {
+    if ex.Sequenced && ex.SendTo == sendScatter {
+        return []Type{Wildcard, Int}
+    }
+    return []Type{Wildcard}
+}
+
+// the sequence numbers column is removed by a sequenced gather
+func (ex *exchange) returnsFrom(inp []Type) []Type {
+    n := len(inp)
+    if ex.Sequenced && ex.SendTo == sendGather && n > 0 && !isWildcard(inp[n - 1]) {
+        return append([]Type{}, inp[:n - 1]...)
+    }
+    return resolveWildcards(ex.Returns(), inp)
 }
 
-func (ex *exchange) Returns() []Type { return []Type{Wildcard} }
 func (ex *exchange) Run(ctx context.Context, inp, out chan Dataset) (err error) {
-    // thisNode := ctx.Value("ep.ThisNode").(string)
-    defer func() { ex.Close(err) }()
+    if ex.Sequenced && ex.SendTo == sendScatter {
+        inp = sequenced(ctx, inp)
+        defer func() { for _ = range inp {} }()
+    }
+
+    if ctx.Value("ep.AllNodes") == nil {
+        if ex.Sequenced && ex.SendTo == sendGather {
+            for data := range inp {
+                out <- unsequenced(data)
+            }
+            return nil
+        }
+        return PassThrough().Run(ctx, inp, out) // not distributed.
+    }
+
+    // the connections are per run, as the same runner might run concurrently
+    // in separate jobs, see Job
+    ex = &exchange{
+        UID: ex.UID,
+        SendTo: ex.SendTo,
+        Columns: ex.Columns,
+        Keys: ex.Keys,
+        Splits: ex.Splits,
+        Ordered: ex.Ordered,
+        Sequenced: ex.Sequenced,
+    }
+
+    ctx, end := startSpan(ctx, "ep.Exchange", map[string]string{
+        "ep.uid": ex.UID,
+        "ep.mode": exchangeModes[ex.SendTo],
+    })
+    defer func() { end(err) }()
 
     err = ex.Init(ctx)
     if err != nil {
+        ex.Close(err)
         return
     }
 
-    // receive remote data from peers in a go-routine. Write the final error (or
-    // nil) to the channel when done.
-    errs := make(chan error)
-    go func() {
-        defer close(errs)
-        for {
-            data, err := ex.Receive()
-            if err == io.EOF {
-                break
-            } else if err != nil {
-                errs <- err
-                return
-            }
-
-            out <- data
+    // receive remote data from peers in a go-routine of the group, until all
+    // of them are done sending. Its error cancels the sending below
+    g, gctx := NewGroup(ctx)
+    rcvDone := make(chan struct{})
+    g.Go(func() error {
+        defer close(rcvDone)
+        if ex.Sequenced && ex.SendTo == sendGather {
+            return ex.receiveSequenced(gctx, out)
         }
+        return ex.receiveAll(gctx, out)
+    })
 
-        errs <- nil
+    // when canceled, the exchange is stopped gracefully (see below), thus the
+    // cancellation error isn't transmitted to the peers. Closing the exchange
+    // also unblocks the receiving go-routine, if it's still running
+    defer func() {
+        g.Cancel()
+        if ctx.Err() != nil {
+            ex.Close(nil)
+        } else {
+            ex.Close(err)
+        }
+        g.Wait()
     }()
 
-    // send the local data to the peers, until completion or error. Also listen
-    // for the completetion of the received go-routine above. When both sending
-    // and receiving is complete, exit. Upon error, exit early.
-    rcvDone := false
-    sndDone := false
-    for err == nil && (!rcvDone || !sndDone) {
+    // send the local data to the peers, until completion or error. Then wait
+    // for the receiving to complete. Upon error, exit early.
+    err = ex.sendAll(gctx, inp)
+    if err == nil {
+        select {
+        case <- rcvDone:
+        case <- ctx.Done():
+        }
+    }
+
+    if ctx.Err() != nil {
+        // context timeout or cancel, usually because the downstream runners
+        // are done (like a LIMIT). Ask the source nodes to stop sending, and
+        // drain the data they've already sent until they're done. This allows
+        // them to exit gracefully.
+        ex.StopAll()
+        if err != nil {
+            select {
+            case <- rcvDone:
+                ex.closeShortCircuit() // there's no local receiver to notify
+            default:
+            }
+            ex.EncodeAll(io.EOF)
+        }
+
+        timer := time.NewTimer(time.Second)
+        select {
+        case <- rcvDone:
+        case <- timer.C:
+        }
+        timer.Stop()
+        return ctx.Err()
+    } else if err == nil || gctx.Err() != nil {
+        return g.Wait() // the error of the receiving, if any
+    }
+    return err
+}
+
+// sendAll sends the input to the destination nodes until it's exhausted, and
+// then notifies them that we're done sending data (they will use it to stop
+// listening to data from us). Returns early when the context is done.
+func (ex *exchange) sendAll(ctx context.Context, inp chan Dataset) error {
+    for {
         select {
         case data, ok := <- inp:
             if !ok {
-                // the input is exhauted. Notify peers that we're done sending
-                // data (they will use it to stop listening to data from us).
                 ex.EncodeAll(io.EOF)
-                sndDone = true
-
-                // inp is closed. If we keep iterating, it will infinitely
-                // resolve to (nil, true). Nill-ify it to block it on the next
-                // iteration.
-                inp = nil
-                continue
+                return ex.flush()
             }
 
-            err = ex.Send(data)
-        case err = <- errs:
-            rcvDone = true // errors (or nil) from the receive go-routine
+            err := ex.Send(data)
+            if err != nil {
+                return err
+            }
+        case <- ex.stops:
+            // all of the destinations have stopped, there's no need to keep
+            // sending (or reading the input). This will cancel the upstream
+            // runners.
+            if ex.AllStopped() {
+                ex.EncodeAll(io.EOF)
+                return nil
+            }
         case <- ctx.Done():
-            err = ctx.Err() // context timeout or cancel
+            return ctx.Err()
         }
     }
+}
 
-    return err
+// receiveAll receives the remote data from the source nodes into the output,
+// until all of them are done sending. When the context is done, the data is
+// discarded instead, in order to drain the peers until they're done.
+func (ex *exchange) receiveAll(ctx context.Context, out chan Dataset) error {
+    for {
+        data, err := ex.Receive()
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+
+        Metrics.ExchangeQueue.Add(1)
+        select {
+        case out <- data:
+        case <- ctx.Done():
+        }
+        Metrics.ExchangeQueue.Add(-1)
+    }
 }
 
-// Send a dataset to destination nodes
+// Send a dataset to destination nodes. Datasets larger than BatchSize are sent
+// in several batches, in order to bound the size of the messages.
 func (ex *exchange) Send(data Dataset) error {
+    for data.Len() > BatchSize {
+        err := ex.send(Slice(data, 0, BatchSize))
+        if err != nil {
+            return err
+        }
+        data = Slice(data, BatchSize, data.Len())
+    }
+    return ex.send(data)
+}
+
+func (ex *exchange) send(data Dataset) error {
     switch ex.SendTo {
     case sendScatter:
         return ex.EncodeNext(data)
-    case sendPartition:
+    case sendPartition, sendRange:
         return ex.EncodePartition(data)
     default:
         return ex.EncodeAll(data)
@@ -134,7 +279,13 @@ func (ex *exchange) Receive() (Dataset, error) {
 func (ex *exchange) Close(err error) error {
     var errOut error
     if err != nil {
-        errOut = ex.EncodeAll(err)
+        // this node is already failing with the error, and its receiver might
+        // have already exited, thus the error is only sent to the peers
+        ex.closeShortCircuit()
+        ex.within(senderCloseTimeout, func() {
+            ex.EncodeAll(err)
+            ex.flush()
+        })
 
         // the error below is triggered very infrequently when we hang up too
         // fast. 1ms timeout in case of error is a good tradeoff compared to
@@ -142,6 +293,9 @@ func (ex *exchange) Close(err error) error {
         time.Sleep(1 * time.Millisecond) // use of closed network connection
     }
 
+    // the senders are flushed when the sending completes, otherwise a peer
+    // that has stopped reading might block them. Closing the connections
+    // unblocks them
     for _, conn := range ex.conns {
         err1 := conn.Close()
         if err1 != nil {
@@ -149,10 +303,60 @@ func (ex *exchange) Close(err error) error {
         }
     }
 
+    ex.closeSenders()
     return errOut
 }
 
-// Encode an object to all destination connections
+// flush waits for the datasets queued to the remote destinations to be sent,
+// and returns the first error, if any
+func (ex *exchange) flush() (err error) {
+    for _, s := range ex.senders {
+        err1 := s.Flush()
+        if err1 != nil && err == nil {
+            err = err1
+        }
+    }
+    return err
+}
+
+// within waits for the sending function to complete, up to the timeout, as a
+// peer that has stopped reading might block it until the connections are
+// closed
+func (ex *exchange) within(timeout time.Duration, send func()) {
+    sent := make(chan struct{})
+    go func() {
+        defer close(sent)
+        send()
+    }()
+
+    timer := time.NewTimer(timeout)
+    defer timer.Stop()
+    select {
+    case <- sent:
+    case <- timer.C:
+    }
+}
+
+// closeSenders stops the senders, once their connections are closed. Their
+// errors were already returned by the sending
+func (ex *exchange) closeSenders() {
+    for _, s := range ex.senders {
+        s.Close()
+    }
+}
+
+// closeShortCircuit closes the local queue of the datasets sent to this node,
+// if any, such that encoding into it fails rather than blocks
+func (ex *exchange) closeShortCircuit() {
+    for _, conn := range ex.conns {
+        if sc, ok := conn.(*shortCircuit); ok {
+            sc.Close()
+        }
+    }
+}
+
+// Encode an object to all destination connections. Datasets are not sent to
+// stopped destinations.
 func (ex *exchange) EncodeAll(e interface{}) (err error) {
     err, _ = e.(error)
     if err != nil {
@@ -160,8 +364,14 @@ func (ex *exchange) EncodeAll(e interface{}) (err error) {
         err = nil
     }
 
+    _, isData := e.(Dataset)
+
     req := &dataReq{e}
-    for _, enc := range ex.encs {
+    for i, enc := range ex.encs {
+        if isData && ex.IsStopped(i) {
+            continue
+        }
+
         err1 := enc.Encode(req)
         if err1 != nil {
             err = err1
@@ -177,13 +387,63 @@ func (ex *exchange) EncodeNext(e interface{}) error {
         return io.ErrClosedPipe
     }
 
-    req := &dataReq{e}
-    ex.encsNext = (ex.encsNext + 1) % len(ex.encs)
-    return ex.encs[ex.encsNext].Encode(req)
+    // skip stopped destinations. When all are stopped, the data is dropped.
+    for i := 0; i < len(ex.encs); i++ {
+        ex.encsNext = (ex.encsNext + 1) % len(ex.encs)
+        if !ex.IsStopped(ex.encsNext) {
+            return ex.encs[ex.encsNext].Encode(&dataReq{e})
+        }
+    }
+    return nil
 }
 
-// Encode an object to a destination connection selected by partitioning
+// Encode the rows of a dataset to the destination connections selected by
+// either hashing the values of the partitioning columns, or by the ranges
+// between the split points.
 func (ex *exchange) EncodePartition(e interface{}) error {
+    if len(ex.encs) == 0 {
+        return io.ErrClosedPipe
+    }
+
+    data := e.(Dataset)
+    rows := make([][]int, len(ex.encs))
+    if ex.SendTo == sendRange {
+        for i := 0; i < data.Len(); i++ {
+            // the destination is the number of split points lower than or
+            // equal to the row. Thus node i receives the rows between split
+            // points i-1 and i.
+            n := 0
+            if ex.Splits != nil {
+                n = ex.Splits.Len()
+            }
+
+            dest := sort.Search(n, func(j int) bool {
+                return lessRows(data, i, ex.Splits, j, ex.Keys)
+            })
+
+            if dest >= len(ex.encs) {
+                dest = len(ex.encs) - 1
+            }
+            rows[dest] = append(rows[dest], i)
+        }
+    } else {
+        for i, h := range hashRows(data, ex.Columns) {
+            dest := h % uint64(len(ex.encs))
+            rows[dest] = append(rows[dest], i)
+        }
+    }
+
+    for i, enc := range ex.encs {
+        if len(rows[i]) == 0 || ex.IsStopped(i) {
+            continue
+        }
+
+        err := enc.Encode(&dataReq{pick(data, rows[i])})
+        if err != nil {
+            return err
+        }
+    }
+
     return nil
 }
 
@@ -194,40 +454,116 @@ func (ex *exchange) DecodeNext() (Dataset, error) {
     }
 
     i := (ex.decsNext + 1) % len(ex.decs)
-
-    req := &dataReq{}
-    err := ex.decs[i].Decode(req)
-    data := req.Payload
-    if err == nil {
-        err, _ = data.(error)
-    }
-
-    if err != nil && err.Error() == io.EOF.Error() {
-        err = io.EOF
+    if ex.Ordered {
+        i = 0 // exhaust the first source before moving on to the next
     }
 
+    data, err := ex.decodeFrom(i)
     if err == io.EOF {
         // remove the current decoder and try again
         ex.decs = append(ex.decs[:i], ex.decs[i + 1:]...)
+        ex.decNodes = append(ex.decNodes[:i], ex.decNodes[i + 1:]...)
         return ex.DecodeNext()
     } else if err != nil {
         return nil, err
     }
 
     ex.decsNext = i
-    return data.(Dataset), nil
+    return data, nil
+}
+
+// Decode the next dataset from the i-th source connection, or io.EOF when the
+// source is done sending
+func (ex *exchange) decodeFrom(i int) (Dataset, error) {
+    for {
+        req := &dataReq{}
+        err := ex.decs[i].Decode(req)
+        data := req.Payload
+        if err == nil {
+            err, _ = data.(error)
+        }
+
+        if _, ok := data.(*stopMsg); ok && err == nil {
+            // the source is also a destination that has stopped. Keep
+            // receiving its data until EOF.
+            ex.Stop(ex.decNodes[i])
+            continue
+        }
+
+        if err != nil && err.Error() == io.EOF.Error() {
+            err = io.EOF
+        }
+
+        if err != nil {
+            return nil, err
+        }
+        return data.(Dataset), nil
+    }
+}
+
+// Stop sending data to the destination node
+func (ex *exchange) Stop(node string) {
+    ex.l.Lock()
+    ex.stopped[node] = true
+    ex.l.Unlock()
+
+    select {
+    case ex.stops <- struct{}{}:
+    default: // already notified
+    }
+}
+
+// IsStopped returns true if the destination of the i-th encoder has stopped
+func (ex *exchange) IsStopped(i int) bool {
+    ex.l.Lock()
+    defer ex.l.Unlock()
+    return ex.stopped[ex.encNodes[i]]
+}
+
+// AllStopped returns true if all of the destinations have stopped
+func (ex *exchange) AllStopped() bool {
+    for i := range ex.encs {
+        if !ex.IsStopped(i) {
+            return false
+        }
+    }
+    return true
+}
+
+// StopAll asks all of the remote source nodes to stop sending data
+func (ex *exchange) StopAll() {
+    for _, enc := range ex.ctrls {
+        enc.Encode(&dataReq{&stopMsg{}})
+    }
+}
+
+// listen to stop messages from a destination node that isn't also a source,
+// and thus its connection isn't decoded by DecodeNext
+func (ex *exchange) listenStop(node string, conn net.Conn) {
+    dec := NewWireDecoder(conn)
+    for {
+        req := &dataReq{}
+        err := dec.Decode(req)
+        if err != nil {
+            return // connection closed
+        }
+
+        if _, ok := req.Payload.(*stopMsg); ok {
+            ex.Stop(node)
+        }
+    }
 }
 
 // initialize the connections, encoders & decoders
 func (ex *exchange) Init(ctx context.Context) error {
     var err error
+    ex.stopped = map[string]bool{}
+    ex.stops = make(chan struct{}, 1)
 
     allNodes := ctx.Value("ep.AllNodes").([]string)
     thisNode := ctx.Value("ep.ThisNode").(string)
     masterNode := ctx.Value("ep.MasterNode").(string)
-    dist := ctx.Value("ep.Distributer").(interface {
-        Connect(addr, uid string) (net.Conn, error)
-    })
+    dist := ctx.Value("ep.Distributer").(connector)
 
     targetNodes := allNodes
     if ex.SendTo == sendGather {
@@ -237,25 +573,39 @@ func (ex *exchange) Init(ctx context.Context) error {
     // open a connection to all target nodes
     var conn net.Conn
     connsMap := map[string]net.Conn{}
+    encsMap := map[string]encoder{}
     var shortCircuit *shortCircuit
     for _, n := range targetNodes {
         if n == thisNode {
-            shortCircuit = newShortCircuit()
+            shortCircuit = newShortCircuit(buffersOf(ctx).ShortCircuit, memoryOf(ctx))
             ex.conns = append(ex.conns, shortCircuit)
             ex.encs = append(ex.encs, shortCircuit)
+            ex.encNodes = append(ex.encNodes, n)
             continue
         }
 
         msg := "THIS " + thisNode + " OTHER " + n
 
-        conn, err = dist.Connect(n, ex.UID)
+        conn, err = ex.connect(ctx, dist, n)
         if err != nil {
             return err
         }
 
+        enc := newSender(dbgEncoder{NewWireEncoder(conn), msg}, buffersOf(ctx).Send)
+        ex.senders = append(ex.senders, enc)
         connsMap[n] = conn
+        encsMap[n] = enc
         ex.conns = append(ex.conns, conn)
-        ex.encs = append(ex.encs, dbgEncoder{gob.NewEncoder(conn), msg})
+        ex.encs = append(ex.encs, enc)
+        ex.encNodes = append(ex.encNodes, n)
+    }
+
+    // if we're not a destination, the connections are only used for sending
+    // data, and receiving stop messages
+    for n, conn := range connsMap {
+        if shortCircuit == nil {
+            go ex.listenStop(n, conn)
+        }
     }
 
     // if we're also a destination, listen to all nodes
@@ -264,6 +614,7 @@ func (ex *exchange) Init(ctx context.Context) error {
 
         if n == thisNode {
             ex.decs = append(ex.decs, shortCircuit)
+            ex.decNodes = append(ex.decNodes, n)
             continue
         }
 
@@ -272,22 +623,44 @@ func (ex *exchange) Init(ctx context.Context) error {
         // if we already established a connection to this node from the targets,
         // re-use it. We don't need 2 uni-directional connections.
         if connsMap[n] != nil {
-            ex.decs = append(ex.decs, dbgDecoder{gob.NewDecoder(connsMap[n]), msg})
+            ex.decs = append(ex.decs, dbgDecoder{NewWireDecoder(connsMap[n]), msg})
+            ex.decNodes = append(ex.decNodes, n)
+            ex.ctrls = append(ex.ctrls, encsMap[n])
             continue
         }
 
-        conn, err = dist.Connect(n, ex.UID)
+        conn, err = ex.connect(ctx, dist, n)
         if err != nil {
             return err
         }
 
         ex.conns = append(ex.conns, conn)
-        ex.decs = append(ex.decs, dbgDecoder{gob.NewDecoder(conn), msg})
+        ex.decs = append(ex.decs, dbgDecoder{NewWireDecoder(conn), msg})
+        ex.decNodes = append(ex.decNodes, n)
+        ex.ctrls = append(ex.ctrls, dbgEncoder{NewWireEncoder(conn), msg})
     }
 
     return nil
 }
 
+// connector connects to the other nodes, see Distributer
+type connector interface {
+    Connect(addr, uid string) (net.Conn, error)
+}
+
+// connect to a node for this exchange. When the exchanges are acknowledged
+// (see WithAcks), the connection is re-established when it breaks
+func (ex *exchange) connect(ctx context.Context, dist connector, node string) (net.Conn, error) {
+    conn, err := dist.Connect(node, ex.UID)
+    if err != nil || !acksOf(ctx) {
+        return conn, err
+    }
+
+    return newAckedConn(conn, func() (net.Conn, error) {
+        return dist.Connect(node, ex.UID)
+    }), nil
+}
+
 // interfqace for gob.Encoder/Decoder. Used to also implement the short-circuit.
 type encoder interface { Encode(interface{}) error }
 type decoder interface { Decode(interface{}) error }
@@ -310,11 +683,13 @@ func (dec dbgDecoder) Decode(e interface{}) error {
 
 // shortCircuit implements io.Closer, encoder and dedocder and provides the
 // means to short-circuit internal communications within the same node. This is
-// in order to not complicate the generic nature of the exchange code
+// in order to not complicate the generic nature of the exchange code. The
+// queued datasets are accounted by the memory manager, if any
 type shortCircuit struct {
     C chan interface{};
     Closed bool
     all []interface{}
+    mem *MemoryManager
 }
 
 func (sc *shortCircuit) Close() error {
@@ -333,6 +708,9 @@ func (sc *shortCircuit) Encode(e interface{}) error {
         return io.ErrClosedPipe
     }
 
+    if sc.mem != nil {
+        sc.mem.Force(queuedBytes(e))
+    }
     sc.C <- e;
     // fmt.Println("SC: Encoded", e)
     return nil
@@ -345,14 +723,27 @@ func (sc *shortCircuit) Decode(e interface{}) error {
         return io.EOF
     }
 
+    if sc.mem != nil {
+        sc.mem.Release(queuedBytes(v))
+    }
     req.Payload = v.(*dataReq).Payload
     return nil
 }
 
-func newShortCircuit() *shortCircuit {
-    return &shortCircuit{C: make(chan interface{}, 1000)}
+func newShortCircuit(size int, mem *MemoryManager) *shortCircuit {
+    return &shortCircuit{C: make(chan interface{}, size), mem: mem}
+}
+
+// queuedBytes returns the estimated size of the dataset of the request, if any
+func queuedBytes(e interface{}) int {
+    data, ok := e.(*dataReq).Payload.(Dataset)
+    if !ok {
+        return 0
+    }
+    return estimateBytes(data)
 }
 
 type dataReq struct { Payload interface{} }
+type stopMsg struct {}
 type errMsg struct { Msg string }
 func (err *errMsg) Error() string { return err.Msg }
@@ -1,8 +1,10 @@
 package ep
 
 import (
+    "io"
     "fmt"
     "net"
+    "sync"
     "time"
     "context"
     "testing"
@@ -19,12 +21,13 @@ func (e *errDialer) Dial(net, addr string) (net.Conn, error) {
 // datasets. Thus the output in the local node just returns half of the output.
 // Pipelining into a Gather runner would recollected the scattered outputs
 func ExampleScatter() {
-    ln1, _ := net.Listen("tcp", ":5551")
+    pipes := NewPipes()
+    ln1, _ := pipes.Listen(":5551")
     dist1 := NewDistributer(":5551", ln1)
     go dist1.Start()
     defer dist1.Close()
 
-    ln2, _ := net.Listen("tcp", ":5552")
+    ln2, _ := pipes.Listen(":5552")
     dist2 := NewDistributer(":5552", ln2)
     go dist2.Start()
     defer dist2.Close()
@@ -42,16 +45,15 @@ func ExampleScatter() {
 // Test that errors are transmitted across the network (an error in one node
 // is reported to the calling node).
 func TestExchangeErr(t *testing.T) {
-    defer time.Sleep(1 * time.Millisecond) // bind: address already in use
-
-    ln1, err := net.Listen("tcp", ":5551")
+    pipes := NewPipes()
+    ln1, err := pipes.Listen(":5551")
     require.NoError(t, err)
 
     dist1 := NewDistributer(":5551", ln1)
     defer dist1.Close()
     go dist1.Start()
 
-    ln2, err := net.Listen("tcp", ":5552")
+    ln2, err := pipes.Listen(":5552")
     require.NoError(t, err)
 
     dialer := &errDialer{ln2, fmt.Errorf("bad connection")}
@@ -59,7 +61,7 @@ func TestExchangeErr(t *testing.T) {
     defer dist2.Close()
     go dist2.Start()
 
-    ln3, err := net.Listen("tcp", ":5553")
+    ln3, err := pipes.Listen(":5553")
     require.NoError(t, err)
 
     dist3 := NewDistributer(":5553", ln3)
@@ -79,7 +81,8 @@ func TestExchangeErr(t *testing.T) {
 // Tests the scattering when there's just one node - the whole thing should
 // be short-circuited to act as a pass-through
 func TestScatterSingleNode(t *testing.T) {
-    ln, err := net.Listen("tcp", ":5551")
+    pipes := NewPipes()
+    ln, err := pipes.Listen(":5551")
     require.NoError(t, err)
 
     dist := NewDistributer(":5551", ln)
@@ -97,22 +100,10 @@ func TestScatterSingleNode(t *testing.T) {
 }
 
 func TestScatterGather(t *testing.T) {
-    ln1, err := net.Listen("tcp", ":5551")
-    require.NoError(t, err)
-
-    dist1 := NewDistributer(":5551", ln1)
-    defer dist1.Close()
-    go dist1.Start()
-
-    ln2, err := net.Listen("tcp", ":5552")
-    require.NoError(t, err)
-
-    dist2 := NewDistributer(":5552", ln2)
-    defer dist2.Close()
-    go dist2.Start()
+    dists := testCluster(t, 2)
 
     runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
-    runner = dist1.Distribute(runner, ":5551", ":5552")
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
 
     data1 := NewDataset(Strs{"hello", "world"})
     data2 := NewDataset(Strs{"foo", "bar"})
@@ -150,3 +141,118 @@ func (*nodeAddr) Run(ctx context.Context, inp, out chan Dataset) error {
     }
     return nil
 }
+
+// Test that nodes listening on one address (like a private address behind a
+// NAT) but advertising another, use the advertised addresses consistently
+func TestScatterGatherAdvertised(t *testing.T) {
+    ln1, err := net.Listen("tcp", "0.0.0.0:5551")
+    require.NoError(t, err)
+
+    dist1 := NewDistributer("127.0.0.1:5551", ln1)
+    defer dist1.Close()
+    go dist1.Start()
+
+    ln2, err := net.Listen("tcp", "0.0.0.0:5552")
+    require.NoError(t, err)
+
+    dist2 := NewDistributer("127.0.0.1:5552", ln2)
+    defer dist2.Close()
+    go dist2.Start()
+
+    require.Equal(t, "127.0.0.1:5551", dist1.Addr())
+
+    runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
+    runner = dist1.Distribute(runner, dist1.Addr(), dist2.Addr())
+
+    data1 := NewDataset(Strs{"hello", "world"})
+    data2 := NewDataset(Strs{"foo", "bar"})
+    data, err := testRun(runner, data1, data2)
+
+    require.NoError(t, err)
+    require.Equal(t, "[[hello world foo bar] [127.0.0.1:5552 127.0.0.1:5552 127.0.0.1:5551 127.0.0.1:5551]]", fmt.Sprintf("%v", data))
+}
+
+// Test that rows with the same values are always sent to the same node
+func TestRepartition(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), Repartition(0), &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data1 := NewDataset(Strs{"a", "b", "c", "d"})
+    data2 := NewDataset(Strs{"d", "c", "b", "a"})
+    data3 := NewDataset(Strs{"a", "b", "c", "d"})
+    data, err := testRun(runner, data1, data2, data3)
+    require.NoError(t, err)
+    require.Equal(t, 12, data.Len())
+
+    nodes := map[string]string{}
+    addrs := data.At(1).Strings()
+    for i, k := range data.At(0).Strings() {
+        if nodes[k] == "" {
+            nodes[k] = addrs[i]
+        }
+        require.Equal(t, nodes[k], addrs[i], "key %s sent to multiple nodes", k)
+    }
+}
+
+// exchanges are passthrough when they're not distributed
+func TestExchangeNotDistributed(t *testing.T) {
+    runner := Pipeline(Scatter(), Repartition(0), Gather())
+    data, err := testRun(runner, NewDataset(Strs{"hello", "world"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[hello world]]", fmt.Sprintf("%v", data))
+}
+
+// stuckConn is the connection of a peer that has stopped reading. Its writes
+// block until it's closed
+type stuckConn struct {
+    net.Conn
+    closed chan struct{}
+    once sync.Once
+}
+
+func (c *stuckConn) Write(b []byte) (int, error) {
+    <- c.closed
+    return 0, net.ErrClosed
+}
+
+func (c *stuckConn) Close() error {
+    c.once.Do(func() { close(c.closed) })
+    return nil
+}
+
+// Tests that closing an exchange doesn't block on a peer that has stopped
+// reading, with or without an error
+func TestExchangeCloseStuck(t *testing.T) {
+    defer func() { senderCloseTimeout = time.Second }()
+    senderCloseTimeout = 10 * time.Millisecond
+
+    for _, err := range []error{nil, fmt.Errorf("bad")} {
+        conn := &stuckConn{closed: make(chan struct{})}
+        s := newSender(NewWireEncoder(conn), 1)
+        ex := &exchange{
+            encs: []encoder{s},
+            senders: []*sender{s},
+            conns: []io.Closer{conn},
+            encNodes: []string{":5552"},
+            stopped: map[string]bool{},
+        }
+
+        // the first dataset is written, while the second is queued
+        require.NoError(t, ex.EncodeAll(NewDataset(Strs{"a"})))
+        require.NoError(t, ex.EncodeAll(NewDataset(Strs{"b"})))
+
+        closed := make(chan struct{})
+        go func() {
+            ex.Close(err)
+            close(closed)
+        }()
+
+        select {
+        case <- closed:
+        case <- time.After(5 * time.Second):
+            t.Fatalf("blocked on closing with error %v", err)
+        }
+    }
+}
@@ -0,0 +1,269 @@
+package ep
+
+import (
+    "fmt"
+    "bytes"
+    "reflect"
+    "strings"
+)
+
+// PlanNode describes a single runner of a plan, as explained by Explain
+type PlanNode struct {
+    Kind string // the kind of the runner, like Pipeline, Join or Exchange
+    Detail string // kind-specific details, like the columns of a Pick
+    Returns []Type
+    Exchange string // gather, scatter, broadcast, partition or range
+    Nodes []string // the nodes that run it, or nil when it's not distributed
+    Targets []string // the nodes that exchanges send their data to
+    Stats []RunStats // by node, attached by Analyze
+    Children []*PlanNode
+}
+
+// Explain walks the composed plan, and describes what runs where: a node per
+// runner, where the stages of pipelines and the runners of projects are
+// flattened into their children. Runners that aren't composed of the built-in
+// composite runners are described as a whole, by their type name and String()
+// method, if any. The plan isn't modified nor run.
+func Explain(r Runner) *PlanNode {
+    return explain(r, []Type{Wildcard}, nil, "")
+}
+
+// explain describes the runner, given the types of its input, which are used
+// for resolving the return types of pipelined runners
+func explain(r Runner, inp []Type, nodes []string, master string) *PlanNode {
+    n := &PlanNode{Kind: runnerKind(r), Returns: returnsFrom(r, inp), Nodes: nodes}
+    if s, ok := r.(fmt.Stringer); ok {
+        n.Detail = s.String()
+    }
+
+    switch r := r.(type) {
+    case *distRunner:
+        n.Nodes, master = r.Addrs, r.MasterAddr
+    case *exchange:
+        n.Exchange = exchangeModes[r.SendTo]
+        if r.SendTo == sendPartition {
+            n.Detail = fmt.Sprint(r.Columns)
+        } else if r.SendTo == sendRange {
+            n.Detail = fmt.Sprint(r.Keys)
+        } else if r.Sequenced {
+            n.Detail = "ordered"
+        }
+
+        n.Targets = nodes
+        if r.SendTo == sendGather && nodes != nil {
+            n.Targets = []string{master}
+        }
+    case *join:
+        n.Detail = joinDetail(r)
+    case *distJoin:
+        n.Detail = joinDetail(r.Join)
+        switch r.Threshold {
+        case alwaysBroadcast:
+            n.Detail += " broadcast"
+        case alwaysPartition:
+            n.Detail += " partition"
+        default:
+            n.Detail += fmt.Sprintf(" threshold %d", r.Threshold)
+        }
+    case *filter:
+        n.Detail = predicateDetail(r.Predicate)
+    case *picker:
+        n.Detail = fmt.Sprint(r.Cols)
+    case *dropper:
+        n.Detail = fmt.Sprint(r.Cols)
+    case *rename:
+        n.Detail = fmt.Sprintf("%s -> %s", r.Old, r.New)
+    case *limit:
+        n.Detail = fmt.Sprint(r.N)
+    case *offset:
+        n.Detail = fmt.Sprint(r.N)
+    }
+
+    _, isPipeline := r.(*pipeline)
+    for _, child := range planChildren(r) {
+        c := explain(child, inp, n.Nodes, master)
+        n.Children = append(n.Children, c)
+        if isPipeline {
+            inp = c.Returns
+        }
+    }
+    return n
+}
+
+// planChildren returns the inner runners of the runner, as explained
+func planChildren(r Runner) []Runner {
+    switch r := r.(type) {
+    case *pipeline:
+        return Stages(r)
+    case *project:
+        return projected(r)
+    case composite:
+        return r.inner()
+    }
+    return nil
+}
+
+// withPlanChildren returns a copy of the runner composed of the provided inner
+// runners instead of its own. See planChildren
+func withPlanChildren(r Runner, children []Runner) Runner {
+    switch r := r.(type) {
+    case *pipeline:
+        return Pipeline(children...)
+    case *project:
+        return Project(children...)
+    case composite:
+        return r.withInner(children)
+    }
+    return r
+}
+
+var exchangeModes = map[int]string{
+    sendGather: "gather",
+    sendScatter: "scatter",
+    sendBroadcast: "broadcast",
+    sendPartition: "partition",
+    sendRange: "range",
+}
+
+var joinTypes = map[JoinType]string{
+    InnerJoin: "inner",
+    LeftJoin: "left",
+    RightJoin: "right",
+    FullJoin: "full",
+    SemiJoin: "semi",
+    AntiJoin: "anti",
+}
+
+// runnerKind returns the kind of the runner, which is its exported constructor
+// name for most of the built-in runners, or its type name otherwise
+func runnerKind(r Runner) string {
+    switch r.(type) {
+    case *distRunner: return "Distribute"
+    case *pipeline: return "Pipeline"
+    case *project: return "Project"
+    case *exchange: return "Exchange"
+    case *join: return "Join"
+    case *distJoin: return "DistributedJoin"
+    case *union: return "Union"
+    case *filter: return "Filter"
+    case *picker: return "Pick"
+    case *dropper: return "Drop"
+    case *rename: return "Rename"
+    case *limit: return "Limit"
+    case *offset: return "Offset"
+    case *wrap: return "Wrap"
+    case *withMemory: return "WithMemory"
+    case *withBuffers: return "WithBuffers"
+    case *withProgress: return "WithProgress"
+    case *withAcks: return "WithAcks"
+    case *tee: return "Tee"
+    case *passthrough: return "PassThrough"
+    case *scanData: return "ScanDataset"
+    case *speculate: return "Speculate"
+    case *singleton: return "Singleton"
+    }
+
+    t := reflect.TypeOf(r)
+    for t.Kind() == reflect.Ptr {
+        t = t.Elem()
+    }
+    return t.String()
+}
+
+func joinDetail(r *join) string {
+    return fmt.Sprintf("%s %v = %v", joinTypes[r.Type], r.LeftKeys, r.RightKeys)
+}
+
+func predicateDetail(p Predicate) string {
+    switch p := p.(type) {
+    case *where:
+        return fmt.Sprintf("$%d %s %q", p.Col, p.Op, p.Value)
+    case *isTrue:
+        return fmt.Sprintf("$%d", p.Col)
+    case fmt.Stringer:
+        return p.String()
+    }
+    return ""
+}
+
+// String renders the plan as an indented text tree, with a line per node
+func (n *PlanNode) String() string {
+    var b bytes.Buffer
+    n.writeText(&b, 0, nil)
+    return b.String()
+}
+
+func (n *PlanNode) writeText(b *bytes.Buffer, depth int, parentNodes []string) {
+    b.WriteString(strings.Repeat("  ", depth))
+    b.WriteString(n.label(" ", parentNodes))
+    b.WriteString("\n")
+    for _, child := range n.Children {
+        child.writeText(b, depth + 1, n.Nodes)
+    }
+}
+
+// label describes the node in a single line, separated by sep. The nodes are
+// omitted when they're the same as the nodes of the parent
+func (n *PlanNode) label(sep string, parentNodes []string) string {
+    parts := []string{n.Kind}
+    if n.Detail != "" {
+        parts = append(parts, n.Detail)
+    }
+
+    if n.Exchange != "" {
+        parts = append(parts, "mode=" + n.Exchange)
+    }
+
+    parts = append(parts, "returns=" + typesString(n.Returns))
+    if n.Nodes != nil && !reflect.DeepEqual(n.Nodes, parentNodes) {
+        parts = append(parts, fmt.Sprintf("nodes=%v", n.Nodes))
+    }
+
+    if n.Targets != nil {
+        parts = append(parts, fmt.Sprintf("to=%v", n.Targets))
+    }
+
+    if n.Stats != nil {
+        parts = append(parts, total(n.Stats).String())
+    }
+    return strings.Join(parts, sep)
+}
+
+// Dot renders the plan as a Graphviz DOT digraph, with an edge from every
+// node to each of its children
+func (n *PlanNode) Dot() string {
+    var b bytes.Buffer
+    b.WriteString("digraph plan {\n")
+    b.WriteString("    node [shape=box];\n")
+
+    id := 0
+    var write func(n *PlanNode, parentNodes []string) int
+    write = func(n *PlanNode, parentNodes []string) int {
+        this := id
+        id++
+
+        fmt.Fprintf(&b, "    n%d [label=%q];\n", this, n.label("\n", parentNodes))
+        for _, child := range n.Children {
+            fmt.Fprintf(&b, "    n%d -> n%d;\n", this, write(child, n.Nodes))
+        }
+        return this
+    }
+
+    write(n, nil)
+    b.WriteString("}\n")
+    return b.String()
+}
+
+// typesString returns the names of the types, prefixed by their column names
+// if they're named (see As)
+func typesString(types []Type) string {
+    names := []string{}
+    for _, t := range types {
+        name := t.Name()
+        if as, ok := t.(interface{ As() string }); ok && as.As() != "" {
+            name = as.As() + ":" + name
+        }
+        names = append(names, name)
+    }
+    return "[" + strings.Join(names, " ") + "]"
+}
@@ -0,0 +1,69 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleExplain() {
+    ln, _ := net.Listen("tcp", "127.0.0.1:0")
+    defer ln.Close()
+
+    catalog := NewCatalog().RegisterDataset("users", newNamedDataset(
+        []string{"id", "name"},
+        Ints{1, 2},
+        Strs{"bob", "alice"},
+    ))
+
+    _, users, _ := catalog.Table("users")
+    runner := Pipeline(users, Filter(Where(1, "!=", "eve")), Pick(1), Gather())
+
+    dist := NewDistributer("a:5551", ln)
+    fmt.Print(Explain(dist.Distribute(runner, "a:5551", "b:5551")))
+
+    // Output:
+    // Distribute returns=[name:string] nodes=[a:5551 b:5551]
+    //   Pipeline returns=[name:string]
+    //     ScanDataset returns=[id:int name:string]
+    //     Filter $1 != "eve" returns=[id:int name:string]
+    //     Pick [1] returns=[name:string]
+    //     Exchange mode=gather returns=[name:string] to=[a:5551]
+}
+
+func TestExplainJoin(t *testing.T) {
+    runner := Pipeline(
+        Project(Pick(0), Pick(1)),
+        DistributedJoin(LeftJoin, []int{0}, []int{0}, PassThrough(), Repartition(0), 10),
+        Limit(10),
+    )
+
+    expected := []string{
+        "Pipeline returns=[? ? ? ?]",
+        "  Project returns=[? ?]",
+        "    Pick [0] returns=[?]",
+        "    Pick [1] returns=[?]",
+        "  DistributedJoin left [0] = [0] threshold 10 returns=[? ? ? ?]",
+        "    PassThrough returns=[? ?]",
+        "    Exchange [0] mode=partition returns=[? ?]",
+        "  Limit 10 returns=[? ? ? ?]",
+    }
+    require.Equal(t, strings.Join(expected, "\n") + "\n", Explain(runner).String())
+}
+
+func TestExplainDot(t *testing.T) {
+    dot := Explain(Pipeline(Pick(0), Offset(1))).Dot()
+    expected := []string{
+        "digraph plan {",
+        "    node [shape=box];",
+        `    n0 [label="Pipeline\nreturns=[?]"];`,
+        `    n1 [label="Pick\n[0]\nreturns=[?]"];`,
+        "    n0 -> n1;",
+        `    n2 [label="Offset\n1\nreturns=[?]"];`,
+        "    n0 -> n2;",
+        "}",
+    }
+    require.Equal(t, strings.Join(expected, "\n") + "\n", dot)
+}
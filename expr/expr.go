@@ -0,0 +1,453 @@
+// Package expr implements a small expression language over ep datasets:
+// column references, literals, arithmetic, comparison and boolean operators,
+// and function calls. Expressions are evaluated over entire datasets (a value
+// per row), and are serializable with gob, thus they can be compiled into
+// runners (see Filter and Project) that are distributed along with the rest of
+// the plan.
+package expr
+
+import (
+    "fmt"
+    "strings"
+    "strconv"
+    "encoding/gob"
+    "github.com/panoplyio/ep"
+)
+
+func init() {
+    gob.Register(&col{})
+    gob.Register(&lit{})
+    gob.Register(&binary{})
+    gob.Register(&not{})
+    gob.Register(&call{})
+    gob.Register(&getPath{})
+}
+
+// string representations of boolean values. Boolean expressions evaluate to
+// ep.Bools, but accept these strings as boolean operands as well
+const (
+    True = "true"
+    False = "false"
+)
+
+// Expr is an expression that computes a value for every row of a dataset
+type Expr interface {
+
+    // Eval evaluates the expression over all of the rows of the dataset, and
+    // returns a Data with a value per row
+    Eval(data ep.Dataset) (ep.Data, error)
+
+    // Returns the type of the computed values
+    Returns() ep.Type
+
+    // String representation of the expression, for debugging
+    String() string
+}
+
+// Col returns an Expr that references the values of the column at index i
+func Col(i int) Expr { return &col{i} }
+
+// Lit returns an Expr of a constant string value
+func Lit(v string) Expr { return &lit{v} }
+
+// Add returns an Expr that adds the numeric values of a and b
+func Add(a, b Expr) Expr { return &binary{"+", a, b} }
+
+// Sub returns an Expr that subtracts the numeric values of b from a
+func Sub(a, b Expr) Expr { return &binary{"-", a, b} }
+
+// Mul returns an Expr that multiplies the numeric values of a and b
+func Mul(a, b Expr) Expr { return &binary{"*", a, b} }
+
+// Div returns an Expr that divides the numeric values of a by b
+func Div(a, b Expr) Expr { return &binary{"/", a, b} }
+
+// Eq returns a boolean Expr that compares a and b for equality. Values are
+// compared numerically if both are numbers, and as strings otherwise. The same
+// applies to all of the comparison operators.
+func Eq(a, b Expr) Expr { return &binary{"=", a, b} }
+
+// Ne returns a boolean Expr that compares a and b for inequality
+func Ne(a, b Expr) Expr { return &binary{"!=", a, b} }
+
+// Lt returns a boolean Expr that tests if a is less than b
+func Lt(a, b Expr) Expr { return &binary{"<", a, b} }
+
+// Le returns a boolean Expr that tests if a is less than or equal to b
+func Le(a, b Expr) Expr { return &binary{"<=", a, b} }
+
+// Gt returns a boolean Expr that tests if a is greater than b
+func Gt(a, b Expr) Expr { return &binary{">", a, b} }
+
+// Ge returns a boolean Expr that tests if a is greater than or equal to b
+func Ge(a, b Expr) Expr { return &binary{">=", a, b} }
+
+// And returns a boolean Expr that's true where both a and b are true
+func And(a, b Expr) Expr { return &binary{"AND", a, b} }
+
+// Or returns a boolean Expr that's true where either a or b are true
+func Or(a, b Expr) Expr { return &binary{"OR", a, b} }
+
+// Not returns a boolean Expr that negates a boolean Expr
+func Not(e Expr) Expr { return &not{e} }
+
+// Call returns an Expr that calls the scalar function registered by name in the
+// ep.Functions registry, with the values of the arguments. This package
+// registers the built-in functions: upper, lower, length, concat and json_text.
+func Call(name string, args ...Expr) Expr { return &call{name, args} }
+
+// GetPath returns an Expr that extracts the nested values at the path from the
+// JSON values of e, which may also be JSON encoded strings. See ep.GetPath. Use
+// the built-in json_text function for the unquoted text of the values.
+func GetPath(e Expr, path string) Expr { return &getPath{e, path} }
+
+type col struct { Index int }
+func (*col) Returns() ep.Type { return ep.Any } // depends on the input
+func (e *col) String() string { return fmt.Sprintf("$%d", e.Index) }
+func (e *col) Eval(data ep.Dataset) (ep.Data, error) {
+    if e.Index < 0 || e.Index >= data.Width() {
+        return nil, fmt.Errorf("column %d out of range %d", e.Index, data.Width())
+    }
+    return data.At(e.Index), nil
+}
+
+type lit struct { Value string }
+func (*lit) Returns() ep.Type { return ep.Str }
+func (e *lit) String() string { return strconv.Quote(e.Value) }
+func (e *lit) Eval(data ep.Dataset) (ep.Data, error) {
+    res := make(ep.Strs, data.Len())
+    for i := range res {
+        res[i] = e.Value
+    }
+    return res, nil
+}
+
+type binary struct {
+    Op string
+    Left Expr
+    Right Expr
+}
+
+func (e *binary) Returns() ep.Type {
+    switch e.Op {
+    case "+", "-", "*", "/":
+        return ep.Str
+    }
+    return ep.Bool
+}
+
+func (e *binary) String() string {
+    return fmt.Sprintf("(%s %s %s)", e.Left, e.Op, e.Right)
+}
+
+func (e *binary) Eval(data ep.Dataset) (ep.Data, error) {
+    args, err := evalData(data, e.Left, e.Right)
+    if args == nil || err != nil {
+        return ep.Null.Data(uint(data.Len())), err
+    }
+
+    valid := validRows(args...)
+    switch e.Op {
+    case "AND", "OR":
+        left, right := toBools(args[0]), toBools(args[1])
+        res := make(ep.Bools, len(left))
+        for i := range res {
+            if e.Op == "AND" {
+                res[i] = left[i] && right[i]
+            } else {
+                res[i] = left[i] || right[i]
+            }
+        }
+        return withNulls(res, valid), nil
+    }
+
+    left, right := args[0].Strings(), args[1].Strings()
+    switch e.Op {
+    case "=", "!=", "<", "<=", ">", ">=":
+        res := make(ep.Bools, len(left))
+        for i := range res {
+            res[i] = compare(e.Op, left[i], right[i])
+        }
+        return withNulls(res, valid), nil
+    }
+
+    res := make(ep.Strs, len(left))
+    for i := range res {
+        if valid != nil && !valid[i] {
+            continue // null
+        }
+
+        res[i], err = arithmetic(e.Op, left[i], right[i])
+        if err != nil {
+            return nil, err
+        }
+    }
+    return withNulls(res, valid), nil
+}
+
+type not struct { Expr Expr }
+func (*not) Returns() ep.Type { return ep.Bool }
+func (e *not) String() string { return fmt.Sprintf("NOT %s", e.Expr) }
+func (e *not) Eval(data ep.Dataset) (ep.Data, error) {
+    args, err := evalData(data, e.Expr)
+    if args == nil || err != nil {
+        return ep.Null.Data(uint(data.Len())), err
+    }
+
+    res := make(ep.Bools, args[0].Len())
+    for i, v := range toBools(args[0]) {
+        res[i] = !v
+    }
+    return withNulls(res, validRows(args...)), nil
+}
+
+type getPath struct {
+    Expr Expr
+    Path string
+}
+
+func (*getPath) Returns() ep.Type { return ep.JSON }
+func (e *getPath) String() string { return fmt.Sprintf("%s->%s", e.Expr, strconv.Quote(e.Path)) }
+func (e *getPath) Eval(data ep.Dataset) (ep.Data, error) {
+    args, err := evalData(data, e.Expr)
+    if args == nil || err != nil {
+        return ep.Null.Data(uint(data.Len())), err
+    }
+
+    jsons, ok := args[0].(ep.JSONs)
+    valid := validRows(args...)
+    if !ok {
+        strs := append([]string{}, args[0].Strings()...)
+        for i := range strs {
+            if valid != nil && !valid[i] {
+                strs[i] = "null"
+            }
+        }
+
+        jsons, err = ep.ParseJSONs(strs...)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    res, err := ep.GetPath(jsons, e.Path)
+    if err != nil || valid == nil {
+        return res, err
+    }
+
+    for i, null := range ep.NullMask(res) {
+        valid[i] = valid[i] && !null
+    }
+    return ep.Nullable(ep.JSONs(res.Strings()), valid), nil
+}
+
+type call struct {
+    Name string
+    Args []Expr
+}
+
+// Returns the type of the registered function, if any
+func (e *call) Returns() ep.Type {
+    f := ep.Functions.Get(e.Name)
+    if f == nil {
+        return ep.Any
+    }
+    return f.Returns
+}
+
+func (e *call) String() string {
+    args := []string{}
+    for _, arg := range e.Args {
+        args = append(args, arg.String())
+    }
+    return fmt.Sprintf("%s(%s)", e.Name, strings.Join(args, ", "))
+}
+
+func (e *call) Eval(data ep.Dataset) (ep.Data, error) {
+    f := ep.Functions.Get(e.Name)
+    if f == nil {
+        return nil, fmt.Errorf("unknown function: %s", e.Name)
+    }
+
+    args := []ep.Data{}
+    for _, arg := range e.Args {
+        v, err := arg.Eval(data)
+        if err != nil {
+            return nil, err
+        } else if v.Type() == ep.Null {
+            return ep.Null.Data(uint(data.Len())), nil
+        }
+        args = append(args, v)
+    }
+
+    return f.Call(args)
+}
+
+// rowFunc returns a vectorized ScalarFunc out of a function that computes the
+// value of a single row of strings
+func rowFunc(fn func(args []string) (string, error)) ep.ScalarFunc {
+    return func(args []ep.Data) (ep.Data, error) {
+        if len(args) == 0 {
+            return ep.Strs{}, nil
+        }
+
+        strs := [][]string{}
+        for _, arg := range args {
+            strs = append(strs, arg.Strings())
+        }
+
+        var err error
+        res := make(ep.Strs, args[0].Len())
+        row := make([]string, len(args))
+        for i := range res {
+            for j := range strs {
+                row[j] = strs[j][i]
+            }
+
+            res[i], err = fn(row)
+            if err != nil {
+                return nil, err
+            }
+        }
+        return res, nil
+    }
+}
+
+// built-in functions, registered in the ep.Functions registry
+var _ = ep.Functions.
+    Register("upper", ep.Str, rowFunc(func(args []string) (string, error) {
+        if len(args) != 1 {
+            return "", fmt.Errorf("upper expects 1 argument, got %d", len(args))
+        }
+        return strings.ToUpper(args[0]), nil
+    })).
+    Register("lower", ep.Str, rowFunc(func(args []string) (string, error) {
+        if len(args) != 1 {
+            return "", fmt.Errorf("lower expects 1 argument, got %d", len(args))
+        }
+        return strings.ToLower(args[0]), nil
+    })).
+    Register("length", ep.Str, rowFunc(func(args []string) (string, error) {
+        if len(args) != 1 {
+            return "", fmt.Errorf("length expects 1 argument, got %d", len(args))
+        }
+        return strconv.Itoa(len(args[0])), nil
+    })).
+    Register("concat", ep.Str, rowFunc(func(args []string) (string, error) {
+        return strings.Join(args, ""), nil
+    })).
+    Register("json_text", ep.Str, func(args []ep.Data) (ep.Data, error) {
+        if len(args) != 1 {
+            return nil, fmt.Errorf("json_text expects 1 argument, got %d", len(args))
+        }
+        return ep.JSONs(args[0].Strings()).Text(), nil
+    })
+
+// evalData evaluates the expressions, and returns the values of each. Returns
+// nil if any of the results is of the Null type.
+func evalData(data ep.Dataset, exprs ...Expr) ([]ep.Data, error) {
+    res := []ep.Data{}
+    for _, e := range exprs {
+        v, err := e.Eval(data)
+        if err != nil {
+            return nil, err
+        } else if v.Type() == ep.Null {
+            return nil, nil
+        }
+        res = append(res, v)
+    }
+    return res, nil
+}
+
+// validRows returns a mask of the rows where none of the values is null, or nil
+// if there are no nulls at all
+func validRows(args ...ep.Data) ep.Bools {
+    var valid ep.Bools
+    for _, arg := range args {
+        for i, null := range ep.NullMask(arg) {
+            if !null {
+                continue
+            } else if valid == nil {
+                valid = make(ep.Bools, arg.Len())
+                for j := range valid {
+                    valid[j] = true
+                }
+            }
+            valid[i] = false
+        }
+    }
+    return valid
+}
+
+// withNulls returns the result with nulls where the rows aren't valid
+func withNulls(res ep.Data, valid ep.Bools) ep.Data {
+    if valid == nil {
+        return res
+    }
+    return ep.Nullable(res, valid)
+}
+
+// toBools returns the boolean values of the data. Non-boolean data is true
+// where its string value is True
+func toBools(data ep.Data) ep.Bools {
+    if bools, ok := data.(ep.Bools); ok {
+        return bools
+    }
+
+    strs := data.Strings()
+    res := make(ep.Bools, len(strs))
+    for i, s := range strs {
+        res[i] = s == True
+    }
+    return res
+}
+
+// compare the values numerically if both are numbers, or as strings otherwise
+func compare(op, a, b string) bool {
+    c := strings.Compare(a, b)
+    x, err1 := strconv.ParseFloat(a, 64)
+    y, err2 := strconv.ParseFloat(b, 64)
+    if err1 == nil && err2 == nil {
+        switch {
+        case x < y: c = -1
+        case x > y: c = 1
+        default: c = 0
+        }
+    }
+
+    switch op {
+    case "=": return c == 0
+    case "!=": return c != 0
+    case "<": return c < 0
+    case "<=": return c <= 0
+    case ">": return c > 0
+    default: return c >= 0
+    }
+}
+
+func arithmetic(op, a, b string) (string, error) {
+    x, err := strconv.ParseFloat(a, 64)
+    if err != nil {
+        return "", err
+    }
+
+    y, err := strconv.ParseFloat(b, 64)
+    if err != nil {
+        return "", err
+    }
+
+    var v float64
+    switch op {
+    case "+": v = x + y
+    case "-": v = x - y
+    case "*": v = x * y
+    case "/":
+        if y == 0 {
+            return "", fmt.Errorf("division by zero")
+        }
+        v = x / y
+    default:
+        return "", fmt.Errorf("unsupported operator: %s", op)
+    }
+    return strconv.FormatFloat(v, 'f', -1, 64), nil
+}
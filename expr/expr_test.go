@@ -0,0 +1,145 @@
+package expr
+
+import (
+    "fmt"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+var data = ep.NewDataset(ep.Strs{"1", "2", "10"}, ep.Strs{"a", "b", "c"})
+
+func ExampleExpr() {
+    e := And(Gt(Col(0), Lit("1")), Ne(Col(1), Lit("c")))
+    res, err := e.Eval(data)
+    fmt.Println(e, res, err)
+
+    // Output: (($0 > "1") AND ($1 != "c")) [false true false] <nil>
+}
+
+func TestExprEval(t *testing.T) {
+    tests := map[string]Expr{
+        "[2 4 20]": Mul(Col(0), Lit("2")),
+        "[0.5 1 5]": Div(Col(0), Lit("2")),
+        "[0 1 9]": Sub(Col(0), Lit("1")),
+        "[true false false]": Lt(Col(0), Lit("2")),
+        "[false true true]": Not(Eq(Col(1), Lit("a"))),
+        "[true true false]": Or(Le(Col(0), Lit("1")), Eq(Col(1), Lit("b"))),
+        "[A1 B2 C10]": Call("concat", Call("upper", Col(1)), Col(0)),
+        "[1 1 2]": Call("length", Col(0)),
+    }
+
+    for expected, e := range tests {
+        res, err := e.Eval(data)
+        require.NoError(t, err, e.String())
+        require.Equal(t, expected, fmt.Sprintf("%v", res), e.String())
+    }
+}
+
+func TestExprNull(t *testing.T) {
+    data := ep.NewDataset(ep.Null.Data(2))
+    res, err := Add(Col(0), Lit("1")).Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, ep.Null, res.Type())
+    require.Equal(t, 2, res.Len())
+}
+
+// boolean expressions evaluate to Bools, which can be used as filter masks
+func TestExprBools(t *testing.T) {
+    e := And(Gt(Col(0), Lit("1")), Lit(True))
+    require.Equal(t, ep.Bool, e.Returns())
+    require.Equal(t, ep.Str, Add(Col(0), Col(0)).Returns())
+
+    res, err := e.Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, ep.Bools{false, true, true}, res)
+    require.Equal(t, "[[2 10] [b c]]", fmt.Sprint(data.Filter(res.(ep.Bools))))
+
+    res, err = Not(Col(2)).Eval(ep.NewDataset(data.At(0), data.At(1), ep.Bools{true, false, true}))
+    require.NoError(t, err)
+    require.Equal(t, ep.Bools{false, true, false}, res)
+}
+
+func TestExprErr(t *testing.T) {
+    errs := map[string]Expr{
+        "column 5 out of range 2": Col(5),
+        "unknown function: foo": Call("foo"),
+        "division by zero": Div(Col(0), Lit("0")),
+        "upper expects 1 argument, got 2": Call("upper", Col(0), Col(1)),
+    }
+
+    for msg, e := range errs {
+        _, err := e.Eval(data)
+        require.Error(t, err)
+        require.Equal(t, msg, err.Error())
+    }
+}
+
+// expressions are serializable, in order to be distributed
+func TestExprGob(t *testing.T) {
+    var e Expr = Eq(Call("lower", Col(1)), Lit("b"))
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&e))
+
+    var decoded Expr
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, e.String(), decoded.String())
+}
+
+// user-defined functions are registered in ep.Functions, and called by name
+func TestExprUDF(t *testing.T) {
+    ep.Functions.Register("test.reverse", ep.Str, func(args []ep.Data) (ep.Data, error) {
+        res := ep.Strs{}
+        for _, s := range args[0].Strings() {
+            r := []rune(s)
+            for i, j := 0, len(r) - 1; i < j; i, j = i + 1, j - 1 {
+                r[i], r[j] = r[j], r[i]
+            }
+            res = append(res, string(r))
+        }
+        return res, nil
+    })
+
+    e := Call("test.reverse", Call("concat", Col(1), Col(0)))
+    require.Equal(t, ep.Str, e.Returns())
+    require.Equal(t, ep.Any, Call("foo").Returns())
+
+    res, err := e.Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, "[1a 2b 01c]", fmt.Sprint(res))
+}
+
+// expressions over null values evaluate to null, and are never true
+func TestExprNullable(t *testing.T) {
+    data := ep.NewDataset(ep.Nullable(ep.Strs{"1", "", "3"}, ep.Bools{true, false, true}))
+    res, err := Add(Col(0), Lit("1")).Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, "[2 <nil> 4]", fmt.Sprint(res))
+
+    res, err = Not(Gt(Col(0), Lit("2"))).Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, "[true <nil> false]", fmt.Sprint(res))
+
+    mask, err := Predicate(Lt(Col(0), Lit("5"))).Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{true, false, true}, mask)
+}
+
+func TestExprGetPath(t *testing.T) {
+    data := ep.NewDataset(ep.Nullable(ep.Strs{`{"a": {"b": "x"}}`, `{"a": 1}`, ""}, ep.Bools{true, true, false}))
+    e := Call("json_text", GetPath(Col(0), "a.b"))
+    require.Equal(t, `json_text($0->"a.b")`, e.String())
+
+    res, err := GetPath(Col(0), "a.b").Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, `["x" <nil> <nil>]`, fmt.Sprint(res))
+
+    res, err = e.Eval(data)
+    require.NoError(t, err)
+    require.Equal(t, ep.Strs{"x", "", ""}, res)
+
+    _, err = GetPath(Lit("{"), "a").Eval(data)
+    require.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package expr
+
+import (
+    "github.com/panoplyio/ep"
+)
+
+func init() {
+    ep.Rules.Register(MergeProjects)
+}
+
+// MergeProjects is an ep.Rule that merges adjacent Projects into a single
+// Project, by substituting the columns referenced by the second Project with
+// the expressions of the first. Expressions other than columns and literals
+// are only substituted if they're referenced once, in order to not evaluate
+// them more than once. It's registered in ep.Rules.
+func MergeProjects(r ep.Runner) (ep.Runner, bool) {
+    stages := ep.Stages(r)
+    for i := 1; i < len(stages); i++ {
+        first, ok1 := stages[i - 1].(*project)
+        second, ok2 := stages[i].(*project)
+        if !ok1 || !ok2 {
+            continue
+        }
+
+        refs := make([]int, len(first.Exprs))
+        valid := true
+        for _, e := range second.Exprs {
+            valid = valid && countRefs(e, refs)
+        }
+
+        for j, e := range first.Exprs {
+            if refs[j] > 1 && !isSimple(e) {
+                valid = false
+            }
+        }
+
+        if !valid {
+            continue
+        }
+
+        exprs := make([]Expr, len(second.Exprs))
+        for j, e := range second.Exprs {
+            exprs[j] = substitute(e, first.Exprs)
+        }
+
+        stages[i - 1] = Project(exprs...)
+        stages = append(stages[:i], stages[i + 1:]...)
+        return ep.Pipeline(stages...), true
+    }
+    return r, false
+}
+
+func isSimple(e Expr) bool {
+    switch e.(type) {
+    case *col, *lit:
+        return true
+    }
+    return false
+}
+
+// countRefs counts the references to every column by the expression, and
+// returns false if it references out of range columns, or unknown expressions
+func countRefs(e Expr, refs []int) bool {
+    switch e := e.(type) {
+    case *col:
+        if e.Index < 0 || e.Index >= len(refs) {
+            return false // fail at runtime
+        }
+        refs[e.Index]++
+        return true
+    case *lit:
+        return true
+    case *binary:
+        return countRefs(e.Left, refs) && countRefs(e.Right, refs)
+    case *not:
+        return countRefs(e.Expr, refs)
+    case *getPath:
+        return countRefs(e.Expr, refs)
+    case *call:
+        for _, arg := range e.Args {
+            if !countRefs(arg, refs) {
+                return false
+            }
+        }
+        return true
+    }
+    return false
+}
+
+// substitute returns a copy of the expression where the columns are replaced
+// by the expressions at their indices. See countRefs.
+func substitute(e Expr, exprs []Expr) Expr {
+    switch e := e.(type) {
+    case *col:
+        return exprs[e.Index]
+    case *binary:
+        return &binary{e.Op, substitute(e.Left, exprs), substitute(e.Right, exprs)}
+    case *not:
+        return &not{substitute(e.Expr, exprs)}
+    case *getPath:
+        return &getPath{substitute(e.Expr, exprs), e.Path}
+    case *call:
+        args := make([]Expr, len(e.Args))
+        for i, arg := range e.Args {
+            args[i] = substitute(arg, exprs)
+        }
+        return &call{e.Name, args}
+    }
+    return e
+}
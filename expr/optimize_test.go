@@ -0,0 +1,44 @@
+package expr
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleMergeProjects() {
+    runner := ep.Pipeline(
+        Project(Col(1), Add(Col(0), Lit("1"))),
+        Project(Call("upper", Col(0)), Col(1)),
+    )
+
+    runner = ep.Optimize(runner)
+    res, err := ep.Collect(context.Background(), runner, data)
+    fmt.Println(len(ep.Stages(runner)), res, err)
+
+    // Output: 1 [[A B C] [2 3 11]] <nil>
+}
+
+func TestMergeProjects(t *testing.T) {
+    runner := ep.Pipeline(
+        Project(Col(1), Add(Col(0), Lit("1"))),
+        Project(Call("upper", Col(0)), Mul(Col(1), Lit("2")), Col(0)),
+    )
+
+    optimized := ep.Optimize(runner)
+    require.Equal(t, 1, len(ep.Stages(optimized)))
+    require.Equal(t, "[upper($1) (($0 + \"1\") * \"2\") $1]", fmt.Sprint(optimized.(*project).Exprs))
+
+    expected, err := ep.Collect(context.Background(), runner, data)
+    require.NoError(t, err)
+
+    res, err := ep.Collect(context.Background(), optimized, data)
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprint(expected), fmt.Sprint(res))
+
+    // expressions referenced more than once aren't substituted
+    runner = ep.Pipeline(Project(Add(Col(0), Lit("1"))), Project(Mul(Col(0), Col(0))))
+    require.Equal(t, 2, len(ep.Stages(ep.Optimize(runner))))
+}
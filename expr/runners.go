@@ -0,0 +1,74 @@
+package expr
+
+import (
+    "fmt"
+    "context"
+    "encoding/gob"
+    "github.com/panoplyio/ep"
+)
+
+func init() {
+    gob.Register(&predicate{})
+    gob.Register(&project{})
+}
+
+// Filter returns a Runner that emits only the rows for which the boolean
+// expression is true. Rows where it's null are dropped. See ep.Filter.
+func Filter(e Expr) ep.Runner {
+    return ep.Filter(Predicate(e))
+}
+
+// Predicate returns an ep.Predicate that tests the rows with the boolean
+// expression
+func Predicate(e Expr) ep.Predicate {
+    return &predicate{e}
+}
+
+// Project returns a Runner that evaluates the expressions over its input, and
+// emits a column per expression
+func Project(exprs ...Expr) ep.Runner {
+    return &project{exprs}
+}
+
+type predicate struct { Expr Expr }
+func (p *predicate) String() string { return p.Expr.String() }
+func (p *predicate) Test(data ep.Dataset) ([]bool, error) {
+    v, err := p.Expr.Eval(data)
+    if err != nil {
+        return nil, err
+    }
+
+    if v.Type() == ep.Null {
+        return make([]bool, data.Len()), nil
+    }
+    return toBools(v), nil
+}
+
+type project struct { Exprs []Expr }
+func (r *project) String() string { return fmt.Sprint(r.Exprs) }
+func (r *project) Returns() []ep.Type {
+    types := []ep.Type{}
+    for _, e := range r.Exprs {
+        types = append(types, e.Returns())
+    }
+    return types
+}
+
+func (r *project) Run(ctx context.Context, inp, out chan ep.Dataset) error {
+    return ep.RunIter(ctx, r, inp, out)
+}
+
+// Iter implements ep.IterRunner
+func (r *project) Iter(ctx context.Context, inp ep.Iterator) ep.Iterator {
+    return ep.MapBatches(inp, func(data ep.Dataset) (ep.Dataset, error) {
+        cols := []ep.Data{}
+        for _, e := range r.Exprs {
+            v, err := e.Eval(data)
+            if err != nil {
+                return nil, err
+            }
+            cols = append(cols, v)
+        }
+        return ep.NewDataset(cols...), nil
+    })
+}
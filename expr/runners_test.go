@@ -0,0 +1,35 @@
+package expr
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleFilter() {
+    runner := Filter(Gt(Col(0), Lit("1")))
+    res, err := ep.Collect(context.Background(), runner, data)
+    fmt.Println(res, err)
+
+    // Output: [[2 10] [b c]] <nil>
+}
+
+func ExampleProject() {
+    runner := Project(Col(1), Add(Col(0), Lit("1")))
+    res, err := ep.Collect(context.Background(), runner, data)
+    fmt.Println(res, err)
+
+    // Output: [[a b c] [2 3 11]] <nil>
+}
+
+func TestProjectReturns(t *testing.T) {
+    runner := Project(Col(1), Lit("x"))
+    require.Equal(t, []ep.Type{ep.Any, ep.Str}, runner.Returns())
+}
+
+func TestFilterErr(t *testing.T) {
+    _, err := ep.Collect(context.Background(), Filter(Col(3)), data)
+    require.Error(t, err)
+}
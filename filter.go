@@ -0,0 +1,222 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+)
+
+var _ = registerGob(&filter{}, &where{}, &isTrue{})
+
+// Predicate tests the rows of datasets, used for filtering
+type Predicate interface {
+
+    // Test all of the rows in the dataset, and return a slice with a boolean
+    // per row indicating if the row matches the predicate or not
+    Test(data Dataset) ([]bool, error)
+}
+
+// PredicateFunc is a Predicate implemented by a Go function over a single
+// row. NOTE: functions cannot be serialized, thus a Filter using it cannot be
+// distributed. Use Where() for distributed filtering.
+type PredicateFunc func(data Dataset, i int) bool
+
+// Test implements Predicate by calling the function for every row
+func (fn PredicateFunc) Test(data Dataset) ([]bool, error) {
+    res := make([]bool, data.Len())
+    for i := range res {
+        res[i] = fn(data, i)
+    }
+    return res, nil
+}
+
+// Where returns a serializable Predicate that compares the values in column
+// `col` against a constant value, using one of the operators: =, !=, <, <=, >,
+// >=. The value is converted into the type of the column (see CastData), and
+// compared like CompareAt, where integers are compared to fractional values as
+// floats. Nulls never match. Unsupported operators and values that aren't
+// convertible into the type of the column fail the predicate
+func Where(col int, op string, value string) Predicate {
+    return &where{col, op, value}
+}
+
+// IsTrue returns a serializable Predicate that selects the rows where the
+// boolean column `col` is true, for filtering by a boolean column computed
+// upstream. Non-boolean columns are true where their value is "true", and
+// Null columns are never true.
+func IsTrue(col int) Predicate {
+    return &isTrue{col}
+}
+
+type isTrue struct { Col int }
+func (p *isTrue) Test(data Dataset) ([]bool, error) {
+    if p.Col >= data.Width() {
+        return nil, fmt.Errorf("column %d out of range %d", p.Col, data.Width())
+    }
+
+    switch vs := data.At(p.Col).(type) {
+    case Bools:
+        return vs, nil
+    case nulls:
+        return make([]bool, vs.Len()), nil
+    }
+
+    strs := data.At(p.Col).Strings()
+    res := make([]bool, len(strs))
+    for i, s := range strs {
+        res[i] = s == "true"
+    }
+    return res, nil
+}
+
+type where struct {
+    Col int
+    Op string
+    Value string
+}
+
+func (p *where) Test(data Dataset) ([]bool, error) {
+    if p.Col < 0 || p.Col >= data.Width() {
+        return nil, fmt.Errorf("column %d out of range %d", p.Col, data.Width())
+    }
+
+    switch p.Op {
+    case "=", "!=", "<", "<=", ">", ">=":
+    default:
+        return nil, fmt.Errorf("unsupported operator: %s", p.Op)
+    }
+
+    col := data.At(p.Col)
+    if col.Type() == Null {
+        return make([]bool, col.Len()), nil
+    }
+
+    value, err := CastData(Strs{p.Value}, col.Type())
+    if err != nil && isIntData(col) {
+        value, err = CastData(Strs{p.Value}, Float)
+        if err == nil {
+            col, err = CastData(col, Float)
+        }
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    values, valid := col, Bools(nil)
+    if vs, ok := col.(nullable); ok {
+        values, valid = vs.Values, vs.Valid
+    }
+
+    var res []bool
+    switch vs := values.(type) {
+    case Ints:
+        res = compareAll(vs, p.Op, value.(Ints)[0])
+    case Floats:
+        res = compareAll(vs, p.Op, value.(Floats)[0])
+    case Strs:
+        res = compareAll(vs, p.Op, value.(Strs)[0])
+    default:
+        res = make([]bool, values.Len())
+        for i := range res {
+            res[i] = matchOp(p.Op, CompareAt(values, i, value, 0))
+        }
+    }
+
+    for i, ok := range valid {
+        res[i] = res[i] && ok
+    }
+    return res, nil
+}
+
+// isIntData returns true if the data is of integers, or nullable integers
+func isIntData(data Data) bool {
+    if vs, ok := data.(nullable); ok {
+        data = vs.Values
+    }
+
+    _, ok := data.(Ints)
+    return ok
+}
+
+// compareAll compares all of the values to v with the operator, see Where
+func compareAll[T int64 | float64 | string, S ~[]T](vs S, op string, v T) []bool {
+    res := make([]bool, len(vs))
+    for i, x := range vs {
+        c := 0
+        if x < v {
+            c = -1
+        } else if x > v {
+            c = 1
+        }
+        res[i] = matchOp(op, c)
+    }
+    return res
+}
+
+// matchOp returns true if the result of a comparison matches the operator
+func matchOp(op string, c int) bool {
+    switch op {
+    case "=": return c == 0
+    case "!=": return c != 0
+    case "<": return c < 0
+    case "<=": return c <= 0
+    case ">": return c > 0
+    case ">=": return c >= 0
+    }
+    return false
+}
+
+// Filter returns a Runner that emits only the rows of its input datasets that
+// match the predicate, preserving the column types. Empty batches are dropped.
+func Filter(pred Predicate) Runner {
+    return &filter{pred}
+}
+
+type filter struct { Predicate Predicate }
+func (*filter) Returns() []Type { return []Type{Wildcard} }
+func (r *filter) Run(ctx context.Context, inp, out chan Dataset) error {
+    return RunIter(ctx, r, inp, out)
+}
+
+func (r *filter) Iter(ctx context.Context, inp Iterator) Iterator {
+    return MapBatches(inp, func(data Dataset) (Dataset, error) {
+        mask, err := r.Predicate.Test(data)
+        if err != nil {
+            return nil, err
+        }
+
+        data = data.Filter(mask)
+        if data.Len() == 0 {
+            return nil, nil
+        }
+        return data, nil
+    })
+}
+
+// keep returns a new Data containing only the rows marked as true in the mask.
+// The input data is never modified.
+func keep(data Data, mask []bool) Data {
+    return pick(data, Bools(mask).Selection())
+}
+
+// pick returns a new Data containing only the rows at the provided indices, in
+// order. Contiguous rows are sliced and appended together in order to avoid
+// per-row overhead. The input data is never modified.
+func pick(data Data, rows []int) Data {
+    set, ok := data.(Dataset)
+    if ok {
+        res := make([]Data, set.Width())
+        for i := range res {
+            res[i] = pick(set.At(i), rows)
+        }
+        return newNamedDataset(namesOf(set), res...)
+    }
+
+    res := data.Type().Data(0)
+    for i := 0; i < len(rows); {
+        start := i
+        for i++; i < len(rows) && rows[i] == rows[i - 1] + 1; i++ {}
+        res = res.Append(data.Slice(rows[start], rows[i - 1] + 1))
+    }
+    return res
+}
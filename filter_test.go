@@ -0,0 +1,125 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleFilter() {
+    runner := Filter(Where(0, "!=", "world"))
+    data := NewDataset(Strs{"hello", "world", "foo"}, Strs{"a", "b", "c"})
+    data, err := testRun(runner, data)
+    fmt.Println(data, err)
+
+    // Output: [[hello foo] [a c]] <nil>
+}
+
+func ExamplePredicateFunc() {
+    runner := Filter(PredicateFunc(func(data Dataset, i int) bool {
+        return len(data.At(0).Strings()[i]) > 3
+    }))
+
+    data := NewDataset(Strs{"hello", "foo", "world"})
+    data, err := testRun(runner, data)
+    fmt.Println(data, err)
+
+    // Output: [[hello world]] <nil>
+}
+
+func TestFilterEmpty(t *testing.T) {
+    runner := Filter(Where(0, "=", "nothing"))
+    data := NewDataset(Strs{"hello", "world"})
+    data, err := testRun(runner, data)
+    require.NoError(t, err)
+    require.Equal(t, 0, data.Len())
+}
+
+func TestFilterErr(t *testing.T) {
+    runner := Filter(Where(3, "=", "hello"))
+    _, err := testRun(runner, NewDataset(Strs{"hello"}))
+    require.Error(t, err)
+}
+
+func TestWhereNumeric(t *testing.T) {
+    data := NewDataset(Ints{9, 10, 100}, Floats{9.5, 10, 100}, Nullable(Ints{9, 0, 100}, Bools{true, false, true}))
+    res, err := Where(0, "<", "10").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{true, false, false}, res)
+
+    res, err = Where(0, ">=", "9.5").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{false, true, true}, res)
+
+    res, err = Where(1, ">", "9.75").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{false, true, true}, res)
+
+    // nulls never match
+    res, err = Where(2, "!=", "100").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{true, false, false}, res)
+
+    _, err = Where(0, "<", "2a").Test(data)
+    require.EqualError(t, err, `unable to cast "2a" from string to float`)
+
+    _, err = Where(-1, "=", "9").Test(data)
+    require.EqualError(t, err, "column -1 out of range 3")
+
+    _, err = Where(0, "~", "9").Test(data)
+    require.EqualError(t, err, "unsupported operator: ~")
+}
+
+// Tests that the values are compared by the types of the columns
+func TestWhereTypes(t *testing.T) {
+    decimals, err := ParseDecimals(10, 2, "10.00", "9.00", "100.50")
+    require.NoError(t, err)
+
+    times, err := CastData(Strs{"2020-01-02", "2020-01-10", "2019-12-31"}, Time)
+    require.NoError(t, err)
+
+    strs := Nullable(Strs{"a", "", "b"}, Bools{true, false, true})
+    data := NewDataset(decimals, times, strs)
+
+    res, err := Where(0, ">", "9.5").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{true, false, true}, res)
+
+    res, err = Where(1, "<", "2020-01-03").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{true, false, true}, res)
+
+    // nulls never match
+    res, err = Where(2, "!=", "a").Test(data)
+    require.NoError(t, err)
+    require.Equal(t, []bool{false, false, true}, res)
+}
+
+// filtering must not modify the input in-place
+func TestFilterNoInPlace(t *testing.T) {
+    strs := Strs{"a", "b", "c", "d"}
+    data := keep(NewDataset(strs), []bool{false, true, false, true})
+    require.Equal(t, "[[b d]]", fmt.Sprintf("%v", data))
+    require.Equal(t, Strs{"a", "b", "c", "d"}, strs)
+}
+
+func ExampleDataset_Filter() {
+    data := NewDataset(Strs{"a", "b", "c"}, Ints{1, 2, 3})
+    fmt.Println(data.Filter(Bools{true, false, true}), data)
+
+    // Output: [[a c] [1 3]] [[a b c] [1 2 3]]
+}
+
+func TestIsTrue(t *testing.T) {
+    data := NewDataset(Strs{"a", "b", "c"}, Bools{false, true, true}, Strs{"true", "", "false"})
+    res, err := testRun(Filter(IsTrue(1)), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[b c] [true true] [ false]]", fmt.Sprint(res))
+
+    res, err = testRun(Filter(IsTrue(2)), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[a] [false] [true]]", fmt.Sprint(res))
+
+    _, err = testRun(Filter(IsTrue(3)), data)
+    require.Error(t, err)
+}
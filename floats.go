@@ -0,0 +1,27 @@
+package ep
+
+import (
+    "math"
+    "strconv"
+)
+
+// Float is a built-in Type representing 64-bit floating point values. Use
+// Float.Data(n) to create Data instances of `n` zeros
+var Float = NewType(TypeOps[float64]{
+    Name: "float",
+    Size: 8,
+    Less: func(a, b float64) bool {
+        return a < b || (math.IsNaN(a) && !math.IsNaN(b))
+    },
+    String: func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) },
+    Hash: hashFloat,
+})
+
+// FloatType is the Type of Floats
+type FloatType = ColumnType[float64]
+
+// Floats is a built-in Data implementation of 64-bit floating point values.
+// Values are compared numerically, with NaNs ordered before all other values,
+// and hashed (for partitioning and grouping) by their shortest decimal string
+// representation, where -0 is hashed like 0.
+type Floats = Column[float64]
@@ -0,0 +1,97 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+)
+
+var _ = registerGob(&apply{})
+
+// Functions registry of named scalar functions. Functions cannot be
+// serialized, thus in order to distribute them they must be registered on all
+// nodes under the same name, and referenced by name (see Apply, and the Call
+// expression of the expr package).
+var Functions = make(functionsReg)
+
+// ScalarFunc is a vectorized scalar function: it computes a value per row out
+// of the values of the argument columns, which are all of the same length.
+// The returned Data must have the same length as the arguments.
+type ScalarFunc func(args []Data) (Data, error)
+
+// Function is a registered scalar function
+type Function struct {
+    Name string
+    Returns Type
+    Func ScalarFunc
+}
+
+// Call the function with the arguments, and verify the length of its result
+func (f *Function) Call(args []Data) (Data, error) {
+    res, err := f.Func(args)
+    if err != nil {
+        return nil, err
+    }
+
+    if len(args) > 0 && res.Len() != args[0].Len() {
+        err = fmt.Errorf("function %s returned %d values for %d rows", f.Name, res.Len(), args[0].Len())
+        return nil, err
+    }
+    return res, nil
+}
+
+// Apply returns a Runner that calls the scalar function registered under the
+// provided name via `Functions.Register()` with the values of the provided
+// columns as arguments, and emits a single column of its results. Compose it
+// with Project in order to also keep the input columns.
+func Apply(name string, cols ...int) Runner {
+    return &apply{name, cols}
+}
+
+type apply struct {
+    Name string
+    Cols []int
+}
+
+func (r *apply) Returns() []Type {
+    f := Functions.Get(r.Name)
+    if f == nil {
+        return []Type{Any}
+    }
+    return []Type{f.Returns}
+}
+
+func (r *apply) Run(ctx context.Context, inp, out chan Dataset) error {
+    f := Functions.Get(r.Name)
+    if f == nil {
+        return fmt.Errorf("Unregistered function %s", r.Name)
+    }
+
+    for data := range inp {
+        args := []Data{}
+        for _, col := range r.Cols {
+            if col < 0 || col >= data.Width() {
+                return fmt.Errorf("column %d out of range %d", col, data.Width())
+            }
+            args = append(args, data.At(col))
+        }
+
+        res, err := f.Call(args)
+        if err != nil {
+            return err
+        }
+
+        out <- NewDataset(res)
+    }
+    return nil
+}
+
+// registry of scalar functions
+type functionsReg map[string]*Function
+func (reg functionsReg) Register(name string, returns Type, fn ScalarFunc) functionsReg {
+    reg[name] = &Function{name, returns, fn}
+    return reg
+}
+
+func (reg functionsReg) Get(name string) *Function {
+    return reg[name]
+}
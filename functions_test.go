@@ -0,0 +1,45 @@
+package ep
+
+import (
+    "fmt"
+    "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = Functions.Register("test.join", Str, func(args []Data) (Data, error) {
+    res := make(Strs, args[0].Len())
+    for _, arg := range args {
+        for i, s := range arg.Strings() {
+            res[i] += s
+        }
+    }
+    return res, nil
+})
+
+var _ = Functions.Register("test.short", Str, func(args []Data) (Data, error) {
+    return Strs{}, nil
+})
+
+func ExampleApply() {
+    runner := Project(PassThrough(), Apply("test.join", 1, 0))
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}, Strs{"1", "2"}))
+    fmt.Println(data, err)
+
+    // Output: [[a b] [1 2] [1a 2b]] <nil>
+}
+
+func TestApplyErr(t *testing.T) {
+    _, err := testRun(Apply("test.missing", 0), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Equal(t, "Unregistered function test.missing", err.Error())
+
+    _, err = testRun(Apply("test.short", 0), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.True(t, strings.Contains(err.Error(), "returned 0 values for 1 rows"))
+}
+
+func TestApplyReturns(t *testing.T) {
+    require.Equal(t, []Type{Str}, Apply("test.join").Returns())
+    require.Equal(t, []Type{Any}, Apply("test.missing").Returns())
+}
@@ -0,0 +1,87 @@
+package ep
+
+import (
+    "sync"
+    "context"
+    "runtime/debug"
+)
+
+// Group runs the go-routines of a single task, like errgroup.Group: the first
+// error cancels the context of the group, and is returned by Wait. The errors
+// of the cancellation by the group itself (context.Canceled) are ignored, such
+// that the remaining go-routines can exit gracefully. Panics are recovered
+// into PanicErrors. It's the orchestration of the built-in composite runners,
+// thus cancellation and error semantics are consistent across all of them.
+type Group struct {
+    parent context.Context
+    cancel context.CancelFunc
+    wg sync.WaitGroup
+
+    l sync.Mutex
+    err error
+    canceled bool
+}
+
+// NewGroup returns a new Group, and its context which is derived from the
+// provided context
+func NewGroup(ctx context.Context) (*Group, context.Context) {
+    gctx, cancel := context.WithCancel(ctx)
+    return &Group{parent: ctx, cancel: cancel}, gctx
+}
+
+// Go runs the function in a new go-routine. Its error, if any, fails the group
+func (g *Group) Go(fn func() error) {
+    g.wg.Add(1)
+    go func() {
+        defer g.wg.Done()
+        g.fail(g.call(fn))
+    }()
+}
+
+func (g *Group) call(fn func() error) (err error) {
+    defer func() {
+        if p := recover(); p != nil {
+            err = &PanicError{p, string(debug.Stack())}
+        }
+    }()
+    return fn()
+}
+
+func (g *Group) fail(err error) {
+    if err == nil {
+        return
+    }
+
+    g.l.Lock()
+    defer g.l.Unlock()
+    if err == context.Canceled && g.canceled && g.parent.Err() == nil {
+        return // canceled by the group
+    }
+
+    if g.err == nil {
+        g.err = err
+    }
+
+    g.canceled = true
+    g.cancel()
+}
+
+// Cancel the context of the group without failing it, when the remaining work
+// is no longer needed, like when a downstream runner has exited early
+func (g *Group) Cancel() {
+    g.l.Lock()
+    defer g.l.Unlock()
+    g.canceled = true
+    g.cancel()
+}
+
+// Wait for all of the go-routines to exit, and returns the first error. The
+// context of the group is canceled afterwards
+func (g *Group) Wait() error {
+    g.wg.Wait()
+    g.cancel()
+
+    g.l.Lock()
+    defer g.l.Unlock()
+    return g.err
+}
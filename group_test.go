@@ -0,0 +1,89 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleGroup() {
+    g, ctx := NewGroup(context.Background())
+    g.Go(func() error {
+        return fmt.Errorf("something bad happened")
+    })
+
+    g.Go(func() error {
+        <- ctx.Done() // canceled by the error above
+        return ctx.Err()
+    })
+
+    fmt.Println(g.Wait())
+
+    // Output: something bad happened
+}
+
+func TestGroupFirstError(t *testing.T) {
+    g, ctx := NewGroup(context.Background())
+    first := make(chan struct{})
+    g.Go(func() error {
+        defer close(first)
+        return fmt.Errorf("first")
+    })
+
+    g.Go(func() error {
+        <- first
+        return fmt.Errorf("second")
+    })
+
+    g.Go(func() error {
+        <- ctx.Done()
+        return ctx.Err()
+    })
+
+    require.Equal(t, "first", g.Wait().Error())
+    require.Error(t, ctx.Err())
+}
+
+func TestGroupCancel(t *testing.T) {
+    g, ctx := NewGroup(context.Background())
+    g.Go(func() error {
+        <- ctx.Done()
+        return ctx.Err()
+    })
+
+    g.Cancel()
+    require.NoError(t, g.Wait())
+}
+
+// Tests that the cancellation of the parent context is an error, unlike the
+// cancellation by the group itself
+func TestGroupParentCanceled(t *testing.T) {
+    parent, cancel := context.WithCancel(context.Background())
+    g, ctx := NewGroup(parent)
+    g.Go(func() error {
+        <- ctx.Done()
+        return ctx.Err()
+    })
+
+    cancel()
+    g.Cancel()
+    require.Equal(t, context.Canceled, g.Wait())
+}
+
+func TestGroupPanic(t *testing.T) {
+    g, _ := NewGroup(context.Background())
+    g.Go(func() error { panic("oh no") })
+
+    err := g.Wait()
+    require.IsType(t, &PanicError{}, err)
+    require.Contains(t, err.Error(), "oh no")
+}
+
+func TestGroupNoError(t *testing.T) {
+    g, ctx := NewGroup(context.Background())
+    g.Go(func() error { return nil })
+    g.Go(func() error { return nil })
+    require.NoError(t, g.Wait())
+    require.Error(t, ctx.Err()) // canceled by Wait
+}
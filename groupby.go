@@ -0,0 +1,355 @@
+package ep
+
+import (
+    "fmt"
+    "strings"
+    "context"
+)
+
+var _ = registerGob(&groupBy{}, states{}, &statesType{})
+
+const (
+    aggPartial = 1 // compute the partial states of the local groups
+    aggFinal = 2 // merge the partial states, and finalize them
+)
+
+// GroupBy returns a Runner that groups its input rows by the values of the key
+// columns, and computes the aggregators for each group. The output contains the
+// key columns followed by a column per aggregator. With no key columns, the
+// aggregators are computed over the entire input.
+//
+// When distributed, each node first computes partial aggregates of its local
+// rows, which are then repartitioned by the key columns (see Repartition) such
+// that the partial aggregates of each group are merged on a single node. Thus,
+// each node outputs a distinct subset of the groups; Gather them as needed.
+//
+// When the groups exceed the memory budget (see WithMemory), the partial
+// aggregates are emitted early, and the final aggregates are spilled to disk
+// by partitions of their keys, which are then merged one at a time. In that
+// case the groups aren't emitted in the order they were first seen.
+func GroupBy(keys []int, aggs ...Aggregator) Runner {
+    partialKeys := make([]int, len(keys))
+    for i := range partialKeys {
+        partialKeys[i] = i
+    }
+
+    return Pipeline(
+        &groupBy{keys, aggs, aggPartial},
+        Repartition(partialKeys...),
+        &groupBy{partialKeys, aggs, aggFinal},
+    )
+}
+
+type groupBy struct {
+    Keys []int
+    Aggs []Aggregator
+    Phase int
+}
+
+// Returns the key columns, followed by the aggregated columns. The types of
+// the key columns depend on the input, and thus are unknown.
+func (r *groupBy) Returns() []Type {
+    types := []Type{}
+    for _ = range r.Keys {
+        types = append(types, Any)
+    }
+
+    for _, agg := range r.Aggs {
+        if r.Phase == aggPartial {
+            types = append(types, &statesType{})
+        } else {
+            types = append(types, agg.Returns())
+        }
+    }
+    return types
+}
+
+// groupStateBytes is the estimated size of a single aggregation state
+const groupStateBytes = 64
+
+func (r *groupBy) Run(ctx context.Context, inp, out chan Dataset) error {
+    groups := map[uint64][]*group{} // by the hash of their keys
+    order := []*group{} // emit groups in the order they were first seen
+
+    mem := memoryOf(ctx)
+    reserved := 0
+    defer func() { mem.Release(reserved) }()
+
+    var parts spillPartitions
+    defer func() { parts.Close() }()
+
+    var names []string // of the key columns, kept in the result
+    for data := range inp {
+        if names == nil {
+            names = r.keyNames(data)
+        }
+
+        if parts != nil {
+            err := parts.Write(data, r.Keys)
+            if err != nil {
+                return err
+            }
+            continue
+        }
+
+        rows := map[*group][]int{}
+        batch := []*group{} // groups of this batch, in order
+        size := 0 // of the new groups
+        for i, h := range hashRows(data, r.Keys) {
+            g := r.find(groups[h], data, i)
+            if g == nil {
+                g = r.newGroup(data, i)
+                groups[h] = append(groups[h], g)
+                order = append(order, g)
+                size += estimateBytes(NewDataset(g.Keys...))
+                size += groupStateBytes * len(r.Aggs)
+            }
+
+            if rows[g] == nil {
+                batch = append(batch, g)
+            }
+            rows[g] = append(rows[g], i)
+        }
+
+        for _, g := range batch {
+            err := r.add(g, data, rows[g])
+            if err != nil {
+                return err
+            }
+        }
+
+        if mem.Reserve(size) {
+            reserved += size
+            continue
+        } else if len(order) == 0 {
+            continue // nothing to free
+        }
+
+        // out of memory. The partial aggregates are merged by the final phase,
+        // thus they can be emitted early. The final aggregates are spilled, in
+        // their partial form, which is also the input of the final phase
+        res, err := r.partial(order, names)
+        if err != nil {
+            return err
+        }
+
+        if r.Phase == aggPartial {
+            out <- res
+        } else {
+            parts, err = newSpillPartitions()
+            if err == nil {
+                err = parts.Write(res, r.Keys)
+            }
+
+            if err != nil {
+                return err
+            }
+        }
+
+        groups, order = map[uint64][]*group{}, []*group{}
+        mem.Release(reserved)
+        reserved = 0
+    }
+
+    if parts != nil {
+        return r.mergeSpilled(ctx, parts, out)
+    }
+
+    // global aggregation of the partial states always emits the (possibly
+    // empty) state, in order for the final phase to produce its result.
+    if len(order) == 0 && len(r.Keys) == 0 && r.Phase == aggPartial {
+        order = append(order, r.newGroup(NewDataset(), 0))
+    }
+
+    if len(order) == 0 {
+        return nil
+    }
+
+    res, err := r.result(order, names)
+    if err != nil {
+        return err
+    }
+
+    out <- res
+    return nil
+}
+
+// mergeSpilled runs the final phase over every spilled partition separately,
+// in memory
+func (r *groupBy) mergeSpilled(ctx context.Context, parts spillPartitions, out chan Dataset) error {
+    ctx = context.WithValue(ctx, "ep.Memory", (*MemoryManager)(nil))
+    for _, f := range parts {
+        err := runSpilled(ctx, r, f, out)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// partial returns the groups with their aggregation states, in the output
+// format of the partial phase
+func (r *groupBy) partial(groups []*group, names []string) (Dataset, error) {
+    return (&groupBy{r.Keys, r.Aggs, aggPartial}).result(groups, names)
+}
+
+// keyNames returns the names of the key columns of the data, or nil if none of
+// them is named
+func (r *groupBy) keyNames(data Dataset) []string {
+    names := []string{}
+    named := false
+    for _, k := range r.Keys {
+        names = append(names, data.ColumnName(k))
+        named = named || names[len(names) - 1] != ""
+    }
+
+    if !named {
+        return nil
+    }
+    return names
+}
+
+// group is the key values and aggregation states of a single group
+type group struct {
+    Keys []Data
+    States []interface{}
+}
+
+// find the group of row i of the data among the groups of its hash, or nil
+func (r *groupBy) find(groups []*group, data Dataset, i int) *group {
+    for _, g := range groups {
+        found := true
+        for j, k := range r.Keys {
+            if !equalAt(g.Keys[j], 0, data.At(k), i) {
+                found = false
+                break
+            }
+        }
+
+        if found {
+            return g
+        }
+    }
+    return nil
+}
+
+func (r *groupBy) newGroup(data Dataset, i int) *group {
+    g := &group{}
+    for _, k := range r.Keys {
+        g.Keys = append(g.Keys, Clone(data.At(k).Slice(i, i + 1)))
+    }
+
+    for _, agg := range r.Aggs {
+        g.States = append(g.States, agg.InitState())
+    }
+    return g
+}
+
+// add the rows of a single group to its states
+func (r *groupBy) add(g *group, data Dataset, rows []int) (err error) {
+    if r.Phase == aggPartial {
+        batch := pick(data, rows).(Dataset)
+        for j, agg := range r.Aggs {
+            g.States[j], err = agg.Add(g.States[j], batch)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    // final phase - the input contains the partial states after the keys
+    for j, agg := range r.Aggs {
+        col := data.At(len(r.Keys) + j).(states)
+        for _, i := range rows {
+            g.States[j], err = agg.Merge(g.States[j], col[i])
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// build the result dataset out of the groups. The key columns are named by
+// the provided names, if any, while the aggregated columns are unnamed
+func (r *groupBy) result(groups []*group, names []string) (Dataset, error) {
+    cols := []Data{}
+    for i := range r.Keys {
+        col := groups[0].Keys[i].Type().Data(0)
+        for _, g := range groups {
+            col = col.Append(g.Keys[i])
+        }
+        cols = append(cols, col)
+    }
+
+    for j, agg := range r.Aggs {
+        if r.Phase == aggPartial {
+            col := make(states, len(groups))
+            for i, g := range groups {
+                col[i] = g.States[j]
+            }
+            cols = append(cols, col)
+            continue
+        }
+
+        var col Data
+        for _, g := range groups {
+            v, err := agg.Finalize(g.States[j])
+            if err != nil {
+                return nil, err
+            }
+
+            if col == nil {
+                col = v.Type().Data(0)
+            }
+            col = appendData(col, v) // nulls of empty groups
+        }
+        cols = append(cols, col)
+    }
+
+    if names != nil {
+        names = append(names[:len(r.Keys):len(r.Keys)], make([]string, len(r.Aggs))...)
+        return newNamedDataset(names, cols...), nil
+    }
+    return NewDataset(cols...), nil
+}
+
+// rowKeys returns a string key for each row, composed of the values of the
+// provided columns. Rows with equal values have equal keys.
+func rowKeys(data Dataset, cols []int) []string {
+    strs := make([][]string, len(cols))
+    for i, col := range cols {
+        strs[i] = data.At(col).Strings()
+    }
+
+    res := make([]string, data.Len())
+    key := make([]string, len(cols))
+    for i := range res {
+        for j := range strs {
+            key[j] = strs[j][i]
+        }
+        res[i] = strings.Join(key, "\x00")
+    }
+    return res
+}
+
+// states is a Data of aggregation states, used for transmitting the partial
+// states between the aggregation phases.
+type states []interface{}
+type statesType struct {}
+func (*statesType) Name() string { return "states" }
+func (*statesType) Data(n uint) Data { return make(states, n) }
+func (states) Type() Type { return &statesType{} }
+func (vs states) Len() int { return len(vs) }
+func (vs states) Less(i, j int) bool { return false }
+func (vs states) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs states) Slice(s, e int) Data { return vs[s:e] }
+func (vs states) Append(o Data) Data { return append(vs, o.(states)...) }
+func (vs states) Strings() []string {
+    res := make([]string, len(vs))
+    for i, v := range vs {
+        res[i] = fmt.Sprintf("%v", v)
+    }
+    return res
+}
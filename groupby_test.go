@@ -0,0 +1,77 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleGroupBy() {
+    keys := Strs{"a", "b", "a"}
+    values := Strs{"1", "2", "3"}
+    runner := GroupBy([]int{0}, Count(), Sum(1), Min(1), Max(1), Avg(1))
+    data, err := testRun(runner, NewDataset(keys, values))
+    fmt.Println(data, err)
+
+    // Output: [[a b] [2 1] [4 2] [1 2] [3 2] [2 2]] <nil>
+}
+
+// global aggregation without keys always produces a single row, even when the
+// input is empty
+func TestGroupByGlobal(t *testing.T) {
+    runner := GroupBy(nil, Count(), Sum(0))
+    data, err := testRun(runner)
+    require.NoError(t, err)
+    require.Equal(t, "[[0] [0]]", fmt.Sprintf("%v", data))
+
+    data1 := NewDataset(Strs{"1", "2"})
+    data2 := NewDataset(Strs{"3"})
+    data, err = testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, "[[3] [6]]", fmt.Sprintf("%v", data))
+}
+
+// the key columns keep their names, while the aggregated columns are unnamed
+func TestGroupByNamed(t *testing.T) {
+    data := WithSchema(NewDataset(Strs{"a", "b", "a"}, Strs{"1", "2", "3"}), Schema{{"k", Str}, {"v", Str}})
+    res, err := testRun(GroupBy([]int{1, 0}, Count()), data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"v", "k", ""}, res.Schema().Names())
+
+    res, err = testRun(GroupBy([]int{0}, Count()), NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, []string{"", ""}, res.Schema().Names())
+}
+
+func TestGroupByErr(t *testing.T) {
+    runner := GroupBy([]int{0}, Sum(0))
+    _, err := testRun(runner, NewDataset(Strs{"hello"}))
+    require.Error(t, err)
+}
+
+// partial aggregates are exchanged, and merged on a single node per group
+func TestGroupByDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), GroupBy([]int{0}, Count(), Sum(1)), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data1 := NewDataset(Strs{"a", "b", "a"}, Strs{"1", "2", "3"})
+    data2 := NewDataset(Strs{"b", "c", "a"}, Strs{"4", "5", "6"})
+    data3 := NewDataset(Strs{"c", "c"}, Strs{"7", "8"})
+    data, err := testRun(runner, data1, data2, data3)
+    require.NoError(t, err)
+
+    rows := []string{}
+    for i := 0; i < data.Len(); i++ {
+        row := ""
+        for j := 0; j < data.Width(); j++ {
+            row += data.At(j).Strings()[i] + " "
+        }
+        rows = append(rows, row)
+    }
+
+    sort.Strings(rows)
+    require.Equal(t, []string{"a 3 10 ", "b 2 6 ", "c 3 20 "}, rows)
+}
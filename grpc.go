@@ -0,0 +1,252 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "reflect"
+    "strings"
+)
+
+// ServerStream is the receiving side of gRPC server-streaming calls, as
+// implemented by the clients generated by protoc-gen-go-grpc, where M is the
+// generated message type (a pointer to a struct). See GRPCSource
+type ServerStream[M any] interface {
+    Recv() (M, error)
+}
+
+// ClientStream is the sending side of gRPC client-streaming calls, as
+// implemented by the clients generated by protoc-gen-go-grpc, where M is the
+// generated message type (a pointer to a struct), and R is the response. See
+// GRPCSink
+type ClientStream[M, R any] interface {
+    Send(M) error
+    CloseAndRecv() (R, error)
+}
+
+// GRPCSource returns a source Runner that receives the messages of the stream
+// until io.EOF, and emits their fields in batches of BatchSize, converted into
+// the schema like Decode. Message fields are named by their protobuf names
+// (i.e. user_id), or by their JSON or Go names for non-protobuf structs, nested
+// messages are converted into Structs or JSONs, repeated fields into Lists, and
+// enums into their numbers. The stream isn't canceled by the Runner, so it
+// should share its context. Its input is ignored. NOTE: streams cannot be
+// serialized, thus the returned Runner cannot be distributed.
+func GRPCSource[M any](stream ServerStream[M], schema Schema) Runner {
+    return &decode{&messageDecoder[M]{stream}, schema}
+}
+
+// GRPCSink returns a Runner that sends every row of its input datasets into the
+// stream as a message, where the columns are matched to the message fields by
+// their protobuf, JSON or Go names (case-insensitive), and then closes the
+// stream and emits a single summary row of the number of rows sent. The
+// response of the stream is discarded, but its error is returned. Like
+// GRPCSource, it cannot be distributed.
+func GRPCSink[M, R any](stream ClientStream[M, R]) Runner {
+    return &grpcSink[M, R]{stream}
+}
+
+type grpcSink[M, R any] struct {
+    stream ClientStream[M, R]
+}
+
+func (*grpcSink[M, R]) Returns() []Type { return []Type{As(Int, "rows")} }
+func (r *grpcSink[M, R]) Run(ctx context.Context, inp, out chan Dataset) error {
+    summary := make(chan Dataset, 1)
+    err := (&encode{&messageEncoder[M]{r.stream.Send}}).Run(ctx, inp, summary)
+    if err != nil {
+        return err
+    }
+
+    _, err = r.stream.CloseAndRecv()
+    if err != nil {
+        return err
+    }
+
+    out <- <- summary
+    return nil
+}
+
+// messageDecoder is a ValueDecoder of the received messages, as maps of their
+// fields
+type messageDecoder[M any] struct {
+    stream ServerStream[M]
+}
+
+func (d *messageDecoder[M]) Decode(v interface{}) error {
+    msg, err := d.stream.Recv()
+    if err != nil {
+        return err
+    }
+
+    obj, _ := messageValue(reflect.ValueOf(msg)).(map[string]interface{})
+    *v.(*map[string]interface{}) = obj
+    return nil
+}
+
+// messageEncoder is a ValueEncoder that sends maps of fields as messages
+type messageEncoder[M any] struct {
+    send func(M) error
+}
+
+func (e *messageEncoder[M]) Encode(v interface{}) error {
+    var msg M
+    rv := reflect.ValueOf(&msg).Elem()
+    err := setMessageValue(rv, v)
+    if err != nil {
+        return err
+    }
+    return e.send(msg)
+}
+
+// messageValue converts the value of a message field into the values of
+// Value: structs into maps of their exported fields (by their protobuf names),
+// slices into slices of values, and named numeric types (like enums) into
+// their underlying types
+func messageValue(v reflect.Value) interface{} {
+    switch v.Kind() {
+    case reflect.Ptr, reflect.Interface:
+        if v.IsNil() {
+            return nil
+        }
+        return messageValue(v.Elem())
+    case reflect.Struct:
+        obj := map[string]interface{}{}
+        t := v.Type()
+        for i := 0; i < t.NumField(); i++ {
+            if t.Field(i).IsExported() {
+                obj[messageFieldName(t.Field(i))] = messageValue(v.Field(i))
+            }
+        }
+        return obj
+    case reflect.Slice:
+        if v.Type().Elem().Kind() == reflect.Uint8 {
+            return string(v.Bytes())
+        }
+
+        values := make([]interface{}, v.Len())
+        for i := range values {
+            values[i] = messageValue(v.Index(i))
+        }
+        return values
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        return v.Int()
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        return int64(v.Uint())
+    case reflect.Float32, reflect.Float64:
+        return v.Float()
+    case reflect.String:
+        return v.String()
+    case reflect.Bool:
+        return v.Bool()
+    case reflect.Invalid:
+        return nil
+    }
+    return v.Interface()
+}
+
+// setMessageValue sets the message field to the value of Value, where maps are
+// set into structs by their matching fields (see GRPCSource)
+func setMessageValue(field reflect.Value, v interface{}) error {
+    if v == nil {
+        return nil // zero value
+    } else if field.Kind() == reflect.Ptr {
+        if field.IsNil() {
+            field.Set(reflect.New(field.Type().Elem()))
+        }
+        return setMessageValue(field.Elem(), v)
+    }
+
+    switch v := v.(type) {
+    case map[string]interface{}:
+        if field.Kind() != reflect.Struct {
+            break
+        }
+
+        for k, fv := range v {
+            i := messageField(field.Type(), k)
+            if i < 0 {
+                return fmt.Errorf("unknown field %s in %s", k, field.Type())
+            }
+
+            err := setMessageValue(field.Field(i), fv)
+            if err != nil {
+                return fmt.Errorf("%s: %s", k, err)
+            }
+        }
+        return nil
+    case []interface{}:
+        if field.Kind() != reflect.Slice {
+            break
+        }
+
+        field.Set(reflect.MakeSlice(field.Type(), len(v), len(v)))
+        for i, ev := range v {
+            err := setMessageValue(field.Index(i), ev)
+            if err != nil {
+                return err
+            }
+        }
+        return nil
+    }
+
+    rv := reflect.ValueOf(v)
+    if kindOf(rv.Kind()) != kindOf(field.Kind()) || !rv.Type().ConvertibleTo(field.Type()) {
+        return fmt.Errorf("unable to convert %T into %s", v, field.Type())
+    }
+
+    field.Set(rv.Convert(field.Type()))
+    return nil
+}
+
+// kindOf returns the group of convertible kinds of the kind, where numbers
+// aren't converted into strings, and strings are converted into bytes
+func kindOf(k reflect.Kind) reflect.Kind {
+    switch k {
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+        reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+        reflect.Float32, reflect.Float64:
+        return reflect.Float64
+    case reflect.Slice:
+        return reflect.String // bytes, as other slices are set element-wise
+    }
+    return k
+}
+
+// messageField returns the index of the exported field of the struct that
+// matches the name, or -1 if none
+func messageField(t reflect.Type, name string) int {
+    for i := 0; i < t.NumField(); i++ {
+        f := t.Field(i)
+        if !f.IsExported() {
+            continue
+        } else if strings.EqualFold(f.Name, name) || messageFieldName(f) == name {
+            return i
+        }
+
+        for _, opt := range strings.Split(f.Tag.Get("protobuf"), ",") {
+            if opt == "json=" + name {
+                return i
+            }
+        }
+
+        if jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ","); jsonName == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// messageFieldName returns the protobuf name of the struct field, or its JSON
+// name or Go name when it isn't a protobuf field
+func messageFieldName(f reflect.StructField) string {
+    for _, opt := range strings.Split(f.Tag.Get("protobuf"), ",") {
+        if strings.HasPrefix(opt, "name=") {
+            return opt[len("name="):]
+        }
+    }
+
+    if jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ","); jsonName != "" && jsonName != "-" {
+        return jsonName
+    }
+    return f.Name
+}
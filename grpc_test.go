@@ -0,0 +1,112 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// pbUser is a message like those generated by protoc-gen-go
+type pbUser struct {
+    state int // unexported internals of generated messages
+    UserId int64 `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+    Name string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+    Role pbRole `protobuf:"varint,3,opt,name=role,proto3,enum=Role" json:"role,omitempty"`
+    Tags []string `protobuf:"bytes,4,rep,name=tags,proto3" json:"tags,omitempty"`
+    Address *pbAddress `protobuf:"bytes,5,opt,name=address,proto3" json:"address,omitempty"`
+}
+
+type pbRole int32
+
+type pbAddress struct {
+    City string `protobuf:"bytes,1,opt,name=city,proto3" json:"city,omitempty"`
+}
+
+// usersStream is a fake server-streaming and client-streaming gRPC client
+type usersStream struct {
+    users []*pbUser
+    closed bool
+    err error
+}
+
+func (s *usersStream) Recv() (*pbUser, error) {
+    if len(s.users) == 0 {
+        return nil, io.EOF
+    }
+
+    u := s.users[0]
+    s.users = s.users[1:]
+    return u, nil
+}
+
+func (s *usersStream) Send(u *pbUser) error {
+    s.users = append(s.users, u)
+    return s.err
+}
+
+func (s *usersStream) CloseAndRecv() (*pbAddress, error) {
+    s.closed = true
+    return &pbAddress{}, nil
+}
+
+func ExampleGRPCSource() {
+    stream := &usersStream{users: []*pbUser{
+        {UserId: 1, Name: "bob", Role: 2, Tags: []string{"a", "b"}},
+        {UserId: 2, Name: "alice", Address: &pbAddress{"Paris"}},
+    }}
+
+    schema := Schema{
+        {"user_id", Int},
+        {"name", Str},
+        {"role", Int},
+        {"tags", List(Str)},
+        {"address", Struct(Field{"city", Str})},
+    }
+
+    data, err := testRun(GRPCSource[*pbUser](stream, schema))
+    fmt.Println(data, err)
+
+    // Output: [[1 2] [bob alice] [2 0] [[a b] []] [<nil> {city: Paris}]] <nil>
+}
+
+func TestGRPCSink(t *testing.T) {
+    stream := &usersStream{}
+    data := WithSchema(NewDataset(
+        Ints{1, 2},
+        Strs{"bob", "alice"},
+        NewLists(Str, Strs{"a"}, Strs{}),
+        NewStructs([]string{"city"}, Strs{"Paris", "Rome"}),
+    ), Schema{{"userId", Int}, {"Name", Str}, {"tags", List(Str)}, {"address", Struct(Field{"city", Str})}})
+
+    res, err := testRun(GRPCSink[*pbUser, *pbAddress](stream), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[2]]", fmt.Sprint(res))
+    require.True(t, stream.closed)
+    require.Equal(t, []*pbUser{
+        {UserId: 1, Name: "bob", Tags: []string{"a"}, Address: &pbAddress{"Paris"}},
+        {UserId: 2, Name: "alice", Tags: []string{}, Address: &pbAddress{"Rome"}},
+    }, stream.users)
+
+    // round-trip
+    schema := Schema{{"user_id", Int}, {"name", Str}}
+    res, err = testRun(GRPCSource[*pbUser](stream, schema))
+    require.NoError(t, err)
+    require.Equal(t, "[[1 2] [bob alice]]", fmt.Sprint(res))
+}
+
+func TestGRPCSinkErr(t *testing.T) {
+    data := WithSchema(NewDataset(Strs{"bob"}), Schema{{"user_id", Str}})
+    _, err := testRun(GRPCSink[*pbUser, *pbAddress](&usersStream{}), data)
+    require.EqualError(t, err, "user_id: unable to convert string into int64")
+
+    data = WithSchema(NewDataset(Strs{"bob"}), Schema{{"nope", Str}})
+    _, err = testRun(GRPCSink[*pbUser, *pbAddress](&usersStream{}), data)
+    require.EqualError(t, err, "unknown field nope in ep.pbUser")
+
+    data = WithSchema(NewDataset(Ints{1}), Schema{{"user_id", Int}})
+    stream := &usersStream{err: fmt.Errorf("unavailable")}
+    _, err = testRun(GRPCSink[*pbUser, *pbAddress](stream), data)
+    require.EqualError(t, err, "unavailable")
+    require.False(t, stream.closed)
+}
@@ -0,0 +1,157 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "strings"
+    "net/http"
+    "encoding/json"
+)
+
+// Handler returns an http.Handler that plans, runs and streams the results of
+// plans requested by their registered names. The last segment of the request
+// path is the key of the plan in the Runners registry (see Plan), and the
+// request parameters (query string or form) are available to RunnerPlans via
+// the "ep.Params" context value, as url.Values. The planned Runner is
+// distributed to the addrs (or only to this node, when empty) and gathered to
+// this node.
+//
+// The results are streamed with chunked transfer as JSON lines (see
+// JSONWrite), or as CSV with a header line when requested with "format=csv" or
+// an "Accept: text/csv" header, flushed after every dataset. Errors before the
+// first result are returned with an error status. Errors while streaming are
+// reported with a final error frame: a {"$error": "..."} line in JSON, or an
+// "#error: ..." line in CSV, and the Ep-Error HTTP trailer.
+//
+// NOTE: plans that contain exchanges (like Scatter) must implement RunnerPlan
+// and return new Runners for every request, as concurrent runs of the same
+// exchange would collide.
+func Handler(dist Distributer, addrs ...string) http.Handler {
+    if len(addrs) == 0 {
+        addrs = []string{dist.Addr()}
+    }
+    return &handler{dist, addrs}
+}
+
+type handler struct {
+    dist Distributer
+    addrs []string
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+    if req.Method != "GET" && req.Method != "POST" {
+        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    err := req.ParseForm()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    name := req.URL.Path[strings.LastIndex(req.URL.Path, "/") + 1:]
+    ctx, cancel := context.WithCancel(req.Context())
+    defer cancel()
+
+    ctx = context.WithValue(ctx, "ep.Params", req.Form)
+    runner, err := Plan(ctx, name)
+    if _, ok := err.(*errUnregistered); ok {
+        http.Error(w, fmt.Sprintf("unregistered plan %q", name), http.StatusNotFound)
+        return
+    } else if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    csv := req.Form.Get("format") == "csv" || strings.Contains(req.Header.Get("Accept"), "text/csv")
+    stream := &resultStream{w: w, cw: &countWriter{W: w}, csv: csv}
+
+    runner = h.dist.Distribute(Pipeline(runner, Gather()), h.addrs...)
+    inp, out := make(chan Dataset), make(chan Dataset)
+    close(inp)
+
+    errs := make(chan error, 1)
+    go func() {
+        defer close(out)
+        errs <- runSafe(ctx, runner, inp, out)
+    }()
+
+    for data := range out {
+        if stream.cw.Err != nil {
+            continue // the client is gone, drain until canceled
+        } else if err = stream.Write(data); err != nil {
+            cancel()
+        }
+    }
+
+    if runErr := <- errs; runErr != nil && err == nil {
+        err = runErr
+    }
+    stream.Close(err)
+}
+
+// resultStream writes the results of a plan into a response
+type resultStream struct {
+    w http.ResponseWriter
+    cw *countWriter
+    csv bool
+    started bool
+}
+
+// start the response upon the first dataset, with the header line for CSV
+func (s *resultStream) start(data Dataset) {
+    s.started = true
+    if s.csv {
+        s.w.Header().Set("Content-Type", "text/csv")
+    } else {
+        s.w.Header().Set("Content-Type", "application/x-ndjson")
+    }
+
+    s.w.Header().Set("Trailer", "Ep-Error")
+    s.w.WriteHeader(http.StatusOK)
+    if s.csv && data != nil {
+        (&csvWrite{}).writeRecord(s.cw, data.Schema().Names())
+    }
+}
+
+func (s *resultStream) Write(data Dataset) error {
+    if !s.started {
+        s.start(data)
+    }
+
+    if s.csv {
+        (&csvWrite{}).writeRows(s.cw, data)
+    } else if err := writeJSONRows(s.cw, data); err != nil {
+        return err
+    }
+
+    if f, ok := s.w.(http.Flusher); ok && s.cw.Err == nil {
+        f.Flush()
+    }
+    return s.cw.Err
+}
+
+// Close ends the response with the error, if any
+func (s *resultStream) Close(err error) {
+    if err == nil {
+        if !s.started {
+            s.start(nil)
+        }
+        return
+    } else if !s.started {
+        http.Error(s.w, err.Error(), http.StatusInternalServerError)
+        return
+    } else if s.cw.Err != nil {
+        return // the client is gone
+    }
+
+    msg := strings.Replace(err.Error(), "\n", " ", -1)
+    if s.csv {
+        s.cw.WriteString("#error: " + msg + "\n")
+    } else {
+        b, _ := json.Marshal(map[string]string{"$error": msg})
+        s.cw.Write(append(b, '\n'))
+    }
+    s.w.Header().Set("Ep-Error", msg)
+}
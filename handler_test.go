@@ -0,0 +1,112 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "context"
+    "strings"
+    "testing"
+    "net/url"
+    "net/http"
+    "net/http/httptest"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = Runners.Register("lines", &linesPlan{})
+
+// linesPlan plans the emission of the lines of the "lines" parameter, failing
+// with the "fail" parameter, if provided, either immediately or after all of
+// the lines were emitted with the "partial" parameter
+type linesPlan struct {}
+func (*linesPlan) Returns() []Type { return []Type{Str} }
+func (*linesPlan) Run(ctx context.Context, inp, out chan Dataset) error {
+    return fmt.Errorf("unplanned")
+}
+
+func (*linesPlan) Plan(ctx context.Context, arg interface{}) (Runner, error) {
+    params := ctx.Value("ep.Params").(url.Values)
+    if params.Get("lines") == "" {
+        return nil, fmt.Errorf("missing lines")
+    }
+
+    runner := ReadLines(strings.NewReader(params.Get("lines")))
+    if msg := params.Get("fail"); msg != "" && params.Get("partial") != "" {
+        runner = Pipeline(runner, &failAfter{fmt.Errorf(msg)})
+    } else if msg != "" {
+        runner = Pipeline(runner, &ErrRunner{fmt.Errorf(msg)})
+    }
+    return runner, nil
+}
+
+// failAfter forwards its input, and then fails
+type failAfter struct { error }
+func (*failAfter) Returns() []Type { return []Type{Wildcard} }
+func (r *failAfter) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        out <- data
+    }
+    return r.error
+}
+
+func TestHandler(t *testing.T) {
+    pipes := NewPipes()
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 1
+
+    ln, err := pipes.Listen(":5551")
+    require.NoError(t, err)
+
+    dist := NewDistributer(":5551", ln)
+    go dist.Start()
+    defer dist.Close()
+
+    srv := httptest.NewServer(Handler(dist))
+    defer srv.Close()
+
+    get := func(path string, headers ...string) (int, string, string) {
+        req, err := http.NewRequest("GET", srv.URL + path, nil)
+        require.NoError(t, err)
+        if len(headers) > 0 {
+            req.Header.Set("Accept", headers[0])
+        }
+
+        resp, err := http.DefaultClient.Do(req)
+        require.NoError(t, err)
+        defer resp.Body.Close()
+
+        body, err := io.ReadAll(resp.Body)
+        require.NoError(t, err)
+        return resp.StatusCode, string(body), resp.Trailer.Get("Ep-Error")
+    }
+
+    status, body, trailer := get("/plans/lines?lines=a%0Ab")
+    require.Equal(t, 200, status)
+    require.Equal(t, "{\"line\":\"a\"}\n{\"line\":\"b\"}\n", body)
+    require.Equal(t, "", trailer)
+
+    status, body, _ = get("/lines?lines=a%0A\"b\"", "text/csv")
+    require.Equal(t, 200, status)
+    require.Equal(t, "line\na\n\"\"\"b\"\"\"\n", body)
+
+    status, body, trailer = get("/lines?lines=a&fail=oops&partial=1&format=csv")
+    require.Equal(t, 200, status)
+    require.Equal(t, "line\na\n#error: oops\n", body)
+    require.Equal(t, "oops", trailer)
+
+    status, body, trailer = get("/lines?lines=a&fail=oops&partial=1")
+    require.Equal(t, 200, status)
+    require.Equal(t, "{\"line\":\"a\"}\n{\"$error\":\"oops\"}\n", body)
+    require.Equal(t, "oops", trailer)
+
+    status, body, _ = get("/lines?lines=a&fail=oops")
+    require.Equal(t, 500, status)
+    require.Equal(t, "oops\n", body)
+
+    status, body, _ = get("/lines")
+    require.Equal(t, 400, status)
+    require.Equal(t, "missing lines\n", body)
+
+    status, body, _ = get("/nope")
+    require.Equal(t, 404, status)
+    require.Equal(t, "unregistered plan \"nope\"\n", body)
+}
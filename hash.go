@@ -0,0 +1,208 @@
+package ep
+
+import (
+    "math"
+    "strconv"
+)
+
+// Hasher is optionally implemented by Data types in order to provide a fast,
+// vectorized hash of their values, used for partitioning (see Partition),
+// grouping (see GroupBy) and hash joins (see Join). Data types that don't
+// implement it are hashed by their string representation.
+type Hasher interface {
+
+    // Hash the value of every row with the seed, and combine it into the
+    // corresponding element of hashes, which is of the same length as the data.
+    // Equal values must have equal hashes. In order to keep the hashes
+    // consistent across types (e.g. when joining integer and string keys),
+    // values should hash like their string representation, see HashString.
+    Hash(seed uint64, hashes []uint64)
+}
+
+// Hash the values of the data with the seed into hashes, which must be of the
+// same length as the data. The value of every row is combined into its
+// existing hash, thus calling Hash for several columns with the same hashes
+// produces a hash of the combined row. See Hasher
+func Hash(data Data, seed uint64, hashes []uint64) {
+    if h, ok := data.(Hasher); ok {
+        h.Hash(seed, hashes)
+        return
+    }
+
+    for i, s := range data.Strings() {
+        hashes[i] = combineHash(hashes[i], seed ^ HashString(s))
+    }
+}
+
+// fnv-1a constants
+const hashOffset uint64 = 14695981039346656037
+const hashPrime uint64 = 1099511628211
+
+// HashString returns the hash of a string, which is the hash of values that are
+// represented by it. See Hasher
+func HashString(s string) uint64 {
+    h := hashOffset
+    for i := 0; i < len(s); i++ {
+        h ^= uint64(s[i])
+        h *= hashPrime
+    }
+    return h
+}
+
+func hashBytes(b []byte) uint64 {
+    h := hashOffset
+    for _, c := range b {
+        h ^= uint64(c)
+        h *= hashPrime
+    }
+    return h
+}
+
+// combineHash mixes a value hash into an existing row hash
+func combineHash(h, v uint64) uint64 {
+    h = (h ^ v) * hashPrime
+    return h ^ (h >> 32)
+}
+
+// hashRows returns the hash of every row, composed of the values of the
+// provided columns. Rows with equal values have equal hashes.
+func hashRows(data Dataset, cols []int) []uint64 {
+    hashes := make([]uint64, data.Len())
+    for _, col := range cols {
+        Hash(data.At(col), 0, hashes)
+    }
+    return hashes
+}
+
+// equalRows returns true if row i of the columns of a is equal to row j of the
+// columns of b
+func equalRows(a Dataset, i int, aCols []int, b Dataset, j int, bCols []int) bool {
+    for k := range aCols {
+        if !equalAt(a.At(aCols[k]), i, b.At(bCols[k]), j) {
+            return false
+        }
+    }
+    return true
+}
+
+// equalAt returns true if the value at index i of a equals the value at index j
+// of b. Values of different types are compared by their string representation,
+// like they're hashed. Nulls are equal to other nulls.
+func equalAt(a Data, i int, b Data, j int) bool {
+    if vs, ok := a.(nullable); ok {
+        if !vs.Valid[i] {
+            return isNullAt(b, j)
+        }
+        a = vs.Values
+    }
+
+    if vs, ok := b.(nullable); ok {
+        if !vs.Valid[j] {
+            return isNullAt(a, i)
+        }
+        b = vs.Values
+    }
+
+    switch a := a.(type) {
+    case Strs:
+        if b, ok := b.(Strs); ok {
+            return a[i] == b[j]
+        }
+    case Ints:
+        if b, ok := b.(Ints); ok {
+            return a[i] == b[j]
+        }
+    case Bools:
+        if b, ok := b.(Bools); ok {
+            return a[i] == b[j]
+        }
+    case Enums:
+        if b, ok := b.(Enums); ok && sameDict(a, b) {
+            return a.Codes[i] == b.Codes[j]
+        }
+    }
+
+    if !Equal(a.Type(), b.Type()) {
+        return a.Slice(i, i + 1).Strings()[0] == b.Slice(j, j + 1).Strings()[0]
+    }
+    return CompareAt(a, i, b, j) == 0
+}
+
+func isNullAt(data Data, i int) bool {
+    if vs, ok := data.(nullable); ok {
+        return !vs.Valid[i]
+    }
+    return data.Type() == Null
+}
+
+// Hash implements Hasher
+func (vs Strs) Hash(seed uint64, hashes []uint64) {
+    for i, s := range vs {
+        hashes[i] = combineHash(hashes[i], seed ^ HashString(s))
+    }
+}
+
+// Hash implements Hasher
+func (vs Bools) Hash(seed uint64, hashes []uint64) {
+    t, f := seed ^ HashString("true"), seed ^ HashString("false")
+    for i, v := range vs {
+        if v {
+            hashes[i] = combineHash(hashes[i], t)
+        } else {
+            hashes[i] = combineHash(hashes[i], f)
+        }
+    }
+}
+
+// Hash implements Hasher, using the Hash function of the type. See TypeOps
+func (vs Column[T]) Hash(seed uint64, hashes []uint64) {
+    hash := opsOf[T]().Hash
+    for i, v := range vs {
+        hashes[i] = combineHash(hashes[i], seed ^ hash(v))
+    }
+}
+
+// Hash implements Hasher. Nulls are hashed like empty strings, which is their
+// string representation
+func (vs nulls) Hash(seed uint64, hashes []uint64) {
+    h := seed ^ HashString("")
+    for i := range hashes {
+        hashes[i] = combineHash(hashes[i], h)
+    }
+}
+
+// Hash implements Hasher
+func (vs nullable) Hash(seed uint64, hashes []uint64) {
+    values := append([]uint64{}, hashes...)
+    Hash(vs.Values, seed, values)
+
+    null := seed ^ HashString("")
+    for i, ok := range vs.Valid {
+        if ok {
+            hashes[i] = values[i]
+        } else {
+            hashes[i] = combineHash(hashes[i], null)
+        }
+    }
+}
+
+// hash functions of the built-in numeric types, consistent with their string
+// representation, without allocating it
+func hashInt(v int64) uint64 {
+    var b [20]byte
+    return hashBytes(strconv.AppendInt(b[:0], v, 10))
+}
+
+// hashFloat normalizes -0 to 0 and all NaNs to a single NaN, as they're equal
+// by Less, before hashing the text rather than the bits, in order to hash
+// whole floats like the equal Ints and Strs
+func hashFloat(v float64) uint64 {
+    if v == 0 {
+        v = 0
+    } else if math.IsNaN(v) {
+        v = math.NaN()
+    }
+
+    var b [32]byte
+    return hashBytes(strconv.AppendFloat(b[:0], v, 'f', -1, 64))
+}
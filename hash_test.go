@@ -0,0 +1,103 @@
+package ep
+
+import (
+    "fmt"
+    "math"
+    "time"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleHash() {
+    a, b := make([]uint64, 2), make([]uint64, 2)
+    Hash(Ints{1, 2}, 0, a)
+    Hash(Strs{"1", "3"}, 0, b)
+    fmt.Println(a[0] == b[0], a[1] == b[1])
+
+    // Output: true false
+}
+
+// Tests that equal floats are hashed equally, regardless of their bits
+func TestHashFloatsEqual(t *testing.T) {
+    nan := math.Float64frombits(math.Float64bits(math.NaN()) + 1)
+    data := Floats{0, math.Copysign(0, -1), math.NaN(), nan}
+    require.True(t, math.IsNaN(nan))
+
+    hashes := make([]uint64, data.Len())
+    Hash(data, 0, hashes)
+    require.Equal(t, hashes[0], hashes[1])
+    require.Equal(t, hashes[2], hashes[3])
+    require.NotEqual(t, hashes[0], hashes[2])
+}
+
+// Tests that values are hashed consistently with their string representation
+// across the types
+func TestHashConsistent(t *testing.T) {
+    ts := NewTimes(time.Unix(1, 0))
+    all := []Data{
+        Strs{"1", "", "true"},
+        Ints{1, 0, 0},
+        Floats{1, 0.5, 0},
+        Bools{false, false, true},
+        Null.Data(3),
+        Nullable(Ints{1, 5, 0}, Bools{true, false, true}),
+        ts,
+    }
+
+    expected := []uint64{}
+    for _, s := range []string{"1", "", "true", "0", "0.5", "false", ts.Strings()[0]} {
+        expected = append(expected, combineHash(0, HashString(s)))
+    }
+
+    for _, data := range all {
+        hashes := make([]uint64, data.Len())
+        Hash(data, 0, hashes)
+        for i, s := range data.Strings() {
+            h := combineHash(0, HashString(s))
+            require.Equal(t, h, hashes[i], "%s %d", data.Type().Name(), i)
+            require.Contains(t, expected, h)
+        }
+    }
+}
+
+func TestHashRows(t *testing.T) {
+    data := NewDataset(Strs{"a", "a", "b"}, Ints{1, 2, 1}, Ints{1, 1, 1})
+    hashes := hashRows(data, []int{0, 2})
+    require.Equal(t, hashes[0], hashes[1])
+    require.NotEqual(t, hashes[0], hashes[2])
+
+    hashes = hashRows(data, []int{0, 1})
+    require.NotEqual(t, hashes[0], hashes[1])
+    require.NotEqual(t, hashes[0], hashes[2])
+
+    // the seed changes the hashes
+    seeded := make([]uint64, 3)
+    Hash(data.At(0), 42, seeded)
+    Hash(data.At(1), 42, seeded)
+    require.NotEqual(t, hashes, seeded)
+}
+
+func TestEqualAt(t *testing.T) {
+    values := Nullable(Ints{1, 2}, Bools{true, false})
+    require.True(t, equalAt(values, 0, Ints{1}, 0))
+    require.True(t, equalAt(values, 0, Strs{"1"}, 0))
+    require.False(t, equalAt(values, 0, Ints{2}, 0))
+    require.True(t, equalAt(values, 1, Null.Data(1), 0))
+    require.False(t, equalAt(values, 1, Ints{2}, 0))
+    require.True(t, equalAt(NewTimes(time.Unix(1, 0)), 0, NewTimes(time.Unix(1, 0)), 0))
+}
+
+// Tests that rows are joined and grouped by the values of their keys across
+// types and batches
+func TestHashKeysAcrossTypes(t *testing.T) {
+    ids := &constRunner{NewDataset(Ints{1, 3}, Strs{"x", "y"})}
+    runner := Join(InnerJoin, []int{0}, []int{0}, users, ids)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    require.NoError(t, err)
+    require.Equal(t, "[[1 3] [alice carol] [1 3] [x y]]", fmt.Sprintf("%v", data))
+
+    runner = GroupBy([]int{0}, Count())
+    data, err = testRun(runner, NewDataset(Ints{1, 2}), NewDataset(Ints{2, 1, 2}))
+    require.NoError(t, err)
+    require.Equal(t, "[[1 2] [2 3]]", fmt.Sprintf("%v", data))
+}
@@ -0,0 +1,321 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "sync"
+    "bytes"
+    "context"
+    "strings"
+    "strconv"
+    "net/url"
+    "net/http"
+    "encoding/json"
+)
+
+var _ = registerGob(&httpSource{}, &jsonPages{})
+
+// Pagination describes how the pages of an HTTP API are requested. Pages are
+// requested by substituting placeholders in the URL template of HTTPSource:
+// {page} and {offset} for the index-based paginations (see PageNumbers and
+// Offsets), {limit} for the page size, and {cursor} for cursor-based
+// pagination (see Cursors). The zero value requests a single page.
+type Pagination struct {
+    Start int64 // number of the first page, or the first offset
+    Size int64 // number of items per page, for offsets
+
+    // Concurrency is the maximum number of concurrent requests of index-based
+    // pagination. Cursor-based pagination is always sequential
+    Concurrency int
+
+    // CursorPath is the dot-separated path (see GetPath) of the cursor of the
+    // next page within every response, for cursor-based pagination. Cursor is
+    // the cursor of the first page, for resuming from a previous run
+    CursorPath string
+    Cursor string
+}
+
+// PageNumbers returns the Pagination of pages numbered from start, requesting
+// up to concurrency pages concurrently, until the first empty page
+func PageNumbers(start int64, concurrency int) Pagination {
+    return Pagination{Start: start, Concurrency: concurrency}
+}
+
+// Offsets returns the Pagination of pages of size items, requested by their
+// offsets from 0, up to concurrency pages concurrently, until the first page
+// with less than size items
+func Offsets(size int64, concurrency int) Pagination {
+    return Pagination{Size: size, Concurrency: concurrency}
+}
+
+// Cursors returns the Pagination of pages that contain the cursor of their
+// next page at the path, starting from the resume cursor (or from the first
+// page if it's empty), until the first page without a next cursor
+func Cursors(path, resume string) Pagination {
+    return Pagination{CursorPath: path, Cursor: resume}
+}
+
+// PageDecoder decodes the body of a single page into a dataset
+type PageDecoder interface {
+
+    // Returns the types of the decoded datasets
+    Returns() []Type
+
+    // Decode the body of a page
+    Decode(body []byte) (Dataset, error)
+}
+
+// JSONPages returns a PageDecoder of JSON responses, where the items of the
+// page are the array of objects at the dot-separated path (see GetPath), or
+// the response itself when the path is empty. The items are converted into
+// the schema like JSONScan.
+func JSONPages(path string, schema Schema) PageDecoder {
+    return &jsonPages{path, schema}
+}
+
+// HTTPSource returns a source Runner that fetches the pages of an HTTP API with
+// GET requests of the URL template (see Pagination), decodes every page with
+// the decoder, and emits them in order. Optional headers, like authorization,
+// are provided as "Name: value" strings. Non-2xx responses are errors, which
+// include the cursor of the failed page for cursor-based pagination, in order
+// to resume from it. Its input is ignored.
+//
+// When distributed, the pages of index-based pagination are divided between
+// the participating nodes, while cursor-based pagination (which can't be
+// divided) is fetched by the first node.
+func HTTPSource(urlTemplate string, pagination Pagination, decoder PageDecoder, headers ...string) Runner {
+    return &httpSource{urlTemplate, pagination, decoder, headers}
+}
+
+type httpSource struct {
+    URL string
+    Pagination Pagination
+    Decoder PageDecoder
+    Headers []string
+}
+
+func (r *httpSource) Returns() []Type { return r.Decoder.Returns() }
+func (r *httpSource) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    node, nodes := 0, 1
+    for i, n := range allNodes {
+        if n == thisNode {
+            node, nodes = i, len(allNodes)
+        }
+    }
+
+    p := r.Pagination
+    if p.CursorPath != "" {
+        if node != 0 {
+            return nil // fetched by the first node
+        }
+        return r.runCursors(ctx, out)
+    }
+
+    paginated := strings.Contains(r.URL, "{page}") || strings.Contains(r.URL, "{offset}")
+    if !paginated {
+        if node != 0 {
+            return nil
+        }
+
+        data, err := r.fetch(ctx, r.expand(p.Start, ""))
+        if err != nil {
+            return err
+        }
+        return r.emit(ctx, data, out)
+    }
+
+    concurrency := p.Concurrency
+    if concurrency < 1 {
+        concurrency = 1
+    }
+
+    // fetch waves of concurrent pages, where this node fetches every nodes'th
+    // page, and emit them in order until the last page
+    for idx := int64(node); ; {
+        wave := make([]Dataset, concurrency)
+        errs := make([]error, concurrency)
+        var wg sync.WaitGroup
+        for i := range wave {
+            n := idx + int64(i * nodes)
+            v := p.Start + n
+            if p.Size > 0 {
+                v = p.Start + n * p.Size
+            }
+
+            wg.Add(1)
+            go func(i int, v int64) {
+                defer wg.Done()
+                wave[i], errs[i] = r.fetch(ctx, r.expand(v, ""))
+            }(i, v)
+        }
+        wg.Wait()
+
+        for i, data := range wave {
+            if errs[i] != nil {
+                return errs[i]
+            } else if data.Len() == 0 {
+                return nil
+            }
+
+            err := r.emit(ctx, data, out)
+            if err != nil {
+                return err
+            } else if p.Size > 0 && int64(data.Len()) < p.Size {
+                return nil
+            }
+        }
+        idx += int64(concurrency * nodes)
+    }
+}
+
+// runCursors fetches the pages sequentially, by the cursor of the previous page
+func (r *httpSource) runCursors(ctx context.Context, out chan Dataset) error {
+    keys := strings.Split(r.Pagination.CursorPath, ".")
+    cursor := r.Pagination.Cursor
+    for {
+        body, err := r.get(ctx, r.expand(r.Pagination.Start, cursor))
+        if err != nil {
+            return fmt.Errorf("cursor %q: %s", cursor, err)
+        }
+
+        data, err := r.Decoder.Decode(body)
+        if err != nil {
+            return fmt.Errorf("cursor %q: %s", cursor, err)
+        }
+
+        err = r.emit(ctx, data, out)
+        if err != nil {
+            return err
+        }
+
+        var v interface{}
+        dec := json.NewDecoder(bytes.NewReader(body))
+        dec.UseNumber()
+        if err = dec.Decode(&v); err != nil {
+            return fmt.Errorf("cursor %q: %s", cursor, err)
+        }
+
+        next, ok := getPath(v, keys)
+        if !ok || next == nil || next == "" {
+            return nil
+        }
+        cursor = fmt.Sprint(next)
+    }
+}
+
+// expand the placeholders of the URL template
+func (r *httpSource) expand(v int64, cursor string) string {
+    s := strconv.FormatInt(v, 10)
+    return strings.NewReplacer(
+        "{page}", s,
+        "{offset}", s,
+        "{limit}", strconv.FormatInt(r.Pagination.Size, 10),
+        "{cursor}", url.QueryEscape(cursor),
+    ).Replace(r.URL)
+}
+
+func (r *httpSource) fetch(ctx context.Context, u string) (Dataset, error) {
+    body, err := r.get(ctx, u)
+    if err != nil {
+        return nil, err
+    }
+    return r.Decoder.Decode(body)
+}
+
+func (r *httpSource) get(ctx context.Context, u string) ([]byte, error) {
+    req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, h := range r.Headers {
+        k, v, ok := strings.Cut(h, ":")
+        if !ok {
+            return nil, fmt.Errorf("invalid header %q", h)
+        }
+        req.Header.Add(strings.TrimSpace(k), strings.TrimSpace(v))
+    }
+
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    defer resp.Body.Close()
+
+    body, err := io.ReadAll(resp.Body)
+    if err != nil {
+        return nil, err
+    } else if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return nil, fmt.Errorf("GET %s: %s", u, resp.Status)
+    }
+    return body, nil
+}
+
+func (r *httpSource) emit(ctx context.Context, data Dataset, out chan Dataset) error {
+    if data.Len() == 0 {
+        return nil
+    }
+
+    select {
+    case out <- data:
+        return nil
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
+
+type jsonPages struct {
+    Path string
+    Schema Schema
+}
+
+func (d *jsonPages) Returns() []Type {
+    types := []Type{}
+    for _, f := range d.Schema {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (d *jsonPages) Decode(body []byte) (Dataset, error) {
+    var v interface{}
+    dec := json.NewDecoder(bytes.NewReader(body))
+    dec.UseNumber()
+    err := dec.Decode(&v)
+    if err != nil {
+        return nil, err
+    }
+
+    var keys []string
+    if d.Path != "" {
+        keys = strings.Split(d.Path, ".")
+    }
+
+    items, _ := getPath(v, keys)
+    arr, ok := items.([]interface{})
+    if !ok && items != nil {
+        return nil, fmt.Errorf("expected an array at %q, got %T", d.Path, items)
+    }
+
+    cols := make([]Data, len(d.Schema))
+    values := make([]interface{}, len(arr))
+    for i, f := range d.Schema {
+        for j, item := range arr {
+            obj, ok := item.(map[string]interface{})
+            if !ok {
+                return nil, fmt.Errorf("expected an object at %q, got %T", d.Path, item)
+            }
+            values[j] = obj[f.Name]
+        }
+
+        cols[i], err = jsonData(f.Type, values)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return newNamedDataset(d.Schema.Names(), cols...), nil
+}
@@ -0,0 +1,152 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "context"
+    "strconv"
+    "testing"
+    "net/http"
+    "net/http/httptest"
+    "github.com/stretchr/testify/require"
+)
+
+// itemsServer serves 7 items in pages, by page numbers, offsets or cursors,
+// and counts the requests
+func itemsServer(requests *int) *httptest.Server {
+    var l sync.Mutex
+    return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        l.Lock()
+        *requests++
+        l.Unlock()
+
+        if r.Header.Get("Authorization") != "Bearer token" {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+
+        // pages of 3 items
+        q := r.URL.Query()
+        start := 0
+        if page := q.Get("page"); page != "" {
+            n, _ := strconv.Atoi(page)
+            start = (n - 1) * 3
+        } else if offset := q.Get("offset"); offset != "" {
+            start, _ = strconv.Atoi(offset)
+        } else if cursor := q.Get("cursor"); cursor != "" {
+            start, _ = strconv.Atoi(cursor)
+        }
+
+        items := ""
+        for i := start; i < start + 3 && i < 7; i++ {
+            if items != "" {
+                items += ","
+            }
+            items += fmt.Sprintf(`{"id": %d, "name": "item%d"}`, i, i)
+        }
+
+        next := "null"
+        if start + 3 < 7 {
+            next = strconv.Itoa(start + 3)
+        }
+        fmt.Fprintf(w, `{"data": {"items": [%s]}, "next": %s}`, items, next)
+    }))
+}
+
+func ExampleHTTPSource() {
+    requests := 0
+    srv := itemsServer(&requests)
+    defer srv.Close()
+
+    decoder := JSONPages("data.items", Schema{{"id", Int}, {"name", Str}})
+    runner := HTTPSource(srv.URL + "?page={page}", PageNumbers(1, 2), decoder, "Authorization: Bearer token")
+    data, err := testRun(runner)
+    fmt.Println(data, err)
+
+    // Output: [[0 1 2 3 4 5 6] [item0 item1 item2 item3 item4 item5 item6]] <nil>
+}
+
+func TestHTTPSourcePagination(t *testing.T) {
+    requests := 0
+    srv := itemsServer(&requests)
+    defer srv.Close()
+
+    decoder := JSONPages("data.items", Schema{{"id", Int}})
+    auth := "Authorization: Bearer token"
+    tests := []struct {
+        URL string
+        Pagination Pagination
+        Expected string
+        Requests int
+    }{
+        {"?offset={offset}&limit={limit}", Offsets(3, 1), "[[0 1 2 3 4 5 6]]", 3},
+        {"?offset={offset}&limit={limit}", Offsets(3, 5), "[[0 1 2 3 4 5 6]]", 5},
+        {"?page={page}", PageNumbers(2, 1), "[[3 4 5 6]]", 3},
+        {"?cursor={cursor}", Cursors("next", ""), "[[0 1 2 3 4 5 6]]", 3},
+        {"?cursor={cursor}", Cursors("next", "3"), "[[3 4 5 6]]", 2},
+        {"", Pagination{}, "[[0 1 2]]", 1},
+    }
+
+    for _, test := range tests {
+        requests = 0
+        data, err := testRun(HTTPSource(srv.URL + test.URL, test.Pagination, decoder, auth))
+        require.NoError(t, err)
+        require.Equal(t, test.Expected, fmt.Sprint(data), test.URL)
+        require.Equal(t, test.Requests, requests, test.URL)
+    }
+}
+
+// Test that the pages are divided between the nodes, except for cursors
+func TestHTTPSourceDistributed(t *testing.T) {
+    requests := 0
+    srv := itemsServer(&requests)
+    defer srv.Close()
+
+    decoder := JSONPages("data.items", Schema{{"id", Int}})
+    nodes := []string{":5551", ":5552"}
+    tests := []struct {
+        URL string
+        Pagination Pagination
+    }{
+        {"?page={page}", PageNumbers(1, 1)},
+        {"?offset={offset}", Offsets(3, 2)},
+        {"?cursor={cursor}", Cursors("next", "")},
+    }
+
+    for _, test := range tests {
+        runner := HTTPSource(srv.URL + test.URL, test.Pagination, decoder, "Authorization: Bearer token")
+        seen := map[string]int{}
+        for _, node := range nodes {
+            ctx := context.WithValue(context.Background(), "ep.AllNodes", nodes)
+            ctx = context.WithValue(ctx, "ep.ThisNode", node)
+            data, err := runCtx(ctx, runner)
+            require.NoError(t, err)
+            if data.Width() == 0 {
+                continue // no pages were fetched by this node
+            }
+
+            for _, id := range data.At(0).Strings() {
+                seen[id]++
+            }
+        }
+
+        require.Equal(t, map[string]int{"0": 1, "1": 1, "2": 1, "3": 1, "4": 1, "5": 1, "6": 1}, seen, test.URL)
+    }
+}
+
+func TestHTTPSourceErr(t *testing.T) {
+    requests := 0
+    srv := itemsServer(&requests)
+    defer srv.Close()
+
+    decoder := JSONPages("data.items", Schema{{"id", Int}})
+    _, err := testRun(HTTPSource(srv.URL + "?cursor={cursor}", Cursors("next", "3"), decoder))
+    require.EqualError(t, err, `cursor "3": GET ` + srv.URL + "?cursor=3: 401 Unauthorized")
+
+    decoder = JSONPages("data", Schema{{"id", Int}})
+    _, err = testRun(HTTPSource(srv.URL, Pagination{}, decoder, "Authorization: Bearer token"))
+    require.EqualError(t, err, `expected an array at "data", got map[string]interface {}`)
+
+    _, err = testRun(HTTPSource(srv.URL, Pagination{}, decoder, "invalid"))
+    require.EqualError(t, err, `invalid header "invalid"`)
+}
@@ -0,0 +1,23 @@
+package ep
+
+import (
+    "strconv"
+)
+
+// Int is a built-in Type representing 64-bit integer values. Use Int.Data(n) to
+// create Data instances of `n` zeros
+var Int = NewType(TypeOps[int64]{
+    Name: "int",
+    Size: 8,
+    Less: func(a, b int64) bool { return a < b },
+    String: func(v int64) string { return strconv.FormatInt(v, 10) },
+    Hash: hashInt,
+})
+
+// IntType is the Type of Ints
+type IntType = ColumnType[int64]
+
+// Ints is a built-in Data implementation of 64-bit integer values. Values are
+// compared numerically, and hashed (for partitioning and grouping) by their
+// decimal string representation.
+type Ints = Column[int64]
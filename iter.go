@@ -0,0 +1,181 @@
+package ep
+
+import (
+    "io"
+    "context"
+)
+
+// Iterator is a pull-based stream of datasets: the lower-level alternative to
+// the channels between Runners. Next returns the next dataset, or io.EOF when
+// the stream is exhausted. See IterRunner
+type Iterator interface {
+    Next() (Dataset, error)
+}
+
+// IterRunner is implemented by Runners that can also be pulled as iterators,
+// in the go-routine of the caller. Pipelines of IterRunners run in a single
+// go-routine, without a channel per batch per stage, which dominates the cost
+// of stages over small batches. Iter returns the iterator of the output of the
+// runner given its input iterator. The built-in stateless runners, like Filter,
+// Pick and PassThrough, implement it. Their Run is implemented by RunIter.
+type IterRunner interface {
+    Runner
+    Iter(ctx context.Context, inp Iterator) Iterator
+}
+
+// Iter returns the iterator of the output of any runner given its input
+// iterator. IterRunners are pulled directly, while other runners are run in a
+// go-routine, where their input and output are adapted to channels. In that
+// case, the iterator must be exhausted, or the context canceled, in order for
+// the go-routine to exit
+func Iter(ctx context.Context, r Runner, inp Iterator) Iterator {
+    if r, ok := r.(IterRunner); ok {
+        return r.Iter(ctx, inp)
+    }
+
+    parent := ctx
+    ctx, cancel := context.WithCancel(ctx)
+    it := &chanIter{
+        out: newChan(ctx),
+        errs: make(chan error, 1),
+        inpErrs: make(chan error, 1),
+    }
+
+    in := newChan(ctx)
+    go func() {
+        defer close(in)
+        for {
+            data, err := inp.Next()
+            if err == io.EOF {
+                break
+            } else if err != nil {
+                it.inpErrs <- err
+                cancel()
+                return
+            }
+
+            select {
+            case in <- data:
+            case <- ctx.Done():
+                // the runner has exited early, the rest of the input is
+                // discarded
+                it.inpErrs <- nil
+                return
+            }
+        }
+        it.inpErrs <- nil
+    }()
+
+    go func() {
+        err := runSafe(ctx, r, in, it.out)
+        if err == context.Canceled && parent.Err() == nil {
+            err = nil // canceled by the input error, reported below
+        }
+
+        cancel()
+        for _ = range in {} // drain the input in case the runner has exited early
+        close(it.out)
+        it.errs <- err
+    }()
+    return it
+}
+
+// chanIter is the iterator of a runner that's run over channels
+type chanIter struct {
+    out chan Dataset
+    errs chan error // the error of the runner
+    inpErrs chan error // the error of the input iterator
+    err error // the final error, once the output is exhausted
+}
+
+func (it *chanIter) Next() (Dataset, error) {
+    if data, ok := <- it.out; ok {
+        return data, nil
+    }
+
+    if it.errs != nil {
+        it.err = <- it.errs
+        if inpErr := <- it.inpErrs; it.err == nil {
+            it.err = inpErr
+        }
+
+        if it.err == nil {
+            it.err = io.EOF
+        }
+        it.errs = nil
+    }
+    return nil, it.err
+}
+
+// ChanIterator returns an Iterator of the datasets of the channel, until it's
+// closed
+func ChanIterator(ch chan Dataset) Iterator {
+    return chanIterator(ch)
+}
+
+type chanIterator chan Dataset
+func (ch chanIterator) Next() (Dataset, error) {
+    data, ok := <- ch
+    if !ok {
+        return nil, io.EOF
+    }
+    return data, nil
+}
+
+// SendIter sends all of the datasets of the iterator to the channel, until the
+// iterator is exhausted or fails, and returns its error, if any
+func SendIter(it Iterator, out chan Dataset) error {
+    for {
+        data, err := it.Next()
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+        out <- data
+    }
+}
+
+// RunIter runs the IterRunner over channels, by adapting the input channel to
+// an Iterator, and sending its output iterator to the output channel. It's the
+// Run implementation of IterRunners that only implement Iter
+func RunIter(ctx context.Context, r IterRunner, inp, out chan Dataset) error {
+    return SendIter(r.Iter(ctx, ChanIterator(inp)), out)
+}
+
+// MapBatches returns an Iterator that applies the function to every dataset
+// of the input iterator, and skips the nil results. It's the Iter
+// implementation of stateless runners that process every batch separately
+func MapBatches(inp Iterator, fn func(Dataset) (Dataset, error)) Iterator {
+    return &mapIter{inp, fn}
+}
+
+type mapIter struct {
+    inp Iterator
+    fn func(Dataset) (Dataset, error)
+}
+
+func (it *mapIter) Next() (Dataset, error) {
+    for {
+        data, err := it.inp.Next()
+        if err != nil {
+            return nil, err
+        }
+
+        data, err = it.fn(data)
+        if err != nil || data != nil {
+            return data, err
+        }
+    }
+}
+
+// iterable returns true if all of the stages of the runner are IterRunners,
+// and thus it can be pulled in a single go-routine
+func iterable(r Runner) bool {
+    for _, stage := range Stages(r) {
+        if _, ok := stage.(IterRunner); !ok {
+            return false
+        }
+    }
+    return true
+}
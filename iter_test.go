@@ -0,0 +1,147 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleIter() {
+    inp := make(chan Dataset, 2)
+    inp <- NewDataset(Strs{"a", "b", "c"}, Strs{"1", "2", "3"})
+    inp <- NewDataset(Strs{"d"}, Strs{"4"})
+    close(inp)
+
+    runner := Pipeline(Filter(Where(0, "!=", "b")), Pick(1))
+    it := Iter(context.Background(), runner, ChanIterator(inp))
+    for {
+        data, err := it.Next()
+        if err != nil {
+            fmt.Println(err)
+            break
+        }
+        fmt.Println(data)
+    }
+
+    // Output:
+    // [[1 3]]
+    // [[4]]
+    // EOF
+}
+
+// sliceIter iterates over the datasets, and then fails with the error, if any
+type sliceIter struct {
+    Datasets []Dataset
+    Err error
+}
+
+func (it *sliceIter) Next() (Dataset, error) {
+    if len(it.Datasets) == 0 {
+        if it.Err != nil {
+            return nil, it.Err
+        }
+        return nil, io.EOF
+    }
+
+    data := it.Datasets[0]
+    it.Datasets = it.Datasets[1:]
+    return data, nil
+}
+
+func iterAll(it Iterator) ([]string, error) {
+    res := []string{}
+    for {
+        data, err := it.Next()
+        if err == io.EOF {
+            return res, nil
+        } else if err != nil {
+            return res, err
+        }
+        res = append(res, fmt.Sprint(data))
+    }
+}
+
+// runners that don't implement IterRunner are adapted with channels
+func TestIterChanRunner(t *testing.T) {
+    ctx := context.Background()
+    inp := &sliceIter{Datasets: []Dataset{
+        NewDataset(Strs{"b", "a"}),
+        NewDataset(Strs{"c"}),
+    }}
+
+    runner := Pipeline(Sort([]SortKey{{Col: 0}}), Pick(0))
+    require.False(t, iterable(runner))
+
+    res, err := iterAll(Iter(ctx, runner, inp))
+    require.NoError(t, err)
+    require.Equal(t, []string{"[[a b c]]"}, res)
+
+    // errors of the runner
+    res, err = iterAll(Iter(ctx, &ErrRunner{fmt.Errorf("bad runner")}, &sliceIter{}))
+    require.EqualError(t, err, "bad runner")
+    require.Empty(t, res)
+
+    // errors of the input
+    inp = &sliceIter{[]Dataset{NewDataset(Strs{"a"})}, fmt.Errorf("bad input")}
+    _, err = iterAll(Iter(ctx, Sort([]SortKey{{Col: 0}}), inp))
+    require.EqualError(t, err, "bad input")
+}
+
+func TestIterRunners(t *testing.T) {
+    ctx := context.Background()
+    runner := Pipeline(PassThrough(), Filter(Where(0, ">", "a")), Drop(1), Rename("x", "y"))
+    require.True(t, iterable(runner))
+
+    inp := &sliceIter{Datasets: []Dataset{
+        NewDataset(Strs{"a"}, Strs{"1"}),
+        NewDataset(Strs{"b", "c"}, Strs{"2", "3"}),
+    }}
+
+    res, err := iterAll(Iter(ctx, runner, inp))
+    require.NoError(t, err)
+    require.Equal(t, []string{"[[b c]]"}, res) // empty batches are skipped
+
+    // errors are returned by Next
+    inp = &sliceIter{Datasets: []Dataset{NewDataset(Strs{"a"})}}
+    _, err = iterAll(Iter(ctx, Pick(3), inp))
+    require.EqualError(t, err, "column 3 out of range 1")
+
+    // iterable pipelines are also run over channels
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}, Strs{"1", "2"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[b]]", fmt.Sprint(data))
+}
+
+// chanOnly hides the Iter method of the runner
+type chanOnly struct { Runner }
+
+// pulling iterable stages avoids the channel per batch per stage
+func BenchmarkPipelineIter(b *testing.B) {
+    stages := []Runner{Filter(Where(0, "!=", "")), Pick(0), PassThrough(), Pick(0)}
+    for _, iter := range []bool{true, false} {
+        b.Run(fmt.Sprintf("iter=%v", iter), func(b *testing.B) {
+            r := Pipeline(stages...)
+            if !iter {
+                r = Pipeline(chanOnly{stages[0]}, chanOnly{stages[1]}, chanOnly{stages[2]}, chanOnly{stages[3]})
+            }
+
+            inp := make(chan Dataset)
+            go func() {
+                defer close(inp)
+                for i := 0; i < b.N; i++ {
+                    inp <- NewDataset(Strs{"a"})
+                }
+            }()
+
+            out := make(chan Dataset)
+            go func() {
+                defer close(out)
+                r.Run(context.Background(), inp, out)
+            }()
+
+            for _ = range out {}
+        })
+    }
+}
@@ -0,0 +1,66 @@
+package ep
+
+import (
+    "net"
+    "sync"
+    "context"
+)
+
+// Job is a single run of a distributed Runner across all of its nodes (see
+// Distributer). Every run is a separate job, of a unique ID assigned by the
+// master and shared by all of its nodes. The connections of its exchanges, and
+// the values stored in it, are scoped to the job, such that concurrent runs of
+// the same Runner in the same process don't collide.
+type Job struct {
+    ID string
+    d *distributer
+    l sync.Mutex
+    keys []string // the keys of the pending connections of the job
+    values map[interface{}]interface{}
+}
+
+func newJob(id string, d *distributer) *Job {
+    return &Job{ID: id, d: d, values: map[interface{}]interface{}{}}
+}
+
+// JobOf returns the job of the distributed run in the context, or nil when it
+// isn't distributed
+func JobOf(ctx context.Context) *Job {
+    job, _ := ctx.Value("ep.Job").(*Job)
+    return job
+}
+
+// Value returns the value stored in the job for the key on this node, or nil
+func (j *Job) Value(key interface{}) interface{} {
+    j.l.Lock()
+    defer j.l.Unlock()
+    return j.values[key]
+}
+
+// SetValue stores a value in the job for the key on this node. It's used for
+// state that's shared by the runners of the job, but not by concurrent jobs
+func (j *Job) SetValue(key, value interface{}) {
+    j.l.Lock()
+    defer j.l.Unlock()
+    j.values[key] = value
+}
+
+// Connect to a node address for the given uid, like the Distributer, except
+// that the uid is only matched by the same job on the other node
+func (j *Job) Connect(addr, uid string) (net.Conn, error) {
+    uid = j.ID + "/" + uid
+
+    j.l.Lock()
+    j.keys = append(j.keys, addr + ":" + uid)
+    j.l.Unlock()
+    return j.d.Connect(addr, uid)
+}
+
+// close releases the pending connections of the job, once it's done on this
+// node
+func (j *Job) close() {
+    j.l.Lock()
+    defer j.l.Unlock()
+    j.d.release(j.keys...)
+    j.keys = nil
+}
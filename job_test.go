@@ -0,0 +1,91 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&jobValue{})
+
+// jobValue emits the value stored in its job by the runner before it, which
+// stores the ID of the job
+type jobValue struct { Store bool }
+
+func (*jobValue) Returns() []Type { return []Type{Str} }
+func (r *jobValue) Run(ctx context.Context, inp, out chan Dataset) error {
+    job := JobOf(ctx)
+    if r.Store {
+        job.SetValue("id", job.ID)
+        for data := range inp {
+            out <- data
+        }
+        return nil
+    }
+
+    for _ = range inp {}
+    out <- NewDataset(Strs{job.Value("id").(string)})
+    return nil
+}
+
+// Tests that concurrent runs of the same distributed runner don't collide on
+// their exchanges
+func TestJobConcurrent(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    var wg sync.WaitGroup
+    errs := make([]error, 10)
+    res := make([][]string, 10)
+    for i := range errs {
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            a, b := fmt.Sprint(2 * i), fmt.Sprint(2 * i + 1)
+            data, err := testRun(runner, NewDataset(Strs{a}), NewDataset(Strs{b}))
+            errs[i] = err
+            if err == nil {
+                res[i] = sortedStrings(data.At(0).Strings())
+            }
+        }(i)
+    }
+    wg.Wait()
+
+    for i := range errs {
+        require.NoError(t, errs[i])
+        require.Equal(t, []string{fmt.Sprint(2 * i), fmt.Sprint(2 * i + 1)}, res[i])
+    }
+
+    // the pending connections are released once the jobs are done
+    for _, d := range []Distributer{dists[0], dists[1]} {
+        d := d.(*distributer)
+        d.l.Lock()
+        require.Empty(t, d.connsMap)
+        d.l.Unlock()
+    }
+}
+
+func TestJobOf(t *testing.T) {
+    require.Nil(t, JobOf(context.Background()))
+
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(&jobValue{true}, &jobValue{false}, Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    // all nodes share the ID of the job, which differs between runs
+    data1, err := testRun(runner)
+    require.NoError(t, err)
+    ids := data1.At(0).Strings()
+    require.Equal(t, 2, len(ids))
+    require.Equal(t, ids[0], ids[1])
+    require.NotEmpty(t, ids[0])
+
+    data2, err := testRun(runner)
+    require.NoError(t, err)
+    require.NotEqual(t, ids[0], data2.At(0).Strings()[0])
+}
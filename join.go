@@ -0,0 +1,485 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&join{})
+
+// JoinType determines which rows are produced by a Join
+type JoinType int
+
+const (
+    // InnerJoin produces only the matching rows of both sides
+    InnerJoin JoinType = iota
+
+    // LeftJoin also produces the unmatched left rows, with null right values
+    LeftJoin
+
+    // RightJoin also produces the unmatched right rows, with null left values
+    RightJoin
+
+    // FullJoin produces the unmatched rows of both sides
+    FullJoin
+
+    // SemiJoin produces only the left rows that have at least one matching
+    // right row, each exactly once and without the right columns (EXISTS)
+    SemiJoin
+
+    // AntiJoin produces only the left rows that have no matching right rows,
+    // without the right columns (NOT EXISTS)
+    AntiJoin
+)
+
+// leftOnly returns true for the join types that produce only the left columns
+func (typ JoinType) leftOnly() bool {
+    return typ == SemiJoin || typ == AntiJoin
+}
+
+// Join returns a composite Runner that dispatches its input to both the left
+// and right runners, and joins their outputs by matching the values of the
+// left key columns with the values of the right key columns. The output
+// contains the left columns followed by the right columns, except for semi and
+// anti joins that contain only the left columns. Rows with null keys never
+// match.
+//
+// It's an in-memory hash join: the entire output of the right runner is
+// buffered into a hash table, and the left output is streamed through it.
+// Thus, the smaller side should be on the right, unless both sides are already
+// sorted by their keys (see MergeJoin). When the hash table exceeds the memory
+// budget (see WithMemory), both sides are spilled to disk by partitions of
+// their keys, which are then joined one at a time. In order to distribute it,
+// repartition both sides by their keys (see Repartition), or broadcast the
+// smaller right side to all nodes (see BroadcastJoin and DistributedJoin).
+//
+// NOTE: Unmatched rows are produced in separate batches, where the columns of
+// the other side are all of the Null type.
+func Join(typ JoinType, leftKeys, rightKeys []int, left, right Runner) Runner {
+    return &join{typ, leftKeys, rightKeys, left, right}
+}
+
+// CrossJoin returns a Join that produces the cartesian product of the left and
+// right outputs: every left row is joined with every right row. Like Join, the
+// right side is buffered and the left side is streamed, thus the smaller side
+// should be on the right. It's useful when there are no keys to join by.
+func CrossJoin(left, right Runner) Runner {
+    return Join(InnerJoin, nil, nil, left, right) // all rows have the same key
+}
+
+type join struct {
+    Type JoinType
+    LeftKeys []int
+    RightKeys []int
+    Left Runner
+    Right Runner
+}
+
+// Returns a concatenation of the left and right return types, or only the
+// left types for semi and anti joins
+func (r *join) Returns() []Type {
+    types := []Type{}
+    types = append(types, r.Left.Returns()...)
+    if !r.Type.leftOnly() {
+        types = append(types, r.Right.Returns()...)
+    }
+    return types
+}
+
+func (r *join) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    ctx, cancel := context.WithCancel(ctx)
+    left, right, g := runBoth(ctx, r.Left, r.Right, inp)
+    defer func() {
+        // upon error, cancel both sides and drain their outputs
+        if err != nil {
+            cancel()
+            for _ = range left {}
+            for right != nil {
+                if _, ok := <- right; !ok {
+                    right = nil
+                }
+            }
+        }
+
+        if err1 := g.Wait(); err == nil {
+            err = err1
+        }
+        cancel()
+    }()
+
+    mem := memoryOf(ctx)
+    reserved := 0
+    defer func() { mem.Release(reserved) }()
+
+    // build the hash table from the right side. Meanwhile, buffer the left
+    // side in order to not block it. When out of memory, both sides are
+    // spilled instead.
+    table := &hashTable{Keys: r.RightKeys}
+    pending := []Dataset{}
+    var leftParts, rightParts spillPartitions
+    defer func() {
+        leftParts.Close()
+        rightParts.Close()
+    }()
+
+    // the widths of the rows of both sides, for padding the unmatched rows of
+    // the other side with nulls. See sideWidth
+    leftWidth, rightWidth := -1, -1
+
+    leftOpen := left
+    for right != nil {
+        var data Dataset
+        var ok bool
+        select {
+        case data, ok = <- right:
+            if !ok {
+                right = nil
+                continue
+            }
+
+            rightWidth = data.Width()
+            if rightParts != nil {
+                err = rightParts.Write(data, r.RightKeys)
+            } else {
+                table.Add(data)
+            }
+        case data, ok = <- leftOpen:
+            if !ok {
+                leftOpen = nil // closed, block it on the next iteration.
+                continue
+            }
+
+            leftWidth = data.Width()
+            if leftParts != nil {
+                err = leftParts.Write(data, r.LeftKeys)
+            } else {
+                pending = append(pending, data)
+            }
+        }
+
+        if err != nil {
+            return err
+        } else if rightParts != nil {
+            continue
+        }
+
+        size := estimateBytes(data)
+        if mem.Reserve(size) {
+            reserved += size
+            continue
+        }
+
+        // out of memory, spill the buffered rows of both sides
+        leftParts, rightParts, err = r.spill(table, pending)
+        if err != nil {
+            return err
+        }
+
+        table, pending = nil, nil
+        mem.Release(reserved)
+        reserved = 0
+    }
+
+    if rightParts != nil {
+        for data := range left {
+            leftWidth = data.Width()
+            err = leftParts.Write(data, r.LeftKeys)
+            if err != nil {
+                return err
+            }
+        }
+
+        widths := [2]int{r.sideWidth(leftWidth, r.Left), r.sideWidth(rightWidth, r.Right)}
+        return r.joinSpilled(leftParts, rightParts, widths, out)
+    }
+
+    // probe the hash table with the left side
+    rightWidth = r.sideWidth(rightWidth, r.Right)
+    for _, data := range pending {
+        r.probe(table, data, rightWidth, out)
+    }
+
+    for data := range left {
+        leftWidth = data.Width()
+        r.probe(table, data, rightWidth, out)
+    }
+
+    r.unmatched(table, r.sideWidth(leftWidth, r.Left), out)
+    return nil
+}
+
+// sideWidth returns the width of the rows of a side of the join, as seen in
+// its output, or the number of its return types when it had no output
+func (r *join) sideWidth(seen int, side Runner) int {
+    if seen >= 0 {
+        return seen
+    }
+    return len(side.Returns())
+}
+
+// unmatched emits the unmatched right rows of the hash table, padded with
+// leftWidth null columns, for the join types that produce them
+func (r *join) unmatched(table *hashTable, leftWidth int, out chan Dataset) {
+    if r.Type == RightJoin || r.Type == FullJoin {
+        rows := table.Unmatched()
+        if len(rows) > 0 {
+            res := pick(table.Data, rows).(Dataset)
+            out <- joinRows(nullRows(len(rows), leftWidth), res)
+        }
+    }
+}
+
+// spill the rows of the hash table and the pending left rows into partitions
+// of their keys
+func (r *join) spill(table *hashTable, pending []Dataset) (spillPartitions, spillPartitions, error) {
+    leftParts, err := newSpillPartitions()
+    if err != nil {
+        return nil, nil, err
+    }
+
+    rightParts, err := newSpillPartitions()
+    if err != nil {
+        leftParts.Close()
+        return nil, nil, err
+    }
+
+    if table.Data != nil {
+        err = rightParts.Write(table.Data, r.RightKeys)
+    }
+
+    for i := 0; i < len(pending) && err == nil; i++ {
+        err = leftParts.Write(pending[i], r.LeftKeys)
+    }
+    return leftParts, rightParts, err
+}
+
+// joinSpilled joins every pair of the spilled partitions separately, in
+// memory, as rows with equal keys are in partitions of the same index. The
+// widths are of the left and right rows, see sideWidth
+func (r *join) joinSpilled(leftParts, rightParts spillPartitions, widths [2]int, out chan Dataset) error {
+    for p := range rightParts {
+        table := &hashTable{Keys: r.RightKeys}
+        err := readSpilled(rightParts[p], func(data Dataset) {
+            table.Add(data)
+        })
+
+        if err == nil {
+            err = readSpilled(leftParts[p], func(data Dataset) {
+                r.probe(table, data, widths[1], out)
+            })
+        }
+
+        if err != nil {
+            return err
+        }
+        r.unmatched(table, widths[0], out)
+    }
+    return nil
+}
+
+// probe the hash table with the left dataset, and emit the joined rows, where
+// the unmatched left rows are padded with rightWidth null columns
+func (r *join) probe(table *hashTable, data Dataset, rightWidth int, out chan Dataset) {
+    var li, ri, hits, unmatched []int
+    nulls := nullKeyRows(data, r.LeftKeys)
+    for i, h := range hashRows(data, r.LeftKeys) {
+        var matches []int
+        if !nulls[i] {
+            matches = table.Match(data, r.LeftKeys, i, h)
+        }
+
+        for _, j := range matches {
+            li = append(li, i)
+            ri = append(ri, j)
+        }
+
+        if len(matches) == 0 {
+            unmatched = append(unmatched, i)
+        } else {
+            hits = append(hits, i)
+        }
+    }
+
+    if r.Type == SemiJoin {
+        if len(hits) > 0 {
+            out <- pick(data, hits).(Dataset)
+        }
+        return
+    } else if r.Type == AntiJoin {
+        if len(unmatched) > 0 {
+            out <- pick(data, unmatched).(Dataset)
+        }
+        return
+    }
+
+    if len(li) > 0 {
+        res := pick(data, li).(Dataset)
+        out <- joinRows(res, pick(table.Data, ri).(Dataset))
+    }
+
+    if len(unmatched) > 0 && (r.Type == LeftJoin || r.Type == FullJoin) {
+        res := pick(data, unmatched).(Dataset)
+        out <- joinRows(res, nullRows(len(unmatched), rightWidth))
+    }
+}
+
+// joinRows concatenates the columns of the left and right datasets
+func joinRows(left, right Dataset) Dataset {
+    cols := []Data{}
+    names := []string{}
+    for _, set := range []Dataset{left, right} {
+        for i := 0; i < set.Width(); i++ {
+            cols = append(cols, set.At(i))
+            names = append(names, set.ColumnName(i))
+        }
+    }
+    return newNamedDataset(names, cols...)
+}
+
+// nullRows returns a dataset of `width` columns, each containing `n` nulls
+func nullRows(n, width int) Dataset {
+    cols := make([]Data, width)
+    for i := range cols {
+        cols[i] = Null.Data(uint(n))
+    }
+    return NewDataset(cols...)
+}
+
+// hashTable of rows by the hashes of their key columns
+type hashTable struct {
+    Keys []int
+    Data Dataset // all of the rows
+    rows map[uint64][]int // row indices by hash
+    matched []bool
+}
+
+func (t *hashTable) Add(data Dataset) {
+    if data.Len() == 0 {
+        return
+    }
+
+    if t.rows == nil {
+        t.rows = map[uint64][]int{}
+    }
+
+    offset := 0
+    if t.Data != nil {
+        offset = t.Data.Len()
+    }
+
+    nulls := nullKeyRows(data, t.Keys)
+    for i, h := range hashRows(data, t.Keys) {
+        if !nulls[i] {
+            t.rows[h] = append(t.rows[h], offset + i)
+        }
+    }
+
+    t.Data = appendClone(t.Data, data)
+    t.matched = append(t.matched, make([]bool, data.Len())...)
+}
+
+// Match returns the indices of the rows matching the key columns of row i in
+// the data, given its hash, and marks them
+func (t *hashTable) Match(data Dataset, cols []int, i int, h uint64) []int {
+    var rows []int
+    for _, j := range t.rows[h] {
+        if equalRows(data, i, cols, t.Data, j, t.Keys) {
+            rows = append(rows, j)
+            t.matched[j] = true
+        }
+    }
+    return rows
+}
+
+// Unmatched returns the indices of the rows that weren't matched
+func (t *hashTable) Unmatched() []int {
+    rows := []int{}
+    for i, ok := range t.matched {
+        if !ok {
+            rows = append(rows, i)
+        }
+    }
+    return rows
+}
+
+// nullKeyRows returns a mask of the rows that have a null value in any of the
+// key columns. Null keys never match other keys.
+func nullKeyRows(data Dataset, cols []int) Bools {
+    res := make(Bools, data.Len())
+    if hasNullKeys(data, cols) {
+        for i := range res {
+            res[i] = true
+        }
+        return res
+    }
+
+    if nulls := nullKeys(data, cols); nulls != nil {
+        copy(res, nulls)
+    }
+    return res
+}
+
+// hasNullKeys returns true if any of the key columns is of the Null type
+func hasNullKeys(data Dataset, keys []int) bool {
+    for _, col := range keys {
+        if data.At(col).Type() == Null {
+            return true
+        }
+    }
+    return false
+}
+
+// nullKeys returns a mask of the rows that have a null value in any of the key
+// columns, or nil if none of the key columns is Nullable
+func nullKeys(data Dataset, keys []int) Bools {
+    var mask Bools
+    for _, col := range keys {
+        if vs, ok := data.At(col).(nullable); ok {
+            if mask == nil {
+                mask = make(Bools, data.Len())
+            }
+
+            for i, valid := range vs.Valid {
+                mask[i] = mask[i] || !valid
+            }
+        }
+    }
+    return mask
+}
+
+// runBoth runs the left and right runners concurrently in a Group, dispatching
+// (copying) the input to both of them. Upon error, both runners are canceled.
+// The caller must consume both outputs until they're closed, and then Wait for
+// the returned Group.
+func runBoth(ctx context.Context, left, right Runner, inp chan Dataset) (chan Dataset, chan Dataset, *Group) {
+    g, ctx := NewGroup(ctx)
+    runners := []Runner{left, right}
+    inputs := []chan Dataset{newChan(ctx), newChan(ctx)}
+    outputs := []chan Dataset{newChan(ctx), newChan(ctx)}
+    done := []chan struct{}{make(chan struct{}), make(chan struct{})}
+    for i := range runners {
+        i := i
+        g.Go(func() error {
+            defer close(done[i])
+            defer close(outputs[i])
+            return runners[i].Run(ctx, inputs[i], outputs[i])
+        })
+    }
+
+    g.Go(func() error {
+        defer close(inputs[0])
+        defer close(inputs[1])
+        for data := range inp {
+            for i := range inputs {
+                select {
+                case inputs[i] <- data:
+                case <- done[i]: // the runner has exited early
+                case <- ctx.Done():
+                    return nil
+                }
+            }
+        }
+        return nil
+    })
+
+    return outputs[0], outputs[1], g
+}
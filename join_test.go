@@ -0,0 +1,145 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&constRunner{})
+
+// constRunner is a source Runner that ignores its input, and emits a constant
+// dataset
+type constRunner struct { Data Dataset }
+func (r *constRunner) Returns() []Type {
+    types := []Type{}
+    for i := 0; i < r.Data.Width(); i++ {
+        types = append(types, r.Data.At(i).Type())
+    }
+    return types
+}
+
+func (r *constRunner) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    out <- r.Data
+    return nil
+}
+
+var users = &constRunner{NewDataset(Strs{"1", "2", "3"}, Strs{"alice", "bob", "carol"})}
+var orders = &constRunner{NewDataset(Strs{"1", "1", "3", "4"}, Strs{"book", "pen", "cup", "hat"})}
+
+func ExampleJoin() {
+    runner := Join(InnerJoin, []int{0}, []int{0}, users, orders)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    fmt.Println(data, err)
+
+    // Output: [[1 1 3] [alice alice carol] [1 1 3] [book pen cup]] <nil>
+}
+
+func ExampleCrossJoin() {
+    colors := &constRunner{NewDataset(Strs{"red", "blue"})}
+    sizes := &constRunner{NewDataset(Strs{"S", "M", "L"})}
+    runner := CrossJoin(colors, sizes)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    fmt.Println(data, err)
+
+    // Output: [[red red red blue blue blue] [S M L S M L]] <nil>
+}
+
+func TestJoinOuter(t *testing.T) {
+    tests := map[JoinType][]string{
+        LeftJoin: {"1 alice 1 book", "1 alice 1 pen", "3 carol 3 cup", "2 bob  "},
+        RightJoin: {"1 alice 1 book", "1 alice 1 pen", "3 carol 3 cup", "  4 hat"},
+        FullJoin: {"1 alice 1 book", "1 alice 1 pen", "3 carol 3 cup", "2 bob  ", "  4 hat"},
+    }
+
+    for typ, expected := range tests {
+        // unmatched rows are in separate batches, with null columns, thus
+        // they can't be appended together
+        runner := Join(typ, []int{0}, []int{0}, users, orders)
+        inp := make(chan Dataset, 1)
+        inp <- NewDataset(Null.Data(1))
+        close(inp)
+
+        out := make(chan Dataset, 10)
+        err := runner.Run(context.Background(), inp, out)
+        close(out)
+        require.NoError(t, err)
+
+        rows := []string{}
+        for data := range out {
+            rows = append(rows, rowStrings(data)...)
+        }
+        require.Equal(t, expected, rows, "join type %d", typ)
+    }
+}
+
+// Tests that the unmatched rows are padded to the width of the other side,
+// when it returns a Wildcard
+func TestJoinOuterWildcard(t *testing.T) {
+    tests := map[JoinType][]Runner{
+        LeftJoin: {PassThrough(), Filter(Where(0, "!=", "2"))},
+        RightJoin: {Filter(Where(0, "!=", "2")), PassThrough()},
+    }
+
+    for typ, sides := range tests {
+        runner := Join(typ, []int{0}, []int{0}, sides[0], sides[1])
+        inp := make(chan Dataset, 1)
+        inp <- NewDataset(Strs{"1", "2"}, Strs{"alice", "bob"})
+        close(inp)
+
+        out := make(chan Dataset, 10)
+        err := runner.Run(context.Background(), inp, out)
+        close(out)
+        require.NoError(t, err)
+
+        rows := []string{}
+        for data := range out {
+            require.Equal(t, 4, data.Width(), "join type %d", typ)
+            rows = append(rows, rowStrings(data)...)
+        }
+        require.Equal(t, 2, len(rows), "join type %d", typ)
+    }
+}
+
+func TestJoinSemiAnti(t *testing.T) {
+    tests := map[JoinType][]string{
+        SemiJoin: {"1 alice", "3 carol"},
+        AntiJoin: {"2 bob"},
+    }
+
+    for typ, expected := range tests {
+        runner := Join(typ, []int{0}, []int{0}, users, orders)
+        require.Equal(t, []Type{Str, Str}, runner.Returns())
+
+        data, err := testRun(runner, NewDataset(Null.Data(1)))
+        require.NoError(t, err)
+        require.Equal(t, expected, rowStrings(data), "join type %d", typ)
+    }
+}
+
+func TestJoinErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    infinity := &InfinityRunner{}
+    runner := Join(InnerJoin, []int{0}, []int{0}, infinity, &ErrRunner{err})
+    _, err = testRun(runner, NewDataset(Null.Data(1)))
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
+
+// rowStrings returns the rows of the dataset as space-separated strings
+func rowStrings(data Dataset) []string {
+    rows := make([]string, data.Len())
+    for j := 0; j < data.Width(); j++ {
+        for i, s := range data.At(j).Strings() {
+            if j > 0 {
+                rows[i] += " "
+            }
+            rows[i] += s
+        }
+    }
+    return rows
+}
@@ -0,0 +1,143 @@
+package ep
+
+import (
+    "fmt"
+    "bytes"
+    "strconv"
+    "strings"
+    "encoding/json"
+)
+
+var _ = registerGob(JSON, JSONs{})
+
+// JSON is a built-in Type representing arbitrary (nested) JSON values, for
+// semi-structured data. Use JSON.Data(n) to create Data instances of `n` JSON
+// nulls
+var JSON = &JSONType{}
+
+// JSONType is the Type of JSONs
+type JSONType struct {}
+func (*JSONType) Name() string { return "json" }
+func (*JSONType) Data(n uint) Data {
+    res := make(JSONs, n)
+    for i := range res {
+        res[i] = "null"
+    }
+    return res
+}
+
+// JSONs is a built-in Data implementation of JSON values, stored as compact
+// JSON encoded strings. Values are ordered by their encoding. Use GetPath to
+// extract nested values.
+type JSONs []string
+
+// NewJSONs returns the JSONs of the encoded Go values (see json.Marshal)
+func NewJSONs(values ...interface{}) (JSONs, error) {
+    res := make(JSONs, len(values))
+    for i, v := range values {
+        b, err := json.Marshal(v)
+        if err != nil {
+            return nil, err
+        }
+        res[i] = string(b)
+    }
+    return res, nil
+}
+
+// ParseJSONs validates and compacts the JSON encoded strings
+func ParseJSONs(strs ...string) (JSONs, error) {
+    res := make(JSONs, len(strs))
+    for i, s := range strs {
+        var buf bytes.Buffer
+        err := json.Compact(&buf, []byte(s))
+        if err != nil {
+            return nil, fmt.Errorf("invalid json %q: %s", s, err)
+        }
+        res[i] = buf.String()
+    }
+    return res, nil
+}
+
+func (JSONs) Type() Type { return JSON }
+func (vs JSONs) Len() int { return len(vs) }
+func (vs JSONs) Less(i, j int) bool { return vs[i] < vs[j] }
+func (vs JSONs) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs JSONs) Slice(s, e int) Data { return vs[s:e] }
+func (vs JSONs) Strings() []string { return vs }
+func (vs JSONs) Append(o Data) Data { return append(vs, o.(JSONs)...) }
+
+// Text returns the text of the values: strings are unquoted, nulls are empty,
+// and other values are kept encoded
+func (vs JSONs) Text() Strs {
+    res := make(Strs, len(vs))
+    for i, v := range vs {
+        if v == "null" {
+            continue
+        } else if strings.HasPrefix(v, `"`) {
+            json.Unmarshal([]byte(v), &res[i])
+        } else {
+            res[i] = v
+        }
+    }
+    return res
+}
+
+// GetPath extracts the nested values at the path from every JSON value. The
+// path is a dot-separated list of object keys and array indices, like
+// "items.0.name". Rows where the path doesn't exist are null (see Nullable),
+// while explicit JSON nulls are kept as is.
+func GetPath(data JSONs, path string) (Data, error) {
+    var keys []string
+    if path != "" {
+        keys = strings.Split(path, ".")
+    }
+
+    res := make(JSONs, len(data))
+    valid := make(Bools, len(data))
+    missing := false
+    for i, s := range data {
+        var v interface{}
+        err := json.Unmarshal([]byte(s), &v)
+        if err != nil {
+            return nil, err
+        }
+
+        v, valid[i] = getPath(v, keys)
+        if !valid[i] {
+            res[i], missing = "null", true
+            continue
+        }
+
+        b, err := json.Marshal(v)
+        if err != nil {
+            return nil, err
+        }
+        res[i] = string(b)
+    }
+
+    if missing {
+        return Nullable(res, valid), nil
+    }
+    return res, nil
+}
+
+func getPath(v interface{}, keys []string) (interface{}, bool) {
+    for _, k := range keys {
+        switch node := v.(type) {
+        case map[string]interface{}:
+            var ok bool
+            if v, ok = node[k]; !ok {
+                return nil, false
+            }
+        case []interface{}:
+            i, err := strconv.Atoi(k)
+            if err != nil || i < 0 || i >= len(node) {
+                return nil, false
+            }
+            v = node[i]
+        default:
+            return nil, false
+        }
+    }
+    return v, true
+}
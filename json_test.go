@@ -0,0 +1,37 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleGetPath() {
+    events, _ := ParseJSONs(
+        `{"user": {"name": "bob"}, "items": [1, 2]}`,
+        `{"user": {"name": null}, "items": []}`,
+        `{"user": "alice"}`,
+    )
+
+    names, _ := GetPath(events, "user.name")
+    items, _ := GetPath(events, "items.1")
+    fmt.Println(names, items)
+
+    // Output: ["bob" null <nil>] [2 <nil> <nil>]
+}
+
+func TestJSONs(t *testing.T) {
+    data, err := NewJSONs("a", 1.5, map[string]interface{}{"k": []int{1}}, nil)
+    require.NoError(t, err)
+    require.Equal(t, JSONs{`"a"`, `1.5`, `{"k":[1]}`, `null`}, data)
+    require.Equal(t, Strs{"a", "1.5", `{"k":[1]}`, ""}, data.Text())
+
+    res, err := GetPath(data, "")
+    require.NoError(t, err)
+    require.Equal(t, data, res)
+
+    _, err = ParseJSONs(`{"a":`)
+    require.Error(t, err)
+
+    require.Equal(t, JSONs{"null", "null"}, JSON.Data(2))
+}
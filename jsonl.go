@@ -0,0 +1,450 @@
+package ep
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "sort"
+    "bytes"
+    "bufio"
+    "context"
+    "strings"
+    "strconv"
+    "encoding/json"
+)
+
+var _ = registerGob(&jsonScan{}, &jsonWrite{})
+
+// JSONOptions configure the reading of JSON Lines files. See JSONScan
+type JSONOptions struct {
+
+    // SplitSize is the number of bytes per file split, for reading large files
+    // in parallel across the cluster. Zero means that every file is a single
+    // split. See CSVOptions
+    SplitSize int64
+}
+
+// JSONScan returns a source Runner that reads the newline-delimited JSON
+// objects of the files matching the glob pattern (see storage.Glob), in order,
+// and emits their values in batches of up to BatchSize rows, with a column per
+// field of the schema. Missing fields and JSON nulls are nulls. Nested objects
+// are read into Struct columns and arrays into List columns, or kept as-is in
+// JSON columns. Other values are converted into the type of their column with
+// CastData. Use InferJSONSchema for schemas that aren't known in advance. Its
+// input is ignored.
+//
+// When distributed, the files are divided into splits that are read exactly
+// once across the cluster, like CSVScan.
+func JSONScan(pattern string, schema Schema, opts JSONOptions) Runner {
+    return &jsonScan{pattern, schema, opts}
+}
+
+// InferJSONSchema returns the schema of the JSON objects within the first rows
+// lines of the files matching the glob pattern, or all of the lines if rows is
+// zero. Fields are ordered by their names, and their types are:
+//
+//      string          for strings
+//      int             for integers, or float if any of them isn't an integer
+//      bool            for booleans
+//      struct          for objects, with the fields of all of the objects
+//      list            for arrays, of the type of all of the elements
+//      json            for values of mixed types, empty objects, and fields
+//                      that are always null
+func InferJSONSchema(pattern string, rows int) (Schema, error) {
+    ctx := context.Background()
+    splits, err := globSplits(ctx, pattern, 0)
+    if err != nil {
+        return nil, err
+    }
+
+    var t Type = Null
+    for _, s := range splits {
+        err = readJSONLines(ctx, s, func(v map[string]interface{}) bool {
+            t = mergeJSONTypes(t, inferJSONType(v))
+            rows--
+            return rows != 0
+        })
+
+        if err != nil {
+            return nil, err
+        } else if rows == 0 {
+            break
+        }
+    }
+
+    if t, ok := t.(*StructType); ok {
+        return resolveJSONType(t).(*StructType).Fields, nil
+    }
+    return Schema{}, nil
+}
+
+type jsonScan struct {
+    Pattern string
+    Schema Schema
+    Options JSONOptions
+}
+
+func (r *jsonScan) Returns() []Type {
+    types := []Type{}
+    for _, f := range r.Schema {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (r *jsonScan) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    splits, err := globSplits(ctx, r.Pattern, r.Options.SplitSize)
+    if err != nil {
+        return err
+    }
+
+    for _, s := range localSplits(ctx, splits) {
+        var emitErr error
+        rows := []map[string]interface{}{}
+        err = readJSONLines(ctx, s, func(v map[string]interface{}) bool {
+            rows = append(rows, v)
+            if len(rows) >= BatchSize {
+                emitErr = r.emit(ctx, rows, out)
+                rows = rows[:0]
+            }
+            return emitErr == nil
+        })
+
+        if err == nil && emitErr == nil && len(rows) > 0 {
+            emitErr = r.emit(ctx, rows, out)
+        }
+
+        if err == nil {
+            err = emitErr
+        }
+
+        if err != nil {
+            return fmt.Errorf("%s: %s", s.Path, err)
+        }
+    }
+    return nil
+}
+
+// emit a batch of the rows, converted to the types of the schema
+func (r *jsonScan) emit(ctx context.Context, rows []map[string]interface{}, out chan Dataset) error {
+    cols := make([]Data, len(r.Schema))
+    values := make([]interface{}, len(rows))
+    for i, f := range r.Schema {
+        for j, row := range rows {
+            values[j] = row[f.Name]
+        }
+
+        var err error
+        cols[i], err = jsonData(f.Type, values)
+        if err != nil {
+            return err
+        }
+    }
+
+    select {
+    case out <- newNamedDataset(r.Schema.Names(), cols...):
+        return nil
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// readJSONLines decodes the JSON objects of the lines of the split (see
+// openSplit), until the function returns false. Blank lines are skipped.
+func readJSONLines(ctx context.Context, s fileSplit, fn func(map[string]interface{}) bool) error {
+    f, br, start, err := openSplit(ctx, s)
+    if err != nil {
+        return err
+    }
+    defer f.Close()
+
+    for start < s.End {
+        line, err := br.ReadBytes('\n')
+        if err != nil && err != io.EOF {
+            return err
+        } else if len(line) == 0 {
+            return nil
+        }
+
+        offset := start
+        start += int64(len(line))
+        if len(bytes.TrimSpace(line)) == 0 {
+            continue
+        }
+
+        var v map[string]interface{}
+        dec := json.NewDecoder(bytes.NewReader(line))
+        dec.UseNumber()
+        if err := dec.Decode(&v); err != nil || v == nil {
+            return fmt.Errorf("invalid json object at offset %d: %q", offset, bytes.TrimSpace(line))
+        } else if !fn(v) {
+            return nil
+        }
+    }
+    return nil
+}
+
+// jsonData converts the decoded JSON values into Data of the type. nil values
+// are nulls, except for JSON types where they're JSON nulls.
+func jsonData(t Type, values []interface{}) (Data, error) {
+    t = unnamed(t)
+    if n, ok := t.(*NullableType); ok {
+        t = n.Of
+    }
+
+    valid := make(Bools, len(values))
+    for i, v := range values {
+        valid[i] = v != nil
+    }
+
+    var res Data
+    switch t := t.(type) {
+    case *JSONType:
+        res := make(JSONs, len(values))
+        for i, v := range values {
+            b, err := json.Marshal(v)
+            if err != nil {
+                return nil, err
+            }
+            res[i] = string(b)
+        }
+        return res, nil
+    case *StructType:
+        if len(t.Fields) == 0 {
+            return nil, fmt.Errorf("struct of no fields")
+        }
+
+        fields := make([]Data, len(t.Fields))
+        sub := make([]interface{}, len(values))
+        for k, f := range t.Fields {
+            for i, v := range values {
+                obj, ok := v.(map[string]interface{})
+                if v != nil && !ok {
+                    return nil, fmt.Errorf("unable to convert %T into %s", v, t.Name())
+                }
+                sub[i] = obj[f.Name]
+            }
+
+            var err error
+            fields[k], err = jsonData(f.Type, sub)
+            if err != nil {
+                return nil, err
+            }
+        }
+        res = Structs{t.Fields.Names(), fields}
+    case *ListType:
+        lists := make([]Data, len(values))
+        for i, v := range values {
+            arr, ok := v.([]interface{})
+            if v != nil && !ok {
+                return nil, fmt.Errorf("unable to convert %T into %s", v, t.Name())
+            }
+
+            var err error
+            lists[i], err = jsonData(t.Of, arr)
+            if err != nil {
+                return nil, err
+            }
+        }
+        res = Lists{t.Of, lists}
+    default:
+        for _, v := range values {
+            switch v.(type) {
+            case map[string]interface{}, []interface{}:
+                return nil, fmt.Errorf("unable to convert %T into %s", v, t.Name())
+            }
+        }
+        return castValues(values, t)
+    }
+
+    if allTrue(valid) {
+        return res, nil
+    }
+    return Nullable(res, valid), nil
+}
+
+// inferJSONType returns the type of the decoded JSON value. See InferJSONSchema
+func inferJSONType(v interface{}) Type {
+    switch v := v.(type) {
+    case string:
+        return Str
+    case bool:
+        return Bool
+    case json.Number:
+        if strings.ContainsAny(v.String(), ".eE") {
+            return Float
+        }
+        return Int
+    case map[string]interface{}:
+        fields := Schema{}
+        for k, e := range v {
+            fields = append(fields, Field{k, inferJSONType(e)})
+        }
+        sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+        return Struct(fields...)
+    case []interface{}:
+        var of Type = Null
+        for _, e := range v {
+            of = mergeJSONTypes(of, inferJSONType(e))
+        }
+        return List(of)
+    }
+    return Null
+}
+
+// mergeJSONTypes returns the type of values of both types, where nulls are
+// of any type, integers are floats, and types that can't be merged are JSON.
+// Unknown types (of nulls and empty objects) are resolved by resolveJSONType
+// once all of the values are merged.
+func mergeJSONTypes(a, b Type) Type {
+    if a == Null {
+        return b
+    } else if b == Null || Equal(a, b) {
+        return a
+    } else if a == Float && b == Int || a == Int && b == Float {
+        return Float
+    }
+
+    switch a := a.(type) {
+    case *StructType:
+        b, ok := b.(*StructType)
+        if !ok {
+            return JSON
+        }
+
+        fields := append(Schema{}, a.Fields...)
+        for _, f := range b.Fields {
+            if i := fields.Index(f.Name); i >= 0 {
+                fields[i].Type = mergeJSONTypes(fields[i].Type, f.Type)
+            } else {
+                fields = append(fields, f)
+            }
+        }
+        sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+        return Struct(fields...)
+    case *ListType:
+        if b, ok := b.(*ListType); ok {
+            return List(mergeJSONTypes(a.Of, b.Of))
+        }
+    }
+    return JSON
+}
+
+// resolveJSONType replaces the nested unknown types, of values that are always
+// null and objects that are always empty, with JSON
+func resolveJSONType(t Type) Type {
+    switch t := t.(type) {
+    case *StructType:
+        if len(t.Fields) == 0 {
+            return JSON
+        }
+
+        fields := make(Schema, len(t.Fields))
+        for i, f := range t.Fields {
+            fields[i] = Field{f.Name, resolveJSONType(f.Type)}
+        }
+        return Struct(fields...)
+    case *ListType:
+        return List(resolveJSONType(t.Of))
+    }
+
+    if t == Null {
+        return JSON
+    }
+    return t
+}
+
+// JSONWrite returns a Runner that writes its input datasets as newline-
+// delimited JSON objects into the file at path (truncating it if it exists),
+// and emits a single summary row of the number of rows and bytes written. The
+// keys of the objects are the names of the columns (see Dataset.Schema), or
+// their indices for unnamed columns, and the values are converted with Value:
+// nulls are JSON nulls, Structs are objects, Lists are arrays, and JSONs are
+// embedded as-is. When distributed, every node writes its own input into the
+// path on its own file system.
+func JSONWrite(path string) Runner {
+    return &jsonWrite{Path: path}
+}
+
+// JSONWriteTo is like JSONWrite, except that it writes into the provided
+// writer. Like CSVWriteTo, it cannot be distributed.
+func JSONWriteTo(w io.Writer) Runner {
+    return &jsonWrite{w: w}
+}
+
+type jsonWrite struct {
+    Path string
+    w io.Writer
+}
+
+func (*jsonWrite) Returns() []Type {
+    return []Type{As(Int, "rows"), As(Int, "bytes")}
+}
+
+func (r *jsonWrite) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    w := r.w
+    if w == nil {
+        f, err := os.Create(r.Path)
+        if err != nil {
+            return err
+        }
+
+        defer func() {
+            if closeErr := f.Close(); err == nil {
+                err = closeErr
+            }
+        }()
+        w = f
+    }
+
+    bw := bufio.NewWriter(w)
+    cw := &countWriter{W: bw}
+    rows := 0
+    for data := range inp {
+        err = writeJSONRows(cw, data)
+        if err != nil {
+            return err
+        }
+        rows += data.Len()
+    }
+
+    err = bw.Flush()
+    if err != nil {
+        return err
+    }
+
+    out <- newNamedDataset([]string{"rows", "bytes"}, Ints{int64(rows)}, Ints{cw.N})
+    return nil
+}
+
+// writeJSONRows writes the rows of the dataset as JSON objects, one per line.
+// See JSONWrite
+func writeJSONRows(w *countWriter, data Dataset) error {
+    keys := make([][]byte, data.Width())
+    for i, name := range data.Schema().Names() {
+        if name == "" {
+            name = strconv.Itoa(i)
+        }
+        keys[i], _ = json.Marshal(name)
+    }
+
+    for row := 0; row < data.Len(); row++ {
+        line := []byte{'{'}
+        for i, key := range keys {
+            if i > 0 {
+                line = append(line, ',')
+            }
+
+            v, err := json.Marshal(Value(data.At(i), row))
+            if err != nil {
+                return err
+            }
+
+            line = append(append(append(line, key...), ':'), v...)
+        }
+        w.Write(append(line, '}', '\n'))
+    }
+    return w.Err
+}
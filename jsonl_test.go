@@ -0,0 +1,145 @@
+package ep
+
+import (
+    "os"
+    "fmt"
+    "bytes"
+    "context"
+    "testing"
+    "path/filepath"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleJSONScan() {
+    dir, _ := os.MkdirTemp("", "ep")
+    defer os.RemoveAll(dir)
+
+    lines := `{"name": "bob", "age": 30, "address": {"city": "NYC"}, "tags": ["a", "b"]}
+{"name": "alice", "tags": [], "extra": {"x": [1, null]}}
+`
+    os.WriteFile(filepath.Join(dir, "1.json"), []byte(lines), 0644)
+
+    schema, err := InferJSONSchema(filepath.Join(dir, "*.json"), 0)
+    fmt.Println(schema, err)
+
+    data, err := testRun(JSONScan(filepath.Join(dir, "*.json"), schema, JSONOptions{}))
+    fmt.Println(data, err)
+
+    // Output:
+    // (address:struct<city:string>, age:int, extra:struct<x:list<int>>, name:string, tags:list<string>) <nil>
+    // [[{city: NYC} <nil>] [30 <nil>] [<nil> {x: [1 ]}] [bob alice] [[a b] []]] <nil>
+}
+
+func ExampleJSONWriteTo() {
+    var buf bytes.Buffer
+    data := WithSchema(NewDataset(Strs{"bob", "alice"}, Nullable(Ints{30, 0}, Bools{true, false})), Schema{{"name", Str}, {"", Int}})
+    res, err := testRun(JSONWriteTo(&buf), data)
+    fmt.Print(buf.String())
+    fmt.Println(res, err)
+
+    // Output:
+    // {"name":"bob","1":30}
+    // {"name":"alice","1":null}
+    // [[2] [48]] <nil>
+}
+
+func TestInferJSONSchema(t *testing.T) {
+    dir := t.TempDir()
+    lines := `{"a": 1, "b": null, "c": {}, "d": [1], "e": {"x": null}}
+
+{"a": 1.5, "b": null, "c": {}, "d": [true], "e": {"x": "s"}, "f": 1}
+{"f": "s"}
+`
+    err := os.WriteFile(filepath.Join(dir, "a.json"), []byte(lines), 0644)
+    require.NoError(t, err)
+
+    schema, err := InferJSONSchema(filepath.Join(dir, "a.json"), 0)
+    require.NoError(t, err)
+    require.Equal(t, "(a:float, b:json, c:json, d:list<json>, e:struct<x:string>, f:json)", schema.String())
+
+    schema, err = InferJSONSchema(filepath.Join(dir, "a.json"), 1)
+    require.NoError(t, err)
+    require.Equal(t, "(a:int, b:json, c:json, d:list<int>, e:struct<x:json>)", schema.String())
+
+    err = os.WriteFile(filepath.Join(dir, "b.json"), []byte("[1]\n"), 0644)
+    require.NoError(t, err)
+
+    _, err = InferJSONSchema(filepath.Join(dir, "b.json"), 0)
+    require.EqualError(t, err, `invalid json object at offset 0: "[1]"`)
+}
+
+func TestJSONScanTypes(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "a.json")
+    lines := `{"t": "2018-01-02", "d": "1.25", "j": {"a": [1]}, "n": 5}
+{"j": null, "n": "x"}
+`
+    err := os.WriteFile(path, []byte(lines), 0644)
+    require.NoError(t, err)
+
+    schema := Schema{{"t", Time}, {"d", Decimal(5, 2)}, {"j", JSON}}
+    data, err := testRun(JSONScan(path, schema, JSONOptions{}))
+    require.NoError(t, err)
+    require.Equal(t, []string{"t", "d", "j"}, data.Schema().Names())
+    require.Equal(t, "[[2018-01-02T00:00:00Z <nil>] [1.25 <nil>] [{\"a\":[1]} null]]", fmt.Sprint(data))
+
+    _, err = testRun(JSONScan(path, Schema{{"n", Int}}, JSONOptions{}))
+    require.EqualError(t, err, path + `: unable to cast "x" from string to int`)
+
+    _, err = testRun(JSONScan(path, Schema{{"j", List(Int)}}, JSONOptions{}))
+    require.EqualError(t, err, path + ": unable to convert map[string]interface {} into list<int>")
+}
+
+// Test that every object is read exactly once by the splits of all of the
+// nodes
+func TestJSONScanDistributed(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "a.json")
+    var buf bytes.Buffer
+    for i := 0; i < 50; i++ {
+        fmt.Fprintf(&buf, "{\"id\": %d}\n", i)
+    }
+
+    err := os.WriteFile(path, buf.Bytes(), 0644)
+    require.NoError(t, err)
+
+    nodes := []string{":5551", ":5552"}
+    for _, size := range []int64{0, 1, 7, 100} {
+        seen := map[int64]int{}
+        for _, node := range nodes {
+            ctx := context.WithValue(context.Background(), "ep.AllNodes", nodes)
+            ctx = context.WithValue(ctx, "ep.ThisNode", node)
+            data, err := runCtx(ctx, JSONScan(path, Schema{{"id", Int}}, JSONOptions{SplitSize: size}))
+            require.NoError(t, err)
+            if data.Width() == 0 {
+                continue // no splits were assigned to this node
+            }
+
+            for _, id := range data.At(0).(Ints) {
+                seen[id]++
+            }
+        }
+
+        require.Equal(t, 50, len(seen), "split size %d", size)
+        for id, n := range seen {
+            require.Equal(t, 1, n, "id %d, split size %d", id, size)
+        }
+    }
+}
+
+// Test that written files are read back by JSONScan
+func TestJSONWriteRoundTrip(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "a.json")
+    schema := Schema{{"s", Struct(Field{"a", Int})}, {"l", List(Str)}, {"j", JSON}}
+    data := WithSchema(NewDataset(
+        NewStructs([]string{"a"}, Ints{1, 2}),
+        NewLists(Str, Strs{"x"}, Strs{}),
+        JSONs{`{"k":true}`, "null"},
+    ), schema)
+
+    res, err := testRun(JSONWrite(path), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[2]", fmt.Sprint(res)[:4])
+
+    res, err = testRun(JSONScan(path, schema, JSONOptions{}))
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprint(data), fmt.Sprint(res))
+}
@@ -0,0 +1,61 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&limit{}, &offset{})
+
+// Limit returns a Runner that emits only the first `n` rows of its input.
+// Once the n-th row is emitted, it exits without waiting for the rest of the
+// input, which cancels the upstream runners (see Pipeline), including remote
+// ones across exchanges. Thus, a limit over a large distributed scan
+// terminates quickly. NOTE that the first rows are determined by the order in
+// which they're received, which might not be deterministic across runs
+func Limit(n int) Runner {
+    return &limit{n}
+}
+
+// Offset returns a Runner that skips the first `n` rows of its input, and
+// emits the rest.
+func Offset(n int) Runner {
+    return &offset{n}
+}
+
+type limit struct { N int }
+func (*limit) Returns() []Type { return []Type{Wildcard} }
+func (r *limit) Run(ctx context.Context, inp, out chan Dataset) error {
+    remaining := r.N
+    for remaining > 0 {
+        data, ok := <- inp
+        if !ok {
+            break
+        }
+
+        if data.Len() > remaining {
+            data = data.Slice(0, remaining).(Dataset)
+        }
+
+        remaining -= data.Len()
+        out <- data
+    }
+    return nil
+}
+
+type offset struct { N int }
+func (*offset) Returns() []Type { return []Type{Wildcard} }
+func (r *offset) Run(ctx context.Context, inp, out chan Dataset) error {
+    remaining := r.N
+    for data := range inp {
+        if remaining >= data.Len() {
+            remaining -= data.Len()
+            continue
+        } else if remaining > 0 {
+            data = data.Slice(remaining, data.Len()).(Dataset)
+            remaining = 0
+        }
+
+        out <- data
+    }
+    return nil
+}
@@ -0,0 +1,68 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&InfinityRunner{})
+
+func ExampleLimit() {
+    runner := Limit(3)
+    data1 := NewDataset(Strs{"a", "b"})
+    data2 := NewDataset(Strs{"c", "d"})
+    data, err := testRun(runner, data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[a b c]] <nil>
+}
+
+func ExampleOffset() {
+    runner := Offset(3)
+    data1 := NewDataset(Strs{"a", "b"})
+    data2 := NewDataset(Strs{"c", "d"})
+    data, err := testRun(runner, data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[d]] <nil>
+}
+
+// limit should cancel the upstream runners once it's done, without error
+func TestLimitCancel(t *testing.T) {
+    infinity := &InfinityRunner{}
+    runner := Pipeline(infinity, Limit(5))
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+
+    require.NoError(t, err)
+    require.Equal(t, 5, data.Len())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
+
+// limit over an infinite distributed source should stop the remote peers
+func TestLimitDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(&InfinityRunner{}, Gather(), Limit(10))
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    type result struct {
+        data Dataset
+        err error
+    }
+
+    done := make(chan result, 1)
+    go func() {
+        data, err := testRun(runner, NewDataset(Null.Data(1)))
+        done <- result{data, err}
+    }()
+
+    select {
+    case res := <- done:
+        require.NoError(t, res.err)
+        require.Equal(t, 10, res.data.Len())
+    case <- time.After(5 * time.Second):
+        t.Fatal("limit did not terminate the distributed run")
+    }
+}
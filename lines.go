@@ -0,0 +1,243 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "bufio"
+    "context"
+    "strconv"
+    "strings"
+)
+
+// ValueDecoder decodes values from an underlying stream, like json.Decoder,
+// gob.Decoder and xml.Decoder. See Decode
+type ValueDecoder interface {
+    Decode(v interface{}) error
+}
+
+// ValueEncoder encodes values into an underlying stream, like json.Encoder,
+// gob.Encoder and xml.Encoder. See Encode
+type ValueEncoder interface {
+    Encode(v interface{}) error
+}
+
+// ReadLines returns a source Runner that reads the lines of r, without their
+// trailing newlines, and emits them in batches of BatchSize as a single column
+// named "line". Its input is ignored. Useful for Unix-pipe style tools, i.e.
+// ReadLines(os.Stdin). NOTE: readers cannot be serialized, thus the returned
+// Runner cannot be distributed.
+func ReadLines(r io.Reader) Runner {
+    return &readLines{r}
+}
+
+// WriteLines returns a Runner that writes every row of its input datasets into
+// w as a single line of the tab-separated strings of its columns, where nulls
+// are empty, and emits a single summary row of the number of rows and bytes
+// written. Like ReadLines, it cannot be distributed.
+func WriteLines(w io.Writer) Runner {
+    return &writeLines{w}
+}
+
+// Decode returns a source Runner that decodes objects with the decoder until
+// io.EOF, and emits their fields in batches of BatchSize, converted into the
+// schema with FromValues, where missing fields are nulls. Its input is
+// ignored. For example, Decode(json.NewDecoder(os.Stdin), schema) reads JSON
+// objects. Like ReadLines, it cannot be distributed.
+func Decode(dec ValueDecoder, schema Schema) Runner {
+    return &decode{dec, schema}
+}
+
+// Encode returns a Runner that encodes every row of its input datasets with the
+// encoder as an object, keyed by the names of the columns (see Dataset.Schema)
+// or their indices for unnamed columns, with the values converted with Value.
+// It emits a single summary row of the number of rows encoded. Like
+// ReadLines, it cannot be distributed.
+func Encode(enc ValueEncoder) Runner {
+    return &encode{enc}
+}
+
+type readLines struct {
+    r io.Reader
+}
+
+func (*readLines) Returns() []Type { return []Type{As(Str, "line")} }
+func (r *readLines) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    br := bufio.NewReader(r.r)
+    lines := Strs{}
+    for {
+        line, err := br.ReadString('\n')
+        if err != nil && err != io.EOF {
+            return err
+        } else if err == io.EOF && line == "" {
+            break
+        }
+
+        line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+        lines = append(lines, line)
+        if len(lines) >= BatchSize {
+            if err := emitBatch(ctx, out, newNamedDataset([]string{"line"}, lines)); err != nil {
+                return err
+            }
+            lines = Strs{}
+        }
+
+        if err == io.EOF {
+            break
+        }
+    }
+
+    if len(lines) > 0 {
+        return emitBatch(ctx, out, newNamedDataset([]string{"line"}, lines))
+    }
+    return nil
+}
+
+type writeLines struct {
+    w io.Writer
+}
+
+func (*writeLines) Returns() []Type {
+    return []Type{As(Int, "rows"), As(Int, "bytes")}
+}
+
+func (r *writeLines) Run(ctx context.Context, inp, out chan Dataset) error {
+    bw := bufio.NewWriter(r.w)
+    cw := &countWriter{W: bw}
+    rows := 0
+    for data := range inp {
+        cols := make([][]string, data.Width())
+        for i := range cols {
+            cols[i] = data.At(i).Strings()
+        }
+
+        for row := 0; row < data.Len(); row++ {
+            for i := range cols {
+                if i > 0 {
+                    cw.WriteString("\t")
+                }
+                cw.WriteString(cols[i][row])
+            }
+            cw.WriteString("\n")
+        }
+
+        rows += data.Len()
+        if cw.Err != nil {
+            return cw.Err
+        }
+    }
+
+    err := bw.Flush()
+    if err != nil {
+        return err
+    }
+
+    out <- newNamedDataset([]string{"rows", "bytes"}, Ints{int64(rows)}, Ints{cw.N})
+    return nil
+}
+
+type decode struct {
+    dec ValueDecoder
+    Schema Schema
+}
+
+func (r *decode) Returns() []Type {
+    types := []Type{}
+    for _, f := range r.Schema {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (r *decode) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    values := make([][]interface{}, len(r.Schema))
+    for {
+        var obj map[string]interface{}
+        err := r.dec.Decode(&obj)
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            return err
+        }
+
+        for i, f := range r.Schema {
+            values[i] = append(values[i], obj[f.Name])
+        }
+
+        if len(values) > 0 && len(values[0]) >= BatchSize {
+            if err = r.emit(ctx, values, out); err != nil {
+                return err
+            }
+            values = make([][]interface{}, len(r.Schema))
+        }
+    }
+
+    if len(values) > 0 && len(values[0]) > 0 {
+        return r.emit(ctx, values, out)
+    }
+    return nil
+}
+
+// emit a batch of the values of the columns, converted to the types of the
+// schema, with the conversions of JSONScan for values that aren't natively
+// convertible
+func (r *decode) emit(ctx context.Context, values [][]interface{}, out chan Dataset) error {
+    cols := make([]Data, len(values))
+    for i, f := range r.Schema {
+        var err error
+        cols[i], err = FromValues(f.Type, values[i])
+        if err != nil {
+            cols[i], err = jsonData(f.Type, values[i])
+        }
+
+        if err != nil {
+            return fmt.Errorf("field %s: %s", f.Name, err)
+        }
+    }
+    return emitBatch(ctx, out, newNamedDataset(r.Schema.Names(), cols...))
+}
+
+type encode struct {
+    enc ValueEncoder
+}
+
+func (*encode) Returns() []Type { return []Type{As(Int, "rows")} }
+func (r *encode) Run(ctx context.Context, inp, out chan Dataset) error {
+    rows := 0
+    for data := range inp {
+        keys := data.Schema().Names()
+        for i, name := range keys {
+            if name == "" {
+                keys[i] = strconv.Itoa(i)
+            }
+        }
+
+        for row := 0; row < data.Len(); row++ {
+            obj := make(map[string]interface{}, len(keys))
+            for i, key := range keys {
+                obj[key] = Value(data.At(i), row)
+            }
+
+            if err := r.enc.Encode(obj); err != nil {
+                return err
+            }
+        }
+        rows += data.Len()
+    }
+
+    out <- newNamedDataset([]string{"rows"}, Ints{int64(rows)})
+    return nil
+}
+
+// emitBatch sends the dataset to the output, unless the context is canceled
+func emitBatch(ctx context.Context, out chan Dataset, data Dataset) error {
+    select {
+    case out <- data:
+        return nil
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
@@ -0,0 +1,82 @@
+package ep
+
+import (
+    "fmt"
+    "bytes"
+    "strings"
+    "testing"
+    "encoding/json"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleReadLines() {
+    var buf bytes.Buffer
+    r := strings.NewReader("hello\nworld\r\n\nlast")
+    data, err := testRun(Pipeline(ReadLines(r), WriteLines(&buf)))
+    fmt.Println(data, err)
+    fmt.Printf("%q\n", buf.String())
+
+    // Output:
+    // [[4] [18]] <nil>
+    // "hello\nworld\n\nlast\n"
+}
+
+func ExampleDecode() {
+    r := strings.NewReader(`{"id": 1, "name": "bob"} {"name": "alice", "tags": ["a"]}`)
+    schema := Schema{{"id", Int}, {"name", Str}}
+    data, err := testRun(Decode(json.NewDecoder(r), schema))
+    fmt.Println(data, err)
+
+    // Output: [[1 <nil>] [bob alice]] <nil>
+}
+
+func TestReadLinesBatches(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 2
+
+    data, err := testRun(ReadLines(strings.NewReader("a\nb\nc\n")))
+    require.NoError(t, err)
+    require.Equal(t, "[[a b c]]", fmt.Sprint(data))
+    require.Equal(t, []string{"line"}, data.Schema().Names())
+
+    data, err = testRun(ReadLines(strings.NewReader("")))
+    require.NoError(t, err)
+    require.Equal(t, 0, data.Width())
+}
+
+func TestWriteLines(t *testing.T) {
+    var buf bytes.Buffer
+    data := NewDataset(Strs{"a", "b"}, Nullable(Ints{1, 0}, Bools{true, false}))
+    res, err := testRun(WriteLines(&buf), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[2] [7]]", fmt.Sprint(res))
+    require.Equal(t, "a\t1\nb\t\n", buf.String())
+}
+
+func TestEncodeDecode(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 1
+
+    var buf bytes.Buffer
+    schema := Schema{{"id", Int}, {"tags", List(Str)}, {"at", Time}}
+    data := WithSchema(NewDataset(
+        Ints{1, 2},
+        NewLists(Str, Strs{"a", "b"}, Strs{"c"}),
+        Nullable(Times{0, 0}, Bools{false, false}),
+    ), schema)
+
+    res, err := testRun(Encode(json.NewEncoder(&buf)), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[2]]", fmt.Sprint(res))
+    require.Equal(t, "{\"at\":null,\"id\":1,\"tags\":[\"a\",\"b\"]}\n{\"at\":null,\"id\":2,\"tags\":[\"c\"]}\n", buf.String())
+
+    res, err = testRun(Decode(json.NewDecoder(&buf), schema))
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprint(data), fmt.Sprint(res))
+
+    _, err = testRun(Decode(json.NewDecoder(strings.NewReader(`{"id": "x"}`)), schema))
+    require.EqualError(t, err, `field id: unable to cast "x" from string to int`)
+
+    _, err = testRun(Decode(json.NewDecoder(strings.NewReader(`{"id": 1`)), schema))
+    require.EqualError(t, err, "unexpected EOF")
+}
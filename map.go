@@ -0,0 +1,129 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+)
+
+var _ = registerGob(&mapper{}, &flatMapper{}, &namedMap{})
+
+// Maps registry of named map functions. Map functions cannot be serialized,
+// thus in order to distribute them they must be registered on all nodes under
+// the same name, and referenced by name using NamedMap()
+var Maps = make(mapsReg)
+
+// MapFunc transforms a single input dataset into a single output dataset
+type MapFunc func(Dataset) (Dataset, error)
+
+// Map returns a Runner that applies the function to every input dataset, and
+// emits its results. The returned types must match the datasets produced by
+// the function. Like FlatMap, nil or empty results are skipped. NOTE: functions
+// cannot be serialized, thus the returned Runner cannot be distributed. See
+// NamedMap.
+func Map(returns []Type, fn MapFunc) Runner {
+    return &mapper{returns, fn}
+}
+
+// FlatMapFunc transforms a single input dataset into any number of output
+// datasets, including none at all
+type FlatMapFunc func(Dataset) ([]Dataset, error)
+
+// FlatMap returns a Runner that applies the function to every input dataset,
+// and emits all of the datasets it produces, in order. It's useful for runners
+// that expand their input, like splitting a document column into rows of
+// tokens. Empty results are skipped, thus input datasets that produce no rows
+// produce no output. Like Map, it cannot be distributed.
+func FlatMap(returns []Type, fn FlatMapFunc) Runner {
+    return &flatMapper{returns, fn}
+}
+
+// NamedMap returns a Runner that applies the map function registered under the
+// provided name via `Maps.Register()`. Unlike Map, it's safe to distribute, as
+// the function is resolved by name on every node.
+func NamedMap(name string) Runner {
+    return &namedMap{name}
+}
+
+type mapper struct {
+    Types []Type
+    fn MapFunc
+}
+
+func (r *mapper) Returns() []Type { return r.Types }
+func (r *mapper) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        res, err := r.fn(data)
+        if err != nil {
+            return err
+        } else if res == nil || res.Len() == 0 {
+            continue
+        }
+
+        select {
+        case out <- res:
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+type flatMapper struct {
+    Types []Type
+    fn FlatMapFunc
+}
+
+func (r *flatMapper) Returns() []Type { return r.Types }
+func (r *flatMapper) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        res, err := r.fn(data)
+        if err != nil {
+            return err
+        }
+
+        for _, data := range res {
+            if data == nil || data.Len() == 0 {
+                continue
+            }
+
+            select {
+            case out <- data:
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+    }
+    return nil
+}
+
+type namedMap struct { Name string }
+func (r *namedMap) Returns() []Type {
+    m := Maps.Get(r.Name)
+    if m == nil {
+        return []Type{}
+    }
+    return m.Returns()
+}
+
+func (r *namedMap) Run(ctx context.Context, inp, out chan Dataset) error {
+    m := Maps.Get(r.Name)
+    if m == nil {
+        return fmt.Errorf("Unregistered map %s", r.Name)
+    }
+    return m.Run(ctx, inp, out)
+}
+
+// registry of map functions
+type mapsReg map[string]*mapper
+func (reg mapsReg) Register(name string, returns []Type, fn MapFunc) mapsReg {
+    reg[name] = &mapper{returns, fn}
+    return reg
+}
+
+func (reg mapsReg) Get(name string) Runner {
+    m, ok := reg[name]
+    if !ok {
+        return nil
+    }
+    return m
+}
@@ -0,0 +1,154 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = Maps.Register("lower", []Type{Str}, func(data Dataset) (Dataset, error) {
+    res := make(Strs, data.Len())
+    for i, s := range data.At(0).Strings() {
+        res[i] = strings.ToLower(s)
+    }
+    return NewDataset(res), nil
+})
+
+func ExampleMap() {
+    runner := Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+        res := make(Strs, data.Len())
+        for i, s := range data.At(0).Strings() {
+            res[i] = s + "!"
+        }
+        return NewDataset(res), nil
+    })
+
+    data := NewDataset(Strs{"hello", "world"})
+    data, err := testRun(runner, data)
+    fmt.Println(data, err)
+
+    // Output: [[hello! world!]] <nil>
+}
+
+func ExampleFlatMap() {
+    // split every sentence into a row per word
+    runner := FlatMap([]Type{Str}, func(data Dataset) ([]Dataset, error) {
+        res := []Dataset{}
+        for _, s := range data.At(0).Strings() {
+            res = append(res, NewDataset(Strs(strings.Fields(s))))
+        }
+        return res, nil
+    })
+
+    data := NewDataset(Strs{"hello world", "", "foo"})
+    data, err := testRun(runner, data)
+    fmt.Println(data, err)
+
+    // Output: [[hello world foo]] <nil>
+}
+
+func ExampleNamedMap() {
+    runner := NamedMap("lower")
+    data := NewDataset(Strs{"HELLO", "World"})
+    data, err := testRun(runner, data)
+    fmt.Println(data, err)
+
+    // Output: [[hello world]] <nil>
+}
+
+func TestMapErr(t *testing.T) {
+    runner := Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+        return nil, fmt.Errorf("something bad happened")
+    })
+
+    _, err := testRun(runner, NewDataset(Strs{"hello"}))
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+}
+
+// nil and empty results are skipped, and the output stops once canceled
+func TestMapBatches(t *testing.T) {
+    runner := Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+        switch data.At(0).Strings()[0] {
+        case "nil":
+            return nil, nil
+        case "empty":
+            return NewDataset(Strs{}), nil
+        }
+        return data, nil
+    })
+
+    data, err := testRun(runner, NewDataset(Strs{"nil"}), NewDataset(Strs{"a"}), NewDataset(Strs{"empty"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[a]]", fmt.Sprint(data))
+
+    inp := make(chan Dataset, 1)
+    inp <- NewDataset(Strs{"a"})
+    close(inp)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    err = runner.Run(ctx, inp, make(chan Dataset))
+    require.Equal(t, context.Canceled, err)
+}
+
+func TestFlatMapBatches(t *testing.T) {
+    runner := FlatMap([]Type{Str}, func(data Dataset) ([]Dataset, error) {
+        if data.At(0).Strings()[0] == "skip" {
+            return nil, nil
+        }
+        return []Dataset{data, nil, NewDataset(Strs{}), data}, nil
+    })
+
+    inp := make(chan Dataset, 3)
+    inp <- NewDataset(Strs{"a"})
+    inp <- NewDataset(Strs{"skip"})
+    inp <- NewDataset(Strs{"b"})
+    close(inp)
+
+    out := make(chan Dataset, 10)
+    err := runner.Run(context.Background(), inp, out)
+    close(out)
+    require.NoError(t, err)
+
+    batches := []string{}
+    for data := range out {
+        batches = append(batches, fmt.Sprint(data))
+    }
+    require.Equal(t, []string{"[[a]]", "[[a]]", "[[b]]", "[[b]]"}, batches)
+}
+
+func TestFlatMapErr(t *testing.T) {
+    runner := FlatMap([]Type{Str}, func(data Dataset) ([]Dataset, error) {
+        return nil, fmt.Errorf("something bad happened")
+    })
+
+    _, err := testRun(runner, NewDataset(Strs{"hello"}))
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+}
+
+func TestNamedMapUnregistered(t *testing.T) {
+    runner := NamedMap("nothing")
+    require.Equal(t, []Type{}, runner.Returns())
+
+    _, err := testRun(runner, NewDataset(Strs{"hello"}))
+    require.Error(t, err)
+}
+
+// named maps are distributed by name, and resolved on every node
+func TestNamedMapDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), NamedMap("lower"), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data1 := NewDataset(Strs{"HELLO", "WORLD"})
+    data2 := NewDataset(Strs{"FOO", "BAR"})
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+    require.Equal(t, strings.ToLower(fmt.Sprint(data)), fmt.Sprint(data))
+}
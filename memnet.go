@@ -0,0 +1,204 @@
+package ep
+
+import (
+    "io"
+    "net"
+    "fmt"
+    "time"
+    "sync"
+    "bytes"
+    "errors"
+)
+
+var errPipeDeadline = errors.New("ep: deadlines are not supported by pipes")
+
+// Pipes is an in-memory network of listeners, keyed by arbitrary addresses,
+// which can be used by NewDistributer instead of TCP listeners. It's intended
+// for tests, as they run without binding real ports, and every test may use
+// its own network in parallel to others. The connections are buffered, thus
+// writes never block, like TCP connections with unlimited buffers
+type Pipes struct {
+    l sync.Mutex
+    listeners map[string]*pipeListener
+}
+
+// NewPipes returns a new empty in-memory network
+func NewPipes() *Pipes {
+    return &Pipes{listeners: map[string]*pipeListener{}}
+}
+
+// Listen returns a listener on the address in the network, which also dials
+// other addresses of the network (see NewDistributer). It fails if the address
+// is already in use
+func (p *Pipes) Listen(addr string) (net.Listener, error) {
+    p.l.Lock()
+    defer p.l.Unlock()
+    if p.listeners[addr] != nil {
+        return nil, fmt.Errorf("ep: listen %s: address already in use", addr)
+    }
+
+    ln := &pipeListener{
+        p: p,
+        addr: pipeAddr(addr),
+        conns: make(chan net.Conn),
+        done: make(chan struct{}),
+    }
+    p.listeners[addr] = ln
+    return ln, nil
+}
+
+type pipeListener struct {
+    p *Pipes
+    addr pipeAddr
+    conns chan net.Conn
+    done chan struct{}
+    once sync.Once
+}
+
+func (ln *pipeListener) Addr() net.Addr { return ln.addr }
+
+func (ln *pipeListener) Accept() (net.Conn, error) {
+    select {
+    case conn := <- ln.conns:
+        return conn, nil
+    case <- ln.done:
+        return nil, net.ErrClosed
+    }
+}
+
+func (ln *pipeListener) Close() error {
+    ln.once.Do(func() {
+        ln.p.l.Lock()
+        defer ln.p.l.Unlock()
+        delete(ln.p.listeners, string(ln.addr))
+        close(ln.done)
+    })
+    return nil
+}
+
+// Dial connects to the listener on the address in the network, and blocks
+// until it's accepted. Unix addresses are dialed by their "unix:" prefixed
+// node addresses, while the network argument is otherwise ignored
+func (ln *pipeListener) Dial(network, addr string) (net.Conn, error) {
+    if network == "unix" {
+        addr = "unix:" + addr
+    }
+
+    ln.p.l.Lock()
+    target := ln.p.listeners[addr]
+    ln.p.l.Unlock()
+
+    refused := fmt.Errorf("ep: dial %s: connection refused", addr)
+    if target == nil {
+        return nil, refused
+    }
+
+    a, b := newPipeBuffer(), newPipeBuffer()
+    client := &pipeConn{r: a, w: b, local: ln.addr, remote: target.addr}
+    server := &pipeConn{r: b, w: a, local: target.addr, remote: ln.addr}
+    select {
+    case target.conns <- server:
+        return client, nil
+    case <- target.done:
+        return nil, refused
+    }
+}
+
+type pipeAddr string
+
+func (pipeAddr) Network() string { return "pipe" }
+func (addr pipeAddr) String() string { return string(addr) }
+
+// pipeConn is one end of a connection, reading from the buffer written by the
+// other end, and vice versa
+type pipeConn struct {
+    r, w *pipeBuffer
+    local, remote pipeAddr
+    l sync.Mutex
+    closed bool
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+    if c.isClosed() {
+        return 0, net.ErrClosed
+    }
+    return c.r.Read(b)
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) {
+    if c.isClosed() {
+        return 0, net.ErrClosed
+    }
+    return c.w.Write(b)
+}
+
+// Close closes both directions, such that the other end reads EOF once it
+// has read all of the buffered data, and fails to write
+func (c *pipeConn) Close() error {
+    c.l.Lock()
+    c.closed = true
+    c.l.Unlock()
+
+    c.r.Close()
+    c.w.Close()
+    return nil
+}
+
+func (c *pipeConn) isClosed() bool {
+    c.l.Lock()
+    defer c.l.Unlock()
+    return c.closed
+}
+
+func (c *pipeConn) LocalAddr() net.Addr { return c.local }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remote }
+func (c *pipeConn) SetDeadline(t time.Time) error { return errPipeDeadline }
+func (c *pipeConn) SetReadDeadline(t time.Time) error { return errPipeDeadline }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return errPipeDeadline }
+
+// pipeBuffer is an unbounded buffer of a single direction of a connection
+type pipeBuffer struct {
+    l sync.Mutex
+    cond *sync.Cond
+    buf bytes.Buffer
+    closed bool
+}
+
+func newPipeBuffer() *pipeBuffer {
+    b := &pipeBuffer{}
+    b.cond = sync.NewCond(&b.l)
+    return b
+}
+
+// Read blocks until there's buffered data, or returns EOF once the buffer is
+// closed and drained
+func (b *pipeBuffer) Read(p []byte) (int, error) {
+    b.l.Lock()
+    defer b.l.Unlock()
+    for b.buf.Len() == 0 && !b.closed {
+        b.cond.Wait()
+    }
+
+    if b.buf.Len() == 0 {
+        return 0, io.EOF
+    }
+    return b.buf.Read(p)
+}
+
+func (b *pipeBuffer) Write(p []byte) (int, error) {
+    b.l.Lock()
+    defer b.l.Unlock()
+    if b.closed {
+        return 0, io.ErrClosedPipe
+    }
+
+    b.cond.Broadcast()
+    return b.buf.Write(p)
+}
+
+func (b *pipeBuffer) Close() {
+    b.l.Lock()
+    defer b.l.Unlock()
+    b.closed = true
+    b.cond.Broadcast()
+}
@@ -0,0 +1,85 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "net"
+    "testing"
+    "io/ioutil"
+    "github.com/stretchr/testify/require"
+)
+
+func TestPipes(t *testing.T) {
+    pipes := NewPipes()
+    ln1, err := pipes.Listen("a")
+    require.NoError(t, err)
+    defer ln1.Close()
+
+    _, err = pipes.Listen("a")
+    require.EqualError(t, err, "ep: listen a: address already in use")
+
+    ln2, err := pipes.Listen("b")
+    require.NoError(t, err)
+
+    _, err = ln1.(dialer).Dial("tcp", "c")
+    require.EqualError(t, err, "ep: dial c: connection refused")
+
+    accepted := make(chan net.Conn, 1)
+    go func() {
+        conn, err := ln2.Accept()
+        if err != nil {
+            close(accepted)
+            return
+        }
+
+        // writes don't block, even when the other end doesn't read
+        for i := 0; i < 1000; i++ {
+            conn.Write([]byte("hello world\n"))
+        }
+        conn.Close()
+        accepted <- conn
+    }()
+
+    conn, err := ln1.(dialer).Dial("tcp", "b")
+    require.NoError(t, err)
+    require.Equal(t, "b", conn.RemoteAddr().String())
+
+    server := <- accepted
+    require.NotNil(t, server)
+    require.Equal(t, "a", server.RemoteAddr().String())
+
+    b, err := ioutil.ReadAll(conn)
+    require.NoError(t, err)
+    require.Equal(t, 12000, len(b))
+
+    _, err = conn.Write([]byte("hello"))
+    require.Equal(t, io.ErrClosedPipe, err)
+
+    ln2.Close()
+    _, err = ln2.Accept()
+    require.Error(t, err)
+
+    _, err = ln1.(dialer).Dial("tcp", "b")
+    require.EqualError(t, err, "ep: dial b: connection refused")
+}
+
+// Distributed tests with separate in-memory networks can run in parallel
+func TestPipesDistributed(t *testing.T) {
+    for i := 0; i < 4; i++ {
+        t.Run(fmt.Sprint(i), func(t *testing.T) {
+            t.Parallel()
+
+            dists := testCluster(t, 2)
+
+            runner := Pipeline(Scatter(), &nodeAddr{}, Gather())
+            runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+            data1 := NewDataset(Strs{"hello", "world"})
+            data2 := NewDataset(Strs{"foo", "bar"})
+            data, err := testRun(runner, data1, data2)
+
+            require.NoError(t, err)
+            require.Equal(t, "[[hello world foo bar] [:5552 :5552 :5551 :5551]]", fmt.Sprintf("%v", data))
+        })
+    }
+}
@@ -0,0 +1,130 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "context"
+)
+
+var _ = registerGob(&withMemory{})
+
+// MemoryManager accounts the memory buffered by the runners of a single job,
+// within a budget. The memory-hungry runners reserve the estimated size of
+// the data they buffer, and when the reservation is denied they degrade to
+// disk instead of exceeding the budget: Sort spills sorted runs, Join spills
+// both sides by partitions of their keys, and GroupBy flushes its partial
+// aggregates or spills its final aggregates by partitions. The local queues of
+// exchanges are accounted, but never spilled. See WithMemory
+type MemoryManager struct {
+    Budget int // in bytes, or 0 for unlimited
+
+    l sync.Mutex
+    reserved int
+    peak int
+}
+
+// NewMemoryManager returns a MemoryManager with the budget in bytes, or an
+// unlimited one for a budget of 0
+func NewMemoryManager(budget int) *MemoryManager {
+    return &MemoryManager{Budget: budget}
+}
+
+// Reserve n bytes, if they're within the budget. Returns false otherwise, in
+// which case nothing is reserved and the caller should release or spill some
+// of its memory. A nil MemoryManager is unlimited
+func (m *MemoryManager) Reserve(n int) bool {
+    if m == nil {
+        return true
+    }
+
+    m.l.Lock()
+    defer m.l.Unlock()
+    if m.Budget > 0 && m.reserved + n > m.Budget {
+        return false
+    }
+
+    m.add(n)
+    return true
+}
+
+// Force reserves n bytes even if they exceed the budget, for memory that
+// can't be spilled. It causes the following reservations to be denied
+func (m *MemoryManager) Force(n int) {
+    if m == nil {
+        return
+    }
+
+    m.l.Lock()
+    defer m.l.Unlock()
+    m.add(n)
+}
+
+// Release n previously reserved bytes
+func (m *MemoryManager) Release(n int) {
+    if m == nil {
+        return
+    }
+
+    m.l.Lock()
+    defer m.l.Unlock()
+    m.reserved -= n
+}
+
+func (m *MemoryManager) add(n int) {
+    m.reserved += n
+    if m.reserved > m.peak {
+        m.peak = m.reserved
+    }
+}
+
+// Reserved returns the number of currently reserved bytes
+func (m *MemoryManager) Reserved() int {
+    m.l.Lock()
+    defer m.l.Unlock()
+    return m.reserved
+}
+
+// Peak returns the maximum number of bytes that were reserved at once
+func (m *MemoryManager) Peak() int {
+    m.l.Lock()
+    defer m.l.Unlock()
+    return m.peak
+}
+
+// memoryOf returns the MemoryManager of the job in the context, or nil when
+// the memory is unlimited
+func memoryOf(ctx context.Context) *MemoryManager {
+    m, _ := ctx.Value("ep.Memory").(*MemoryManager)
+    return m
+}
+
+// WithMemory returns a Runner that runs the provided runner within a memory
+// budget in bytes, by a new MemoryManager per run. When distributed, every node
+// has its own budget. Runners are unlimited by default
+func WithMemory(budget int, r Runner) Runner {
+    return &withMemory{budget, r}
+}
+
+type withMemory struct {
+    Budget int
+    Runner Runner
+}
+
+func (r *withMemory) Returns() []Type { return r.Runner.Returns() }
+func (r *withMemory) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *withMemory) Run(ctx context.Context, inp, out chan Dataset) error {
+    ctx = context.WithValue(ctx, "ep.Memory", NewMemoryManager(r.Budget))
+    return r.Runner.Run(ctx, inp, out)
+}
+
+func (r *withMemory) String() string {
+    return fmt.Sprintf("budget %dB", r.Budget)
+}
+
+func (r *withMemory) inner() []Runner { return []Runner{r.Runner} }
+func (r *withMemory) withInner(inner []Runner) Runner {
+    return &withMemory{r.Budget, inner[0]}
+}
@@ -0,0 +1,133 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "context"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestMemoryManager(t *testing.T) {
+    m := NewMemoryManager(100)
+    require.True(t, m.Reserve(60))
+    require.False(t, m.Reserve(50))
+    require.True(t, m.Reserve(40))
+    require.Equal(t, 100, m.Reserved())
+
+    m.Release(60)
+    m.Force(100)
+    require.Equal(t, 140, m.Reserved())
+    require.False(t, m.Reserve(1))
+
+    m.Release(140)
+    require.Equal(t, 0, m.Reserved())
+    require.Equal(t, 140, m.Peak())
+
+    // nil and zero budgets are unlimited
+    var none *MemoryManager
+    require.True(t, none.Reserve(1 << 40))
+    require.True(t, NewMemoryManager(0).Reserve(1 << 40))
+}
+
+// memoryRows runs the runner with and without a tiny memory budget, and
+// returns the sorted rows of both runs. Outer joins emit unmatched rows in
+// separate batches, thus the rows are collected per batch
+func memoryRows(t *testing.T, r Runner, datasets ...Dataset) ([]string, []string) {
+    var res [2][]string
+    for i, runner := range []Runner{r, WithMemory(1, r)} {
+        inp := make(chan Dataset, len(datasets))
+        for _, data := range datasets {
+            inp <- data
+        }
+        close(inp)
+
+        out := make(chan Dataset)
+        errs := make(chan error, 1)
+        go func() {
+            errs <- runner.Run(context.Background(), inp, out)
+            close(out)
+        }()
+
+        for data := range out {
+            res[i] = append(res[i], rowStrings(data)...)
+        }
+
+        require.NoError(t, <- errs)
+        sort.Strings(res[i])
+    }
+    return res[0], res[1]
+}
+
+// generate batches of rows with keys 0-99, and unique values
+func memoryBatches(n int, prefix string) []Dataset {
+    res := []Dataset{}
+    for i := 0; i < n; i++ {
+        keys, values := Strs{}, Strs{}
+        for j := 0; j < 50; j++ {
+            keys = append(keys, strconv.Itoa((i * 50 + j) * 7 % 100))
+            values = append(values, prefix + strconv.Itoa(i * 50 + j))
+        }
+        res = append(res, NewDataset(keys, values))
+    }
+    return res
+}
+
+func TestMemorySort(t *testing.T) {
+    runner := Sort([]SortKey{{Col: 0}, {Col: 1}})
+    expected, actual := memoryRows(t, runner, memoryBatches(10, "v")...)
+    require.Equal(t, 500, len(actual))
+    require.Equal(t, expected, actual)
+}
+
+func TestMemoryGroupBy(t *testing.T) {
+    runner := GroupBy([]int{0}, Count(), Max(1))
+    expected, actual := memoryRows(t, runner, memoryBatches(10, "v")...)
+    require.Equal(t, 100, len(actual))
+    require.Equal(t, expected, actual)
+}
+
+func TestMemoryJoin(t *testing.T) {
+    left := &batchesRunner{memoryBatches(10, "l")}
+    right := &batchesRunner{[]Dataset{
+        memoryBatches(2, "r")[1], // half of the keys
+        NewDataset(Strs{"x", "y"}, Strs{"rx", "ry"}), // unmatched
+    }}
+    for _, typ := range []JoinType{InnerJoin, LeftJoin, RightJoin, FullJoin, SemiJoin, AntiJoin} {
+        runner := Join(typ, []int{0}, []int{0}, left, right)
+        expected, actual := memoryRows(t, runner, NewDataset(Null.Data(1)))
+        require.NotEmpty(t, actual)
+        require.Equal(t, expected, actual, "join type %d", typ)
+    }
+}
+
+// the memory of the local exchange queues is accounted, and released when
+// they're consumed
+func TestMemoryExchange(t *testing.T) {
+    mem := NewMemoryManager(0)
+    sc := newShortCircuit(DefaultBuffers.ShortCircuit, mem)
+    data := NewDataset(Strs{"hello", "world"})
+    require.NoError(t, sc.Encode(&dataReq{data}))
+    require.Equal(t, 10, mem.Reserved())
+
+    req := &dataReq{}
+    require.NoError(t, sc.Decode(req))
+    require.Equal(t, 0, mem.Reserved())
+    require.Equal(t, 10, mem.Peak())
+}
+
+func TestMemoryDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), GroupBy([]int{0}, Count()), Gather())
+    runner = dists[0].Distribute(WithMemory(1, runner), ":5551", ":5552")
+    data, err := testRun(runner, memoryBatches(10, "v")...)
+    require.NoError(t, err)
+
+    rows := rowStrings(data)
+    sort.Strings(rows)
+    require.Equal(t, 100, len(rows))
+    require.Equal(t, "0 5", rows[0])
+    require.Equal(t, "budget 1B", fmt.Sprint(WithMemory(1, runner)))
+}
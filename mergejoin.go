@@ -0,0 +1,193 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&mergeJoin{})
+
+// MergeJoin returns a Join that expects the outputs of both the left and right
+// runners to be already sorted in ascending order by their key columns (for
+// example, after a Sort or DistributedSort), and joins them in a streaming
+// fashion. Unlike Join, it doesn't buffer the right side, only the rows of the
+// current key. Rows with equal keys on both sides produce all of their
+// combinations. The output is undefined if the inputs aren't sorted.
+//
+// NOTE: Like Join, unmatched rows are produced in separate batches, where the
+// columns of the other side are all of the Null type.
+func MergeJoin(typ JoinType, leftKeys, rightKeys []int, left, right Runner) Runner {
+    return &mergeJoin{typ, leftKeys, rightKeys, left, right}
+}
+
+type mergeJoin join
+
+func (r *mergeJoin) Returns() []Type { return (*join)(r).Returns() }
+func (r *mergeJoin) Run(ctx context.Context, inp, out chan Dataset) error {
+    left, right, g := runBoth(ctx, r.Left, r.Right, inp)
+
+    res := &mergeOutput{out: out}
+    leftWidth, rightWidth := len(r.Left.Returns()), len(r.Right.Returns())
+    emitLeft := func(data Dataset) {
+        if r.Type == AntiJoin {
+            res.Add(mergeLeft, data)
+        } else if r.Type == LeftJoin || r.Type == FullJoin {
+            res.Add(mergeLeft, joinRows(data, nullRows(data.Len(), rightWidth)))
+        }
+    }
+
+    emitRight := func(data Dataset) {
+        if r.Type == RightJoin || r.Type == FullJoin {
+            res.Add(mergeRight, joinRows(nullRows(data.Len(), leftWidth), data))
+        }
+    }
+
+    // batches with null keys are emitted as is, as their types differ from
+    // the other batches
+    nullLeft := func(data Dataset) {
+        if r.Type == AntiJoin {
+            out <- data
+        } else if r.Type == LeftJoin || r.Type == FullJoin {
+            out <- joinRows(data, nullRows(data.Len(), rightWidth))
+        }
+    }
+
+    nullRight := func(data Dataset) {
+        if r.Type == RightJoin || r.Type == FullJoin {
+            out <- joinRows(nullRows(data.Len(), leftWidth), data)
+        }
+    }
+
+    l := &mergeCursor{ch: left, keys: r.LeftKeys, nulls: nullLeft}
+    rr := &mergeCursor{ch: right, keys: r.RightKeys, nulls: nullRight}
+    for l.Next() && rr.Next() {
+        c := compareKeys(l.Data, l.I, r.LeftKeys, rr.Data, rr.I, r.RightKeys)
+        if c < 0 {
+            emitLeft(l.Row())
+            l.I++
+            continue
+        } else if c > 0 {
+            emitRight(rr.Row())
+            rr.I++
+            continue
+        }
+
+        // collect the group of right rows with the current key, possibly
+        // across several batches. This is the only buffered data.
+        group := Clone(rr.Row()).(Dataset)
+        rr.I++
+        for rr.Next() && compareKeys(rr.Data, rr.I, r.RightKeys, group, 0, r.RightKeys) == 0 {
+            group = appendClone(group, rr.Row())
+            rr.I++
+        }
+
+        // join all of the left rows with the current key with the group
+        for l.Next() && compareKeys(l.Data, l.I, r.LeftKeys, group, 0, r.RightKeys) == 0 {
+            if r.Type.leftOnly() {
+                if r.Type == SemiJoin {
+                    res.Add(mergeMatched, l.Row())
+                }
+
+                l.I++
+                continue
+            }
+
+            rows := make([]int, group.Len())
+            for i := range rows {
+                rows[i] = l.I
+            }
+
+            res.Add(mergeMatched, joinRows(pick(l.Data, rows).(Dataset), group))
+            l.I++
+        }
+    }
+
+    // one of the sides is exhausted, the remaining rows of the other side are
+    // all unmatched. This also drains both sides.
+    for l.Next() {
+        emitLeft(l.Data.Slice(l.I, l.Data.Len()).(Dataset))
+        l.I = l.Data.Len()
+    }
+
+    for rr.Next() {
+        emitRight(rr.Data.Slice(rr.I, rr.Data.Len()).(Dataset))
+        rr.I = rr.Data.Len()
+    }
+
+    res.Flush()
+    return g.Wait()
+}
+
+// mergeCursor is the current row in a sorted stream of datasets
+type mergeCursor struct {
+    Data Dataset
+    I int
+    ch chan Dataset
+    keys []int
+    nulls func(Dataset) // called with the batches that have null keys
+}
+
+// Next ensures that the cursor points to a valid row, receiving the next batch
+// if needed. Returns false when the stream is exhausted
+func (c *mergeCursor) Next() bool {
+    for c.Data == nil || c.I >= c.Data.Len() {
+        data, ok := <- c.ch
+        if !ok {
+            c.Data = nil
+            return false
+        }
+
+        c.Data, c.I = data, 0
+        if data.Len() > 0 && hasNullKeys(data, c.keys) {
+            c.nulls(data) // can't be compared, and never match
+            c.Data = nil
+        } else if mask := nullKeys(data, c.keys); mask != nil {
+            valid := make(Bools, len(mask))
+            for i, null := range mask {
+                valid[i] = !null
+            }
+
+            if rows := mask.Selection(); len(rows) > 0 {
+                c.nulls(pick(data, rows).(Dataset))
+            }
+            c.Data = data.Filter(valid)
+        }
+    }
+    return true
+}
+
+// Row returns the current row as a single-row dataset
+func (c *mergeCursor) Row() Dataset {
+    return c.Data.Slice(c.I, c.I + 1).(Dataset)
+}
+
+const (
+    mergeMatched = iota // joined rows
+    mergeLeft // unmatched left rows
+    mergeRight // unmatched right rows
+)
+
+// mergeOutput accumulates the output rows, in order to emit them in batches
+// rather than row by row. Matched and unmatched rows are accumulated
+// separately, as their column types differ.
+type mergeOutput struct {
+    out chan Dataset
+    buffs [3]Dataset
+}
+
+func (o *mergeOutput) Add(kind int, data Dataset) {
+    o.buffs[kind] = appendClone(o.buffs[kind], data)
+    if o.buffs[kind].Len() >= BatchSize {
+        o.out <- o.buffs[kind]
+        o.buffs[kind] = nil
+    }
+}
+
+func (o *mergeOutput) Flush() {
+    for kind, data := range o.buffs {
+        if data != nil {
+            o.out <- data
+            o.buffs[kind] = nil
+        }
+    }
+}
+
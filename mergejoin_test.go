@@ -0,0 +1,93 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleMergeJoin() {
+    runner := MergeJoin(InnerJoin, []int{0}, []int{0}, users, orders)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    fmt.Println(data, err)
+
+    // Output: [[1 1 3] [alice alice carol] [1 1 3] [book pen cup]] <nil>
+}
+
+// Test that duplicate keys on both sides, spanning several batches, produce
+// all of their combinations
+func TestMergeJoinDuplicates(t *testing.T) {
+    left := &batchesRunner{[]Dataset{
+        NewDataset(Strs{"a", "b"}, Strs{"l1", "l2"}),
+        NewDataset(Strs{"b", "c"}, Strs{"l3", "l4"}),
+    }}
+
+    right := &batchesRunner{[]Dataset{
+        NewDataset(Strs{"b"}, Strs{"r1"}),
+        NewDataset(Strs{"b", "c", "d"}, Strs{"r2", "r3", "r4"}),
+    }}
+
+    runner := MergeJoin(InnerJoin, []int{0}, []int{0}, left, right)
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    require.NoError(t, err)
+
+    expected := []string{"b l2 b r1", "b l2 b r2", "b l3 b r1", "b l3 b r2", "c l4 c r3"}
+    require.Equal(t, expected, rowStrings(data))
+}
+
+// Test that the outer, semi and anti merge joins produce the same rows as the
+// hash join
+func TestMergeJoinTypes(t *testing.T) {
+    for _, typ := range []JoinType{LeftJoin, RightJoin, FullJoin, SemiJoin, AntiJoin} {
+        expected := outerRows(t, Join(typ, []int{0}, []int{0}, users, orders))
+        rows := outerRows(t, MergeJoin(typ, []int{0}, []int{0}, users, orders))
+        require.ElementsMatch(t, expected, rows, "join type %d", typ)
+    }
+}
+
+func TestMergeJoinErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    infinity := &InfinityRunner{}
+    runner := MergeJoin(InnerJoin, []int{0}, []int{0}, infinity, &ErrRunner{err})
+    _, err = testRun(runner, NewDataset(Null.Data(1)))
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
+
+// outerRows runs the runner, and returns the rows of all of its output batches
+func outerRows(t *testing.T, runner Runner) []string {
+    inp := make(chan Dataset, 1)
+    inp <- NewDataset(Null.Data(1))
+    close(inp)
+
+    out := make(chan Dataset, 10)
+    err := runner.Run(context.Background(), inp, out)
+    close(out)
+    require.NoError(t, err)
+
+    rows := []string{}
+    for data := range out {
+        rows = append(rows, rowStrings(data)...)
+    }
+    return rows
+}
+
+var _ = registerGob(&batchesRunner{})
+
+// batchesRunner is a source Runner that ignores its input, and emits constant
+// datasets
+type batchesRunner struct { Batches []Dataset }
+func (r *batchesRunner) Returns() []Type {
+    return (&constRunner{r.Batches[0]}).Returns()
+}
+
+func (r *batchesRunner) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    for _, data := range r.Batches {
+        out <- data
+    }
+    return nil
+}
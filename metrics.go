@@ -0,0 +1,112 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "sync"
+    "bytes"
+    "expvar"
+    "strconv"
+)
+
+// Metrics of the distributers and exchanges of this process. They're
+// published with expvar under the "ep" name, thus they're served as JSON by
+// the expvar handler (/debug/vars), and can be exported to Prometheus with its
+// expvar collector (see prometheus/collectors.NewExpvarCollector)
+var Metrics = newClusterMetrics()
+
+func init() {
+    expvar.Publish("ep", Metrics)
+}
+
+// ClusterMetrics are the operational metrics of an ep node
+type ClusterMetrics struct {
+    ActiveRunners expvar.Int // distributed runners currently running
+    AcceptedConns expvar.Int // connections accepted by the distributers
+    ExchangeQueue expvar.Int // datasets received by exchanges, not yet consumed
+    BytesSent expvar.Map // bytes sent to each peer, by its address
+    BytesReceived expvar.Map // bytes received from each peer, by its address
+    RunDurations *Histogram // durations of distributed runs in seconds
+}
+
+func newClusterMetrics() *ClusterMetrics {
+    buckets := []float64{.001, .01, .1, 1, 10, 60, 600}
+    return &ClusterMetrics{RunDurations: NewHistogram(buckets...)}
+}
+
+// String implements expvar.Var, as a JSON object of all of the metrics
+func (m *ClusterMetrics) String() string {
+    return fmt.Sprintf(
+        `{"active_runners": %s, "accepted_conns": %s, "exchange_queue": %s, ` +
+        `"bytes_sent": %s, "bytes_received": %s, "run_durations": %s}`,
+        &m.ActiveRunners, &m.AcceptedConns, &m.ExchangeQueue,
+        &m.BytesSent, &m.BytesReceived, m.RunDurations,
+    )
+}
+
+// Histogram is a cumulative histogram of observed values, like the
+// histograms of Prometheus. It implements expvar.Var
+type Histogram struct {
+    l sync.Mutex
+    buckets []float64 // upper bounds, sorted
+    counts []int64 // observations at most each bucket
+    count int64
+    sum float64
+}
+
+// NewHistogram returns a Histogram with the provided sorted bucket upper
+// bounds. Values above the last bucket are only counted by the total count.
+func NewHistogram(buckets ...float64) *Histogram {
+    return &Histogram{buckets: buckets, counts: make([]int64, len(buckets))}
+}
+
+// Observe adds a single value to the histogram
+func (h *Histogram) Observe(v float64) {
+    h.l.Lock()
+    defer h.l.Unlock()
+    h.count++
+    h.sum += v
+    for i, bound := range h.buckets {
+        if v <= bound {
+            h.counts[i]++
+        }
+    }
+}
+
+// String returns the histogram as a JSON object of its cumulative counts by
+// bucket, along with the total count and sum of the values
+func (h *Histogram) String() string {
+    h.l.Lock()
+    defer h.l.Unlock()
+
+    var b bytes.Buffer
+    b.WriteString(`{"buckets": {`)
+    for i, bound := range h.buckets {
+        if i > 0 {
+            b.WriteString(", ")
+        }
+        fmt.Fprintf(&b, "%q: %d", strconv.FormatFloat(bound, 'g', -1, 64), h.counts[i])
+    }
+
+    fmt.Fprintf(&b, `}, "count": %d, "sum": %s}`, h.count, strconv.FormatFloat(h.sum, 'g', -1, 64))
+    return b.String()
+}
+
+// meteredConn is a connection to a peer that counts the bytes it sends and
+// receives into the Metrics
+type meteredConn struct {
+    net.Conn
+    peer string
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+    n, err := c.Conn.Read(b)
+    Metrics.BytesReceived.Add(c.peer, int64(n))
+    return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+    n, err := c.Conn.Write(b)
+    Metrics.BytesSent.Add(c.peer, int64(n))
+    return n, err
+}
@@ -0,0 +1,65 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "time"
+    "expvar"
+    "testing"
+    "encoding/json"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleHistogram() {
+    h := NewHistogram(1, 10)
+    h.Observe(0.5)
+    h.Observe(5)
+    h.Observe(50)
+    fmt.Println(h)
+
+    // Output: {"buckets": {"1": 1, "10": 2}, "count": 3, "sum": 55.5}
+}
+
+func TestMetrics(t *testing.T) {
+    dists := []Distributer{}
+    addrs := []string{}
+    for i := 0; i < 2; i++ {
+        ln, err := net.Listen("tcp", "127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(ln.Addr().String(), ln)
+        go dist.Start()
+        defer dist.Close()
+
+        dists = append(dists, dist)
+        addrs = append(addrs, dist.Addr())
+    }
+
+    accepted := Metrics.AcceptedConns.Value()
+    runs := metricsValue(t)["run_durations"].(map[string]interface{})["count"].(float64)
+
+    runner := dists[0].Distribute(Pipeline(Scatter(), Gather()), addrs...)
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}))
+    require.NoError(t, err)
+    require.Equal(t, 2, data.Len())
+
+    require.True(t, Metrics.AcceptedConns.Value() > accepted)
+    require.True(t, Metrics.BytesSent.Get(addrs[1]).(*expvar.Int).Value() > 0)
+    require.True(t, Metrics.BytesReceived.Get(addrs[1]).(*expvar.Int).Value() > 0)
+
+    // the peer may still be completing its run. The metrics are global, thus
+    // runs of other tests may be counted as well
+    require.Eventually(t, func() bool {
+        values := metricsValue(t)
+        count := values["run_durations"].(map[string]interface{})["count"].(float64)
+        return count >= runs + 2
+    }, time.Second, time.Millisecond)
+}
+
+// metricsValue returns the decoded JSON of the published metrics
+func metricsValue(t *testing.T) map[string]interface{} {
+    values := map[string]interface{}{}
+    err := json.Unmarshal([]byte(expvar.Get("ep").String()), &values)
+    require.NoError(t, err)
+    return values
+}
@@ -0,0 +1,239 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "strings"
+)
+
+var _ = registerGob(&StructType{}, Structs{}, &ListType{}, Lists{}, &explode{})
+
+// Struct returns a built-in composite Type of values with named sub-columns
+// (fields) of the provided types, for nested records. Use it to create Data
+// instances of `n` zero-value structs
+func Struct(fields ...Field) Type {
+    return &StructType{fields}
+}
+
+// List returns a built-in composite Type of variable-length arrays of values of
+// the provided type. Use it to create Data instances of `n` empty lists
+func List(of Type) Type {
+    return &ListType{of}
+}
+
+// StructType is the Type of Structs
+type StructType struct { Fields Schema }
+func (t *StructType) Name() string {
+    fields := []string{}
+    for _, f := range t.Fields {
+        fields = append(fields, f.Name + ":" + f.Type.Name())
+    }
+    return "struct<" + strings.Join(fields, ",") + ">"
+}
+
+func (t *StructType) Data(n uint) Data {
+    fields := make([]Data, len(t.Fields))
+    for i, f := range t.Fields {
+        fields[i] = f.Type.Data(n)
+    }
+    return Structs{t.Fields.Names(), fields}
+}
+
+// Structs is a built-in Data implementation of nested records, stored as a
+// column per field. Values are ordered by their fields, in order, and their
+// string representation is like "{name: bob, age: 30}".
+type Structs struct {
+    Names []string
+    Fields []Data
+}
+
+// NewStructs returns the Structs of the field columns, named by the names. It
+// panics if there isn't a name per field, or if there are no fields at all.
+func NewStructs(names []string, fields ...Data) Structs {
+    if len(fields) == 0 || len(names) != len(fields) {
+        panic(fmt.Sprintf("struct of %d names for %d fields", len(names), len(fields)))
+    }
+    return Structs{names, fields}
+}
+
+// Field returns the column of the field with the provided name, or nil if
+// there's no such field
+func (vs Structs) Field(name string) Data {
+    for i, n := range vs.Names {
+        if n == name {
+            return vs.Fields[i]
+        }
+    }
+    return nil
+}
+
+func (vs Structs) Type() Type {
+    fields := Schema{}
+    for i, f := range vs.Fields {
+        fields = append(fields, Field{vs.Names[i], f.Type()})
+    }
+    return &StructType{fields}
+}
+
+func (vs Structs) Len() int { return vs.Fields[0].Len() }
+func (vs Structs) Less(i, j int) bool { return vs.Compare(i, vs, j) < 0 }
+
+// Compare implements Comparer, by the fields in order
+func (vs Structs) Compare(i int, other Data, j int) int {
+    o := other.(Structs)
+    for k, f := range vs.Fields {
+        c := CompareAt(f, i, o.Fields[k], j)
+        if c != 0 {
+            return c
+        }
+    }
+    return 0
+}
+
+func (vs Structs) Swap(i, j int) {
+    for _, f := range vs.Fields {
+        f.Swap(i, j)
+    }
+}
+
+func (vs Structs) Slice(s, e int) Data {
+    fields := make([]Data, len(vs.Fields))
+    for i, f := range vs.Fields {
+        fields[i] = f.Slice(s, e)
+    }
+    return Structs{vs.Names, fields}
+}
+
+func (vs Structs) Append(o Data) Data {
+    other := o.(Structs)
+    fields := make([]Data, len(vs.Fields))
+    for i, f := range vs.Fields {
+        fields[i] = appendData(f, other.Fields[i])
+    }
+    return Structs{vs.Names, fields}
+}
+
+func (vs Structs) Strings() []string {
+    strs := make([][]string, len(vs.Fields))
+    for i, f := range vs.Fields {
+        strs[i] = f.Strings()
+    }
+
+    res := make([]string, vs.Len())
+    for i := range res {
+        fields := make([]string, len(strs))
+        for j := range strs {
+            fields[j] = vs.Names[j] + ": " + strs[j][i]
+        }
+        res[i] = "{" + strings.Join(fields, ", ") + "}"
+    }
+    return res
+}
+
+func (vs Structs) String() string { return fmt.Sprint(vs.Strings()) }
+
+// ListType is the Type of Lists
+type ListType struct { Of Type }
+func (t *ListType) Name() string { return "list<" + t.Of.Name() + ">" }
+func (t *ListType) Data(n uint) Data {
+    values := make([]Data, n)
+    for i := range values {
+        values[i] = t.Of.Data(0)
+    }
+    return Lists{t.Of, values}
+}
+
+// Lists is a built-in Data implementation of variable-length arrays, where
+// every row is a Data of the elements. Values are ordered element by element,
+// and their string representation is like "[1 2 3]". See Explode
+type Lists struct {
+    Of Type
+    Values []Data
+}
+
+// NewLists returns the Lists of the provided rows of elements of the type
+func NewLists(of Type, rows ...Data) Lists {
+    return Lists{of, rows}
+}
+
+func (vs Lists) Type() Type { return &ListType{vs.Of} }
+func (vs Lists) Len() int { return len(vs.Values) }
+func (vs Lists) Swap(i, j int) { vs.Values[i], vs.Values[j] = vs.Values[j], vs.Values[i] }
+func (vs Lists) Slice(s, e int) Data { return Lists{vs.Of, vs.Values[s:e]} }
+func (vs Lists) Append(o Data) Data {
+    return Lists{vs.Of, append(vs.Values, o.(Lists).Values...)}
+}
+
+func (vs Lists) Less(i, j int) bool { return vs.Compare(i, vs, j) < 0 }
+
+// Compare implements Comparer, by the elements in order, and then by the length
+// of the lists
+func (vs Lists) Compare(i int, other Data, j int) int {
+    a, b := vs.Values[i], other.(Lists).Values[j]
+    for k := 0; k < a.Len() && k < b.Len(); k++ {
+        c := CompareAt(a, k, b, k)
+        if c != 0 {
+            return c
+        }
+    }
+    return compareInts(int64(a.Len()), int64(b.Len()))
+}
+
+func (vs Lists) Strings() []string {
+    res := make([]string, len(vs.Values))
+    for i, v := range vs.Values {
+        res[i] = "[" + strings.Join(v.Strings(), " ") + "]"
+    }
+    return res
+}
+
+func (vs Lists) String() string { return fmt.Sprint(vs.Strings()) }
+
+// Explode returns a Runner that unnests the list column `col` of its input: a
+// row is emitted for every element of the list, with the element replacing the
+// list, and the rest of the columns repeated. Rows with empty lists are
+// dropped. See FlatMap for custom expansions.
+func Explode(col int) Runner {
+    return &explode{col}
+}
+
+type explode struct { Col int }
+func (*explode) Returns() []Type { return []Type{Wildcard} }
+func (r *explode) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        if r.Col < 0 || r.Col >= data.Width() {
+            return fmt.Errorf("column %d out of range %d", r.Col, data.Width())
+        }
+
+        lists, ok := data.At(r.Col).(Lists)
+        if !ok {
+            return fmt.Errorf("column %d of type %s isn't a list", r.Col, data.At(r.Col).Type().Name())
+        }
+
+        rows := []int{}
+        elements := lists.Of.Data(0)
+        for i, v := range lists.Values {
+            for j := 0; j < v.Len(); j++ {
+                rows = append(rows, i)
+            }
+            elements = appendData(elements, v)
+        }
+
+        if len(rows) == 0 {
+            continue
+        }
+
+        cols := make([]Data, data.Width())
+        names := make([]string, data.Width())
+        for i := range cols {
+            names[i] = data.ColumnName(i)
+            if i == r.Col {
+                cols[i] = elements
+            } else {
+                cols[i] = pick(data.At(i), rows)
+            }
+        }
+        out <- newNamedDataset(names, cols...)
+    }
+    return nil
+}
@@ -0,0 +1,64 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleStructs() {
+    users := NewStructs([]string{"name", "age"}, Strs{"bob", "alice"}, Ints{30, 25})
+    sort.Sort(users)
+    fmt.Println(users.Strings(), users.Field("age"))
+    fmt.Println(users.Type().Name())
+
+    // Output:
+    // [{name: alice, age: 25} {name: bob, age: 30}] [25 30]
+    // struct<name:string,age:int>
+}
+
+func ExampleExplode() {
+    tags := NewLists(Str, Strs{"a", "b"}, Strs{}, Strs{"c"})
+    data := NewDataset(Ints{1, 2, 3}, tags)
+    fmt.Println(tags.Strings(), tags.Type().Name())
+
+    data, err := testRun(Explode(1), data)
+    fmt.Println(data, err)
+
+    // Output:
+    // [[a b] [] [c]] list<string>
+    // [[1 1 3] [a b c]] <nil>
+}
+
+func TestListsSort(t *testing.T) {
+    lists := NewLists(Int, Ints{2}, Ints{1, 5}, Ints{1}, Ints{})
+    sort.Sort(lists)
+    require.Equal(t, []string{"[]", "[1]", "[1 5]", "[2]"}, lists.Strings())
+
+    lists = List(Int).Data(2).Append(lists.Slice(1, 2)).(Lists)
+    require.Equal(t, []string{"[]", "[]", "[1]"}, lists.Strings())
+}
+
+func TestExplodeErr(t *testing.T) {
+    _, err := testRun(Explode(0), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+
+    _, err = testRun(Explode(1), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+}
+
+// nested data is serializable, in order to be distributed
+func TestNestedGob(t *testing.T) {
+    event := NewStructs([]string{"id", "tags"}, Ints{1}, NewLists(Str, Strs{"a"}))
+    var data Data = NewDataset(event, Struct(Field{"x", Int}).Data(1))
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+    require.Equal(t, "struct<id:int,tags:list<string>>", decoded.(Dataset).At(0).Type().Name())
+}
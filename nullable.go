@@ -0,0 +1,166 @@
+package ep
+
+import (
+    "fmt"
+)
+
+var _ = registerGob(&NullableType{}, nullable{})
+
+// Nullable returns a Data of the provided values that can also carry nulls:
+// rows that are marked as false in the validity mask are null, regardless of
+// their value. It's of the NullableType of the values' type, which has the same
+// name, and its string representation of nulls is empty.
+//
+// Nulls are ordered before all other values, and are equal to one another.
+// Aggregators skip them (except for Count, which counts all rows), joins never
+// match rows with null keys, and expressions (see the expr package) over null
+// values evaluate to null.
+func Nullable(values Data, valid Bools) Data {
+    if values.Len() != len(valid) {
+        panic(fmt.Sprintf("validity mask of %d rows for %d values", len(valid), values.Len()))
+    }
+    return nullable{values, valid}
+}
+
+// IsNull returns true if the value at index i is null, either because the data
+// is of the Null type, or because it's a Nullable data with an invalid row
+func IsNull(data Data, i int) bool {
+    switch vs := data.(type) {
+    case nulls:
+        return true
+    case nullable:
+        return !vs.Valid[i]
+    }
+    return false
+}
+
+// NullMask returns a mask of the null rows of the data. See IsNull
+func NullMask(data Data) Bools {
+    mask := make(Bools, data.Len())
+    switch vs := data.(type) {
+    case nulls:
+        for i := range mask {
+            mask[i] = true
+        }
+    case nullable:
+        for i, ok := range vs.Valid {
+            mask[i] = !ok
+        }
+    }
+    return mask
+}
+
+// NullableType is the Type of Nullable data of the underlying type. Its Data
+// contains `n` nulls
+type NullableType struct { Of Type }
+func (t *NullableType) Name() string { return t.Of.Name() }
+func (t *NullableType) Data(n uint) Data {
+    return nullable{t.Of.Data(n), make(Bools, n)}
+}
+
+type nullable struct {
+    Values Data
+    Valid Bools
+}
+
+func (vs nullable) Type() Type { return &NullableType{vs.Values.Type()} }
+func (vs nullable) Len() int { return len(vs.Valid) }
+func (vs nullable) Swap(i, j int) {
+    vs.Values.Swap(i, j)
+    vs.Valid.Swap(i, j)
+}
+
+// Less orders nulls before all other values
+func (vs nullable) Less(i, j int) bool {
+    if !vs.Valid[i] || !vs.Valid[j] {
+        return !vs.Valid[i] && vs.Valid[j]
+    }
+    return vs.Values.Less(i, j)
+}
+
+func (vs nullable) Slice(s, e int) Data {
+    return nullable{vs.Values.Slice(s, e), vs.Valid[s:e]}
+}
+
+// Append another nullable data, or data of the underlying type (all valid), or
+// of the Null type (all null)
+func (vs nullable) Append(o Data) Data {
+    switch other := o.(type) {
+    case nullable:
+        return nullable{vs.Values.Append(other.Values), append(vs.Valid, other.Valid...)}
+    case nulls:
+        values := vs.Values.Append(vs.Values.Type().Data(uint(other)))
+        return nullable{values, append(vs.Valid, make(Bools, other)...)}
+    }
+
+    return vs.Append(allValid(o))
+}
+
+func (vs nullable) Strings() []string {
+    strs := vs.Values.Strings()
+    res := make([]string, len(strs))
+    for i, ok := range vs.Valid {
+        if ok {
+            res[i] = strs[i]
+        }
+    }
+    return res
+}
+
+// to-string, for debugging. Nulls are printed as <nil>
+func (vs nullable) String() string {
+    res := make([]interface{}, vs.Len())
+    for i, s := range vs.Values.Strings() {
+        if vs.Valid[i] {
+            res[i] = s
+        }
+    }
+    return fmt.Sprintf("%v", res)
+}
+
+// appendData appends b to a, like a.Append(b), except that when only b can
+// carry nulls (it's Nullable or of the Null type), a is first converted into a
+// Nullable data. This way, batches with and without nulls can be appended
+// together.
+func appendData(a, b Data) Data {
+    _, nullsA := a.(nulls)
+    _, nullsB := b.(nulls)
+    _, nullableA := a.(nullable)
+    _, nullableB := b.(nullable)
+
+    if nullsB && b.Len() == 0 {
+        return a
+    } else if nullsA && a.Len() == 0 {
+        return Clone(b)
+    } else if nullsA && !nullsB {
+        n := uint(a.Len())
+        if t, ok := b.Type().(*NullableType); ok {
+            a = t.Data(n)
+        } else {
+            a = nullable{b.Type().Data(n), make(Bools, n)}
+        }
+    } else if !nullsA && !nullableA && (nullsB || nullableB) {
+        a = allValid(a)
+    }
+    return a.Append(b)
+}
+
+// allValid returns a Nullable data of the values, without nulls
+func allValid(values Data) Data {
+    valid := make(Bools, values.Len())
+    for i := range valid {
+        valid[i] = true
+    }
+    return nullable{values, valid}
+}
+
+// nonNulls returns the data without its null rows
+func nonNulls(data Data) Data {
+    switch vs := data.(type) {
+    case nulls:
+        return nulls(0)
+    case nullable:
+        return pick(vs.Values, vs.Valid.Selection())
+    }
+    return data
+}
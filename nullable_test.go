@@ -0,0 +1,75 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleNullable() {
+    data := Nullable(Ints{3, 0, 1}, Bools{true, false, true})
+    sort.Sort(data)
+    fmt.Println(data, data.Strings(), data.Type().Name(), IsNull(data, 0))
+
+    // Output: [<nil> 1 3] [ 1 3] int true
+}
+
+// batches with and without nulls can be appended together
+func TestNullableAppend(t *testing.T) {
+    data := appendData(Ints{1}, Nullable(Ints{2, 0}, Bools{true, false}))
+    data = appendData(data, Null.Data(1))
+    data = appendData(data, Ints{4})
+    require.Equal(t, "[1 2 <nil> <nil> 4]", fmt.Sprint(data))
+    require.Equal(t, Bools{false, false, true, true, false}, NullMask(data))
+
+    data = appendData(Null.Data(2), Ints{1})
+    require.Equal(t, "[<nil> <nil> 1]", fmt.Sprint(data))
+    require.Equal(t, Int.Name(), data.Type().Name())
+
+    data = appendData(Null.Data(0), Ints{1})
+    require.Equal(t, Ints{1}, data)
+    require.Equal(t, Ints{1}, appendData(Ints{1}, Null.Data(0)))
+
+    require.Panics(t, func() { Nullable(Ints{1}, Bools{}) })
+}
+
+// nulls are skipped by the aggregators, except for Count
+func TestNullableAggregate(t *testing.T) {
+    data := NewDataset(Strs{"a", "a", "b"}, Nullable(Ints{1, 5, 2}, Bools{true, false, false}))
+    runner := GroupBy([]int{0}, Sum(1), Avg(1), Min(1), Count())
+    res, err := testRun(Pipeline(runner, Sort([]SortKey{{Col: 0}})), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[a b] [1 0] [1 <nil>] [1 <nil>] [2 1]]", fmt.Sprint(res))
+}
+
+// rows with null keys never match
+func TestNullableJoin(t *testing.T) {
+    left := NewDataset(Nullable(Ints{1, 2}, Bools{true, false}), Strs{"l1", "l2"})
+    right := NewDataset(Nullable(Ints{1, 2}, Bools{true, false}), Strs{"r1", "r2"})
+
+    res, err := testRun(Join(InnerJoin, []int{0}, []int{0}, &constRunner{left}, &constRunner{right}))
+    require.NoError(t, err)
+    require.Equal(t, "[[1] [l1] [1] [r1]]", fmt.Sprint(res))
+
+    res, err = testRun(MergeJoin(InnerJoin, []int{0}, []int{0}, &constRunner{left}, &constRunner{right}))
+    require.NoError(t, err)
+    require.Equal(t, "[[1] [l1] [1] [r1]]", fmt.Sprint(res))
+
+    res, err = testRun(Join(AntiJoin, []int{0}, []int{0}, &constRunner{left}, &constRunner{right}))
+    require.NoError(t, err)
+    require.Equal(t, "[[<nil>] [l2]]", fmt.Sprint(res))
+}
+
+// nullable data is serializable, in order to be distributed
+func TestNullableGob(t *testing.T) {
+    var data Data = NewDataset(Nullable(Strs{"a", ""}, Bools{true, false}))
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+}
@@ -0,0 +1,333 @@
+package ep
+
+// maxOptimizePasses bounds the number of passes of Optimize, in case the rules
+// keep rewriting each others' results
+const maxOptimizePasses = 100
+
+// Rules registry of the optimizer rules applied by Optimize, in order. See
+// Rule.
+var Rules = rulesReg{PushFilters, PrunePicks, MergePicks, PruneSatisfied, ChooseJoins}
+
+// Rule rewrites a single runner of a composed plan into an equivalent runner,
+// and returns true if it was rewritten. Rules are applied to all of the
+// runners of the plan, bottom-up, including the composite runners (like
+// Pipeline, Project and the joins) after their inner runners were optimized.
+// Pipelines are best rewritten by their flattened stages (see Stages), as
+// the same pipeline may be composed in different ways
+type Rule func(r Runner) (Runner, bool)
+
+// Estimator is implemented by runners that can estimate the total number of
+// rows they produce across all nodes without running, like in-memory tables.
+// The optimizer uses it for choosing join strategies. Negative estimates are
+// unknown
+type Estimator interface {
+    EstimateRows() int
+}
+
+// Optimize rewrites the plan with the registered Rules, until none of them
+// applies anymore. The rules assume that the runners have no side effects
+// other than producing their output, thus they may reorder runners, or drop
+// those whose output isn't used
+func Optimize(r Runner) Runner {
+    for i := 0; i < maxOptimizePasses; i++ {
+        var changed bool
+        r, changed = optimize(r, Rules)
+        if !changed {
+            break
+        }
+    }
+    return r
+}
+
+func optimize(r Runner, rules []Rule) (Runner, bool) {
+    changed := false
+    if c, ok := r.(composite); ok {
+        inner := c.inner()
+        rewritten := make([]Runner, len(inner))
+        for i, in := range inner {
+            var ok bool
+            rewritten[i], ok = optimize(in, rules)
+            changed = changed || ok
+        }
+
+        if changed {
+            r = c.withInner(rewritten)
+        }
+    }
+
+    for _, rule := range rules {
+        if res, ok := rule(r); ok {
+            r, changed = res, true
+        }
+    }
+    return r, changed
+}
+
+// registry of optimizer rules
+type rulesReg []Rule
+func (reg *rulesReg) Register(rule Rule) *rulesReg {
+    *reg = append(*reg, rule)
+    return reg
+}
+
+// composite is implemented by runners composed of inner runners, in order for
+// the optimizer to rewrite them. Other runners are optimized as a whole
+type composite interface {
+    inner() []Runner
+    withInner(inner []Runner) Runner
+}
+
+func (rs *pipeline) inner() []Runner { return []Runner{rs.From, rs.To} }
+func (rs *pipeline) withInner(inner []Runner) Runner {
+    return &pipeline{inner[0], inner[1]}
+}
+
+func (rs *project) inner() []Runner { return []Runner{rs.Left, rs.Right} }
+func (rs *project) withInner(inner []Runner) Runner {
+    return &project{inner[0], inner[1]}
+}
+
+func (r *join) inner() []Runner { return []Runner{r.Left, r.Right} }
+func (r *join) withInner(inner []Runner) Runner {
+    return &join{r.Type, r.LeftKeys, r.RightKeys, inner[0], inner[1]}
+}
+
+func (r *distJoin) inner() []Runner { return r.Join.inner() }
+func (r *distJoin) withInner(inner []Runner) Runner {
+    return &distJoin{r.UID, r.Join.withInner(inner).(*join), r.Threshold}
+}
+
+func (r *wrap) inner() []Runner { return []Runner{r.Runner} }
+func (r *wrap) withInner(inner []Runner) Runner {
+    return &wrap{inner[0], r.Hooks}
+}
+
+func (r *tee) inner() []Runner { return r.Consumers }
+func (r *tee) withInner(inner []Runner) Runner {
+    return &tee{inner}
+}
+
+func (r *distRunner) inner() []Runner { return []Runner{r.Runner} }
+func (r *distRunner) withInner(inner []Runner) Runner {
+    return &distRunner{inner[0], r.Addrs, r.MasterAddr, r.Job, r.Trace, r.Progress, r.d}
+}
+
+func (r *union) inner() []Runner { return r.Runners }
+func (r *union) withInner(inner []Runner) Runner {
+    return &union{r.Types, inner}
+}
+
+// Stages returns the runners of the pipeline in order, flattening nested
+// pipelines, or just the runner itself if it's not a pipeline. Pipeline() of
+// the stages returns an equivalent pipeline.
+func Stages(r Runner) []Runner {
+    p, ok := r.(*pipeline)
+    if !ok {
+        return []Runner{r}
+    }
+    return append(Stages(p.From), Stages(p.To)...)
+}
+
+// PushFilters is a Rule that moves filters below the exchanges that precede
+// them, in order to filter the rows before they're transmitted to other nodes
+func PushFilters(r Runner) (Runner, bool) {
+    stages := Stages(r)
+    changed := false
+    for i := 1; i < len(stages); i++ {
+        _, isFilter := stages[i].(*filter)
+        _, isExchange := stages[i - 1].(*exchange)
+        if isFilter && isExchange {
+            stages[i], stages[i - 1] = stages[i - 1], stages[i]
+            changed = true
+            i = 0 // the filter may move below more exchanges
+        }
+    }
+
+    if !changed {
+        return r, false
+    }
+    return Pipeline(stages...), true
+}
+
+// PrunePicks is a Rule that drops the runners of a Project whose columns are
+// not picked by the following Pick, and removes picks of all of the columns
+// in order
+func PrunePicks(r Runner) (Runner, bool) {
+    stages := Stages(r)
+    for i := 1; i < len(stages); i++ {
+        pick, ok := stages[i].(*picker)
+        if !ok {
+            continue
+        }
+
+        inp := []Type{Wildcard} // the input of the pipeline is unknown
+        if i > 1 {
+            inp = Pipeline(stages[:i - 1]...).Returns()
+        }
+
+        // identity pick
+        types := returnsFrom(stages[i - 1], inp)
+        if !hasWildcard(types) && isIdentity(pick.Cols, len(types)) {
+            stages = append(stages[:i], stages[i + 1:]...)
+            return Pipeline(stages...), true
+        }
+
+        if proj, ok := stages[i - 1].(*project); ok {
+            res, ok := prunePick(proj, pick, inp)
+            if ok {
+                stages[i - 1], stages[i] = res[0], res[1]
+                return Pipeline(stages...), true
+            }
+        }
+    }
+    return r, false
+}
+
+// prunePick returns the project and pick with only the picked runners of the
+// project, if any were pruned
+func prunePick(proj *project, pick *picker, inp []Type) ([]Runner, bool) {
+    runners := projected(proj)
+    offsets := make([]int, len(runners) + 1)
+    for i, r := range runners {
+        types := returnsFrom(r, inp)
+        if hasWildcard(types) {
+            return nil, false // unknown widths
+        }
+        offsets[i + 1] = offsets[i] + len(types)
+    }
+
+    used := make([]bool, len(runners))
+    for _, col := range pick.Cols {
+        for i := range runners {
+            if col >= offsets[i] && col < offsets[i + 1] {
+                used[i] = true
+            }
+        }
+    }
+
+    kept := []Runner{}
+    moved := make([]int, len(runners)) // offset of each kept runner
+    for i, r := range runners {
+        if used[i] {
+            moved[i] = offsets[i + 1] - offsets[i]
+            kept = append(kept, r)
+        }
+    }
+
+    if len(kept) == len(runners) || len(kept) == 0 {
+        return nil, false
+    }
+
+    for i, offset := 0, 0; i < len(runners); i++ {
+        offset, moved[i] = offset + moved[i], offset
+    }
+
+    cols := make([]int, len(pick.Cols))
+    for j, col := range pick.Cols {
+        for i := range runners {
+            if col >= offsets[i] && col < offsets[i + 1] {
+                cols[j] = col - offsets[i] + moved[i]
+            }
+        }
+    }
+    return []Runner{Project(kept...), Pick(cols...)}, true
+}
+
+// projected returns the runners of the project in order, flattening nested
+// projects
+func projected(r Runner) []Runner {
+    p, ok := r.(*project)
+    if !ok {
+        return []Runner{r}
+    }
+    return append(projected(p.Left), projected(p.Right)...)
+}
+
+func isIdentity(cols []int, width int) bool {
+    if len(cols) != width {
+        return false
+    }
+
+    for i, col := range cols {
+        if col != i {
+            return false
+        }
+    }
+    return true
+}
+
+// MergePicks is a Rule that merges adjacent picks into a single Pick
+func MergePicks(r Runner) (Runner, bool) {
+    stages := Stages(r)
+    for i := 1; i < len(stages); i++ {
+        first, ok1 := stages[i - 1].(*picker)
+        second, ok2 := stages[i].(*picker)
+        if !ok1 || !ok2 {
+            continue
+        }
+
+        cols := make([]int, len(second.Cols))
+        for j, col := range second.Cols {
+            if col < 0 || col >= len(first.Cols) {
+                return r, false // out of range, fail at runtime
+            }
+            cols[j] = first.Cols[col]
+        }
+
+        stages[i - 1] = Pick(cols...)
+        stages = append(stages[:i], stages[i + 1:]...)
+        return Pipeline(stages...), true
+    }
+    return r, false
+}
+
+// ChooseJoins is a Rule that chooses the strategy of DistributedJoins by the
+// estimated number of rows of their right side (see Estimator), instead of
+// counting them at runtime
+func ChooseJoins(r Runner) (Runner, bool) {
+    j, ok := r.(*distJoin)
+    if !ok || j.Threshold < 0 {
+        return r, false
+    }
+
+    n := estimateRows(j.Join.Right)
+    if n < 0 {
+        return r, false
+    }
+
+    threshold := alwaysPartition
+    typ := j.Join.Type
+    if n <= j.Threshold && typ != RightJoin && typ != FullJoin {
+        threshold = alwaysBroadcast
+    }
+    return &distJoin{j.UID, j.Join, threshold}, true
+}
+
+// estimateRows returns the estimated total number of rows produced by the
+// runner across all nodes, or -1 if it's unknown
+func estimateRows(r Runner) int {
+    switch r := r.(type) {
+    case Estimator:
+        return r.EstimateRows()
+    case *pipeline:
+        switch to := r.To.(type) {
+        case *filter, *limit, *picker, *rename, *dropper:
+            return estimateRows(r.From) // at most
+        case *exchange:
+            if to.SendTo != sendBroadcast {
+                return estimateRows(r.From)
+            }
+        }
+    case *union:
+        total := 0
+        for _, r := range r.Runners {
+            n := estimateRows(r)
+            if n < 0 {
+                return -1
+            }
+            total += n
+        }
+        return total
+    }
+    return -1
+}
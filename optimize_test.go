@@ -0,0 +1,83 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleOptimize() {
+    runner := Pipeline(
+        Project(Pick(0), Pick(1), Pick(1, 0)),
+        Pick(3, 0),
+        Gather(),
+        Filter(Where(0, "!=", "b")),
+    )
+
+    optimized := Optimize(runner)
+    data := NewDataset(Strs{"a", "b", "c"}, Strs{"1", "2", "3"})
+    res, err := testRun(optimized, data)
+    fmt.Println(len(Stages(optimized)), res, err)
+
+    // Output: 4 [[a c] [a c]] <nil>
+}
+
+func TestPushFilters(t *testing.T) {
+    runner := Pipeline(Pick(0), Gather(), Repartition(0), Filter(Where(0, "=", "a")))
+    stages := Stages(Optimize(runner))
+    require.Equal(t, 4, len(stages))
+    require.IsType(t, &filter{}, stages[1])
+    require.IsType(t, &exchange{}, stages[3])
+
+    _, ok := PushFilters(Filter(Where(0, "=", "a")))
+    require.False(t, ok)
+}
+
+func TestPrunePicks(t *testing.T) {
+    runner := Pipeline(Project(Pick(0), Pick(1), Pick(1, 0)), Pick(3, 0))
+    optimized := Optimize(runner)
+
+    stages := Stages(optimized)
+    require.Equal(t, 2, len(stages))
+    require.Equal(t, 2, len(projected(stages[0])))
+    require.Equal(t, []int{2, 0}, stages[1].(*picker).Cols)
+
+    data := NewDataset(Strs{"a", "b"}, Strs{"1", "2"})
+    expected, err := testRun(runner, data)
+    require.NoError(t, err)
+
+    res, err := testRun(optimized, data)
+    require.NoError(t, err)
+    require.Equal(t, fmt.Sprint(expected), fmt.Sprint(res))
+
+    // identity picks are removed
+    optimized = Optimize(Pipeline(Pick(1, 0), Pick(0, 1)))
+    require.Equal(t, []int{1, 0}, optimized.(*picker).Cols)
+
+    // unknown widths
+    runner = Pipeline(Project(Pick(0), PassThrough()), Pick(0))
+    require.Equal(t, runner, Optimize(runner))
+}
+
+func TestMergePicks(t *testing.T) {
+    optimized := Optimize(Pipeline(PassThrough(), Pick(2, 0, 1), Pick(2, 0)))
+    stages := Stages(optimized)
+    require.Equal(t, 2, len(stages))
+    require.Equal(t, []int{1, 2}, stages[1].(*picker).Cols)
+}
+
+func TestChooseJoins(t *testing.T) {
+    right := &scanData{NewDataset(Strs{"a", "b"})}
+    runner := DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), right, 2)
+    require.Equal(t, alwaysBroadcast, Optimize(runner).(*distJoin).Threshold)
+
+    runner = DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), Pipeline(right, Gather()), 1)
+    require.Equal(t, alwaysPartition, Optimize(runner).(*distJoin).Threshold)
+
+    runner = DistributedJoin(FullJoin, []int{0}, []int{0}, PassThrough(), right, 2)
+    require.Equal(t, alwaysPartition, Optimize(runner).(*distJoin).Threshold)
+
+    // unknown sizes
+    runner = DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), PassThrough(), 2)
+    require.Equal(t, 2, Optimize(runner).(*distJoin).Threshold)
+}
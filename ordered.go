@@ -0,0 +1,137 @@
+package ep
+
+import (
+    "io"
+    "math"
+    "context"
+    "github.com/satori/go.uuid"
+)
+
+// ScatterOrdered returns an exchange Runner like Scatter, that also appends a
+// column of sequence numbers to the rows of its input, in order for the
+// GatherOrdered that follows it to restore their input order. The runners in
+// between must keep the last column as is, and emit their output in the order
+// of their input, like Filter. Only the order of the input of a single node is
+// restored, thus the input should originate from a single node (e.g. the
+// master). It's useful for pipelines whose output must preserve the order of
+// their input.
+func ScatterOrdered() Runner {
+    return &exchange{UID: uuid.NewV4().String(), SendTo: sendScatter, Sequenced: true}
+}
+
+// GatherOrdered returns an exchange Runner like Gather, that merges the rows
+// received from all nodes by their sequence numbers assigned by ScatterOrdered,
+// which are then removed. See ScatterOrdered
+func GatherOrdered() Runner {
+    return &exchange{UID: uuid.NewV4().String(), SendTo: sendGather, Sequenced: true}
+}
+
+// sequenced returns a channel of the input datasets, with an additional column
+// of the sequence numbers of their rows. The sequence numbers are ordered by
+// the index of this node, and then by the position of the row in the input.
+func sequenced(ctx context.Context, inp chan Dataset) chan Dataset {
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    next := int64(0)
+    for i, node := range allNodes {
+        if node == thisNode {
+            next = int64(i) << 40
+        }
+    }
+
+    res := newChan(ctx)
+    go func() {
+        defer close(res)
+        for data := range inp {
+            seq := make(Ints, data.Len())
+            for i := range seq {
+                seq[i] = next
+                next++
+            }
+
+            cols := columns(data)
+            cols = append(cols[:len(cols):len(cols)], seq)
+            if names := namesOf(data); names != nil {
+                res <- newNamedDataset(append(names[:len(names):len(names)], ""), cols...)
+            } else {
+                res <- NewDataset(cols...)
+            }
+        }
+    }()
+    return res
+}
+
+// unsequenced returns the dataset without its last column of sequence numbers
+func unsequenced(data Dataset) Dataset {
+    w := data.Width() - 1
+    if names := namesOf(data); names != nil {
+        return newNamedDataset(names[:w], columns(data)[:w]...)
+    }
+    return NewDataset(columns(data)[:w]...)
+}
+
+// receiveSequenced receives the remote data from the source nodes into the
+// output, like receiveAll, while merging the sources by their sequence numbers.
+// The rows of every source are assumed to be ordered by their sequence numbers.
+func (ex *exchange) receiveSequenced(ctx context.Context, out chan Dataset) error {
+    n := len(ex.decs)
+    heads := make([]Dataset, n) // the current dataset of every source
+    pos := make([]int, n) // the next row of every source's dataset
+    done := make([]bool, n)
+
+    seqAt := func(i int) int64 {
+        seq := heads[i].At(heads[i].Width() - 1).(Ints)
+        return seq[pos[i]]
+    }
+
+    for {
+        // read the next dataset of every source that was exhausted
+        for i := range heads {
+            for !done[i] && (heads[i] == nil || pos[i] >= heads[i].Len()) {
+                data, err := ex.decodeFrom(i)
+                if err == io.EOF {
+                    done[i] = true
+                } else if err != nil {
+                    return err
+                }
+                heads[i], pos[i] = data, 0
+            }
+        }
+
+        // the source of the lowest sequence number emits its rows up to the
+        // lowest sequence number of the other sources
+        first, bound := -1, int64(math.MaxInt64)
+        for i := range heads {
+            if done[i] {
+                continue
+            } else if first < 0 || seqAt(i) < seqAt(first) {
+                if first >= 0 {
+                    bound = seqAt(first)
+                }
+                first = i
+            } else if seqAt(i) < bound {
+                bound = seqAt(i)
+            }
+        }
+
+        if first < 0 {
+            return nil
+        }
+
+        end := pos[first]
+        seq := heads[first].At(heads[first].Width() - 1).(Ints)
+        for end < len(seq) && seq[end] < bound {
+            end++
+        }
+
+        data := unsequenced(Slice(heads[first], pos[first], end))
+        pos[first] = end
+
+        Metrics.ExchangeQueue.Add(1)
+        select {
+        case out <- data:
+        case <- ctx.Done():
+        }
+        Metrics.ExchangeQueue.Add(-1)
+    }
+}
@@ -0,0 +1,59 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleScatterOrdered() {
+    runner := Pipeline(ScatterOrdered(), Filter(Where(0, "!=", "b")), GatherOrdered())
+    fmt.Print(Explain(runner))
+
+    data, err := testRun(runner, NewDataset(Strs{"a", "b", "c"}))
+    fmt.Println(data, err)
+
+    // Output:
+    // Pipeline returns=[*]
+    //   Exchange ordered mode=scatter returns=[* int]
+    //   Filter $0 != "b" returns=[* int]
+    //   Exchange ordered mode=gather returns=[*]
+    // [[a c]] <nil>
+}
+
+// Tests that the input order is restored, even when the nodes complete in a
+// different order
+func TestScatterOrderedDistributed(t *testing.T) {
+    dists := testCluster(t, 3)
+
+    runner := Pipeline(ScatterOrdered(), &slowNode{":5552"}, Filter(Where(0, "!=", "3")), GatherOrdered())
+    runner = dists[0].Distribute(runner, ":5551", ":5552", ":5553")
+
+    inp := []Dataset{}
+    expected := []string{}
+    for i := 0; i < 10; i++ {
+        a, b := fmt.Sprint(2 * i), fmt.Sprint(2 * i + 1)
+        inp = append(inp, WithSchema(NewDataset(Strs{a, b}), Schema{{"v", Str}}))
+        if a != "3" {
+            expected = append(expected, a)
+        }
+        if b != "3" {
+            expected = append(expected, b)
+        }
+    }
+
+    data, err := testRun(runner, inp...)
+    require.NoError(t, err)
+    require.Equal(t, 1, data.Width())
+    require.Equal(t, "v", data.ColumnName(0))
+    require.Equal(t, expected, data.At(0).Strings())
+}
+
+func TestScatterOrderedExplain(t *testing.T) {
+    runner := Pipeline(ScatterOrdered(), PassThrough())
+    require.Equal(t, []Type{Wildcard, Int}, runner.Returns())
+
+    plan := Explain(Pipeline(runner, GatherOrdered()))
+    require.Equal(t, []Type{Wildcard}, plan.Returns)
+    require.Equal(t, "ordered", plan.Children[0].Detail)
+}
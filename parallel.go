@@ -0,0 +1,92 @@
+package ep
+
+import (
+    "sync"
+    "context"
+)
+
+var _ = registerGob(&parallel{})
+
+// Parallel returns a composite Runner that runs n instances of a runner
+// concurrently on the same node, created by the factory function. The input
+// datasets are dispatched among the instances in a round-robin fashion, and
+// their outputs are merged into a single stream, in no particular order. It's
+// useful for CPU-heavy runners, in order to use all of the cores without
+// distributing them. The instances are created upfront, thus the returned
+// Runner can be distributed as long as the instances are serializable.
+func Parallel(n int, factory func() Runner) Runner {
+    if n < 1 {
+        panic("at least 1 instance is required for parallel")
+    }
+
+    runners := make([]Runner, n)
+    for i := range runners {
+        runners[i] = factory()
+    }
+    return &parallel{runners}
+}
+
+type parallel struct { Runners []Runner }
+
+func (r *parallel) Returns() []Type { return r.Runners[0].Returns() }
+func (r *parallel) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    // cancel all instances upon the first error
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var l sync.Mutex
+    var wg sync.WaitGroup
+    inputs := make([]chan Dataset, len(r.Runners))
+    for i := range r.Runners {
+        inputs[i] = newChan(ctx)
+        outputs := newChan(ctx)
+
+        // merge the output
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for data := range outputs {
+                out <- data
+            }
+        }()
+
+        go func(i int) {
+            err1 := runSafe(ctx, r.Runners[i], inputs[i], outputs)
+            close(outputs)
+            if err1 != nil {
+                l.Lock()
+                if err == nil {
+                    err = err1
+                }
+                l.Unlock()
+                cancel()
+            }
+
+            // drain the input in case the instance has exited early, otherwise
+            // dispatching the input to the rest of the instances would block
+            for _ = range inputs[i] {}
+        }(i)
+    }
+
+    // dispatch the input among the instances
+    go func() {
+        i := 0
+        for data := range inp {
+            if ctx.Err() != nil {
+                break
+            }
+
+            inputs[i] <- data
+            i = (i + 1) % len(inputs)
+        }
+
+        for _, s := range inputs {
+            close(s)
+        }
+    }()
+
+    wg.Wait()
+    l.Lock()
+    defer l.Unlock()
+    return err
+}
@@ -0,0 +1,53 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleParallel() {
+    runner := Parallel(4, func() Runner { return &Upper{} })
+    data, err := testRun(runner, NewDataset(Strs{"hello"}), NewDataset(Strs{"world"}))
+    fmt.Println(data.Len(), err)
+
+    // Output: 2 <nil>
+}
+
+// Test that all of the instances are run, and their outputs are merged
+func TestParallel(t *testing.T) {
+    inputs := []Dataset{}
+    for i := 0; i < 8; i++ {
+        inputs = append(inputs, NewDataset(Strs{fmt.Sprintf("%d", i)}))
+    }
+
+    var runners []*counter
+    runner := Parallel(4, func() Runner {
+        c := &counter{}
+        runners = append(runners, c)
+        return Pipeline(PassThrough(), c)
+    })
+
+    data, err := testRun(runner, inputs...)
+    require.NoError(t, err)
+    require.Equal(t, 12, data.Len()) // every counter emits 3 rows
+    for _, c := range runners {
+        require.Equal(t, 1, c.Runs)
+    }
+}
+
+func TestParallelErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    infinity := &InfinityRunner{}
+    runners := []Runner{infinity, &ErrRunner{err}}
+    runner := Parallel(2, func() Runner {
+        r := runners[0]
+        runners = runners[1:]
+        return r
+    })
+
+    _, err = testRun(runner, NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
@@ -0,0 +1,140 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+)
+
+var _ = registerGob(&picker{}, &rename{}, &dropper{})
+
+// Pick returns a Runner that emits only the provided columns of its input, in
+// the provided order, for pruning and reordering columns. Columns may be
+// picked more than once. When pipelined, its return types are resolved from
+// the types of the previous runner.
+func Pick(cols ...int) Runner {
+    return &picker{cols}
+}
+
+// Rename returns a passthrough Runner that renames the columns named `old` in
+// the return types of the previous runner to `new`. The names are assigned to
+// the types using As(). Named columns of the datasets themselves are renamed
+// as well (see Dataset.Schema).
+func Rename(old, new string) Runner {
+    return &rename{old, new}
+}
+
+// Drop returns a Runner that emits all of the columns of its input except the
+// provided columns, in order, like the non-key columns of a join. Its return
+// types are WildcardExcept(cols...).
+func Drop(cols ...int) Runner {
+    return &dropper{cols}
+}
+
+type picker struct { Cols []int }
+
+// Returns a type per picked column. The actual types depend on the input, and
+// thus are unknown, unless resolved by a Pipeline.
+func (r *picker) Returns() []Type {
+    types := []Type{}
+    for _ = range r.Cols {
+        types = append(types, Any)
+    }
+    return types
+}
+
+func (r *picker) returnsFrom(inp []Type) []Type {
+    if hasWildcard(inp) {
+        return r.Returns() // positions are unknown
+    }
+
+    types := []Type{}
+    for _, col := range r.Cols {
+        if col < 0 || col >= len(inp) {
+            types = append(types, Any) // invalid, see Validate
+        } else {
+            types = append(types, inp[col])
+        }
+    }
+    return types
+}
+
+func (r *picker) Run(ctx context.Context, inp, out chan Dataset) error {
+    return RunIter(ctx, r, inp, out)
+}
+
+func (r *picker) Iter(ctx context.Context, inp Iterator) Iterator {
+    return MapBatches(inp, func(data Dataset) (Dataset, error) {
+        cols := []Data{}
+        names := []string{}
+        for _, col := range r.Cols {
+            if col < 0 || col >= data.Width() {
+                return nil, fmt.Errorf("column %d out of range %d", col, data.Width())
+            }
+            cols = append(cols, data.At(col))
+            names = append(names, data.ColumnName(col))
+        }
+        return newNamedDataset(names, cols...), nil
+    })
+}
+
+type rename struct { Old, New string }
+func (*rename) Returns() []Type { return []Type{Wildcard} }
+func (r *rename) returnsFrom(inp []Type) []Type {
+    types := []Type{}
+    for _, t := range inp {
+        if named, ok := t.(interface { As() string }); ok && named.As() == r.Old {
+            if as, ok := t.(*asType); ok {
+                t = as.Type // avoid nesting the names
+            }
+            t = As(t, r.New)
+        }
+        types = append(types, t)
+    }
+    return types
+}
+
+func (r *rename) Run(ctx context.Context, inp, out chan Dataset) error {
+    return RunIter(ctx, r, inp, out)
+}
+
+func (r *rename) Iter(ctx context.Context, inp Iterator) Iterator {
+    return MapBatches(inp, func(data Dataset) (Dataset, error) {
+        names := namesOf(data)
+        if names != nil {
+            renamed := make([]string, len(names))
+            for i, name := range names {
+                if name == r.Old {
+                    name = r.New
+                }
+                renamed[i] = name
+            }
+            data = newNamedDataset(renamed, columns(data)...)
+        }
+        return data, nil
+    })
+}
+
+type dropper struct { Cols []int }
+func (r *dropper) Returns() []Type { return []Type{WildcardExcept(r.Cols...)} }
+func (r *dropper) Run(ctx context.Context, inp, out chan Dataset) error {
+    return RunIter(ctx, r, inp, out)
+}
+
+func (r *dropper) Iter(ctx context.Context, inp Iterator) Iterator {
+    dropped := map[int]bool{}
+    for _, col := range r.Cols {
+        dropped[col] = true
+    }
+
+    return MapBatches(inp, func(data Dataset) (Dataset, error) {
+        cols := []Data{}
+        names := []string{}
+        for i := 0; i < data.Width(); i++ {
+            if !dropped[i] {
+                cols = append(cols, data.At(i))
+                names = append(names, data.ColumnName(i))
+            }
+        }
+        return newNamedDataset(names, cols...), nil
+    })
+}
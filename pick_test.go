@@ -0,0 +1,96 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExamplePick() {
+    runner := Pick(1, 0)
+    data, err := testRun(runner, NewDataset(Strs{"1", "2"}, Strs{"alice", "bob"}, Strs{"x", "y"}))
+    fmt.Println(data, err)
+
+    // Output: [[alice bob] [1 2]] <nil>
+}
+
+func TestPickReturns(t *testing.T) {
+    require.Equal(t, []Type{Any, Any}, Pick(1, 0).Returns())
+
+    typed := &constRunner{NewDataset(Strs{}, Null.Data(0))}
+    runner := Pipeline(typed, Pick(1, 0, 1))
+    require.Equal(t, []Type{Null, Str, Null}, runner.Returns())
+
+    // unknown positions
+    runner = Pipeline(PassThrough(), Pick(0))
+    require.Equal(t, []Type{Any}, runner.Returns())
+}
+
+func TestPickErr(t *testing.T) {
+    _, err := testRun(Pick(2), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Equal(t, "column 2 out of range 1", err.Error())
+
+    err = Validate(Pipeline(users, Pick(2)))
+    require.Error(t, err)
+}
+
+func TestRename(t *testing.T) {
+    named := Map([]Type{As(Str, "id"), As(Str, "name")}, func(data Dataset) (Dataset, error) {
+        return data, nil
+    })
+
+    runner := Pipeline(named, Rename("name", "user"))
+    types := runner.Returns()
+    require.Equal(t, 2, len(types))
+    require.Equal(t, "id", types[0].(interface { As() string }).As())
+    require.Equal(t, "user", types[1].(interface { As() string }).As())
+    require.Equal(t, Str.Name(), types[1].Name())
+
+    data, err := testRun(runner, NewDataset(Strs{"1"}, Strs{"alice"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[1] [alice]]", fmt.Sprintf("%v", data))
+}
+
+func ExampleDrop() {
+    runner := Drop(0, 2)
+    data, err := testRun(runner, NewDataset(Strs{"1", "2"}, Strs{"alice", "bob"}, Strs{"x", "y"}))
+    fmt.Println(data, err)
+
+    // Output: [[alice bob]] <nil>
+}
+
+// Tests that the types of all of the columns except the dropped ones are
+// resolved, and pass validation within projections
+func TestDropReturns(t *testing.T) {
+    require.Equal(t, "*-[0]", Drop(0).Returns()[0].Name())
+
+    typed := &constRunner{NewDataset(Ints{}, Strs{}, Strs{})}
+    runner := Pipeline(typed, Project(Drop(0), Pick(0)))
+    require.Equal(t, []Type{Str, Str, Int}, runner.Returns())
+
+    require.NoError(t, Validate(Pipeline(typed, Drop(0), &strArgs{})))
+    require.Error(t, Validate(Pipeline(typed, Drop(1), &strArgs{})))
+    require.Error(t, Validate(Pipeline(typed, Drop(3))))
+
+    // unknown positions
+    runner = Pipeline(PassThrough(), Drop(0))
+    require.Equal(t, []Type{WildcardExcept(0)}, runner.Returns())
+    require.NoError(t, Validate(Pipeline(runner, Sort([]SortKey{{Col: 5}}))))
+}
+
+func TestWildcardAt(t *testing.T) {
+    at := Map([]Type{WildcardAt(1), Int}, func(data Dataset) (Dataset, error) {
+        return data, nil
+    })
+
+    typed := &constRunner{NewDataset(Ints{}, Strs{})}
+    require.Equal(t, []Type{Str, Int}, Pipeline(typed, at).Returns())
+    require.Equal(t, []Type{WildcardAt(1), Int}, Pipeline(PassThrough(), at).Returns())
+
+    // unresolved, it's an unknown type
+    require.NoError(t, Validate(&strArgs{}, WildcardAt(1), Str))
+    require.True(t, Compatible(WildcardAt(0), Int))
+    require.False(t, Compatible(WildcardExcept(0), Wildcard))
+    require.True(t, Compatible(WildcardExcept(0), WildcardExcept(0)))
+}
@@ -22,30 +22,55 @@ func Pipeline(runners ...Runner) Runner {
 }
 
 type pipeline struct { From Runner; To Runner }
-func (rs *pipeline) Run(ctx context.Context, inp, out chan Dataset) (err error) {
-    // choose the error out from the From and To errors.
-    var err1 error
-    defer func() { if err == nil && err1 != nil { err = err1 } }()
+
+// Iter chains the iterators of the stages. See IterRunner
+func (rs *pipeline) Iter(ctx context.Context, inp Iterator) Iterator {
+    return Iter(ctx, rs.To, Iter(ctx, rs.From, inp))
+}
+
+func (rs *pipeline) Run(ctx context.Context, inp, out chan Dataset) error {
+    // pipelines of IterRunners are pulled in this go-routine
+    if iterable(rs) {
+        return RunIter(ctx, rs, inp, out)
+    }
 
     // middle chan is the output from the From runner and the input to the To
-    // Runner. Drain it until the go-routine has exited. Usually this will be a
+    // runner. The error of the From runner doesn't cancel the To runner, which
+    // completes with the partial input, thus its own error is preferred. If the
+    // From runner was canceled by us because the To runner has finished early
+    // (like a LIMIT), the cancellation isn't an error.
+    var err1 error
+    parent := ctx
+    g, ctx := NewGroup(ctx)
+    middle := newChan(ctx)
+    g.Go(func() error {
+        defer close(middle)
+        err1 = rs.From.Run(ctx, inp, middle)
+        return nil
+    })
+
+    // cancel the From runner when we're done - just in case it's still running,
+    // and drain the middle chan until it has exited. Usually this will be a
     // no-op, but other times there might still be left overs in the channel.
     // This can happen if the top (To) runner has exited early due to an error
     // or some other logic (LIMIT?). This prevents leaking go-routines
-    middle := make(chan Dataset)
-    defer func() { for _ = range middle {} }()
-
-    // cancel the From runner when we're done - just in case it's still running.
-    ctx, cancel := context.WithCancel(ctx)
-    defer cancel()
+    g.Go(func() error {
+        defer func() {
+            g.Cancel()
+            for _ = range middle {}
+        }()
+        return rs.To.Run(ctx, middle, out)
+    })
 
-    // start the From runner, writing data into the middle chan
-    go func() {
-        defer close(middle)
-        err1 = rs.From.Run(ctx, inp, middle)
-    }()
+    err := g.Wait()
+    if err1 == context.Canceled && parent.Err() == nil {
+        err1 = nil
+    }
 
-    return rs.To.Run(ctx, middle, out)
+    if err == nil {
+        err = err1
+    }
+    return err
 }
 
 // The implementation isn't trivial because it has to account for Wildcard types
@@ -55,18 +80,25 @@ func (rs *pipeline) Run(ctx context.Context, inp, out chan Dataset) (err error)
 // calling this function recursively).
 // see Runner & Wildcard
 func (rs *pipeline) Returns() []Type {
-    res := rs.To.Returns()
+    return returnsFrom(rs.To, rs.From.Returns())
+}
 
-    // check for wildcards, and replace as needed. Walk backwards to allow
-    // adding types in-place without messing the iteration
-    for i := len(res) - 1; i >= 0; i-- {
-        if res[i] == Wildcard {
-            // wildcard found - replace it with the types from the previous
-            // runner (which might also contain Wildcards)
-            prev := rs.From.Returns()
-            res = append(res[:i], append(prev, res[i+1:]...)...)
-        }
+// returnsFrom returns the return types of the runner given its input types,
+// either resolved by the runner itself (see typesResolver), or by replacing
+// its wildcards with the input types (which might also contain Wildcards). A
+// new slice is returned, as the runners may return their internal slices,
+// which must not be modified in-place
+func returnsFrom(r Runner, inp []Type) []Type {
+    if r, ok := r.(typesResolver); ok {
+        return r.returnsFrom(inp)
     }
+    return resolveWildcards(r.Returns(), inp)
+}
 
-    return res
+// typesResolver is implemented by runners whose return types depend on their
+// input types in a way that can't be expressed by a Wildcard, like a subset of
+// the input columns. Pipelines use it to resolve the return types from the
+// types of the previous runner.
+type typesResolver interface {
+    returnsFrom(inp []Type) []Type
 }
@@ -2,6 +2,7 @@ package ep
 
 import (
     "fmt"
+    "context"
     "testing"
     "github.com/stretchr/testify/require"
 )
@@ -39,3 +40,21 @@ func TestPipelineErr(t *testing.T) {
     require.Equal(t, "something bad happened", err.Error())
     require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
 }
+
+// test that Wildcards are resolved from the previous runners, without
+// modifying the types of the inner runners
+func TestPipelineReturns(t *testing.T) {
+    types := []Type{Wildcard, Str}
+    last := &fixedReturns{types}
+    runner := Pipeline(&Upper{}, PassThrough(), last)
+
+    require.Equal(t, []Type{Str, Str}, runner.Returns())
+    require.Equal(t, []Type{Wildcard, Str}, types)
+}
+
+// fixedReturns is a passthrough Runner that returns a fixed slice of types
+type fixedReturns struct { Types []Type }
+func (r *fixedReturns) Returns() []Type { return r.Types }
+func (r *fixedReturns) Run(ctx context.Context, inp, out chan Dataset) error {
+    return PassThrough().Run(ctx, inp, out)
+}
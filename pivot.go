@@ -0,0 +1,161 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&pivot{}, &unpivot{})
+
+// Pivot returns a Runner that reshapes its input from a long format into a
+// wide format: rows are grouped by the values of the `groupBy` columns, and
+// each group produces a single row with a column per pivot value. The value of
+// each such column is taken from the `valueCol` of the row in the group whose
+// `keyCol` equals the pivot value, or empty if there's no such row. Rows with
+// other keys are ignored. The output contains the groupBy columns, followed by
+// the pivoted columns.
+//
+// Like GroupBy, when distributed the input is first repartitioned by the
+// groupBy columns, such that each group is pivoted on a single node.
+func Pivot(groupBy []int, keyCol, valueCol int, pivotValues ...string) Runner {
+    return Pipeline(
+        Repartition(groupBy...),
+        &pivot{groupBy, keyCol, valueCol, pivotValues},
+    )
+}
+
+// Unpivot returns a Runner that reshapes its input from a wide format into a
+// long format, the reverse of Pivot: every input row produces a row per
+// unpivoted column, containing the `ids` columns followed by the name of the
+// unpivoted column and its value. The names of the unpivoted columns are
+// provided in the same order as the columns.
+func Unpivot(ids []int, columns []int, names []string) Runner {
+    if len(columns) != len(names) {
+        panic("unpivot requires a name per column")
+    }
+
+    return &unpivot{ids, columns, names}
+}
+
+type pivot struct {
+    GroupBy []int
+    KeyCol int
+    ValueCol int
+    Values []string
+}
+
+// Returns the groupBy columns, followed by the pivoted columns. The types of
+// the groupBy columns depend on the input, and thus are unknown.
+func (r *pivot) Returns() []Type {
+    types := []Type{}
+    for _ = range r.GroupBy {
+        types = append(types, Any)
+    }
+
+    for _ = range r.Values {
+        types = append(types, Str)
+    }
+    return types
+}
+
+func (r *pivot) Run(ctx context.Context, inp, out chan Dataset) error {
+    index := map[string]int{} // position of every pivot value
+    for i, v := range r.Values {
+        index[v] = i
+    }
+
+    groups := map[string]int{} // group index by key
+    var ids Dataset // first row of every group
+    var values []Strs // pivoted values by pivot position
+    for _ = range r.Values {
+        values = append(values, Strs{})
+    }
+
+    for data := range inp {
+        if data.Len() == 0 {
+            continue
+        }
+
+        keys := data.At(r.KeyCol).Strings()
+        vals := data.At(r.ValueCol).Strings()
+        for i, k := range rowKeys(data, r.GroupBy) {
+            g, ok := groups[k]
+            if !ok {
+                g = len(groups)
+                groups[k] = g
+                ids = appendClone(ids, pick(data, []int{i}).(Dataset))
+                for j := range values {
+                    values[j] = append(values[j], "")
+                }
+            }
+
+            if j, ok := index[keys[i]]; ok {
+                values[j][g] = vals[i]
+            }
+        }
+    }
+
+    if ids == nil {
+        return nil
+    }
+
+    cols := []Data{}
+    for _, col := range r.GroupBy {
+        cols = append(cols, ids.At(col))
+    }
+
+    for _, v := range values {
+        cols = append(cols, v)
+    }
+
+    out <- NewDataset(cols...)
+    return nil
+}
+
+type unpivot struct {
+    Ids []int
+    Columns []int
+    Names []string
+}
+
+// Returns the ids columns, followed by the name and value columns. The types
+// of the ids columns depend on the input, and thus are unknown.
+func (r *unpivot) Returns() []Type {
+    types := []Type{}
+    for _ = range r.Ids {
+        types = append(types, Any)
+    }
+    return append(types, Str, Str)
+}
+
+func (r *unpivot) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        if data.Len() == 0 {
+            continue
+        }
+
+        // every row is repeated once per column
+        rows := []int{}
+        names := Strs{}
+        values := Strs{}
+        strs := make([][]string, len(r.Columns))
+        for j, col := range r.Columns {
+            strs[j] = data.At(col).Strings()
+        }
+
+        for i := 0; i < data.Len(); i++ {
+            for j := range r.Columns {
+                rows = append(rows, i)
+                names = append(names, r.Names[j])
+                values = append(values, strs[j][i])
+            }
+        }
+
+        cols := []Data{}
+        for _, col := range r.Ids {
+            cols = append(cols, pick(data.At(col), rows))
+        }
+
+        out <- NewDataset(append(cols, names, values)...)
+    }
+    return nil
+}
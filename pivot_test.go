@@ -0,0 +1,44 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExamplePivot() {
+    runner := Pivot([]int{0}, 1, 2, "jan", "feb")
+    data1 := NewDataset(Strs{"us", "us", "uk"}, Strs{"jan", "feb", "feb"}, Strs{"1", "2", "3"})
+    data2 := NewDataset(Strs{"uk", "fr"}, Strs{"jan", "mar"}, Strs{"4", "5"})
+    data, err := testRun(runner, data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[us uk fr] [1 4 ] [2 3 ]] <nil>
+}
+
+func ExampleUnpivot() {
+    runner := Unpivot([]int{0}, []int{1, 2}, []string{"jan", "feb"})
+    data, err := testRun(runner, NewDataset(Strs{"us", "uk"}, Strs{"1", "4"}, Strs{"2", "3"}))
+    fmt.Println(data, err)
+
+    // Output: [[us us uk uk] [jan feb jan feb] [1 2 4 3]] <nil>
+}
+
+// unpivoting the pivoted rows restores the original rows
+func TestPivotUnpivot(t *testing.T) {
+    inp := NewDataset(Strs{"a", "a", "b", "b"}, Strs{"x", "y", "x", "y"}, Strs{"1", "2", "3", "4"})
+    runner := Pipeline(
+        Pivot([]int{0}, 1, 2, "x", "y"),
+        Unpivot([]int{0}, []int{1, 2}, []string{"x", "y"}),
+    )
+
+    data, err := testRun(runner, inp)
+    require.NoError(t, err)
+    require.Equal(t, rowStrings(inp), rowStrings(data))
+}
+
+func TestUnpivotMismatch(t *testing.T) {
+    require.Panics(t, func() {
+        Unpivot([]int{0}, []int{1, 2}, []string{"x"})
+    })
+}
@@ -12,6 +12,9 @@ var Runners = make(runnersReg)
 // Types registry. See Registeries in the main doc.
 var Types = make(typesReg)
 
+// Aggregators registry. See Registeries in the main doc.
+var Aggregators = make(aggregatorsReg)
+
 // Plan a new Runner marked by an arbitrary argument that must've been
 // preregistered using the `Runners.Register()` function. if the arg is a
 // struct, it's first converted into a string by reflecting its full type name
@@ -67,6 +70,24 @@ func (reg typesReg) Get(k interface{}) []Type {
     return reg[registryKey(k)]
 }
 
+// registry of aggregators. Also registers the aggregator's state type, as
+// it's transmitted between the aggregation phases
+type aggregatorsReg map[interface{}][]Aggregator
+func (reg aggregatorsReg) Register(k interface{}, agg Aggregator) aggregatorsReg {
+    registerGob(agg)
+    if state := agg.InitState(); state != nil {
+        registerGob(state)
+    }
+
+    k = registryKey(k)
+    reg[k] = append(reg[k], agg)
+    return reg
+}
+
+func (reg aggregatorsReg) Get(k interface{}) []Aggregator {
+    return reg[registryKey(k)]
+}
+
 // Converts a key interface to a registry key according to the convention
 // mentioned in the Registries doc. if the key is a struct, it's first converted
 // into a string by reflecting its full type name and path
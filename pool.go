@@ -0,0 +1,97 @@
+package ep
+
+import (
+    "sync"
+    "reflect"
+    "math/bits"
+)
+
+// maximum capacity class of pooled buffers. Larger buffers aren't pooled
+const maxPoolClass = 24
+
+// pools of buffers by element type and capacity class
+var pools sync.Map // poolKey -> *sync.Pool
+
+type poolKey struct {
+    Type reflect.Type
+    Class int
+}
+
+// GetBuffer returns a zeroed buffer of n elements from the pool, or allocates
+// a new one if there's none available. Buffers are pooled in capacity classes
+// of powers of 2, thus its capacity may be larger than n. Return it to the
+// pool with PutBuffer or Release when it's no longer referenced.
+func GetBuffer[T any](n int) []T {
+    class := bits.Len(uint(n - 1))
+    if n <= 0 || class > maxPoolClass {
+        return make([]T, n)
+    }
+
+    if buf, ok := poolOf[T](class).Get().([]T); ok {
+        return buf[:n]
+    }
+    return make([]T, n, 1 << uint(class))
+}
+
+// PutBuffer returns a buffer to the pool for reuse by subsequent calls to
+// GetBuffer. The buffer, and any slice of it, must not be used by the caller
+// after it's returned. Its contents are zeroed, in order to not retain any
+// references.
+func PutBuffer[T any](buf []T) {
+    if cap(buf) == 0 {
+        return
+    }
+
+    // the largest class that fits within the capacity
+    class := bits.Len(uint(cap(buf))) - 1
+    if class > maxPoolClass {
+        return
+    }
+
+    size := 1 << uint(class)
+    buf = buf[:size:size]
+    var zero T
+    for i := range buf {
+        buf[i] = zero
+    }
+    poolOf[T](class).Put(buf[:0])
+}
+
+func poolOf[T any](class int) *sync.Pool {
+    key := poolKey{reflect.TypeOf((*T)(nil)).Elem(), class}
+    pool, ok := pools.Load(key)
+    if !ok {
+        pool, _ = pools.LoadOrStore(key, &sync.Pool{})
+    }
+    return pool.(*sync.Pool)
+}
+
+// Release returns the buffers of the built-in Data types within the data to
+// the pool (see PutBuffer), including the columns of Datasets. It's an
+// optional optimization for reducing allocations in high-throughput pipelines,
+// that may only be used by the sole owner of the data. A runner solely owns
+// only the buffers that it allocated itself (e.g. with Clone or GetBuffer),
+// and never emitted or shared. Input datasets are never solely owned, as
+// runners like Project, Union, Tee and Broadcast hand the same dataset to
+// several runners, thus they must not be released. See Runner
+func Release(data Data) {
+    switch vs := data.(type) {
+    case Dataset:
+        for i := 0; i < vs.Width(); i++ {
+            Release(vs.At(i))
+        }
+    case Strs:
+        PutBuffer([]string(vs))
+    case Ints:
+        PutBuffer([]int64(vs))
+    case Floats:
+        PutBuffer([]float64(vs))
+    case Bools:
+        PutBuffer([]bool(vs))
+    case Times:
+        PutBuffer([]int64(vs))
+    case nullable:
+        Release(vs.Values)
+        Release(vs.Valid)
+    }
+}
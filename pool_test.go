@@ -0,0 +1,39 @@
+package ep
+
+import (
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestGetBuffer(t *testing.T) {
+    buf := GetBuffer[string](100)
+    require.Equal(t, 100, len(buf))
+    require.Equal(t, 128, cap(buf))
+
+    require.Equal(t, 0, len(GetBuffer[string](0)))
+    require.Equal(t, 1, cap(GetBuffer[int64](1)))
+}
+
+// Tests that pooled buffers are always zeroed, in order to not leak their
+// previous contents or retain references
+func TestPutBufferZeroed(t *testing.T) {
+    for i := 0; i < 10; i++ {
+        buf := GetBuffer[string](100)
+        for j := range buf {
+            require.Equal(t, "", buf[j])
+            buf[j] = "dirty"
+        }
+        PutBuffer(buf[:10])
+    }
+
+    // buffers of other types or capacity classes are never mixed
+    PutBuffer(make([]int64, 100))
+    require.Equal(t, 64, cap(GetBuffer[int64](33)))
+    require.Equal(t, 128, cap(GetBuffer[int64](65)))
+}
+
+func TestRelease(t *testing.T) {
+    data := NewDataset(Strs{"a"}, Ints{1}, Floats{1}, Bools{true}, Nullable(Strs{"a"}, Bools{true}), Null.Data(1))
+    Release(data)
+    require.Equal(t, []string{""}, data.At(0).Strings())
+}
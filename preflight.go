@@ -0,0 +1,165 @@
+package ep
+
+import (
+    "fmt"
+    "bytes"
+    "reflect"
+    "io/ioutil"
+    "encoding/gob"
+)
+
+// checkSerializable round-trips the runner through gob locally, as it's sent to
+// the peers, before any of them is contacted. Returns an error with the path
+// of the field that fails to serialize (like a struct without exported fields),
+// or whose value would be silently lost in transmission, like exported func and
+// chan values, which gob ignores. Unexported fields are ignored as well, as
+// they're assumed to be the local state of the runner on every node.
+func checkSerializable(r Runner, path string) error {
+    var buf bytes.Buffer
+    err := gob.NewEncoder(&buf).Encode(&runnerMsg{r})
+    if err != nil {
+        // a func or chan value is the likely culprit, when it's the only
+        // exported field of its struct
+        v := reflect.ValueOf(r)
+        if lost := checkLost(v, v, path, map[uintptr]bool{}); lost != nil {
+            return lost
+        }
+        return fmt.Errorf("ep: failed to serialize %s: %s", failingPath(v, path), err)
+    }
+
+    decoded := &runnerMsg{}
+    err = gob.NewDecoder(&buf).Decode(decoded)
+    if err != nil {
+        return fmt.Errorf("ep: failed to deserialize %s: %s", path, err)
+    }
+
+    return checkLost(reflect.ValueOf(r), reflect.ValueOf(decoded.Runner), path, map[uintptr]bool{})
+}
+
+// runnerMsg wraps a runner in an interface, like distRunner
+type runnerMsg struct { Runner Runner }
+
+// failingPath returns the path of the deepest field of the value that fails to
+// encode, as gob's errors don't specify it
+func failingPath(v reflect.Value, path string) string {
+    for _, c := range children(v, path) {
+        if encodeErr(c.v) != nil {
+            return failingPath(c.v, c.path)
+        }
+    }
+    return path
+}
+
+// encodeErr returns the error of encoding the value, which is encoded as an
+// interface if it's referenced by one
+func encodeErr(v reflect.Value) error {
+    msg := v.Interface()
+    if v.Kind() == reflect.Interface {
+        msg = &struct{ V interface{} }{msg}
+    }
+    return gob.NewEncoder(ioutil.Discard).Encode(msg)
+}
+
+type child struct {
+    v reflect.Value
+    path string
+}
+
+// children returns the transmitted values that are referenced by the value:
+// the exported fields of structs, and the elements of slices, arrays and maps,
+// that aren't nil
+func children(v reflect.Value, path string) (res []child) {
+    switch v.Kind() {
+    case reflect.Interface, reflect.Ptr:
+        if !v.IsNil() {
+            res = children(v.Elem(), path)
+        }
+    case reflect.Struct:
+        for i := 0; i < v.NumField(); i++ {
+            f := v.Type().Field(i)
+            if f.PkgPath == "" && !isNil(v.Field(i)) {
+                res = append(res, child{v.Field(i), path + "." + f.Name})
+            }
+        }
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            res = append(res, child{v.Index(i), fmt.Sprintf("%s[%d]", path, i)})
+        }
+    case reflect.Map:
+        for _, k := range v.MapKeys() {
+            res = append(res, child{v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k)})
+        }
+    }
+    return res
+}
+
+// isNil returns true for nil values, and for func and chan values, which gob
+// ignores
+func isNil(v reflect.Value) bool {
+    switch v.Kind() {
+    case reflect.Func, reflect.Chan:
+        return true
+    case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+        return v.IsNil()
+    }
+    return false
+}
+
+// checkLost compares the original value with its decoded counterpart, and
+// returns an error for the first value that was lost in transmission
+func checkLost(orig, decoded reflect.Value, path string, seen map[uintptr]bool) error {
+    switch orig.Kind() {
+    case reflect.Interface:
+        if orig.IsNil() || decoded.IsNil() {
+            return nil
+        }
+        return checkLost(orig.Elem(), decoded.Elem(), path, seen)
+    case reflect.Ptr:
+        if orig.IsNil() || decoded.IsNil() || seen[orig.Pointer()] {
+            return nil
+        }
+
+        seen[orig.Pointer()] = true
+        return checkLost(orig.Elem(), decoded.Elem(), path, seen)
+    case reflect.Struct:
+        if orig.Type() != decoded.Type() || reflect.PtrTo(orig.Type()).Implements(gobEncoderType) {
+            return nil
+        }
+
+        for i := 0; i < orig.NumField(); i++ {
+            f := orig.Type().Field(i)
+            v := orig.Field(i)
+            switch {
+            case f.PkgPath != "" || v.IsZero():
+                continue
+            case v.Kind() == reflect.Func || v.Kind() == reflect.Chan:
+                return fmt.Errorf("ep: failed to serialize %s.%s: %s values aren't transmitted", path, f.Name, v.Kind())
+            }
+
+            err := checkLost(v, decoded.Field(i), path + "." + f.Name, seen)
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < orig.Len() && i < decoded.Len(); i++ {
+            err := checkLost(orig.Index(i), decoded.Index(i), fmt.Sprintf("%s[%d]", path, i), seen)
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Map:
+        for _, k := range orig.MapKeys() {
+            d := decoded.MapIndex(k)
+            if !d.IsValid() {
+                continue
+            }
+
+            err := checkLost(orig.MapIndex(k), d, fmt.Sprintf("%s[%v]", path, k), seen)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
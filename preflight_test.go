@@ -0,0 +1,74 @@
+package ep
+
+import (
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&withFunc{}, &withConf{})
+
+// withFunc is a runner of exported func and chan values, which aren't
+// transmitted
+type withFunc struct {
+    Name string
+    Fn func(Dataset) Dataset
+    Ch chan Dataset
+}
+
+func (*withFunc) Returns() []Type { return []Type{Wildcard} }
+func (r *withFunc) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        out <- r.Fn(data)
+    }
+    return nil
+}
+
+// withConf is a runner of a configuration that can't be serialized
+type withConf struct {
+    unregistered
+    Conf interface{}
+}
+
+type opaqueConf struct { size int }
+
+func TestCheckSerializable(t *testing.T) {
+    runner := Pipeline(Scatter(), &withFunc{}, Gather())
+    require.NoError(t, checkSerializable(runner, "Runner"))
+
+    runner = Pipeline(Scatter(), &withFunc{Fn: func(data Dataset) Dataset { return data }}, Gather())
+    err := checkSerializable(runner, "Runner")
+    require.Error(t, err)
+    require.Equal(t, "ep: failed to serialize Runner.From.To.Fn: func values aren't transmitted", err.Error())
+
+    runner = Pipeline(&withFunc{Ch: make(chan Dataset)}, Gather())
+    err = checkSerializable(runner, "Runner")
+    require.Error(t, err)
+    require.Equal(t, "ep: failed to serialize Runner.From.Ch: chan values aren't transmitted", err.Error())
+
+    // the unexported fields are assumed to be the local state of the runner,
+    // but a value of no exported fields fails
+    runner = Pipeline(Scatter(), &withConf{Conf: opaqueConf{1}})
+    require.True(t, Register(opaqueConf{}))
+    err = checkSerializable(runner, "Runner")
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "ep: failed to serialize Runner.To.Conf: ")
+    require.Contains(t, err.Error(), "no exported fields")
+}
+
+// Tests that a distributed runner that can't be serialized fails before any of
+// the peers is contacted
+func TestCheckSerializableDistributed(t *testing.T) {
+    ln, err := NewPipes().Listen(":5551")
+    require.NoError(t, err)
+
+    dist := NewDistributer(":5551", ln)
+    defer dist.Close()
+    go dist.Start()
+
+    runner := Pipeline(Scatter(), &withFunc{Fn: func(data Dataset) Dataset { return data }}, Gather())
+    runner = dist.Distribute(runner, ":5551", ":5559") // unreachable peer
+    _, err = testRun(runner, NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "ep: failed to serialize Runner.From.To.Fn")
+}
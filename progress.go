@@ -0,0 +1,169 @@
+package ep
+
+import (
+    "sync"
+    "time"
+    "context"
+    "encoding/gob"
+)
+
+// Progress of a job, aggregated across all of its nodes from the reports of its
+// runners. See WithProgress
+type Progress struct {
+    Rows int // processed so far, by all of the nodes
+    Total int // the expected number of rows, or 0 when it's unknown
+    Nodes map[string]int // processed rows by node, when distributed
+}
+
+// Fraction returns the completed fraction of the job, between 0 and 1, or 0
+// when its total is unknown
+func (p Progress) Fraction() float64 {
+    if p.Total <= 0 {
+        return 0
+    } else if p.Rows >= p.Total {
+        return 1
+    }
+    return float64(p.Rows) / float64(p.Total)
+}
+
+// ProgressInterval is the minimal interval between the progress reports of the
+// peer nodes to the master node. The reports of the master node are immediate
+var ProgressInterval = 100 * time.Millisecond
+
+// WithProgress returns a Runner that runs the provided runner, and calls fn with
+// the progress of the job whenever it's reported by its runners, on any of the
+// nodes (see ReportProgress). The calls are sequential. It must run on the
+// master node, thus it should wrap the distributed runner (see Distribute),
+// rather than be distributed itself.
+func WithProgress(r Runner, fn func(Progress)) Runner {
+    return &withProgress{r, fn}
+}
+
+type withProgress struct {
+    Runner Runner
+    fn func(Progress)
+}
+
+func (r *withProgress) Returns() []Type { return r.Runner.Returns() }
+func (r *withProgress) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *withProgress) Run(ctx context.Context, inp, out chan Dataset) error {
+    ctx = context.WithValue(ctx, "ep.Progress", &tracker{fn: r.fn})
+    return r.Runner.Run(ctx, inp, out)
+}
+
+func (r *withProgress) inner() []Runner { return []Runner{r.Runner} }
+func (r *withProgress) withInner(inner []Runner) Runner {
+    return &withProgress{inner[0], r.fn}
+}
+
+// ReportProgress reports that the runner has processed more rows, to the job
+// in the context, if its progress is tracked. Rows are summed across all of the
+// runners of the job, thus usually only a single runner of a plan reports them,
+// like its source
+func ReportProgress(ctx context.Context, rows int) {
+    if t := progressOf(ctx); t != nil {
+        node, _ := ctx.Value("ep.ThisNode").(string)
+        t.add(node, rows, 0)
+    }
+}
+
+// ReportTotal adds to the expected number of rows of the job in the context, if
+// its progress is tracked, such that its completed fraction can be computed
+func ReportTotal(ctx context.Context, total int) {
+    if t := progressOf(ctx); t != nil {
+        t.add("", 0, total)
+    }
+}
+
+func progressOf(ctx context.Context) *tracker {
+    t, _ := ctx.Value("ep.Progress").(*tracker)
+    return t
+}
+
+// tracker aggregates the progress reports of a job. On the master node it calls
+// the callback with every report, while on the peers it accumulates them until
+// they're sent to the master node
+type tracker struct {
+    l sync.Mutex
+    progress Progress
+    fn func(Progress)
+}
+
+func (t *tracker) add(node string, rows, total int) {
+    t.l.Lock()
+    defer t.l.Unlock()
+
+    p := &t.progress
+    p.Rows += rows
+    p.Total += total
+    if node != "" && rows != 0 {
+        if p.Nodes == nil {
+            p.Nodes = map[string]int{}
+        }
+        p.Nodes[node] += rows
+    }
+
+    if t.fn != nil {
+        res := *p
+        res.Nodes = make(map[string]int, len(p.Nodes))
+        for k, v := range p.Nodes {
+            res.Nodes[k] = v
+        }
+        t.fn(res)
+    }
+}
+
+// flush returns the progress accumulated since the last flush
+func (t *tracker) flush() (rows, total int) {
+    t.l.Lock()
+    defer t.l.Unlock()
+    rows, total = t.progress.Rows, t.progress.Total
+    t.progress = Progress{}
+    return rows, total
+}
+
+// report is a message from a peer to the master node over the connection of
+// the runner: either its progress since the previous report, or its completion
+// with its error, if any. See Serve
+type report struct {
+    Rows int
+    Total int
+    Done bool
+    Err string
+}
+
+// sendProgress sends the progress accumulated by the tracker to the master
+// node every ProgressInterval, until the returned function is called, which
+// also sends the remaining progress
+func sendProgress(enc *gob.Encoder, t *tracker) func() {
+    stop := make(chan struct{})
+    done := make(chan struct{})
+    send := func() {
+        if rows, total := t.flush(); rows != 0 || total != 0 {
+            enc.Encode(&report{Rows: rows, Total: total})
+        }
+    }
+
+    go func() {
+        defer close(done)
+        ticker := time.NewTicker(ProgressInterval)
+        defer ticker.Stop()
+        for {
+            select {
+            case <- ticker.C:
+                send()
+            case <- stop:
+                return
+            }
+        }
+    }()
+
+    return func() {
+        close(stop)
+        <- done
+        send()
+    }
+}
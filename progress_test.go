@@ -0,0 +1,78 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleWithProgress() {
+    runner := WithProgress(&counted{}, func(p Progress) {
+        fmt.Printf("%d/%d %.2f\n", p.Rows, p.Total, p.Fraction())
+    })
+
+    data1 := NewDataset(Strs{"a", "b", "c"})
+    data2 := NewDataset(Strs{"d"})
+    testRun(runner, data1, data2)
+
+    // Output:
+    // 0/4 0.00
+    // 3/4 0.75
+    // 4/4 1.00
+}
+
+var _ = registerGob(&counted{})
+
+// counted reports the total of 4 rows, and then the progress of every batch
+type counted struct {}
+func (*counted) Returns() []Type { return []Type{Wildcard} }
+func (*counted) Run(ctx context.Context, inp, out chan Dataset) error {
+    ReportTotal(ctx, 4)
+    for data := range inp {
+        out <- data
+        ReportProgress(ctx, data.Len())
+    }
+    return nil
+}
+
+func TestProgressFraction(t *testing.T) {
+    require.Equal(t, 0.0, Progress{Rows: 5}.Fraction())
+    require.Equal(t, 0.5, Progress{Rows: 5, Total: 10}.Fraction())
+    require.Equal(t, 1.0, Progress{Rows: 11, Total: 10}.Fraction())
+}
+
+// Tests that reporting without a tracked progress is a no-op
+func TestProgressUntracked(t *testing.T) {
+    data, err := testRun(&counted{}, NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, 1, data.Len())
+}
+
+func TestProgressDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    // the input exists only on the master, the peer reports its scattered
+    // share of it
+    runner := Pipeline(Scatter(), &counted{}, Gather())
+    reports := []Progress{}
+    runner = WithProgress(dists[0].Distribute(runner, ":5551", ":5552"), func(p Progress) {
+        reports = append(reports, p)
+    })
+
+    data1 := NewDataset(Strs{"a", "b"})
+    data2 := NewDataset(Strs{"c", "d"})
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+
+    last := reports[len(reports) - 1]
+    require.Equal(t, 4, last.Rows)
+    require.Equal(t, 8, last.Total) // reported by both nodes
+    require.Equal(t, map[string]int{":5551": 2, ":5552": 2}, last.Nodes)
+}
+
+func TestProgressExplain(t *testing.T) {
+    runner := WithProgress(PassThrough(), func(Progress) {})
+    require.Equal(t, "WithProgress", Explain(runner).Kind)
+}
@@ -31,65 +31,43 @@ func (rs *project) Returns() []Type {
     return types
 }
 
+func (rs *project) returnsFrom(inp []Type) []Type {
+    types := returnsFrom(rs.Left, inp)
+    return append(types, returnsFrom(rs.Right, inp)...)
+}
+
 // Run dispatches the same input to all inner runners, and then collects and
 // joins their results into a single dataset output
-func (rs *project) Run(ctx context.Context, inp, out chan Dataset) (err error) {
-    // choose the error out from the Left and Right errors.
-    var err1 error
-    defer func() { if err == nil && err1 != nil { err = err1 } }()
-
-    //
-    inpLeft := make(chan Dataset)
-    left := make(chan Dataset)
-    defer func() { for _ = range left {} }()
-
-    inpRight := make(chan Dataset)
-    right := make(chan Dataset)
-    defer func() { for _ = range right {} }()
-
-    // cancel the From runner when we're done - just in case it's still running.
-    ctx, cancel := context.WithCancel(ctx)
-    defer cancel()
-
-    go func() {
-        defer close(left)
-        err1 = rs.Left.Run(ctx, inpLeft, left)
-    }()
-
-    go func() {
-        defer close(right)
-        err = rs.Right.Run(ctx, inpRight, right)
-    }()
-
-    // dispatch (duplicate) input to both left and right runners
-    go func() {
-        defer close(inpLeft)
-        defer close(inpRight)
-        for data := range inp {
-            inpLeft <- data
-            inpRight <- data
-        }
-    }()
+func (rs *project) Run(ctx context.Context, inp, out chan Dataset) error {
+    left, right, g := runBoth(ctx, rs.Left, rs.Right, inp)
 
     // collect & join the output from the Left and Right runners, in order.
+    // When either one is done, cancel the other - just in case it's still
+    // running - and drain both until they've exited.
     for {
         result := []Data{}
         dataLeft, okLeft := <- left
         dataRight, okRight := <- right
 
         if !okLeft || !okRight {
-            return // TODO: what if just one is done? error?
+            g.Cancel() // TODO: what if just one is done? error?
+            for _ = range left {}
+            for _ = range right {}
+            return g.Wait()
         }
 
         // TODO: what if there's a mismatch in Len()?
+        names := []string{}
         for i := 0; okLeft && i < dataLeft.Width(); i++ {
             result = append(result, dataLeft.At(i))
+            names = append(names, dataLeft.ColumnName(i))
         }
 
         for i := 0; okRight && i < dataRight.Width(); i++ {
             result = append(result, dataRight.At(i))
+            names = append(names, dataRight.ColumnName(i))
         }
 
-        out <- NewDataset(result...)
+        out <- newNamedDataset(names, result...)
     }
 }
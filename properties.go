@@ -0,0 +1,153 @@
+package ep
+
+// Properties describe how the output of a runner is distributed across the
+// nodes, and ordered within each node. They're tracked through the composed
+// runners of a plan (see PropertiesOf), in order for the optimizer to skip
+// repartitions and sorts of data that already satisfies them (see
+// PruneSatisfied).
+type Properties struct {
+
+    // Partitioned are the columns by which the rows are partitioned across the
+    // nodes, such that all of the rows with the same values in these columns
+    // are on the same node, or nil if unknown
+    Partitioned []int
+
+    // Sorted are the keys by which the output of every node is sorted, or nil
+    // if unknown
+    Sorted []SortKey
+}
+
+// PropertiesResolver is implemented by runners that preserve or establish the
+// properties of their input, given the properties of their input. The output
+// of other runners, unless built-in, is assumed to have no known properties.
+type PropertiesResolver interface {
+    PropertiesFrom(inp Properties) Properties
+}
+
+// PropertiesOf returns the properties of the output of the runner, given the
+// properties of its input
+func PropertiesOf(r Runner, inp Properties) Properties {
+    switch r := r.(type) {
+    case PropertiesResolver:
+        return r.PropertiesFrom(inp)
+    case *pipeline:
+        return PropertiesOf(r.To, PropertiesOf(r.From, inp))
+    case *passthrough, *filter, *rename, *limit, *offset:
+        return inp
+    case *wrap:
+        return PropertiesOf(r.Runner, inp)
+    case *withMemory:
+        return PropertiesOf(r.Runner, inp)
+    case *withBuffers:
+        return PropertiesOf(r.Runner, inp)
+    case *analysis:
+        return PropertiesOf(r.Runner, inp)
+    case *picker:
+        return inp.picked(r.Cols)
+    case *sorter:
+        return Properties{inp.Partitioned, r.Keys}
+    case *distSort:
+        return Properties{Sorted: r.Keys} // gathered in order
+    case *groupBy:
+        // the keys are the first columns of the output, in any order
+        return Properties{Partitioned: inp.picked(r.Keys).Partitioned}
+    case *exchange:
+        if r.SendTo == sendPartition && len(r.Columns) > 0 {
+            return Properties{Partitioned: r.Columns}
+        }
+    }
+    return Properties{}
+}
+
+// picked returns the properties of the columns at the provided indices, like
+// Pick. The partitioning is kept only if all of its columns are picked, and
+// the sort order is kept up to the first key that isn't picked
+func (p Properties) picked(cols []int) Properties {
+    index := map[int]int{}
+    for i := len(cols) - 1; i >= 0; i-- {
+        index[cols[i]] = i
+    }
+
+    res := Properties{}
+    for _, col := range p.Partitioned {
+        i, ok := index[col]
+        if !ok {
+            res.Partitioned = nil
+            break
+        }
+        res.Partitioned = append(res.Partitioned, i)
+    }
+
+    for _, key := range p.Sorted {
+        i, ok := index[key.Col]
+        if !ok {
+            break
+        }
+        res.Sorted = append(res.Sorted, SortKey{i, key.Desc})
+    }
+    return res
+}
+
+// PartitionedBy returns true if all of the rows with the same values in the
+// provided columns are on the same node. It's the case when the data is
+// partitioned by any subset of these columns.
+func (p Properties) PartitionedBy(cols ...int) bool {
+    if len(p.Partitioned) == 0 {
+        return false
+    }
+
+    for _, col := range p.Partitioned {
+        found := false
+        for _, c := range cols {
+            found = found || c == col
+        }
+
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+// SortedBy returns true if the output of every node is sorted by the provided
+// keys, which are a prefix of the sort keys
+func (p Properties) SortedBy(keys []SortKey) bool {
+    if len(keys) > len(p.Sorted) {
+        return false
+    }
+
+    for i, key := range keys {
+        if p.Sorted[i] != key {
+            return false
+        }
+    }
+    return true
+}
+
+// PruneSatisfied is a Rule that drops the repartitions and sorts whose input
+// is already partitioned or sorted by their keys (see Properties), like the
+// repartition of a GroupBy by the keys of a preceding Repartition.
+func PruneSatisfied(r Runner) (Runner, bool) {
+    stages := Stages(r)
+    props := Properties{} // the input of the pipeline is unknown
+    for i, stage := range stages {
+        if props.satisfies(stage) {
+            stages = append(stages[:i], stages[i + 1:]...)
+            return Pipeline(stages...), true
+        }
+        props = PropertiesOf(stage, props)
+    }
+    return r, false
+}
+
+// satisfies returns true if the runner is a repartition or sort that has no
+// effect on data of these properties
+func (p Properties) satisfies(r Runner) bool {
+    switch r := r.(type) {
+    case *exchange:
+        return r.SendTo == sendPartition && p.PartitionedBy(r.Columns...)
+    case *sorter:
+        return len(r.Keys) > 0 && p.SortedBy(r.Keys)
+    }
+    return false
+}
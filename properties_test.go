@@ -0,0 +1,102 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExamplePropertiesOf() {
+    runner := Pipeline(Repartition(1, 0), Sort([]SortKey{{0, true}}), Pick(1, 0))
+    fmt.Printf("%+v\n", PropertiesOf(runner, Properties{}))
+
+    // Output: {Partitioned:[0 1] Sorted:[{Col:1 Desc:true}]}
+}
+
+func TestPropertiesOf(t *testing.T) {
+    inp := Properties{[]int{1}, []SortKey{{0, false}, {1, true}}}
+    require.Equal(t, inp, PropertiesOf(Pipeline(Filter(IsTrue(0)), Limit(5)), inp))
+    require.Equal(t, Properties{}, PropertiesOf(Gather(), inp))
+    require.Equal(t, Properties{}, PropertiesOf(&nodeAddr{}, inp))
+
+    // picked columns
+    require.Equal(t, Properties{[]int{0}, nil}, PropertiesOf(Pick(1, 2), inp))
+    require.Equal(t, Properties{}, PropertiesOf(Pick(0), Properties{Partitioned: []int{0, 1}}))
+    expected := Properties{nil, []SortKey{{0, false}}}
+    require.Equal(t, expected, PropertiesOf(Pick(0, 2), inp))
+
+    // a local sort keeps the partitioning
+    expected = Properties{[]int{1}, []SortKey{{2, false}}}
+    require.Equal(t, expected, PropertiesOf(Sort([]SortKey{{2, false}}), inp))
+
+    // aggregated by the partitioning columns, and then repartitioned by all of
+    // the keys
+    partial := &groupBy{[]int{2, 1}, []Aggregator{Count()}, aggPartial}
+    require.Equal(t, Properties{Partitioned: []int{1}}, PropertiesOf(partial, inp))
+    partial.Keys = []int{2}
+    require.Equal(t, Properties{}, PropertiesOf(partial, inp))
+
+    expected = Properties{Partitioned: []int{0, 1}}
+    require.Equal(t, expected, PropertiesOf(GroupBy([]int{2, 1}, Count()), inp))
+}
+
+func TestPropertiesSatisfied(t *testing.T) {
+    p := Properties{[]int{1}, []SortKey{{0, false}, {1, true}}}
+    require.True(t, p.PartitionedBy(1))
+    require.True(t, p.PartitionedBy(0, 1))
+    require.False(t, p.PartitionedBy(0))
+    require.False(t, Properties{}.PartitionedBy())
+
+    require.True(t, p.SortedBy([]SortKey{{0, false}}))
+    require.True(t, p.SortedBy([]SortKey{{0, false}, {1, true}}))
+    require.False(t, p.SortedBy([]SortKey{{0, true}}))
+    require.False(t, p.SortedBy([]SortKey{{1, true}}))
+}
+
+func TestPruneSatisfied(t *testing.T) {
+    // the repartition of the group-by is redundant
+    runner := Pipeline(Repartition(0), GroupBy([]int{0}, Count()))
+    stages := Stages(Optimize(runner))
+    require.Equal(t, 3, len(stages))
+    require.IsType(t, &exchange{}, stages[0])
+    require.IsType(t, &groupBy{}, stages[1])
+    require.IsType(t, &groupBy{}, stages[2])
+
+    // the second sort is satisfied by the first one
+    runner = Pipeline(Sort([]SortKey{{0, false}, {1, false}}), Filter(IsTrue(1)), Sort([]SortKey{{0, false}}))
+    stages = Stages(Optimize(runner))
+    require.Equal(t, 2, len(stages))
+    require.IsType(t, &sorter{}, stages[0])
+
+    // not satisfied
+    runner = Pipeline(Repartition(0), Pick(1), Repartition(0), Sort([]SortKey{{0, false}}))
+    require.Equal(t, 4, len(Stages(Optimize(runner))))
+
+    runner = Pipeline(Repartition(0), Gather(), Repartition(0))
+    require.Equal(t, 3, len(Stages(Optimize(runner))))
+
+    _, ok := PruneSatisfied(Repartition(0))
+    require.False(t, ok)
+}
+
+// Tests that the results of the pruned plan are the same, when distributed
+func TestPruneSatisfiedDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Repartition(0), GroupBy([]int{0}, Count()), Gather())
+    runner = dists[0].Distribute(Optimize(runner), ":5551", ":5552")
+
+    data1 := NewDataset(Strs{"a", "b", "a"})
+    data2 := NewDataset(Strs{"b", "c", "a"})
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+
+    res := []string{}
+    for i := 0; i < data.Len(); i++ {
+        res = append(res, fmt.Sprint(data.At(0).Strings()[i], data.At(1).Strings()[i]))
+    }
+
+    sort.Strings(res)
+    require.Equal(t, []string{"a3", "b2", "c1"}, res)
+}
@@ -0,0 +1,138 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&reducer{}, &namedReduce{})
+
+// Reducers registry of named reduce functions. Like map functions, reduce
+// functions cannot be serialized, thus in order to distribute them they must be
+// registered on all nodes under the same name, and referenced by name using
+// NamedReduce()
+var Reducers = make(reducersReg)
+
+// ReduceFunc folds a single input dataset into the accumulated dataset, and
+// returns the updated accumulated dataset. The accumulated dataset may be
+// shared, thus it should return a new dataset rather than modifying it
+// in-place.
+type ReduceFunc func(acc, data Dataset) (Dataset, error)
+
+// Reduce returns a Runner that folds its entire input stream into a single
+// output dataset, starting with the initial dataset, e.g. for computing a
+// global aggregate without grouping. The initial dataset is emitted when the
+// input is empty. NOTE: functions cannot be serialized, thus the returned
+// Runner cannot be distributed. See NamedReduce.
+func Reduce(returns []Type, init Dataset, fn ReduceFunc) Runner {
+    return &reducer{returns, init, fn}
+}
+
+// NamedReduce returns a Runner that applies the reduce function registered
+// under the provided name via `Reducers.Register()`. Unlike Reduce, it's safe
+// to distribute: each node reduces its local input into a partial result, and
+// the partials are gathered into the master node, where they're reduced again
+// (as a single dataset) into the final result. Thus, the reduce function must
+// accept its own output as input. Other nodes emit nothing.
+func NamedReduce(name string) Runner {
+    return &namedReduce{uuid.NewV4().String(), name}
+}
+
+type reducer struct {
+    Types []Type
+    Init Dataset
+    fn ReduceFunc
+}
+
+func (r *reducer) Returns() []Type { return r.Types }
+func (r *reducer) Run(ctx context.Context, inp, out chan Dataset) error {
+    acc, err := r.reduce(inp)
+    if err != nil {
+        return err
+    }
+
+    out <- acc
+    return nil
+}
+
+// reduce the entire input into a single dataset
+func (r *reducer) reduce(inp chan Dataset) (acc Dataset, err error) {
+    defer func() {
+        for _ = range inp {} // drain on errors
+    }()
+
+    acc = r.Init
+    for data := range inp {
+        acc, err = r.fn(acc, data)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return acc, nil
+}
+
+type namedReduce struct {
+    UID string
+    Name string
+}
+
+func (r *namedReduce) Returns() []Type {
+    reducer := Reducers[r.Name]
+    if reducer == nil {
+        return []Type{}
+    }
+    return reducer.Returns()
+}
+
+func (r *namedReduce) Run(ctx context.Context, inp, out chan Dataset) error {
+    reducer := Reducers[r.Name]
+    if reducer == nil {
+        for _ = range inp {}
+        return fmt.Errorf("Unregistered reducer %s", r.Name)
+    }
+
+    if ctx.Value("ep.AllNodes") == nil {
+        return reducer.Run(ctx, inp, out) // not distributed
+    }
+
+    partial, err := reducer.reduce(inp)
+    if err != nil {
+        return err
+    }
+
+    // gather the partials into the master node. The exchange UID is derived
+    // from this runner's UID, in order to be the same on all nodes.
+    partials := make(chan Dataset, 1)
+    partials <- partial
+    close(partials)
+
+    gather := &exchange{UID: r.UID + ":gather", SendTo: sendGather}
+    all, err := collect(ctx, gather, partials)
+    if err != nil || all == nil {
+        return err // not the master node, or no partials at all
+    }
+
+    res, err := reducer.fn(reducer.Init, all)
+    if err != nil {
+        return err
+    }
+
+    out <- res
+    return nil
+}
+
+// registry of reduce functions
+type reducersReg map[string]*reducer
+func (reg reducersReg) Register(name string, returns []Type, init Dataset, fn ReduceFunc) reducersReg {
+    reg[name] = &reducer{returns, init, fn}
+    return reg
+}
+
+func (reg reducersReg) Get(name string) Runner {
+    r, ok := reg[name]
+    if !ok {
+        return nil
+    }
+    return r
+}
@@ -0,0 +1,60 @@
+package ep
+
+import (
+    "fmt"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// sums the numbers of the first column into a single row
+func sumReduce(acc, data Dataset) (Dataset, error) {
+    total, _ := strconv.Atoi(acc.At(0).Strings()[0])
+    for _, s := range data.At(0).Strings() {
+        v, err := strconv.Atoi(s)
+        if err != nil {
+            return nil, err
+        }
+        total += v
+    }
+    return NewDataset(Strs{strconv.Itoa(total)}), nil
+}
+
+var _ = Reducers.Register("sum", []Type{Str}, NewDataset(Strs{"0"}), sumReduce)
+
+func ExampleReduce() {
+    runner := Reduce([]Type{Str}, NewDataset(Strs{"0"}), sumReduce)
+    data, err := testRun(runner, NewDataset(Strs{"1", "2"}), NewDataset(Strs{"3"}))
+    fmt.Println(data, err)
+
+    // Output: [[6]] <nil>
+}
+
+func TestReduceEmpty(t *testing.T) {
+    runner := Reduce([]Type{Str}, NewDataset(Strs{"0"}), sumReduce)
+    data, err := testRun(runner)
+    require.NoError(t, err)
+    require.Equal(t, "[[0]]", fmt.Sprint(data))
+}
+
+func TestReduceErr(t *testing.T) {
+    runner := Reduce([]Type{Str}, NewDataset(Strs{"0"}), sumReduce)
+    _, err := testRun(runner, NewDataset(Strs{"1"}), NewDataset(Strs{"x"}), NewDataset(Strs{"2"}))
+    require.Error(t, err)
+
+    _, err = testRun(NamedReduce("nothing"), NewDataset(Strs{"1"}))
+    require.Error(t, err)
+    require.Equal(t, "Unregistered reducer nothing", err.Error())
+}
+
+func TestNamedReduceDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), NamedReduce("sum"))
+    require.Equal(t, []Type{Str}, runner.Returns())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data, err := testRun(runner, NewDataset(numbers(0, 50)), NewDataset(numbers(50, 100)))
+    require.NoError(t, err)
+    require.Equal(t, "[[4950]]", fmt.Sprint(data))
+}
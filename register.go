@@ -0,0 +1,117 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "reflect"
+    "strings"
+    "io/ioutil"
+    "encoding/gob"
+)
+
+// Register the concrete types of the provided values, like custom Runners and
+// Data types, for transmitting them between nodes. All of the types that are
+// referenced by a distributed Runner through interfaces (like the inner
+// runners of a Pipeline) must be registered on all nodes, usually in an init()
+// function or a package-level variable:
+//
+//      var _ = ep.Register(&myRunner{}, myData{})
+//
+// Distributed runners that reference unregistered types fail before they're
+// distributed. Returns true, for package-level variables.
+func Register(values ...interface{}) bool {
+    for _, v := range values {
+        gob.Register(v)
+    }
+    return true
+}
+
+// registeredTypes caches the types that were found to be registered, see
+// isRegistered
+var registeredTypes sync.Map
+
+var gobEncoderType = reflect.TypeOf((*gob.GobEncoder)(nil)).Elem()
+
+// checkRegistered returns an error if any of the concrete types referenced
+// through interfaces by the value, recursively, isn't registered. The error
+// includes the path of the field that references it.
+func checkRegistered(v interface{}, path string) error {
+    return checkValue(reflect.ValueOf(v), path, map[uintptr]bool{})
+}
+
+func checkValue(v reflect.Value, path string, seen map[uintptr]bool) error {
+    switch v.Kind() {
+    case reflect.Interface:
+        if v.IsNil() {
+            return nil
+        }
+
+        e := v.Elem()
+        if !isRegistered(e.Type()) {
+            return fmt.Errorf("ep: unregistered type %s at %s, use ep.Register", e.Type(), path)
+        }
+        return checkValue(e, path, seen)
+    case reflect.Ptr:
+        if v.IsNil() || seen[v.Pointer()] {
+            return nil
+        }
+
+        seen[v.Pointer()] = true
+        return checkValue(v.Elem(), path, seen)
+    case reflect.Struct:
+        // custom encodings don't expose their fields
+        if reflect.PtrTo(v.Type()).Implements(gobEncoderType) {
+            return nil
+        }
+
+        for i := 0; i < v.NumField(); i++ {
+            f := v.Type().Field(i)
+            if f.PkgPath != "" {
+                continue // unexported fields aren't transmitted
+            }
+
+            err := checkValue(v.Field(i), path + "." + f.Name, seen)
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Slice, reflect.Array:
+        for i := 0; i < v.Len(); i++ {
+            err := checkValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), seen)
+            if err != nil {
+                return err
+            }
+        }
+    case reflect.Map:
+        for _, k := range v.MapKeys() {
+            err := checkValue(v.MapIndex(k), fmt.Sprintf("%s[%v]", path, k), seen)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// isRegistered returns true if the concrete type is registered with gob. As
+// gob doesn't expose its registry, its zero value is encoded as an interface
+func isRegistered(t reflect.Type) bool {
+    if _, ok := registeredTypes.Load(t); ok {
+        return true
+    }
+
+    var v interface{}
+    if t.Kind() == reflect.Ptr {
+        v = reflect.New(t.Elem()).Interface()
+    } else {
+        v = reflect.New(t).Elem().Interface()
+    }
+
+    err := gob.NewEncoder(ioutil.Discard).Encode(&struct{ V interface{} }{v})
+    if err != nil && strings.Contains(err.Error(), "not registered") {
+        return false
+    }
+
+    registeredTypes.Store(t, true)
+    return true
+}
@@ -0,0 +1,56 @@
+package ep
+
+import (
+    "context"
+    "reflect"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// unregistered is a runner that isn't registered for transmission between
+// nodes, see Register
+type unregistered struct {}
+func (*unregistered) Returns() []Type { return []Type{Wildcard} }
+func (*unregistered) Run(ctx context.Context, inp, out chan Dataset) error {
+    for data := range inp {
+        out <- data
+    }
+    return nil
+}
+
+// registeredLater is only registered by the test
+type registeredLater struct { unregistered }
+
+func TestCheckRegistered(t *testing.T) {
+    runner := Pipeline(Scatter(), Project(PassThrough(), &unregistered{}), Gather())
+    err := checkRegistered(runner, "Runner")
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "*ep.unregistered")
+    require.Contains(t, err.Error(), "Runner.From.To.Right")
+
+    // gob registrations are global, thus registeredLater is only unregistered
+    // on the first run of the test (i.e. with -count)
+    runner = Pipeline(Scatter(), &registeredLater{}, Gather())
+    if !isRegistered(reflect.TypeOf(&registeredLater{})) {
+        require.Error(t, checkRegistered(runner, "Runner"))
+    }
+
+    require.True(t, Register(&registeredLater{}))
+    require.NoError(t, checkRegistered(runner, "Runner"))
+}
+
+// Tests that a distributed runner with an unregistered type fails before it's
+// distributed to the peers
+func TestRegisterDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), &unregistered{}, Gather())
+    _, err := testRun(dists[0].Distribute(runner, ":5551", ":5552"), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "unregistered type *ep.unregistered")
+
+    // runners that aren't distributed to peers aren't transmitted
+    data, err := testRun(dists[0].Distribute(runner, ":5551"), NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, []string{"a"}, data.At(0).Strings())
+}
@@ -0,0 +1,104 @@
+package ep
+
+import (
+    "time"
+    "context"
+)
+
+var _ = registerGob(&retry{}, &timeout{})
+
+// Retry returns a Runner that runs the provided runner, and re-runs it upon
+// failure up to `attempts` times in total, waiting `backoff` before the first
+// retry and doubling the wait before every subsequent retry. Every attempt runs
+// with fresh channels: the input is buffered in order to replay it, and the
+// output of every attempt is buffered and emitted only if it succeeds, thus
+// the output of failed attempts is never emitted. It's intended for flaky
+// source runners (like network scans) with small inputs; the last error is
+// returned if all of the attempts fail.
+func Retry(r Runner, attempts int, backoff time.Duration) Runner {
+    return &retry{r, attempts, backoff}
+}
+
+// Timeout returns a Runner that runs the provided runner, and cancels it if it
+// doesn't complete within the duration. In that case, it fails with
+// context.DeadlineExceeded, even if the runner has gracefully exited upon the
+// cancellation.
+func Timeout(r Runner, d time.Duration) Runner {
+    return &timeout{r, d}
+}
+
+type retry struct {
+    Runner Runner
+    Attempts int
+    Backoff time.Duration
+}
+
+func (r *retry) Returns() []Type { return r.Runner.Returns() }
+func (r *retry) Run(ctx context.Context, inp, out chan Dataset) error {
+    inputs := []Dataset{}
+    for data := range inp {
+        inputs = append(inputs, data)
+    }
+
+    wait := r.Backoff
+    for attempt := 1; ; attempt++ {
+        res, err := r.attempt(ctx, inputs)
+        if err == nil {
+            for _, data := range res {
+                out <- data
+            }
+            return nil
+        }
+
+        if attempt >= r.Attempts || ctx.Err() != nil {
+            return err
+        }
+
+        select {
+        case <- time.After(wait):
+        case <- ctx.Done():
+            return err
+        }
+        wait *= 2
+    }
+}
+
+// attempt to run the runner once over the buffered input, and return its
+// buffered output
+func (r *retry) attempt(ctx context.Context, inputs []Dataset) ([]Dataset, error) {
+    inp := make(chan Dataset, len(inputs))
+    for _, data := range inputs {
+        inp <- data
+    }
+    close(inp)
+
+    var err error
+    out := make(chan Dataset)
+    go func() {
+        defer close(out)
+        err = r.Runner.Run(ctx, inp, out)
+    }()
+
+    res := []Dataset{}
+    for data := range out {
+        res = append(res, data)
+    }
+    return res, err
+}
+
+type timeout struct {
+    Runner Runner
+    Duration time.Duration
+}
+
+func (r *timeout) Returns() []Type { return r.Runner.Returns() }
+func (r *timeout) Run(ctx context.Context, inp, out chan Dataset) error {
+    timeoutCtx, cancel := context.WithTimeout(ctx, r.Duration)
+    defer cancel()
+
+    err := r.Runner.Run(timeoutCtx, inp, out)
+    if ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+        return context.DeadlineExceeded
+    }
+    return err
+}
@@ -0,0 +1,57 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// flaky is a passthrough Runner that emits its first input, and then fails
+// for the first `Failures` runs
+type flaky struct {
+    Failures int
+    Runs int
+}
+
+func (*flaky) Returns() []Type { return []Type{Wildcard} }
+func (r *flaky) Run(ctx context.Context, inp, out chan Dataset) error {
+    r.Runs++
+    for data := range inp {
+        out <- data
+        if r.Runs <= r.Failures {
+            return fmt.Errorf("failure %d", r.Runs)
+        }
+    }
+    return nil
+}
+
+func TestRetry(t *testing.T) {
+    r := &flaky{Failures: 2}
+    data, err := testRun(Retry(r, 3, time.Millisecond), NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+    require.NoError(t, err)
+    require.Equal(t, 3, r.Runs)
+
+    // the output of the failed attempts isn't emitted
+    require.Equal(t, "[[a b]]", fmt.Sprintf("%v", data))
+}
+
+func TestRetryExhausted(t *testing.T) {
+    r := &flaky{Failures: 5}
+    _, err := testRun(Retry(r, 3, time.Millisecond), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Equal(t, "failure 3", err.Error())
+    require.Equal(t, 3, r.Runs)
+}
+
+func TestTimeout(t *testing.T) {
+    infinity := &InfinityRunner{}
+    _, err := testRun(Timeout(infinity, 10 * time.Millisecond))
+    require.Equal(t, context.DeadlineExceeded, err)
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+
+    data, err := testRun(Timeout(PassThrough(), time.Minute), NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[a]]", fmt.Sprintf("%v", data))
+}
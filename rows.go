@@ -69,7 +69,7 @@ func (r *rows) Next(dest []driver.Value) error {
         close(inp)
         go func() {
             defer close(r.Out)
-            r.Err = r.Run(r.Ctx, inp, r.Out)
+            r.Err = runSafe(r.Ctx, r, inp, r.Out)
         }()
     }
 
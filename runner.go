@@ -1,7 +1,9 @@
 package ep
 
 import (
+    "fmt"
     "context"
+    "runtime/debug"
 )
 
 var _ = registerGob(&passthrough{})
@@ -11,6 +13,12 @@ var _ = registerGob(&passthrough{})
 // and produce a new stream of the formatted values.
 // NOTE: Some Runners will run concurrently, this it's important to not modify
 // the input in-place. Instead, copy/create a new dataset and use that
+//
+// NOTE: Emitting a dataset transfers its ownership downstream, and the emitting
+// runner must not use it afterwards. Input datasets may be shared with other
+// runners, thus runners may only return the buffers that they allocated
+// themselves, and never emitted, to the pool with Release, in order to reduce
+// allocations. See Release for the ownership rules.
 type Runner interface {
 
     // Run the manipulation code. Receive datasets from the `inp` stream, cast
@@ -41,7 +49,7 @@ type Runner interface {
     // results
     //
     // NOTE: If you need to annotate the returned data with names for
-    // referencing later, use the `As()` helper function
+    // referencing later, use the `As()` helper function. See SchemaOf
     //
     // NOTE: In some cases you may not know the returned types ahead of time,
     // because it's somehow depends on the input types. For such cases, use the
@@ -80,3 +88,101 @@ func (*passthrough) Run(_ context.Context, inp, out chan Dataset) (err error) {
     }
     return nil
 }
+
+func (*passthrough) Iter(_ context.Context, inp Iterator) Iterator { return inp }
+
+// Run runs the runner to completion with the provided input datasets, and
+// invokes the callback for every output dataset, in order. If the callback
+// returns an error, the runner is canceled and the error is returned. It wires
+// the channels required for running a Runner, for the common case of
+// consuming its output directly.
+func Run(ctx context.Context, r Runner, inp []Dataset, fn func(Dataset) error) error {
+    ch := make(chan Dataset, len(inp))
+    for _, data := range inp {
+        ch <- data
+    }
+    close(ch)
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var err error
+    out := make(chan Dataset)
+    go func() {
+        defer close(out)
+        err = runSafe(ctx, r, ch, out)
+    }()
+
+    var fnErr error
+    for data := range out {
+        if fnErr == nil {
+            fnErr = fn(data)
+            if fnErr != nil {
+                cancel() // drain the rest of the output
+            }
+        }
+    }
+
+    if fnErr != nil {
+        return fnErr
+    }
+    return err
+}
+
+// Collect runs the runner to completion with the provided input datasets, and
+// returns all of its output appended into a single dataset, or nil if there
+// was no output. The output datasets must be of the same types in order to be
+// appended. See Run.
+func Collect(ctx context.Context, r Runner, inp ...Dataset) (Dataset, error) {
+    var res Dataset
+    err := Run(ctx, r, inp, func(data Dataset) error {
+        if data.Len() > 0 {
+            res = appendClone(res, data)
+        }
+        return nil
+    })
+    return res, err
+}
+
+// collect runs the runner with the given input to completion, and returns all
+// of its output appended into a single dataset, or nil if there was no output.
+func collect(ctx context.Context, r Runner, inp chan Dataset) (Dataset, error) {
+    var err error
+    out := make(chan Dataset)
+    go func() {
+        defer close(out)
+        err = runSafe(ctx, r, inp, out)
+    }()
+
+    var res Dataset
+    for data := range out {
+        if data.Len() > 0 {
+            res = appendClone(res, data)
+        }
+    }
+    return res, err
+}
+
+// PanicError is the error of a Runner that has panicked, with the stack trace
+// of the panic. Runners are run by the composite runners and by the
+// distributers with runSafe, thus a panic fails the run instead of crashing
+// the process, and it's propagated like any other error. NOTE: panics in
+// go-routines started by the runners themselves can't be recovered.
+type PanicError struct {
+    Value interface{}
+    Stack string
+}
+
+func (e *PanicError) Error() string {
+    return fmt.Sprintf("panic: %v\n\n%s", e.Value, e.Stack)
+}
+
+// runSafe runs the runner, and recovers from its panics into a PanicError
+func runSafe(ctx context.Context, r Runner, inp, out chan Dataset) (err error) {
+    defer func() {
+        if p := recover(); p != nil {
+            err = &PanicError{p, string(debug.Stack())}
+        }
+    }()
+    return r.Run(ctx, inp, out)
+}
@@ -2,10 +2,25 @@ package ep
 
 import (
     "fmt"
+    "net"
     "context"
     "strings"
+    "testing"
+    "github.com/stretchr/testify/require"
 )
 
+var _ = registerGob(&panicker{})
+
+// panicker panics on the peer nodes when distributed, or always otherwise
+type panicker struct {}
+func (*panicker) Returns() []Type { return []Type{Wildcard} }
+func (*panicker) Run(ctx context.Context, inp, out chan Dataset) error {
+    if ctx.Value("ep.ThisNode") == ctx.Value("ep.MasterNode") && ctx.Value("ep.ThisNode") != nil {
+        return PassThrough().Run(ctx, inp, out)
+    }
+    panic("oh no")
+}
+
 type Upper struct {}
 func (*Upper) Returns() []Type { return []Type{Str} }
 func (*Upper) Run(_ context.Context, inp, out chan Dataset) error {
@@ -51,6 +66,79 @@ func ExampleRunner() {
     // Output: [[HELLO WORLD]] <nil>
 }
 
+func ExampleRun() {
+    err := Run(context.Background(), PassThrough(), []Dataset{
+        NewDataset(Strs{"hello"}),
+        NewDataset(Strs{"world"}),
+    }, func(data Dataset) error {
+        fmt.Println(data)
+        return nil
+    })
+    fmt.Println(err)
+
+    // Output:
+    // [[hello]]
+    // [[world]]
+    // <nil>
+}
+
+func ExampleCollect() {
+    data1 := NewDataset(Strs{"hello"})
+    data2 := NewDataset(Strs{"world"})
+    data, err := Collect(context.Background(), PassThrough(), data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[hello world]] <nil>
+}
+
+// callback errors cancel the runner
+func TestRunCallbackErr(t *testing.T) {
+    infinity := &InfinityRunner{}
+    err := Run(context.Background(), infinity, nil, func(Dataset) error {
+        return fmt.Errorf("something bad happened")
+    })
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
+
+// panics are recovered into errors
+func TestRunPanic(t *testing.T) {
+    _, err := Collect(context.Background(), Pipeline(&panicker{}, PassThrough()), NewDataset(Strs{"a"}))
+    require.IsType(t, &PanicError{}, err)
+    require.Equal(t, "oh no", err.(*PanicError).Value)
+    require.Contains(t, err.(*PanicError).Stack, "panicker")
+    require.True(t, strings.HasPrefix(err.Error(), "panic: oh no\n"))
+}
+
+// panics on peers are reported to the master, and the peers keep serving
+func TestRunPanicDistributed(t *testing.T) {
+    dists := []Distributer{}
+    addrs := []string{}
+    for i := 0; i < 2; i++ {
+        ln, err := net.Listen("tcp", "127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(ln.Addr().String(), ln)
+        go dist.Start()
+        defer dist.Close()
+
+        dists = append(dists, dist)
+        addrs = append(addrs, dist.Addr())
+    }
+
+    runner := dists[0].Distribute(Pipeline(&panicker{}, Gather()), addrs...)
+    _, err := testRun(runner, NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.True(t, strings.HasPrefix(err.Error(), "panic: oh no\n"), err.Error())
+
+    runner = dists[0].Distribute(Pipeline(PassThrough(), Gather()), addrs...)
+    data, err := testRun(runner, NewDataset(Strs{"a"}))
+    require.NoError(t, err)
+    require.Equal(t, 1, data.Len())
+}
+
 // run a runner with the given input to completion
 func testRun(r Runner, datasets ...Dataset) (Dataset, error) {
     var err error
@@ -69,8 +157,32 @@ func testRun(r Runner, datasets ...Dataset) (Dataset, error) {
 
     var res = NewDataset()
     for data := range out {
+        if res.Width() == 0 {
+            // the result is appended in-place, while the output might be
+            // shared, like by the runners of a Union
+            data = Clone(data).(Dataset)
+        }
         res = res.Append(data).(Dataset)
     }
 
     return res, err
 }
+
+// testCluster starts n distributers on the addresses :5551, :5552, etc.,
+// connected by Pipes, which are closed when the test completes. Like
+// eptest.NewCluster, for the tests of this package
+func testCluster(t testing.TB, n int) []Distributer {
+    pipes := NewPipes()
+    dists := make([]Distributer, n)
+    for i := range dists {
+        addr := fmt.Sprintf(":%d", 5551 + i)
+        ln, err := pipes.Listen(addr)
+        require.NoError(t, err)
+
+        dist := NewDistributer(addr, ln)
+        t.Cleanup(func() { dist.Close() })
+        go dist.Start()
+        dists[i] = dist
+    }
+    return dists
+}
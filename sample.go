@@ -0,0 +1,162 @@
+package ep
+
+import (
+    "time"
+    "strconv"
+    "context"
+    "math/rand"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&sample{}, &reservoir{})
+
+// Sample returns a Runner that emits a random sample of its input rows, where
+// each row is independently sampled with the provided probability (Bernoulli
+// sampling). Thus, the size of the sample is only approximately the fraction
+// of the input size. It's a streaming runner, and when distributed each node
+// samples its local rows.
+func Sample(fraction float64) Runner {
+    return &sample{fraction}
+}
+
+// Reservoir returns a Runner that emits a uniform random sample of exactly n
+// rows of its input, or all of the rows if there are fewer. The entire input is
+// consumed before emitting the sample, but only n rows are buffered. When
+// distributed, each node samples its local rows, and the local samples are
+// gathered into the master node and merged into a single uniform sample of all
+// of the rows across the cluster.
+func Reservoir(n int) Runner {
+    return &reservoir{uuid.NewV4().String(), n}
+}
+
+type sample struct { Fraction float64 }
+func (*sample) Returns() []Type { return []Type{Wildcard} }
+func (r *sample) Run(ctx context.Context, inp, out chan Dataset) error {
+    rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+    for data := range inp {
+        mask := make([]bool, data.Len())
+        for i := range mask {
+            mask[i] = rnd.Float64() < r.Fraction
+        }
+
+        res := keep(data, mask).(Dataset)
+        if res.Len() > 0 {
+            out <- res
+        }
+    }
+    return nil
+}
+
+type reservoir struct {
+    UID string
+    N int
+}
+
+func (*reservoir) Returns() []Type { return []Type{Wildcard} }
+func (r *reservoir) Run(ctx context.Context, inp, out chan Dataset) error {
+    rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+    // local reservoir sampling (algorithm R): the i-th row replaces a random
+    // sampled row with probability n/i.
+    rows := []Dataset{}
+    var seen int64
+    for data := range inp {
+        for i := 0; i < data.Len(); i++ {
+            seen++
+            if len(rows) < r.N {
+                rows = append(rows, Clone(Slice(data, i, i + 1)).(Dataset))
+            } else if j := rnd.Int63n(seen); j < int64(r.N) {
+                rows[j] = Clone(Slice(data, i, i + 1)).(Dataset)
+            }
+        }
+    }
+
+    local := Concat(rows...)
+
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    if ctx.Value("ep.AllNodes") == nil {
+        if local != nil {
+            out <- local
+        }
+        return nil // not distributed
+    }
+
+    // gather the local samples, along with the node and the number of rows
+    // they were sampled from. The exchange UID is derived from this runner's
+    // UID, in order to be the same on all nodes.
+    samples := make(chan Dataset, 1)
+    if local != nil {
+        node := make(Strs, local.Len())
+        counts := make(Strs, local.Len())
+        for i := range node {
+            node[i] = thisNode
+            counts[i] = strconv.FormatInt(seen, 10)
+        }
+
+        cols := []Data{}
+        for i := 0; i < local.Width(); i++ {
+            cols = append(cols, local.At(i))
+        }
+        samples <- NewDataset(append(cols, node, counts)...)
+    }
+    close(samples)
+
+    gather := &exchange{UID: r.UID + ":gather", SendTo: sendGather}
+    all, err := collect(ctx, gather, samples)
+    if err != nil || all == nil {
+        return err // not the master node, or no rows at all
+    }
+
+    out <- mergeSamples(all, r.N, rnd)
+    return nil
+}
+
+// mergeSamples merges the uniform samples of several nodes into a single
+// uniform sample of n rows of the union of their rows. The last two columns
+// are the node and the number of rows it sampled from. Every row of the merged
+// sample is chosen from a node with probability proportional to the number of
+// its rows that weren't chosen yet.
+func mergeSamples(all Dataset, n int, rnd *rand.Rand) Dataset {
+    width := all.Width() - 2
+    nodes := all.At(width).Strings()
+    counts := all.At(width + 1).Strings()
+
+    pools := map[string][]int{} // the unchosen rows of every node
+    remaining := map[string]int64{}
+    order := []string{}
+    var total int64
+    for i, node := range nodes {
+        if _, ok := pools[node]; !ok {
+            remaining[node], _ = strconv.ParseInt(counts[i], 10, 64)
+            total += remaining[node]
+            order = append(order, node)
+        }
+        pools[node] = append(pools[node], i)
+    }
+
+    chosen := []int{}
+    for len(chosen) < n && total > 0 {
+        k := rnd.Int63n(total)
+        for _, node := range order {
+            if k >= remaining[node] {
+                k -= remaining[node]
+                continue
+            }
+
+            pool := pools[node]
+            j := rnd.Intn(len(pool))
+            chosen = append(chosen, pool[j])
+            pool[j] = pool[len(pool) - 1]
+            pools[node] = pool[:len(pool) - 1]
+            remaining[node]--
+            total--
+            break
+        }
+    }
+
+    cols := make([]Data, width)
+    for i := range cols {
+        cols[i] = pick(all.At(i), chosen)
+    }
+    return NewDataset(cols...)
+}
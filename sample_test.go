@@ -0,0 +1,61 @@
+package ep
+
+import (
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestSample(t *testing.T) {
+    inputs := []Dataset{}
+    for i := 0; i < 100; i++ {
+        inputs = append(inputs, NewDataset(make(Strs, 100)))
+    }
+
+    data, err := testRun(Sample(0.1), inputs...)
+    require.NoError(t, err)
+    require.InDelta(t, 1000, data.Len(), 200)
+}
+
+func TestReservoir(t *testing.T) {
+    data, err := testRun(Reservoir(5), NewDataset(Strs{"a", "b", "c"}))
+    require.NoError(t, err)
+    require.ElementsMatch(t, []string{"a", "b", "c"}, data.At(0).Strings())
+
+    data, err = testRun(Reservoir(5), NewDataset(numbers(0, 100)), NewDataset(numbers(100, 200)))
+    require.NoError(t, err)
+    require.Equal(t, 5, data.Len())
+    requireDistinct(t, data.At(0).Strings())
+}
+
+// Test that the local samples are merged into a single sample of n distinct
+// rows from all nodes
+func TestReservoirDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    runner := Pipeline(Scatter(), Reservoir(10))
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data, err := testRun(runner, NewDataset(numbers(0, 50)), NewDataset(numbers(50, 100)))
+    require.NoError(t, err)
+    require.Equal(t, 1, data.Width())
+    require.Equal(t, 10, data.Len())
+    requireDistinct(t, data.At(0).Strings())
+}
+
+// numbers returns the numbers in the range [from, to) as strings
+func numbers(from, to int) Strs {
+    res := Strs{}
+    for i := from; i < to; i++ {
+        res = append(res, strconv.Itoa(i))
+    }
+    return res
+}
+
+func requireDistinct(t *testing.T, strs []string) {
+    seen := map[string]bool{}
+    for _, s := range strs {
+        require.False(t, seen[s], "duplicate %s", s)
+        seen[s] = true
+    }
+}
@@ -0,0 +1,172 @@
+package ep
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Field is a single named column of a Schema. Unnamed columns have an empty
+// name.
+type Field struct {
+    Name string
+    Type Type
+}
+
+// Schema describes the columns of a Dataset, or the output of a Runner, by
+// their names and types. It allows referring to columns by name instead of by
+// their position. See Dataset.Schema() and SchemaOf().
+type Schema []Field
+
+// SchemaOf returns the Schema of the provided types, typically the Returns()
+// of a Runner. The names are the ones assigned to the types using As(), if any.
+func SchemaOf(types []Type) Schema {
+    schema := Schema{}
+    for _, t := range types {
+        f := Field{Type: t}
+        if named, ok := t.(interface { As() string }); ok {
+            f.Name = named.As()
+        }
+        schema = append(schema, f)
+    }
+    return schema
+}
+
+// WithSchema returns a Dataset of the same columns as the provided dataset,
+// named by the names of the schema. The types of the columns are always the
+// types of the data itself, thus only the names of the schema are used. It
+// panics if the schema doesn't have a field per column.
+func WithSchema(data Dataset, schema Schema) Dataset {
+    if len(schema) != data.Width() {
+        panic(fmt.Sprintf("schema of %d fields for %d columns", len(schema), data.Width()))
+    }
+    return newNamedDataset(schema.Names(), columns(data)...)
+}
+
+// Types of the fields
+func (s Schema) Types() []Type {
+    types := []Type{}
+    for _, f := range s {
+        types = append(types, f.Type)
+    }
+    return types
+}
+
+// Names of the fields, with empty names for unnamed fields
+func (s Schema) Names() []string {
+    names := []string{}
+    for _, f := range s {
+        names = append(names, f.Name)
+    }
+    return names
+}
+
+// Index returns the index of the first field with the provided name, or -1 if
+// there's no such field
+func (s Schema) Index(name string) int {
+    for i, f := range s {
+        if name != "" && f.Name == name {
+            return i
+        }
+    }
+    return -1
+}
+
+// String returns the "name:type" pairs of the fields
+func (s Schema) String() string {
+    fields := []string{}
+    for _, f := range s {
+        fields = append(fields, f.Name + ":" + f.Type.Name())
+    }
+    return "(" + strings.Join(fields, ", ") + ")"
+}
+
+// namedDataset is a dataset with named columns
+type namedDataset struct {
+    Cols dataset
+    Names []string
+}
+
+// newNamedDataset returns a dataset of the columns, named by the names, or an
+// unnamed dataset if none of the columns is named
+func newNamedDataset(names []string, cols ...Data) Dataset {
+    for _, name := range names {
+        if name != "" {
+            return namedDataset{dataset(cols), names}
+        }
+    }
+    return dataset(cols)
+}
+
+// namesOf returns the names of the columns of the dataset, or nil if it's not
+// named
+func namesOf(data Dataset) []string {
+    if named, ok := data.(namedDataset); ok {
+        return named.Names
+    }
+    return nil
+}
+
+// columns returns the unnamed columns of the dataset
+func columns(data Data) dataset {
+    switch set := data.(type) {
+    case dataset:
+        return set
+    case namedDataset:
+        return set.Cols
+    }
+
+    set := data.(Dataset)
+    cols := make(dataset, set.Width())
+    for i := range cols {
+        cols[i] = set.At(i)
+    }
+    return cols
+}
+
+func (set namedDataset) Width() int { return set.Cols.Width() }
+func (set namedDataset) Len() int { return set.Cols.Len() }
+func (set namedDataset) At(i int) Data { return set.Cols.At(i) }
+func (set namedDataset) Less(i, j int) bool { return set.Cols.Less(i, j) }
+func (set namedDataset) Swap(i, j int) { set.Cols.Swap(i, j) }
+func (set namedDataset) Strings() []string { return set.Cols.Strings() }
+func (set namedDataset) Type() Type { return set.Cols.Type() }
+
+// String formats the columns like an unnamed dataset
+func (set namedDataset) String() string { return fmt.Sprint(set.Cols) }
+
+// Append keeps the names of this dataset
+func (set namedDataset) Append(data Data) Data {
+    if set.Cols == nil {
+        other := data.(Dataset)
+        if namesOf(other) == nil {
+            return newNamedDataset(set.Names, columns(other)...)
+        }
+        return other
+    }
+
+    cols := set.Cols.Append(columns(data)).(dataset)
+    return namedDataset{cols, set.Names}
+}
+
+func (set namedDataset) Slice(start, end int) Data {
+    cols := set.Cols.Slice(start, end).(dataset)
+    return namedDataset{cols, set.Names}
+}
+
+func (set namedDataset) Schema() Schema {
+    schema := set.Cols.Schema()
+    for i := range schema {
+        schema[i].Name = set.Names[i]
+    }
+    return schema
+}
+
+func (set namedDataset) Filter(mask Bools) Dataset {
+    return pick(set, mask.Selection()).(Dataset)
+}
+
+func (set namedDataset) Row(i int) []interface{} { return set.Cols.Row(i) }
+func (set namedDataset) ColumnName(i int) string { return set.Names[i] }
+func (set namedDataset) ColumnIndex(name string) int {
+    return set.Schema().Index(name)
+}
@@ -0,0 +1,119 @@
+package ep
+
+import (
+    "fmt"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleSchemaOf() {
+    schema := SchemaOf([]Type{As(Str, "name"), Str})
+    fmt.Println(schema, schema.Index("name"), schema.Index("age"))
+
+    // Output: (name:string, :string) 0 -1
+}
+
+func ExampleWithSchema() {
+    data := NewDataset(Strs{"bob", "alice"}, Strs{"30", "25"})
+    data = WithSchema(data, Schema{{"name", Str}, {"age", Str}})
+    fmt.Println(data.ColumnName(1), data.ColumnIndex("name"), data.Schema())
+
+    // Output: age 0 (name:string, age:string)
+}
+
+func TestDatasetUnnamed(t *testing.T) {
+    data := NewDataset(Strs{"a"}, Strs{"b"})
+    require.Equal(t, "", data.ColumnName(0))
+    require.Equal(t, -1, data.ColumnIndex(""))
+    require.Equal(t, Schema{{"", Str}, {"", Str}}, data.Schema())
+    require.Panics(t, func() { WithSchema(data, Schema{{"a", Str}}) })
+}
+
+// names are kept when the datasets are manipulated
+func TestDatasetNamedManipulation(t *testing.T) {
+    data := WithSchema(NewDataset(Strs{"b", "a"}, Strs{"2", "1"}), Schema{{"k", Str}, {"v", Str}})
+
+    sliced := data.Slice(0, 1).(Dataset)
+    require.Equal(t, "v", sliced.ColumnName(1))
+    require.Equal(t, 1, sliced.Len())
+
+    appended := NewDataset(Strs{"c"}, Strs{"3"}).Append(data).(Dataset)
+    require.Equal(t, []string{"k", "v"}, appended.Schema().Names())
+    require.Equal(t, 3, appended.Len())
+
+    appended = data.Append(NewDataset(Strs{"c"}, Strs{"3"})).(Dataset)
+    require.Equal(t, 1, appended.ColumnIndex("v"))
+
+    require.Equal(t, "k", pick(data, []int{1}).(Dataset).ColumnName(0))
+    require.Equal(t, "k", appendClone(nil, data).ColumnName(0))
+}
+
+// names are propagated by the built-in runners
+func TestDatasetNamedRunners(t *testing.T) {
+    data := WithSchema(NewDataset(Strs{"b", "a"}, Strs{"2", "1"}), Schema{{"k", Str}, {"v", Str}})
+
+    res, err := testRun(Sort([]SortKey{{Col: 0}}), data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"k", "v"}, res.Schema().Names())
+    require.Equal(t, "[[a b] [1 2]]", fmt.Sprint(res))
+
+    res, err = testRun(Pick(1, 0), data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"v", "k"}, res.Schema().Names())
+
+    res, err = testRun(Pipeline(Rename("v", "value"), Project(PassThrough(), Pick(0))), data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"k", "value", "k"}, res.Schema().Names())
+}
+
+// names are serialized along with the data, in order to be distributed
+func TestDatasetNamedGob(t *testing.T) {
+    var data Data = WithSchema(NewDataset(Strs{"a"}), Schema{{"k", Str}})
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, 0, decoded.(Dataset).ColumnIndex("k"))
+}
+
+// names assigned with As() are serialized along with the types, in order to
+// be referenced by the runners on any node
+func TestSchemaOfGob(t *testing.T) {
+    types := []Type{As(Str, "k"), Str, As(As(Int, "a"), "b")}
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&types))
+
+    var decoded []Type
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, "(k:string, :string, b:int)", SchemaOf(decoded).String())
+    require.True(t, Equal(Int, decoded[2]))
+}
+
+// names survive the exchanges between nodes, and joins of the exchanged data
+func TestDatasetNamedDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    data := WithSchema(NewDataset(Strs{"a", "b", "c"}, Strs{"1", "2", "3"}), Schema{{"k", Str}, {"v", Str}})
+    for _, runner := range []Runner{
+        Pipeline(Scatter(), Gather()),
+        Pipeline(Repartition(0), Sort([]SortKey{{Col: 0}}), Gather()),
+        Pipeline(Scatter(), Join(InnerJoin, []int{0}, []int{0}, PassThrough(), Rename("k", "k2")), Gather()),
+        Pipeline(Scatter(), DistributedJoin(InnerJoin, []int{0}, []int{0}, PassThrough(), Rename("k", "k2"), 0), Gather()),
+    } {
+        runner = dists[0].Distribute(runner, ":5551", ":5552")
+        res, err := testRun(runner, data)
+        require.NoError(t, err)
+        require.Equal(t, 3, res.Len())
+        require.Equal(t, "k", res.ColumnName(0))
+        require.Equal(t, "v", res.ColumnName(1))
+    }
+
+    runner := Pipeline(Scatter(), GroupBy([]int{0}, Count()), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+    res, err := testRun(runner, data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"k", ""}, res.Schema().Names())
+}
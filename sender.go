@@ -0,0 +1,104 @@
+package ep
+
+import (
+    "io"
+    "sync"
+    "time"
+)
+
+// senderCloseTimeout bounds the time to wait for the error that fails an
+// exchange to be sent to the peers, see exchange.Close
+var senderCloseTimeout = time.Second
+
+// sender encodes the messages of an exchange to a single destination in its
+// own go-routine, such that the serialization of a dataset overlaps with the
+// production of the next ones, rather than stalling the sending runner. Up to
+// Buffers.Send messages are queued while another is encoded. As the messages
+// are encoded asynchronously, the error of a message is returned by the
+// subsequent calls, and by Flush
+type sender struct {
+    enc encoder
+    queue chan interface{}
+    done chan struct{} // closed when the encoding stops, see err
+
+    l sync.Mutex // guards closed
+    closed bool
+    err error // the first error of the encoding, set before done is closed
+}
+
+func newSender(enc encoder, size int) *sender {
+    s := &sender{enc: enc, queue: make(chan interface{}, size), done: make(chan struct{})}
+    go s.run()
+    return s
+}
+
+func (s *sender) run() {
+    defer close(s.done)
+    for e := range s.queue {
+        if flushed, ok := e.(chan struct{}); ok {
+            close(flushed)
+            continue
+        }
+
+        err := s.enc.Encode(e)
+        if err != nil {
+            s.err = err
+            return
+        }
+    }
+}
+
+// Encode queues the message to be encoded, and returns the error of a previous
+// message, if any. Blocks while the queue is full
+func (s *sender) Encode(e interface{}) error {
+    s.l.Lock()
+    defer s.l.Unlock()
+    if s.closed {
+        return io.ErrClosedPipe
+    }
+
+    select {
+    case <- s.done:
+        return s.err
+    default:
+    }
+
+    select {
+    case s.queue <- e:
+        return nil
+    case <- s.done:
+        return s.err
+    }
+}
+
+// Flush waits for all of the queued messages to be encoded, and returns the
+// first error, if any
+func (s *sender) Flush() error {
+    flushed := make(chan struct{})
+    err := s.Encode(flushed)
+    if err != nil {
+        return err
+    }
+
+    select {
+    case <- flushed:
+        return nil
+    case <- s.done:
+        return s.err
+    }
+}
+
+// Close waits for the queued messages to be encoded, and stops the sender.
+// The subsequent messages fail. Blocks until the messages are written, or
+// fail once the connection is closed
+func (s *sender) Close() error {
+    s.l.Lock()
+    if !s.closed {
+        s.closed = true
+        close(s.queue)
+    }
+    s.l.Unlock()
+
+    <- s.done
+    return s.err
+}
@@ -0,0 +1,60 @@
+package ep
+
+import (
+    "io"
+    "errors"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// blockingEncoder records the encoded messages, each after it's released, and
+// fails the messages that are errors
+type blockingEncoder struct {
+    release chan struct{}
+    encoded []interface{}
+}
+
+func (enc *blockingEncoder) Encode(e interface{}) error {
+    <- enc.release
+    if err, ok := e.(error); ok {
+        return err
+    }
+
+    enc.encoded = append(enc.encoded, e)
+    return nil
+}
+
+// Tests that the messages are queued while the previous one is encoded, and
+// encoded in order
+func TestSender(t *testing.T) {
+    enc := &blockingEncoder{release: make(chan struct{})}
+    s := newSender(enc, 2)
+
+    // the first message is encoding, while the other two are queued
+    for i := 0; i < 3; i++ {
+        require.NoError(t, s.Encode(i))
+    }
+
+    flushed := make(chan error)
+    go func() { flushed <- s.Flush() }()
+    close(enc.release)
+    require.NoError(t, <- flushed)
+    require.Equal(t, []interface{}{0, 1, 2}, enc.encoded)
+
+    require.NoError(t, s.Close())
+    require.Equal(t, io.ErrClosedPipe, s.Encode(3))
+}
+
+// Tests that the error of a message is returned by the subsequent calls
+func TestSenderErr(t *testing.T) {
+    enc := &blockingEncoder{release: make(chan struct{})}
+    close(enc.release)
+
+    s := newSender(enc, 2)
+    bad := errors.New("bad connection")
+    require.NoError(t, s.Encode(bad))
+    require.Equal(t, bad, s.Flush())
+    require.Equal(t, bad, s.Encode(1))
+    require.Equal(t, bad, s.Close())
+    require.Empty(t, enc.encoded)
+}
@@ -0,0 +1,65 @@
+package ep
+
+import (
+    "context"
+)
+
+// RunnerSetup is optionally implemented by Runners that require per-node
+// resources, like database pools, file handles or caches, which are better
+// opened once per run than once per batch. In a distributed run, every node
+// sets up all of the runners of the plan that implement it before running the
+// plan, and tears them down after the plan has completed on that node, even if
+// it has failed. The context is the one of the run on that node (see
+// Distribute). Runners that aren't distributed aren't set up.
+type RunnerSetup interface {
+
+    // Setup is called once per node, before the runner runs. An error fails
+    // the run, without running the plan
+    Setup(ctx context.Context) error
+
+    // Teardown is called once per node after the runner has completed, only
+    // if its Setup has succeeded. An error fails the run, unless it has
+    // already failed
+    Teardown(ctx context.Context) error
+}
+
+// setup all of the runners of the plan that implement RunnerSetup, in order,
+// and returns a function that tears them down in reverse order. If any of
+// them fails, the ones that were already set up are torn down.
+func setup(ctx context.Context, r Runner) (teardown func() error, err error) {
+    var done []RunnerSetup
+    teardown = func() (err error) {
+        for i := len(done) - 1; i >= 0; i-- {
+            if err1 := done[i].Teardown(ctx); err == nil {
+                err = err1
+            }
+        }
+        return err
+    }
+
+    for _, s := range setups(r) {
+        err = s.Setup(ctx)
+        if err != nil {
+            teardown()
+            return nil, err
+        }
+        done = append(done, s)
+    }
+    return teardown, nil
+}
+
+// setups returns the runners of the plan that implement RunnerSetup, with the
+// composite runners before their inner runners
+func setups(r Runner) []RunnerSetup {
+    res := []RunnerSetup{}
+    if s, ok := r.(RunnerSetup); ok {
+        res = append(res, s)
+    }
+
+    if c, ok := r.(composite); ok {
+        for _, in := range c.inner() {
+            res = append(res, setups(in)...)
+        }
+    }
+    return res
+}
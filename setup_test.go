@@ -0,0 +1,140 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "sync"
+    "context"
+    "testing"
+    "github.com/satori/go.uuid"
+    "github.com/stretchr/testify/require"
+)
+
+var _ = registerGob(&pooled{})
+
+// pooled emits the node it runs on after it was set up, while recording the
+// setups and teardowns of every node by its key
+type pooled struct {
+    Key string
+    SetupErr string
+    TeardownErr string
+    ready bool
+}
+
+// newPooled returns a pooled runner of a unique key
+func newPooled(setupErr, teardownErr string) *pooled {
+    return &pooled{uuid.NewV4().String(), setupErr, teardownErr, false}
+}
+
+var pooledCalls = struct {
+    sync.Mutex
+    calls map[string][]string
+}{calls: map[string][]string{}}
+
+func (r *pooled) call(ctx context.Context, name string) {
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    pooledCalls.Lock()
+    defer pooledCalls.Unlock()
+    pooledCalls.calls[r.Key] = append(pooledCalls.calls[r.Key], name + thisNode)
+}
+
+// calls returns the calls so far of the runners of the same key
+func (r *pooled) calls() []string {
+    pooledCalls.Lock()
+    defer pooledCalls.Unlock()
+    return append([]string{}, pooledCalls.calls[r.Key]...)
+}
+
+func (r *pooled) Setup(ctx context.Context) error {
+    r.call(ctx, "setup")
+    if r.SetupErr != "" {
+        return fmt.Errorf(r.SetupErr)
+    }
+
+    r.ready = true
+    return nil
+}
+
+func (r *pooled) Teardown(ctx context.Context) error {
+    r.call(ctx, "teardown")
+    if r.TeardownErr != "" {
+        return fmt.Errorf(r.TeardownErr)
+    }
+    return nil
+}
+
+func (*pooled) Returns() []Type { return []Type{Str} }
+func (r *pooled) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+    if !r.ready {
+        return fmt.Errorf("not ready")
+    }
+
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    out <- NewDataset(Strs{thisNode})
+    return nil
+}
+
+func TestRunnerSetup(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    r := newPooled("", "")
+    runner := dists[0].Distribute(Pipeline(r, Gather()), ":5551", ":5552")
+
+    data, err := testRun(runner)
+    require.NoError(t, err)
+    require.Equal(t, []string{":5551", ":5552"}, sortedStrings(data.At(0).Strings()))
+
+    // once per node, torn down after the run on that node
+    calls := r.calls()
+    expected := []string{"setup:5551", "setup:5552", "teardown:5551", "teardown:5552"}
+    require.Equal(t, expected, sortedStrings(calls))
+    for _, node := range []string{":5551", ":5552"} {
+        require.True(t, indexOf(calls, "setup" + node) < indexOf(calls, "teardown" + node))
+    }
+
+    // setup errors fail the run, without tearing down
+    r = newPooled("bad setup", "")
+    runner = dists[0].Distribute(Pipeline(r, Gather()), ":5551", ":5552")
+    _, err = testRun(runner)
+    require.Error(t, err)
+    require.Equal(t, "bad setup", err.Error())
+    require.NotContains(t, r.calls(), "teardown:5551")
+
+    r = newPooled("", "bad teardown")
+    runner = dists[0].Distribute(Pipeline(r, Gather()), ":5551", ":5552")
+    _, err = testRun(runner)
+    require.Error(t, err)
+    require.Equal(t, "bad teardown", err.Error())
+}
+
+// Tests that the runners that were already set up are torn down in reverse
+// order, when a later setup fails
+func TestRunnerSetupOrder(t *testing.T) {
+    r := newPooled("", "")
+    runner := Project(r, Pipeline(PassThrough(), &pooled{Key: r.Key, SetupErr: "bad setup"}))
+    _, err := setup(context.Background(), runner)
+    require.Error(t, err)
+    require.Equal(t, []string{"setup", "setup", "teardown"}, r.calls())
+
+    // not set up when not distributed
+    r = newPooled("", "")
+    _, err = testRun(r)
+    require.Error(t, err)
+    require.Empty(t, r.calls())
+}
+
+func sortedStrings(strs []string) []string {
+    res := append([]string{}, strs...)
+    sort.Strings(res)
+    return res
+}
+
+func indexOf(strs []string, s string) int {
+    for i, str := range strs {
+        if str == s {
+            return i
+        }
+    }
+    return -1
+}
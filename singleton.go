@@ -0,0 +1,64 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&singleton{})
+
+// Singleton returns a Runner that runs the provided runner only on the master
+// node of a distributed run. On all other nodes, its input is discarded and it
+// produces no output. Useful for side effects that must happen exactly once,
+// like writing a final summary file, typically after a Gather. When not
+// distributed, it's the runner itself. See SingletonOn
+func Singleton(r Runner) Runner {
+    return SingletonOn("", r)
+}
+
+// SingletonOn is like Singleton, but runs the runner on the provided node
+// instead of the master node. The node isn't required to participate in the
+// distributed run, in which case the runner doesn't run at all.
+func SingletonOn(node string, r Runner) Runner {
+    return &singleton{node, r}
+}
+
+type singleton struct {
+    Node string // or empty for the master node
+    Runner Runner
+}
+
+func (r *singleton) Returns() []Type { return r.Runner.Returns() }
+func (r *singleton) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *singleton) Run(ctx context.Context, inp, out chan Dataset) error {
+    thisNode, ok := ctx.Value("ep.ThisNode").(string)
+    if !ok {
+        return r.Runner.Run(ctx, inp, out) // not distributed
+    }
+
+    node := r.Node
+    if node == "" {
+        node, _ = ctx.Value("ep.MasterNode").(string)
+    }
+
+    if thisNode == node {
+        return r.Runner.Run(ctx, inp, out)
+    }
+
+    for _ = range inp {}
+    return nil
+}
+
+func (r *singleton) String() string {
+    if r.Node == "" {
+        return "master"
+    }
+    return r.Node
+}
+
+func (r *singleton) inner() []Runner { return []Runner{r.Runner} }
+func (r *singleton) withInner(inner []Runner) Runner {
+    return &singleton{r.Node, inner[0]}
+}
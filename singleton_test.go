@@ -0,0 +1,49 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleSingleton() {
+    runner := Singleton(PassThrough())
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}))
+    fmt.Println(data, err)
+
+    // Output: [[a b]] <nil>
+}
+
+func TestSingletonDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    data1 := NewDataset(Strs{"hello", "world"})
+    data2 := NewDataset(Strs{"foo", "bar"})
+
+    // only the master's share of the scattered input
+    runner := Pipeline(Scatter(), Singleton(&nodeAddr{}), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+    data, err := testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, "[[foo bar] [:5551 :5551]]", fmt.Sprint(data))
+
+    runner = Pipeline(Scatter(), SingletonOn(":5552", &nodeAddr{}), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+    data, err = testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, "[[hello world] [:5552 :5552]]", fmt.Sprint(data))
+
+    // runs exactly once, on all of the gathered input
+    runner = Pipeline(Scatter(), Gather(), Singleton(&nodeAddr{}))
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+    data, err = testRun(runner, data1, data2)
+    require.NoError(t, err)
+    require.Equal(t, 4, data.Len())
+}
+
+func TestSingletonExplain(t *testing.T) {
+    plan := Explain(SingletonOn(":5552", Pick(0)))
+    require.Equal(t, "Singleton", plan.Kind)
+    require.Equal(t, ":5552", plan.Detail)
+    require.Equal(t, "Pick", plan.Children[0].Kind)
+}
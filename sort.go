@@ -0,0 +1,280 @@
+package ep
+
+import (
+    "io"
+    "sort"
+    "context"
+)
+
+var _ = registerGob(&sorter{})
+
+// SortBuffer is the default maximum number of rows that Sort buffers in memory
+// before spilling them to disk.
+var SortBuffer = 100000
+
+// SortKey is a column to sort by, and the direction of the sort
+type SortKey struct {
+    Col int
+    Desc bool
+}
+
+// Sort returns a Runner that sorts all of its input by the provided columns,
+// comparing their values with CompareAt (consistent with Data.Less()). Rows
+// with equal keys maintain their input order. Up to SortBuffer rows are sorted
+// in memory, within the memory budget (see WithMemory); beyond that, sorted
+// runs are spilled to temporary files and merged.
+func Sort(keys []SortKey) Runner {
+    return SortSpill(keys, SortBuffer)
+}
+
+// SortSpill is like Sort, with an explicit maximum number of rows to buffer in
+// memory before spilling to disk.
+func SortSpill(keys []SortKey, buffer int) Runner {
+    return &sorter{keys, buffer}
+}
+
+type sorter struct {
+    Keys []SortKey
+    Buffer int
+}
+
+func (*sorter) Returns() []Type { return []Type{Wildcard} }
+func (r *sorter) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    var buff Dataset
+    var runs []*spillFile
+    defer func() {
+        for _, f := range runs {
+            f.Close()
+        }
+    }()
+
+    mem := memoryOf(ctx)
+    reserved := 0
+    defer func() { mem.Release(reserved) }()
+
+    for data := range inp {
+        if data.Len() == 0 {
+            continue
+        }
+
+        size := estimateBytes(data)
+        buff = appendClone(buff, data)
+        if mem.Reserve(size) {
+            reserved += size
+            if buff.Len() < r.Buffer {
+                continue
+            }
+        }
+
+        // buffer is full or out of memory, spill a sorted run to disk
+        sort.Stable(&sortable{buff, r.Keys})
+        f, err := newSpillFile()
+        if err != nil {
+            return err
+        }
+
+        runs = append(runs, f)
+        err = f.Write(buff)
+        if err != nil {
+            return err
+        }
+
+        Release(buff) // spilled, no longer referenced
+        buff = nil
+        mem.Release(reserved)
+        reserved = 0
+    }
+
+    if buff != nil {
+        sort.Stable(&sortable{buff, r.Keys})
+    }
+
+    if len(runs) == 0 {
+        if buff != nil {
+            select {
+            case out <- buff:
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+        return nil
+    }
+
+    return r.merge(ctx, runs, buff, out)
+}
+
+// merge the spilled runs and the remaining in-memory buffer, and emit the
+// merged rows in batches. The rows of every batch are first collected by their
+// cursors, and then copied in bulk. See mergeBatch
+func (r *sorter) merge(ctx context.Context, runs []*spillFile, buff Dataset, out chan Dataset) error {
+    cursors := []*sortCursor{}
+    for _, f := range runs {
+        rd, err := f.Reader()
+        if err != nil {
+            return err
+        }
+        cursors = append(cursors, &sortCursor{r: rd})
+    }
+
+    if buff != nil {
+        cursors = append(cursors, &sortCursor{Data: buff})
+    }
+
+    // the index of the cursor of every merged row of the current batch
+    var src []int
+    for {
+        // find the cursor with the lowest current row. Earlier runs win ties,
+        // in order to maintain the input order.
+        min := -1
+        for i, c := range cursors {
+            ok, err := c.Next()
+            if err != nil {
+                return err
+            }
+
+            if ok && (min < 0 || lessRows(c.Data, c.I, cursors[min].Data, cursors[min].I, r.Keys)) {
+                min = i
+            }
+        }
+
+        if min < 0 {
+            break // all cursors are exhausted
+        }
+
+        cursors[min].I++
+        src = append(src, min)
+        if len(src) < BatchSize {
+            continue
+        }
+
+        select {
+        case out <- mergeBatch(cursors, src):
+            src = nil
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+    }
+
+    if len(src) > 0 {
+        select {
+        case out <- mergeBatch(cursors, src):
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+// mergeBatch returns the rows taken from the cursors, in their merged order,
+// where src is the index of the cursor of every row. The rows are copied per
+// cursor, and then moved into their order in-place.
+func mergeBatch(cursors []*sortCursor, src []int) Dataset {
+    var res Dataset
+    offsets := make([]int, len(cursors))
+    for i, c := range cursors {
+        c.take()
+        if res != nil {
+            offsets[i] = res.Len()
+        }
+
+        if c.taken != nil {
+            res = appendClone(res, c.taken)
+            c.taken = nil
+        }
+    }
+
+    // dest is the position in the merged order of every row of res
+    dest := make([]int, len(src))
+    for i, c := range src {
+        dest[offsets[c]] = i
+        offsets[c]++
+    }
+
+    // swapping a row into its position, moves the other row into the position
+    // of the former, thus every swap completes at least one row
+    for i := range dest {
+        for dest[i] != i {
+            d := dest[i]
+            res.Swap(i, d)
+            dest[i], dest[d] = dest[d], dest[i]
+        }
+    }
+    return res
+}
+
+// sortCursor is the current position in a sorted run: either in-memory or
+// read in batches from a spilled file. The rows before the position, starting
+// from `from`, were taken into the current merged batch. See take
+type sortCursor struct {
+    Data Dataset
+    I int
+    r *spillReader
+    from int
+    taken Dataset
+}
+
+// take copies the rows taken from the current batch, before the batch is
+// replaced, or the merged batch is emitted
+func (c *sortCursor) take() {
+    if c.I > c.from {
+        c.taken = appendClone(c.taken, c.Data.Slice(c.from, c.I).(Dataset))
+    }
+    c.from = c.I
+}
+
+// Next ensures that the cursor points to a valid row, reading the next batch
+// if needed. Returns false when the run is exhausted
+func (c *sortCursor) Next() (bool, error) {
+    for c.Data == nil || c.I >= c.Data.Len() {
+        if c.r == nil {
+            return false, nil
+        }
+
+        c.take() // before the batch is replaced
+        data, err := c.r.Next()
+        if err == io.EOF {
+            c.r = nil
+            return false, nil
+        } else if err != nil {
+            return false, err
+        }
+
+        c.Data, c.I, c.from = data, 0, 0
+    }
+    return true, nil
+}
+
+// sortable implements sort.Interface over the sort keys of a dataset
+type sortable struct {
+    Dataset
+    Keys []SortKey
+}
+
+func (s *sortable) Less(i, j int) bool {
+    return compareBy(s.Keys, s.Dataset, i, s.Dataset, j) < 0
+}
+
+// lessRows returns true if row i of dataset a is ordered before row j of
+// dataset b, by the sort keys. See CompareBy
+func lessRows(a Dataset, i int, b Dataset, j int, keys []SortKey) bool {
+    return compareBy(keys, a, i, b, j) < 0
+}
+
+// appendClone appends a copy of the data to the buffer, which may be nil.
+// Unlike Dataset.Append, the data is never referenced by the result, thus the
+// result can be modified in-place without modifying the data.
+func appendClone(buff Dataset, data Dataset) Dataset {
+    if buff == nil {
+        return Clone(data).(Dataset)
+    }
+
+    cols := make([]Data, data.Width())
+    for i := range cols {
+        cols[i] = appendData(buff.At(i), data.At(i))
+    }
+
+    if namesOf(buff) != nil {
+        return newNamedDataset(namesOf(buff), cols...)
+    }
+    return newNamedDataset(namesOf(data), cols...)
+}
@@ -0,0 +1,91 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "context"
+    "strings"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleSort() {
+    runner := Sort([]SortKey{{Col: 0}, {Col: 1, Desc: true}})
+    data1 := NewDataset(Strs{"b", "a", "c"}, Strs{"1", "2", "3"})
+    data2 := NewDataset(Strs{"a", "b"}, Strs{"4", "5"})
+    data, err := testRun(runner, data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[a a b b c] [4 2 5 1 3]] <nil>
+}
+
+// sorting must not modify the input in-place
+func TestSortNoInPlace(t *testing.T) {
+    strs := Strs{"c", "b", "a"}
+    data, err := testRun(Sort([]SortKey{{Col: 0}}), NewDataset(strs))
+    require.NoError(t, err)
+    require.Equal(t, "[[a b c]]", fmt.Sprintf("%v", data))
+    require.Equal(t, Strs{"c", "b", "a"}, strs)
+}
+
+// sorting more rows than the buffer spills sorted runs to disk, and merges them
+func TestSortSpill(t *testing.T) {
+    inputs := []Dataset{}
+    expected := []string{}
+    for i := 0; i < 50; i++ {
+        keys := Strs{}
+        values := Strs{}
+        for j := 0; j < 100; j++ {
+            k := strconv.Itoa((i * 7919 + j * 104729) % 1000)
+            keys = append(keys, k)
+            values = append(values, k + "!")
+            expected = append(expected, k)
+        }
+        inputs = append(inputs, NewDataset(keys, values))
+    }
+
+    sort.Strings(expected)
+    data, err := testRun(SortSpill([]SortKey{{Col: 0}}, 1000), inputs...)
+    require.NoError(t, err)
+    require.Equal(t, len(expected), data.Len())
+    require.Equal(t, expected, data.At(0).Strings())
+
+    for i, k := range data.At(0).Strings() {
+        require.Equal(t, k + "!", data.At(1).Strings()[i])
+    }
+}
+
+// merging the spilled runs maintains the input order of equal keys, across
+// the merged batches
+func TestSortSpillStable(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 3
+
+    inputs := []Dataset{}
+    for i := 0; i < 10; i++ {
+        seq := strconv.Itoa(i)
+        inputs = append(inputs, NewDataset(Ints{int64(i % 3), 1}, Strs{seq, seq}))
+    }
+
+    data, err := testRun(SortSpill([]SortKey{{Col: 0}}, 4), inputs...)
+    require.NoError(t, err)
+    require.Equal(t, 20, data.Len())
+    require.Equal(t, "0 0 0 0 1 1 1 1 1 1 1 1 1 1 1 1 1 2 2 2", strings.Join(data.At(0).Strings(), " "))
+    require.Equal(t, "0 3 6 9 0 1 1 2 3 4 4 5 6 7 7 8 9 2 5 8", strings.Join(data.At(1).Strings(), " "))
+}
+
+// merging stops when the context is canceled, while the output is blocked
+func TestSortSpillCancel(t *testing.T) {
+    inp := make(chan Dataset, 3)
+    for i := 0; i < 3; i++ {
+        inp <- NewDataset(Ints{3, 2, 1})
+    }
+    close(inp)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+
+    err := SortSpill([]SortKey{{Col: 0}}, 2).Run(ctx, inp, make(chan Dataset))
+    require.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,310 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "sync"
+    "time"
+    "context"
+    "encoding/gob"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&speculate{}, &specMsg{})
+
+const (
+    specReq = 1 // request for the next item to run
+    specItem = 2 // response with an item to run
+    specNone = 3 // response indicating there are no more items to run
+    specCommit = 4 // request to commit the results of an item
+    specGranted = 5 // response granting the commit
+    specDenied = 6 // response denying the commit, the item was already committed
+)
+
+// Speculate returns a source Runner that runs the provided runner for every one
+// of the work items, with a dataset of the item as its input. It's similar to
+// pipelining Steal into the runner, with speculative execution of stragglers,
+// like in MapReduce: the items are scheduled by the master node to the nodes as
+// they become idle, and once there are no pending items, idle nodes re-execute
+// the items that are still running on other nodes, the longest running first.
+// The results of every item are emitted only by the node that completes it
+// first, thus they're buffered until it does, and the runner must not exchange
+// data with its peers. It cuts the tail latency of heterogeneous clusters, at
+// the cost of redundant work.
+func Speculate(r Runner, items ...string) Runner {
+    return &speculate{UID: uuid.NewV4().String(), Runner: r, Items: items}
+}
+
+type speculate struct {
+    UID string
+    Runner Runner
+    Items []string
+}
+
+func (s *speculate) Returns() []Type { return s.Runner.Returns() }
+func (s *speculate) returnsFrom(inp []Type) []Type {
+    return returnsFrom(s.Runner, []Type{Str})
+}
+
+func (s *speculate) inner() []Runner { return []Runner{s.Runner} }
+func (s *speculate) withInner(inner []Runner) Runner {
+    return &speculate{s.UID, inner[0], s.Items}
+}
+
+func (s *speculate) Run(ctx context.Context, inp, out chan Dataset) error {
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    masterNode, _ := ctx.Value("ep.MasterNode").(string)
+
+    var next func() (int, bool, error)
+    var commit func(i int) (bool, error)
+    wait := func() error { return nil }
+    if len(allNodes) == 0 || thisNode == masterNode {
+        // schedule the items of all of the nodes, serving the peers in the
+        // background until they're done
+        sp := newSpeculator(len(s.Items))
+        next = func() (int, bool, error) {
+            i, ok := sp.Next(thisNode)
+            return i, ok, nil
+        }
+
+        commit = func(i int) (bool, error) { return sp.Commit(i), nil }
+
+        var conns []net.Conn
+        var wg sync.WaitGroup
+        defer func() {
+            for _, conn := range conns {
+                conn.Close()
+            }
+        }()
+
+        for _, n := range allNodes {
+            if n == thisNode {
+                continue
+            }
+
+            conn, err := s.connect(ctx, n)
+            if err != nil {
+                return err
+            }
+
+            conns = append(conns, conn)
+            wg.Add(1)
+            go func(conn net.Conn, node string) {
+                defer wg.Done()
+                sp.Serve(conn, node)
+            }(conn, n)
+        }
+
+        // once there are no more items to run, wait for the peers to finish
+        // their own before closing the connections
+        wait = func() error {
+            served := make(chan struct{})
+            go func() {
+                wg.Wait()
+                close(served)
+            }()
+
+            select {
+            case <- served:
+                return nil
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+    } else {
+        conn, err := s.connect(ctx, masterNode)
+        if err != nil {
+            return err
+        }
+        defer conn.Close()
+
+        c := &specClient{gob.NewEncoder(conn), NewWireDecoder(conn)}
+        next = func() (int, bool, error) {
+            msg, err := c.Request(&specMsg{Kind: specReq})
+            if err != nil {
+                return 0, false, err
+            } else if msg.Kind != specItem {
+                return 0, false, nil
+            } else if msg.Index < 0 || msg.Index >= len(s.Items) {
+                return 0, false, fmt.Errorf("ep: speculated item %d out of range", msg.Index)
+            }
+            return msg.Index, true, nil
+        }
+
+        commit = func(i int) (bool, error) {
+            msg, err := c.Request(&specMsg{Kind: specCommit, Index: i})
+            return err == nil && msg.Kind == specGranted, err
+        }
+    }
+
+    for {
+        i, ok, err := next()
+        if err != nil {
+            return err
+        } else if !ok {
+            return wait()
+        }
+
+        var res []Dataset
+        inp := []Dataset{NewDataset(Strs{s.Items[i]})}
+        err = Run(ctx, s.Runner, inp, func(data Dataset) error {
+            res = append(res, data)
+            return nil
+        })
+
+        if err != nil {
+            return err
+        }
+
+        granted, err := commit(i)
+        if err != nil {
+            return err
+        }
+
+        for _, data := range res {
+            if !granted {
+                break // completed first by another node
+            }
+
+            select {
+            case out <- data:
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+    }
+}
+
+func (s *speculate) connect(ctx context.Context, node string) (net.Conn, error) {
+    dist := ctx.Value("ep.Distributer").(interface {
+        Connect(addr, uid string) (net.Conn, error)
+    })
+    return dist.Connect(node, s.UID)
+}
+
+// speculator schedules the items of a speculative run on the master node. See
+// Speculate
+type speculator struct {
+    l sync.Mutex
+    pending []int
+    running map[int]*specAttempts // uncommitted items by their index
+    committed map[int]bool
+}
+
+// specAttempts are the nodes that run an item, and the time of the first one
+type specAttempts struct {
+    Nodes []string
+    Since time.Time
+}
+
+func newSpeculator(n int) *speculator {
+    sp := &speculator{running: map[int]*specAttempts{}, committed: map[int]bool{}}
+    for i := 0; i < n; i++ {
+        sp.pending = append(sp.pending, i)
+    }
+    return sp
+}
+
+// Next returns the next item to be run by the node: a pending item, or the
+// longest running item of another node that wasn't speculated yet. Returns
+// false when there are no such items
+func (sp *speculator) Next(node string) (int, bool) {
+    sp.l.Lock()
+    defer sp.l.Unlock()
+
+    if len(sp.pending) > 0 {
+        i := sp.pending[0]
+        sp.pending = sp.pending[1:]
+        sp.running[i] = &specAttempts{[]string{node}, time.Now()}
+        return i, true
+    }
+
+    best := -1
+    for i, a := range sp.running {
+        if len(a.Nodes) > 1 || a.Nodes[0] == node {
+            continue
+        }
+
+        if best < 0 || a.Since.Before(sp.running[best].Since) {
+            best = i
+        } else if a.Since.Equal(sp.running[best].Since) && i < best {
+            best = i
+        }
+    }
+
+    if best < 0 {
+        return 0, false
+    }
+
+    sp.running[best].Nodes = append(sp.running[best].Nodes, node)
+    return best, true
+}
+
+// Commit the results of the item, returns false if it was already committed
+// by another node, in which case the results should be discarded
+func (sp *speculator) Commit(i int) bool {
+    sp.l.Lock()
+    defer sp.l.Unlock()
+    if sp.committed[i] || sp.running[i] == nil {
+        return false
+    }
+
+    sp.committed[i] = true
+    delete(sp.running, i)
+    return true
+}
+
+// Serve the requests of a peer node until it's done, or disconnected
+func (sp *speculator) Serve(conn net.Conn, node string) error {
+    enc, dec := gob.NewEncoder(conn), NewWireDecoder(conn)
+    for {
+        msg := &specMsg{}
+        err := dec.Decode(msg)
+        if err != nil {
+            return err
+        }
+
+        resp := &specMsg{Kind: specNone}
+        switch msg.Kind {
+        case specReq:
+            if i, ok := sp.Next(node); ok {
+                resp = &specMsg{Kind: specItem, Index: i}
+            }
+        case specCommit:
+            resp.Kind = specDenied
+            if sp.Commit(msg.Index) {
+                resp.Kind = specGranted
+            }
+        default:
+            return fmt.Errorf("ep: unexpected speculation message %d", msg.Kind)
+        }
+
+        err = enc.Encode(resp)
+        if err != nil || resp.Kind == specNone {
+            return err // the peer is done
+        }
+    }
+}
+
+// specClient is the connection of a peer node to the speculator on the master
+// node. There's at most one outstanding request at a time
+type specClient struct {
+    enc *gob.Encoder
+    dec *WireDecoder
+}
+
+func (c *specClient) Request(msg *specMsg) (*specMsg, error) {
+    err := c.enc.Encode(msg)
+    if err != nil {
+        return nil, err
+    }
+
+    resp := &specMsg{}
+    return resp, c.dec.Decode(resp)
+}
+
+type specMsg struct {
+    Kind int
+    Index int
+}
@@ -0,0 +1,72 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleSpeculate() {
+    runner := Speculate(PassThrough(), "a", "b", "c")
+    data, err := testRun(runner)
+    fmt.Println(data, err)
+
+    // Output: [[a b c]] <nil>
+}
+
+func TestSpeculator(t *testing.T) {
+    sp := newSpeculator(3)
+    for _, expected := range []struct { Node string; Index int }{
+        {"a", 0}, {"b", 1}, {"a", 2},
+        {"b", 0}, // speculate the longest running item of another node
+        {"a", 1},
+    } {
+        i, ok := sp.Next(expected.Node)
+        require.True(t, ok)
+        require.Equal(t, expected.Index, i)
+    }
+
+    // all of the items are either speculated, or running on the same node
+    _, ok := sp.Next("a")
+    require.False(t, ok)
+
+    require.True(t, sp.Commit(0))
+    require.False(t, sp.Commit(0)) // completed first by the other node
+    require.True(t, sp.Commit(2))
+    require.False(t, sp.Commit(3)) // out of range
+
+    _, ok = sp.Next("c")
+    require.False(t, ok)
+}
+
+// Tests that every item is emitted exactly once, even when the items of the
+// slow node are speculated by the fast node
+func TestSpeculateDistributed(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    items := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+    work := Pipeline(&slowNode{":5552"}, &nodeAddr{})
+    runner := Pipeline(Speculate(work, items...), Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data, err := testRun(runner)
+    require.NoError(t, err)
+
+    res := data.At(0).Strings()
+    sort.Strings(res)
+    require.Equal(t, items, res)
+}
+
+func TestSpeculateErr(t *testing.T) {
+    runner := Speculate(&ErrRunner{fmt.Errorf("bad item")}, "a", "b")
+    _, err := testRun(runner)
+    require.Error(t, err)
+    require.Equal(t, "bad item", err.Error())
+}
+
+func TestSpeculateExplain(t *testing.T) {
+    plan := Explain(Speculate(Pick(0), "a"))
+    require.Equal(t, "Speculate", plan.Kind)
+    require.Equal(t, "Pick", plan.Children[0].Kind)
+}
@@ -0,0 +1,173 @@
+package ep
+
+import (
+    "io"
+    "os"
+    "bufio"
+    "context"
+    "io/ioutil"
+    "encoding/gob"
+)
+
+// SpillDir is the directory of the temporary files of the data spilled to
+// disk, or empty for the default directory of temporary files. See
+// MemoryManager
+var SpillDir = ""
+
+// spillPartitionsCount is the number of partitions that Join and GroupBy spill
+// their data into. Each partition is then processed in memory separately
+const spillPartitionsCount = 16
+
+// spillFile is a temporary file of spilled datasets, which are read back in
+// the order they were written
+type spillFile struct {
+    f *os.File
+    w *bufio.Writer
+    enc *gob.Encoder
+}
+
+func newSpillFile() (*spillFile, error) {
+    f, err := ioutil.TempFile(SpillDir, "ep-spill")
+    if err != nil {
+        return nil, err
+    }
+
+    w := bufio.NewWriter(f)
+    return &spillFile{f, w, gob.NewEncoder(w)}, nil
+}
+
+// Write the dataset to the file in batches
+func (s *spillFile) Write(data Dataset) error {
+    for i := 0; i < data.Len(); i += BatchSize {
+        end := i + BatchSize
+        if end > data.Len() {
+            end = data.Len()
+        }
+
+        var batch Data = data.Slice(i, end)
+        err := s.enc.Encode(&batch)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Reader returns a reader of all of the written datasets, from the start of
+// the file. It must not be written afterwards
+func (s *spillFile) Reader() (*spillReader, error) {
+    err := s.w.Flush()
+    if err != nil {
+        return nil, err
+    }
+
+    _, err = s.f.Seek(0, io.SeekStart)
+    if err != nil {
+        return nil, err
+    }
+    return &spillReader{gob.NewDecoder(bufio.NewReader(s.f))}, nil
+}
+
+// Send all of the written datasets to the channel, and close it
+func (s *spillFile) Send(out chan Dataset) error {
+    defer close(out)
+    return readSpilled(s, func(data Dataset) { out <- data })
+}
+
+// Close and remove the file
+func (s *spillFile) Close() error {
+    s.f.Close()
+    return os.Remove(s.f.Name())
+}
+
+type spillReader struct {
+    dec *gob.Decoder
+}
+
+// Next returns the next dataset, or io.EOF when they're exhausted
+func (r *spillReader) Next() (Dataset, error) {
+    var batch Data
+    err := r.dec.Decode(&batch)
+    if err != nil {
+        return nil, err
+    }
+    return batch.(Dataset), nil
+}
+
+// spillPartitions are spill files of datasets partitioned by the hashes of
+// their key columns, such that rows with equal keys are spilled into the same
+// file
+type spillPartitions []*spillFile
+
+func newSpillPartitions() (spillPartitions, error) {
+    parts := spillPartitions{}
+    for i := 0; i < spillPartitionsCount; i++ {
+        f, err := newSpillFile()
+        if err != nil {
+            parts.Close()
+            return nil, err
+        }
+        parts = append(parts, f)
+    }
+    return parts, nil
+}
+
+// Write the rows of the dataset to their partitions, by the keys
+func (parts spillPartitions) Write(data Dataset, keys []int) error {
+    rows := make([][]int, len(parts))
+    for i, h := range hashRows(data, keys) {
+        p := h % uint64(len(parts))
+        rows[p] = append(rows[p], i)
+    }
+
+    for p, rows := range rows {
+        if len(rows) == 0 {
+            continue
+        }
+
+        err := parts[p].Write(pick(data, rows).(Dataset))
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+func (parts spillPartitions) Close() {
+    for _, f := range parts {
+        f.Close()
+    }
+}
+
+// runSpilled runs the runner with the datasets of the spill file as its input,
+// and returns its error or the error of reading the file
+func runSpilled(ctx context.Context, r Runner, f *spillFile, out chan Dataset) error {
+    inp := make(chan Dataset)
+    errs := make(chan error, 1)
+    go func() { errs <- f.Send(inp) }()
+
+    err := r.Run(ctx, inp, out)
+    for _ = range inp {} // drain the input in case the runner has exited early
+    if err1 := <- errs; err == nil {
+        err = err1
+    }
+    return err
+}
+
+// readSpilled calls fn with every dataset of the spill file
+func readSpilled(f *spillFile, fn func(Dataset)) error {
+    r, err := f.Reader()
+    if err != nil {
+        return err
+    }
+
+    for {
+        data, err := r.Next()
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+        fn(data)
+    }
+}
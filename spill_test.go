@@ -0,0 +1,57 @@
+package ep
+
+import (
+    "fmt"
+    "os"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestSpillFile(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 2
+
+    f, err := newSpillFile()
+    require.NoError(t, err)
+
+    require.NoError(t, f.Write(NewDataset(Strs{"a", "b", "c"}, Ints{1, 2, 3})))
+    require.NoError(t, f.Write(NewDataset(Strs{"d"}, Ints{4})))
+
+    datasets := []string{}
+    err = readSpilled(f, func(data Dataset) {
+        datasets = append(datasets, fmt.Sprint(data))
+    })
+    require.NoError(t, err)
+    require.Equal(t, []string{"[[a b] [1 2]]", "[[c] [3]]", "[[d] [4]]"}, datasets)
+
+    require.NoError(t, f.Close())
+    _, err = os.Stat(f.f.Name())
+    require.True(t, os.IsNotExist(err))
+}
+
+// rows with equal keys are spilled into the same partition
+func TestSpillPartitions(t *testing.T) {
+    parts, err := newSpillPartitions()
+    require.NoError(t, err)
+    defer parts.Close()
+
+    data := NewDataset(Strs{"a", "b", "a", "c", "b"}, Ints{1, 2, 3, 4, 5})
+    require.NoError(t, parts.Write(data, []int{0}))
+
+    partOf := map[string]int{}
+    total := 0
+    for p, f := range parts {
+        err = readSpilled(f, func(data Dataset) {
+            total += data.Len()
+            for _, k := range data.At(0).Strings() {
+                if q, ok := partOf[k]; ok {
+                    require.Equal(t, q, p, k)
+                }
+                partOf[k] = p
+            }
+        })
+        require.NoError(t, err)
+    }
+    require.Equal(t, 5, total)
+    require.Equal(t, 3, len(partOf))
+}
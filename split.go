@@ -0,0 +1,103 @@
+package ep
+
+import (
+    "io"
+    "bufio"
+    "context"
+    "github.com/panoplyio/ep/storage"
+)
+
+// fileSplit is a range of bytes of a file, read by a single node
+type fileSplit struct {
+    Path string
+    Start, End int64
+}
+
+// globSplits returns the splits of all of the files matching the glob pattern,
+// in order, of up to size bytes each. Zero size means that every file is a
+// single split. The pattern may be the URL of an object store, see
+// storage.Glob
+func globSplits(ctx context.Context, pattern string, size int64) ([]fileSplit, error) {
+    objects, err := storage.Glob(ctx, pattern)
+    if err != nil {
+        return nil, err
+    }
+
+    splits := []fileSplit{}
+    for _, obj := range objects {
+        path := storage.URL(pattern, obj.Key)
+        n := size
+        if n <= 0 || n > obj.Size {
+            n = obj.Size
+        }
+
+        for start := int64(0); start == 0 || start < obj.Size; start += n {
+            end := start + n
+            if end > obj.Size || n == 0 {
+                end = obj.Size
+            }
+            splits = append(splits, fileSplit{path, start, end})
+        }
+    }
+    return splits, nil
+}
+
+// localSplits returns the splits that are assigned to this node, such that
+// every split is assigned to exactly one of the participating nodes. All of
+// the splits are local when not distributed.
+func localSplits(ctx context.Context, splits []fileSplit) []fileSplit {
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    if len(allNodes) == 0 {
+        return splits
+    }
+
+    res := []fileSplit{}
+    for i, s := range splits {
+        if allNodes[i % len(allNodes)] == thisNode {
+            res = append(res, s)
+        }
+    }
+    return res
+}
+
+// openSplit opens the file of the split, and returns a reader positioned at the
+// beginning of its first line, along with that offset. A split contains the
+// lines that start within its range: when it starts mid-line, the partial line
+// belongs to the previous split. Thus the reader should be read until a line
+// starts at or after the end of the split. The file must be closed by the
+// caller.
+func openSplit(ctx context.Context, s fileSplit) (io.Closer, *bufio.Reader, int64, error) {
+    f, err := storage.Open(ctx, s.Path)
+    if err != nil {
+        return nil, nil, 0, err
+    }
+
+    // align the start to the beginning of the next line
+    start := s.Start
+    if start > 0 {
+        start--
+        _, err = f.Seek(start, io.SeekStart)
+        if err != nil {
+            f.Close()
+            return nil, nil, 0, err
+        }
+    }
+
+    br := bufio.NewReader(f)
+    for start < s.Start || start > 0 && start < s.End {
+        b, err := br.ReadByte()
+        if err == io.EOF {
+            break
+        } else if err != nil {
+            f.Close()
+            return nil, nil, 0, err
+        }
+
+        start++
+        if b == '\n' {
+            break
+        }
+    }
+    return f, br, start, nil
+}
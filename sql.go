@@ -0,0 +1,219 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "database/sql"
+)
+
+var _ = registerGob(&sqlScan{})
+
+// SQLOptions configure the partitioning of SQL queries, for pulling large
+// tables in parallel across the cluster. See SQLScan
+type SQLOptions struct {
+
+    // Partitions is the number of partitions of the query, by ranges of the
+    // integer column PartitionColumn between Lower and Upper. The ranges are
+    // equally sized, except that the first and last partitions also include
+    // all of the rows below and above the bounds, and the first also includes
+    // the null values. Thus the bounds only affect the balance of the
+    // partitions, and never filter any rows. Zero or one partitions read the
+    // query as a whole
+    Partitions int
+    PartitionColumn string
+    Lower, Upper int64
+}
+
+// SQLScan returns a source Runner that runs the query on the database, and
+// emits its results in batches of up to BatchSize rows, with a column per field
+// of the schema. The values are converted into the types of the schema with
+// FromValues, or with CastData for values that aren't natively convertible
+// (like numbers into decimals, or strings into timestamps). Null values are
+// nulls. Its input is ignored. NOTE: databases cannot be serialized, thus the
+// returned Runner cannot be distributed. See SQLScanDSN.
+func SQLScan(db *sql.DB, query string, schema Schema, opts SQLOptions) Runner {
+    return &sqlScan{Query: query, Schema: schema, Options: opts, db: db}
+}
+
+// SQLScanDSN is like SQLScan, except that every node connects to the database
+// with the driver and data source name (see sql.Open), thus it's safe to
+// distribute. When distributed, the partitions of the query (see SQLOptions)
+// are divided between the participating nodes, such that every partition is
+// read exactly once across the cluster.
+func SQLScanDSN(driver, dsn, query string, schema Schema, opts SQLOptions) Runner {
+    return &sqlScan{driver, dsn, query, schema, opts, nil}
+}
+
+type sqlScan struct {
+    Driver string
+    DSN string
+    Query string
+    Schema Schema
+    Options SQLOptions
+    db *sql.DB
+}
+
+func (r *sqlScan) Returns() []Type {
+    types := []Type{}
+    for _, f := range r.Schema {
+        types = append(types, As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (r *sqlScan) Run(ctx context.Context, inp, out chan Dataset) error {
+    for _ = range inp {}
+
+    db := r.db
+    if db == nil {
+        var err error
+        db, err = sql.Open(r.Driver, r.DSN)
+        if err != nil {
+            return err
+        }
+        defer db.Close()
+    }
+
+    queries := r.Partitions()
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    for i, query := range queries {
+        if len(allNodes) > 0 && allNodes[i % len(allNodes)] != thisNode {
+            continue // assigned to another node
+        }
+
+        err := r.scan(ctx, db, query, out)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// Partitions returns the queries of the partitions, in order. See SQLOptions
+func (r *sqlScan) Partitions() []string {
+    opts := r.Options
+    if opts.Partitions <= 1 || opts.PartitionColumn == "" {
+        return []string{r.Query}
+    }
+
+    n := int64(opts.Partitions)
+    stride := (opts.Upper - opts.Lower) / n
+    if stride < 1 {
+        stride = 1
+    }
+
+    col := opts.PartitionColumn
+    queries := []string{}
+    for i := int64(0); i < n; i++ {
+        var where string
+        lower, upper := opts.Lower + i * stride, opts.Lower + (i + 1) * stride
+        switch i {
+        case 0:
+            where = fmt.Sprintf("%s < %d OR %s IS NULL", col, upper, col)
+        case n - 1:
+            where = fmt.Sprintf("%s >= %d", col, lower)
+        default:
+            where = fmt.Sprintf("%s >= %d AND %s < %d", col, lower, col, upper)
+        }
+        queries = append(queries, fmt.Sprintf("SELECT * FROM (%s) ep_partition WHERE %s", r.Query, where))
+    }
+    return queries
+}
+
+func (r *sqlScan) scan(ctx context.Context, db *sql.DB, query string, out chan Dataset) error {
+    rows, err := db.QueryContext(ctx, query)
+    if err != nil {
+        return err
+    }
+    defer rows.Close()
+
+    cols, err := rows.Columns()
+    if err != nil {
+        return err
+    } else if len(cols) != len(r.Schema) {
+        return fmt.Errorf("query returned %d columns for a schema of %d fields", len(cols), len(r.Schema))
+    }
+
+    values := make([][]interface{}, len(cols))
+    row := make([]interface{}, len(cols))
+    for i := range row {
+        row[i] = new(interface{})
+    }
+
+    for rows.Next() {
+        err = rows.Scan(row...)
+        if err != nil {
+            return err
+        }
+
+        for i, v := range row {
+            values[i] = append(values[i], sqlValue(*v.(*interface{})))
+        }
+
+        if len(values[0]) >= BatchSize {
+            err = r.emit(ctx, values, out)
+            if err != nil {
+                return err
+            }
+            values = make([][]interface{}, len(cols))
+        }
+    }
+
+    if err = rows.Err(); err != nil {
+        return err
+    } else if len(values) > 0 && len(values[0]) > 0 {
+        return r.emit(ctx, values, out)
+    }
+    return nil
+}
+
+// emit a batch of the values of the columns, converted to the types of the
+// schema
+func (r *sqlScan) emit(ctx context.Context, values [][]interface{}, out chan Dataset) error {
+    cols := make([]Data, len(values))
+    for i, f := range r.Schema {
+        var err error
+        cols[i], err = FromValues(f.Type, values[i])
+        if err != nil {
+            cols[i], err = castValues(values[i], f.Type)
+        }
+
+        if err != nil {
+            return fmt.Errorf("column %s: %s", f.Name, err)
+        }
+    }
+
+    select {
+    case out <- newNamedDataset(r.Schema.Names(), cols...):
+        return nil
+    case <- ctx.Done():
+        return ctx.Err()
+    }
+}
+
+// sqlValue returns the native value of a scanned driver value, where bytes are
+// strings
+func sqlValue(v interface{}) interface{} {
+    if b, ok := v.([]byte); ok {
+        return string(b)
+    }
+    return v
+}
+
+// castValues converts the native values into the type through their string
+// representation, where nil values are nulls. See CastData
+func castValues(values []interface{}, t Type) (Data, error) {
+    strs := make(Strs, len(values))
+    valid := make(Bools, len(values))
+    for i, v := range values {
+        if v != nil {
+            strs[i], valid[i] = fmt.Sprint(v), true
+        }
+    }
+
+    if allTrue(valid) {
+        return CastData(strs, t)
+    }
+    return CastData(Nullable(strs, valid), t)
+}
@@ -0,0 +1,560 @@
+// Package sql implements a frontend of a useful subset of SQL queries over ep:
+// SELECT [DISTINCT] with expressions and aliases, FROM a table, INNER, LEFT,
+// RIGHT and FULL equi-JOINs, WHERE, GROUP BY with the COUNT, SUM, AVG, MIN
+// and MAX aggregates, HAVING, ORDER BY, LIMIT and OFFSET. Queries are parsed
+// into a Select, which is planned via ep.Plan (see Plan) into a distributed
+// plan of ep runners, where the table references are resolved with Tables.
+package sql
+
+import (
+    "fmt"
+    "strings"
+    "strconv"
+    "unicode"
+    "github.com/panoplyio/ep"
+)
+
+// Node is a node of the expressions of a parsed query: Ident, Literal, Binary,
+// Not or Call
+type Node interface {
+
+    // String representation of the node, as SQL
+    String() string
+}
+
+// Ident is a reference to a column, optionally qualified by a table name or
+// alias
+type Ident struct {
+    Table string
+    Name string
+}
+
+// Literal is a constant number or string
+type Literal struct {
+    Value string
+    IsString bool
+}
+
+// Binary is an arithmetic, comparison or boolean operator, where Op is one of:
+// + - * / = != < <= > >= AND OR
+type Binary struct {
+    Op string
+    Left Node
+    Right Node
+}
+
+// Not negates a boolean expression
+type Not struct {
+    Expr Node
+}
+
+// Call is a call of a function or an aggregate, where the Name is upper-cased
+// and Star is set for COUNT(*)
+type Call struct {
+    Name string
+    Args []Node
+    Star bool
+}
+
+func (n *Ident) String() string {
+    if n.Table != "" {
+        return n.Table + "." + n.Name
+    }
+    return n.Name
+}
+
+func (n *Literal) String() string {
+    if n.IsString {
+        return "'" + strings.Replace(n.Value, "'", "''", -1) + "'"
+    }
+    return n.Value
+}
+
+func (n *Binary) String() string {
+    return fmt.Sprintf("(%s %s %s)", n.Left, n.Op, n.Right)
+}
+
+func (n *Not) String() string { return fmt.Sprintf("NOT %s", n.Expr) }
+func (n *Call) String() string {
+    if n.Star {
+        return n.Name + "(*)"
+    }
+
+    args := []string{}
+    for _, arg := range n.Args {
+        args = append(args, arg.String())
+    }
+    return fmt.Sprintf("%s(%s)", n.Name, strings.Join(args, ", "))
+}
+
+// Select is a parsed SELECT query. It's the key of its RunnerPlan in the
+// ep.Runners registry, thus the planning of queries can be extended or
+// overridden by registering additional RunnerPlans for Select{}
+type Select struct {
+    Distinct bool
+    Fields []Field // empty for SELECT *
+    From TableRef
+    Joins []Join
+    Where Node // nil if none, as are the Having
+    GroupBy []Node
+    Having Node
+    OrderBy []Order
+    Limit int // -1 if none
+    Offset int
+}
+
+// Field is an output expression of a Select, with an optional alias
+type Field struct {
+    Expr Node
+    Alias string
+}
+
+// TableRef is a reference to a table, with an optional alias
+type TableRef struct {
+    Name string
+    Alias string
+}
+
+// Join of a table with the preceding tables, on equality conditions
+type Join struct {
+    Type ep.JoinType // InnerJoin, LeftJoin, RightJoin or FullJoin
+    Table TableRef
+    On Node
+}
+
+// Order is an ORDER BY key
+type Order struct {
+    Expr Node
+    Desc bool
+}
+
+// keywords that can't be used as aliases
+var reserved = map[string]bool{
+    "SELECT": true, "DISTINCT": true, "FROM": true, "WHERE": true,
+    "GROUP": true, "HAVING": true, "ORDER": true, "BY": true, "LIMIT": true,
+    "OFFSET": true, "JOIN": true, "INNER": true, "LEFT": true, "RIGHT": true,
+    "FULL": true, "OUTER": true, "ON": true, "AS": true, "AND": true,
+    "OR": true, "NOT": true, "ASC": true, "DESC": true,
+}
+
+// Parse parses a SELECT query
+func Parse(query string) (Select, error) {
+    tokens, err := lex(query)
+    if err != nil {
+        return Select{}, err
+    }
+
+    p := &parser{tokens: tokens}
+    stmt, err := p.parseSelect()
+    if err != nil {
+        return Select{}, fmt.Errorf("%s at offset %d", err, p.peek().pos)
+    }
+    return stmt, nil
+}
+
+const (
+    tokEOF = iota
+    tokIdent // including keywords
+    tokQuoted // quoted identifier
+    tokString
+    tokNumber
+    tokOp
+)
+
+type token struct {
+    kind int
+    text string
+    pos int
+}
+
+// lex splits the query into tokens
+func lex(query string) ([]token, error) {
+    tokens := []token{}
+    runes := []rune(query)
+    for i := 0; i < len(runes); {
+        r := runes[i]
+        start := i
+        switch {
+        case unicode.IsSpace(r):
+            i++
+            continue
+        case r == '-' && i + 1 < len(runes) && runes[i + 1] == '-':
+            for i < len(runes) && runes[i] != '\n' {
+                i++ // comment
+            }
+            continue
+        case unicode.IsLetter(r) || r == '_':
+            for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+                i++
+            }
+            tokens = append(tokens, token{tokIdent, string(runes[start:i]), start})
+        case unicode.IsDigit(r) || r == '.' && i + 1 < len(runes) && unicode.IsDigit(runes[i + 1]):
+            for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || runes[i] == 'e' || runes[i] == 'E') {
+                i++
+            }
+            tokens = append(tokens, token{tokNumber, string(runes[start:i]), start})
+        case r == '\'' || r == '"':
+            s, n, ok := unquote(runes[i:], r)
+            if !ok {
+                return nil, fmt.Errorf("unterminated %c at offset %d", r, start)
+            }
+
+            kind := tokString
+            if r == '"' {
+                kind = tokQuoted
+            }
+            tokens = append(tokens, token{kind, s, start})
+            i += n
+        default:
+            op := string(r)
+            if i + 1 < len(runes) {
+                switch two := string(runes[i:i + 2]); two {
+                case "<=", ">=", "!=", "<>":
+                    op = two
+                }
+            }
+
+            if op == "!" || !strings.Contains("+-*/=<>!(),.;", op[:1]) {
+                return nil, fmt.Errorf("unexpected %q at offset %d", r, start)
+            } else if op == "<>" {
+                op = "!="
+            }
+
+            i += len([]rune(op))
+            tokens = append(tokens, token{tokOp, op, start})
+        }
+    }
+    return append(tokens, token{tokEOF, "", len(runes)}), nil
+}
+
+// unquote the quoted string at the beginning of the runes, where the quote is
+// escaped by doubling it. Returns the number of runes consumed
+func unquote(runes []rune, quote rune) (string, int, bool) {
+    var b strings.Builder
+    for i := 1; i < len(runes); i++ {
+        if runes[i] != quote {
+            b.WriteRune(runes[i])
+        } else if i + 1 < len(runes) && runes[i + 1] == quote {
+            b.WriteRune(quote)
+            i++
+        } else {
+            return b.String(), i + 1, true
+        }
+    }
+    return "", 0, false
+}
+
+type parser struct {
+    tokens []token
+    i int
+}
+
+func (p *parser) peek() token { return p.tokens[p.i] }
+func (p *parser) next() token {
+    t := p.tokens[p.i]
+    if t.kind != tokEOF {
+        p.i++
+    }
+    return t
+}
+
+// is returns true if the next token is one of the keywords or operators
+func (p *parser) is(words ...string) bool {
+    t := p.peek()
+    for _, w := range words {
+        if t.kind == tokIdent && strings.EqualFold(t.text, w) || t.kind == tokOp && t.text == w {
+            return true
+        }
+    }
+    return false
+}
+
+// accept consumes the next token if it's one of the keywords or operators
+func (p *parser) accept(words ...string) bool {
+    if p.is(words...) {
+        p.next()
+        return true
+    }
+    return false
+}
+
+func (p *parser) expect(words ...string) error {
+    if !p.accept(words...) {
+        return fmt.Errorf("expected %s, got %s", strings.Join(words, " or "), p.describe())
+    }
+    return nil
+}
+
+// describe the next token, for errors
+func (p *parser) describe() string {
+    t := p.peek()
+    if t.kind == tokEOF {
+        return "end of query"
+    }
+    return strconv.Quote(t.text)
+}
+
+// ident parses an identifier, that isn't a reserved keyword
+func (p *parser) ident() (string, error) {
+    t := p.peek()
+    if t.kind == tokQuoted || t.kind == tokIdent && !reserved[strings.ToUpper(t.text)] {
+        p.next()
+        return t.text, nil
+    }
+    return "", fmt.Errorf("expected an identifier, got %s", p.describe())
+}
+
+// alias parses an optional alias, with or without AS
+func (p *parser) alias() (string, error) {
+    if p.accept("AS") {
+        return p.ident()
+    } else if t := p.peek(); t.kind == tokQuoted || t.kind == tokIdent && !reserved[strings.ToUpper(t.text)] {
+        return p.ident()
+    }
+    return "", nil
+}
+
+func (p *parser) parseSelect() (stmt Select, err error) {
+    stmt.Limit = -1
+    if err = p.expect("SELECT"); err != nil {
+        return
+    }
+
+    stmt.Distinct = p.accept("DISTINCT")
+    if !p.accept("*") {
+        for {
+            var f Field
+            if f.Expr, err = p.parseExpr(); err != nil {
+                return
+            } else if f.Alias, err = p.alias(); err != nil {
+                return
+            }
+
+            stmt.Fields = append(stmt.Fields, f)
+            if !p.accept(",") {
+                break
+            }
+        }
+    }
+
+    if err = p.expect("FROM"); err != nil {
+        return
+    } else if stmt.From, err = p.parseTableRef(); err != nil {
+        return
+    }
+
+    for p.is("JOIN", "INNER", "LEFT", "RIGHT", "FULL") {
+        j := Join{Type: ep.InnerJoin}
+        switch {
+        case p.accept("LEFT"): j.Type = ep.LeftJoin
+        case p.accept("RIGHT"): j.Type = ep.RightJoin
+        case p.accept("FULL"): j.Type = ep.FullJoin
+        default: p.accept("INNER")
+        }
+
+        if j.Type != ep.InnerJoin {
+            p.accept("OUTER")
+        }
+
+        if err = p.expect("JOIN"); err != nil {
+            return
+        } else if j.Table, err = p.parseTableRef(); err != nil {
+            return
+        } else if err = p.expect("ON"); err != nil {
+            return
+        } else if j.On, err = p.parseExpr(); err != nil {
+            return
+        }
+        stmt.Joins = append(stmt.Joins, j)
+    }
+
+    if p.accept("WHERE") {
+        if stmt.Where, err = p.parseExpr(); err != nil {
+            return
+        }
+    }
+
+    if p.accept("GROUP") {
+        if err = p.expect("BY"); err != nil {
+            return
+        } else if stmt.GroupBy, err = p.parseExprs(); err != nil {
+            return
+        }
+    }
+
+    if p.accept("HAVING") {
+        if stmt.Having, err = p.parseExpr(); err != nil {
+            return
+        }
+    }
+
+    if p.accept("ORDER") {
+        if err = p.expect("BY"); err != nil {
+            return
+        }
+
+        for {
+            var o Order
+            if o.Expr, err = p.parseExpr(); err != nil {
+                return
+            }
+
+            o.Desc = p.accept("DESC")
+            if !o.Desc {
+                p.accept("ASC")
+            }
+
+            stmt.OrderBy = append(stmt.OrderBy, o)
+            if !p.accept(",") {
+                break
+            }
+        }
+    }
+
+    if p.accept("LIMIT") {
+        if stmt.Limit, err = p.parseInt(); err != nil {
+            return
+        }
+    }
+
+    if p.accept("OFFSET") {
+        if stmt.Offset, err = p.parseInt(); err != nil {
+            return
+        }
+    }
+
+    p.accept(";")
+    if p.peek().kind != tokEOF {
+        err = fmt.Errorf("unexpected %s", p.describe())
+    }
+    return
+}
+
+func (p *parser) parseTableRef() (ref TableRef, err error) {
+    if ref.Name, err = p.ident(); err != nil {
+        return
+    }
+    ref.Alias, err = p.alias()
+    return
+}
+
+func (p *parser) parseInt() (int, error) {
+    t := p.peek()
+    n, err := strconv.Atoi(t.text)
+    if t.kind != tokNumber || err != nil || n < 0 {
+        return 0, fmt.Errorf("expected a non-negative integer, got %s", p.describe())
+    }
+
+    p.next()
+    return n, nil
+}
+
+func (p *parser) parseExprs() ([]Node, error) {
+    nodes := []Node{}
+    for {
+        n, err := p.parseExpr()
+        if err != nil {
+            return nil, err
+        }
+
+        nodes = append(nodes, n)
+        if !p.accept(",") {
+            return nodes, nil
+        }
+    }
+}
+
+// parseExpr parses an expression, by the precedence of the operators: OR, AND,
+// NOT, comparisons, additive, multiplicative
+func (p *parser) parseExpr() (Node, error) {
+    return p.parseBinary(0)
+}
+
+var precedence = [][]string{
+    {"OR"},
+    {"AND"},
+    nil, // NOT
+    {"=", "!=", "<", "<=", ">", ">="},
+    {"+", "-"},
+    {"*", "/"},
+}
+
+func (p *parser) parseBinary(level int) (Node, error) {
+    if level == len(precedence) {
+        return p.parseUnary()
+    } else if precedence[level] == nil {
+        if p.accept("NOT") {
+            n, err := p.parseBinary(level)
+            return &Not{n}, err
+        }
+        return p.parseBinary(level + 1)
+    }
+
+    left, err := p.parseBinary(level + 1)
+    for err == nil && p.is(precedence[level]...) {
+        op := strings.ToUpper(p.next().text)
+
+        var right Node
+        right, err = p.parseBinary(level + 1)
+        left = &Binary{op, left, right}
+    }
+    return left, err
+}
+
+func (p *parser) parseUnary() (Node, error) {
+    if p.accept("-") {
+        n, err := p.parseUnary()
+        if lit, ok := n.(*Literal); ok && !lit.IsString && err == nil {
+            return &Literal{"-" + lit.Value, false}, nil
+        }
+        return &Binary{"-", &Literal{"0", false}, n}, err
+    }
+    return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+    t := p.peek()
+    switch {
+    case t.kind == tokNumber:
+        p.next()
+        if _, err := strconv.ParseFloat(t.text, 64); err != nil {
+            return nil, fmt.Errorf("invalid number %q", t.text)
+        }
+        return &Literal{t.text, false}, nil
+    case t.kind == tokString:
+        p.next()
+        return &Literal{t.text, true}, nil
+    case p.accept("("):
+        n, err := p.parseExpr()
+        if err != nil {
+            return nil, err
+        }
+        return n, p.expect(")")
+    case p.is("TRUE", "FALSE"):
+        p.next()
+        return &Literal{strings.ToLower(t.text), false}, nil
+    }
+
+    name, err := p.ident()
+    if err != nil {
+        return nil, err
+    }
+
+    if p.accept(".") {
+        col, err := p.ident()
+        return &Ident{name, col}, err
+    } else if !p.accept("(") {
+        return &Ident{"", name}, nil
+    }
+
+    call := &Call{Name: strings.ToUpper(name)}
+    if p.accept("*") {
+        call.Star = true
+    } else if !p.is(")") {
+        call.Args, err = p.parseExprs()
+        if err != nil {
+            return nil, err
+        }
+    }
+    return call, p.expect(")")
+}
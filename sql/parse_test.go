@@ -0,0 +1,79 @@
+package sql
+
+import (
+    "fmt"
+    "testing"
+    "github.com/panoplyio/ep"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleParse() {
+    stmt, err := Parse(`
+        SELECT u.name, COUNT(*) AS n
+        FROM users u LEFT JOIN orders o ON u.id = o.user_id
+        WHERE o.total > 10 AND NOT u.name = 'bob' -- no bobs
+        GROUP BY u.name
+        ORDER BY n DESC
+        LIMIT 10`)
+
+    fmt.Println(stmt.Fields, stmt.From, stmt.Joins[0].Type == ep.LeftJoin, stmt.Joins[0].On)
+    fmt.Println(stmt.Where, stmt.GroupBy, stmt.OrderBy, stmt.Limit, err)
+
+    // Output:
+    // [{u.name } {COUNT(*) n}] {users u} true (u.id = o.user_id)
+    // ((o.total > 10) AND NOT (u.name = 'bob')) [u.name] [{n true}] 10 <nil>
+}
+
+func TestParseExpr(t *testing.T) {
+    tests := map[string]string{
+        "a + b * c - d": "((a + (b * c)) - d)",
+        "(a + b) * -c": "((a + b) * (0 - c))",
+        "a <> -1.5 OR b <= 'it''s'": "((a != -1.5) OR (b <= 'it''s'))",
+        "NOT a AND b OR c": "((NOT a AND b) OR c)",
+        "upper(\"Name\") = lower(x, 1)": "(UPPER(Name) = LOWER(x, 1))",
+        "t.a >= true": "(t.a >= true)",
+    }
+
+    for query, expected := range tests {
+        stmt, err := Parse("SELECT " + query + " FROM t")
+        require.NoError(t, err, query)
+        require.Equal(t, expected, stmt.Fields[0].Expr.String(), query)
+    }
+}
+
+func TestParseClauses(t *testing.T) {
+    stmt, err := Parse("select distinct * from t as x inner join y on x.a = y.b right outer join z on a = c limit 5 offset 2;")
+    require.NoError(t, err)
+    require.True(t, stmt.Distinct)
+    require.Empty(t, stmt.Fields)
+    require.Equal(t, TableRef{"t", "x"}, stmt.From)
+    require.Equal(t, 2, len(stmt.Joins))
+    require.Equal(t, ep.InnerJoin, stmt.Joins[0].Type)
+    require.Equal(t, ep.RightJoin, stmt.Joins[1].Type)
+    require.Equal(t, 5, stmt.Limit)
+    require.Equal(t, 2, stmt.Offset)
+
+    stmt, err = Parse("SELECT a FROM t")
+    require.NoError(t, err)
+    require.Equal(t, -1, stmt.Limit)
+}
+
+func TestParseErr(t *testing.T) {
+    tests := map[string]string{
+        "SELECT": "expected an identifier, got end of query at offset 6",
+        "SELECT a": "expected FROM, got end of query at offset 8",
+        "SELECT a FROM": "expected an identifier, got end of query at offset 13",
+        "SELECT a FROM t WHERE": "expected an identifier, got end of query at offset 21",
+        "SELECT a FROM t LIMIT x": `expected a non-negative integer, got "x" at offset 22`,
+        "SELECT a FROM t extra junk": `unexpected "junk" at offset 22`,
+        "SELECT (a FROM t": `expected ), got "FROM" at offset 10`,
+        "SELECT 'a FROM t": "unterminated ' at offset 7",
+        "SELECT a ! b FROM t": `unexpected '!' at offset 9`,
+        "UPDATE t": `expected SELECT, got "UPDATE" at offset 0`,
+    }
+
+    for query, expected := range tests {
+        _, err := Parse(query)
+        require.EqualError(t, err, expected, query)
+    }
+}
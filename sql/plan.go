@@ -0,0 +1,580 @@
+package sql
+
+import (
+    "fmt"
+    "context"
+    "strings"
+    "strconv"
+    "encoding/gob"
+    "github.com/panoplyio/ep"
+    "github.com/panoplyio/ep/expr"
+)
+
+var _ = ep.Runners.Register(Select{}, &selectPlan{})
+
+func init() {
+    gob.Register(&project{})
+}
+
+// Tables resolves the tables referenced by queries. See TableMap
+type Tables interface {
+
+    // Table returns the schema of the named table, and the source Runner that
+    // scans it. The Runner is distributed with the rest of the plan, thus
+    // every node should scan a distinct portion of the table, like
+    // ep.CSVScan and ep.SQLScanDSN
+    Table(name string) (ep.Schema, ep.Runner, error)
+}
+
+// Table is a table of a TableMap
+type Table struct {
+    Schema ep.Schema
+    Runner ep.Runner
+}
+
+// TableMap is a Tables of a fixed set of tables, by their names
+type TableMap map[string]Table
+
+func (m TableMap) Table(name string) (ep.Schema, ep.Runner, error) {
+    t, ok := m[name]
+    if !ok {
+        return nil, nil, fmt.Errorf("unknown table %s", name)
+    }
+    return t.Schema, t.Runner, nil
+}
+
+// JoinThreshold is the maximum number of rows of the right side of joins that
+// are broadcasted to all nodes, instead of repartitioning both sides. See
+// ep.DistributedJoin
+var JoinThreshold = 10000
+
+// Plan parses the query, and plans it with ep.Plan, where the tables are
+// resolved by the tables, which are also available to the RunnerPlans of
+// Select via the "ep.Tables" context value. When tables is nil, they're
+// resolved by the ep.Tables catalog. The planned Runner gathers its output
+// into the master node, and is optimized with ep.Optimize.
+//
+// Expressions are evaluated with the expr package, where unknown functions
+// are called from the ep.Functions registry by their lower-cased names.
+// NOTE: COUNT(column) counts all of the rows of the group, including nulls.
+func Plan(ctx context.Context, query string, tables Tables) (ep.Runner, error) {
+    stmt, err := Parse(query)
+    if err != nil {
+        return nil, err
+    }
+
+    if tables != nil {
+        ctx = context.WithValue(ctx, "ep.Tables", tables)
+    }
+
+    runner, err := ep.Plan(ctx, stmt)
+    if err != nil {
+        return nil, err
+    }
+    return ep.Optimize(runner), nil
+}
+
+// selectPlan is the built-in RunnerPlan of Select
+type selectPlan struct {}
+
+func (*selectPlan) Returns() []ep.Type { return []ep.Type{ep.Wildcard} }
+func (*selectPlan) Run(ctx context.Context, inp, out chan ep.Dataset) error {
+    return fmt.Errorf("sql: unplanned select")
+}
+
+func (*selectPlan) Plan(ctx context.Context, arg interface{}) (ep.Runner, error) {
+    stmt, ok := arg.(Select)
+    if !ok {
+        return nil, fmt.Errorf("sql: unable to plan %T", arg)
+    }
+
+    tables, ok := ctx.Value("ep.Tables").(Tables)
+    if !ok {
+        tables = ep.Tables
+    }
+    return planSelect(stmt, tables)
+}
+
+func planSelect(stmt Select, tables Tables) (ep.Runner, error) {
+    s := &scope{}
+    runner, err := s.addTable(tables, stmt.From)
+    if err != nil {
+        return nil, err
+    }
+
+    for _, j := range stmt.Joins {
+        right := &scope{}
+        rightRunner, err := right.addTable(tables, j.Table)
+        if err != nil {
+            return nil, err
+        }
+
+        leftKeys, rightKeys, err := joinKeys(j.On, s, right)
+        if err != nil {
+            return nil, err
+        }
+
+        runner = ep.DistributedJoin(j.Type, leftKeys, rightKeys, runner, rightRunner, JoinThreshold)
+        s.cols = append(s.cols, right.cols...)
+    }
+
+    if stmt.Where != nil {
+        e, err := s.compile(stmt.Where)
+        if err != nil {
+            return nil, err
+        }
+        runner = ep.Pipeline(runner, expr.Filter(e))
+    }
+
+    fields := stmt.Fields
+    if len(fields) == 0 {
+        for _, c := range s.cols {
+            fields = append(fields, Field{Expr: &Ident{c.Table, c.Name}})
+        }
+    }
+
+    nodes := []Node{stmt.Having}
+    for _, f := range fields {
+        nodes = append(nodes, f.Expr)
+    }
+
+    calls := aggregates(nodes...)
+    if len(stmt.GroupBy) > 0 || len(calls) > 0 {
+        if len(stmt.Fields) == 0 {
+            return nil, fmt.Errorf("SELECT * with aggregation")
+        }
+
+        runner, s, err = s.aggregate(runner, stmt.GroupBy, calls)
+        if err != nil {
+            return nil, err
+        }
+
+        if stmt.Having != nil {
+            e, err := s.compile(stmt.Having)
+            if err != nil {
+                return nil, err
+            }
+            runner = ep.Pipeline(runner, expr.Filter(e))
+        }
+    } else if stmt.Having != nil {
+        return nil, fmt.Errorf("HAVING without aggregation")
+    }
+
+    proj := &project{}
+    for _, f := range fields {
+        e, err := s.compile(f.Expr)
+        if err != nil {
+            return nil, err
+        }
+
+        name := f.Alias
+        if name == "" {
+            name = defaultName(f.Expr)
+        }
+
+        proj.Exprs = append(proj.Exprs, e)
+        proj.Names = append(proj.Names, name)
+    }
+    runner = ep.Pipeline(runner, proj)
+
+    if stmt.Distinct {
+        keys := make([]int, len(proj.Exprs))
+        cols := &project{Names: proj.Names}
+        for i := range keys {
+            keys[i] = i
+            cols.Exprs = append(cols.Exprs, expr.Col(i))
+        }
+        runner = ep.Pipeline(runner, ep.GroupBy(keys), cols)
+    }
+
+    keys := []ep.SortKey{}
+    for _, o := range stmt.OrderBy {
+        i, err := s.outputIndex(o.Expr, fields, proj)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, ep.SortKey{Col: i, Desc: o.Desc})
+    }
+
+    // sort or gather into the master node, limiting the rows of every node
+    // before gathering them
+    if len(keys) > 0 {
+        runner = ep.Pipeline(runner, ep.DistributedSort(keys))
+    } else if stmt.Limit >= 0 {
+        runner = ep.Pipeline(runner, ep.Limit(stmt.Limit + stmt.Offset), ep.Gather())
+    } else {
+        runner = ep.Pipeline(runner, ep.Gather())
+    }
+
+    if stmt.Offset > 0 {
+        runner = ep.Pipeline(runner, ep.Offset(stmt.Offset))
+    }
+
+    if stmt.Limit >= 0 {
+        runner = ep.Pipeline(runner, ep.Limit(stmt.Limit))
+    }
+    return runner, nil
+}
+
+// column of the rows of a scope
+type column struct {
+    Table string // the alias of the table, or its name
+    Name string
+}
+
+// scope of the columns that expressions can reference. After aggregation, the
+// columns are the group expressions followed by the aggregates, which can be
+// referenced by expressions that are equal to them
+type scope struct {
+    cols []column
+    pre *scope // the scope of the input of the aggregation, if aggregated
+    groups []string // keys of the group expressions
+    aggs []string // keys of the aggregates
+}
+
+// addTable resolves the table, adds its columns to the scope, and returns the
+// Runner that scans it
+func (s *scope) addTable(tables Tables, ref TableRef) (ep.Runner, error) {
+    schema, runner, err := tables.Table(ref.Name)
+    if err != nil {
+        return nil, err
+    }
+
+    name := ref.Alias
+    if name == "" {
+        name = ref.Name
+    }
+
+    for _, f := range schema {
+        s.cols = append(s.cols, column{name, f.Name})
+    }
+    return runner, nil
+}
+
+// resolve the index of the referenced column
+func (s *scope) resolve(n *Ident) (int, error) {
+    idx := -1
+    for i, c := range s.cols {
+        if n.Table != "" && !strings.EqualFold(n.Table, c.Table) {
+            continue
+        } else if !strings.EqualFold(n.Name, c.Name) {
+            continue
+        } else if idx >= 0 {
+            return -1, fmt.Errorf("ambiguous column %s", n)
+        }
+        idx = i
+    }
+
+    if idx < 0 {
+        return -1, fmt.Errorf("unknown column %s", n)
+    }
+    return idx, nil
+}
+
+// compile the node into an expression over the columns of the scope
+func (s *scope) compile(n Node) (expr.Expr, error) {
+    if s.pre != nil {
+        if c, ok := n.(*Call); ok && isAggregate(c.Name) {
+            key, err := s.pre.aggKey(c)
+            if err != nil {
+                return nil, err
+            }
+
+            for i, agg := range s.aggs {
+                if agg == key {
+                    return expr.Col(len(s.groups) + i), nil
+                }
+            }
+        }
+
+        if e, err := s.pre.compile(n); err == nil {
+            for i, g := range s.groups {
+                if g == e.String() {
+                    return expr.Col(i), nil
+                }
+            }
+        }
+    }
+
+    switch n := n.(type) {
+    case *Ident:
+        if s.pre != nil {
+            return nil, fmt.Errorf("column %s must appear in the GROUP BY clause or be used in an aggregate function", n)
+        }
+
+        i, err := s.resolve(n)
+        return expr.Col(i), err
+    case *Literal:
+        return expr.Lit(n.Value), nil
+    case *Not:
+        e, err := s.compile(n.Expr)
+        return expr.Not(e), err
+    case *Binary:
+        left, err := s.compile(n.Left)
+        if err != nil {
+            return nil, err
+        }
+
+        right, err := s.compile(n.Right)
+        if err != nil {
+            return nil, err
+        }
+        return binaryOps[n.Op](left, right), nil
+    case *Call:
+        if isAggregate(n.Name) {
+            return nil, fmt.Errorf("aggregate %s is not allowed here", n)
+        } else if n.Star {
+            return nil, fmt.Errorf("%s(*) is not supported", n.Name)
+        }
+
+        args := []expr.Expr{}
+        for _, arg := range n.Args {
+            e, err := s.compile(arg)
+            if err != nil {
+                return nil, err
+            }
+            args = append(args, e)
+        }
+        return expr.Call(strings.ToLower(n.Name), args...), nil
+    }
+    return nil, fmt.Errorf("unsupported expression %s", n)
+}
+
+// aggregate plans the aggregation of the scope's rows by the group
+// expressions, and returns the scope of the aggregated rows
+func (s *scope) aggregate(runner ep.Runner, groups []Node, calls []*Call) (ep.Runner, *scope, error) {
+    post := &scope{pre: s}
+    exprs := []expr.Expr{}
+    keys := []int{}
+    for i, g := range groups {
+        e, err := s.compile(g)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        exprs = append(exprs, e)
+        keys = append(keys, i)
+        post.groups = append(post.groups, e.String())
+    }
+
+    aggs := []ep.Aggregator{}
+    for _, c := range calls {
+        key, err := s.aggKey(c)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        if c.Name == "COUNT" && (c.Star || len(c.Args) == 1) {
+            aggs = append(aggs, ep.Count())
+            post.aggs = append(post.aggs, key)
+            continue
+        } else if c.Star || len(c.Args) != 1 {
+            return nil, nil, fmt.Errorf("%s expects 1 argument", c.Name)
+        }
+
+        col := len(exprs)
+        arg, _ := s.compile(c.Args[0]) // compiled by aggKey
+        exprs = append(exprs, arg)
+
+        switch c.Name {
+        case "SUM": aggs = append(aggs, ep.Sum(col))
+        case "AVG": aggs = append(aggs, ep.Avg(col))
+        case "MIN": aggs = append(aggs, ep.Min(col))
+        case "MAX": aggs = append(aggs, ep.Max(col))
+        }
+        post.aggs = append(post.aggs, key)
+    }
+
+    if len(exprs) == 0 {
+        exprs = append(exprs, expr.Lit("")) // retain the number of rows
+    }
+
+    runner = ep.Pipeline(runner, expr.Project(exprs...), ep.GroupBy(keys, aggs...))
+    return runner, post, nil
+}
+
+// aggKey returns the key of the aggregate call, by its compiled arguments
+func (s *scope) aggKey(c *Call) (string, error) {
+    if c.Star {
+        return c.Name + "(*)", nil
+    }
+
+    args := []string{}
+    for _, arg := range c.Args {
+        if len(aggregates(arg)) > 0 {
+            return "", fmt.Errorf("nested aggregate in %s", c)
+        }
+
+        e, err := s.compile(arg)
+        if err != nil {
+            return "", err
+        }
+        args = append(args, e.String())
+    }
+    return fmt.Sprintf("%s(%s)", c.Name, strings.Join(args, ", ")), nil
+}
+
+// outputIndex returns the index of the output column referenced by an ORDER BY
+// key: either its position (from 1), its name or alias, or its expression
+func (s *scope) outputIndex(n Node, fields []Field, proj *project) (int, error) {
+    if lit, ok := n.(*Literal); ok && !lit.IsString {
+        i, err := strconv.Atoi(lit.Value)
+        if err != nil || i < 1 || i > len(fields) {
+            return -1, fmt.Errorf("ORDER BY position %s is out of range", lit.Value)
+        }
+        return i - 1, nil
+    }
+
+    if id, ok := n.(*Ident); ok && id.Table == "" {
+        for i, name := range proj.Names {
+            if strings.EqualFold(name, id.Name) {
+                return i, nil
+            }
+        }
+    }
+
+    if e, err := s.compile(n); err == nil {
+        for i, pe := range proj.Exprs {
+            if pe.String() == e.String() {
+                return i, nil
+            }
+        }
+    }
+    return -1, fmt.Errorf("ORDER BY %s must be in the select list", n)
+}
+
+// joinKeys returns the key columns of the left and right scopes, out of a join
+// condition of equalities of their columns
+func joinKeys(on Node, left, right *scope) ([]int, []int, error) {
+    b, ok := on.(*Binary)
+    if ok && b.Op == "AND" {
+        lk1, rk1, err := joinKeys(b.Left, left, right)
+        if err != nil {
+            return nil, nil, err
+        }
+
+        lk2, rk2, err := joinKeys(b.Right, left, right)
+        return append(lk1, lk2...), append(rk1, rk2...), err
+    }
+
+    var a, c *Ident
+    if ok && b.Op == "=" {
+        a, _ = b.Left.(*Ident)
+        c, _ = b.Right.(*Ident)
+    }
+
+    if a == nil || c == nil {
+        return nil, nil, fmt.Errorf("unsupported join condition %s, expected equalities of columns", on)
+    }
+
+    for _, pair := range [][2]*Ident{{a, c}, {c, a}} {
+        l, err1 := left.resolve(pair[0])
+        r, err2 := right.resolve(pair[1])
+        if err1 == nil && err2 == nil {
+            return []int{l}, []int{r}, nil
+        }
+    }
+    return nil, nil, fmt.Errorf("unable to resolve join condition %s", on)
+}
+
+var binaryOps = map[string]func(a, b expr.Expr) expr.Expr{
+    "+": expr.Add,
+    "-": expr.Sub,
+    "*": expr.Mul,
+    "/": expr.Div,
+    "=": expr.Eq,
+    "!=": expr.Ne,
+    "<": expr.Lt,
+    "<=": expr.Le,
+    ">": expr.Gt,
+    ">=": expr.Ge,
+    "AND": expr.And,
+    "OR": expr.Or,
+}
+
+func isAggregate(name string) bool {
+    switch name {
+    case "COUNT", "SUM", "AVG", "MIN", "MAX":
+        return true
+    }
+    return false
+}
+
+// aggregates returns the distinct aggregate calls within the nodes
+func aggregates(nodes ...Node) []*Call {
+    calls := []*Call{}
+    seen := map[string]bool{}
+    var walk func(n Node)
+    walk = func(n Node) {
+        switch n := n.(type) {
+        case *Call:
+            if isAggregate(n.Name) && !seen[n.String()] {
+                seen[n.String()] = true
+                calls = append(calls, n)
+                return
+            }
+
+            for _, arg := range n.Args {
+                walk(arg)
+            }
+        case *Binary:
+            walk(n.Left)
+            walk(n.Right)
+        case *Not:
+            walk(n.Expr)
+        }
+    }
+
+    for _, n := range nodes {
+        walk(n)
+    }
+    return calls
+}
+
+// defaultName returns the name of an output column without an alias: the
+// name of referenced columns, the lower-cased name of function calls, or the
+// expression itself
+func defaultName(n Node) string {
+    switch n := n.(type) {
+    case *Ident:
+        return n.Name
+    case *Call:
+        return strings.ToLower(n.Name)
+    }
+    return n.String()
+}
+
+// project evaluates the expressions over its input, and emits a column per
+// expression, named by the names
+type project struct {
+    Exprs []expr.Expr
+    Names []string
+}
+
+func (r *project) Returns() []ep.Type {
+    types := []ep.Type{}
+    for i, e := range r.Exprs {
+        types = append(types, ep.As(e.Returns(), r.Names[i]))
+    }
+    return types
+}
+
+func (r *project) Run(ctx context.Context, inp, out chan ep.Dataset) error {
+    schema := ep.Schema{}
+    for _, name := range r.Names {
+        schema = append(schema, ep.Field{Name: name})
+    }
+
+    for data := range inp {
+        cols := []ep.Data{}
+        for _, e := range r.Exprs {
+            v, err := e.Eval(data)
+            if err != nil {
+                return err
+            }
+            cols = append(cols, v)
+        }
+        out <- ep.WithSchema(ep.NewDataset(cols...), schema)
+    }
+    return nil
+}
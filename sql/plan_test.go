@@ -0,0 +1,183 @@
+package sql
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "encoding/gob"
+    "github.com/panoplyio/ep"
+    "github.com/panoplyio/ep/eptest"
+    "github.com/stretchr/testify/require"
+)
+
+func init() {
+    gob.Register(&table{})
+}
+
+// table emits the rows of its columns. When distributed, every node emits
+// every nodes'th row
+type table struct {
+    Names []string
+    Cols []ep.Data
+}
+
+func (t *table) Schema() ep.Schema {
+    schema := ep.Schema{}
+    for i, name := range t.Names {
+        schema = append(schema, ep.Field{Name: name, Type: t.Cols[i].Type()})
+    }
+    return schema
+}
+
+func (t *table) Returns() []ep.Type {
+    types := []ep.Type{}
+    for _, f := range t.Schema() {
+        types = append(types, ep.As(f.Type, f.Name))
+    }
+    return types
+}
+
+func (t *table) Run(ctx context.Context, inp, out chan ep.Dataset) error {
+    for _ = range inp {}
+
+    data := ep.WithSchema(ep.NewDataset(t.Cols...), t.Schema())
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    for node, addr := range allNodes {
+        if addr != thisNode {
+            continue
+        }
+
+        mask := make(ep.Bools, data.Len())
+        for i := range mask {
+            mask[i] = i % len(allNodes) == node
+        }
+        data = data.Filter(mask)
+    }
+
+    out <- data
+    return nil
+}
+
+var users = &table{
+    []string{"id", "name", "age"},
+    []ep.Data{ep.Ints{1, 2, 3, 4}, ep.Strs{"bob", "alice", "eve", "mallory"}, ep.Ints{30, 25, 35, 25}},
+}
+
+var orders = &table{
+    []string{"id", "user_id", "total"},
+    []ep.Data{ep.Ints{1, 2, 3, 4}, ep.Ints{1, 1, 2, 5}, ep.Floats{10, 20, 5, 100}},
+}
+
+var tables = TableMap{
+    "users": {users.Schema(), users},
+    "orders": {orders.Schema(), orders},
+}
+
+func ExamplePlan() {
+    query := `
+        SELECT u.name, SUM(o.total) AS total
+        FROM users u JOIN orders o ON u.id = o.user_id
+        GROUP BY u.name
+        ORDER BY total DESC`
+
+    runner, err := Plan(context.Background(), query, tables)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+
+    data, err := ep.Collect(context.Background(), runner)
+    fmt.Println(data.Schema().Names(), data, err)
+
+    // Output: [name total] [[bob alice] [30 5]] <nil>
+}
+
+var queries = map[string]string{
+    "SELECT name FROM users WHERE age > 26 ORDER BY name": "[[bob eve]]",
+    "SELECT age, COUNT(*) AS n FROM users GROUP BY age ORDER BY age": "[[25 30 35] [2 1 1]]",
+    "SELECT COUNT(*) FROM users u LEFT JOIN orders o ON o.user_id = u.id": "[[5]]",
+    "SELECT o.id FROM users u RIGHT JOIN orders o ON o.user_id = u.id WHERE u.id = 2": "[[3]]",
+    "SELECT DISTINCT age FROM users ORDER BY age DESC LIMIT 2": "[[35 30]]",
+    "SELECT name, age * 2 AS double FROM users ORDER BY double, name LIMIT 2 OFFSET 1": "[[mallory bob] [50 60]]",
+    "SELECT age FROM users GROUP BY age HAVING COUNT(*) > 1": "[[25]]",
+    "SELECT * FROM users WHERE id = 3": "[[3] [eve] [35]]",
+    "SELECT upper(name) FROM users WHERE name = 'eve'": "[[EVE]]",
+    "SELECT COUNT(*), MAX(age), AVG(age) + 1 FROM users": "[[4] [35] [29.75]]",
+    "SELECT age + 1, MIN(name) FROM users GROUP BY age + 1 ORDER BY 1": "[[26 31 36] [alice bob eve]]",
+}
+
+func TestPlan(t *testing.T) {
+    for query, expected := range queries {
+        runner, err := Plan(context.Background(), query, tables)
+        require.NoError(t, err, query)
+
+        data, err := ep.Collect(context.Background(), runner)
+        require.NoError(t, err, query)
+        require.Equal(t, expected, fmt.Sprint(data), query)
+    }
+
+    runner, err := Plan(context.Background(), "SELECT id FROM users LIMIT 2", tables)
+    require.NoError(t, err)
+
+    data, err := ep.Collect(context.Background(), runner)
+    require.NoError(t, err)
+    require.Equal(t, 2, data.Len())
+}
+
+// Test that the plans produce the same results when distributed
+func TestPlanDistributed(t *testing.T) {
+    cluster := eptest.NewCluster(t, 2)
+    defer eptest.NoLeaks(t)()
+
+    for query, expected := range queries {
+        runner, err := Plan(context.Background(), query, tables)
+        require.NoError(t, err, query)
+
+        runner = cluster.Distribute(runner)
+        data, err := ep.Collect(context.Background(), runner)
+        require.NoError(t, err, query)
+        require.Equal(t, expected, fmt.Sprint(data), query)
+    }
+
+    cluster.NoOpenConns(t)
+}
+
+func TestPlanErr(t *testing.T) {
+    tests := map[string]string{
+        "SELECT nope FROM users": "unknown column nope",
+        "SELECT * FROM nope": "unknown table nope",
+        "SELECT id FROM users u JOIN orders o ON u.id = o.user_id": "ambiguous column id",
+        "SELECT name, COUNT(*) FROM users": "column name must appear in the GROUP BY clause or be used in an aggregate function",
+        "SELECT * FROM users GROUP BY age": "SELECT * with aggregation",
+        "SELECT name FROM users WHERE COUNT(*) > 1": "aggregate COUNT(*) is not allowed here",
+        "SELECT SUM(MAX(age)) FROM users": "nested aggregate in SUM(MAX(age))",
+        "SELECT name FROM users ORDER BY age": "ORDER BY age must be in the select list",
+        "SELECT name FROM users ORDER BY 2": "ORDER BY position 2 is out of range",
+        "SELECT name FROM users HAVING 1": "HAVING without aggregation",
+        "SELECT * FROM users u JOIN orders o ON u.id > o.user_id": "unsupported join condition (u.id > o.user_id), expected equalities of columns",
+        "SELECT * FROM users u JOIN orders o ON u.id = u.age": "unable to resolve join condition (u.id = u.age)",
+        "SELECT": "expected an identifier, got end of query at offset 6",
+    }
+
+    for query, expected := range tests {
+        _, err := Plan(context.Background(), query, tables)
+        require.EqualError(t, err, expected, query)
+    }
+
+    _, err := ep.Plan(context.Background(), Select{From: TableRef{Name: "nope"}})
+    require.EqualError(t, err, "unknown table nope")
+}
+
+// Test that tables are resolved by the ep.Tables catalog by default
+func TestPlanCatalog(t *testing.T) {
+    ep.Tables.Register("catalog_users", users.Schema(), users)
+    defer ep.Tables.Drop("catalog_users")
+
+    runner, err := Plan(context.Background(), "SELECT MAX(age) FROM catalog_users", nil)
+    require.NoError(t, err)
+
+    data, err := ep.Collect(context.Background(), runner)
+    require.NoError(t, err)
+    require.Equal(t, "[[35]]", fmt.Sprint(data))
+}
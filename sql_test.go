@@ -0,0 +1,151 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "time"
+    "regexp"
+    "context"
+    "strconv"
+    "testing"
+    "database/sql"
+    "database/sql/driver"
+    "github.com/stretchr/testify/require"
+)
+
+func init() {
+    sql.Register("eptest", fakeDriver{})
+}
+
+func ExampleSQLScan() {
+    db, _ := sql.Open("eptest", "users")
+    defer db.Close()
+
+    schema := Schema{{"id", Int}, {"name", Str}, {"score", Decimal(5, 2)}, {"at", Time}}
+    data, err := testRun(SQLScan(db, "SELECT * FROM users", schema, SQLOptions{}))
+    fmt.Println(data, err)
+
+    // Output: [[1 2 3 <nil>] [bob alice eve mallory] [1.50 2.00 <nil> 0.25] [1970-01-01T00:00:00Z <nil> <nil> <nil>]] <nil>
+}
+
+func TestSQLScanPartitions(t *testing.T) {
+    runner := SQLScanDSN("eptest", "users", "SELECT * FROM users", nil, SQLOptions{
+        Partitions: 3,
+        PartitionColumn: "id",
+        Lower: 0,
+        Upper: 10,
+    })
+
+    require.Equal(t, []string{
+        "SELECT * FROM (SELECT * FROM users) ep_partition WHERE id < 3 OR id IS NULL",
+        "SELECT * FROM (SELECT * FROM users) ep_partition WHERE id >= 3 AND id < 6",
+        "SELECT * FROM (SELECT * FROM users) ep_partition WHERE id >= 6",
+    }, runner.(*sqlScan).Partitions())
+}
+
+// Test that every row is read exactly once by the partitions of all of the
+// nodes
+func TestSQLScanDistributed(t *testing.T) {
+    defer func(size int) { BatchSize = size }(BatchSize)
+    BatchSize = 1
+
+    schema := Schema{{"id", Int}, {"name", Str}, {"score", Float}, {"at", Time}}
+    nodes := []string{":5551", ":5552"}
+    for _, partitions := range []int{0, 2, 3, 10} {
+        runner := SQLScanDSN("eptest", "users", "SELECT * FROM users", schema, SQLOptions{
+            Partitions: partitions,
+            PartitionColumn: "id",
+            Lower: 1,
+            Upper: 3,
+        })
+
+        seen := map[string]int{}
+        for _, node := range nodes {
+            ctx := context.WithValue(context.Background(), "ep.AllNodes", nodes)
+            ctx = context.WithValue(ctx, "ep.ThisNode", node)
+            data, err := runCtx(ctx, runner)
+            require.NoError(t, err)
+            if data.Width() == 0 {
+                continue // no partitions were assigned to this node
+            }
+
+            for _, name := range data.At(1).Strings() {
+                seen[name]++
+            }
+        }
+
+        require.Equal(t, map[string]int{"bob": 1, "alice": 1, "eve": 1, "mallory": 1}, seen, "%d partitions", partitions)
+    }
+}
+
+func TestSQLScanErr(t *testing.T) {
+    _, err := testRun(SQLScanDSN("eptest", "users", "SELECT", Schema{{"id", Int}}, SQLOptions{}))
+    require.EqualError(t, err, "query returned 4 columns for a schema of 1 fields")
+
+    schema := Schema{{"id", Int}, {"name", Int}, {"score", Float}, {"at", Time}}
+    _, err = testRun(SQLScanDSN("eptest", "users", "SELECT", schema, SQLOptions{}))
+    require.EqualError(t, err, `column name: unable to cast "bob" from string to int`)
+
+    _, err = testRun(SQLScanDSN("nodriver", "", "SELECT", schema, SQLOptions{}))
+    require.Error(t, err)
+}
+
+// fakeDriver is a database/sql driver of a single read-only users table, that
+// evaluates the range predicates of the partitions of SQLScan
+type fakeDriver struct {}
+
+var fakeUsers = [][]driver.Value{
+    {int64(1), "bob", []byte("1.5"), time.Unix(0, 0)},
+    {int64(2), "alice", 2.0, nil},
+    {int64(3), "eve", nil, nil},
+    {nil, []byte("mallory"), "0.25", nil},
+}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct {}
+func (fakeConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("read-only") }
+func (fakeConn) Close() error { return nil }
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+
+type fakeStmt string
+func (fakeStmt) Close() error { return nil }
+func (fakeStmt) NumInput() int { return 0 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) { return nil, fmt.Errorf("read-only") }
+func (q fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+    bound := func(re string) *int64 {
+        m := regexp.MustCompile(re).FindStringSubmatch(string(q))
+        if m == nil {
+            return nil
+        }
+        v, _ := strconv.ParseInt(m[1], 10, 64)
+        return &v
+    }
+
+    lower, upper := bound(`id >= (\d+)`), bound(`id < (\d+)`)
+    withNulls := regexp.MustCompile(`id IS NULL`).MatchString(string(q))
+    partition := lower != nil || upper != nil
+
+    rows := [][]driver.Value{}
+    for _, row := range fakeUsers {
+        id, ok := row[0].(int64)
+        if partition && (!ok && !withNulls || ok && (lower != nil && id < *lower || upper != nil && id >= *upper)) {
+            continue
+        }
+        rows = append(rows, row)
+    }
+    return &fakeRows{rows}, nil
+}
+
+type fakeRows struct { rows [][]driver.Value }
+func (*fakeRows) Columns() []string { return []string{"id", "name", "score", "at"} }
+func (*fakeRows) Close() error { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+    if len(r.rows) == 0 {
+        return io.EOF
+    }
+
+    copy(dest, r.rows[0])
+    r.rows = r.rows[1:]
+    return nil
+}
@@ -0,0 +1,230 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "context"
+    "strings"
+    "database/sql"
+    "encoding/json"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&sqlInsert{})
+
+// SQLInsertOptions configure the writing of datasets into SQL tables. See
+// SQLInsert
+type SQLInsertOptions struct {
+
+    // Columns of the table to insert into, in the order of the columns of the
+    // input. Defaults to the names of the input columns (see Dataset.Schema)
+    Columns []string
+
+    // BatchSize is the number of rows inserted per transaction. Defaults to
+    // BatchSize
+    BatchSize int
+
+    // Dialect of the placeholders of the statements: "postgres" for numbered
+    // placeholders ($1, $2...), or empty for question marks
+    Dialect string
+
+    // Copy inserts the rows with the COPY FROM STDIN protocol of Postgres, as
+    // supported by the github.com/lib/pq driver, instead of INSERT statements
+    Copy bool
+
+    // Attempts is the total number of attempts of every batch, retrying
+    // failed transactions after Backoff, doubled before every subsequent
+    // retry. Defaults to a single attempt
+    Attempts int
+    Backoff time.Duration
+}
+
+// SQLInsert returns a Runner that inserts the rows of its input datasets into
+// the table, and emits a single summary row of the number of rows inserted.
+// Every batch of rows (see SQLInsertOptions) is inserted with a single
+// multi-row INSERT statement within its own transaction, which is retried
+// upon failure, thus a failure may leave the previous batches committed. The
+// table and column names are used as-is, and the values are converted with
+// Value, where JSONs, Structs and Lists are written as JSON encoded strings.
+// NOTE: databases cannot be serialized, thus the returned Runner cannot be
+// distributed. See SQLInsertDSN.
+func SQLInsert(db *sql.DB, table string, opts SQLInsertOptions) Runner {
+    return &sqlInsert{Table: table, Options: opts, db: db}
+}
+
+// SQLInsertDSN is like SQLInsert, except that every node connects to the
+// database with the driver and data source name (see sql.Open), and inserts
+// its own input, thus it's safe to distribute.
+func SQLInsertDSN(driver, dsn, table string, opts SQLInsertOptions) Runner {
+    return &sqlInsert{driver, dsn, table, opts, nil}
+}
+
+type sqlInsert struct {
+    Driver string
+    DSN string
+    Table string
+    Options SQLInsertOptions
+    db *sql.DB
+}
+
+func (*sqlInsert) Returns() []Type { return []Type{As(Int, "rows")} }
+func (r *sqlInsert) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    db := r.db
+    if db == nil {
+        db, err = sql.Open(r.Driver, r.DSN)
+        if err != nil {
+            return err
+        }
+        defer db.Close()
+    }
+
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    batches := make(chan Dataset)
+    go func() {
+        defer close(batches)
+        (&rebatch{r.Options.BatchSize}).Run(ctx, inp, batches)
+    }()
+
+    defer func() {
+        cancel()
+        for _ = range batches {} // drain the remaining batches upon failure
+    }()
+
+    rows := 0
+    for data := range batches {
+        err = r.insertRetry(ctx, db, data)
+        if err != nil {
+            return err
+        }
+        rows += data.Len()
+    }
+
+    out <- newNamedDataset([]string{"rows"}, Ints{int64(rows)})
+    return nil
+}
+
+// insertRetry inserts the batch, retrying upon failure. See SQLInsertOptions
+func (r *sqlInsert) insertRetry(ctx context.Context, db *sql.DB, data Dataset) error {
+    wait := r.Options.Backoff
+    for attempt := 1; ; attempt++ {
+        err := r.insert(ctx, db, data)
+        if err == nil || attempt >= r.Options.Attempts || ctx.Err() != nil {
+            return err
+        }
+
+        select {
+        case <- time.After(wait):
+        case <- ctx.Done():
+            return err
+        }
+        wait *= 2
+    }
+}
+
+// insert the batch within a single transaction
+func (r *sqlInsert) insert(ctx context.Context, db *sql.DB, data Dataset) error {
+    cols := r.Options.Columns
+    if len(cols) == 0 {
+        cols = data.Schema().Names()
+    }
+
+    if len(cols) != data.Width() {
+        return fmt.Errorf("%d columns for %d input columns", len(cols), data.Width())
+    }
+
+    tx, err := db.BeginTx(ctx, nil)
+    if err != nil {
+        return err
+    }
+    defer tx.Rollback() // no-op after commit
+
+    if r.Options.Copy {
+        err = r.copy(ctx, tx, cols, data)
+    } else {
+        _, err = tx.ExecContext(ctx, r.statement(cols, data.Len()), sqlArgs(data)...)
+    }
+
+    if err != nil {
+        return err
+    }
+    return tx.Commit()
+}
+
+// statement returns the INSERT statement of n rows
+func (r *sqlInsert) statement(cols []string, n int) string {
+    var b strings.Builder
+    fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", r.Table, strings.Join(cols, ", "))
+    for i := 0; i < n; i++ {
+        if i > 0 {
+            b.WriteString(", ")
+        }
+
+        b.WriteString("(")
+        for j := range cols {
+            if j > 0 {
+                b.WriteString(", ")
+            }
+
+            if r.Options.Dialect == "postgres" {
+                fmt.Fprintf(&b, "$%d", i * len(cols) + j + 1)
+            } else {
+                b.WriteString("?")
+            }
+        }
+        b.WriteString(")")
+    }
+    return b.String()
+}
+
+// copy the rows with a COPY statement, executed once per row and once more
+// without arguments in order to flush the rows, as implemented by lib/pq
+func (r *sqlInsert) copy(ctx context.Context, tx *sql.Tx, cols []string, data Dataset) error {
+    query := fmt.Sprintf("COPY %s (%s) FROM STDIN", r.Table, strings.Join(cols, ", "))
+    stmt, err := tx.PrepareContext(ctx, query)
+    if err != nil {
+        return err
+    }
+    defer stmt.Close()
+
+    args := sqlArgs(data)
+    for i := 0; i < len(args); i += len(cols) {
+        _, err = stmt.ExecContext(ctx, args[i:i + len(cols)]...)
+        if err != nil {
+            return err
+        }
+    }
+
+    _, err = stmt.ExecContext(ctx)
+    return err
+}
+
+// sqlArgs returns the values of the rows of the dataset, row by row, as
+// arguments of SQL statements
+func sqlArgs(data Dataset) []interface{} {
+    args := make([]interface{}, 0, data.Len() * data.Width())
+    for i := 0; i < data.Len(); i++ {
+        for j := 0; j < data.Width(); j++ {
+            args = append(args, sqlArg(Value(data.At(j), i)))
+        }
+    }
+    return args
+}
+
+// sqlArg converts the native value into a value supported by database/sql
+func sqlArg(v interface{}) interface{} {
+    switch v := v.(type) {
+    case json.RawMessage:
+        return string(v)
+    case uuid.UUID:
+        return v.String()
+    case map[string]interface{}, []interface{}:
+        b, err := json.Marshal(v)
+        if err != nil {
+            return fmt.Sprint(v)
+        }
+        return string(b)
+    }
+    return v
+}
@@ -0,0 +1,139 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "strings"
+    "testing"
+    "database/sql"
+    "database/sql/driver"
+    "github.com/stretchr/testify/require"
+)
+
+func init() {
+    sql.Register("eptestlog", logDriver{})
+}
+
+func ExampleSQLInsert() {
+    db, _ := sql.Open("eptestlog", "example")
+    defer db.Close()
+
+    data := WithSchema(NewDataset(Strs{"bob", "alice", "eve"}, Ints{30, 25, 7}), Schema{{"name", Str}, {"age", Int}})
+    res, err := testRun(SQLInsert(db, "users", SQLInsertOptions{BatchSize: 2}), data)
+    fmt.Println(res, err)
+    fmt.Println(strings.Join(logs.Take("example"), "\n"))
+
+    // Output:
+    // [[3]] <nil>
+    // BEGIN
+    // INSERT INTO users (name, age) VALUES (?, ?), (?, ?) [bob 30 alice 25]
+    // COMMIT
+    // BEGIN
+    // INSERT INTO users (name, age) VALUES (?, ?) [eve 7]
+    // COMMIT
+}
+
+func TestSQLInsertPostgres(t *testing.T) {
+    data := NewDataset(JSONs{`{"a":1}`}, NewStructs([]string{"k"}, Ints{1}), Nullable(Floats{0}, Bools{false}))
+    opts := SQLInsertOptions{Columns: []string{"j", "s", "f"}, Dialect: "postgres"}
+    res, err := testRun(SQLInsertDSN("eptestlog", "postgres", "t", opts), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[1]]", fmt.Sprint(res))
+    require.Equal(t, []string{
+        "BEGIN",
+        `INSERT INTO t (j, s, f) VALUES ($1, $2, $3) [{"a":1} {"k":1} <nil>]`,
+        "COMMIT",
+    }, logs.Take("postgres"))
+
+    opts.Copy = true
+    _, err = testRun(SQLInsertDSN("eptestlog", "copy", "t", opts), Concat(data, data))
+    require.NoError(t, err)
+    require.Equal(t, []string{
+        "BEGIN",
+        `COPY t (j, s, f) FROM STDIN [{"a":1} {"k":1} <nil>]`,
+        `COPY t (j, s, f) FROM STDIN [{"a":1} {"k":1} <nil>]`,
+        "COPY t (j, s, f) FROM STDIN []",
+        "COMMIT",
+    }, logs.Take("copy"))
+}
+
+// Test that failed batches are rolled back and retried, and that the error is
+// returned when all of the attempts fail
+func TestSQLInsertRetry(t *testing.T) {
+    data := WithSchema(NewDataset(Ints{1}), Schema{{"id", Int}})
+
+    logs.Fail("retry", 1)
+    opts := SQLInsertOptions{Attempts: 2}
+    res, err := testRun(SQLInsertDSN("eptestlog", "retry", "t", opts), data)
+    require.NoError(t, err)
+    require.Equal(t, "[[1]]", fmt.Sprint(res))
+    require.Equal(t, []string{
+        "BEGIN",
+        "ROLLBACK",
+        "BEGIN",
+        "INSERT INTO t (id) VALUES (?) [1]",
+        "COMMIT",
+    }, logs.Take("retry"))
+
+    logs.Fail("retry", 2)
+    _, err = testRun(SQLInsertDSN("eptestlog", "retry", "t", opts), data, data)
+    require.EqualError(t, err, "failed")
+    logs.Take("retry")
+
+    _, err = testRun(SQLInsertDSN("eptestlog", "retry", "t", SQLInsertOptions{Columns: []string{"a", "b"}}), data)
+    require.EqualError(t, err, "2 columns for 1 input columns")
+}
+
+// logs of the statements executed by the logDriver, by data source name
+var logs = &stmtLogs{logs: map[string][]string{}, fails: map[string]int{}}
+
+type stmtLogs struct {
+    sync.Mutex
+    logs map[string][]string
+    fails map[string]int // number of statements to fail
+}
+
+func (l *stmtLogs) Log(dsn, s string) error {
+    l.Lock()
+    defer l.Unlock()
+    if l.fails[dsn] > 0 && s != "BEGIN" && s != "ROLLBACK" {
+        l.fails[dsn]--
+        return fmt.Errorf("failed")
+    }
+
+    l.logs[dsn] = append(l.logs[dsn], s)
+    return nil
+}
+
+func (l *stmtLogs) Fail(dsn string, n int) {
+    l.Lock()
+    defer l.Unlock()
+    l.fails[dsn] = n
+}
+
+func (l *stmtLogs) Take(dsn string) []string {
+    l.Lock()
+    defer l.Unlock()
+    res := l.logs[dsn]
+    delete(l.logs, dsn)
+    return res
+}
+
+// logDriver is a database/sql driver that logs the executed statements
+type logDriver struct {}
+func (logDriver) Open(dsn string) (driver.Conn, error) { return logConn(dsn), nil }
+
+type logConn string
+func (c logConn) Close() error { return nil }
+func (c logConn) Prepare(query string) (driver.Stmt, error) { return logStmt{string(c), query}, nil }
+func (c logConn) Begin() (driver.Tx, error) { return c, logs.Log(string(c), "BEGIN") }
+func (c logConn) Commit() error { return logs.Log(string(c), "COMMIT") }
+func (c logConn) Rollback() error { return logs.Log(string(c), "ROLLBACK") }
+
+type logStmt struct { DSN, SQL string }
+func (logStmt) Close() error { return nil }
+func (logStmt) NumInput() int { return -1 }
+func (logStmt) Query([]driver.Value) (driver.Rows, error) { return nil, fmt.Errorf("write-only") }
+func (s logStmt) Exec(args []driver.Value) (driver.Result, error) {
+    return driver.RowsAffected(len(args)), logs.Log(s.DSN, fmt.Sprint(s.SQL, " ", args))
+}
@@ -0,0 +1,132 @@
+package ep
+
+import (
+    "sort"
+)
+
+// number of hashes kept by the distinct values sketch of Stats
+const statsSketch = 256
+
+// Stats are lightweight statistics of the values of a single column, for
+// planning decisions like choosing the smaller side of a join, or estimating
+// the split points of range partitioning. Stats of several batches (or nodes)
+// are combined with Merge. See ColumnStats
+type Stats struct {
+    Rows int // number of rows, including nulls
+    Nulls int // number of null rows
+    Min Data // the single lowest non-null value, or nil if there are none
+    Max Data // the single highest non-null value, or nil if there are none
+
+    // Hashes are the lowest distinct hashes of the non-null values, in order
+    // (a K-minimum-values sketch), used for estimating the number of distinct
+    // values. See Distinct
+    Hashes []uint64
+}
+
+// ColumnStats computes the Stats of the values of the data, comparing them
+// with CompareAt and hashing them with Hash.
+func ColumnStats(data Data) Stats {
+    s := Stats{Rows: data.Len()}
+    hashes := make([]uint64, data.Len())
+    Hash(data, 0, hashes)
+
+    min, max := -1, -1
+    distinct := []uint64{}
+    for i := range hashes {
+        if isNullAt(data, i) {
+            s.Nulls++
+            continue
+        }
+
+        if min < 0 || CompareAt(data, i, data, min) < 0 {
+            min = i
+        }
+
+        if max < 0 || CompareAt(data, i, data, max) > 0 {
+            max = i
+        }
+
+        distinct = append(distinct, mixHash(hashes[i]))
+    }
+
+    if min >= 0 {
+        s.Min, s.Max = statsValue(data, min), statsValue(data, max)
+    }
+
+    s.Hashes = lowestHashes(distinct)
+    return s
+}
+
+// DatasetStats computes the Stats of every column of the dataset. See
+// ColumnStats
+func DatasetStats(data Dataset) []Stats {
+    res := make([]Stats, data.Width())
+    for i := range res {
+        res[i] = ColumnStats(data.At(i))
+    }
+    return res
+}
+
+// Merge returns the combined Stats of both stats, as if they were computed
+// over the rows of both.
+func (s Stats) Merge(other Stats) Stats {
+    res := Stats{Rows: s.Rows + other.Rows, Nulls: s.Nulls + other.Nulls}
+    res.Min, res.Max = s.Min, s.Max
+    if res.Min == nil || other.Min != nil && CompareAt(other.Min, 0, res.Min, 0) < 0 {
+        res.Min = other.Min
+    }
+
+    if res.Max == nil || other.Max != nil && CompareAt(other.Max, 0, res.Max, 0) > 0 {
+        res.Max = other.Max
+    }
+
+    res.Hashes = lowestHashes(append(append([]uint64{}, s.Hashes...), other.Hashes...))
+    return res
+}
+
+// Distinct returns an estimate of the number of distinct non-null values. It's
+// exact for up to a few hundred distinct values, and is typically within a
+// few percent otherwise.
+func (s Stats) Distinct() int {
+    if len(s.Hashes) < statsSketch {
+        return len(s.Hashes)
+    }
+
+    // the k-th lowest of n uniformly distributed hashes is expected at k/n of
+    // the hashes range
+    kth := float64(s.Hashes[statsSketch - 1]) / (1 << 64)
+    return int(float64(statsSketch - 1) / kth)
+}
+
+// statsValue returns a copy of the single non-null value at index i
+func statsValue(data Data, i int) Data {
+    if vs, ok := data.(nullable); ok {
+        data = vs.Values
+    }
+    return Clone(data.Slice(i, i + 1))
+}
+
+// lowestHashes returns the sorted lowest distinct hashes, up to the size of the
+// sketch
+func lowestHashes(hashes []uint64) []uint64 {
+    sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+    res := []uint64{}
+    for i, h := range hashes {
+        if len(res) == statsSketch {
+            break
+        } else if i == 0 || h != hashes[i - 1] {
+            res = append(res, h)
+        }
+    }
+    return res
+}
+
+// mixHash scrambles the bits of the hash (the splitmix64 finalizer), in order
+// for the hashes of similar values to be uniformly distributed
+func mixHash(h uint64) uint64 {
+    h ^= h >> 30
+    h *= 0xbf58476d1ce4e5b9
+    h ^= h >> 27
+    h *= 0x94d049bb133111eb
+    return h ^ (h >> 31)
+}
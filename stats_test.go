@@ -0,0 +1,60 @@
+package ep
+
+import (
+    "fmt"
+    "strconv"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleColumnStats() {
+    s := ColumnStats(Nullable(Ints{3, 1, 5, 3}, Bools{true, true, false, true}))
+    fmt.Println(s.Rows, s.Nulls, s.Min, s.Max, s.Distinct())
+
+    // Output: 4 1 [1] [3] 2
+}
+
+func TestStatsEmpty(t *testing.T) {
+    s := ColumnStats(Null.Data(3))
+    require.Equal(t, 3, s.Nulls)
+    require.Nil(t, s.Min)
+    require.Nil(t, s.Max)
+    require.Equal(t, 0, s.Distinct())
+
+    s = s.Merge(ColumnStats(Strs{"b", "a"}))
+    require.Equal(t, 5, s.Rows)
+    require.Equal(t, Strs{"a"}, s.Min)
+    require.Equal(t, Strs{"b"}, s.Max)
+}
+
+func TestDatasetStats(t *testing.T) {
+    stats := DatasetStats(NewDataset(Strs{"x", "y", "x"}, Floats{0.5, -1, 2}))
+    require.Equal(t, 2, len(stats))
+    require.Equal(t, 2, stats[0].Distinct())
+    require.Equal(t, Floats{-1}, stats[1].Min)
+    require.Equal(t, Floats{2}, stats[1].Max)
+}
+
+// Tests that the distinct estimate is exact for small numbers of values, and
+// approximate for large ones, when merged across batches
+func TestStatsDistinct(t *testing.T) {
+    var s Stats
+    for batch := 0; batch < 10; batch++ {
+        values := make(Ints, 2000)
+        for i := range values {
+            values[i] = int64(i * 10 + batch) % 10000 // 10k distinct values
+        }
+        s = s.Merge(ColumnStats(values))
+    }
+
+    require.Equal(t, 20000, s.Rows)
+    require.Equal(t, Ints{0}, s.Min)
+    require.Equal(t, Ints{19999 % 10000}, s.Max)
+    require.InDelta(t, 10000, s.Distinct(), 2000)
+
+    strs := Strs{}
+    for i := 0; i < 200; i++ {
+        strs = append(strs, strconv.Itoa(i % 100))
+    }
+    require.Equal(t, 100, ColumnStats(strs).Distinct())
+}
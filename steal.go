@@ -0,0 +1,242 @@
+package ep
+
+import (
+    "net"
+    "sync"
+    "context"
+    "encoding/gob"
+    "github.com/satori/go.uuid"
+)
+
+var _ = registerGob(&steal{}, &stealMsg{})
+
+const (
+    stealReq = 1 // request for a pending item
+    stealItem = 2 // response with a stolen item
+    stealNone = 3 // response indicating there are no more pending items
+    stealDone = 4 // notification that the peer will not request more items
+)
+
+// Steal returns a source Runner that emits the provided work items (like file
+// splits to scan), one item per dataset. When distributed, the items are
+// assigned uniformly to all of the participating nodes, but a node that has
+// exhausted its own share requests the pending items of its busy peers. This
+// way, a node with a larger (or slower) share doesn't lag behind while the
+// other nodes are idle. Pipeline it into a Runner that performs the actual
+// work for each item. Each item is emitted exactly once across the cluster.
+func Steal(items ...string) Runner {
+    return &steal{UID: uuid.NewV4().String(), Items: items}
+}
+
+type steal struct {
+    UID string
+    Items []string
+}
+
+func (*steal) Returns() []Type { return []Type{Str} }
+func (s *steal) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    allNodes, _ := ctx.Value("ep.AllNodes").([]string)
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+
+    // determine the local share of the items. When not distributed, all of
+    // the items are local.
+    q := &stealQueue{}
+    for i, item := range s.Items {
+        if len(allNodes) == 0 || allNodes[i % len(allNodes)] == thisNode {
+            q.Items = append(q.Items, item)
+        }
+    }
+
+    // open the control connections to all peers, and start serving their
+    // steal requests from the local queue
+    var peers []*stealPeer
+    defer func() {
+        for _, p := range peers {
+            p.conn.Close()
+        }
+    }()
+
+    for _, n := range allNodes {
+        if n == thisNode {
+            continue
+        }
+
+        dist := ctx.Value("ep.Distributer").(interface {
+            Connect(addr, uid string) (net.Conn, error)
+        })
+
+        conn, err := dist.Connect(n, s.UID)
+        if err != nil {
+            return err
+        }
+
+        p := newStealPeer(conn)
+        peers = append(peers, p)
+        go p.Listen(q)
+    }
+
+    // emit the local share first
+    for item, ok := q.PopFront(); ok; item, ok = q.PopFront() {
+        if ctx.Err() != nil {
+            return ctx.Err()
+        }
+
+        select {
+        case out <- NewDataset(Strs{item}):
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+    }
+
+    // local share is exhausted, steal the pending items from the peers until
+    // they have none left. Once a peer reports that it has no pending items,
+    // it will never have any, as the queues are only shrinking.
+    for _, p := range peers {
+        for {
+            err = p.Send(&stealMsg{Kind: stealReq})
+            if err != nil {
+                return err
+            }
+
+            var msg *stealMsg
+            select {
+            case msg = <- p.resps:
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+
+            if msg == nil || msg.Kind == stealNone {
+                break // peer has no more items, or disconnected.
+            }
+
+            select {
+            case out <- NewDataset(Strs{msg.Item}):
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+    }
+
+    // notify all peers that we're done stealing, and wait for them to finish
+    // stealing from us before closing the connections.
+    for _, p := range peers {
+        err = p.Send(&stealMsg{Kind: stealDone})
+        if err != nil {
+            return err
+        }
+    }
+
+    for _, p := range peers {
+        select {
+        case <- p.done:
+        case <- ctx.Done():
+            return ctx.Err()
+        }
+    }
+
+    return nil
+}
+
+// stealQueue is the thread-safe queue of pending local items. The local node
+// consumes it from the front, while peers steal from the back.
+type stealQueue struct {
+    l sync.Mutex
+    Items []string
+}
+
+func (q *stealQueue) PopFront() (item string, ok bool) {
+    q.l.Lock()
+    defer q.l.Unlock()
+    if len(q.Items) == 0 {
+        return "", false
+    }
+
+    item, q.Items = q.Items[0], q.Items[1:]
+    return item, true
+}
+
+func (q *stealQueue) PopBack() (item string, ok bool) {
+    q.l.Lock()
+    defer q.l.Unlock()
+    if len(q.Items) == 0 {
+        return "", false
+    }
+
+    item, q.Items = q.Items[len(q.Items) - 1], q.Items[:len(q.Items) - 1]
+    return item, true
+}
+
+// stealPeer is the control connection to a single peer. The same connection is
+// used in both directions: for stealing from the peer, and for serving the
+// peer's steal requests.
+type stealPeer struct {
+    conn net.Conn
+    l sync.Mutex // guards the encoder, used by both Send and Listen
+    enc *gob.Encoder
+    dec *WireDecoder
+    resps chan *stealMsg // responses to our own requests
+    done chan struct{} // closed when the peer is done stealing from us
+}
+
+func newStealPeer(conn net.Conn) *stealPeer {
+    return &stealPeer{
+        conn: conn,
+        enc: gob.NewEncoder(conn),
+        dec: NewWireDecoder(conn),
+
+        // there's at most one outstanding request per peer, thus at most one
+        // response to buffer. This ensures that Listen never blocks on it.
+        resps: make(chan *stealMsg, 1),
+        done: make(chan struct{}),
+    }
+}
+
+func (p *stealPeer) Send(msg *stealMsg) error {
+    p.l.Lock()
+    defer p.l.Unlock()
+    return p.enc.Encode(msg)
+}
+
+// Listen to the messages from the peer until the connection is closed. Steal
+// requests are served from the local queue.
+func (p *stealPeer) Listen(q *stealQueue) {
+    defer close(p.resps)
+
+    done := false
+    defer func() {
+        if !done {
+            close(p.done) // disconnected before done.
+        }
+    }()
+
+    for {
+        msg := &stealMsg{}
+        err := p.dec.Decode(msg)
+        if err != nil {
+            return // connection closed
+        }
+
+        switch msg.Kind {
+        case stealReq:
+            resp := &stealMsg{Kind: stealNone}
+            item, ok := q.PopBack()
+            if ok {
+                resp = &stealMsg{Kind: stealItem, Item: item}
+            }
+
+            if p.Send(resp) != nil {
+                return
+            }
+        case stealDone:
+            done = true
+            close(p.done)
+        default:
+            p.resps <- msg
+        }
+    }
+}
+
+type stealMsg struct {
+    Kind int
+    Item string
+}
@@ -0,0 +1,85 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleSteal() {
+    runner := Steal("file1.csv", "file2.csv")
+    data, err := testRun(runner)
+    fmt.Println(data, err)
+
+    // Output: [[file1.csv file2.csv]] <nil>
+}
+
+// Test that the idle node steals the pending items of the slow node, and that
+// all items are emitted exactly once
+func TestStealUnbalanced(t *testing.T) {
+    dists := testCluster(t, 2)
+
+    items := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+    runner := Pipeline(Steal(items...), &slowNode{":5552"}, &nodeAddr{}, Gather())
+    runner = dists[0].Distribute(runner, ":5551", ":5552")
+
+    data, err := testRun(runner, NewDataset(Null.Data(1)))
+    require.NoError(t, err)
+    require.Equal(t, len(items), data.Len())
+
+    seen := map[string]int{}
+    for _, item := range data.At(0).Strings() {
+        seen[item]++
+    }
+
+    for _, item := range items {
+        require.Equal(t, 1, seen[item], "item %s", item)
+    }
+
+    fast := 0
+    for _, addr := range data.At(1).Strings() {
+        if addr == ":5551" {
+            fast++
+        }
+    }
+
+    require.True(t, fast > len(items) / 2, "no items were stolen")
+}
+
+// Tests that Steal returns once it's canceled while its output isn't read
+func TestStealCancel(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    inp, out := make(chan Dataset), make(chan Dataset)
+    close(inp)
+
+    errs := make(chan error, 1)
+    go func() { errs <- Steal("a", "b").Run(ctx, inp, out) }()
+    time.Sleep(10 * time.Millisecond) // blocked on emitting the first item
+    cancel()
+
+    select {
+    case err := <- errs:
+        require.Equal(t, context.Canceled, err)
+    case <- time.After(time.Second):
+        t.Fatal("steal did not return once canceled")
+    }
+}
+
+var _ = registerGob(&slowNode{})
+
+// slowNode is a passthrough Runner that's delayed on a specific node
+type slowNode struct { Addr string }
+func (*slowNode) Returns() []Type { return []Type{Wildcard} }
+func (r *slowNode) Run(ctx context.Context, inp, out chan Dataset) error {
+    thisNode, _ := ctx.Value("ep.ThisNode").(string)
+    for data := range inp {
+        if thisNode == r.Addr {
+            time.Sleep(20 * time.Millisecond)
+        }
+
+        out <- data
+    }
+    return nil
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+    "io"
+    "fmt"
+    "sort"
+    "context"
+    "strconv"
+    "strings"
+    "net/url"
+    "net/http"
+    "encoding/json"
+)
+
+// GCSConfig configures the access to Google Cloud Storage
+type GCSConfig struct {
+    Endpoint string // defaults to https://storage.googleapis.com
+
+    // Token returns the OAuth2 access token of the requests, which are
+    // anonymous when it's nil. It's called for every request, thus it should
+    // cache the token until it expires.
+    Token func(ctx context.Context) (string, error)
+
+    Client *http.Client // defaults to http.DefaultClient
+}
+
+// GCS returns the Storage of the objects of a Google Cloud Storage bucket,
+// using its JSON API
+func GCS(bucket string, cfg GCSConfig) Storage {
+    if cfg.Endpoint == "" {
+        cfg.Endpoint = "https://storage.googleapis.com"
+    }
+
+    if cfg.Client == nil {
+        cfg.Client = http.DefaultClient
+    }
+    return &gcs{bucket, cfg}
+}
+
+type gcs struct {
+    Bucket string
+    Config GCSConfig
+}
+
+// gcsObject is the metadata of an object in the JSON API, where sizes are
+// strings
+type gcsObject struct {
+    Name string `json:"name"`
+    Size string `json:"size"`
+}
+
+func (o gcsObject) Object() (Object, error) {
+    size, err := strconv.ParseInt(o.Size, 10, 64)
+    if err != nil {
+        return Object{}, fmt.Errorf("gcs: invalid size of %s: %q", o.Name, o.Size)
+    }
+    return Object{o.Name, size}, nil
+}
+
+func (s *gcs) List(ctx context.Context, prefix string) ([]Object, error) {
+    res := []Object{}
+    token := ""
+    for {
+        query := url.Values{"prefix": {prefix}, "fields": {"items(name,size),nextPageToken"}}
+        if token != "" {
+            query.Set("pageToken", token)
+        }
+
+        var page struct {
+            Items []gcsObject `json:"items"`
+            NextPageToken string `json:"nextPageToken"`
+        }
+
+        err := s.getJSON(ctx, "/storage/v1/b/" + url.PathEscape(s.Bucket) + "/o", query, &page)
+        if err != nil {
+            return nil, err
+        }
+
+        for _, item := range page.Items {
+            obj, err := item.Object()
+            if err != nil {
+                return nil, err
+            }
+            res = append(res, obj)
+        }
+
+        if page.NextPageToken == "" {
+            break
+        }
+        token = page.NextPageToken
+    }
+
+    sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+    return res, nil
+}
+
+func (s *gcs) Open(ctx context.Context, key string) (Reader, error) {
+    var meta gcsObject
+    err := s.getJSON(ctx, s.objectPath(key), nil, &meta)
+    if err != nil {
+        return nil, err
+    }
+
+    obj, err := meta.Object()
+    if err != nil {
+        return nil, err
+    }
+
+    open := func(offset int64) (io.ReadCloser, error) {
+        header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+        query := url.Values{"alt": {"media"}}
+        resp, err := s.do(ctx, s.objectPath(key), query, header)
+        if err != nil {
+            return nil, err
+        }
+        return rangeBody(resp, offset)
+    }
+    return &rangeReader{size: obj.Size, open: open}, nil
+}
+
+func (s *gcs) objectPath(key string) string {
+    return "/storage/v1/b/" + url.PathEscape(s.Bucket) + "/o/" + url.PathEscape(key)
+}
+
+func (s *gcs) getJSON(ctx context.Context, path string, query url.Values, v interface{}) error {
+    resp, err := s.do(ctx, path, query, nil)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    err = json.NewDecoder(resp.Body).Decode(v)
+    if err != nil {
+        return fmt.Errorf("gcs: %s: %s", path, err)
+    }
+    return nil
+}
+
+// do sends a GET request of the escaped path, and fails on unsuccessful
+// responses
+func (s *gcs) do(ctx context.Context, path string, query url.Values, header http.Header) (*http.Response, error) {
+    u := strings.TrimSuffix(s.Config.Endpoint, "/") + path
+    if len(query) > 0 {
+        u += "?" + query.Encode()
+    }
+
+    req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+    if err != nil {
+        return nil, err
+    }
+
+    for k, vs := range header {
+        req.Header[k] = vs
+    }
+
+    if s.Config.Token != nil {
+        token, err := s.Config.Token(ctx)
+        if err != nil {
+            return nil, err
+        }
+        req.Header.Set("Authorization", "Bearer " + token)
+    }
+
+    resp, err := s.Config.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    return resp, checkStatus("gcs", "GET", path, resp)
+}
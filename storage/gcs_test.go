@@ -0,0 +1,97 @@
+package storage
+
+import (
+    "io"
+    "fmt"
+    "time"
+    "sort"
+    "context"
+    "strings"
+    "testing"
+    "net/http"
+    "encoding/json"
+    "net/http/httptest"
+    "github.com/stretchr/testify/require"
+)
+
+func TestGCS(t *testing.T) {
+    objects := map[string]string{
+        "data/a.json": "0123456789",
+        "data/b.json": "abc",
+        "other/c.json": "x",
+    }
+
+    srv := httptest.NewServer(fakeGCS("bucket", objects))
+    defer srv.Close()
+
+    token := func(ctx context.Context) (string, error) { return "token", nil }
+    s := GCS("bucket", GCSConfig{Endpoint: srv.URL, Token: token})
+
+    ctx := context.Background()
+    res, err := s.List(ctx, "data/")
+    require.NoError(t, err)
+    require.Equal(t, []Object{{"data/a.json", 10}, {"data/b.json", 3}}, res)
+
+    r, err := s.Open(ctx, "data/a.json")
+    require.NoError(t, err)
+    require.Equal(t, int64(10), r.Size())
+
+    _, err = r.Seek(8, io.SeekStart)
+    require.NoError(t, err)
+
+    b, err := io.ReadAll(r)
+    require.NoError(t, err)
+    require.Equal(t, "89", string(b))
+
+    _, err = s.Open(ctx, "data/missing.json")
+    require.EqualError(t, err, "gcs: GET /storage/v1/b/bucket/o/data%2Fmissing.json: 404 Not Found")
+
+    s = GCS("bucket", GCSConfig{Endpoint: srv.URL})
+    _, err = s.List(ctx, "")
+    require.EqualError(t, err, "gcs: GET /storage/v1/b/bucket/o: 401 Unauthorized")
+}
+
+// fakeGCS is a minimal server of the JSON API of the objects, that lists a
+// single object per page and requires a token
+func fakeGCS(bucket string, objects map[string]string) http.Handler {
+    keys := []string{}
+    for k := range objects {
+        keys = append(keys, k)
+    }
+    sort.Strings(keys)
+
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("Authorization") != "Bearer token" {
+            w.WriteHeader(http.StatusUnauthorized)
+            return
+        }
+
+        path := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/" + bucket + "/o")
+        q := r.URL.Query()
+        if path == "" {
+            page := []map[string]string{}
+            for _, k := range keys {
+                if strings.HasPrefix(k, q.Get("prefix")) && k > q.Get("pageToken") {
+                    page = append(page, map[string]string{"name": k, "size": fmt.Sprint(len(objects[k]))})
+                }
+            }
+
+            res := map[string]interface{}{"items": page}
+            if len(page) > 1 {
+                res["items"], res["nextPageToken"] = page[:1], page[0]["name"]
+            }
+            json.NewEncoder(w).Encode(res)
+            return
+        }
+
+        key := strings.TrimPrefix(path, "/")
+        content, ok := objects[key]
+        if !ok {
+            w.WriteHeader(http.StatusNotFound)
+        } else if q.Get("alt") == "media" {
+            http.ServeContent(w, r, key, time.Time{}, strings.NewReader(content))
+        } else {
+            json.NewEncoder(w).Encode(map[string]string{"name": key, "size": fmt.Sprint(len(content))})
+        }
+    })
+}
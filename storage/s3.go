@@ -0,0 +1,256 @@
+package storage
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "sort"
+    "time"
+    "context"
+    "strings"
+    "net/url"
+    "net/http"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/xml"
+)
+
+// hash of empty payloads, as all of the requests are bodiless
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// S3Config configures the access to S3, or to S3-compatible object stores
+type S3Config struct {
+    Region string // defaults to us-east-1
+
+    // Endpoint is the base URL of S3-compatible object stores, which are
+    // addressed in path-style (endpoint/bucket/key). Defaults to AWS S3, which
+    // is addressed in virtual-hosted-style (bucket.s3.region.amazonaws.com/key)
+    Endpoint string
+
+    // Credentials of signed requests (Signature Version 4). Requests are
+    // anonymous when AccessKey is empty
+    AccessKey string
+    SecretKey string
+    SessionToken string
+
+    Client *http.Client // defaults to http.DefaultClient
+}
+
+// S3ConfigFromEnv returns the S3Config of the standard AWS environment
+// variables: AWS_REGION (or AWS_DEFAULT_REGION), AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN and AWS_ENDPOINT_URL_S3 (or
+// AWS_ENDPOINT_URL)
+func S3ConfigFromEnv() S3Config {
+    env := func(keys ...string) string {
+        for _, k := range keys {
+            if v := os.Getenv(k); v != "" {
+                return v
+            }
+        }
+        return ""
+    }
+
+    return S3Config{
+        Region: env("AWS_REGION", "AWS_DEFAULT_REGION"),
+        Endpoint: env("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+        AccessKey: env("AWS_ACCESS_KEY_ID"),
+        SecretKey: env("AWS_SECRET_ACCESS_KEY"),
+        SessionToken: env("AWS_SESSION_TOKEN"),
+    }
+}
+
+// S3 returns the Storage of the objects of an S3 bucket
+func S3(bucket string, cfg S3Config) Storage {
+    if cfg.Region == "" {
+        cfg.Region = "us-east-1"
+    }
+
+    if cfg.Client == nil {
+        cfg.Client = http.DefaultClient
+    }
+    return &s3{bucket, cfg}
+}
+
+type s3 struct {
+    Bucket string
+    Config S3Config
+}
+
+func (s *s3) List(ctx context.Context, prefix string) ([]Object, error) {
+    res := []Object{}
+    token := ""
+    for {
+        query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+        if token != "" {
+            query.Set("continuation-token", token)
+        }
+
+        resp, err := s.do(ctx, "GET", "", query, nil)
+        if err != nil {
+            return nil, err
+        }
+
+        var page struct {
+            Contents []struct {
+                Key string
+                Size int64
+            }
+            IsTruncated bool
+            NextContinuationToken string
+        }
+
+        err = xml.NewDecoder(resp.Body).Decode(&page)
+        resp.Body.Close()
+        if err != nil {
+            return nil, fmt.Errorf("s3: list %s: %s", prefix, err)
+        }
+
+        for _, obj := range page.Contents {
+            res = append(res, Object{obj.Key, obj.Size})
+        }
+
+        if !page.IsTruncated || page.NextContinuationToken == "" {
+            break
+        }
+        token = page.NextContinuationToken
+    }
+
+    sort.Slice(res, func(i, j int) bool { return res[i].Key < res[j].Key })
+    return res, nil
+}
+
+func (s *s3) Open(ctx context.Context, key string) (Reader, error) {
+    resp, err := s.do(ctx, "HEAD", key, nil, nil)
+    if err != nil {
+        return nil, err
+    }
+    resp.Body.Close()
+
+    open := func(offset int64) (io.ReadCloser, error) {
+        header := http.Header{"Range": {fmt.Sprintf("bytes=%d-", offset)}}
+        resp, err := s.do(ctx, "GET", key, nil, header)
+        if err != nil {
+            return nil, err
+        }
+        return rangeBody(resp, offset)
+    }
+    return &rangeReader{size: resp.ContentLength, open: open}, nil
+}
+
+// do sends a signed request for the key (or the bucket itself when empty), and
+// fails on unsuccessful responses
+func (s *s3) do(ctx context.Context, method, key string, query url.Values, header http.Header) (*http.Response, error) {
+    u, err := url.Parse(s.Config.Endpoint)
+    if s.Config.Endpoint == "" {
+        u, err = &url.URL{Scheme: "https", Host: s.Bucket + ".s3." + s.Config.Region + ".amazonaws.com"}, nil
+    } else if err == nil {
+        u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.Bucket
+    }
+
+    if err != nil {
+        return nil, err
+    }
+
+    u.Path += "/" + key
+    u.RawPath = uriEncode(u.Path)
+    u.RawQuery = query.Encode()
+    req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+    if err != nil {
+        return nil, err
+    }
+
+    for k, vs := range header {
+        req.Header[k] = vs
+    }
+
+    if s.Config.AccessKey != "" {
+        signV4(req, s.Config, time.Now())
+    }
+
+    resp, err := s.Config.Client.Do(req)
+    if err != nil {
+        return nil, err
+    }
+    return resp, checkStatus("s3", method, key, resp)
+}
+
+// signV4 signs the request with AWS Signature Version 4. See
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-header-based-auth.html
+func signV4(req *http.Request, cfg S3Config, now time.Time) {
+    now = now.UTC()
+    amzDate := now.Format("20060102T150405Z")
+    date := now.Format("20060102")
+
+    req.Header.Set("X-Amz-Date", amzDate)
+    req.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+    if cfg.SessionToken != "" {
+        req.Header.Set("X-Amz-Security-Token", cfg.SessionToken)
+    }
+
+    // canonical headers: the host and all of the amz headers, sorted
+    headers := map[string]string{"host": req.URL.Host}
+    for k, vs := range req.Header {
+        if k = strings.ToLower(k); strings.HasPrefix(k, "x-amz-") {
+            headers[k] = strings.TrimSpace(strings.Join(vs, ","))
+        }
+    }
+
+    names := []string{}
+    for k := range headers {
+        names = append(names, k)
+    }
+    sort.Strings(names)
+
+    canonicalHeaders := ""
+    for _, k := range names {
+        canonicalHeaders += k + ":" + headers[k] + "\n"
+    }
+    signedHeaders := strings.Join(names, ";")
+
+    // canonical query: sorted keys and values, encoded with %20 for spaces
+    query := strings.Replace(req.URL.Query().Encode(), "+", "%20", -1)
+    canonicalRequest := strings.Join([]string{
+        req.Method,
+        req.URL.EscapedPath(),
+        query,
+        canonicalHeaders,
+        signedHeaders,
+        emptySHA256,
+    }, "\n")
+
+    scope := date + "/" + cfg.Region + "/s3/aws4_request"
+    hash := sha256.Sum256([]byte(canonicalRequest))
+    toSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+    key := []byte("AWS4" + cfg.SecretKey)
+    for _, v := range []string{date, cfg.Region, "s3", "aws4_request"} {
+        key = hmacSHA256(key, v)
+    }
+
+    signature := hex.EncodeToString(hmacSHA256(key, toSign))
+    req.Header.Set("Authorization", fmt.Sprintf(
+        "AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+        cfg.AccessKey, scope, signedHeaders, signature))
+}
+
+// uriEncode escapes every byte of the path except for the unreserved
+// characters and slashes, as required for signing
+func uriEncode(path string) string {
+    var b strings.Builder
+    for i := 0; i < len(path); i++ {
+        c := path[i]
+        if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+            b.WriteByte(c)
+        } else {
+            fmt.Fprintf(&b, "%%%02X", c)
+        }
+    }
+    return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+    h := hmac.New(sha256.New, key)
+    h.Write([]byte(data))
+    return h.Sum(nil)
+}
@@ -0,0 +1,299 @@
+// Package storage abstracts the file systems and object stores that are read
+// by the ep source runners (like ep.CSVScan), such that they can read directly
+// from S3 or GCS, and divide the objects between the nodes of the cluster.
+// Locations are URLs, where the scheme selects the storage and the host is its
+// bucket:
+//
+//      /data/*.csv                 local files
+//      s3://bucket/data/*.csv      S3 objects (see S3)
+//      gs://bucket/data/*.csv      GCS objects (see GCS)
+//
+// S3 is configured by the standard AWS environment variables by default, and
+// GCS allows anonymous access to public buckets. Use Register in order to
+// configure them differently, or to add other storages.
+package storage
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "path"
+    "sort"
+    "sync"
+    "strings"
+    "context"
+    "net/url"
+    "net/http"
+    "path/filepath"
+)
+
+// Object is a single file or object within a Storage
+type Object struct {
+    Key string
+    Size int64
+}
+
+// Storage is a flat namespace of objects, like a bucket of an object store
+type Storage interface {
+
+    // List returns the objects whose keys start with the prefix, in order
+    List(ctx context.Context, prefix string) ([]Object, error)
+
+    // Open returns a Reader of the object. Reading starts lazily, such that
+    // seeking before reading only reads the requested range.
+    Open(ctx context.Context, key string) (Reader, error)
+}
+
+// Reader reads the content of an object. Object stores implement Seek by
+// issuing a range request from the new offset on the next Read.
+type Reader interface {
+    io.ReadSeekCloser
+
+    // Size of the object in bytes
+    Size() int64
+}
+
+// Opener returns the Storage of a bucket. See Register
+type Opener func(bucket string) (Storage, error)
+
+var openers = struct {
+    sync.Mutex
+    m map[string]Opener
+}{m: map[string]Opener{
+    "": openLocal,
+    "file": openLocal,
+    "s3": func(bucket string) (Storage, error) { return S3(bucket, S3ConfigFromEnv()), nil },
+    "gs": func(bucket string) (Storage, error) { return GCS(bucket, GCSConfig{}), nil },
+}}
+
+// Register the Opener of the storages of the URL scheme, replacing the
+// existing one, if any. A nil Opener unregisters the scheme
+func Register(scheme string, open Opener) {
+    openers.Lock()
+    defer openers.Unlock()
+    if open == nil {
+        delete(openers.m, scheme)
+    } else {
+        openers.m[scheme] = open
+    }
+}
+
+// Resolve returns the Storage of the URL, and the key within it. Locations
+// without a scheme are local paths.
+func Resolve(location string) (Storage, string, error) {
+    u, err := url.Parse(location)
+    if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+        return local{}, location, nil // paths, including windows drives
+    }
+
+    openers.Lock()
+    open, ok := openers.m[u.Scheme]
+    openers.Unlock()
+    if !ok {
+        return nil, "", fmt.Errorf("unknown storage scheme %q", u.Scheme)
+    } else if u.Scheme == "file" {
+        return local{}, u.Path, nil
+    }
+
+    s, err := open(u.Host)
+    return s, strings.TrimPrefix(u.Path, "/"), err
+}
+
+// Glob returns the objects matching the location, in order, where the key may
+// be a pattern (see path.Match). Thus patterns can't span multiple buckets.
+func Glob(ctx context.Context, location string) ([]Object, error) {
+    s, pattern, err := Resolve(location)
+    if err != nil {
+        return nil, err
+    }
+
+    if g, ok := s.(interface { Glob(string) ([]Object, error) }); ok {
+        return g.Glob(pattern)
+    }
+
+    // list the static prefix of the pattern, and filter the matching keys
+    prefix := pattern
+    if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+        prefix = pattern[:i]
+    }
+
+    objects, err := s.List(ctx, prefix)
+    if err != nil {
+        return nil, err
+    }
+
+    res := []Object{}
+    for _, obj := range objects {
+        ok, err := path.Match(pattern, obj.Key)
+        if err != nil {
+            return nil, err
+        } else if ok {
+            res = append(res, obj)
+        }
+    }
+    return res, nil
+}
+
+// Open returns a Reader of the object at the location. See Resolve
+func Open(ctx context.Context, location string) (Reader, error) {
+    s, key, err := Resolve(location)
+    if err != nil {
+        return nil, err
+    }
+    return s.Open(ctx, key)
+}
+
+// URL returns the location of the key within the same storage as the provided
+// location, for referencing the objects returned by Glob
+func URL(location, key string) string {
+    u, err := url.Parse(location)
+    if err != nil || u.Scheme == "" || len(u.Scheme) == 1 {
+        return key
+    }
+    return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/" + key}).String()
+}
+
+// local is the Storage of the local file system, where keys are paths
+type local struct {}
+
+func openLocal(string) (Storage, error) { return local{}, nil }
+
+func (local) List(ctx context.Context, prefix string) ([]Object, error) {
+    res := []Object{}
+    dir := filepath.Dir(prefix + "x")
+    err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+        if os.IsNotExist(err) {
+            return nil
+        } else if err != nil {
+            return err
+        } else if !info.IsDir() && strings.HasPrefix(p, prefix) {
+            res = append(res, Object{p, info.Size()})
+        }
+        return nil
+    })
+    return res, err
+}
+
+func (local) Glob(pattern string) ([]Object, error) {
+    paths, err := filepath.Glob(pattern)
+    if err != nil {
+        return nil, err
+    }
+
+    sort.Strings(paths)
+    res := []Object{}
+    for _, p := range paths {
+        info, err := os.Stat(p)
+        if err != nil {
+            return nil, err
+        } else if !info.IsDir() {
+            res = append(res, Object{p, info.Size()})
+        }
+    }
+    return res, nil
+}
+
+func (local) Open(ctx context.Context, key string) (Reader, error) {
+    f, err := os.Open(key)
+    if err != nil {
+        return nil, err
+    }
+
+    info, err := f.Stat()
+    if err != nil {
+        f.Close()
+        return nil, err
+    }
+    return &localReader{f, info.Size()}, nil
+}
+
+type localReader struct {
+    *os.File
+    size int64
+}
+
+func (r *localReader) Size() int64 { return r.size }
+
+// checkStatus returns an error for unsuccessful responses, and closes their
+// body
+func checkStatus(service, method, key string, resp *http.Response) error {
+    if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+        return nil
+    }
+
+    defer resp.Body.Close()
+    msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+    if len(msg) > 0 {
+        return fmt.Errorf("%s: %s %s: %s: %s", service, method, key, resp.Status, strings.TrimSpace(string(msg)))
+    }
+    return fmt.Errorf("%s: %s %s: %s", service, method, key, resp.Status)
+}
+
+// rangeBody returns the body of a range request from the offset, verifying
+// that the range was honored
+func rangeBody(resp *http.Response, offset int64) (io.ReadCloser, error) {
+    if resp.StatusCode != http.StatusPartialContent && offset > 0 {
+        resp.Body.Close()
+        return nil, fmt.Errorf("range request not supported: %s", resp.Status)
+    }
+    return resp.Body, nil
+}
+
+// rangeReader is a Reader over range requests, which are issued lazily from
+// the current offset, and restarted after seeking
+type rangeReader struct {
+    size int64
+    offset int64
+    body io.ReadCloser
+    open func(offset int64) (io.ReadCloser, error)
+}
+
+func (r *rangeReader) Size() int64 { return r.size }
+
+func (r *rangeReader) Read(b []byte) (int, error) {
+    if r.offset >= r.size {
+        return 0, io.EOF
+    }
+
+    if r.body == nil {
+        body, err := r.open(r.offset)
+        if err != nil {
+            return 0, err
+        }
+        r.body = body
+    }
+
+    n, err := r.body.Read(b)
+    r.offset += int64(n)
+    if err == io.EOF && r.offset < r.size {
+        err = io.ErrUnexpectedEOF
+    }
+    return n, err
+}
+
+func (r *rangeReader) Seek(offset int64, whence int) (int64, error) {
+    switch whence {
+    case io.SeekCurrent:
+        offset += r.offset
+    case io.SeekEnd:
+        offset += r.size
+    }
+
+    if offset < 0 {
+        return 0, fmt.Errorf("negative offset %d", offset)
+    } else if offset != r.offset {
+        r.Close()
+        r.offset = offset
+    }
+    return offset, nil
+}
+
+func (r *rangeReader) Close() error {
+    if r.body == nil {
+        return nil
+    }
+
+    err := r.body.Close()
+    r.body = nil
+    return err
+}
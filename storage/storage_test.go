@@ -0,0 +1,141 @@
+package storage
+
+import (
+    "io"
+    "os"
+    "fmt"
+    "bytes"
+    "context"
+    "strings"
+    "testing"
+    "path/filepath"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleResolve() {
+    _, key, err := Resolve("s3://bucket/data/a.csv")
+    fmt.Println(key, err)
+
+    _, key, err = Resolve("/data/a.csv")
+    fmt.Println(key, err)
+
+    _, _, err = Resolve("foo://bucket/a.csv")
+    fmt.Println(err)
+
+    // Output:
+    // data/a.csv <nil>
+    // /data/a.csv <nil>
+    // unknown storage scheme "foo"
+}
+
+func ExampleURL() {
+    fmt.Println(URL("gs://bucket/data/*.csv", "data/a.csv"))
+    fmt.Println(URL("/data/*.csv", "/data/a.csv"))
+
+    // Output:
+    // gs://bucket/data/a.csv
+    // /data/a.csv
+}
+
+func TestLocal(t *testing.T) {
+    dir := t.TempDir()
+    require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+    for _, name := range []string{"b.csv", "a.csv", "c.txt", "sub/d.csv"} {
+        err := os.WriteFile(filepath.Join(dir, name), []byte("0123456789"), 0644)
+        require.NoError(t, err)
+    }
+
+    ctx := context.Background()
+    objects, err := Glob(ctx, filepath.Join(dir, "*.csv"))
+    require.NoError(t, err)
+    require.Equal(t, []Object{{filepath.Join(dir, "a.csv"), 10}, {filepath.Join(dir, "b.csv"), 10}}, objects)
+
+    objects, err = Glob(ctx, "file://" + filepath.Join(dir, "sub", "*"))
+    require.NoError(t, err)
+    require.Equal(t, []Object{{filepath.Join(dir, "sub", "d.csv"), 10}}, objects)
+
+    objects, err = local{}.List(ctx, filepath.Join(dir, "s"))
+    require.NoError(t, err)
+    require.Equal(t, []Object{{filepath.Join(dir, "sub", "d.csv"), 10}}, objects)
+
+    r, err := Open(ctx, filepath.Join(dir, "a.csv"))
+    require.NoError(t, err)
+    defer r.Close()
+
+    _, err = r.Seek(4, io.SeekStart)
+    require.NoError(t, err)
+
+    b, err := io.ReadAll(r)
+    require.NoError(t, err)
+    require.Equal(t, "456789", string(b))
+    require.Equal(t, int64(10), r.Size())
+}
+
+// Test that range requests are issued lazily from the offset, and restarted
+// after seeking
+func TestRangeReader(t *testing.T) {
+    content := "0123456789"
+    requests := []int64{}
+    r := &rangeReader{size: int64(len(content)), open: func(offset int64) (io.ReadCloser, error) {
+        requests = append(requests, offset)
+        return io.NopCloser(strings.NewReader(content[offset:])), nil
+    }}
+
+    _, err := r.Seek(2, io.SeekStart)
+    require.NoError(t, err)
+
+    b := make([]byte, 3)
+    _, err = io.ReadFull(r, b)
+    require.NoError(t, err)
+    require.Equal(t, "234", string(b))
+
+    _, err = io.ReadFull(r, b)
+    require.NoError(t, err)
+    require.Equal(t, "567", string(b))
+
+    offset, err := r.Seek(-3, io.SeekEnd)
+    require.NoError(t, err)
+    require.Equal(t, int64(7), offset)
+
+    rest, err := io.ReadAll(r)
+    require.NoError(t, err)
+    require.Equal(t, "789", string(rest))
+    require.Equal(t, []int64{2, 7}, requests)
+
+    _, err = r.Seek(-1, io.SeekStart)
+    require.Error(t, err)
+}
+
+func TestRegister(t *testing.T) {
+    Register("mem", func(bucket string) (Storage, error) {
+        return memStorage{"a.csv": "a", "b.txt": "b"}, nil
+    })
+
+    objects, err := Glob(context.Background(), "mem://x/*.csv")
+    require.NoError(t, err)
+    require.Equal(t, []Object{{"a.csv", 1}}, objects)
+
+    Register("mem", nil)
+    _, err = Glob(context.Background(), "mem://x/*.csv")
+    require.EqualError(t, err, `unknown storage scheme "mem"`)
+}
+
+// memStorage is an in-memory Storage of keys to contents
+type memStorage map[string]string
+
+func (s memStorage) List(ctx context.Context, prefix string) ([]Object, error) {
+    res := []Object{}
+    for k, v := range s {
+        if strings.HasPrefix(k, prefix) {
+            res = append(res, Object{k, int64(len(v))})
+        }
+    }
+    return res, nil
+}
+
+func (s memStorage) Open(ctx context.Context, key string) (Reader, error) {
+    return memReader{bytes.NewReader([]byte(s[key]))}, nil
+}
+
+type memReader struct { *bytes.Reader }
+func (memReader) Close() error { return nil }
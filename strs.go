@@ -0,0 +1,39 @@
+package ep
+
+import (
+    "strconv"
+)
+
+var _ = registerGob(Str, Strs{})
+
+// Str is a built-in Type representing string values. Use Str.Data(n) to
+// create Data instances of `n` empty strings
+var Str = &StrType{}
+
+// Varchar returns a Type of strings of up to `length` characters, like SQL's
+// varchar(length). Its data is Strs, and the length isn't enforced; it's
+// metadata for planning (see TypeMeta).
+func Varchar(length int) Type {
+    return &StrType{length}
+}
+
+// StrType is the Type of Strs, with an optional maximum length (see Varchar)
+type StrType struct { Length int }
+func (t *StrType) Name() string {
+    if t.Length > 0 {
+        return "varchar(" + strconv.Itoa(t.Length) + ")"
+    }
+    return "string"
+}
+
+func (*StrType) Data(n uint) Data { return make(Strs, n) }
+
+// Strs is a built-in Data implementation of string values
+type Strs []string
+func (Strs) Type() Type { return Str }
+func (vs Strs) Len() int { return len(vs) }
+func (vs Strs) Less(i, j int) bool { return vs[i] < vs[j] }
+func (vs Strs) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs Strs) Slice(s, e int) Data { return vs[s:e] }
+func (vs Strs) Strings() []string { return vs }
+func (vs Strs) Append(o Data) Data { return append(vs, o.(Strs)...) }
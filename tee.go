@@ -0,0 +1,79 @@
+package ep
+
+import (
+    "sync"
+    "context"
+)
+
+var _ = registerGob(&tee{})
+
+// Tee returns a Runner that passes its input through unchanged, while also
+// dispatching it to all of the consumer runners concurrently, for side outputs
+// like audit logs or sinks. The outputs of the consumers are discarded. An
+// error in any of the consumers cancels the rest of them, and fails the Tee.
+// In order to merge the outputs of several runners instead, see Union.
+//
+// NOTE: every consumer receives its own copy of the datasets, as the emitted
+// datasets may be modified in-place downstream (for example, by appending to
+// them).
+func Tee(consumers ...Runner) Runner {
+    return &tee{consumers}
+}
+
+type tee struct { Consumers []Runner }
+
+func (*tee) Returns() []Type { return []Type{Wildcard} }
+func (r *tee) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    // cancel all consumers upon the first error
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
+
+    var l sync.Mutex
+    var wg sync.WaitGroup
+    inputs := make([]chan Dataset, len(r.Consumers))
+    for i := range r.Consumers {
+        inputs[i] = newChan(ctx)
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            discard := make(chan Dataset)
+            go func() { for _ = range discard {} }()
+
+            err1 := runSafe(ctx, r.Consumers[i], inputs[i], discard)
+            close(discard)
+            if err1 != nil {
+                l.Lock()
+                if err == nil {
+                    err = err1
+                }
+                l.Unlock()
+                cancel()
+            }
+
+            // drain the input in case the consumer has exited early, otherwise
+            // forking the input to the rest of the consumers would block
+            for _ = range inputs[i] {}
+        }(i)
+    }
+
+    for data := range inp {
+        if ctx.Err() != nil {
+            break // one of the consumers has failed
+        }
+
+        for _, s := range inputs {
+            s <- Clone(data).(Dataset)
+        }
+        out <- data
+    }
+
+    for _, s := range inputs {
+        close(s)
+    }
+
+    wg.Wait()
+    if err == nil {
+        err = ctx.Err()
+    }
+    return err
+}
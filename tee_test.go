@@ -0,0 +1,36 @@
+package ep
+
+import (
+    "fmt"
+    "sync"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestTee(t *testing.T) {
+    var l sync.Mutex
+    seen := []string{}
+    audit := Map([]Type{Str}, func(data Dataset) (Dataset, error) {
+        l.Lock()
+        defer l.Unlock()
+        seen = append(seen, data.At(0).Strings()...)
+        return data, nil
+    })
+
+    runner := Tee(audit, PassThrough())
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}), NewDataset(Strs{"c"}))
+    require.NoError(t, err)
+    require.Equal(t, "[[a b c]]", fmt.Sprintf("%v", data))
+    require.Equal(t, []string{"a", "b", "c"}, seen)
+}
+
+func TestTeeErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    infinity := &InfinityRunner{}
+    runner := Tee(infinity, &ErrRunner{err})
+    _, err = testRun(runner, NewDataset(Strs{"a"}))
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
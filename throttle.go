@@ -0,0 +1,67 @@
+package ep
+
+import (
+    "time"
+    "context"
+)
+
+var _ = registerGob(&throttle{})
+
+// Throttle returns a Runner that passes its input through, while limiting its
+// throughput to the provided number of rows per second on average. It's
+// intended for pipelines that write into rate-limited sinks. Batches are never
+// split, thus a single batch larger than the rate is emitted at once, and the
+// following batches are delayed accordingly. When distributed, the limit
+// applies to every node separately.
+func Throttle(rowsPerSec float64) Runner {
+    return &throttle{rowsPerSec, false}
+}
+
+// ThrottleBytes is like Throttle, except that the throughput is measured in
+// bytes per second. The size of every batch is estimated by the total length of
+// the string representations of its values.
+func ThrottleBytes(bytesPerSec float64) Runner {
+    return &throttle{bytesPerSec, true}
+}
+
+type throttle struct {
+    Rate float64
+    Bytes bool
+}
+
+func (*throttle) Returns() []Type { return []Type{Wildcard} }
+func (r *throttle) Run(ctx context.Context, inp, out chan Dataset) error {
+    start := time.Now()
+    var total float64
+    for data := range inp {
+        // wait until the average rate, including this batch, is within the
+        // limit
+        total += r.size(data)
+        due := start.Add(time.Duration(total / r.Rate * float64(time.Second)))
+        if wait := due.Sub(time.Now()); wait > 0 {
+            select {
+            case <- time.After(wait):
+            case <- ctx.Done():
+                return ctx.Err()
+            }
+        }
+
+        out <- data
+    }
+    return nil
+}
+
+// size of the dataset, in the throttled units
+func (r *throttle) size(data Dataset) float64 {
+    if !r.Bytes {
+        return float64(data.Len())
+    }
+
+    var n int
+    for i := 0; i < data.Width(); i++ {
+        for _, s := range data.At(i).Strings() {
+            n += len(s)
+        }
+    }
+    return float64(n)
+}
@@ -0,0 +1,42 @@
+package ep
+
+import (
+    "time"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func TestThrottle(t *testing.T) {
+    inputs := []Dataset{}
+    for i := 0; i < 5; i++ {
+        inputs = append(inputs, NewDataset(Strs{"a", "b"}))
+    }
+
+    start := time.Now()
+    data, err := testRun(Throttle(200), inputs...)
+    require.NoError(t, err)
+    require.Equal(t, 10, data.Len())
+
+    // 10 rows at 200 rows per second
+    require.True(t, time.Since(start) >= 50 * time.Millisecond, "not throttled")
+}
+
+func TestThrottleBytes(t *testing.T) {
+    inputs := []Dataset{}
+    for i := 0; i < 5; i++ {
+        inputs = append(inputs, NewDataset(Strs{"hello", "world"}))
+    }
+
+    start := time.Now()
+    _, err := testRun(ThrottleBytes(1000), inputs...)
+    require.NoError(t, err)
+
+    // 50 bytes at 1000 bytes per second
+    require.True(t, time.Since(start) >= 50 * time.Millisecond, "not throttled")
+}
+
+func TestThrottleCancel(t *testing.T) {
+    runner := Pipeline(&InfinityRunner{}, Throttle(1))
+    _, err := testRun(Timeout(runner, 20 * time.Millisecond))
+    require.Error(t, err)
+}
@@ -0,0 +1,129 @@
+package ep
+
+import (
+    "time"
+)
+
+var _ = registerGob(Time, Times{}, Interval, Intervals{})
+
+// Time is a built-in Type representing instants in time, with nanosecond
+// precision. Use Time.Data(n) to create Data instances of `n` Unix epochs
+var Time = &TimeType{}
+
+// Interval is a built-in Type representing durations of time, with nanosecond
+// precision. Use Interval.Data(n) to create Data instances of `n` zero
+// durations
+var Interval = &IntervalType{}
+
+// TimeType is the Type of Times
+type TimeType struct {}
+func (*TimeType) Name() string { return "timestamp" }
+func (*TimeType) Data(n uint) Data { return make(Times, n) }
+
+// IntervalType is the Type of Intervals
+type IntervalType struct {}
+func (*IntervalType) Name() string { return "interval" }
+func (*IntervalType) Data(n uint) Data { return make(Intervals, n) }
+
+// Times is a built-in Data implementation of instants in time, stored as the
+// number of nanoseconds since the Unix epoch. Thus, the values are independent
+// of time zones, and are compared chronologically. Their string representation
+// is in RFC3339 format in UTC. Use In() for the values in other time zones.
+type Times []int64
+
+// NewTimes returns the Times of the provided time values, in any time zone
+func NewTimes(ts ...time.Time) Times {
+    res := make(Times, len(ts))
+    for i, t := range ts {
+        res[i] = t.UnixNano()
+    }
+    return res
+}
+
+// ParseTimes parses the strings with the layout (see time.Parse), where values
+// without an explicit time zone are in the provided location
+func ParseTimes(layout string, loc *time.Location, strs ...string) (Times, error) {
+    res := make(Times, len(strs))
+    for i, s := range strs {
+        t, err := time.ParseInLocation(layout, s, loc)
+        if err != nil {
+            return nil, err
+        }
+        res[i] = t.UnixNano()
+    }
+    return res, nil
+}
+
+func (Times) Type() Type { return Time }
+func (vs Times) Len() int { return len(vs) }
+func (vs Times) Less(i, j int) bool { return vs[i] < vs[j] }
+func (vs Times) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs Times) Slice(s, e int) Data { return vs[s:e] }
+func (vs Times) Append(o Data) Data { return append(vs, o.(Times)...) }
+func (vs Times) Strings() []string {
+    strs := make([]string, len(vs))
+    for i := range vs {
+        strs[i] = vs.At(i).Format(time.RFC3339Nano)
+    }
+    return strs
+}
+
+// At returns the time value at index i, in UTC
+func (vs Times) At(i int) time.Time {
+    return time.Unix(0, vs[i]).UTC()
+}
+
+// In returns the time values in the provided location
+func (vs Times) In(loc *time.Location) []time.Time {
+    res := make([]time.Time, len(vs))
+    for i := range vs {
+        res[i] = vs.At(i).In(loc)
+    }
+    return res
+}
+
+// Truncate returns new Times of the values rounded down to a multiple of d
+// since the Unix epoch (in UTC), for bucketing by time. See time.Truncate
+func (vs Times) Truncate(d time.Duration) Times {
+    res := make(Times, len(vs))
+    for i := range vs {
+        res[i] = vs.At(i).Truncate(d).UnixNano()
+    }
+    return res
+}
+
+// Add returns new Times of the values shifted by the intervals, row by row
+func (vs Times) Add(intervals Intervals) Times {
+    res := make(Times, len(vs))
+    for i, v := range vs {
+        res[i] = v + int64(intervals[i])
+    }
+    return res
+}
+
+// Sub returns the Intervals between the values and the other values, row by
+// row
+func (vs Times) Sub(other Times) Intervals {
+    res := make(Intervals, len(vs))
+    for i, v := range vs {
+        res[i] = time.Duration(v - other[i])
+    }
+    return res
+}
+
+// Intervals is a built-in Data implementation of durations of time. Their
+// string representation is the one of time.Duration
+type Intervals []time.Duration
+func (Intervals) Type() Type { return Interval }
+func (vs Intervals) Len() int { return len(vs) }
+func (vs Intervals) Less(i, j int) bool { return vs[i] < vs[j] }
+func (vs Intervals) Swap(i, j int) { vs[i], vs[j] = vs[j], vs[i] }
+func (vs Intervals) Slice(s, e int) Data { return vs[s:e] }
+func (vs Intervals) Append(o Data) Data { return append(vs, o.(Intervals)...) }
+func (vs Intervals) Strings() []string {
+    strs := make([]string, len(vs))
+    for i, v := range vs {
+        strs[i] = v.String()
+    }
+    return strs
+}
@@ -0,0 +1,63 @@
+package ep
+
+import (
+    "fmt"
+    "sort"
+    "time"
+    "bytes"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleTimes() {
+    ny, _ := time.LoadLocation("America/New_York")
+    times, err := ParseTimes("2006-01-02 15:04", ny, "2017-03-01 12:30", "2017-03-01 09:15")
+    sort.Sort(times)
+    fmt.Println(times.Strings(), err)
+    fmt.Println(times.In(ny)[0].Format(time.Kitchen))
+
+    // Output:
+    // [2017-03-01T14:15:00Z 2017-03-01T17:30:00Z] <nil>
+    // 9:15AM
+}
+
+func ExampleTimes_Truncate() {
+    times := NewTimes(time.Date(2017, 3, 1, 14, 15, 10, 0, time.UTC))
+    fmt.Println(times.Truncate(time.Hour).Strings())
+
+    // Output: [2017-03-01T14:00:00Z]
+}
+
+func ExampleIntervals() {
+    start := NewTimes(time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC))
+    end := start.Add(Intervals{90 * time.Minute})
+    fmt.Println(end.Strings(), end.Sub(start))
+
+    // Output: [2017-03-01T01:30:00Z] [1h30m0s]
+}
+
+// times are grouped and sorted natively
+func TestTimesGroupBy(t *testing.T) {
+    base := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+    times := NewTimes(base.Add(time.Hour + time.Minute), base, base.Add(time.Minute), base.Add(time.Hour))
+    data := NewDataset(times.Truncate(time.Hour), times)
+
+    runner := Pipeline(GroupBy([]int{0}, Count(), Min(1)), Sort([]SortKey{{Col: 0}}))
+    res, err := testRun(runner, data)
+    require.NoError(t, err)
+    require.Equal(t, []string{"2017-03-01T00:00:00Z", "2017-03-01T01:00:00Z"}, res.At(0).Strings())
+    require.Equal(t, Ints{2, 2}, res.At(1))
+    require.Equal(t, NewTimes(base, base.Add(time.Hour)), res.At(2))
+}
+
+// times and intervals are serializable, in order to be distributed
+func TestTimesGob(t *testing.T) {
+    var data Data = NewDataset(NewTimes(time.Now()), Intervals{time.Second})
+    var buf bytes.Buffer
+    require.NoError(t, gob.NewEncoder(&buf).Encode(&data))
+
+    var decoded Data
+    require.NoError(t, gob.NewDecoder(&buf).Decode(&decoded))
+    require.Equal(t, data, decoded)
+}
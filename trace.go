@@ -0,0 +1,60 @@
+package ep
+
+import (
+    "context"
+)
+
+// Tracing is the Tracer of distributed runs, or nil to disable tracing. When
+// set, spans are started for every Distribute, for the runner execution on
+// every node, and for every exchange. The trace context is propagated to the
+// peers along with the distributed runner, thus a single distributed run is
+// traced as a single trace across all nodes. It must be set on all nodes
+// before they start running.
+var Tracing Tracer
+
+// Tracer starts the tracing spans of ep. It mirrors the subset of the
+// OpenTelemetry API used by ep, thus an OpenTelemetry trace.Tracer and
+// propagation.TextMapPropagator can be adapted to it with a few lines:
+//
+//      func (t *otelTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, ep.Span) {
+//          ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(kvs(attrs)...))
+//          return ctx, &otelSpan{span}
+//      }
+//
+//      func (t *otelTracer) Inject(ctx context.Context, carrier map[string]string) {
+//          t.propagator.Inject(ctx, propagation.MapCarrier(carrier))
+//      }
+//
+type Tracer interface {
+
+    // Start starts a span as a child of the span in the context, if any, and
+    // returns a context with the new span
+    Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span)
+
+    // Inject the trace context of the span in the context into the carrier
+    Inject(ctx context.Context, carrier map[string]string)
+
+    // Extract returns a context with the trace context of the carrier, such
+    // that new spans are started as its children
+    Extract(ctx context.Context, carrier map[string]string) context.Context
+}
+
+// Span is a single traced operation
+type Span interface {
+
+    // End ends the span, with the error of the operation if it has failed,
+    // or nil otherwise
+    End(err error)
+}
+
+// startSpan starts a span with the Tracing Tracer, if any, and returns the
+// function that ends it
+func startSpan(ctx context.Context, name string, attrs map[string]string) (context.Context, func(error)) {
+    t := Tracing
+    if t == nil {
+        return ctx, func(error) {}
+    }
+
+    ctx, span := t.Start(ctx, name, attrs)
+    return ctx, span.End
+}
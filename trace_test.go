@@ -0,0 +1,119 @@
+package ep
+
+import (
+    "fmt"
+    "net"
+    "sync"
+    "time"
+    "strings"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// testTracer records the ended spans, propagating the trace and parent span
+// ids by a "traceparent" key
+type testTracer struct {
+    sync.Mutex
+    spans []*testSpan
+    ids int
+}
+
+type testSpan struct {
+    t *testTracer
+    Name string
+    Attrs map[string]string
+    Trace, ID, Parent string
+    Err error
+}
+
+type spanKey struct {}
+
+func (t *testTracer) Start(ctx context.Context, name string, attrs map[string]string) (context.Context, Span) {
+    t.Lock()
+    defer t.Unlock()
+    t.ids++
+
+    span := &testSpan{t: t, Name: name, Attrs: attrs, ID: fmt.Sprint(t.ids)}
+    span.Trace = span.ID
+    if parent, ok := ctx.Value(spanKey{}).(*testSpan); ok {
+        span.Trace, span.Parent = parent.Trace, parent.ID
+    }
+    return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *testTracer) Inject(ctx context.Context, carrier map[string]string) {
+    if span, ok := ctx.Value(spanKey{}).(*testSpan); ok {
+        carrier["traceparent"] = span.Trace + "-" + span.ID
+    }
+}
+
+func (t *testTracer) Extract(ctx context.Context, carrier map[string]string) context.Context {
+    ids := strings.SplitN(carrier["traceparent"], "-", 2)
+    if len(ids) != 2 {
+        return ctx
+    }
+    return context.WithValue(ctx, spanKey{}, &testSpan{Trace: ids[0], ID: ids[1]})
+}
+
+func (s *testSpan) End(err error) {
+    s.t.Lock()
+    defer s.t.Unlock()
+    s.Err = err
+    s.t.spans = append(s.t.spans, s)
+}
+
+func (t *testTracer) Spans() []*testSpan {
+    t.Lock()
+    defer t.Unlock()
+    return append([]*testSpan{}, t.spans...)
+}
+
+// Test that a distributed run is traced as a single trace across all nodes
+func TestTracing(t *testing.T) {
+    tracer := &testTracer{}
+    Tracing = tracer
+    defer func() { Tracing = nil }()
+
+    dists := []Distributer{}
+    addrs := []string{}
+    for i := 0; i < 2; i++ {
+        ln, err := net.Listen("tcp", "127.0.0.1:0")
+        require.NoError(t, err)
+
+        dist := NewDistributer(ln.Addr().String(), ln)
+        go dist.Start()
+        defer dist.Close()
+
+        dists = append(dists, dist)
+        addrs = append(addrs, dist.Addr())
+    }
+
+    runner := dists[0].Distribute(Pipeline(Scatter(), Gather()), addrs...)
+    data, err := testRun(runner, NewDataset(Strs{"a", "b"}))
+    require.NoError(t, err)
+    require.Equal(t, 2, data.Len())
+
+    // distribute, run on both nodes, and 2 exchanges on both nodes
+    require.Eventually(t, func() bool {
+        return len(tracer.Spans()) == 7
+    }, time.Second, time.Millisecond)
+
+    spans := tracer.Spans()
+    byName := map[string][]*testSpan{}
+    for _, span := range spans {
+        require.Equal(t, spans[0].Trace, span.Trace, span.Name)
+        require.NoError(t, span.Err)
+        byName[span.Name] = append(byName[span.Name], span)
+    }
+
+    require.Equal(t, 1, len(byName["ep.Distribute"]))
+    require.Equal(t, 2, len(byName["ep.Run"]))
+    require.Equal(t, 4, len(byName["ep.Exchange"]))
+
+    dist := byName["ep.Distribute"][0]
+    require.Equal(t, "", dist.Parent)
+    for _, span := range byName["ep.Run"] {
+        require.Equal(t, dist.ID, span.Parent)
+    }
+}
@@ -1,5 +1,9 @@
 package ep
 
+import (
+    "fmt"
+)
+
 // Wildcard is a pseduo-type used to denote types that are dependent on their
 // input type. For example, a function returning [Wildcard, Int] effectively
 // returns its input followed by an int column. It should never be used in the
@@ -14,7 +18,9 @@ var Wildcard = &wildcardType{}
 // may be able to support Any as input.
 var Any = &anyType{}
 
-var _ = registerGob(asType{}, Wildcard, Any)
+// named types are registered as pointers, in order for their names to survive
+// the transmission of the types between nodes (see As)
+var _ = registerGob(&asType{}, Wildcard, Any, &wildcardExcept{}, &wildcardAt{})
 
 // Type is an interface that represnts specific data types
 type Type interface {
@@ -29,6 +35,86 @@ type wildcardType struct {}
 func (*wildcardType) Name() string { return "*" }
 func (*wildcardType) Data(uint) Data { panic("wildcard has no concrete data") }
 
+// WildcardExcept returns a pseudo-type like Wildcard, that denotes all of the
+// input types except the ones at the provided indices. For example, a function
+// returning [WildcardExcept(0)] returns all of its input columns except the
+// first, like the non-key columns of a join. See Drop
+func WildcardExcept(cols ...int) Type {
+    return &wildcardExcept{cols}
+}
+
+// WildcardAt returns a pseudo-type that denotes the type of the input column at
+// index i, for functions that return some of their input columns (see Pick).
+// Until it's resolved from the input types (see Pipeline), it's an unknown
+// type, like Any.
+func WildcardAt(i int) Type {
+    return &wildcardAt{i}
+}
+
+type wildcardExcept struct { Cols []int }
+func (t *wildcardExcept) Name() string { return fmt.Sprintf("*-%v", t.Cols) }
+func (*wildcardExcept) Data(uint) Data { panic("wildcard has no concrete data") }
+
+type wildcardAt struct { Index int }
+func (t *wildcardAt) Name() string { return fmt.Sprintf("*[%d]", t.Index) }
+func (*wildcardAt) Data(uint) Data { panic("wildcard has no concrete data") }
+
+// isWildcard returns true for the pseudo-types of an unknown number of input
+// types (Wildcard and WildcardExcept)
+func isWildcard(t Type) bool {
+    _, ok := t.(*wildcardExcept)
+    return ok || t == Wildcard
+}
+
+// isUnknown returns true for the pseudo-types of a single unknown type (Any and
+// WildcardAt)
+func isUnknown(t Type) bool {
+    _, ok := t.(*wildcardAt)
+    return ok || t == Any
+}
+
+// resolveWildcards replaces the wildcards in the types with the input types
+// they denote. Wildcards that can't be resolved, because the input types
+// themselves contain wildcards, are kept as-is.
+func resolveWildcards(types, inp []Type) []Type {
+    res := []Type{}
+    for _, t := range types {
+        switch w := t.(type) {
+        case *wildcardExcept:
+            if hasWildcard(inp) {
+                res = append(res, t)
+                continue
+            }
+
+            excluded := map[int]bool{}
+            for _, col := range w.Cols {
+                excluded[col] = true
+            }
+
+            for i, t := range inp {
+                if !excluded[i] {
+                    res = append(res, t)
+                }
+            }
+        case *wildcardAt:
+            if hasWildcard(inp) {
+                res = append(res, t) // positions are unknown
+            } else if w.Index < 0 || w.Index >= len(inp) {
+                res = append(res, Any) // invalid, see Validate
+            } else {
+                res = append(res, inp[w.Index])
+            }
+        default:
+            if t == Wildcard {
+                res = append(res, inp...)
+            } else {
+                res = append(res, t)
+            }
+        }
+    }
+    return res
+}
+
 type anyType struct {}
 func (*anyType) Name() string { return "?" }
 func (*anyType) Data(uint) Data { panic("any has no concrete data") }
@@ -0,0 +1,101 @@
+package ep
+
+// TypeMeta is optionally implemented by Types that carry metadata beyond their
+// name, like parametrized types (e.g. decimal(10,2) or varchar(255)). Use
+// Meta() in order to get the metadata of any Type.
+type TypeMeta interface {
+
+    // BaseName returns the name of the type without its parameters, e.g.
+    // "decimal" for decimal(10,2)
+    BaseName() string
+
+    // Parameters of the type, e.g. [10 2] for decimal(10,2), or nil
+    Parameters() []int
+
+    // Size returns the width of a single value in bytes, or -1 if the values
+    // are of variable width
+    Size() int
+}
+
+// Meta returns the metadata of the type. Types that don't implement TypeMeta
+// are variable width types with no parameters, whose base name is their name.
+// Types named with As() and Nullable types have the metadata of their
+// underlying type.
+func Meta(t Type) TypeMeta {
+    switch t := t.(type) {
+    case *asType:
+        return Meta(t.Type)
+    case *NullableType:
+        return Meta(t.Of)
+    case TypeMeta:
+        return t
+    }
+    return &typeMeta{t.Name(), nil, -1}
+}
+
+// Equal returns true if both types are the same, including their parameters,
+// regardless of their names assigned with As()
+func Equal(a, b Type) bool {
+    return unnamed(a).Name() == unnamed(b).Name()
+}
+
+// Compatible returns true if values of both types can be used interchangeably,
+// possibly with an implicit conversion between their parameters (e.g.
+// decimal(10,2) and decimal(12,4)). Any, WildcardAt and Null types are
+// compatible with all types, and Wildcards are compatible only with the same
+// Wildcards. It's used for plan validation (see Validate and Union).
+func Compatible(a, b Type) bool {
+    a, b = unnamed(a), unnamed(b)
+    if isWildcard(a) || isWildcard(b) {
+        return a.Name() == b.Name()
+    } else if isUnknown(a) || isUnknown(b) || Null.Is(a) || Null.Is(b) {
+        return true
+    }
+    return Meta(a).BaseName() == Meta(b).BaseName()
+}
+
+// unnamed returns the type without the name assigned by As()
+func unnamed(t Type) Type {
+    for {
+        as, ok := t.(*asType)
+        if !ok {
+            return t
+        }
+        t = as.Type
+    }
+}
+
+type typeMeta struct {
+    Base string
+    Params []int
+    Width int
+}
+
+func (m *typeMeta) BaseName() string { return m.Base }
+func (m *typeMeta) Parameters() []int { return m.Params }
+func (m *typeMeta) Size() int { return m.Width }
+
+func (*BoolType) BaseName() string { return "bool" }
+func (*BoolType) Parameters() []int { return nil }
+func (*BoolType) Size() int { return 1 }
+
+func (*TimeType) BaseName() string { return "timestamp" }
+func (*TimeType) Parameters() []int { return nil }
+func (*TimeType) Size() int { return 8 }
+
+func (*IntervalType) BaseName() string { return "interval" }
+func (*IntervalType) Parameters() []int { return nil }
+func (*IntervalType) Size() int { return 8 }
+
+func (*DecimalType) BaseName() string { return "decimal" }
+func (t *DecimalType) Parameters() []int { return []int{t.Precision, t.Scale} }
+func (*DecimalType) Size() int { return 8 }
+
+func (*StrType) BaseName() string { return "string" }
+func (*StrType) Size() int { return -1 }
+func (t *StrType) Parameters() []int {
+    if t.Length > 0 {
+        return []int{t.Length}
+    }
+    return nil
+}
@@ -0,0 +1,48 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleMeta() {
+    for _, t := range []Type{Decimal(10, 2), Varchar(255), Str, Int, As(UUID, "id")} {
+        m := Meta(t)
+        fmt.Println(t.Name(), m.BaseName(), m.Parameters(), m.Size())
+    }
+
+    // Output:
+    // decimal(10,2) decimal [10 2] 8
+    // varchar(255) string [255] -1
+    // string string [] -1
+    // int int [] 8
+    // uuid uuid [] 16
+}
+
+func TestTypesEqualCompatible(t *testing.T) {
+    require.True(t, Equal(As(Decimal(10, 2), "price"), Decimal(10, 2)))
+    require.False(t, Equal(Decimal(10, 2), Decimal(12, 4)))
+    require.True(t, Compatible(Decimal(10, 2), Decimal(12, 4)))
+    require.True(t, Compatible(Varchar(10), Str))
+    require.True(t, Compatible(Nullable(Ints{}, Bools{}).Type(), Int))
+    require.True(t, Compatible(Any, Int))
+    require.True(t, Compatible(Null, Str))
+    require.False(t, Compatible(Int, Str))
+    require.False(t, Compatible(Wildcard, Str))
+    require.True(t, Compatible(Wildcard, Wildcard))
+
+    m := Meta(JSON)
+    require.Equal(t, "json", m.BaseName())
+    require.Nil(t, m.Parameters())
+    require.Equal(t, -1, m.Size())
+}
+
+// validation accepts compatible arguments
+func TestValidateCompatible(t *testing.T) {
+    require.NoError(t, Validate(&strArgs{}, Varchar(20), Str))
+    require.Error(t, Validate(&strArgs{}, Int, Str))
+
+    _, err := Union(&constRunner{NewDataset(Strs{"a"})}, Map([]Type{Varchar(5)}, nil))
+    require.NoError(t, err)
+}
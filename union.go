@@ -2,6 +2,7 @@ package ep
 
 import (
     "fmt"
+    "sync"
     "context"
 )
 
@@ -22,8 +23,9 @@ func Union(runners ...Runner) (Runner, error) {
     // determine the return types - skipping NULLS as they don't expose any
     // information about the actual data types.
 
-    // ensure that the return types are compatible
-    types := runners[0].Returns()
+    // ensure that the return types are compatible. Copy the types, as they're
+    // modified in-place below.
+    types := append([]Type{}, runners[0].Returns()...)
     for _, r := range runners {
         have := r.Returns()
         if len(have) != len(types) {
@@ -35,7 +37,7 @@ func Union(runners ...Runner) (Runner, error) {
             // choose the first column type that isn't a null
             if Null.Is(types[i]) {
                 types[i] = have[i]
-            } else if !Null.Is(t) && t.Name() != types[i].Name() {
+            } else if !Compatible(t, types[i]) {
                 return nil, fmt.Errorf("type mismatch %v and %v", types, have)
             }
         }
@@ -55,43 +57,71 @@ func (r *union) Returns() []Type {
 }
 
 func (r *union) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    // cancel all inner runners upon the first error
+    ctx, cancel := context.WithCancel(ctx)
+    defer cancel()
 
     // start all inner runners
+    var l sync.Mutex
     inputs := make([]chan Dataset, len(r.Runners))
     outputs := make([]chan Dataset, len(r.Runners))
+    done := make([]chan struct{}, len(r.Runners))
     for i := range r.Runners {
-        inputs[i] = make(chan Dataset)
-        outputs[i] = make(chan Dataset)
+        inputs[i] = newChan(ctx)
+        outputs[i] = newChan(ctx)
+        done[i] = make(chan struct{})
 
         go func(i int) {
+            defer close(done[i])
             defer close(outputs[i])
-            err1 := r.Runners[i].Run(ctx, inputs[i], outputs[i])
-            if err1 != nil && err == nil {
-                err = err1
+            err1 := runSafe(ctx, r.Runners[i], inputs[i], outputs[i])
+            if err1 != nil {
+                l.Lock()
+                if err == nil {
+                    err = err1
+                }
+                l.Unlock()
+                cancel()
             }
         }(i)
     }
 
-    // fork the input to all inner runners
+    // fork the input to all inner runners, skipping the ones that have
+    // exited early
     go func() {
-        for data := range inp {
+        defer func() {
             for _, s := range inputs {
-                s <- data
+                close(s)
             }
-        }
+        }()
 
-        // close all inner runners
-        for _, s := range inputs {
-            close(s)
+        for data := range inp {
+            for i := range inputs {
+                select {
+                case inputs[i] <- data:
+                case <- done[i]:
+                case <- ctx.Done():
+                    return
+                }
+            }
         }
     }()
 
-    // collect and union all of the stream into a single output
+    // collect and union all of the streams into a single output, concurrently,
+    // as every runner might block until its output is consumed
+    var wg sync.WaitGroup
     for _, s := range outputs {
-        for data := range s {
-            out <- data
-        }
+        wg.Add(1)
+        go func(s chan Dataset) {
+            defer wg.Done()
+            for data := range s {
+                out <- data
+            }
+        }(s)
     }
+    wg.Wait()
 
+    l.Lock()
+    defer l.Unlock()
     return err
 }
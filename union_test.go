@@ -2,14 +2,59 @@ package ep
 
 import (
     "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
 )
 
 func ExampleUnion() {
     runner, _ := Union(&Upper{}, &Question{})
     data := NewDataset(Strs([]string{"hello", "world"}))
     data, err := testRun(runner, data)
-    fmt.Println(data, err)
+
+    // the outputs of the runners are collected concurrently
+    fmt.Println(sortedStrings(data.At(0).Strings()), err)
 
     // Output:
-    // [[HELLO WORLD is hello? is world?]] <nil>
+    // [HELLO WORLD is hello? is world?] <nil>
+}
+
+func TestUnionMismatch(t *testing.T) {
+    _, err := Union(&Upper{}, &nodeAddr{})
+    require.Error(t, err)
+
+    // nulls are compatible with any type
+    types := []Type{Null}
+    runner, err := Union(&fixedReturns{types}, &Upper{})
+    require.NoError(t, err)
+    require.Equal(t, []Type{Str}, runner.Returns())
+    require.Equal(t, []Type{Null}, types)
+}
+
+// union error should cancel all inner runners
+func TestUnionErr(t *testing.T) {
+    err := fmt.Errorf("something bad happened")
+    infinity := &InfinityRunner{}
+    runner := &union{[]Type{Str}, []Runner{infinity, &ErrRunner{err}}}
+    data := NewDataset(Null.Data(1))
+    data, err = testRun(runner, data)
+
+    require.Error(t, err)
+    require.Equal(t, "something bad happened", err.Error())
+    require.Equal(t, false, infinity.Running, "Infinity go-routine leak")
+}
+
+// Tests that the outputs are collected concurrently, as every runner blocks
+// until its output is consumed
+func TestUnionBatches(t *testing.T) {
+    runner, err := Union(PassThrough(), PassThrough())
+    require.NoError(t, err)
+
+    inp := []Dataset{}
+    for i := 0; i < 3; i++ {
+        inp = append(inp, NewDataset(Strs{fmt.Sprint(i)}))
+    }
+
+    data, err := testRun(runner, inp...)
+    require.NoError(t, err)
+    require.Equal(t, []string{"0", "0", "1", "1", "2", "2"}, sortedStrings(data.At(0).Strings()))
 }
@@ -0,0 +1,37 @@
+package ep
+
+import (
+    "bytes"
+    "github.com/satori/go.uuid"
+)
+
+// UUID is a built-in Type representing 16-byte UUID values. Use UUID.Data(n) to
+// create Data instances of `n` nil UUIDs
+var UUID = NewType(TypeOps[uuid.UUID]{
+    Name: "uuid",
+    Size: 16,
+    Less: func(a, b uuid.UUID) bool { return bytes.Compare(a[:], b[:]) < 0 },
+    String: func(v uuid.UUID) string { return v.String() },
+})
+
+// UUIDType is the Type of UUIDs
+type UUIDType = ColumnType[uuid.UUID]
+
+// UUIDs is a built-in Data implementation of fixed-width UUID values, ordered by
+// their bytes. Their string representation is the canonical one, thus they're
+// interchangeable with Strs of canonical UUIDs, with a fraction of the memory.
+type UUIDs = Column[uuid.UUID]
+
+// ParseUUIDs parses the UUID strings, in any of the formats supported by
+// uuid.FromString
+func ParseUUIDs(strs ...string) (UUIDs, error) {
+    res := make(UUIDs, len(strs))
+    for i, s := range strs {
+        var err error
+        res[i], err = uuid.FromString(s)
+        if err != nil {
+            return nil, err
+        }
+    }
+    return res, nil
+}
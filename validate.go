@@ -0,0 +1,195 @@
+package ep
+
+import (
+    "fmt"
+)
+
+// Validate statically type-checks a composed plan, given the types of its
+// input (or no types if the input is unknown). It walks the composite runners
+// (Pipeline, Project, Union, joins, etc.), resolving the input types of every
+// inner runner from the Returns() of its upstream runner, and verifies that:
+//
+//   - RunnerArgs receive the argument types they require
+//   - column indices (keys of joins, sorts, groupings, etc.) are within the
+//     width of their input
+//
+// Inputs that contain a Wildcard are of unknown width, and aren't checked.
+// It's intended for catching errors at build time, before running the plan.
+func Validate(r Runner, inp ...Type) error {
+    if len(inp) == 0 {
+        inp = nil // unknown
+    }
+    return validate(r, inp)
+}
+
+func validate(r Runner, inp []Type) error {
+    err := validateSelf(r, inp)
+    if err != nil {
+        return fmt.Errorf("%T: %s", r, err)
+    }
+
+    // inner runners
+    switch r := r.(type) {
+    case *pipeline:
+        err = validate(r.From, inp)
+        if err == nil {
+            err = validate(r.To, r.From.Returns())
+        }
+        return err
+    case *distRunner:
+        return validate(r.Runner, inp)
+    case *distJoin:
+        return validate(r.Join, inp)
+    case *wrap:
+        return validate(r.Runner, inp)
+    case *cache:
+        return validate(r.Runner, inp)
+    case *retry:
+        return validate(r.Runner, inp)
+    case *timeout:
+        return validate(r.Runner, inp)
+    case *checkpoint:
+        return validate(r.Runner, inp)
+    case *project:
+        return validateAll(inp, r.Left, r.Right)
+    case *join:
+        return validateAll(inp, r.Left, r.Right)
+    case *mergeJoin:
+        return validateAll(inp, r.Left, r.Right)
+    case *branch:
+        return validateAll(inp, r.IfTrue, r.IfFalse)
+    case *union:
+        return validateAll(inp, r.Runners...)
+    case *tee:
+        return validateAll(inp, r.Consumers...)
+    case *parallel:
+        return validateAll(inp, r.Runners...)
+    }
+    return nil
+}
+
+func validateAll(inp []Type, runners ...Runner) error {
+    for _, r := range runners {
+        err := validate(r, inp)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// validateSelf validates the runner's own requirements from its input
+func validateSelf(r Runner, inp []Type) error {
+    if r, ok := r.(RunnerArgs); ok {
+        err := validateArgs(r.Args(), inp)
+        if err != nil {
+            return err
+        }
+    }
+
+    switch r := r.(type) {
+    case *join:
+        return validateJoinKeys(r)
+    case *mergeJoin:
+        return validateJoinKeys((*join)(r))
+    case *sorter:
+        return validateSortKeys(r.Keys, inp)
+    case *window:
+        err := validateCols(r.PartitionBy, inp)
+        if err == nil {
+            err = validateSortKeys(r.OrderBy, inp)
+        }
+        return err
+    case *groupBy:
+        return validateCols(r.Keys, inp)
+    case *pivot:
+        return validateCols(append([]int{r.KeyCol, r.ValueCol}, r.GroupBy...), inp)
+    case *unpivot:
+        return validateCols(append(append([]int{}, r.Ids...), r.Columns...), inp)
+    case *exchange:
+        return validateCols(r.Columns, inp)
+    case *picker:
+        return validateCols(r.Cols, inp)
+    case *dropper:
+        return validateCols(r.Cols, inp)
+    case *apply:
+        return validateCols(r.Cols, inp)
+    case *cast:
+        return validateCols([]int{r.Col}, inp)
+    }
+    return nil
+}
+
+// validateArgs verifies that the input types match the required arguments. A
+// Wildcard argument accepts the rest of the input, and an Any argument accepts
+// any single type. Unknown (Any or WildcardAt) and Null inputs match any
+// argument, and other inputs must be Compatible with their argument.
+func validateArgs(args, inp []Type) error {
+    if inp == nil || hasWildcard(inp) {
+        return nil // unknown input
+    }
+
+    for i, t := range args {
+        if t == Wildcard {
+            return nil
+        } else if i >= len(inp) {
+            return fmt.Errorf("missing argument %d of type %s", i, t.Name())
+        }
+
+        have := inp[i]
+        if isUnknown(t) || isUnknown(have) || Null.Is(have) {
+            continue
+        } else if !Compatible(t, have) {
+            return fmt.Errorf("argument %d type mismatch: %s and %s", i, t.Name(), have.Name())
+        }
+    }
+
+    if len(inp) > len(args) {
+        return fmt.Errorf("mismatch number of arguments: %d and %d", len(args), len(inp))
+    }
+    return nil
+}
+
+func validateJoinKeys(r *join) error {
+    if len(r.LeftKeys) != len(r.RightKeys) {
+        return fmt.Errorf("mismatch number of join keys: %v and %v", r.LeftKeys, r.RightKeys)
+    }
+
+    err := validateCols(r.LeftKeys, r.Left.Returns())
+    if err == nil {
+        err = validateCols(r.RightKeys, r.Right.Returns())
+    }
+    return err
+}
+
+func validateSortKeys(keys []SortKey, inp []Type) error {
+    cols := []int{}
+    for _, k := range keys {
+        cols = append(cols, k.Col)
+    }
+    return validateCols(cols, inp)
+}
+
+// validateCols verifies that the column indices are within the width of the
+// input, if it's known
+func validateCols(cols []int, inp []Type) error {
+    if inp == nil || hasWildcard(inp) {
+        return nil // unknown width
+    }
+
+    for _, col := range cols {
+        if col < 0 || col >= len(inp) {
+            return fmt.Errorf("column %d out of range %d", col, len(inp))
+        }
+    }
+    return nil
+}
+
+func hasWildcard(types []Type) bool {
+    for _, t := range types {
+        if isWildcard(t) {
+            return true
+        }
+    }
+    return false
+}
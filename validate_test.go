@@ -0,0 +1,57 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+// strArgs is a passthrough Runner that requires two string arguments
+type strArgs struct {}
+func (*strArgs) Args() []Type { return []Type{Str, Str} }
+func (*strArgs) Returns() []Type { return []Type{Wildcard} }
+func (*strArgs) Run(ctx context.Context, inp, out chan Dataset) error {
+    return PassThrough().Run(ctx, inp, out)
+}
+
+func ExampleValidate() {
+    runner := Pipeline(users, Sort([]SortKey{{Col: 2}}))
+    fmt.Println(Validate(runner))
+
+    // Output: *ep.sorter: column 2 out of range 2
+}
+
+func TestValidate(t *testing.T) {
+    valid := []Runner{
+        Pipeline(users, &strArgs{}, Sort([]SortKey{{Col: 1}})),
+        Join(InnerJoin, []int{0}, []int{0}, users, orders),
+        Pipeline(PassThrough(), Sort([]SortKey{{Col: 5}})), // unknown width
+    }
+
+    for _, r := range valid {
+        require.NoError(t, Validate(r))
+    }
+
+    invalid := map[string]Runner{
+        "*ep.strArgs: missing argument 1 of type string": Pipeline(&Upper{}, &strArgs{}),
+        "*ep.join: mismatch number of join keys: [0] and [0 1]": Join(InnerJoin, []int{0}, []int{0, 1}, users, orders),
+        "*ep.join: column 3 out of range 2": Join(InnerJoin, []int{3}, []int{0}, users, orders),
+        "*ep.groupBy: column 2 out of range 2": Pipeline(users, GroupBy([]int{2}, Count())),
+    }
+
+    for msg, r := range invalid {
+        err := Validate(r)
+        require.Error(t, err, msg)
+        require.Equal(t, msg, err.Error())
+    }
+}
+
+func TestValidateArgs(t *testing.T) {
+    require.NoError(t, Validate(&strArgs{}, Str, Null))
+    require.NoError(t, Validate(&strArgs{})) // unknown input
+
+    err := Validate(&strArgs{}, Str, Str, Str)
+    require.Error(t, err)
+    require.Equal(t, "*ep.strArgs: mismatch number of arguments: 2 and 3", err.Error())
+}
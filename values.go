@@ -0,0 +1,214 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "encoding/json"
+    "github.com/satori/go.uuid"
+)
+
+// Valuer is optionally implemented by Data types in order to convert their
+// values into native Go values. All of the built-in types implement it or are
+// supported by Value. See Value and Row
+type Valuer interface {
+
+    // Value returns the native Go value at index i
+    Value(i int) interface{}
+}
+
+// Value returns the native Go value at index i of the data, for sinks like
+// database writers that need to extract values without type-switching on every
+// Data type. Nulls are nil, and the built-in types are converted as follows:
+//
+//      Strs, Enums     string
+//      Ints            int64
+//      Floats          float64
+//      Bools           bool
+//      Times           time.Time (in UTC)
+//      Intervals       time.Duration
+//      UUIDs           uuid.UUID
+//      Decimals        string (exact decimal representation)
+//      JSONs           json.RawMessage
+//      Structs         map[string]interface{}
+//      Lists           []interface{}
+//
+// Other types are converted by their Valuer implementation, or into their
+// string representation.
+func Value(data Data, i int) interface{} {
+    switch vs := data.(type) {
+    case Valuer:
+        return vs.Value(i)
+    case nulls:
+        return nil
+    case Strs:
+        return vs[i]
+    case Bools:
+        return vs[i]
+    case Times:
+        return vs.At(i)
+    case Intervals:
+        return vs[i]
+    case Decimals:
+        return vs.String(i)
+    case JSONs:
+        return json.RawMessage(vs[i])
+    case Enums:
+        return vs.Dict[vs.Codes[i]]
+    case Structs:
+        res := map[string]interface{}{}
+        for j, f := range vs.Fields {
+            res[vs.Names[j]] = Value(f, i)
+        }
+        return res
+    case Lists:
+        return Values(vs.Values[i])
+    }
+    return data.Strings()[i]
+}
+
+// Values returns all of the native Go values of the data. See Value
+func Values(data Data) []interface{} {
+    res := make([]interface{}, data.Len())
+    for i := range res {
+        res[i] = Value(data, i)
+    }
+    return res
+}
+
+// FromValues builds a Data of the provided type out of native Go values, the
+// reverse of Values. nil values are nulls (see Nullable). Values are converted
+// into the type where it's unambiguous, e.g. any integer into an Int, or a
+// string into a UUID. Types other than the built-in types must implement
+// `interface { FromValues([]interface{}) (Data, error) }`, as Column types do.
+func FromValues(t Type, values []interface{}) (Data, error) {
+    valid := make(Bools, len(values))
+    hasNulls := false
+    for i, v := range values {
+        valid[i] = v != nil
+        hasNulls = hasNulls || v == nil
+    }
+
+    res, err := fromValues(unnamed(t), values)
+    if err != nil || !hasNulls {
+        return res, err
+    }
+    return Nullable(res, valid), nil
+}
+
+func fromValues(t Type, values []interface{}) (Data, error) {
+    if n, ok := t.(*NullableType); ok {
+        return fromValues(n.Of, values)
+    } else if f, ok := t.(interface { FromValues([]interface{}) (Data, error) }); ok {
+        return f.FromValues(values)
+    }
+
+    res := t.Data(uint(len(values)))
+    strs := make([]string, len(values))
+    for i, v := range values {
+        if v == nil {
+            continue // null
+        }
+
+        var ok bool
+        switch vs := res.(type) {
+        case Strs:
+            vs[i], ok = v.(string)
+        case Bools:
+            vs[i], ok = v.(bool)
+        case Times:
+            var t time.Time
+            if t, ok = v.(time.Time); ok {
+                vs[i] = t.UnixNano()
+            }
+        case Intervals:
+            vs[i], ok = v.(time.Duration)
+        case JSONs:
+            b, err := json.Marshal(v)
+            vs[i], ok = string(b), err == nil
+        default:
+            // types that are parsed from strings (decimals, enums)
+            strs[i], ok = v.(string)
+        }
+
+        if !ok {
+            return nil, fmt.Errorf("unable to convert %T into %s", v, t.Name())
+        }
+    }
+
+    switch t := t.(type) {
+    case *DecimalType:
+        return ParseDecimals(t.Precision, t.Scale, strs...)
+    case *EnumType:
+        return NewEnums(strs...), nil
+    }
+    return res, nil
+}
+
+func toInt(v interface{}) (int64, bool) {
+    switch v := v.(type) {
+    case int: return int64(v), true
+    case int8: return int64(v), true
+    case int16: return int64(v), true
+    case int32: return int64(v), true
+    case int64: return v, true
+    case uint8: return int64(v), true
+    case uint16: return int64(v), true
+    case uint32: return int64(v), true
+    }
+    return 0, false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+    switch v := v.(type) {
+    case float32: return float64(v), true
+    case float64: return v, true
+    }
+
+    i, ok := toInt(v)
+    return float64(i), ok
+}
+
+// Value returns the value at index i. See Valuer
+func (vs Column[T]) Value(i int) interface{} { return vs[i] }
+
+// FromValues returns a Column of the values, which must all be of type T, or
+// convertible into the built-in numeric and UUID types
+func (*ColumnType[T]) FromValues(values []interface{}) (Data, error) {
+    res := make(Column[T], len(values))
+    for i, v := range values {
+        if v == nil {
+            continue // null
+        }
+
+        var ok bool
+        if res[i], ok = v.(T); ok {
+            continue
+        }
+
+        switch p := any(&res[i]).(type) {
+        case *int64:
+            *p, ok = toInt(v)
+        case *float64:
+            *p, ok = toFloat(v)
+        case *uuid.UUID:
+            var err error
+            if s, isStr := v.(string); isStr {
+                *p, err = uuid.FromString(s)
+                ok = err == nil
+            }
+        }
+
+        if !ok {
+            return nil, fmt.Errorf("unable to convert %T into %s", v, opsOf[T]().Name)
+        }
+    }
+    return res, nil
+}
+
+// Value returns nil for nulls, or the value of the underlying data
+func (vs nullable) Value(i int) interface{} {
+    if !vs.Valid[i] {
+        return nil
+    }
+    return Value(vs.Values, i)
+}
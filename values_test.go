@@ -0,0 +1,91 @@
+package ep
+
+import (
+    "fmt"
+    "time"
+    "testing"
+    "encoding/json"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleValues() {
+    ts := NewTimes(time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC))
+    fmt.Println(Values(Ints{1, 2}), Values(Nullable(Strs{"a", "b"}, Bools{true, false})), Values(ts))
+
+    // Output: [1 2] [a <nil>] [2018-01-02 03:04:05 +0000 UTC]
+}
+
+func ExampleFromValues() {
+    data, err := FromValues(Int, []interface{}{1, int32(2), nil})
+    fmt.Println(data, data.Type().Name(), err)
+
+    // Output: [1 2 <nil>] int <nil>
+}
+
+func TestDatasetRow(t *testing.T) {
+    dec, err := ParseDecimals(5, 2, "1.50")
+    require.NoError(t, err)
+
+    data := NewDataset(Strs{"a"}, Floats{1.5}, Bools{true}, dec, NewEnums("x"), Null.Data(1))
+    data = WithSchema(data, Schema{{"s", Str}, {"f", Float}, {"b", Bool}, {"d", dec.Type()}, {"e", Enum}, {"n", Null}})
+    require.Equal(t, []interface{}{"a", 1.5, true, "1.50", "x", nil}, data.Row(0))
+}
+
+func TestValuesNested(t *testing.T) {
+    js, err := NewJSONs(map[string]int{"a": 1})
+    require.NoError(t, err)
+    require.Equal(t, []interface{}{json.RawMessage(`{"a":1}`)}, Values(js))
+
+    structs := NewStructs([]string{"k", "v"}, Strs{"a", "b"}, Ints{1, 2})
+    require.Equal(t, map[string]interface{}{"k": "b", "v": int64(2)}, Value(structs, 1))
+
+    lists := NewLists(Int, Ints{1, 2}, Ints{})
+    require.Equal(t, []interface{}{[]interface{}{int64(1), int64(2)}, []interface{}{}}, Values(lists))
+}
+
+// Tests that converting to native values and back yields the same data
+func TestFromValuesRoundTrip(t *testing.T) {
+    dec, err := ParseDecimals(5, 2, "1.50", "-2.25")
+    require.NoError(t, err)
+
+    ts := NewTimes(time.Unix(1, 0), time.Unix(2, 0))
+    uuids, err := ParseUUIDs("6ba7b810-9dad-11d1-80b4-00c04fd430c8", "6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+    require.NoError(t, err)
+
+    all := []Data{Strs{"a", "b"}, Ints{1, 2}, Floats{1.5, 2}, Bools{true, false}, ts, Intervals{time.Second, 0}, dec, uuids, NewEnums("x", "y")}
+    for _, data := range all {
+        res, err := FromValues(data.Type(), Values(data))
+        require.NoError(t, err, data.Type().Name())
+        require.Equal(t, data.Strings(), res.Strings(), data.Type().Name())
+        require.Equal(t, data.Type().Name(), res.Type().Name())
+    }
+}
+
+func TestFromValuesConversions(t *testing.T) {
+    data, err := FromValues(Float, []interface{}{1, float32(0.5)})
+    require.NoError(t, err)
+    require.Equal(t, Floats{1, 0.5}, data)
+
+    data, err = FromValues(UUID, []interface{}{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"})
+    require.NoError(t, err)
+    require.Equal(t, []string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, data.Strings())
+
+    data, err = FromValues(JSON, []interface{}{[]int{1, 2}})
+    require.NoError(t, err)
+    require.Equal(t, JSONs{"[1,2]"}, data)
+
+    data, err = FromValues(Nullable(Strs{}, Bools{}).Type(), []interface{}{nil, "a"})
+    require.NoError(t, err)
+    require.Equal(t, []interface{}{nil, "a"}, Values(data))
+}
+
+func TestFromValuesMismatch(t *testing.T) {
+    _, err := FromValues(Int, []interface{}{"1"})
+    require.EqualError(t, err, "unable to convert string into int")
+
+    _, err = FromValues(UUID, []interface{}{"foo"})
+    require.Error(t, err)
+
+    _, err = FromValues(Point, []interface{}{1})
+    require.EqualError(t, err, "unable to convert int into point")
+}
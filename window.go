@@ -0,0 +1,265 @@
+package ep
+
+import (
+    "context"
+    "math/big"
+)
+
+var _ = registerGob(&window{}, &rowNumber{}, &rank{}, &lag{}, &runningSum{})
+
+// WindowFunc computes a value for every row of a partition, out of the rows of
+// the partition in their sorted order. See Window.
+type WindowFunc interface {
+
+    // Returns the type of the computed values
+    Returns() Type
+
+    // Compute the values of all of the rows of a single partition, sorted by
+    // the order keys. The returned Data must have a value per row.
+    Compute(partition Dataset, order []SortKey) (Data, error)
+}
+
+// Window returns a Runner that computes window functions over partitions of
+// its input: rows are partitioned by the values of the partition columns, and
+// each partition is sorted by the order keys. The output contains the input
+// columns, followed by a column per function. With no partition columns, the
+// entire input is a single partition.
+//
+// It's built atop Sort, thus partitions exceeding the SortBuffer are spilled to
+// disk, but every single partition is buffered in memory while computing the
+// functions. When distributed, the input is first repartitioned by the
+// partition columns (see Repartition), such that each partition is computed on
+// a single node.
+func Window(partitionBy []int, orderBy []SortKey, funcs ...WindowFunc) Runner {
+    keys := []SortKey{}
+    for _, col := range partitionBy {
+        keys = append(keys, SortKey{Col: col})
+    }
+    keys = append(keys, orderBy...)
+
+    return Pipeline(
+        Repartition(partitionBy...),
+        Sort(keys),
+        &window{partitionBy, orderBy, funcs},
+    )
+}
+
+type window struct {
+    PartitionBy []int
+    OrderBy []SortKey
+    Funcs []WindowFunc
+}
+
+// Returns the input columns, followed by the computed columns
+func (r *window) Returns() []Type {
+    types := []Type{Wildcard}
+    for _, fn := range r.Funcs {
+        types = append(types, fn.Returns())
+    }
+    return types
+}
+
+// Run expects the input to be sorted by the partition columns, followed by the
+// order keys, thus the rows of each partition are consecutive.
+func (r *window) Run(ctx context.Context, inp, out chan Dataset) error {
+    var partition, res Dataset
+    var key string
+    for data := range inp {
+        if data.Len() == 0 {
+            continue
+        }
+
+        // find the boundaries of the partitions within the batch
+        start := 0
+        for i, k := range rowKeys(data, r.PartitionBy) {
+            if partition == nil && i == 0 {
+                key = k // first row
+                continue
+            } else if k == key {
+                continue
+            }
+
+            // the partition ends before the current row
+            var err error
+            partition = appendClone(partition, data.Slice(start, i).(Dataset))
+            res, err = r.compute(res, partition, out)
+            if err != nil {
+                return err
+            }
+
+            partition, start, key = nil, i, k
+        }
+
+        partition = appendClone(partition, data.Slice(start, data.Len()).(Dataset))
+    }
+
+    if partition != nil {
+        var err error
+        res, err = r.compute(res, partition, out)
+        if err != nil {
+            return err
+        }
+    }
+
+    if res != nil {
+        out <- res
+    }
+    return nil
+}
+
+// compute the functions over the partition, and append the result to the
+// buffered output. Full batches are emitted.
+func (r *window) compute(res, partition Dataset, out chan Dataset) (Dataset, error) {
+    cols := []Data{}
+    names := []string{}
+    for i := 0; i < partition.Width(); i++ {
+        cols = append(cols, partition.At(i))
+        names = append(names, partition.ColumnName(i))
+    }
+
+    for _, fn := range r.Funcs {
+        values, err := fn.Compute(partition, r.OrderBy)
+        if err != nil {
+            return res, err
+        }
+        cols = append(cols, values)
+        names = append(names, "")
+    }
+
+    res = appendClone(res, newNamedDataset(names, cols...))
+    if res.Len() >= BatchSize {
+        out <- res
+        return nil, nil
+    }
+    return res, nil
+}
+
+// RowNumber returns a WindowFunc that numbers the rows of each partition,
+// starting with 1
+func RowNumber() WindowFunc { return &rowNumber{} }
+
+// Rank returns a WindowFunc that ranks the rows of each partition by the order
+// keys. Rows with equal order keys have the same rank, followed by a gap
+func Rank() WindowFunc { return &rank{} }
+
+// Lag returns a WindowFunc that returns the value of the column at `offset`
+// rows before the current row in the partition, or a null if there's no such
+// row. The values are of the type of the column, as Nullable data
+func Lag(col, offset int) WindowFunc { return &lag{col, offset} }
+
+// Lead returns a WindowFunc that returns the value of the column at `offset`
+// rows after the current row in the partition, or a null if there's no such
+// row. See Lag
+func Lead(col, offset int) WindowFunc { return &lag{col, -offset} }
+
+// RunningSum returns a WindowFunc that sums the numeric values of the column
+// from the first row of the partition up to the current row, skipping nulls.
+// Ints are summed into Ints, and Decimals into Decimals of the same scale and
+// the maximum precision, while other columns are summed into Floats
+func RunningSum(col int) WindowFunc { return &runningSum{col} }
+
+type rowNumber struct {}
+func (*rowNumber) Returns() Type { return Int }
+func (*rowNumber) Compute(partition Dataset, order []SortKey) (Data, error) {
+    res := make(Ints, partition.Len())
+    for i := range res {
+        res[i] = int64(i + 1)
+    }
+    return res, nil
+}
+
+type rank struct {}
+func (*rank) Returns() Type { return Int }
+func (*rank) Compute(partition Dataset, order []SortKey) (Data, error) {
+    cols := make([]int, len(order))
+    for i, k := range order {
+        cols[i] = k.Col
+    }
+
+    res := make(Ints, partition.Len())
+    var current int64 = 1
+    for i := range res {
+        if i > 0 && compareKeys(partition, i - 1, cols, partition, i, cols) != 0 {
+            current = int64(i + 1)
+        }
+        res[i] = current
+    }
+    return res, nil
+}
+
+type lag struct { Col, Offset int }
+func (*lag) Returns() Type { return Any }
+func (fn *lag) Compute(partition Dataset, order []SortKey) (Data, error) {
+    col := partition.At(fn.Col)
+    if col.Type() == Null {
+        return Null.Data(uint(col.Len())), nil
+    }
+
+    values, valid := col, Bools(nil)
+    if vs, ok := col.(nullable); ok {
+        values, valid = vs.Values, vs.Valid
+    }
+
+    // rows without a source row pick the first row, masked as null
+    rows := make([]int, col.Len())
+    res := make(Bools, col.Len())
+    for i := range rows {
+        j := i - fn.Offset
+        if j >= 0 && j < len(rows) {
+            rows[i] = j
+            res[i] = valid == nil || valid[j]
+        }
+    }
+    return Nullable(pick(values, rows), res), nil
+}
+
+type runningSum struct { Col int }
+func (*runningSum) Returns() Type { return Any }
+func (fn *runningSum) Compute(partition Dataset, order []SortKey) (Data, error) {
+    col := partition.At(fn.Col)
+    values, valid := col, Bools(nil)
+    if vs, ok := col.(nullable); ok {
+        values, valid = vs.Values, vs.Valid
+    }
+
+    switch vs := values.(type) {
+    case Ints:
+        res := make(Ints, len(vs))
+        var total int64
+        for i, v := range vs {
+            if valid == nil || valid[i] {
+                total += v
+            }
+            res[i] = total
+        }
+        return res, nil
+    case Decimals:
+        res := Decimals{MaxDecimalPrecision, vs.Scale, make([]int64, vs.Len())}
+        total := new(big.Int)
+        for i, v := range vs.Values {
+            if valid == nil || valid[i] {
+                total.Add(total, big.NewInt(v))
+            }
+
+            _, err := res.fit(total)
+            if err != nil {
+                return nil, err
+            }
+            res.Values[i] = total.Int64()
+        }
+        return res, nil
+    }
+
+    res := make(Floats, col.Len())
+    var total float64
+    for i := range res {
+        v, _, err := sumColumn(col.Slice(i, i + 1))
+        if err != nil {
+            return nil, err
+        }
+
+        total += v
+        res[i] = total
+    }
+    return res, nil
+}
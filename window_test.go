@@ -0,0 +1,69 @@
+package ep
+
+import (
+    "fmt"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleWindow() {
+    runner := Window([]int{0}, []SortKey{{Col: 1}}, RowNumber(), RunningSum(1))
+    data1 := NewDataset(Strs{"b", "a", "a"}, Strs{"4", "2", "1"})
+    data2 := NewDataset(Strs{"b", "a"}, Strs{"3", "5"})
+    data, err := testRun(runner, data1, data2)
+    fmt.Println(data, err)
+
+    // Output: [[a a a b b] [1 2 5 3 4] [1 2 3 1 2] [1 3 8 3 7]] <nil>
+}
+
+func TestWindowRankLagLead(t *testing.T) {
+    runner := Window(nil, []SortKey{{Col: 0, Desc: true}}, Rank(), Lag(1, 1), Lead(1, 2))
+    data, err := testRun(runner, NewDataset(Strs{"x", "y", "x", "z"}, Strs{"1", "2", "3", "4"}))
+    require.NoError(t, err)
+
+    expected := []string{"z 4 1  1", "y 2 2 4 3", "x 1 3 2 ", "x 3 3 1 "}
+    require.Equal(t, expected, rowStrings(data))
+}
+
+// partitions spanning several batches are computed as a whole
+func TestWindowPartitionsAcrossBatches(t *testing.T) {
+    inputs := []Dataset{}
+    for i := 0; i < 5; i++ {
+        inputs = append(inputs, NewDataset(Strs{"a", "b", "c"}, Strs{"1", "1", "1"}))
+    }
+
+    runner := Window([]int{0}, nil, RowNumber(), RunningSum(1))
+    data, err := testRun(runner, inputs...)
+    require.NoError(t, err)
+    require.Equal(t, 15, data.Len())
+
+    last := map[string]string{}
+    sums := data.At(3).Strings()
+    for i, k := range data.At(0).Strings() {
+        last[k] = sums[i]
+    }
+    require.Equal(t, map[string]string{"a": "5", "b": "5", "c": "5"}, last)
+}
+
+// Lag and Lead keep the type of the column, with nulls for missing rows and
+// null values, while RunningSum keeps Ints and Decimals exact
+func TestWindowTypes(t *testing.T) {
+    ints := Nullable(Ints{1, 2, 0, 4}, Bools{true, true, false, true})
+    decimals, err := ParseDecimals(3, 1, "0.1", "0.2", "0.1", "0.3")
+    require.NoError(t, err)
+
+    runner := Window(nil, []SortKey{{Col: 0}}, Lag(1, 1), Lead(1, 1), RunningSum(1), RunningSum(2))
+    data, err := testRun(runner, NewDataset(Ints{1, 2, 3, 4}, ints, decimals))
+    require.NoError(t, err)
+
+    lag, lead := data.At(3), data.At(4)
+    require.Equal(t, ints.Type(), lag.Type())
+    require.Equal(t, Bools{true, false, false, true}, NullMask(lag))
+    require.Equal(t, []string{"", "1", "2", ""}, lag.Strings())
+    require.Equal(t, Bools{false, true, false, true}, NullMask(lead))
+    require.Equal(t, []string{"2", "", "4", ""}, lead.Strings())
+
+    require.Equal(t, Ints{1, 3, 3, 7}, data.At(5))
+    require.Equal(t, Decimal(MaxDecimalPrecision, 1), data.At(6).Type())
+    require.Equal(t, []string{"0.1", "0.3", "0.4", "0.7"}, data.At(6).Strings())
+}
@@ -0,0 +1,379 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "sync"
+    "bytes"
+    "errors"
+    "unsafe"
+    "encoding/gob"
+)
+
+// MaxMessageSize is the maximum size in bytes of a single message sent to or
+// received from a peer node, like a dataset sent by an exchange. Larger
+// messages fail the connection before they're allocated, in order to not
+// exhaust the memory on corrupt or malicious messages, and fail the sender
+// before they're sent, like a single huge batch produced by a runner
+var MaxMessageSize = 256 << 20
+
+// MaxRunnerSize is the maximum size in bytes of an encoded distributed runner,
+// which is checked by the master before it's sent, and by the peers before
+// it's decoded. See MaxMessageSize
+var MaxRunnerSize = 64 << 20
+
+// The connections between nodes start with a handshake frame, written by the
+// dialing node in a single write, that identifies the connection:
+//
+//      magic    2 bytes    "ep"
+//      version  1 byte     handshakeVersion
+//      type     string     connData, connRunner or connPing
+//      key      string     "<node>:<uid>" of data connections, empty otherwise
+//
+// The strings are length-prefixed, by 2 bytes of big-endian length followed by
+// up to maxStrLen bytes. Runner and ping connections are answered by a status
+// string (statusOK or statusDraining). Then, data connections carry a gob
+// stream of dataReq messages in both directions, while runner connections
+// carry the distRunner from the master, followed by the reports of the peer.
+// Gob streams are read with a WireDecoder. A node rejects the handshakes of
+// other versions, as the protocol is incompatible between versions.
+const handshakeVersion = 1
+
+// handshakeMagic prefixes the handshake frame, in order to fail fast on
+// connections from other protocols
+const handshakeMagic = "ep"
+
+// the types of the connections between nodes, see handshakeVersion
+const (
+    connData = "D"
+    connRunner = "X"
+    connPing = "P"
+)
+
+// maxStrLen is the maximum length of the strings of the handshake frame. See
+// readStr
+const maxStrLen = 4096
+
+// writeHandshake writes the handshake frame of a connection, see
+// handshakeVersion
+func writeHandshake(w io.Writer, typee, key string) error {
+    err := checkStr(typee, key)
+    if err != nil {
+        return err
+    }
+
+    b := make([]byte, 0, len(handshakeMagic) + 5 + len(typee) + len(key))
+    b = append(b, handshakeMagic...)
+    b = append(b, handshakeVersion)
+    b = appendStr(appendStr(b, typee), key)
+    _, err = w.Write(b)
+    return err
+}
+
+// readHandshake reads the handshake frame of a connection, and returns its
+// type and key, see handshakeVersion
+func readHandshake(r io.Reader) (typee, key string, err error) {
+    var header [len(handshakeMagic) + 1]byte
+    _, err = io.ReadFull(r, header[:])
+    if err != nil {
+        return "", "", err
+    } else if string(header[:len(handshakeMagic)]) != handshakeMagic {
+        return "", "", errors.New("ep: unrecognized connection")
+    } else if v := header[len(handshakeMagic)]; v != handshakeVersion {
+        return "", "", fmt.Errorf("ep: unsupported protocol version %d, expected %d", v, handshakeVersion)
+    }
+
+    typee, err = readStr(r)
+    if err != nil {
+        return "", "", err
+    }
+
+    key, err = readStr(r)
+    return typee, key, err
+}
+
+// write a length-prefixed string to a writer, in a single write
+func writeStr(w io.Writer, s string) error {
+    err := checkStr(s)
+    if err != nil {
+        return err
+    }
+
+    _, err = w.Write(appendStr(make([]byte, 0, 2 + len(s)), s))
+    return err
+}
+
+// appendStr appends a length-prefixed string, that was verified by checkStr
+func appendStr(b []byte, s string) []byte {
+    b = append(b, byte(len(s) >> 8), byte(len(s)))
+    return append(b, s...)
+}
+
+// checkStr returns an error if any of the strings exceeds maxStrLen, as it
+// can't be read by the peer
+func checkStr(strs ...string) error {
+    for _, s := range strs {
+        if len(s) > maxStrLen {
+            return fmt.Errorf("ep: string exceeds the maximum length of %d", maxStrLen)
+        }
+    }
+    return nil
+}
+
+// shortStrLen is the length of the strings that readStr reads with a single
+// allocation, including the statuses, types and keys of the handshakes
+const shortStrLen = 126
+
+// read a length-prefixed string from a reader, of up to maxStrLen bytes.
+// Exactly the bytes of the string are read, such that the rest of the stream
+// isn't consumed. The length and the string are read into a single buffer,
+// which is then converted into the string without copying
+func readStr(r io.Reader) (string, error) {
+    b := make([]byte, 2, 2 + shortStrLen)
+    _, err := io.ReadFull(r, b)
+    if err != nil {
+        return "", err
+    }
+
+    n := int(b[0]) << 8 | int(b[1])
+    if n > maxStrLen {
+        return "", fmt.Errorf("ep: string exceeds the maximum length of %d", maxStrLen)
+    } else if n > shortStrLen {
+        b = make([]byte, n)
+    } else {
+        b = b[2:2 + n]
+    }
+
+    _, err = io.ReadFull(r, b)
+    if err == io.EOF {
+        err = io.ErrUnexpectedEOF
+    }
+
+    if err != nil {
+        return "", err
+    }
+
+    // b isn't referenced elsewhere, like in strings.Builder.String
+    return *(*string)(unsafe.Pointer(&b)), nil
+}
+
+// WireDecoder decodes the gob messages of the protocol between nodes, as sent
+// by exchanges and distributers. Unlike a bare gob.Decoder, it's safe for
+// untrusted input: messages larger than MaxMessageSize are rejected before
+// they're read, and the decoded messages are validated, such that the payloads
+// of exchanges are only datasets of equal length columns, errors or control
+// messages
+type WireDecoder struct {
+    dec *gob.Decoder
+}
+
+// NewWireDecoder returns a WireDecoder that reads from r
+func NewWireDecoder(r io.Reader) *WireDecoder {
+    return newWireDecoder(r, messageLimit())
+}
+
+func newWireDecoder(r io.Reader, l sizeLimit) *WireDecoder {
+    return &WireDecoder{gob.NewDecoder(&frameReader{r: r, limit: l})}
+}
+
+// Decode the next message into v, and validates it
+func (dec *WireDecoder) Decode(v interface{}) error {
+    err := dec.dec.Decode(v)
+    if err != nil {
+        return err
+    }
+    return validateMessage(v)
+}
+
+// WireEncoder encodes the gob messages of the protocol between nodes, like a
+// gob.Encoder, except that messages larger than MaxMessageSize fail before
+// they're written, rather than failing the connection on the receiving end.
+// Every message, including the definitions of its types, is encoded to a
+// buffer and then written at once. As the types of a failed message are
+// considered as sent by gob, the encoder fails all of the subsequent messages
+type WireEncoder struct {
+    l sync.Mutex
+    w io.Writer
+    buf bytes.Buffer
+    enc *gob.Encoder
+    limit sizeLimit
+    err error // of an oversized message, see Encode
+}
+
+// NewWireEncoder returns a WireEncoder that writes to w
+func NewWireEncoder(w io.Writer) *WireEncoder {
+    return newWireEncoder(w, messageLimit())
+}
+
+func newWireEncoder(w io.Writer, l sizeLimit) *WireEncoder {
+    enc := &WireEncoder{w: w, limit: l}
+    enc.enc = gob.NewEncoder(&enc.buf)
+    return enc
+}
+
+// Encode the message v, and writes it
+func (enc *WireEncoder) Encode(v interface{}) error {
+    enc.l.Lock()
+    defer enc.l.Unlock()
+    if enc.err != nil {
+        return enc.err
+    }
+
+    defer enc.buf.Reset()
+    err := enc.enc.Encode(v)
+    if err != nil {
+        return err
+    }
+
+    enc.err = enc.limit.check(uint64(enc.buf.Len()))
+    if enc.err != nil {
+        return enc.err
+    }
+
+    _, err = enc.w.Write(enc.buf.Bytes())
+    if enc.buf.Cap() > maxRetainedBuffer {
+        enc.buf = bytes.Buffer{} // don't retain the memory of large messages
+    }
+    return err
+}
+
+// maxRetainedBuffer is the capacity of the encoding buffer of a WireEncoder
+// that's retained between messages
+const maxRetainedBuffer = 1 << 20
+
+// sizeLimit is the maximum size of the messages of a stream, named after the
+// variable that configures it for errors
+type sizeLimit struct {
+    name string
+    max int
+}
+
+func messageLimit() sizeLimit { return sizeLimit{"MaxMessageSize", MaxMessageSize} }
+func runnerLimit() sizeLimit { return sizeLimit{"MaxRunnerSize", MaxRunnerSize} }
+
+func (l sizeLimit) check(count uint64) error {
+    if count > uint64(l.max) {
+        return fmt.Errorf("ep: message of %d bytes exceeds the maximum size of %d, see ep.%s", count, l.max, l.name)
+    }
+    return nil
+}
+
+func validateMessage(v interface{}) error {
+    switch v := v.(type) {
+    case *dataReq:
+        switch payload := v.Payload.(type) {
+        case *stopMsg, *errMsg:
+            return nil
+        case Dataset:
+            return validateDataset(payload)
+        }
+        return fmt.Errorf("ep: unexpected message %T", v.Payload)
+    case *distRunner:
+        if v.Runner == nil {
+            return errors.New("ep: missing distributed runner")
+        }
+    }
+    return nil
+}
+
+// validateDataset verifies that all of the columns of the dataset, including
+// nested datasets, are present and of equal lengths
+func validateDataset(data Dataset) error {
+    set, ok := data.(dataset)
+    if !ok {
+        return nil // other implementations are validated by their decoders
+    }
+
+    for _, col := range set {
+        if col == nil {
+            return errors.New("ep: missing column")
+        } else if col.Len() != set.Len() {
+            return errors.New("ep: columns of different lengths")
+        }
+
+        if nested, ok := col.(Dataset); ok {
+            err := validateDataset(nested)
+            if err != nil {
+                return err
+            }
+        }
+    }
+    return nil
+}
+
+// frameReader reads a gob stream, verifying that the byte count that prefixes
+// every message doesn't exceed the limit. Gob encodes the count as a
+// single byte when it's less than 128, or otherwise as the negated number of
+// bytes that follow, in big-endian
+type frameReader struct {
+    r io.Reader
+    limit sizeLimit
+    remaining int // the bytes left to read from the current message
+    header []byte // the unread bytes of the count of the current message
+}
+
+func (f *frameReader) Read(b []byte) (int, error) {
+    if len(f.header) == 0 && f.remaining == 0 {
+        err := f.readHeader()
+        if err != nil {
+            return 0, err
+        }
+    }
+
+    if len(f.header) > 0 {
+        n := copy(b, f.header)
+        f.header = f.header[n:]
+        return n, nil
+    }
+
+    if len(b) > f.remaining {
+        b = b[:f.remaining]
+    }
+
+    n, err := f.r.Read(b)
+    f.remaining -= n
+    if err == io.EOF && f.remaining > 0 {
+        err = io.ErrUnexpectedEOF
+    }
+    return n, err
+}
+
+func (f *frameReader) readHeader() error {
+    header := make([]byte, 1, 9)
+    _, err := io.ReadFull(f.r, header)
+    if err != nil {
+        return err
+    }
+
+    count := uint64(header[0])
+    if count >= 0x80 {
+        n := int(-int8(header[0]))
+        if n < 1 || n > 8 {
+            return errors.New("ep: corrupt message size")
+        }
+
+        header = header[:1 + n]
+        _, err = io.ReadFull(f.r, header[1:])
+        if err == io.EOF {
+            err = io.ErrUnexpectedEOF
+        }
+
+        if err != nil {
+            return err
+        }
+
+        count = 0
+        for _, b := range header[1:] {
+            count = count << 8 | uint64(b)
+        }
+    }
+
+    if count == 0 {
+        return errors.New("ep: corrupt message size")
+    } else if err = f.limit.check(count); err != nil {
+        return err
+    }
+
+    f.header, f.remaining = header, int(count)
+    return nil
+}
@@ -0,0 +1,198 @@
+package ep
+
+import (
+    "io"
+    "fmt"
+    "bytes"
+    "strings"
+    "testing"
+    "encoding/gob"
+    "github.com/stretchr/testify/require"
+)
+
+func encodeMessages(vs ...interface{}) []byte {
+    var buf bytes.Buffer
+    enc := gob.NewEncoder(&buf)
+    for _, v := range vs {
+        err := enc.Encode(v)
+        if err != nil {
+            panic(err)
+        }
+    }
+    return buf.Bytes()
+}
+
+func TestWireDecoder(t *testing.T) {
+    data := NewDataset(Strs{"hello", "world"}, Ints{1, 2})
+    b := encodeMessages(&dataReq{data}, &dataReq{&stopMsg{}}, &dataReq{&errMsg{"bad"}})
+
+    dec := NewWireDecoder(bytes.NewReader(b))
+    req := &dataReq{}
+    require.NoError(t, dec.Decode(req))
+    require.Equal(t, "[[hello world] [1 2]]", fmt.Sprint(req.Payload))
+
+    require.NoError(t, dec.Decode(req))
+    require.Equal(t, &stopMsg{}, req.Payload)
+
+    require.NoError(t, dec.Decode(req))
+    require.EqualError(t, req.Payload.(error), "bad")
+}
+
+func TestWireDecoderMaxSize(t *testing.T) {
+    defer func(size int) { MaxMessageSize = size }(MaxMessageSize)
+    MaxMessageSize = 1000
+
+    data := NewDataset(Strs{strings.Repeat("x", 1000)})
+    b := encodeMessages(&dataReq{data})
+    err := NewWireDecoder(bytes.NewReader(b)).Decode(&dataReq{})
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "exceeds the maximum size of 1000")
+
+    // a huge message size is rejected without reading the message
+    b = []byte{0xF8, 0x7F, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}
+    err = NewWireDecoder(bytes.NewReader(b)).Decode(&dataReq{})
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "exceeds the maximum size of 1000")
+
+    err = NewWireDecoder(bytes.NewReader([]byte{0x80})).Decode(&dataReq{})
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "corrupt message size")
+}
+
+func TestWireEncoderMaxSize(t *testing.T) {
+    defer func(size int) { MaxMessageSize = size }(MaxMessageSize)
+    MaxMessageSize = 1000
+
+    var buf bytes.Buffer
+    enc := NewWireEncoder(&buf)
+    err := enc.Encode(&dataReq{NewDataset(Strs{strings.Repeat("x", 1000)})})
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "exceeds the maximum size of 1000, see ep.MaxMessageSize")
+
+    require.Equal(t, 0, buf.Len())
+
+    // the encoder remains failed
+    require.Equal(t, err, enc.Encode(&dataReq{NewDataset(Strs{"hello"})}))
+    require.Equal(t, 0, buf.Len())
+}
+
+// Tests that a batch larger than MaxMessageSize fails the exchange that sends
+// it, and a runner larger than MaxRunnerSize fails before it's distributed
+func TestMaxSizeDistributed(t *testing.T) {
+    defer func(size int) { MaxMessageSize = size }(MaxMessageSize)
+    defer func(size int) { MaxRunnerSize = size }(MaxRunnerSize)
+    MaxMessageSize = 1000
+
+    pipes := NewPipes()
+    dists := []Distributer{}
+    for _, addr := range []string{":5551", ":5552"} {
+        ln, err := pipes.Listen(addr)
+        require.NoError(t, err)
+
+        dist := NewDistributer(addr, ln)
+        defer dist.Close()
+        go dist.Start()
+        dists = append(dists, dist)
+    }
+
+    runner := dists[0].Distribute(Pipeline(Scatter(), Gather()), ":5551", ":5552")
+    _, err := testRun(runner, NewDataset(Strs{strings.Repeat("x", 1000)}), NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "see ep.MaxMessageSize")
+
+    MaxMessageSize, MaxRunnerSize = 1 << 20, 10
+    _, err = testRun(runner, NewDataset(Strs{"a"}))
+    require.Error(t, err)
+    require.Contains(t, err.Error(), "see ep.MaxRunnerSize")
+}
+
+func TestWireDecoderWhitelist(t *testing.T) {
+    b := encodeMessages(&dataReq{&passthrough{}})
+    err := NewWireDecoder(bytes.NewReader(b)).Decode(&dataReq{})
+    require.EqualError(t, err, "ep: unexpected message *ep.passthrough")
+
+    b = encodeMessages(&dataReq{NewDataset(Strs{"a", "b"}, Ints{1})})
+    err = NewWireDecoder(bytes.NewReader(b)).Decode(&dataReq{})
+    require.EqualError(t, err, "ep: columns of different lengths")
+}
+
+func TestReadStrMaxLen(t *testing.T) {
+    var buf bytes.Buffer
+    require.NoError(t, writeStr(&buf, strings.Repeat("x", maxStrLen)))
+    err := writeStr(&buf, strings.Repeat("x", maxStrLen + 1))
+    require.EqualError(t, err, "ep: string exceeds the maximum length of 4096")
+    err = writeHandshake(&buf, connData, strings.Repeat("x", 1 << 16))
+    require.EqualError(t, err, "ep: string exceeds the maximum length of 4096")
+
+    s, err := readStr(&buf)
+    require.NoError(t, err)
+    require.Equal(t, maxStrLen, len(s))
+    require.Equal(t, 0, buf.Len())
+
+    buf.Write([]byte{maxStrLen >> 8, maxStrLen & 0xff + 1})
+    _, err = readStr(&buf)
+    require.EqualError(t, err, "ep: string exceeds the maximum length of 4096")
+
+    _, err = readStr(strings.NewReader("\x00\x05abc"))
+    require.Equal(t, io.ErrUnexpectedEOF, err)
+}
+
+// Tests that short strings are read with a single allocation
+func TestReadStrAllocs(t *testing.T) {
+    b := []byte("\x00\x2a:5551:6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+    r := bytes.NewReader(b)
+    allocs := testing.AllocsPerRun(100, func() {
+        r.Reset(b)
+        s, err := readStr(r)
+        if err != nil || s != string(b[2:]) {
+            panic(fmt.Sprint(s, err))
+        }
+    })
+    require.Equal(t, 1.0, allocs)
+}
+
+func TestHandshake(t *testing.T) {
+    var buf bytes.Buffer
+    require.NoError(t, writeHandshake(&buf, connData, ":5551:uid"))
+    require.Equal(t, "ep\x01\x00\x01D\x00\x09:5551:uid", buf.String())
+
+    // the stream that follows the handshake isn't consumed
+    buf.WriteString("rest")
+    typee, key, err := readHandshake(&buf)
+    require.NoError(t, err)
+    require.Equal(t, connData, typee)
+    require.Equal(t, ":5551:uid", key)
+    require.Equal(t, "rest", buf.String())
+
+    _, _, err = readHandshake(strings.NewReader("ep\x02\x00\x01P\x00\x00"))
+    require.EqualError(t, err, "ep: unsupported protocol version 2, expected 1")
+
+    _, _, err = readHandshake(strings.NewReader("GET / HTTP/1.1"))
+    require.EqualError(t, err, "ep: unrecognized connection")
+}
+
+// Decoding arbitrary input must fail gracefully, without panics or unbounded
+// allocations
+func FuzzWireDecoder(f *testing.F) {
+    f.Add(encodeMessages(&dataReq{NewDataset(Strs{"hello", "world"}, Ints{1, 2})}))
+    f.Add(encodeMessages(&dataReq{NewDataset(Floats{1.5}, Bools{true}, Strs{"a"})}))
+    f.Add(encodeMessages(&dataReq{&stopMsg{}}, &dataReq{&errMsg{"bad"}}))
+    f.Fuzz(func(t *testing.T, b []byte) {
+        dec := NewWireDecoder(bytes.NewReader(b))
+        for i := 0; i < 10; i++ {
+            if dec.Decode(&dataReq{}) != nil {
+                return
+            }
+        }
+    })
+}
+
+func FuzzReadHandshake(f *testing.F) {
+    f.Add([]byte("ep\x01\x00\x01D\x00\x09:5551:uid"))
+    f.Fuzz(func(t *testing.T, b []byte) {
+        typee, key, err := readHandshake(bytes.NewReader(b))
+        if err == nil && (len(typee) > maxStrLen || len(key) > maxStrLen) {
+            t.Fatalf("invalid handshake %q %q", typee, key)
+        }
+    })
+}
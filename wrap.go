@@ -0,0 +1,111 @@
+package ep
+
+import (
+    "context"
+)
+
+var _ = registerGob(&wrap{})
+
+// Hooks are callbacks invoked by a wrapped Runner, for layering cross-cutting
+// concerns (metrics, tracing, auditing, etc.) onto any Runner. See Wrap.
+//
+// NOTE: In order to distribute a wrapped Runner, the Hooks implementation must
+// be serializable with gob, and registered.
+type Hooks interface {
+
+    // Start is called before the runner starts running
+    Start(ctx context.Context)
+
+    // Input is called for every input dataset, before the runner receives it
+    Input(ctx context.Context, data Dataset)
+
+    // Output is called for every output dataset, before it's emitted
+    Output(ctx context.Context, data Dataset)
+
+    // End is called after the runner has completed, with its error if it has
+    // failed, or nil otherwise
+    End(ctx context.Context, err error)
+}
+
+// HookFuncs implements Hooks with optional callback functions, where missing
+// callbacks are skipped. NOTE: functions cannot be serialized, thus a Runner
+// wrapped with it cannot be distributed.
+type HookFuncs struct {
+    OnStart func(ctx context.Context)
+    OnInput func(ctx context.Context, data Dataset)
+    OnOutput func(ctx context.Context, data Dataset)
+    OnEnd func(ctx context.Context, err error)
+}
+
+func (h *HookFuncs) Start(ctx context.Context) {
+    if h.OnStart != nil {
+        h.OnStart(ctx)
+    }
+}
+
+func (h *HookFuncs) Input(ctx context.Context, data Dataset) {
+    if h.OnInput != nil {
+        h.OnInput(ctx, data)
+    }
+}
+
+func (h *HookFuncs) Output(ctx context.Context, data Dataset) {
+    if h.OnOutput != nil {
+        h.OnOutput(ctx, data)
+    }
+}
+
+func (h *HookFuncs) End(ctx context.Context, err error) {
+    if h.OnEnd != nil {
+        h.OnEnd(ctx, err)
+    }
+}
+
+// Wrap returns a Runner that runs the provided runner as is, while invoking
+// the hooks upon its start and end, and for each of its input and output
+// datasets. The hooks must not modify the datasets.
+func Wrap(r Runner, hooks Hooks) Runner {
+    return &wrap{r, hooks}
+}
+
+type wrap struct {
+    Runner Runner
+    Hooks Hooks
+}
+
+func (r *wrap) Returns() []Type { return r.Runner.Returns() }
+func (r *wrap) returnsFrom(inp []Type) []Type {
+    return returnsFrom(r.Runner, inp)
+}
+
+func (r *wrap) Run(ctx context.Context, inp, out chan Dataset) (err error) {
+    r.Hooks.Start(ctx)
+    defer func() { r.Hooks.End(ctx, err) }()
+
+    wrappedInp := newChan(ctx)
+    go func() {
+        defer close(wrappedInp)
+        for data := range inp {
+            r.Hooks.Input(ctx, data)
+            wrappedInp <- data
+        }
+    }()
+
+    // drain the input in case the runner has exited early
+    defer func() { for _ = range wrappedInp {} }()
+
+    wrappedOut := newChan(ctx)
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        for data := range wrappedOut {
+            r.Hooks.Output(ctx, data)
+            out <- data
+        }
+    }()
+
+    err = r.Runner.Run(ctx, wrappedInp, wrappedOut)
+    close(wrappedOut)
+    <- done
+    return err
+}
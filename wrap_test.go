@@ -0,0 +1,48 @@
+package ep
+
+import (
+    "fmt"
+    "context"
+    "testing"
+    "github.com/stretchr/testify/require"
+)
+
+func ExampleWrap() {
+    rows := 0
+    runner := Wrap(&Upper{}, &HookFuncs{
+        OnOutput: func(ctx context.Context, data Dataset) { rows += data.Len() },
+        OnEnd: func(ctx context.Context, err error) {
+            fmt.Println("rows:", rows, "err:", err)
+        },
+    })
+
+    data, err := testRun(runner, NewDataset(Strs{"hello", "world"}))
+    fmt.Println(data, err)
+
+    // Output:
+    // rows: 2 err: <nil>
+    // [[HELLO WORLD]] <nil>
+}
+
+func TestWrapHooks(t *testing.T) {
+    events := []string{}
+    hooks := &HookFuncs{
+        OnStart: func(context.Context) { events = append(events, "start") },
+        OnInput: func(_ context.Context, data Dataset) {
+            events = append(events, fmt.Sprintf("in %v", data))
+        },
+        OnEnd: func(_ context.Context, err error) {
+            events = append(events, fmt.Sprintf("end %v", err))
+        },
+    }
+
+    err := fmt.Errorf("something bad happened")
+    failed := Map([]Type{Str}, func(Dataset) (Dataset, error) { return nil, err })
+    _, err = testRun(Wrap(failed, hooks), NewDataset(Strs{"a"}), NewDataset(Strs{"b"}))
+    require.Error(t, err)
+
+    // the input might be consumed after the failure
+    require.Equal(t, "start", events[0])
+    require.Equal(t, "in [[a]]", events[1])
+    require.Equal(t, "end something bad happened", events[len(events) - 1])
+}